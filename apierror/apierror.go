@@ -0,0 +1,216 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Code is a stable, machine-readable error identifier. Codes never change between
+// releases. Validation errors keep their own code (e.g. TOKEN_EMPTY, EMAIL_DISPOSABLE).
+type Code string
+
+const (
+	CodeTokenMissing                   Code = "TOKEN_MISSING"
+	CodeTokenExpired                   Code = "TOKEN_EXPIRED"
+	CodeTokenInvalid                   Code = "TOKEN_INVALID"
+	CodeTokenRevoked                   Code = "TOKEN_REVOKED"
+	CodeInsufficientUserAuthentication Code = "INSUFFICIENT_USER_AUTHENTICATION"
+	CodeStepUpRequired                 Code = "STEP_UP_REQUIRED"
+	CodeElevationRequired              Code = "ELEVATION_REQUIRED"
+	CodeClaimRejected                  Code = "CLAIM_REJECTED"
+	CodeInsufficientScope              Code = "INSUFFICIENT_SCOPE"
+	CodeAudienceNotAllowed             Code = "AUDIENCE_NOT_ALLOWED"
+	CodeAccessDenied                   Code = "ACCESS_DENIED"
+	CodeSignedURLInvalid               Code = "SIGNED_URL_INVALID"
+	CodeSignedURLExpired               Code = "SIGNED_URL_EXPIRED"
+	CodeInvalidRequest                 Code = "INVALID_REQUEST"
+	CodeInvalidNonce                   Code = "INVALID_NONCE"
+	CodeInvalidTarget                  Code = "INVALID_TARGET"
+	CodeInvalidScope                   Code = "INVALID_SCOPE"
+	CodeOTPInvalid                     Code = "OTP_INVALID"
+	CodeRateLimited                    Code = "RATE_LIMITED"
+	CodeConflict                       Code = "CONFLICT"
+	CodeServiceUnavailable             Code = "SERVICE_UNAVAILABLE"
+	CodeInternal                       Code = "INTERNAL_ERROR"
+)
+
+// ErrTokenMissing is the error of requests without credentials, mapped to 401 TOKEN_MISSING.
+var ErrTokenMissing = errors.New("missing bearer token")
+
+// defaultMessages are the messages sent to clients for each code. Error details are
+// not sent, as they may reveal internals (keys, addresses, claims).
+var defaultMessages = map[Code]string{
+	CodeTokenMissing:                   "missing token",
+	CodeTokenExpired:                   "token expired",
+	CodeTokenInvalid:                   "invalid token",
+	CodeTokenRevoked:                   "token revoked",
+	CodeInsufficientUserAuthentication: "stronger or more recent authentication required",
+	CodeStepUpRequired:                 "additional authentication required",
+	CodeElevationRequired:              "re-authentication required",
+	CodeClaimRejected:                  "token not accepted",
+	CodeInsufficientScope:              "token scope insufficient",
+	CodeAudienceNotAllowed:             "audience not allowed",
+	CodeAccessDenied:                   "access denied",
+	CodeSignedURLInvalid:               "invalid signed URL",
+	CodeSignedURLExpired:               "signed URL expired",
+	CodeInvalidRequest:                 "invalid request",
+	CodeInvalidNonce:                   "invalid nonce",
+	CodeInvalidTarget:                  "audience not allowed for this token",
+	CodeInvalidScope:                   "scope not granted by this token",
+	CodeOTPInvalid:                     "invalid one-time password",
+	CodeRateLimited:                    "too many attempts",
+	CodeConflict:                       "operation already in progress",
+	CodeServiceUnavailable:             "service temporarily unavailable",
+	CodeInternal:                       "internal error",
+}
+
+// Error is the HTTP representation of an error.
+//
+// Fields:
+//   - Status: Recommended HTTP status code
+//   - Code: Stable machine-readable code
+//   - Message: Message safe to send to clients
+//
+// JSON serialization:
+//   - Example: {"code": "TOKEN_EXPIRED", "message": "token expired"}
+type Error struct {
+	Status  int    `json:"-"`
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the client message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// mapping associates a sentinel error with its status and code.
+type mapping struct {
+	err    error
+	status int
+	code   Code
+}
+
+// mappings are checked in order: more specific errors come first (e.g. an
+// expired token is also an invalid one).
+var mappings = []mapping{
+	{ErrTokenMissing, http.StatusUnauthorized, CodeTokenMissing},
+	{jwt.ErrTokenExpired, http.StatusUnauthorized, CodeTokenExpired},
+	{service.ErrTokenEpochRevoked, http.StatusUnauthorized, CodeTokenRevoked},
+	{service.ErrTokenReplayed, http.StatusUnauthorized, CodeTokenRevoked},
+	{service.ErrInsufficientUserAuthentication, http.StatusUnauthorized, CodeInsufficientUserAuthentication},
+	{service.ErrStepUpRequired, http.StatusUnauthorized, CodeStepUpRequired},
+	{jwt.ErrTokenMalformed, http.StatusUnauthorized, CodeTokenInvalid},
+	{jwt.ErrTokenUnverifiable, http.StatusUnauthorized, CodeTokenInvalid},
+	{jwt.ErrTokenSignatureInvalid, http.StatusUnauthorized, CodeTokenInvalid},
+	{jwt.ErrTokenInvalidClaims, http.StatusUnauthorized, CodeTokenInvalid},
+	{jwt.ErrTokenNotValidYet, http.StatusUnauthorized, CodeTokenInvalid},
+	{service.ErrInvalidTokenClaim, http.StatusUnauthorized, CodeTokenInvalid},
+	{service.ErrUnknownTokenType, http.StatusUnauthorized, CodeTokenInvalid},
+	{service.ErrClaimRejected, http.StatusForbidden, CodeClaimRejected},
+	{service.ErrInsufficientScope, http.StatusForbidden, CodeInsufficientScope},
+	{service.ErrAudienceNotAllowed, http.StatusForbidden, CodeAudienceNotAllowed},
+	{service.ErrElevationRequired, http.StatusForbidden, CodeElevationRequired},
+	{service.ErrRiskDenied, http.StatusForbidden, CodeAccessDenied},
+	{service.ErrGeoDenied, http.StatusForbidden, CodeAccessDenied},
+	{service.ErrActorNotAllowed, http.StatusForbidden, CodeAccessDenied},
+	{lib.ErrSignedURLExpired, http.StatusForbidden, CodeSignedURLExpired},
+	{lib.ErrSignedURLInvalid, http.StatusForbidden, CodeSignedURLInvalid},
+	{service.ErrMaxAttemptsExceeded, http.StatusTooManyRequests, CodeRateLimited},
+	{service.ErrPasswordResetQuotaExceeded, http.StatusTooManyRequests, CodeRateLimited},
+	{service.ErrInvalidOTP, http.StatusBadRequest, CodeOTPInvalid},
+	{service.ErrInvalidNonce, http.StatusBadRequest, CodeInvalidNonce},
+	{service.ErrInvalidTarget, http.StatusBadRequest, CodeInvalidTarget},
+	{service.ErrInvalidScope, http.StatusBadRequest, CodeInvalidScope},
+	{service.ErrInvalidUserID, http.StatusBadRequest, CodeInvalidRequest},
+	{lib.ErrLockNotAcquired, http.StatusConflict, CodeConflict},
+	{redis.ErrClosed, http.StatusServiceUnavailable, CodeServiceUnavailable},
+	{context.DeadlineExceeded, http.StatusServiceUnavailable, CodeServiceUnavailable},
+}
+
+// From returns the HTTP representation of err. Validation errors are returned with
+// status 400 and their own code and message; unknown errors as a 500 INTERNAL_ERROR.
+//
+// Parameters:
+//   - err: Error returned by a service, possibly wrapped
+//
+// Returns:
+//   - *Error: Status, code and client message, nil if err is nil
+//
+// Example:
+//
+//	claim, err := accessService.VerifyAccessToken(token)
+//	if err != nil {
+//	    apiErr := apierror.From(err) // 401 TOKEN_EXPIRED, 401 TOKEN_INVALID, ...
+//	    log.Printf("rejected token: %v (%s)", err, apiErr.Code)
+//	}
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var validationError *validation.ValidationError
+	if errors.As(err, &validationError) {
+		return &Error{Status: http.StatusBadRequest, Code: Code(validationError.Code), Message: validationError.Message}
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return newError(m.status, m.code)
+		}
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return newError(http.StatusServiceUnavailable, CodeServiceUnavailable)
+	}
+	return newError(http.StatusInternalServerError, CodeInternal)
+}
+
+// Status returns the recommended HTTP status code of err (see From), 200 if err is nil.
+func Status(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return From(err).Status
+}
+
+// WriteJSONError writes err as a JSON response with its recommended status code:
+// {"code": "...", "message": "..."}. Headers set beforehand (e.g. WWW-Authenticate,
+// Retry-After) are kept.
+//
+// Parameters:
+//   - w: Response of the handler
+//   - err: Error returned by a service; nil writes nothing
+//
+// Example:
+//
+//	valid, err := otpService.VerifyOTP(ctx, userID, form.Code)
+//	if err != nil {
+//	    apierror.WriteJSONError(w, err) // 429 RATE_LIMITED, 400 OTP_INVALID, ...
+//	    return
+//	}
+func WriteJSONError(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+
+	apiErr := From(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
+
+func newError(status int, code Code) *Error {
+	return &Error{Status: status, Code: code, Message: defaultMessages[code]}
+}
@@ -0,0 +1,134 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType string = "application/problem+json"
+
+// Problem is the RFC 7807 problem details representation of an error.
+// The problem type is "about:blank": the title is the HTTP status text, and the
+// stable code (see Error) identifies the failure.
+//
+// Fields:
+//   - Type: Problem type URI ("about:blank")
+//   - Title: Short summary, the HTTP status text
+//   - Status: HTTP status code
+//   - Detail: Message safe to send to clients
+//   - Instance: URI of the failed request, omitted when empty
+//   - Code: Stable machine-readable code (extension member)
+//   - RemainingAttempts: Attempts left before being blocked (extension member, rate limited operations only)
+//   - RetryAfter: Seconds to wait before retrying (extension member, exhausted rate limits only)
+//
+// JSON serialization:
+//   - Example: {"type": "about:blank", "title": "Too Many Requests", "status": 429,
+//     "detail": "too many attempts", "code": "RATE_LIMITED", "remaining_attempts": 0, "retry_after": 540}
+type Problem struct {
+	Type              string `json:"type"`
+	Title             string `json:"title"`
+	Status            int    `json:"status"`
+	Detail            string `json:"detail"`
+	Instance          string `json:"instance,omitempty"`
+	Code              Code   `json:"code"`
+	RemainingAttempts *int   `json:"remaining_attempts,omitempty"`
+	RetryAfter        *int64 `json:"retry_after,omitempty"`
+}
+
+// ProblemOption adds members to a Problem.
+type ProblemOption func(*Problem)
+
+// WithInstance sets the instance member, usually the path of the request.
+func WithInstance(instance string) ProblemOption {
+	return func(p *Problem) {
+		p.Instance = instance
+	}
+}
+
+// WithRateLimit sets the remaining_attempts member, and retry_after when no attempt is left.
+//
+// Parameters:
+//   - state: State returned by OTPService.RateLimit or LoginAttemptService.RateLimit
+func WithRateLimit(state service.RateLimitState) ProblemOption {
+	return func(p *Problem) {
+		remaining := max(state.Remaining, 0)
+		p.RemainingAttempts = &remaining
+		if state.Exhausted() && state.Reset > 0 {
+			retryAfter := ceilSeconds(state.Reset)
+			p.RetryAfter = &retryAfter
+		}
+	}
+}
+
+// NewProblem returns the problem details of err, with the status and code of From.
+//
+// Parameters:
+//   - err: Error returned by a service, possibly wrapped
+//   - opts: Additional members (instance, rate limiting state)
+//
+// Returns:
+//   - *Problem: Problem details, nil if err is nil
+func NewProblem(err error, opts ...ProblemOption) *Problem {
+	apiErr := From(err)
+	if apiErr == nil {
+		return nil
+	}
+
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(apiErr.Status),
+		Status: apiErr.Status,
+		Detail: apiErr.Message,
+		Code:   apiErr.Code,
+	}
+	for _, opt := range opts {
+		opt(problem)
+	}
+	return problem
+}
+
+// WriteProblem writes err as an RFC 7807 problem details response with its recommended
+// status code. The Retry-After header is set along with the retry_after member.
+// Headers set beforehand (e.g. WWW-Authenticate, RateLimit-*) are kept.
+//
+// Parameters:
+//   - w: Response of the handler
+//   - err: Error returned by a service; nil writes nothing
+//   - opts: Additional members (instance, rate limiting state)
+//
+// Example:
+//
+//	valid, err := otpService.VerifyOTP(ctx, userID, form.Code)
+//	if err != nil {
+//	    state, _ := otpService.RateLimit(ctx, userID)
+//	    apierror.WriteProblem(w, err, apierror.WithRateLimit(state), apierror.WithInstance(r.URL.Path))
+//	    return
+//	}
+func WriteProblem(w http.ResponseWriter, err error, opts ...ProblemOption) {
+	problem := NewProblem(err, opts...)
+	if problem == nil {
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", ProblemContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if problem.RetryAfter != nil {
+		header.Set("Retry-After", strconv.FormatInt(*problem.RetryAfter, 10))
+	}
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// ceilSeconds returns d in whole seconds, rounded up (0 for non-positive durations).
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,317 @@
+// Package dynamostore implements service.KVStore and service.TokenStore on Amazon
+// DynamoDB, for serverless deployments without a Redis of their own.
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+const (
+	// attributeKey is the partition key of the table (S).
+	attributeKey string = "key"
+	// attributeValue holds the value of the key (S).
+	attributeValue string = "value"
+	// attributeExpiresAt is the DynamoDB TTL attribute: expiry in Unix seconds (N).
+	attributeExpiresAt string = "expires_at"
+	// attributeExpiresAtMs is the expiry in Unix milliseconds (N). DynamoDB deletes
+	// expired items within days, so reads compare it to the current time.
+	attributeExpiresAtMs string = "expires_at_ms"
+
+	// maxIncrAttempts bounds the compare-and-swap retries of Incr under contention.
+	maxIncrAttempts int = 10
+
+	// defaultScanCount is the batch size of Scan when count is not positive.
+	defaultScanCount int = 500
+)
+
+// ErrContention is returned by Incr when the key kept changing during maxIncrAttempts
+// compare-and-swap attempts.
+var ErrContention = errors.New("too many concurrent updates")
+
+// Client is the subset of *dynamodb.Client used by the store.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// kvStore implements service.KVStore on a DynamoDB table.
+type kvStore struct {
+	client Client
+	table  string
+	now    func() time.Time
+}
+
+// NewKVStore returns a service.KVStore keeping its keys in a DynamoDB table, one item
+// per key. The table has the string partition key "key" and the TTL attribute
+// "expires_at", see CreateTable. Reads are strongly consistent, and Incr is a
+// conditional write retried on contention, so that attempt counters stay exact
+// across instances.
+//
+// Item layout:
+//   - key (S, partition key): The KVStore key, e.g. "otp:{userID}"
+//   - value (S): The value
+//   - expires_at (N): Expiry in Unix seconds, the table TTL attribute
+//   - expires_at_ms (N): Expiry in Unix milliseconds, checked on every read
+//
+// Returns an error if the client is nil or the table name is empty.
+//
+// Example:
+//
+//	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	store, err := dynamostore.NewKVStore(dynamodb.NewFromConfig(cfg), "tokens")
+//	otpService, err := service.NewOTPServiceWithStore(ctx, store, config)
+func NewKVStore(client Client, table string) (service.KVStore, error) {
+	if client == nil {
+		return nil, errors.New("client is nil")
+	}
+	if table == "" {
+		return nil, errors.New("invalid table name")
+	}
+
+	return &kvStore{client: client, table: table, now: time.Now}, nil
+}
+
+// item is a decoded table item.
+type item struct {
+	value   string
+	expires time.Time
+}
+
+// itemKey returns the primary key of key.
+func itemKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{attributeKey: &types.AttributeValueMemberS{Value: key}}
+}
+
+// millis formats t in Unix milliseconds.
+func millis(t time.Time) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.UnixMilli(), 10)}
+}
+
+// newItem returns the attributes of key holding value until expires.
+func newItem(key string, value string, expires time.Time) map[string]types.AttributeValue {
+	// Round the TTL attribute up, so that DynamoDB never deletes a live item
+	seconds := expires.Add(time.Second - time.Nanosecond).Unix()
+	return map[string]types.AttributeValue{
+		attributeKey:         &types.AttributeValueMemberS{Value: key},
+		attributeValue:       &types.AttributeValueMemberS{Value: value},
+		attributeExpiresAt:   &types.AttributeValueMemberN{Value: strconv.FormatInt(seconds, 10)},
+		attributeExpiresAtMs: millis(expires),
+	}
+}
+
+// decodeItem returns the item of the attributes, false if they are missing or expired.
+func (s *kvStore) decodeItem(attributes map[string]types.AttributeValue) (item, bool, error) {
+	if len(attributes) == 0 {
+		return item{}, false, nil
+	}
+
+	value, ok := attributes[attributeValue].(*types.AttributeValueMemberS)
+	if !ok {
+		return item{}, false, errors.New("invalid item: missing value")
+	}
+	expires, ok := attributes[attributeExpiresAtMs].(*types.AttributeValueMemberN)
+	if !ok {
+		return item{}, false, errors.New("invalid item: missing expiry")
+	}
+	ms, err := strconv.ParseInt(expires.Value, 10, 64)
+	if err != nil {
+		return item{}, false, fmt.Errorf("invalid item expiry: %w", err)
+	}
+
+	decoded := item{value: value.Value, expires: time.UnixMilli(ms)}
+	if !s.now().Before(decoded.expires) {
+		return item{}, false, nil
+	}
+	return decoded, true, nil
+}
+
+// get returns the live item of key.
+func (s *kvStore) get(ctx context.Context, key string) (item, bool, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            itemKey(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return item{}, false, err
+	}
+	return s.decodeItem(out.Item)
+}
+
+func (s *kvStore) Get(ctx context.Context, key string) (string, bool, error) {
+	found, ok, err := s.get(ctx, key)
+	return found.value, ok, err
+}
+
+func (s *kvStore) SetEX(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      newItem(key, value, s.now().Add(ttl)),
+	})
+	return err
+}
+
+func (s *kvStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	var n int64
+	for _, key := range keys {
+		out, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:    aws.String(s.table),
+			Key:          itemKey(key),
+			ReturnValues: types.ReturnValueAllOld,
+		})
+		if err != nil {
+			return n, err
+		}
+		// Expired items awaiting the TTL sweeper did not exist
+		if _, ok, err := s.decodeItem(out.Attributes); err == nil && ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *kvStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	for range maxIncrAttempts {
+		current, ok, err := s.get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		var n int64
+		if ok {
+			n, err = strconv.ParseInt(current.value, 10, 64)
+			if err != nil {
+				return 0, errors.New("value is not an integer")
+			}
+			n++
+			err = s.swap(ctx, key, current, n)
+		} else {
+			n = 1
+			err = s.create(ctx, key, newItem(key, "1", s.now().Add(ttl)))
+		}
+
+		var conflict *types.ConditionalCheckFailedException
+		if errors.As(err, &conflict) {
+			// Changed, created or expired since read: read again
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("failed to increment key %s: %w", key, ErrContention)
+}
+
+// swap sets the value of key to n if it still holds current and did not expire,
+// keeping its expiry.
+func (s *kvStore) swap(ctx context.Context, key string, current item, n int64) error {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 itemKey(key),
+		UpdateExpression:    aws.String("SET #value = :next"),
+		ConditionExpression: aws.String("#value = :current AND #expires = :expires AND #expires > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#value":   attributeValue,
+			"#expires": attributeExpiresAtMs,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":next":    &types.AttributeValueMemberS{Value: strconv.FormatInt(n, 10)},
+			":current": &types.AttributeValueMemberS{Value: current.value},
+			":expires": millis(current.expires),
+			":now":     millis(s.now()),
+		},
+	})
+	return err
+}
+
+// create puts the item of key unless a live item exists.
+func (s *kvStore) create(ctx context.Context, key string, attributes map[string]types.AttributeValue) error {
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                attributes,
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expires <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":     attributeKey,
+			"#expires": attributeExpiresAtMs,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": millis(s.now()),
+		},
+	})
+	return err
+}
+
+func (s *kvStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	found, ok, err := s.get(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return found.expires.Sub(s.now()), nil
+}
+
+func (s *kvStore) Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(s.table),
+		ProjectionExpression: aws.String("#key"),
+		FilterExpression:     aws.String("begins_with(#key, :prefix) AND #expires > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":     attributeKey,
+			"#expires": attributeExpiresAtMs,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+			":now":    millis(s.now()),
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	var batch []string
+	paginator := dynamodb.NewScanPaginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, attributes := range page.Items {
+			key, ok := attributes[attributeKey].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			if batch = append(batch, key.Value); len(batch) == count {
+				if err := fn(batch); err != nil {
+					return err
+				}
+				batch = nil
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
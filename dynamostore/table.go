@@ -0,0 +1,125 @@
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TableClient is the subset of *dynamodb.Client used by CreateTable and CreateTokenTable.
+type TableClient interface {
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// CreateTable creates the table of NewKVStore, billed per request, waits until it
+// is active and enables the expiry of the items on their "expires_at" attribute.
+// Tables managed by infrastructure code only need the same key schema and TTL attribute.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - client: DynamoDB client, usually *dynamodb.Client
+//   - table: Name of the table to create
+//   - wait: Maximum time to wait for the table to become active
+//
+// Returns:
+//   - error: Validation or DynamoDB errors, including types.ResourceInUseException if the table exists
+//
+// Example:
+//
+//	err := dynamostore.CreateTable(ctx, dynamoClient, "tokens", 2*time.Minute)
+func CreateTable(ctx context.Context, client TableClient, table string, wait time.Duration) error {
+	if client == nil {
+		return errors.New("client is nil")
+	}
+	if table == "" {
+		return errors.New("invalid table name")
+	}
+
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(attributeKey), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(attributeKey), KeyType: types.KeyTypeHash},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return enableExpiry(ctx, client, table, wait)
+}
+
+// CreateTokenTable creates the table of NewTokenStore, billed per request, with its
+// TokenHashIndex, waits until it is active and enables the expiry of the items on
+// their "expires_at" attribute. Tables managed by infrastructure code only need the
+// same key schema, index and TTL attribute.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - client: DynamoDB client, usually *dynamodb.Client
+//   - table: Name of the table to create
+//   - wait: Maximum time to wait for the table to become active
+//
+// Returns:
+//   - error: Validation or DynamoDB errors, including types.ResourceInUseException if the table exists
+//
+// Example:
+//
+//	err := dynamostore.CreateTokenTable(ctx, dynamoClient, "refresh_tokens", 2*time.Minute)
+func CreateTokenTable(ctx context.Context, client TableClient, table string, wait time.Duration) error {
+	if client == nil {
+		return errors.New("client is nil")
+	}
+	if table == "" {
+		return errors.New("invalid table name")
+	}
+
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(attributeUserID), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(attributeTokenHash), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(attributeUserID), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String(attributeTokenHash), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName: aws.String(TokenHashIndex),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String(attributeTokenHash), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return enableExpiry(ctx, client, table, wait)
+}
+
+// enableExpiry waits until a new table is active and enables its TTL attribute.
+func enableExpiry(ctx context.Context, client TableClient, table string, wait time.Duration) error {
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, wait); err != nil {
+		return err
+	}
+
+	_, err := client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(table),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(attributeExpiresAt),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	return err
+}
@@ -0,0 +1,122 @@
+package dynamostore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/dynamostore"
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenStore(t *testing.T) {
+	t.Run("Should fail with nil client", func(t *testing.T) {
+		_, err := dynamostore.NewTokenStore(nil, tokenTable)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "client is nil")
+	})
+
+	t.Run("Should fail with empty table", func(t *testing.T) {
+		_, err := dynamostore.NewTokenStore(client, "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid table name")
+	})
+}
+
+func TestTokenStore(t *testing.T) {
+	store, err := dynamostore.NewTokenStore(client, tokenTable)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("Should put, get, find and delete tokens", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "123", Hash: "h1", Value: "v1", ExpiresAt: expiresAt}))
+
+		stored, found, err := store.Get(ctx, "123", "h1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "v1", stored.Value)
+		assert.WithinDuration(t, expiresAt, stored.ExpiresAt, time.Millisecond)
+
+		// The index is eventually consistent
+		require.Eventually(t, func() bool {
+			stored, found, err := store.Find(ctx, "h1")
+			return err == nil && found && stored.UserID == "123"
+		}, 5*time.Second, 50*time.Millisecond)
+
+		_, found, err = store.Get(ctx, "456", "h1")
+		require.NoError(t, err)
+		assert.False(t, found, "tokens are per user")
+
+		deleted, err := store.Delete(ctx, "123", "h1", "missing")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, found, err = store.Get(ctx, "123", "h1")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Should list and scan tokens", func(t *testing.T) {
+		for i := range 3 {
+			require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "list", Hash: fmt.Sprintf("l%d", i), Value: "v", ExpiresAt: expiresAt}))
+		}
+
+		tokens, err := store.List(ctx, "list")
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+
+		var batches int
+		var hashes []string
+		require.NoError(t, store.Scan(ctx, 2, func(tokens []service.StoredToken) error {
+			batches++
+			for _, token := range tokens {
+				hashes = append(hashes, token.Hash)
+			}
+			return nil
+		}))
+		assert.Subset(t, hashes, []string{"l0", "l1", "l2"})
+		assert.GreaterOrEqual(t, batches, 2)
+	})
+
+	t.Run("Should not return expired tokens", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "short", Hash: "s", Value: "v", ExpiresAt: time.Now().Add(50 * time.Millisecond)}))
+		time.Sleep(100 * time.Millisecond)
+
+		_, found, err := store.Get(ctx, "short", "s")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		tokens, err := store.List(ctx, "short")
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+
+		deleted, err := store.Delete(ctx, "short", "s")
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("Should back a refresh token service", func(t *testing.T) {
+		ttl := "1h"
+		rts, err := service.NewRefreshTokenServiceWithStore(ctx, store, &lib.Config{RefreshTokenTTL: &ttl})
+		require.NoError(t, err)
+
+		token, err := rts.CreateRefreshToken(ctx, "svc")
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(ctx, "svc", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		require.NoError(t, rts.RevokeAllUserRefreshTokens(ctx, "svc"))
+		valid, err = rts.VerifyRefreshToken(ctx, "svc", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
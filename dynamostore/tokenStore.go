@@ -0,0 +1,272 @@
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+const (
+	// attributeUserID is the partition key of the token table (S).
+	attributeUserID string = "user_id"
+	// attributeTokenHash is the sort key of the token table (S), and the partition
+	// key of its TokenHashIndex.
+	attributeTokenHash string = "token_hash"
+
+	// TokenHashIndex is the global secondary index of the token table on token_hash,
+	// finding a token without its user.
+	TokenHashIndex string = "token_hash-index"
+)
+
+// TokenClient is the subset of *dynamodb.Client used by the token store.
+type TokenClient interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// tokenStore implements service.TokenStore on a DynamoDB table.
+type tokenStore struct {
+	client TokenClient
+	table  string
+	now    func() time.Time
+}
+
+// NewTokenStore returns a service.TokenStore keeping the refresh or password reset
+// tokens in a DynamoDB table, one item per token, for
+// service.NewRefreshTokenServiceWithStore and service.NewPasswordResetServiceWithStore.
+// Each service needs its own table, see CreateTokenTable. Reads by user are strongly
+// consistent; lookups by hash go through TokenHashIndex and are eventually consistent.
+//
+// Item layout:
+//   - user_id (S, partition key): Owner of the token
+//   - token_hash (S, sort key): Hex-encoded SHA-256 hash of the token, partition key of TokenHashIndex
+//   - value (S): The token record
+//   - expires_at (N): Expiry in Unix seconds, the table TTL attribute
+//   - expires_at_ms (N): Expiry in Unix milliseconds, checked on every read
+//
+// Returns an error if the client is nil or the table name is empty.
+//
+// Example:
+//
+//	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	store, err := dynamostore.NewTokenStore(dynamodb.NewFromConfig(cfg), "refresh_tokens")
+//	refreshService, err := service.NewRefreshTokenServiceWithStore(ctx, store, config)
+func NewTokenStore(client TokenClient, table string) (service.TokenStore, error) {
+	if client == nil {
+		return nil, errors.New("client is nil")
+	}
+	if table == "" {
+		return nil, errors.New("invalid table name")
+	}
+
+	return &tokenStore{client: client, table: table, now: time.Now}, nil
+}
+
+// tokenKey returns the primary key of the token of userID with hash.
+func tokenKey(userID string, hash string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		attributeUserID:    &types.AttributeValueMemberS{Value: userID},
+		attributeTokenHash: &types.AttributeValueMemberS{Value: hash},
+	}
+}
+
+// decodeToken returns the token of the attributes, false if they are missing or expired.
+func (s *tokenStore) decodeToken(attributes map[string]types.AttributeValue) (service.StoredToken, bool, error) {
+	if len(attributes) == 0 {
+		return service.StoredToken{}, false, nil
+	}
+
+	var token service.StoredToken
+	for name, field := range map[string]*string{
+		attributeUserID:    &token.UserID,
+		attributeTokenHash: &token.Hash,
+		attributeValue:     &token.Value,
+	} {
+		value, ok := attributes[name].(*types.AttributeValueMemberS)
+		if !ok {
+			return service.StoredToken{}, false, fmt.Errorf("invalid item: missing %s", name)
+		}
+		*field = value.Value
+	}
+	expires, ok := attributes[attributeExpiresAtMs].(*types.AttributeValueMemberN)
+	if !ok {
+		return service.StoredToken{}, false, errors.New("invalid item: missing expiry")
+	}
+	ms, err := strconv.ParseInt(expires.Value, 10, 64)
+	if err != nil {
+		return service.StoredToken{}, false, fmt.Errorf("invalid item expiry: %w", err)
+	}
+
+	token.ExpiresAt = time.UnixMilli(ms)
+	if !s.now().Before(token.ExpiresAt) {
+		return service.StoredToken{}, false, nil
+	}
+	return token, true, nil
+}
+
+func (s *tokenStore) Put(ctx context.Context, token service.StoredToken) error {
+	if token.UserID == "" || token.Hash == "" {
+		return errors.New("incomplete token")
+	}
+	if !token.ExpiresAt.After(s.now()) {
+		return fmt.Errorf("token of user %s already expired", token.UserID)
+	}
+
+	// Round the TTL attribute up, so that DynamoDB never deletes a live item
+	seconds := token.ExpiresAt.Add(time.Second - time.Nanosecond).Unix()
+	attributes := tokenKey(token.UserID, token.Hash)
+	attributes[attributeValue] = &types.AttributeValueMemberS{Value: token.Value}
+	attributes[attributeExpiresAt] = &types.AttributeValueMemberN{Value: strconv.FormatInt(seconds, 10)}
+	attributes[attributeExpiresAtMs] = millis(token.ExpiresAt)
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      attributes,
+	})
+	return err
+}
+
+func (s *tokenStore) Get(ctx context.Context, userID string, hash string) (service.StoredToken, bool, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            tokenKey(userID, hash),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return service.StoredToken{}, false, err
+	}
+	return s.decodeToken(out.Item)
+}
+
+func (s *tokenStore) Find(ctx context.Context, hash string) (service.StoredToken, bool, error) {
+	out, err := s.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String(TokenHashIndex),
+		KeyConditionExpression: aws.String("#hash = :hash"),
+		ExpressionAttributeNames: map[string]string{
+			"#hash": attributeTokenHash,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":hash": &types.AttributeValueMemberS{Value: hash},
+		},
+	})
+	if err != nil {
+		return service.StoredToken{}, false, err
+	}
+
+	// Hashes are unique, but an expired copy may await the TTL sweeper
+	for _, attributes := range out.Items {
+		if token, ok, err := s.decodeToken(attributes); err != nil || ok {
+			return token, ok, err
+		}
+	}
+	return service.StoredToken{}, false, nil
+}
+
+func (s *tokenStore) List(ctx context.Context, userID string) ([]service.StoredToken, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("#user = :user"),
+		ExpressionAttributeNames: map[string]string{
+			"#user": attributeUserID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user": &types.AttributeValueMemberS{Value: userID},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	var tokens []service.StoredToken
+	paginator := dynamodb.NewQueryPaginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, attributes := range page.Items {
+			token, ok, err := s.decodeToken(attributes)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func (s *tokenStore) Delete(ctx context.Context, userID string, hashes ...string) (int64, error) {
+	var n int64
+	for _, hash := range hashes {
+		out, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:    aws.String(s.table),
+			Key:          tokenKey(userID, hash),
+			ReturnValues: types.ReturnValueAllOld,
+		})
+		if err != nil {
+			return n, err
+		}
+		// Expired items awaiting the TTL sweeper did not exist
+		if _, ok, err := s.decodeToken(out.Attributes); err == nil && ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *tokenStore) Scan(ctx context.Context, count int, fn func(tokens []service.StoredToken) error) error {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("#expires > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#expires": attributeExpiresAtMs,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": millis(s.now()),
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	var batch []service.StoredToken
+	paginator := dynamodb.NewScanPaginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, attributes := range page.Items {
+			token, ok, err := s.decodeToken(attributes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if batch = append(batch, token); len(batch) == count {
+				if err := fn(batch); err != nil {
+					return err
+				}
+				batch = nil
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package lib
+
+import (
+	"context"
+	"time"
+)
+
+// AuditEventType identifies a security-relevant event (e.g. "refresh_token.geo_denied").
+type AuditEventType string
+
+// AuditEvent describes a security-relevant event emitted by the services.
+// Token values are never included.
+//
+// Fields:
+//   - Type: Event identifier
+//   - UserID: User concerned by the event
+//   - Timestamp: When the event happened (UTC)
+//   - Meta: Client information from WithRequestMeta, zero value if absent
+//   - Details: Event-specific key/value pairs (e.g. "reason": "country")
+type AuditEvent struct {
+	Type      AuditEventType    `json:"type"`
+	UserID    string            `json:"user_id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Meta      RequestMeta       `json:"meta"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// AuditLogger receives audit events. Implementations must be safe for
+// concurrent use and should not block (e.g. write to a buffered channel or logger).
+type AuditLogger interface {
+	LogAuditEvent(ctx context.Context, event AuditEvent)
+}
+
+// AuditLoggerFunc adapts a function to the AuditLogger interface.
+//
+// Example:
+//
+//	logger := lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+//	    slog.InfoContext(ctx, "audit", "type", event.Type, "user_id", event.UserID)
+//	})
+type AuditLoggerFunc func(ctx context.Context, event AuditEvent)
+
+// LogAuditEvent calls f(ctx, event).
+func (f AuditLoggerFunc) LogAuditEvent(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
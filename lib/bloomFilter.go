@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"errors"
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// BloomFilter is an in-memory, concurrency-safe Bloom filter: a compact set that
+// answers "definitely absent" or "maybe present". Items cannot be removed, and the
+// false positive rate grows as items are added beyond the capacity; rebuild the
+// filter to forget removed items.
+//
+// Hashes are seeded per filter, so filters are not portable between processes.
+type BloomFilter struct {
+	bits  []atomic.Uint64
+	m     uint64 // Number of bits
+	k     uint64 // Number of hash functions
+	seeds [2]maphash.Seed
+	count atomic.Uint64
+}
+
+// NewBloomFilter creates a filter sized for capacity items at the given false positive rate.
+// A capacity of 1 million items at 0.1% takes about 1.7 MiB.
+//
+// Parameters:
+//   - capacity: Expected number of items, must be positive
+//   - falsePositiveRate: Probability that an absent item is reported present, between 0 and 1 (exclusive)
+//
+// Returns:
+//   - *BloomFilter: Empty filter
+//   - error: Validation errors
+//
+// Example:
+//
+//	filter, err := lib.NewBloomFilter(1_000_000, 0.001)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	filter.Add(hash)
+//	if !filter.MightContain(candidate) {
+//	    return false // Never added
+//	}
+func NewBloomFilter(capacity int, falsePositiveRate float64) (*BloomFilter, error) {
+	if capacity <= 0 {
+		return nil, errors.New("bloom filter capacity must be positive")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, errors.New("bloom filter false positive rate must be between 0 and 1")
+	}
+
+	// Optimal sizes: m = -n ln(p) / ln(2)², k = m/n ln(2)
+	n := float64(capacity)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = max((m+63)/64*64, 64)
+	k := max(uint64(math.Round(float64(m)/n*math.Ln2)), 1)
+
+	return &BloomFilter{
+		bits:  make([]atomic.Uint64, m/64),
+		m:     m,
+		k:     k,
+		seeds: [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+	}, nil
+}
+
+// Add inserts item in the filter.
+func (bf *BloomFilter) Add(item string) {
+	h1, h2 := bf.hashes(item)
+	for i := range bf.k {
+		bit := (h1 + i*h2) % bf.m
+		bf.bits[bit/64].Or(1 << (bit % 64))
+	}
+	bf.count.Add(1)
+}
+
+// MightContain reports whether item may have been added. false is certain, true is
+// wrong with the probability returned by FalsePositiveRate.
+func (bf *BloomFilter) MightContain(item string) bool {
+	h1, h2 := bf.hashes(item)
+	for i := range bf.k {
+		bit := (h1 + i*h2) % bf.m
+		if bf.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of Add calls, duplicates included.
+func (bf *BloomFilter) Count() uint64 {
+	return bf.count.Load()
+}
+
+// FalsePositiveRate estimates the current false positive rate from the number of
+// added items. It exceeds the rate given to NewBloomFilter once the filter holds
+// more items than its capacity.
+func (bf *BloomFilter) FalsePositiveRate() float64 {
+	k := float64(bf.k)
+	return math.Pow(1-math.Exp(-k*float64(bf.Count())/float64(bf.m)), k)
+}
+
+// hashes returns two independent hashes of item, for double hashing. The second
+// one is odd so that the k positions differ.
+func (bf *BloomFilter) hashes(item string) (uint64, uint64) {
+	return maphash.String(bf.seeds[0], item), maphash.String(bf.seeds[1], item) | 1
+}
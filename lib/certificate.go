@@ -0,0 +1,31 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+)
+
+// CertificateThumbprint computes the SHA-256 thumbprint of a client certificate,
+// base64url-encoded without padding, as used by the "x5t#S256" confirmation
+// method of RFC 8705 (OAuth 2.0 mutual-TLS certificate-bound tokens).
+//
+// Parameters:
+//   - cert: The client certificate presented during the TLS handshake
+//
+// Returns:
+//   - string: The certificate thumbprint, empty if cert is nil
+//
+// Example:
+//
+//	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+//	    thumbprint := lib.CertificateThumbprint(r.TLS.PeerCertificates[0])
+//	    token, err := refreshService.CreateBoundRefreshToken(ctx, userID, thumbprint)
+//	}
+func CertificateThumbprint(cert *x509.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// TokenChecksumLength is the number of characters appended by AppendTokenChecksum.
+const TokenChecksumLength int = 6
+
+// tokenChecksumCharset encodes the checksum with the RFC 4648 base32 alphabet,
+// so that checksums survive case folding (see TokenNormalization.FoldCase).
+const tokenChecksumCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+// AppendTokenChecksum appends a 6 characters checksum to token, allowing
+// HasValidTokenChecksum to reject typos and truncated tokens without any storage lookup.
+//
+// Checksum:
+//   - Empty secret: CRC32 (IEEE) of the token, detects accidental corruption only
+//   - Non-empty secret: HMAC-SHA256 of the token truncated to 30 bits, also rejects
+//     forged tokens from scanners that do not know the secret
+//
+// Parameters:
+//   - token: The random token
+//   - secret: Optional HMAC key
+//
+// Returns:
+//   - string: token followed by its checksum
+//
+// Example:
+//
+//	token := lib.AppendTokenChecksum(random, config.TokenChecksumSecret)
+//	lib.HasValidTokenChecksum(token, config.TokenChecksumSecret) // true
+func AppendTokenChecksum(token, secret string) string {
+	return token + tokenChecksum(token, secret)
+}
+
+// HasValidTokenChecksum reports whether token ends with the checksum of its
+// leading part, computed with the same secret as AppendTokenChecksum.
+func HasValidTokenChecksum(token, secret string) bool {
+	if len(token) <= TokenChecksumLength {
+		return false
+	}
+	body, checksum := token[:len(token)-TokenChecksumLength], token[len(token)-TokenChecksumLength:]
+	return subtle.ConstantTimeCompare([]byte(checksum), []byte(tokenChecksum(body, secret))) == 1
+}
+
+// tokenChecksum encodes the 30 most significant bits of the token digest.
+func tokenChecksum(token, secret string) string {
+	var sum uint32
+	if secret == "" {
+		sum = crc32.ChecksumIEEE([]byte(token))
+	} else {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(token))
+		sum = binary.BigEndian.Uint32(mac.Sum(nil))
+	}
+
+	ret := make([]byte, TokenChecksumLength)
+	for i := range ret {
+		ret[i] = tokenChecksumCharset[(sum>>(27-5*i))&0x1f]
+	}
+	return string(ret)
+}
@@ -1,5 +1,10 @@
 package lib
 
+import (
+	"slices"
+	"strings"
+)
+
 // Config holds the configuration for all authentication services.
 // Contains JWT settings, Redis connection parameters, and TTL configurations.
 //
@@ -7,12 +12,29 @@ package lib
 //   - Issuer: Application identifier for JWT tokens
 //   - JWTSecret: Secret key for signing and verifying JWTs (keep secure!)
 //   - JWTExpiry: Duration string for access token expiration (e.g., "15m")
+//   - JWTAlgorithm: HMAC algorithm of the access and ID tokens, HS256 (default), HS384 or HS512.
+//     Verification only accepts this algorithm: changing it invalidates the tokens in flight
+//
+// Access token guardrails (zero values use defaults):
+//   - AccessTokenMaxClaimsSize: Largest JSON size in bytes of the custom claims of an access token (default: 1024)
+//   - AccessTokenAllowedClaims: Custom claim names accepted in access tokens (default: any name not reserved)
+//   - AccessTokenMaxSize: Largest signed access token in bytes (default: 0, unlimited)
 //
 // Redis Configuration:
 //   - RedisAddr: Redis server address (e.g., "localhost:6379")
 //   - RedisPwd: Redis password (empty string if no authentication)
 //   - RedisDB: Redis database number (0-15)
 //
+// Redis pool Configuration (zero values and nil pointers use the go-redis defaults):
+//   - RedisPoolSize: Maximum number of connections (default: 10 per CPU)
+//   - RedisMinIdleConns: Idle connections kept open (default: 0)
+//   - RedisMaxIdleConns: Idle connections above which connections are closed (default: 0, unlimited)
+//   - RedisConnMaxIdleTime: Idle time after which a connection is closed (default: "30m")
+//   - RedisDialTimeout: Timeout for establishing a connection (default: "5s")
+//   - RedisReadTimeout: Timeout for socket reads (default: "3s")
+//   - RedisWriteTimeout: Timeout for socket writes (default: RedisReadTimeout)
+//   - RedisMaxRetries: Retries of a failed command (default: 3, -1 disables retries)
+//
 // TTL Configuration (pointers allow nil detection and default values):
 //   - RefreshTokenTTL: Refresh token expiration (default: "1h")
 //   - PasswordResetTTL: Password reset token expiration (default: "10m")
@@ -20,10 +42,68 @@ package lib
 //
 // OTP Configuration:
 //   - OTPSecret: Secret key for OTP generation (currently unused, reserved for TOTP)
+//
+// Password history Configuration:
+//   - PasswordHistorySize: Number of previous password hashes kept per user (default: 5 when 0)
+//
+// Login lockout Configuration (zero values and nil pointers use defaults):
+//   - LoginMaxAttempts: Failed logins allowed within the window before locking (default: 5)
+//   - LoginAttemptWindow: Window in which failed logins are counted (default: "15m")
+//   - LoginLockoutDuration: How long an account stays locked (default: "15m")
+//   - LoginChallengeThreshold: Failed logins after which a challenge (e.g. CAPTCHA) is required (default: 0, disabled)
+//   - LoginMaxAttemptsPerIP: Failed logins allowed from a client IP (lib.RequestMeta), all users
+//     combined, within the window before locking the IP (default: 0, disabled)
+//
+// Password reset Configuration:
+//   - PasswordResetPolicy: How a new reset request treats an unexpired token (default: PasswordResetPolicyReplace)
+//   - PasswordResetQuota: Reset tokens issued per user within PasswordResetTTL, further requests
+//     failing with ErrPasswordResetQuotaExceeded (default: 0, unlimited)
+//
+// Token length Configuration (zero values use defaults):
+//   - RefreshTokenLength: Length of generated refresh tokens (default: 255, min: 32), prefix and checksum excluded
+//   - RefreshTokenMaxLength: Longest refresh token accepted (default: max of 255 and RefreshTokenLength)
+//   - PasswordResetTokenLength: Length of generated password reset tokens (default: 32, min: 16), prefix and checksum excluded
+//   - PasswordResetTokenMaxLength: Longest password reset token accepted (default: max of 32 and PasswordResetTokenLength)
+//
+// Token format Configuration:
+//   - TokenPrefix: Start refresh and password reset tokens with a "{type}_v1_" prefix (default: false),
+//     e.g. "prt_v1_..." (see TokenPrefix)
+//   - TokenChecksum: End refresh and password reset tokens with a checksum checked before any Redis query (default: false).
+//     Tokens issued before enabling it stop being accepted
+//   - TokenChecksumSecret: HMAC key of the checksum, CRC32 is used when empty (see AppendTokenChecksum)
+//
+// Token storage Configuration:
+//   - RefreshTokenStorage: Whether refresh tokens are stored in clear or hashed (default: RefreshTokenStoragePlaintext)
+//
+// Token normalization Configuration:
+//   - TokenNormalization: Clean-up applied to incoming tokens before verification (default: none)
+//
+// Device code Configuration (nil pointers use defaults):
+//   - DeviceCodeTTL: Device and user code expiration (default: "10m")
+//   - DeviceCodePollInterval: Minimum interval between two token polls (default: "5s")
+//
+// Nonce Configuration:
+//   - NonceTTL: OIDC nonce and OAuth state expiration (default: "10m")
+//
+// Email change Configuration:
+//   - EmailChangeTTL: Email change confirmation token expiration (default: "24h")
+//
+// Account deletion Configuration:
+//   - AccountDeletionGracePeriod: Delay before a scheduled deletion, during which it can be cancelled (default: "336h", 14 days)
+//
+// Share link Configuration:
+//   - ShareLinkTTL: Share link expiration, whatever the remaining uses (default: "168h", 7 days)
 type Config struct {
-	Issuer           string
-	JWTSecret        string
-	JWTExpiry        string
+	Issuer    string
+	JWTSecret string
+	JWTExpiry string
+
+	JWTAlgorithm string
+
+	AccessTokenMaxClaimsSize int
+	AccessTokenAllowedClaims []string
+	AccessTokenMaxSize       int
+
 	RedisAddr        string
 	RedisPwd         string
 	RedisDB          int
@@ -31,8 +111,102 @@ type Config struct {
 	PasswordResetTTL *string
 	OTPSecret        string
 	OTPTTL           *string
+
+	RedisPoolSize        int
+	RedisMinIdleConns    int
+	RedisMaxIdleConns    int
+	RedisConnMaxIdleTime *string
+	RedisDialTimeout     *string
+	RedisReadTimeout     *string
+	RedisWriteTimeout    *string
+	RedisMaxRetries      int
+
+	PasswordHistorySize int
+
+	LoginMaxAttempts        int
+	LoginAttemptWindow      *string
+	LoginLockoutDuration    *string
+	LoginChallengeThreshold int
+	LoginMaxAttemptsPerIP   int
+
+	DeviceCodeTTL          *string
+	DeviceCodePollInterval *string
+
+	NonceTTL *string
+
+	EmailChangeTTL *string
+
+	AccountDeletionGracePeriod *string
+
+	ShareLinkTTL *string
+
+	PasswordResetPolicy PasswordResetPolicy
+	PasswordResetQuota  int
+
+	TokenNormalization TokenNormalization
+
+	RefreshTokenStorage RefreshTokenStorage
+
+	RefreshTokenLength          int
+	RefreshTokenMaxLength       int
+	PasswordResetTokenLength    int
+	PasswordResetTokenMaxLength int
+
+	TokenPrefix         bool
+	TokenChecksum       bool
+	TokenChecksumSecret string
+}
+
+// TokenNormalization configures how incoming tokens are cleaned up before verification,
+// so that tokens mangled by email scanners or copy/paste are still accepted.
+//
+// Fields:
+//   - TrimSpace: Strip leading and trailing whitespace (refresh, password reset, OTP and device codes)
+//   - FoldCase: Compare password reset tokens case-insensitively. Reset tokens are then generated
+//     in base32 (A-Z, 2-7), tokens generated before enabling it stop being accepted
+type TokenNormalization struct {
+	TrimSpace bool
+	FoldCase  bool
 }
 
+// Normalize applies the configured clean-up to token. Case folding is left to the
+// services whose tokens support it (see FoldCase).
+func (tn TokenNormalization) Normalize(token string) string {
+	if tn.TrimSpace {
+		token = strings.TrimSpace(token)
+	}
+	return token
+}
+
+// PasswordResetPolicy selects how a password reset request is handled when the
+// user already has an unexpired reset token (e.g. they clicked "resend email").
+type PasswordResetPolicy string
+
+const (
+	// PasswordResetPolicyReplace revokes the previous token and issues a new one (default).
+	PasswordResetPolicyReplace PasswordResetPolicy = "replace"
+	// PasswordResetPolicyReuse returns the existing token, keeping its expiration,
+	// so that every email sent remains valid.
+	PasswordResetPolicyReuse PasswordResetPolicy = "reuse"
+	// PasswordResetPolicyExtend returns the existing token with a fresh TTL.
+	PasswordResetPolicyExtend PasswordResetPolicy = "extend"
+)
+
+// RefreshTokenStorage selects how refresh tokens are stored in Redis. Moving an
+// existing deployment to hashed storage goes through RefreshTokenStorageTransition
+// while RefreshTokenService.MigrateRefreshTokenStorage rewrites the stored tokens,
+// then RefreshTokenStorageHashed once the migration completed.
+type RefreshTokenStorage string
+
+const (
+	// RefreshTokenStoragePlaintext stores the token in the key: "refresh:{userID}:{token}" (default).
+	RefreshTokenStoragePlaintext RefreshTokenStorage = "plaintext"
+	// RefreshTokenStorageTransition stores new tokens hashed and still accepts plaintext ones.
+	RefreshTokenStorageTransition RefreshTokenStorage = "transition"
+	// RefreshTokenStorageHashed stores and accepts hashed tokens only: "refresh:{userID}:sha256.{hex}".
+	RefreshTokenStorageHashed RefreshTokenStorage = "hashed"
+)
+
 // NewConfig creates a new configuration instance with default TTL values.
 // If any TTL parameter is nil, a sensible default is applied.
 //
@@ -96,3 +270,47 @@ func NewConfig(issuer, jwtSecret, jwtExpiry, redisAddr, redisPwd, otpSecret stri
 		OTPTTL:           otpTTL,
 	}
 }
+
+// Clone returns a deep copy of the configuration. Services keep a clone of the
+// configuration they are created with, so that changing it afterwards does not
+// race with requests in progress.
+//
+// Returns:
+//   - *Config: Independent copy (nil if c is nil)
+//
+// Example:
+//
+//	refreshService, _ := service.NewRefreshTokenService(ctx, redisClient, config)
+//	config.TokenPrefix = true // no effect on refreshService
+func (c *Config) Clone() *Config {
+	if c == nil {
+		return nil
+	}
+
+	clone := *c
+	clone.AccessTokenAllowedClaims = slices.Clone(c.AccessTokenAllowedClaims)
+	clone.RefreshTokenTTL = cloneString(c.RefreshTokenTTL)
+	clone.PasswordResetTTL = cloneString(c.PasswordResetTTL)
+	clone.OTPTTL = cloneString(c.OTPTTL)
+	clone.LoginAttemptWindow = cloneString(c.LoginAttemptWindow)
+	clone.LoginLockoutDuration = cloneString(c.LoginLockoutDuration)
+	clone.DeviceCodeTTL = cloneString(c.DeviceCodeTTL)
+	clone.DeviceCodePollInterval = cloneString(c.DeviceCodePollInterval)
+	clone.NonceTTL = cloneString(c.NonceTTL)
+	clone.EmailChangeTTL = cloneString(c.EmailChangeTTL)
+	clone.AccountDeletionGracePeriod = cloneString(c.AccountDeletionGracePeriod)
+	clone.ShareLinkTTL = cloneString(c.ShareLinkTTL)
+	clone.RedisConnMaxIdleTime = cloneString(c.RedisConnMaxIdleTime)
+	clone.RedisDialTimeout = cloneString(c.RedisDialTimeout)
+	clone.RedisReadTimeout = cloneString(c.RedisReadTimeout)
+	clone.RedisWriteTimeout = cloneString(c.RedisWriteTimeout)
+	return &clone
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	value := *s
+	return &value
+}
@@ -0,0 +1,283 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// distributedLockPrefix is the Redis key prefix of the locks.
+	// Key patterns: "[{keyPrefix}:]lock:{name}" with the owner value, "[{keyPrefix}:]lock:{name}:fence"
+	// with the last fencing token.
+	distributedLockPrefix string = "lock"
+
+	// distributedLockValueLength is the character length of the random owner values.
+	distributedLockValueLength int = 32
+)
+
+var (
+	// ErrLockNotAcquired is returned when the lock is held by another owner.
+	ErrLockNotAcquired = errors.New("lock not acquired")
+
+	// ErrLockLost is returned when a lease expired or was taken over before being refreshed.
+	ErrLockLost = errors.New("lock lost")
+)
+
+// acquireLockScript sets the lock if it is free and returns the next fencing token, 0 if held.
+var acquireLockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// refreshLockScript extends the lock if it is still owned by ARGV[1].
+var refreshLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript deletes the lock if it is still owned by ARGV[1].
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// LockOption configures a DistributedLock or a LeaderElection.
+type LockOption func(*lockOptions)
+
+// lockOptions holds the values set by the lock options.
+type lockOptions struct {
+	keyPrefix string
+}
+
+// WithLockKeyPrefix namespaces the lock keys ("{prefix}:lock:{name}"), like
+// service.WithKeyPrefix does for the services. Give both the same prefix when the
+// Redis user is restricted to it (service.RedisACLSetUser).
+func WithLockKeyPrefix(prefix string) LockOption {
+	return func(o *lockOptions) {
+		o.keyPrefix = prefix
+	}
+}
+
+// DistributedLock is a Redis lock (SET NX PX) letting a single application replica
+// run a job at a time, e.g. a cleanup job started on every replica by a timer.
+//
+// Every acquisition gets a fencing token, increasing with each acquisition of the
+// lock. A lease can expire while its owner is paused (GC, network), letting another
+// replica in: systems written by the job should reject writes carrying a token
+// lower than the last one they saw.
+//
+// Redis key patterns (prefixed with "{prefix}:" by WithLockKeyPrefix):
+//   - Lock: "lock:{name}" → random owner value, expiring after the lock TTL
+//   - Fencing: "lock:{name}:fence" → last fencing token, no TTL
+type DistributedLock struct {
+	db       *redis.Client
+	key      string
+	fenceKey string
+	ttl      time.Duration
+}
+
+// Lease is an acquired lock.
+//
+// Fields:
+//   - Fence: Fencing token of the acquisition
+type Lease struct {
+	lock  *DistributedLock
+	value string
+	Fence int64
+}
+
+// NewDistributedLock creates a lock named name, held for ttl unless refreshed.
+// Returns an error if the database client is nil, the name is empty or the TTL is below a millisecond.
+//
+// Parameters:
+//   - db: Redis client shared by the replicas
+//   - name: Lock name, the same on every replica (e.g. "cleanup")
+//   - ttl: Lease duration, longer than the pauses the owner may suffer
+//   - opts: Optional settings (WithLockKeyPrefix)
+//
+// Returns:
+//   - *DistributedLock: Initialized lock
+//   - error: Validation errors
+//
+// Example:
+//
+//	lock, err := lib.NewDistributedLock(redisClient, "cleanup", 30*time.Second, lib.WithLockKeyPrefix("myapp"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewDistributedLock(db *redis.Client, name string, ttl time.Duration, opts ...LockOption) (*DistributedLock, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if name == "" {
+		return nil, errors.New("lock name is empty")
+	}
+	if ttl < time.Millisecond {
+		return nil, errors.New("lock ttl must be at least 1ms")
+	}
+
+	var options lockOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	key := fmt.Sprintf("%s:%s", distributedLockPrefix, name)
+	if options.keyPrefix != "" {
+		key = fmt.Sprintf("%s:%s", options.keyPrefix, key)
+	}
+	return &DistributedLock{db: db, key: key, fenceKey: key + ":fence", ttl: ttl}, nil
+}
+
+// Acquire takes the lock without waiting.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - *Lease: The lease, to refresh before the TTL and release when done
+//   - error: ErrLockNotAcquired if another owner holds the lock, or storage errors
+func (dl *DistributedLock) Acquire(ctx context.Context) (*Lease, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, err := GenerateRandomString(distributedLockValueLength)
+	if err != nil {
+		return nil, err
+	}
+
+	fence, err := acquireLockScript.Run(ctx, dl.db, []string{dl.key, dl.fenceKey}, value, dl.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if fence == 0 {
+		return nil, ErrLockNotAcquired
+	}
+
+	return &Lease{lock: dl, value: value, Fence: fence}, nil
+}
+
+// Run acquires the lock and calls fn while holding it, refreshing the lease every
+// third of the TTL. If the lease is lost or cannot be refreshed, the context given
+// to fn is cancelled.
+// The lock is released when fn returns.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), also given to fn
+//   - fn: The job, receiving the fencing token of the lease
+//
+// Returns:
+//   - error: ErrLockNotAcquired if another owner holds the lock, ErrLockLost if the lease
+//     was lost while fn ran, otherwise the error of fn
+//
+// Example:
+//
+//	// On every replica, every hour
+//	err := lock.Run(ctx, func(ctx context.Context, fence int64) error {
+//	    _, err := refreshService.RevokeAllRefreshTokensInBatches(ctx, service.CleanupOptions{})
+//	    return err
+//	})
+//	if errors.Is(err, lib.ErrLockNotAcquired) {
+//	    return nil // Another replica is on it
+//	}
+func (dl *DistributedLock) Run(ctx context.Context, fn func(ctx context.Context, fence int64) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	lease, err := dl.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	return lease.hold(ctx, fn)
+}
+
+// hold calls fn while refreshing the lease, then releases it.
+func (l *Lease) hold(ctx context.Context, fn func(ctx context.Context, fence int64) error) error {
+	defer func() {
+		_ = l.Release(context.WithoutCancel(ctx))
+	}()
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+
+		ticker := time.NewTicker(l.lock.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Refresh(jobCtx); err != nil {
+					cancel(ErrLockLost)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(jobCtx, l.Fence)
+	close(done)
+	<-refreshed
+
+	if errors.Is(context.Cause(jobCtx), ErrLockLost) {
+		return errors.Join(ErrLockLost, err)
+	}
+	return err
+}
+
+// Refresh extends the lease by the lock TTL.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: ErrLockLost if the lease expired or another owner took the lock, or storage errors
+func (l *Lease) Refresh(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	refreshed, err := refreshLockScript.Run(ctx, l.lock.db, []string{l.lock.key}, l.value, l.lock.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if refreshed == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release frees the lock if the lease still holds it. Releasing a lost lease is not an error.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors
+func (l *Lease) Release(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return releaseLockScript.Run(ctx, l.lock.db, []string{l.lock.key}, l.value).Err()
+}
@@ -0,0 +1,36 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// TokenFingerprintLength is the number of hexadecimal characters returned by TokenFingerprint.
+const TokenFingerprintLength int = 16
+
+// TokenFingerprint returns a stable, non-reversible fingerprint of a token: the first
+// 16 hexadecimal characters (64 bits) of its SHA-256 hash. Log the fingerprint instead
+// of the token to correlate a token across logs, audit events and support tickets
+// without storing its value.
+//
+// The fingerprint is a prefix of the "token_hash" of the revocation audit events and
+// of the hashes listed by revocation lists. It identifies a token, it does not
+// authenticate it: never accept a fingerprint in place of a token.
+//
+// Parameters:
+//   - token: Any token (refresh token, reset token, JWT, ...)
+//
+// Returns:
+//   - string: 16 lowercase hexadecimal characters, empty for an empty token
+//
+// Example:
+//
+//	slog.InfoContext(ctx, "refresh rejected", "token", lib.TokenFingerprint(refreshToken))
+//	// refresh rejected token=9f86d081884c7d65
+func TokenFingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:TokenFingerprintLength/2])
+}
@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// hotpModulo holds 10^digits for the supported HOTP code lengths.
+var hotpModulo = map[int]uint32{
+	6: 1_000_000,
+	7: 10_000_000,
+	8: 100_000_000,
+}
+
+// GenerateHOTP computes the HOTP code (RFC 4226) of a secret for a counter value:
+// HMAC-SHA1 of the counter, dynamically truncated to digits decimal digits.
+//
+// Parameters:
+//   - secret: Shared secret of the token (raw bytes, not base32)
+//   - counter: Moving factor
+//   - digits: Code length, 6 to 8
+//
+// Returns:
+//   - string: The code, zero-padded to digits
+//   - error: Validation errors
+//
+// Example:
+//
+//	code, err := lib.GenerateHOTP([]byte("12345678901234567890"), 0, 6) // "755224"
+func GenerateHOTP(secret []byte, counter uint64, digits int) (string, error) {
+	modulo, ok := hotpModulo[digits]
+	if !ok {
+		return "", fmt.Errorf("invalid hotp digits: %d", digits)
+	}
+	if len(secret) == 0 {
+		return "", errors.New("hotp secret is empty")
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	binCode := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, binCode%modulo), nil
+}
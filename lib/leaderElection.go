@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderElection elects one instance of a deployment to run periodic maintenance
+// (cleanup, deletion jobs, reports). It is built on a DistributedLock: the leader
+// holds the lock and refreshes it, the other instances try to take it regularly.
+//
+// Takeover:
+//   - A leader stopping gracefully (context cancelled) releases the lock, another
+//     instance takes over at its next attempt
+//   - A leader dying keeps the lock until its TTL elapses, so another instance takes
+//     over within TTL + retry interval
+//   - A leader failing to refresh the lock (network partition) steps down: the context
+//     given to its job is cancelled
+//
+// Every term gets a fencing token (see DistributedLock), higher than the previous terms.
+type LeaderElection struct {
+	lock   *DistributedLock
+	retry  time.Duration
+	leader atomic.Bool
+}
+
+// NewLeaderElection creates an election named name. Candidates try to become leader
+// every retry interval, the leader holds the lock for ttl between refreshes.
+// Returns an error if the database client is nil, the name is empty or the TTL is below a millisecond.
+//
+// Parameters:
+//   - db: Redis client shared by the instances
+//   - name: Election name, the same on every instance (e.g. "maintenance")
+//   - ttl: Lease duration of the leader, bounding the takeover delay when it dies
+//   - retry: Time between two attempts of a candidate (default: ttl / 2 when not positive)
+//   - opts: Optional settings of the lock (WithLockKeyPrefix)
+//
+// Returns:
+//   - *LeaderElection: Initialized election, joined with Run
+//   - error: Validation errors
+//
+// Example:
+//
+//	election, err := lib.NewLeaderElection(redisClient, "maintenance", 15*time.Second, 0)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewLeaderElection(db *redis.Client, name string, ttl time.Duration, retry time.Duration, opts ...LockOption) (*LeaderElection, error) {
+	if name == "" {
+		return nil, errors.New("election name is empty")
+	}
+
+	lock, err := NewDistributedLock(db, "leader:"+name, ttl, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if retry <= 0 {
+		retry = ttl / 2
+	}
+
+	return &LeaderElection{lock: lock, retry: retry}, nil
+}
+
+// Run takes part in the election until ctx is done. Each time this instance becomes
+// leader, lead is called with a context cancelled when the leadership is lost, and the
+// term (fencing token). lead should run until its context is done, e.g. a ticker loop;
+// when it returns, the instance steps down and becomes a candidate again.
+// Run blocks: run it in its own goroutine.
+//
+// Parameters:
+//   - ctx: Leaves the election when done (the leader steps down and releases the lock)
+//   - lead: The maintenance loop run by the leader
+//
+// Returns:
+//   - error: The context error once ctx is done
+//
+// Example:
+//
+//	go election.Run(ctx, func(ctx context.Context, term int64) {
+//	    ticker := time.NewTicker(time.Hour)
+//	    defer ticker.Stop()
+//	    for {
+//	        runMaintenance(ctx)
+//	        select {
+//	        case <-ctx.Done():
+//	            return
+//	        case <-ticker.C:
+//	        }
+//	    }
+//	})
+func (le *LeaderElection) Run(ctx context.Context, lead func(ctx context.Context, term int64)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if lead == nil {
+		return errors.New("lead is nil")
+	}
+
+	ticker := time.NewTicker(le.retry)
+	defer ticker.Stop()
+	for {
+		// Storage errors and lost leases are retried: the election must outlive Redis outages
+		if lease, err := le.lock.Acquire(ctx); err == nil {
+			le.leader.Store(true)
+			_ = lease.hold(ctx, func(ctx context.Context, term int64) error {
+				lead(ctx, term)
+				return nil
+			})
+			le.leader.Store(false)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsLeader reports whether this instance currently leads.
+func (le *LeaderElection) IsLeader() bool {
+	return le.leader.Load()
+}
@@ -54,3 +54,51 @@ func GenerateOTP() (string, error) {
 	}
 	return fmt.Sprintf("%06d", otp.Int64()), nil
 }
+
+// GenerateBase32String creates a cryptographically secure random string of the
+// specified length using the RFC 4648 base32 alphabet (A-Z, 2-7). Such strings
+// survive case changes, see TokenNormalization.FoldCase.
+//
+// Parameters:
+//   - n: The desired length of the generated string
+//
+// Returns:
+//   - string: A randomly generated string of length n
+//   - error: An error if random number generation fails
+func GenerateBase32String(n int) (string, error) {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	ret := make([]byte, n)
+	for i := 0; i < n; i++ {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", err
+		}
+		ret[i] = letters[num.Int64()]
+	}
+
+	return string(ret), nil
+}
+
+// userCodeCharset is the RFC 8628 recommended alphabet for user codes:
+// uppercase consonants only, avoiding vowels (no accidental words) and
+// ambiguous characters such as 0/O or 1/I.
+const userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+// GenerateUserCode creates a random 8 characters user code formatted as "XXXX-XXXX",
+// short enough to be typed by hand on a second device (RFC 8628 device flow).
+//
+// Returns:
+//   - string: A user code such as "WDJB-MJHT"
+//   - error: An error if random number generation fails
+func GenerateUserCode() (string, error) {
+	ret := make([]byte, 8)
+	for i := range ret {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeCharset))))
+		if err != nil {
+			return "", err
+		}
+		ret[i] = userCodeCharset[num.Int64()]
+	}
+
+	return fmt.Sprintf("%s-%s", ret[:4], ret[4:]), nil
+}
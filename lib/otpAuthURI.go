@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HOTPKey describes an HOTP key to enroll in an authenticator app.
+//
+// Fields:
+//   - Issuer: Name of the application, shown by the authenticator (e.g. "Acme")
+//   - AccountName: Account of the user, shown by the authenticator (e.g. "alice@example.com")
+//   - Secret: Shared secret of the key (raw bytes, 20 recommended)
+//   - Counter: Initial counter value, see HOTPService.SetCounter
+//   - Digits: Code length, 6 to 8 (default: 6)
+type HOTPKey struct {
+	Issuer      string
+	AccountName string
+	Secret      []byte
+	Counter     uint64
+	Digits      int
+}
+
+// URI returns the otpauth:// key URI of the key, the format read by authenticator apps
+// from QR codes: "otpauth://hotp/Acme:alice@example.com?secret=...&issuer=Acme&counter=0&digits=6".
+//
+// Returns:
+//   - string: The key URI, containing the secret: never log it
+//   - error: Validation errors
+//
+// Example:
+//
+//	uri, err := lib.HOTPKey{Issuer: "Acme", AccountName: user.Email, Secret: secret}.URI()
+func (k HOTPKey) URI() (string, error) {
+	if len(k.Secret) == 0 {
+		return "", errors.New("hotp secret is empty")
+	}
+	if k.AccountName == "" {
+		return "", errors.New("account name is empty")
+	}
+	if strings.Contains(k.Issuer, ":") || strings.Contains(k.AccountName, ":") {
+		return "", errors.New("issuer and account name must not contain ':'")
+	}
+	digits := k.Digits
+	if digits == 0 {
+		digits = 6
+	}
+	if _, ok := hotpModulo[digits]; !ok {
+		return "", fmt.Errorf("invalid hotp digits: %d", digits)
+	}
+
+	label := k.AccountName
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.AccountName
+		query.Set("issuer", k.Issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("counter", strconv.FormatUint(k.Counter, 10))
+	query.Set("digits", strconv.Itoa(digits))
+
+	// Spaces as %20: some authenticators show "+" literally
+	u := url.URL{Scheme: "otpauth", Host: "hotp", Path: "/" + label, RawQuery: strings.ReplaceAll(query.Encode(), "+", "%20")}
+	return u.String(), nil
+}
+
+// QRCodeEncoder renders content as a QR code image. Implement it with the QR code
+// library of the application; the module does not depend on one.
+//
+// Example:
+//
+//	// github.com/skip2/go-qrcode
+//	type pngQRCode struct{}
+//
+//	func (pngQRCode) EncodeQRCode(content string) ([]byte, string, error) {
+//	    image, err := qrcode.Encode(content, qrcode.Medium, 256)
+//	    return image, "image/png", err
+//	}
+type QRCodeEncoder interface {
+	// EncodeQRCode returns the image and its content type (e.g. "image/png", "image/svg+xml").
+	EncodeQRCode(content string) ([]byte, string, error)
+}
+
+// ProvisioningImage is a QR code of a key URI, ready to be returned by an enrollment endpoint.
+//
+// Fields:
+//   - URI: The key URI, for users entering it by hand or opening it on the same device
+//   - Image: The QR code image
+//   - ContentType: Content type of Image (e.g. "image/png")
+type ProvisioningImage struct {
+	URI         string
+	Image       []byte
+	ContentType string
+}
+
+// NewProvisioningImage renders a key URI as a QR code with encoder.
+//
+// Parameters:
+//   - uri: Key URI, e.g. from HOTPKey.URI
+//   - encoder: QR code encoder of the application
+//
+// Returns:
+//   - *ProvisioningImage: The URI and its QR code
+//   - error: Validation or encoding errors
+//
+// Example:
+//
+//	uri, err := lib.HOTPKey{Issuer: "Acme", AccountName: user.Email, Secret: secret}.URI()
+//	image, err := lib.NewProvisioningImage(uri, pngQRCode{})
+//	json.NewEncoder(w).Encode(map[string]string{"qr_code": image.DataURL()})
+func NewProvisioningImage(uri string, encoder QRCodeEncoder) (*ProvisioningImage, error) {
+	if uri == "" {
+		return nil, errors.New("uri is empty")
+	}
+	if encoder == nil {
+		return nil, errors.New("qr code encoder is nil")
+	}
+
+	image, contentType, err := encoder.EncodeQRCode(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	if len(image) == 0 || contentType == "" {
+		return nil, errors.New("qr code encoder returned no image")
+	}
+
+	return &ProvisioningImage{
+		URI:         uri,
+		Image:       image,
+		ContentType: contentType,
+	}, nil
+}
+
+// DataURL returns the image as a data URL, usable as the src of an <img> element.
+func (p *ProvisioningImage) DataURL() string {
+	return "data:" + p.ContentType + ";base64," + base64.StdEncoding.EncodeToString(p.Image)
+}
@@ -6,6 +6,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordHashCost is the bcrypt cost factor applied to every new hash.
+// Stored hashes generated with a lower cost are reported by NeedsRehash.
+const passwordHashCost int = 14
+
 // PasswordHash provides secure password hashing and verification functionality
 // using bcrypt algorithm with a cost factor of 14 for optimal security.
 type PasswordHash struct {
@@ -18,6 +22,25 @@ type PasswordHashInterface interface {
 	CheckHash(password, hash string) bool
 }
 
+// LegacyVerifier checks a password against a hash produced by a legacy algorithm
+// (e.g. salted SHA-1 or argon2 hashes imported from another system), see
+// PasswordHash.CheckAndUpgradeLegacy.
+type LegacyVerifier func(password, hash string) bool
+
+// RehashChecker is implemented by the hashers able to tell outdated hashes, such as
+// PasswordHash. It is kept apart from PasswordHashInterface so that existing hashers
+// remain valid; callers detect it with a type assertion.
+//
+// Example:
+//
+//	if checker, ok := hasher.(lib.RehashChecker); ok {
+//	    ok, upgraded, err = checker.CheckAndUpgrade(password, user.PasswordHash)
+//	}
+type RehashChecker interface {
+	NeedsRehash(hash string) bool
+	CheckAndUpgrade(password, hash string) (bool, string, error)
+}
+
 // NewPasswordHash creates a new password hasher instance.
 // The hasher uses bcrypt with a cost factor of 14, providing strong
 // protection against brute-force attacks while maintaining reasonable
@@ -36,7 +59,7 @@ func (ph *PasswordHash) Hash(password string) (string, error) {
 	if len(password) == 0 {
 		return "", fmt.Errorf("empty password")
 	}
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
 	return string(bytes), err
 }
 
@@ -53,3 +76,86 @@ func (ph *PasswordHash) CheckHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil // Return true when no errors
 }
+
+// NeedsRehash reports whether a stored hash should be regenerated with the
+// current hashing parameters. A hash needs rehashing when it was produced with
+// a bcrypt cost lower than 14, or when it is not a bcrypt hash at all
+// (e.g. a legacy algorithm being migrated away from).
+//
+// Returns true if the hash is outdated or unrecognized.
+func (ph *PasswordHash) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true // Unknown format, cannot be trusted as up to date
+	}
+	return cost < passwordHashCost
+}
+
+// CheckAndUpgrade verifies the password against the stored bcrypt hash and, when
+// the password matches but the hash was produced with a lower cost, computes a
+// replacement hash with the current parameters. Applications persist the returned
+// hash to migrate users transparently at their next login. Hashes of other
+// algorithms never match: use CheckAndUpgradeLegacy to migrate them.
+//
+// Returns:
+//   - bool: true if the password matches the stored hash
+//   - string: the upgraded hash, empty when no upgrade is required or the password does not match
+//   - error: an error if generating the upgraded hash fails
+//
+// Example:
+//
+//	ok, upgraded, err := hasher.CheckAndUpgrade(password, user.PasswordHash)
+//	if err != nil || !ok {
+//	    return errors.New("invalid credentials")
+//	}
+//	if upgraded != "" {
+//	    user.PasswordHash = upgraded // Persist the new hash
+//	}
+func (ph *PasswordHash) CheckAndUpgrade(password, hash string) (bool, string, error) {
+	return ph.CheckAndUpgradeLegacy(password, hash, nil)
+}
+
+// CheckAndUpgradeLegacy verifies the password like CheckAndUpgrade, checking the
+// hashes that are not bcrypt hashes with legacy. When the password matches a legacy
+// hash, a bcrypt hash is returned to replace it.
+//
+// Parameters:
+//   - password: The password presented by the user
+//   - hash: The stored hash, bcrypt or legacy
+//   - legacy: Verifier of the legacy hashes, nil to reject them
+//
+// Returns:
+//   - bool: true if the password matches the stored hash
+//   - string: the upgraded hash, empty when no upgrade is required or the password does not match
+//   - error: an error if generating the upgraded hash fails
+//
+// Example:
+//
+//	ok, upgraded, err := hasher.CheckAndUpgradeLegacy(password, user.PasswordHash, func(password, hash string) bool {
+//	    return subtle.ConstantTimeCompare([]byte(hash), []byte(sha1Hex(user.Salt+password))) == 1
+//	})
+//	if upgraded != "" {
+//	    user.PasswordHash = upgraded // bcrypt from now on
+//	}
+func (ph *PasswordHash) CheckAndUpgradeLegacy(password, hash string, legacy LegacyVerifier) (bool, string, error) {
+	if len(password) == 0 || len(hash) == 0 {
+		return false, "", nil
+	}
+
+	if _, err := bcrypt.Cost([]byte(hash)); err == nil {
+		if !ph.CheckHash(password, hash) {
+			return false, "", nil
+		}
+		if !ph.NeedsRehash(hash) {
+			return true, "", nil
+		}
+	} else if legacy == nil || !legacy(password, hash) {
+		return false, "", nil
+	}
+
+	upgraded, err := ph.Hash(password)
+	if err != nil {
+		return true, "", err
+	}
+	return true, upgraded, nil
+}
@@ -0,0 +1,161 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Query parameters of the password reset links.
+const (
+	PasswordResetTokenParam string = "token"
+	PasswordResetUserParam  string = "user"
+)
+
+// passwordResetLinkScope is the SignURL scope of the password reset links.
+const passwordResetLinkScope string = "password_reset"
+
+// PasswordResetLink holds the values carried by a password reset link.
+//
+// Fields:
+//   - Token: The password reset token
+//   - UserID: The signed user hint, empty for links built without one
+type PasswordResetLink struct {
+	Token  string
+	UserID string
+}
+
+// PasswordResetLinkBuilder builds the password reset links sent to users and parses
+// them back, so that the emails, the front-end and the back-end share the URL format:
+// "{baseURL}?token={token}", or with a user hint
+// "{baseURL}?token={token}&user={userID}&expires=...&scope=password_reset&signature=...".
+//
+// The user hint tells the back-end whose token it is (VerifyPasswordResetToken needs
+// the user); it is signed with SignURL, so that a link edited to name another user is
+// rejected. It is not encrypted: use an opaque user identifier.
+type PasswordResetLinkBuilder struct {
+	baseURL *url.URL
+	secret  string
+}
+
+// NewPasswordResetLinkBuilder creates a link builder.
+//
+// Parameters:
+//   - baseURL: Absolute URL of the reset page of the front-end, may have a query (e.g. "?lang=fr")
+//   - secret: HMAC key signing the user hints, shared by the servers parsing the links
+//     (empty to build links without user hint)
+//
+// Returns:
+//   - *PasswordResetLinkBuilder: The builder
+//   - error: Validation or URL parsing errors
+//
+// Example:
+//
+//	links, err := lib.NewPasswordResetLinkBuilder("https://app.example.com/reset-password", os.Getenv("RESET_LINK_SECRET"))
+func NewPasswordResetLinkBuilder(baseURL string, secret string) (*PasswordResetLinkBuilder, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if !u.IsAbs() {
+		return nil, errors.New("URL must be absolute")
+	}
+	u.Fragment = ""
+
+	return &PasswordResetLinkBuilder{
+		baseURL: u,
+		secret:  secret,
+	}, nil
+}
+
+// Build returns the reset link of a token.
+//
+// Parameters:
+//   - token: The password reset token
+//   - userID: User hint, signed into the link (empty for none)
+//   - ttl: Lifetime of the signed user hint, e.g. Config.PasswordResetTTL (ignored without user hint)
+//
+// Returns:
+//   - string: The link
+//   - error: Validation errors, or a user hint without signing secret
+//
+// Example:
+//
+//	token, err := resetService.CreatePasswordResetToken(ctx, user.ID)
+//	link, err := links.Build(*token, user.ID, time.Hour)
+//	// https://app.example.com/reset-password?expires=...&scope=password_reset&signature=...&token=...&user=42
+func (b *PasswordResetLinkBuilder) Build(token string, userID string, ttl time.Duration) (string, error) {
+	if token == "" {
+		return "", errors.New("password reset token is empty")
+	}
+
+	u := *b.baseURL
+	query := u.Query()
+	query.Set(PasswordResetTokenParam, token)
+	if userID == "" {
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	if b.secret == "" {
+		return "", errors.New("a signing secret is required for user hints")
+	}
+	query.Set(PasswordResetUserParam, userID)
+	u.RawQuery = query.Encode()
+	return SignURL(b.secret, u.String(), ttl, passwordResetLinkScope)
+}
+
+// Parse extracts the token and the user hint of a link built by Build, checking the
+// signature of the user hint.
+//
+// Parameters:
+//   - link: The full link, or its query as received by the front-end ("?token=...&user=...")
+//
+// Returns:
+//   - PasswordResetLink: The token and the user hint
+//   - error: ErrSignedURLInvalid for missing tokens and tampered hints, ErrSignedURLExpired
+//     for expired hints (errors.Is)
+//
+// Example:
+//
+//	link, err := links.Parse(r.URL.RawQuery)
+//	if err != nil {
+//	    w.WriteHeader(http.StatusBadRequest)
+//	    return
+//	}
+//	valid, err := resetService.VerifyPasswordResetToken(ctx, link.UserID, link.Token)
+func (b *PasswordResetLinkBuilder) Parse(link string) (PasswordResetLink, error) {
+	u := new(url.URL)
+	if strings.Contains(link, "://") {
+		var err error
+		if u, err = url.Parse(link); err != nil {
+			return PasswordResetLink{}, fmt.Errorf("%w: %v", ErrSignedURLInvalid, err)
+		}
+	} else {
+		// A query alone: user hints were signed on the base URL
+		*u = *b.baseURL
+		u.RawQuery = strings.TrimPrefix(link, "?")
+	}
+
+	query := u.Query()
+	parsed := PasswordResetLink{
+		Token:  query.Get(PasswordResetTokenParam),
+		UserID: query.Get(PasswordResetUserParam),
+	}
+	if parsed.Token == "" {
+		return PasswordResetLink{}, fmt.Errorf("%w: missing token", ErrSignedURLInvalid)
+	}
+	if parsed.UserID == "" && query.Get(SignedURLSignatureParam) == "" {
+		return parsed, nil
+	}
+
+	if b.secret == "" {
+		return PasswordResetLink{}, fmt.Errorf("%w: user hint without signing secret", ErrSignedURLInvalid)
+	}
+	if err := VerifySignedURL(b.secret, u.String(), passwordResetLinkScope); err != nil {
+		return PasswordResetLink{}, err
+	}
+	return parsed, nil
+}
@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+)
+
+// PseudonymizeUserID returns the keyed hash (HMAC-SHA256, hex-encoded) of a user
+// identifier. The same key always gives the same pseudonym, so events of a user can be
+// correlated and looked up (by pseudonymizing the identifier searched for), but the
+// identifier cannot be recovered without the key.
+//
+// Parameters:
+//   - key: Secret HMAC key of the deployment, at least 32 random bytes recommended
+//   - userID: User identifier, an empty identifier stays empty
+//
+// Returns:
+//   - string: 64 lowercase hexadecimal characters
+//
+// Example:
+//
+//	pseudonym := lib.PseudonymizeUserID(auditKey, "123")
+//	events := siem.Search("user_id", pseudonym)
+func PseudonymizeUserID(key []byte, userID string) string {
+	if userID == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pseudonymizingAuditLogger replaces the user identifiers of the events before
+// passing them to the next logger.
+type pseudonymizingAuditLogger struct {
+	next       AuditLogger
+	key        []byte
+	detailKeys []string
+}
+
+// NewPseudonymizingAuditLogger wraps an audit logger so that it only receives
+// pseudonymized user identifiers (see PseudonymizeUserID): the UserID of the events and
+// their "actor_id" detail, plus the given detail keys. Give the wrapped logger to every
+// service (WithLogger or SetAuditLogger) so that telemetry pipelines and webhooks never
+// receive raw user identifiers. Request metadata (IP, user agent) is left unchanged.
+//
+// Parameters:
+//   - next: Logger receiving the pseudonymized events
+//   - key: Secret HMAC key of the deployment, must not be empty
+//   - detailKeys: Additional details holding user identifiers
+//
+// Returns:
+//   - AuditLogger: The wrapping logger
+//   - error: Validation errors
+//
+// Example:
+//
+//	auditLogger := lib.AuditLogger(siemLogger)
+//	if key := os.Getenv("AUDIT_PSEUDONYMIZATION_KEY"); key != "" {
+//	    auditLogger, err = lib.NewPseudonymizingAuditLogger(siemLogger, []byte(key))
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//	refreshService, err := service.NewRefreshTokenService(ctx, redisClient, config, service.WithLogger(auditLogger))
+func NewPseudonymizingAuditLogger(next AuditLogger, key []byte, detailKeys ...string) (AuditLogger, error) {
+	if next == nil {
+		return nil, errors.New("audit logger is nil")
+	}
+	if len(key) == 0 {
+		return nil, errors.New("pseudonymization key is empty")
+	}
+
+	return &pseudonymizingAuditLogger{
+		next:       next,
+		key:        append([]byte(nil), key...),
+		detailKeys: append([]string{"actor_id"}, detailKeys...),
+	}, nil
+}
+
+// LogAuditEvent pseudonymizes the user identifiers of event and passes it on.
+// The details are copied, the map of the caller is not modified.
+func (l *pseudonymizingAuditLogger) LogAuditEvent(ctx context.Context, event AuditEvent) {
+	event.UserID = PseudonymizeUserID(l.key, event.UserID)
+	if len(event.Details) > 0 {
+		event.Details = maps.Clone(event.Details)
+		for _, detail := range l.detailKeys {
+			if value, ok := event.Details[detail]; ok {
+				event.Details[detail] = PseudonymizeUserID(l.key, value)
+			}
+		}
+	}
+	l.next.LogAuditEvent(ctx, event)
+}
@@ -3,6 +3,7 @@ package lib
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -27,7 +28,7 @@ type RedisClient struct {
 // NewRedisClient creates a new Redis client wrapper with configuration.
 //
 // Parameters:
-//   - config: Configuration containing RedisAddr, RedisPwd, and RedisDB
+//   - config: Configuration containing RedisAddr, RedisPwd, RedisDB and the optional pool settings
 //
 // Returns:
 //   - *RedisClient: Client wrapper ready for initialization
@@ -48,15 +49,16 @@ func NewRedisClient(config *Config) *RedisClient {
 //
 // Connection pooling:
 //   - Managed automatically by go-redis
-//   - Default: 10 connections per CPU
+//   - Default: 10 connections per CPU, tuned with the Redis pool settings of Config
 //   - Automatic reconnection on failure
+//   - Statistics exposed through ReportRedisPoolStats
 //
 // Parameters:
 //   - ctx: Context for initialization (uses Background if nil)
 //
 // Returns:
 //   - *redis.Client: Connected Redis client ready for use
-//   - error: Connection or authentication errors
+//   - error: Configuration, connection or authentication errors
 //
 // Example:
 //
@@ -74,16 +76,52 @@ func (rc *RedisClient) InitRedisClient(ctx context.Context) (*redis.Client, erro
 		ctx = context.Background()
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     rc.config.RedisAddr,
-		Password: rc.config.RedisPwd,
-		DB:       rc.config.RedisDB,
-	})
+	options, err := rc.options()
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(options)
 
-	_, err := rdb.Ping(ctx).Result()
+	_, err = rdb.Ping(ctx).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to ping Redis at %s: %w", rc.config.RedisAddr, err)
 	}
 
 	return rdb, nil
 }
+
+// options maps the configuration to go-redis options, zero values keeping the go-redis defaults.
+func (rc *RedisClient) options() (*redis.Options, error) {
+	options := &redis.Options{
+		Addr:         rc.config.RedisAddr,
+		Password:     rc.config.RedisPwd,
+		DB:           rc.config.RedisDB,
+		PoolSize:     rc.config.RedisPoolSize,
+		MinIdleConns: rc.config.RedisMinIdleConns,
+		MaxIdleConns: rc.config.RedisMaxIdleConns,
+		MaxRetries:   rc.config.RedisMaxRetries,
+	}
+
+	durations := []struct {
+		name  string
+		value *string
+		dest  *time.Duration
+	}{
+		{"connection max idle time", rc.config.RedisConnMaxIdleTime, &options.ConnMaxIdleTime},
+		{"dial timeout", rc.config.RedisDialTimeout, &options.DialTimeout},
+		{"read timeout", rc.config.RedisReadTimeout, &options.ReadTimeout},
+		{"write timeout", rc.config.RedisWriteTimeout, &options.WriteTimeout},
+	}
+	for _, duration := range durations {
+		if duration.value == nil {
+			continue
+		}
+		parsed, err := time.ParseDuration(*duration.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis %s format: %w", duration.name, err)
+		}
+		*duration.dest = parsed
+	}
+
+	return options, nil
+}
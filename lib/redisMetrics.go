@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPoolStats is a snapshot of the connection pool of a Redis client.
+//
+// Fields:
+//   - Hits: Times a free connection was found in the pool (cumulative)
+//   - Misses: Times a new connection had to be opened (cumulative)
+//   - Timeouts: Times no connection was available within the pool timeout (cumulative)
+//   - TotalConns: Open connections
+//   - IdleConns: Idle connections
+//   - StaleConns: Connections closed as stale (cumulative)
+type RedisPoolStats struct {
+	Hits       uint32
+	Misses     uint32
+	Timeouts   uint32
+	TotalConns uint32
+	IdleConns  uint32
+	StaleConns uint32
+}
+
+// RedisMetricsHook receives Redis pool statistics, e.g. to export them as gauges.
+// Implementations must be safe for concurrent use and should not block.
+type RedisMetricsHook interface {
+	ObserveRedisPool(stats RedisPoolStats)
+}
+
+// RedisMetricsHookFunc adapts a function to the RedisMetricsHook interface.
+//
+// Example:
+//
+//	hook := lib.RedisMetricsHookFunc(func(stats lib.RedisPoolStats) {
+//	    idleConns.Set(float64(stats.IdleConns))
+//	    poolTimeouts.Set(float64(stats.Timeouts))
+//	})
+type RedisMetricsHookFunc func(stats RedisPoolStats)
+
+// ObserveRedisPool calls f(stats).
+func (f RedisMetricsHookFunc) ObserveRedisPool(stats RedisPoolStats) {
+	f(stats)
+}
+
+// ReadRedisPoolStats returns the current pool statistics of rdb.
+func ReadRedisPoolStats(rdb *redis.Client) RedisPoolStats {
+	stats := rdb.PoolStats()
+	return RedisPoolStats{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		StaleConns: stats.StaleConns,
+	}
+}
+
+// ReportRedisPoolStats sends the pool statistics of rdb to hook immediately, then
+// every interval until ctx is done. It blocks: run it in its own goroutine.
+//
+// Parameters:
+//   - ctx: Stops the reporting when done
+//   - rdb: Redis client to observe
+//   - interval: Time between two reports (default: 15s when not positive)
+//   - hook: Receiver of the statistics
+//
+// Example:
+//
+//	go lib.ReportRedisPoolStats(ctx, redisClient, 15*time.Second, hook)
+func ReportRedisPoolStats(ctx context.Context, rdb *redis.Client, interval time.Duration, hook RedisMetricsHook) {
+	if rdb == nil || hook == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		hook.ObserveRedisPool(ReadRedisPoolStats(rdb))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"maps"
+	"sync"
+)
+
+// ReplayReason tells why a replayed token was rejected.
+type ReplayReason string
+
+const (
+	// ReplayReasonRevoked is a token presented after its revocation (logout, rotation,
+	// epoch bump...), a strong signal that it was stolen.
+	ReplayReasonRevoked ReplayReason = "revoked"
+	// ReplayReasonExpired is a token presented after its expiration.
+	ReplayReasonExpired ReplayReason = "expired"
+	// ReplayReasonConsumed is a one-time-use token presented after its first use.
+	ReplayReasonConsumed ReplayReason = "consumed"
+)
+
+// ReplayMetricsHook receives the tokens presented after their revocation,
+// expiration or single use (attempted replays), e.g. to export them as counters.
+// Implementations must be safe for concurrent use and should not block.
+type ReplayMetricsHook interface {
+	ObserveReplay(tokenType TokenType, reason ReplayReason)
+}
+
+// ReplayMetricsHookFunc adapts a function to the ReplayMetricsHook interface.
+//
+// Example:
+//
+//	hook := lib.ReplayMetricsHookFunc(func(tokenType lib.TokenType, reason lib.ReplayReason) {
+//	    replays.WithLabelValues(string(tokenType), string(reason)).Inc()
+//	})
+type ReplayMetricsHookFunc func(tokenType TokenType, reason ReplayReason)
+
+// ObserveReplay calls f(tokenType, reason).
+func (f ReplayMetricsHookFunc) ObserveReplay(tokenType TokenType, reason ReplayReason) {
+	f(tokenType, reason)
+}
+
+// ReplayStats counts the attempted replays per token type and reason in memory, for
+// applications without a metrics system. It implements ReplayMetricsHook and is safe
+// for concurrent use. The zero value is ready to use.
+//
+// Example:
+//
+//	stats := &lib.ReplayStats{}
+//	refreshService.SetReplayMetricsHook(stats)
+//	// Security review endpoint
+//	json.NewEncoder(w).Encode(stats.Snapshot()) // {"rt": {"revoked": 3}}
+type ReplayStats struct {
+	mu     sync.Mutex
+	counts map[TokenType]map[ReplayReason]int64
+}
+
+// ObserveReplay counts an attempted replay.
+func (s *ReplayStats) ObserveReplay(tokenType TokenType, reason ReplayReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[TokenType]map[ReplayReason]int64)
+	}
+	if s.counts[tokenType] == nil {
+		s.counts[tokenType] = make(map[ReplayReason]int64)
+	}
+	s.counts[tokenType][reason]++
+}
+
+// Count returns the attempted replays of a token type for a reason.
+func (s *ReplayStats) Count(tokenType TokenType, reason ReplayReason) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[tokenType][reason]
+}
+
+// Snapshot returns a copy of the counts, by token type then reason.
+func (s *ReplayStats) Snapshot() map[TokenType]map[ReplayReason]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make(map[TokenType]map[ReplayReason]int64, len(s.counts))
+	for tokenType, reasons := range s.counts {
+		snapshot[tokenType] = maps.Clone(reasons)
+	}
+	return snapshot
+}
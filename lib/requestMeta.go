@@ -0,0 +1,51 @@
+package lib
+
+import "context"
+
+// requestMetaKey is the context key under which RequestMeta is stored.
+type requestMetaKey struct{}
+
+// RequestMeta carries client information about the request being served.
+// HTTP layers attach it to the context once; services read it without every
+// method signature having to change.
+//
+// Fields:
+//   - IP: Client IP address (e.g. "203.0.113.7")
+//   - UserAgent: Client User-Agent header
+//   - Country: ISO 3166-1 alpha-2 country code resolved by a GeoIP lookup (e.g. "FR")
+//   - DeviceID: Stable device identifier, when the client provides one
+//   - Locale: BCP 47 language tag of the user (e.g. "fr-FR"), for the messages sent to them
+type RequestMeta struct {
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Country   string `json:"country,omitempty"`
+	DeviceID  string `json:"device_id,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// WithRequestMeta returns a copy of ctx carrying the request metadata.
+//
+// Example:
+//
+//	ctx := lib.WithRequestMeta(r.Context(), lib.RequestMeta{
+//	    IP:        clientIP(r),
+//	    UserAgent: r.UserAgent(),
+//	    Country:   geoip.Country(clientIP(r)),
+//	})
+//	token, err := refreshService.CreateRefreshToken(ctx, userID)
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext returns the request metadata attached to ctx.
+// The boolean is false when no metadata was attached.
+func RequestMetaFromContext(ctx context.Context) (RequestMeta, bool) {
+	if ctx == nil {
+		return RequestMeta{}, false
+	}
+	meta, ok := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta, ok
+}
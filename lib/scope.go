@@ -0,0 +1,122 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ScopeSeparator separates the segments of hierarchical scopes ("repo:read").
+	ScopeSeparator string = ":"
+	// ScopeWildcard is the segment matching any segment ("repo:*").
+	ScopeWildcard string = "*"
+)
+
+// ErrInvalidScopeSyntax is returned by ParseScope for malformed scopes.
+var ErrInvalidScopeSyntax = errors.New("invalid scope syntax")
+
+// ParseScope splits a space-separated scope string (the OAuth "scope" parameter and
+// claim) into scopes, dropping duplicates. An empty string gives no scopes.
+//
+// Scopes are made of segments separated by ':' ("repo:read"), each a non-empty run of
+// the RFC 6749 scope characters other than ':'. A "*" segment is a wildcard.
+//
+// Returns:
+//   - []string: The scopes, in their order of appearance
+//   - error: ErrInvalidScopeSyntax for malformed scopes
+//
+// Example:
+//
+//	scopes, err := lib.ParseScope("repo:* user:read")
+//	// scopes = ["repo:*", "user:read"]
+func ParseScope(scope string) ([]string, error) {
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		if err := validateScope(s); err != nil {
+			return nil, err
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// validateScope checks the syntax of a single scope.
+func validateScope(scope string) error {
+	for _, segment := range strings.Split(scope, ScopeSeparator) {
+		if segment == "" {
+			return fmt.Errorf("%w: empty segment in %q", ErrInvalidScopeSyntax, scope)
+		}
+		for _, c := range segment {
+			// RFC 6749 scope-token: %x21 / %x23-5B / %x5D-7E
+			if c < 0x21 || c > 0x7E || c == '"' || c == '\\' {
+				return fmt.Errorf("%w: invalid character in %q", ErrInvalidScopeSyntax, scope)
+			}
+		}
+		if segment != ScopeWildcard && strings.Contains(segment, ScopeWildcard) {
+			return fmt.Errorf("%w: partial wildcard in %q", ErrInvalidScopeSyntax, scope)
+		}
+	}
+	return nil
+}
+
+// ScopeMatches reports whether a granted scope implies a required one.
+//
+// Matching rules, segment by segment:
+//   - A scope implies itself and its sub-scopes: "repo" implies "repo:read"
+//   - A "*" segment matches any segment: "repo:*" implies "repo:read" and
+//     "repo:read:private", "*" implies every scope
+//   - A required wildcard is only implied by the same wildcard (or a broader one):
+//     "repo:read" does not imply "repo:*"
+//
+// Malformed scopes never match.
+//
+// Example:
+//
+//	lib.ScopeMatches("repo:*", "repo:read")  // true
+//	lib.ScopeMatches("repo:read", "repo")    // false
+func ScopeMatches(granted string, required string) bool {
+	if validateScope(granted) != nil || validateScope(required) != nil {
+		return false
+	}
+
+	grantedSegments := strings.Split(granted, ScopeSeparator)
+	requiredSegments := strings.Split(required, ScopeSeparator)
+	if len(grantedSegments) > len(requiredSegments) {
+		return false
+	}
+	for i, segment := range grantedSegments {
+		if segment != ScopeWildcard && segment != requiredSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ScopesAllow reports whether the granted scopes imply every required scope.
+//
+// Example:
+//
+//	granted, _ := lib.ParseScope(claim.Scope)
+//	if !lib.ScopesAllow(granted, "repo:read", "user:read") {
+//	    return errors.New("insufficient scope")
+//	}
+func ScopesAllow(granted []string, required ...string) bool {
+	for _, r := range required {
+		allowed := false
+		for _, g := range granted {
+			if ScopeMatches(g, r) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
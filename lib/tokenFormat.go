@@ -0,0 +1,44 @@
+package lib
+
+import "strings"
+
+// TokenType identifies the kind of a structured token, used as its prefix.
+type TokenType string
+
+const (
+	TokenTypeRefresh       TokenType = "rt"
+	TokenTypePasswordReset TokenType = "prt"
+	// TokenTypeAccess identifies access tokens in reports and metrics. JWTs are
+	// never prefixed.
+	TokenTypeAccess TokenType = "at"
+)
+
+// TokenFormatVersion is the version of the structured token format.
+const TokenFormatVersion string = "v1"
+
+// TokenPrefix returns the prefix of structured tokens of the given type,
+// e.g. "prt_v1_" for password reset tokens. Prefixed tokens are easy to
+// route (see service.TokenRouter) and to detect by secret-scanning tools.
+func TokenPrefix(tokenType TokenType) string {
+	return string(tokenType) + "_" + TokenFormatVersion + "_"
+}
+
+// ParseTokenPrefix splits the "{type}_{version}_" prefix of a structured token.
+// Random token parts never contain '_' (see GenerateRandomString).
+//
+// Returns:
+//   - TokenType: The token type (e.g. "prt")
+//   - string: The format version (e.g. "v1")
+//   - bool: false if the token has no structured prefix
+//
+// Example:
+//
+//	tokenType, version, ok := lib.ParseTokenPrefix("prt_v1_aB3...")
+//	// tokenType = "prt", version = "v1", ok = true
+func ParseTokenPrefix(token string) (TokenType, string, bool) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return TokenType(parts[0]), parts[1], true
+}
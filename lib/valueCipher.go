@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedValuePrefix marks the values encrypted by ValueCipher:
+// "enc:v1:{keyID}:{base64url(nonce|ciphertext)}".
+const encryptedValuePrefix string = "enc:v1:"
+
+// ErrUnknownEncryptionKey is returned by ValueCipher.Decrypt for values encrypted
+// with a key it does not hold.
+var ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+
+// ValueCipher encrypts values stored in Redis with AES-GCM, so that Redis persistence
+// files (RDB, AOF) and replicas do not expose them. Each value records the identifier
+// of its key: to rotate, add a new key and make it current, and remove the previous key
+// once the values it encrypted expired.
+type ValueCipher struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewValueCipher creates a cipher from a keyring.
+//
+// Parameters:
+//   - keys: AES keys (16, 24 or 32 random bytes) by identifier, identifiers must not contain ":"
+//   - current: Identifier of the key encrypting new values
+//
+// Returns:
+//   - *ValueCipher: The cipher
+//   - error: Validation errors
+//
+// Example:
+//
+//	valueCipher, err := lib.NewValueCipher(map[string][]byte{
+//	    "2026-01": oldKey, // kept until the values it encrypted expired
+//	    "2026-10": newKey,
+//	}, "2026-10")
+func NewValueCipher(keys map[string][]byte, current string) (*ValueCipher, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current encryption key %q is not in the keyring", current)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id: %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+
+	return &ValueCipher{
+		current: current,
+		keys:    aeads,
+	}, nil
+}
+
+// Encrypt encrypts value with the current key. The associated data (e.g. the user
+// identifier) must be given again to Decrypt, so that a value copied to another
+// record does not decrypt.
+func (vc *ValueCipher) Encrypt(value string, associatedData string) (string, error) {
+	aead := vc.keys[vc.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(associatedData))
+	return encryptedValuePrefix + vc.current + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt. Values without the encryption prefix,
+// stored before encryption was enabled, are returned as is.
+//
+// Returns:
+//   - string: The value
+//   - error: ErrUnknownEncryptionKey, or an error for corrupted or tampered values
+func (vc *ValueCipher) Decrypt(value string, associatedData string) (string, error) {
+	rest, encrypted := strings.CutPrefix(value, encryptedValuePrefix)
+	if !encrypted {
+		return value, nil
+	}
+
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("corrupted encrypted value")
+	}
+	aead, ok := vc.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownEncryptionKey, id)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("corrupted encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
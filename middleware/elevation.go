@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/apierror"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// ElevationHeader is the request header carrying the elevation token.
+const ElevationHeader = "X-Elevation-Token"
+
+// ElevationVerifier verifies elevation tokens, implemented by service.ElevationService.
+type ElevationVerifier interface {
+	VerifyElevation(ctx context.Context, userID string, token string, maxAge time.Duration) (*service.ElevationGrant, error)
+}
+
+// RequireRecentElevation protects sensitive routes (changing the email, deleting the
+// account...) with an elevation granted less than maxAge ago. The elevation token is
+// read from the X-Elevation-Token header and must belong to the user of the access token.
+//
+// When the request already went through RequireStepUp, its verified claims are used;
+// otherwise the access token is read from the "Authorization: Bearer" header, and
+// verified once per request below ShareVerification.
+//
+// Responses, with an RFC 7807 problem details body written by apierror.WriteProblem:
+//   - 401 with error="invalid_token" when the access token is missing, invalid or expired
+//   - 403 ELEVATION_REQUIRED when the elevation token is missing, unknown, expired or
+//     too old, so the client can ask the user to re-authenticate
+//
+// The verified claims are available to the next handler through ClaimFromContext.
+//
+// Parameters:
+//   - verifier: Access token verifier (e.g. service.AccessTokenService)
+//   - elevation: Elevation verifier (e.g. service.ElevationService)
+//   - maxAge: Maximum age of the re-authentication (0 for the grant TTL)
+//
+// Returns:
+//   - func(http.Handler) http.Handler: The middleware
+//
+// Example:
+//
+//	requireSudo := middleware.RequireRecentElevation(accessService, elevationService, 5*time.Minute)
+//	mux.Handle("DELETE /account", requireSudo(deleteAccountHandler))
+func RequireRecentElevation(verifier AccessTokenVerifier, elevation ElevationVerifier, maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claim, ok := verifiedClaim(w, r, verifier)
+			if !ok {
+				return
+			}
+
+			if _, err := elevation.VerifyElevation(r.Context(), claim.Subject, r.Header.Get(ElevationHeader), maxAge); err != nil {
+				apierror.WriteProblem(w, err, apierror.WithInstance(r.URL.Path))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimContextKey{}, claim)))
+		})
+	}
+}
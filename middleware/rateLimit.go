@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// SetRateLimitHeaders writes the rate limiting state of a user as RateLimit headers
+// (IETF draft "RateLimit header fields for HTTP"), so that clients can back off:
+//   - RateLimit-Limit: Attempts allowed within the window
+//   - RateLimit-Remaining: Attempts left
+//   - RateLimit-Reset: Seconds until the window (or the lock) expires, rounded up
+//   - Retry-After: Same as RateLimit-Reset, only when no attempt is left (RFC 9110)
+//
+// Headers must be set before the status code is written.
+//
+// Parameters:
+//   - w: Response of the handler
+//   - state: State returned by OTPService.RateLimit or LoginAttemptService.RateLimit
+//
+// Example:
+//
+//	state, err := otpService.RateLimit(ctx, userID)
+//	if err == nil {
+//	    middleware.SetRateLimitHeaders(w, state)
+//	    if state.Exhausted() {
+//	        w.WriteHeader(http.StatusTooManyRequests)
+//	        return
+//	    }
+//	}
+func SetRateLimitHeaders(w http.ResponseWriter, state service.RateLimitState) {
+	reset := strconv.FormatInt(ceilSeconds(state.Reset), 10)
+
+	header := w.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(state.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(max(state.Remaining, 0)))
+	header.Set("RateLimit-Reset", reset)
+	if state.Exhausted() && state.Reset > 0 {
+		header.Set("Retry-After", reset)
+	} else {
+		header.Del("Retry-After")
+	}
+}
+
+// ceilSeconds returns d in whole seconds, rounded up (0 for non-positive durations).
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
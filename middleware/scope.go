@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bcetienne/tools-go-token/v4/apierror"
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// ScopeRequirement is the scope policy of RequireScopeWith.
+//
+// Fields:
+//   - Scopes: The required scopes, all of them
+//   - AllowUnscoped: Accept tokens without a scope claim as unrestricted (off by default:
+//     tokens from CreateAccessToken carry no scope and are refused)
+type ScopeRequirement struct {
+	Scopes        []string
+	AllowUnscoped bool
+}
+
+// RequireScope protects routes with the scopes the access token must grant, matched
+// hierarchically (see lib.ScopeMatches): a token with "repo:*" is accepted by
+// RequireScope(verifier, "repo:read"). Tokens without a scope claim grant no scope and
+// are refused; use RequireScopeWith and AllowUnscoped to accept them.
+//
+// When the request already went through RequireStepUp, its verified claims are used;
+// otherwise the access token is read from the "Authorization: Bearer" header, and
+// verified once per request below ShareVerification.
+//
+// Responses (RFC 6750), with an RFC 7807 problem details body written by apierror.WriteProblem:
+//   - 401 with error="invalid_token" when the token is missing, invalid or expired
+//   - 403 with error="insufficient_scope" and the required scopes (INSUFFICIENT_SCOPE)
+//
+// The verified claims are available to the next handler through ClaimFromContext.
+// RequireScope panics on a malformed required scope, as it is a route definition error.
+//
+// Parameters:
+//   - verifier: Access token verifier (e.g. service.AccessTokenService)
+//   - scopes: The required scopes, all of them
+//
+// Returns:
+//   - func(http.Handler) http.Handler: The middleware
+//
+// Example:
+//
+//	mux.Handle("GET /repos", middleware.RequireScope(accessService, "repo:read")(listReposHandler))
+func RequireScope(verifier AccessTokenVerifier, scopes ...string) func(http.Handler) http.Handler {
+	return RequireScopeWith(verifier, ScopeRequirement{Scopes: scopes})
+}
+
+// RequireScopeWith is RequireScope with a scope policy, e.g. to keep accepting the
+// unscoped tokens of first-party clients while third-party clients get scoped tokens
+// through ExchangeToken.
+//
+// Parameters:
+//   - verifier: Access token verifier (e.g. service.AccessTokenService)
+//   - requirement: The required scopes and the policy for unscoped tokens
+//
+// Returns:
+//   - func(http.Handler) http.Handler: The middleware
+//
+// Example:
+//
+//	requireRead := middleware.RequireScopeWith(accessService, middleware.ScopeRequirement{
+//	    Scopes:        []string{"repo:read"},
+//	    AllowUnscoped: true,
+//	})
+func RequireScopeWith(verifier AccessTokenVerifier, requirement ScopeRequirement) func(http.Handler) http.Handler {
+	scopes := requirement.Scopes
+	for _, scope := range scopes {
+		if _, err := lib.ParseScope(scope); err != nil || len(strings.Fields(scope)) != 1 {
+			panic(fmt.Sprintf("middleware: invalid required scope %q", scope))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claim, ok := verifiedClaim(w, r, verifier)
+			if !ok {
+				return
+			}
+
+			for _, scope := range scopes {
+				if !claim.HasScope(scope) && !(requirement.AllowUnscoped && !claim.IsScoped()) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s", scope="%s"`, service.ErrInsufficientScope, strings.Join(scopes, " ")))
+					apierror.WriteProblem(w, service.ErrInsufficientScope, apierror.WithInstance(r.URL.Path))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimContextKey{}, claim)))
+		})
+	}
+}
+
+// verifiedClaim returns the claims verified by a previous middleware, or verifies the
+// bearer token of the request. When it fails, the request has been answered.
+func verifiedClaim(w http.ResponseWriter, r *http.Request, verifier AccessTokenVerifier) (*modelAuth.Claim, bool) {
+	if claim, ok := ClaimFromContext(r.Context()); ok {
+		return claim, true
+	}
+
+	claim, err := verifyBearer(r, verifier)
+	if err != nil {
+		rejectToken(w, r, err)
+		return nil, false
+	}
+	return claim, true
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bcetienne/tools-go-token/v4/apierror"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// claimContextKey is the unexported context key type for the verified claims.
+type claimContextKey struct{}
+
+// AccessTokenVerifier verifies bearer access tokens, implemented by service.AccessTokenService.
+// The middlewares pass the request context, so that the Redis lookups of the
+// verification (jti, epochs) are cancelled with the request.
+type AccessTokenVerifier interface {
+	VerifyAccessTokenContext(ctx context.Context, token string) (*modelAuth.Claim, error)
+}
+
+// ClaimFromContext returns the claims of the access token verified by RequireStepUp.
+//
+// Returns:
+//   - *modelAuth.Claim: The verified claims
+//   - bool: false if the request did not go through the middleware
+func ClaimFromContext(ctx context.Context) (*modelAuth.Claim, bool) {
+	claim, ok := ctx.Value(claimContextKey{}).(*modelAuth.Claim)
+	return claim, ok
+}
+
+// RequireStepUp protects sensitive routes with a step-up requirement.
+// The access token is read from the "Authorization: Bearer" header.
+//
+// Responses (RFC 6750 and RFC 9470), with an RFC 7807 problem details body written by
+// apierror.WriteProblem:
+//   - 401 with error="invalid_token" when the token is missing, invalid or expired
+//     (TOKEN_MISSING, TOKEN_INVALID, TOKEN_EXPIRED, TOKEN_REVOKED)
+//   - 401 with error="insufficient_user_authentication", acr_values and max_age when
+//     the token does not meet the requirement, so the client can re-authenticate
+//   - The status mapped by apierror for other verification errors (e.g. 403 CLAIM_REJECTED)
+//
+// The verified claims are available to the next handler through ClaimFromContext.
+// Below ShareVerification, the token is verified once per request.
+//
+// Parameters:
+//   - verifier: Access token verifier (e.g. service.AccessTokenService)
+//   - requirement: Minimum assurance level and maximum authentication age
+//
+// Returns:
+//   - func(http.Handler) http.Handler: The middleware
+//
+// Example:
+//
+//	requireMFA := middleware.RequireStepUp(accessService, service.StepUpRequirement{
+//	    ACR:        modelAuth.AssuranceLevel2,
+//	    MaxAuthAge: 5 * time.Minute,
+//	})
+//	mux.Handle("POST /account/email", requireMFA(changeEmailHandler))
+func RequireStepUp(verifier AccessTokenVerifier, requirement service.StepUpRequirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claim, err := verifyBearer(r, verifier)
+			if err != nil {
+				rejectToken(w, r, err)
+				return
+			}
+
+			if err := requirement.Check(claim); err != nil {
+				w.Header().Set("WWW-Authenticate", stepUpChallenge(requirement))
+				apierror.WriteProblem(w, err, apierror.WithInstance(r.URL.Path))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimContextKey{}, claim)))
+		})
+	}
+}
+
+// rejectToken answers a request whose token could not be verified, with the
+// invalid_token challenge when the error maps to 401.
+func rejectToken(w http.ResponseWriter, r *http.Request, err error) {
+	if apierror.Status(err) == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	apierror.WriteProblem(w, err, apierror.WithInstance(r.URL.Path))
+}
+
+// stepUpChallenge builds the RFC 9470 WWW-Authenticate challenge for the requirement.
+func stepUpChallenge(requirement service.StepUpRequirement) string {
+	challenge := fmt.Sprintf(`Bearer error="%s"`, service.ErrInsufficientUserAuthentication)
+	if requirement.ACR != "" {
+		challenge += fmt.Sprintf(`, acr_values="%s"`, requirement.ACR)
+	}
+	if requirement.MaxAuthAge > 0 {
+		challenge += fmt.Sprintf(`, max_age=%d`, int(requirement.MaxAuthAge.Seconds()))
+	}
+	return challenge
+}
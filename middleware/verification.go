@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/bcetienne/tools-go-token/v4/apierror"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+// verificationMemoKey is the unexported context key type for the verification memo.
+type verificationMemoKey struct{}
+
+// verificationMemo holds the access token verifications of a request.
+type verificationMemo struct {
+	mu      sync.Mutex
+	results []verificationResult
+}
+
+// verificationResult is the outcome of a verification, by verifier and token.
+type verificationResult struct {
+	verifier AccessTokenVerifier
+	token    string
+	claim    *modelAuth.Claim
+	err      error
+}
+
+// VerificationOption configures ShareVerification.
+type VerificationOption func(*verificationOptions)
+
+// verificationOptions holds the values set by the verification options.
+type verificationOptions struct {
+	cacheControl string
+	vary         []string
+}
+
+// WithUnauthorizedCacheControl sets the Cache-Control header of the 401 responses
+// (default: "no-store"). An empty value leaves the header as set by the handlers.
+func WithUnauthorizedCacheControl(value string) VerificationOption {
+	return func(o *verificationOptions) {
+		o.cacheControl = value
+	}
+}
+
+// WithUnauthorizedVary sets the headers added to the Vary header of the 401 responses
+// (default: "Authorization"). No headers leaves the Vary header as set by the handlers.
+func WithUnauthorizedVary(headers ...string) VerificationOption {
+	return func(o *verificationOptions) {
+		o.vary = headers
+	}
+}
+
+// ShareVerification verifies each access token once per request: RequireStepUp,
+// RequireScope and RequireRecentElevation below it reuse the verification of the
+// first of them for the same verifier and token, instead of checking the signature
+// and the epochs again. It also sets caching headers on the 401 responses below it,
+// so that shared caches and CDNs never serve a rejection to another client.
+//
+// Options:
+//   - WithUnauthorizedCacheControl: Cache-Control of 401 responses (default: "no-store")
+//   - WithUnauthorizedVary: Headers added to Vary on 401 responses (default: "Authorization")
+//
+// Returns:
+//   - func(http.Handler) http.Handler: The middleware, to install before the others
+//
+// Example:
+//
+//	shared := middleware.ShareVerification(middleware.WithUnauthorizedVary("Authorization", "Cookie"))
+//	requireMFA := middleware.RequireStepUp(accessService, requirement)
+//	requireAdmin := middleware.RequireScope(accessService, "admin")
+//	mux.Handle("POST /admin/users", shared(requireMFA(requireAdmin(createUserHandler))))
+func ShareVerification(opts ...VerificationOption) func(http.Handler) http.Handler {
+	options := verificationOptions{cacheControl: "no-store", vary: []string{"Authorization"}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(verificationMemoKey{}).(*verificationMemo); !ok {
+				r = r.WithContext(context.WithValue(r.Context(), verificationMemoKey{}, &verificationMemo{}))
+			}
+			next.ServeHTTP(&unauthorizedHeaderWriter{ResponseWriter: w, options: options}, r)
+		})
+	}
+}
+
+// verifyBearer verifies the bearer token of the request, once per request, verifier
+// and token when the request went through ShareVerification.
+func verifyBearer(r *http.Request, verifier AccessTokenVerifier) (*modelAuth.Claim, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, apierror.ErrTokenMissing
+	}
+
+	memo, ok := r.Context().Value(verificationMemoKey{}).(*verificationMemo)
+	if !ok || !reflect.TypeOf(verifier).Comparable() {
+		return verifier.VerifyAccessTokenContext(r.Context(), token)
+	}
+
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	for _, result := range memo.results {
+		if result.verifier == verifier && result.token == token {
+			return result.claim, result.err
+		}
+	}
+	claim, err := verifier.VerifyAccessTokenContext(r.Context(), token)
+	memo.results = append(memo.results, verificationResult{verifier: verifier, token: token, claim: claim, err: err})
+	return claim, err
+}
+
+// unauthorizedHeaderWriter sets the caching headers of ShareVerification on 401 responses.
+type unauthorizedHeaderWriter struct {
+	http.ResponseWriter
+	options verificationOptions
+}
+
+func (w *unauthorizedHeaderWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusUnauthorized {
+		header := w.Header()
+		if w.options.cacheControl != "" {
+			header.Set("Cache-Control", w.options.cacheControl)
+		}
+		for _, vary := range w.options.vary {
+			header.Add("Vary", vary)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *unauthorizedHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush keeps streaming handlers working below the middleware.
+func (w *unauthorizedHeaderWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
@@ -0,0 +1,89 @@
+package auth
+
+import "time"
+
+// AuthenticationMethod identifies a factor used to authenticate the user,
+// recorded in the "amr" (Authentication Methods References) claim.
+type AuthenticationMethod string
+
+const (
+	AuthenticationMethodPassword AuthenticationMethod = "pwd"
+	AuthenticationMethodOTP      AuthenticationMethod = "otp"
+	AuthenticationMethodWebAuthn AuthenticationMethod = "webauthn"
+)
+
+// Assurance levels recorded in the "acr" (Authentication Context Class Reference) claim,
+// from least to most assured (NIST SP 800-63B authenticator assurance levels).
+const (
+	AssuranceLevel1 string = "aal1"
+	AssuranceLevel2 string = "aal2"
+	AssuranceLevel3 string = "aal3"
+)
+
+// assuranceLevels orders the known levels, the index is the strength.
+var assuranceLevels = []string{AssuranceLevel1, AssuranceLevel2, AssuranceLevel3}
+
+// Authentication describes how and when the user authenticated.
+// Used to mint access tokens carrying step-up markers.
+//
+// Fields:
+//   - Methods: Factors used (e.g. pwd then otp)
+//   - Level: Assurance level, derived from Methods when empty (see AssuranceLevel)
+//   - Time: When the user authenticated, token creation time when zero
+type Authentication struct {
+	Methods []AuthenticationMethod
+	Level   string
+	Time    time.Time
+}
+
+// AssuranceLevel returns the explicit Level, or derives it from the methods:
+//   - aal3: webauthn combined with another factor
+//   - aal2: at least two distinct factors
+//   - aal1: a single factor
+//   - "": no factor
+func (a *Authentication) AssuranceLevel() string {
+	if a.Level != "" {
+		return a.Level
+	}
+
+	distinct := make(map[AuthenticationMethod]struct{}, len(a.Methods))
+	for _, method := range a.Methods {
+		distinct[method] = struct{}{}
+	}
+	_, webAuthn := distinct[AuthenticationMethodWebAuthn]
+
+	switch {
+	case len(distinct) >= 2 && webAuthn:
+		return AssuranceLevel3
+	case len(distinct) >= 2:
+		return AssuranceLevel2
+	case len(distinct) == 1:
+		return AssuranceLevel1
+	default:
+		return ""
+	}
+}
+
+// IsAssuranceLevelAtLeast reports whether level is as strong as required.
+// Unknown levels never satisfy a requirement, and an empty requirement is always satisfied.
+//
+// Example:
+//
+//	modelAuth.IsAssuranceLevelAtLeast("aal3", "aal2") // true
+//	modelAuth.IsAssuranceLevelAtLeast("aal1", "aal2") // false
+func IsAssuranceLevelAtLeast(level, required string) bool {
+	if required == "" {
+		return true
+	}
+
+	levelIndex, requiredIndex := -1, -1
+	for i, known := range assuranceLevels {
+		if known == level {
+			levelIndex = i
+		}
+		if known == required {
+			requiredIndex = i
+		}
+	}
+	return levelIndex >= 0 && requiredIndex >= 0 && levelIndex >= requiredIndex
+}
@@ -1,6 +1,14 @@
 package auth
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/golang-jwt/jwt/v5"
+)
 
 // Claim represents the JWT token claims structure.
 // Extends jwt.RegisteredClaims with custom fields for token type and email.
@@ -8,6 +16,15 @@ import "github.com/golang-jwt/jwt/v5"
 // Custom fields:
 //   - KeyType: Discriminator for token type ("access" vs other types)
 //   - Email: User's email address for quick access
+//   - AMR: Authentication methods used (e.g. ["pwd", "otp"]), omitted when unknown
+//   - ACR: Assurance level reached (e.g. "aal2"), omitted when unknown
+//   - AuthTime: When the user authenticated, omitted when unknown
+//   - Actor: Party acting on behalf of the subject (impersonation, token exchange), omitted otherwise
+//   - Scope: Space-separated scopes granted to the token (token exchange), omitted when unscoped
+//   - Epoch: Global token epoch at issuance, omitted when epochs are not used
+//   - UserEpoch: User token epoch at issuance, omitted when epochs are not used
+//   - OneTimeUse: Token accepted once by VerifyAccessToken (jti recorded), omitted otherwise
+//   - Custom: Application claims, serialized at the top level of the token (see ReservedClaimNames)
 //
 // Standard JWT claims (inherited from jwt.RegisteredClaims):
 //   - Subject: User's unique identifier (UUID or numeric ID as string) - RFC 7519 compliant
@@ -21,7 +38,133 @@ import "github.com/golang-jwt/jwt/v5"
 //   - Tags enable JWT marshaling/unmarshaling
 //   - Example: {"key_type": "access", "email": "user@example.com", "sub": "550e8400-...", "exp": 1234567890, ...}
 type Claim struct {
-	KeyType string `json:"key_type"`
-	Email   string `json:"email"`
+	KeyType    string           `json:"key_type"`
+	Email      string           `json:"email"`
+	AMR        []string         `json:"amr,omitempty"`
+	ACR        string           `json:"acr,omitempty"`
+	AuthTime   *jwt.NumericDate `json:"auth_time,omitempty"`
+	Actor      *Actor           `json:"act,omitempty"`
+	Scope      string           `json:"scope,omitempty"`
+	Epoch      int64            `json:"epoch,omitempty"`
+	UserEpoch  int64            `json:"user_epoch,omitempty"`
+	OneTimeUse bool             `json:"one_time_use,omitempty"`
+	Custom     map[string]any   `json:"-"`
 	jwt.RegisteredClaims
 }
+
+// ReservedClaimNames lists the claim names set by the library (registered JWT
+// claims, access and ID token claims). They cannot be used as custom claims.
+var ReservedClaimNames = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+	"key_type", "email", "email_verified", "amr", "acr", "auth_time", "act", "nonce",
+	"epoch", "user_epoch", "scope", "one_time_use",
+}
+
+// IsReservedClaimName reports whether name is one of ReservedClaimNames.
+func IsReservedClaimName(name string) bool {
+	return slices.Contains(ReservedClaimNames, name)
+}
+
+// claimFields has the fields of Claim without its JSON methods.
+type claimFields Claim
+
+// MarshalJSON serializes the claims, custom claims included at the top level.
+// Custom claims never override reserved claims.
+func (c Claim) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(claimFields(c))
+	if err != nil || len(c.Custom) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range c.Custom {
+		if IsReservedClaimName(name) {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		merged[name] = raw
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON parses the claims, unknown claims going to Custom.
+func (c *Claim) UnmarshalJSON(data []byte) error {
+	var fields claimFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for name := range all {
+		if IsReservedClaimName(name) {
+			delete(all, name)
+		}
+	}
+	if len(all) > 0 {
+		fields.Custom = all
+	}
+
+	*c = Claim(fields)
+	return nil
+}
+
+// Actor identifies the party acting on behalf of the token subject,
+// serialized as the RFC 8693 "act" claim. In delegation chains, Actor holds
+// the prior actor, the outermost being the current one.
+//
+// JSON serialization:
+//   - Example: {"act": {"sub": "42", "email": "admin@example.com"}}
+//   - Example: {"act": {"sub": "billing-api", "act": {"sub": "gateway"}}}
+type Actor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// Chain returns the subjects of the actor and its prior actors, current one first.
+func (a *Actor) Chain() []string {
+	var chain []string
+	for actor := a; actor != nil; actor = actor.Actor {
+		chain = append(chain, actor.Subject)
+	}
+	return chain
+}
+
+// IsImpersonated reports whether the token was issued to an actor acting as the subject.
+func (c *Claim) IsImpersonated() bool {
+	return c.Actor != nil && c.Actor.Subject != ""
+}
+
+// Scopes returns the scopes of the token, nil when it carries no scope claim.
+func (c *Claim) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// IsScoped reports whether the token carries a scope claim.
+func (c *Claim) IsScoped() bool {
+	return len(c.Scopes()) > 0
+}
+
+// HasScope reports whether the token grants scope, hierarchically (see lib.ScopeMatches).
+// Tokens without a scope claim grant no scope.
+func (c *Claim) HasScope(scope string) bool {
+	return lib.ScopesAllow(c.Scopes(), scope)
+}
+
+// AuthAge returns the time elapsed since the user authenticated.
+// Returns false when the token does not record the authentication time.
+func (c *Claim) AuthAge(now time.Time) (time.Duration, bool) {
+	if c.AuthTime == nil {
+		return 0, false
+	}
+	return now.Sub(c.AuthTime.Time), true
+}
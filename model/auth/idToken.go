@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaim represents the claims of an OpenID Connect ID token
+// (OpenID Connect Core 1.0, section 2).
+//
+// Custom fields:
+//   - KeyType: "id", so that access tokens signed with the same secret are not accepted as ID tokens
+//   - Email: User's email address
+//   - EmailVerified: Whether the email address was verified, omitted when unknown
+//   - Nonce: Value sent by the client in the authentication request, omitted when none
+//   - AuthTime: When the user authenticated
+//   - AMR: Authentication methods used (e.g. ["pwd", "otp"]), omitted when unknown
+//   - ACR: Assurance level reached (e.g. "aal2"), omitted when unknown
+//
+// Standard JWT claims (inherited from jwt.RegisteredClaims):
+//   - Issuer: Application identifier
+//   - Subject: User's unique identifier
+//   - Audience: Client ID of the relying party
+//   - ExpiresAt, IssuedAt: Token lifetime
+//   - ID (jti): Unique token identifier (UUID)
+//
+// JSON serialization:
+//   - Example: {"key_type": "id", "iss": "myapp", "sub": "123", "aud": ["client-1"], "nonce": "n-0S6_WzA2Mj", "auth_time": 1234567890, "email": "user@example.com", ...}
+type IDTokenClaim struct {
+	KeyType       string           `json:"key_type"`
+	Email         string           `json:"email,omitempty"`
+	EmailVerified *bool            `json:"email_verified,omitempty"`
+	Nonce         string           `json:"nonce,omitempty"`
+	AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR           []string         `json:"amr,omitempty"`
+	ACR           string           `json:"acr,omitempty"`
+	jwt.RegisteredClaims
+}
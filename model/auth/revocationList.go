@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevocationListClaim represents the claims of a signed revocation list, exported for
+// edge caches so they can reject revoked tokens without querying the token store.
+//
+// Custom fields:
+//   - TokenType: Type of the listed tokens (e.g. "rt" for refresh tokens)
+//   - Since: Start of the revocation window covered by the list
+//   - Revoked: Hex-encoded SHA-256 hashes of the revoked tokens, sorted
+//
+// Standard JWT claims (inherited from jwt.RegisteredClaims):
+//   - Issuer: Application identifier
+//   - IssuedAt: When the list was generated, the end of the revocation window
+//
+// JSON serialization:
+//   - Example: {"iss": "myapp", "iat": 1234567890, "token_type": "rt", "since": 1234560000, "revoked": ["9f86d0...", ...]}
+type RevocationListClaim struct {
+	TokenType string           `json:"token_type"`
+	Since     *jwt.NumericDate `json:"since"`
+	Revoked   []string         `json:"revoked"`
+	jwt.RegisteredClaims
+}
+
+// IsRevoked reports whether token is in the list.
+//
+// Parameters:
+//   - token: Token presented by the client
+//
+// Returns:
+//   - bool: true if the hash of the token is listed
+func (c *RevocationListClaim) IsRevoked(token string) bool {
+	sum := sha256.Sum256([]byte(token))
+	_, found := slices.BinarySearch(c.Revoked, hex.EncodeToString(sum[:]))
+	return found
+}
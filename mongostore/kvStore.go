@@ -0,0 +1,244 @@
+// Package mongostore implements service.KVStore and service.TokenStore on MongoDB,
+// for deployments standardized on Mongo.
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// fieldValue holds the value of the key (string).
+	fieldValue string = "value"
+	// fieldExpiresAt is the expiry of the key (date), indexed with expireAfterSeconds 0.
+	// MongoDB deletes expired documents every minute, so reads compare it to the current time.
+	fieldExpiresAt string = "expires_at"
+
+	// maxIncrAttempts bounds the retries of Incr when concurrent upserts collide.
+	maxIncrAttempts int = 10
+
+	// defaultScanCount is the batch size of Scan when count is not positive.
+	defaultScanCount int = 500
+
+	// conversionFailureCode is the MongoDB error code of a failed $toLong.
+	conversionFailureCode int32 = 241
+)
+
+// document is a key of the collection.
+type document struct {
+	Key       string    `bson:"_id"`
+	Value     string    `bson:"value"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// kvStore implements service.KVStore on a MongoDB collection.
+type kvStore struct {
+	collection *mongo.Collection
+	now        func() time.Time
+}
+
+// NewKVStore returns a service.KVStore keeping its keys in a MongoDB collection, one
+// document per key, with the key as _id (unique). Expired documents are deleted by
+// the TTL index of CreateIndexes. Incr is a single atomic update, so that attempt
+// counters stay exact across instances. Requires MongoDB 4.2+ (pipeline updates).
+//
+// Document layout:
+//   - _id (string): The KVStore key, e.g. "otp:{userID}"
+//   - value (string): The value
+//   - expires_at (date): Expiry, with a TTL index (expireAfterSeconds: 0)
+//
+// Returns an error if the collection is nil.
+//
+// Example:
+//
+//	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://mongo:27017"))
+//	collection := client.Database("auth").Collection("tokens")
+//	err = mongostore.CreateIndexes(ctx, collection)
+//	store, err := mongostore.NewKVStore(collection)
+//	otpService, err := service.NewOTPServiceWithStore(ctx, store, config)
+func NewKVStore(collection *mongo.Collection) (service.KVStore, error) {
+	if collection == nil {
+		return nil, errors.New("collection is nil")
+	}
+
+	return &kvStore{collection: collection, now: time.Now}, nil
+}
+
+// CreateIndexes creates the TTL index of the collection of NewKVStore, deleting the
+// documents once expired. It can run on every start: an existing identical index is
+// left as is.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - collection: The collection of the store
+//
+// Returns:
+//   - error: Validation or MongoDB errors
+func CreateIndexes(ctx context.Context, collection *mongo.Collection) error {
+	if collection == nil {
+		return errors.New("collection is nil")
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: fieldExpiresAt, Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// live returns the filter of the unexpired document of key.
+func (s *kvStore) live(key string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: key},
+		{Key: fieldExpiresAt, Value: bson.D{{Key: "$gt", Value: s.now()}}},
+	}
+}
+
+// get returns the unexpired document of key.
+func (s *kvStore) get(ctx context.Context, key string) (document, bool, error) {
+	var doc document
+	err := s.collection.FindOne(ctx, s.live(key)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return document{}, false, nil
+	}
+	if err != nil {
+		return document{}, false, err
+	}
+	return doc, true, nil
+}
+
+func (s *kvStore) Get(ctx context.Context, key string) (string, bool, error) {
+	doc, ok, err := s.get(ctx, key)
+	return doc.Value, ok, err
+}
+
+func (s *kvStore) SetEX(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	doc := document{Key: key, Value: value, ExpiresAt: s.now().Add(ttl)}
+	_, err := s.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: key}}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *kvStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// Expired documents awaiting the TTL monitor did not exist
+	result, err := s.collection.DeleteMany(ctx, bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: keys}}},
+		{Key: fieldExpiresAt, Value: bson.D{{Key: "$gt", Value: s.now()}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *kvStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	for range maxIncrAttempts {
+		now := s.now()
+		// A missing or expired key restarts at 1 with a new expiry, a live one keeps its expiry
+		live := bson.D{{Key: "$gt", Value: bson.A{"$" + fieldExpiresAt, now}}}
+		update := mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{
+				{Key: fieldValue, Value: bson.D{{Key: "$cond", Value: bson.A{
+					live,
+					bson.D{{Key: "$toString", Value: bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$toLong", Value: "$" + fieldValue}}, 1}}}}},
+					"1",
+				}}}},
+				{Key: fieldExpiresAt, Value: bson.D{{Key: "$cond", Value: bson.A{live, "$" + fieldExpiresAt, now.Add(ttl)}}}},
+			}}},
+		}
+
+		var doc document
+		err := s.collection.FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: key}}, update,
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&doc)
+		if mongo.IsDuplicateKeyError(err) {
+			// Concurrent first increments: both upserted, one lost, the document now exists
+			continue
+		}
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == conversionFailureCode {
+			return 0, errors.New("value is not an integer")
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := strconv.ParseInt(doc.Value, 10, 64)
+		if err != nil {
+			return 0, errors.New("value is not an integer")
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("failed to increment key %s: too many concurrent updates", key)
+}
+
+func (s *kvStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	doc, ok, err := s.get(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return doc.ExpiresAt.Sub(s.now()), nil
+}
+
+func (s *kvStore) Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	// An anchored regular expression on _id is served by the _id index
+	filter := bson.D{
+		{Key: "_id", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix)}},
+		{Key: fieldExpiresAt, Value: bson.D{{Key: "$gt", Value: s.now()}}},
+	}
+	cursor, err := s.collection.Find(ctx, filter, options.Find().
+		SetProjection(bson.D{{Key: "_id", Value: 1}}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(int32(count)))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var batch []string
+	for cursor.Next(ctx) {
+		var doc struct {
+			Key string `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if batch = append(batch, doc.Key); len(batch) == count {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/mongostore"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MongoDB collections for all tests
+var (
+	collection      *mongo.Collection
+	tokenCollection *mongo.Collection
+)
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	// Start MongoDB container
+	container, err := testcontainers.Run(ctx,
+		"mongo:7",
+		testcontainers.WithExposedPorts("27017/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort("27017/tcp")),
+	)
+	if err != nil {
+		log.Printf("failed to start MongoDB container: %s", err)
+		return
+	}
+
+	defer func() {
+		if err = testcontainers.TerminateContainer(container); err != nil {
+			log.Printf("failed to terminate MongoDB container: %s", err)
+		}
+	}()
+
+	endpoint, err := container.PortEndpoint(ctx, "27017/tcp", "mongodb")
+	if err != nil {
+		log.Printf("failed to get MongoDB endpoint: %s", err)
+		return
+	}
+
+	// Connect to MongoDB
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(endpoint))
+	if err != nil {
+		log.Fatalf("Cannot connect to MongoDB: %s", err)
+	}
+	defer client.Disconnect(ctx)
+
+	collection = client.Database("auth").Collection("tokens")
+	if err = mongostore.CreateIndexes(ctx, collection); err != nil {
+		log.Fatalf("Cannot create MongoDB indexes: %s", err)
+	}
+	tokenCollection = client.Database("auth").Collection("refresh_tokens")
+	if err = mongostore.CreateTokenIndexes(ctx, tokenCollection); err != nil {
+		log.Fatalf("Cannot create MongoDB token indexes: %s", err)
+	}
+
+	// Run tests
+	exitCode := m.Run()
+
+	// Exit with the tests exit code
+	os.Exit(exitCode)
+}
+
+func TestNewKVStore(t *testing.T) {
+	t.Run("Should fail with nil collection", func(t *testing.T) {
+		_, err := mongostore.NewKVStore(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "collection is nil")
+	})
+
+	t.Run("Should create the indexes again", func(t *testing.T) {
+		require.NoError(t, mongostore.CreateIndexes(context.Background(), collection))
+	})
+}
+
+func TestKVStore(t *testing.T) {
+	store, err := mongostore.NewKVStore(collection)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	prefix := "kv-test:"
+
+	t.Run("Should set, get and delete values", func(t *testing.T) {
+		require.NoError(t, store.SetEX(ctx, prefix+"a", "1", time.Minute))
+
+		value, found, err := store.Get(ctx, prefix+"a")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "1", value)
+
+		ttl, err := store.TTL(ctx, prefix+"a")
+		require.NoError(t, err)
+		assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+		deleted, err := store.Del(ctx, prefix+"a", prefix+"missing")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, found, err = store.Get(ctx, prefix+"a")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		ttl, err = store.TTL(ctx, prefix+"a")
+		require.NoError(t, err)
+		assert.Zero(t, ttl)
+	})
+
+	t.Run("Should increment counters", func(t *testing.T) {
+		n, err := store.Incr(ctx, prefix+"counter", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		n, err = store.Incr(ctx, prefix+"counter", time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		ttl, err := store.TTL(ctx, prefix+"counter")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, ttl, time.Minute, "an existing counter keeps its expiry")
+	})
+
+	t.Run("Should increment counters concurrently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := store.Incr(ctx, prefix+"concurrent", time.Minute)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		value, _, err := store.Get(ctx, prefix+"concurrent")
+		require.NoError(t, err)
+		assert.Equal(t, "5", value)
+	})
+
+	t.Run("Should refuse to increment a non-integer", func(t *testing.T) {
+		require.NoError(t, store.SetEX(ctx, prefix+"text", "abc", time.Minute))
+		_, err := store.Incr(ctx, prefix+"text", time.Minute)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "value is not an integer")
+	})
+
+	t.Run("Should expire values", func(t *testing.T) {
+		require.NoError(t, store.SetEX(ctx, prefix+"short", "1", 50*time.Millisecond))
+		time.Sleep(100 * time.Millisecond)
+
+		_, found, err := store.Get(ctx, prefix+"short")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		// An expired key restarts at 1
+		require.NoError(t, store.SetEX(ctx, prefix+"short", "7", 50*time.Millisecond))
+		time.Sleep(100 * time.Millisecond)
+		n, err := store.Incr(ctx, prefix+"short", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("Should scan keys by prefix", func(t *testing.T) {
+		for i := range 3 {
+			require.NoError(t, store.SetEX(ctx, fmt.Sprintf("%ss:%d", prefix, i), "1", time.Minute))
+		}
+		require.NoError(t, store.SetEX(ctx, prefix+"other", "1", time.Minute))
+
+		var batches int
+		var keys []string
+		require.NoError(t, store.Scan(ctx, prefix+"s:", 2, func(batch []string) error {
+			batches++
+			keys = append(keys, batch...)
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{prefix + "s:0", prefix + "s:1", prefix + "s:2"}, keys)
+		assert.Equal(t, 2, batches)
+	})
+
+	t.Run("Should scan a prefix literally", func(t *testing.T) {
+		require.NoError(t, store.SetEX(ctx, prefix+"a.b:1", "1", time.Minute))
+		require.NoError(t, store.SetEX(ctx, prefix+"axb:1", "1", time.Minute))
+
+		var keys []string
+		require.NoError(t, store.Scan(ctx, prefix+"a.b:", 10, func(batch []string) error {
+			keys = append(keys, batch...)
+			return nil
+		}))
+		assert.Equal(t, []string{prefix + "a.b:1"}, keys)
+	})
+}
@@ -0,0 +1,136 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/mongostore"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenStore(t *testing.T) {
+	t.Run("Should fail with nil collection", func(t *testing.T) {
+		_, err := mongostore.NewTokenStore(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "collection is nil")
+
+		err = mongostore.CreateTokenIndexes(context.Background(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "collection is nil")
+	})
+
+	t.Run("Should create the indexes again", func(t *testing.T) {
+		require.NoError(t, mongostore.CreateTokenIndexes(context.Background(), tokenCollection))
+	})
+}
+
+func TestTokenStore(t *testing.T) {
+	store, err := mongostore.NewTokenStore(tokenCollection)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("Should put, get, find and delete tokens", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "123", Hash: "h1", Value: "v1", ExpiresAt: expiresAt}))
+
+		stored, found, err := store.Get(ctx, "123", "h1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "v1", stored.Value)
+		assert.WithinDuration(t, expiresAt, stored.ExpiresAt, time.Millisecond)
+
+		stored, found, err = store.Find(ctx, "h1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "123", stored.UserID)
+
+		_, found, err = store.Get(ctx, "456", "h1")
+		require.NoError(t, err)
+		assert.False(t, found, "tokens are per user")
+
+		deleted, err := store.Delete(ctx, "123", "h1", "missing")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, found, err = store.Find(ctx, "h1")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Should keep token hashes unique", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "123", Hash: "unique", Value: "v", ExpiresAt: expiresAt}))
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "123", Hash: "unique", Value: "v2", ExpiresAt: expiresAt}), "a token is replaced")
+
+		err := store.Put(ctx, service.StoredToken{UserID: "456", Hash: "unique", Value: "v", ExpiresAt: expiresAt})
+		assert.True(t, mongo.IsDuplicateKeyError(err))
+	})
+
+	t.Run("Should list and scan tokens", func(t *testing.T) {
+		for i := range 3 {
+			require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "list", Hash: fmt.Sprintf("l%d", i), Value: "v", ExpiresAt: expiresAt}))
+		}
+
+		tokens, err := store.List(ctx, "list")
+		require.NoError(t, err)
+		require.Len(t, tokens, 3)
+		assert.Equal(t, "l0", tokens[0].Hash)
+
+		var batches int
+		var hashes []string
+		require.NoError(t, store.Scan(ctx, 2, func(tokens []service.StoredToken) error {
+			batches++
+			for _, token := range tokens {
+				hashes = append(hashes, token.Hash)
+			}
+			return nil
+		}))
+		assert.Subset(t, hashes, []string{"l0", "l1", "l2"})
+		assert.GreaterOrEqual(t, batches, 2)
+	})
+
+	t.Run("Should not return expired tokens", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "short", Hash: "s", Value: "v", ExpiresAt: time.Now().Add(50 * time.Millisecond)}))
+		time.Sleep(100 * time.Millisecond)
+
+		_, found, err := store.Get(ctx, "short", "s")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		tokens, err := store.List(ctx, "short")
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+
+		deleted, err := store.Delete(ctx, "short", "s")
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("Should back a password reset service", func(t *testing.T) {
+		ttl := "10m"
+		resetStore, err := mongostore.NewTokenStore(tokenCollection.Database().Collection("password_reset_tokens"))
+		require.NoError(t, err)
+		prs, err := service.NewPasswordResetServiceWithStore(ctx, resetStore, &lib.Config{PasswordResetTTL: &ttl})
+		require.NoError(t, err)
+
+		token, err := prs.CreatePasswordResetToken(ctx, "svc")
+		require.NoError(t, err)
+
+		info, err := prs.IdentifyPasswordResetToken(ctx, *token)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, "svc", info.UserID)
+
+		require.NoError(t, prs.RevokePasswordResetToken(ctx, "svc", *token))
+		valid, err := prs.VerifyPasswordResetToken(ctx, "svc", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
@@ -0,0 +1,209 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// fieldUserID holds the owner of the token (string).
+	fieldUserID string = "user_id"
+	// fieldTokenHash holds the hex-encoded SHA-256 hash of the token (string), with a unique index.
+	fieldTokenHash string = "token_hash"
+)
+
+// tokenDocument is a token of the collection.
+type tokenDocument struct {
+	UserID    string    `bson:"user_id"`
+	Hash      string    `bson:"token_hash"`
+	Value     string    `bson:"value"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// storedToken returns the service.StoredToken of the document.
+func (d tokenDocument) storedToken() service.StoredToken {
+	return service.StoredToken{UserID: d.UserID, Hash: d.Hash, Value: d.Value, ExpiresAt: d.ExpiresAt}
+}
+
+// tokenStore implements service.TokenStore on a MongoDB collection.
+type tokenStore struct {
+	collection *mongo.Collection
+	now        func() time.Time
+}
+
+// NewTokenStore returns a service.TokenStore keeping the refresh or password reset
+// tokens in a MongoDB collection, one document per token, for
+// service.NewRefreshTokenServiceWithStore and service.NewPasswordResetServiceWithStore.
+// Each service needs its own collection. Token hashes are unique and expired documents
+// are deleted by the TTL index, see CreateTokenIndexes.
+//
+// Document layout:
+//   - user_id (string): Owner of the token
+//   - token_hash (string): Hex-encoded SHA-256 hash of the token, with a unique index
+//   - value (string): The token record
+//   - expires_at (date): Expiry, with a TTL index (expireAfterSeconds: 0)
+//
+// Returns an error if the collection is nil.
+//
+// Example:
+//
+//	collection := client.Database("auth").Collection("refresh_tokens")
+//	err = mongostore.CreateTokenIndexes(ctx, collection)
+//	store, err := mongostore.NewTokenStore(collection)
+//	refreshService, err := service.NewRefreshTokenServiceWithStore(ctx, store, config)
+func NewTokenStore(collection *mongo.Collection) (service.TokenStore, error) {
+	if collection == nil {
+		return nil, errors.New("collection is nil")
+	}
+
+	return &tokenStore{collection: collection, now: time.Now}, nil
+}
+
+// CreateTokenIndexes creates the indexes of the collection of NewTokenStore: the unique
+// index on token_hash, the index listing the tokens of a user and the TTL index
+// deleting the documents once expired. It can run on every start: existing identical
+// indexes are left as is.
+//
+// Parameters:
+//   - ctx: Context for the operation
+//   - collection: The collection of the store
+//
+// Returns:
+//   - error: Validation or MongoDB errors
+func CreateTokenIndexes(ctx context.Context, collection *mongo.Collection) error {
+	if collection == nil {
+		return errors.New("collection is nil")
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: fieldTokenHash, Value: 1}},
+			Options: options.Index().SetName("token_hash_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: fieldUserID, Value: 1}, {Key: fieldTokenHash, Value: 1}},
+			Options: options.Index().SetName("user_id_token_hash"),
+		},
+		{
+			Keys:    bson.D{{Key: fieldExpiresAt, Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// live returns filter restricted to the unexpired documents.
+func (s *tokenStore) live(filter bson.D) bson.D {
+	return append(filter, bson.E{Key: fieldExpiresAt, Value: bson.D{{Key: "$gt", Value: s.now()}}})
+}
+
+// findOne returns the unexpired document matching filter.
+func (s *tokenStore) findOne(ctx context.Context, filter bson.D) (service.StoredToken, bool, error) {
+	var doc tokenDocument
+	err := s.collection.FindOne(ctx, s.live(filter)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return service.StoredToken{}, false, nil
+	}
+	if err != nil {
+		return service.StoredToken{}, false, err
+	}
+	return doc.storedToken(), true, nil
+}
+
+func (s *tokenStore) Put(ctx context.Context, token service.StoredToken) error {
+	if token.UserID == "" || token.Hash == "" {
+		return errors.New("incomplete token")
+	}
+	if !token.ExpiresAt.After(s.now()) {
+		return fmt.Errorf("token of user %s already expired", token.UserID)
+	}
+
+	doc := tokenDocument{UserID: token.UserID, Hash: token.Hash, Value: token.Value, ExpiresAt: token.ExpiresAt}
+	filter := bson.D{{Key: fieldUserID, Value: token.UserID}, {Key: fieldTokenHash, Value: token.Hash}}
+	_, err := s.collection.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *tokenStore) Get(ctx context.Context, userID string, hash string) (service.StoredToken, bool, error) {
+	return s.findOne(ctx, bson.D{{Key: fieldUserID, Value: userID}, {Key: fieldTokenHash, Value: hash}})
+}
+
+func (s *tokenStore) Find(ctx context.Context, hash string) (service.StoredToken, bool, error) {
+	return s.findOne(ctx, bson.D{{Key: fieldTokenHash, Value: hash}})
+}
+
+func (s *tokenStore) List(ctx context.Context, userID string) ([]service.StoredToken, error) {
+	cursor, err := s.collection.Find(ctx, s.live(bson.D{{Key: fieldUserID, Value: userID}}),
+		options.Find().SetSort(bson.D{{Key: fieldTokenHash, Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []tokenDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	tokens := make([]service.StoredToken, len(docs))
+	for i, doc := range docs {
+		tokens[i] = doc.storedToken()
+	}
+	return tokens, nil
+}
+
+func (s *tokenStore) Delete(ctx context.Context, userID string, hashes ...string) (int64, error) {
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+
+	// Expired documents awaiting the TTL monitor did not exist
+	result, err := s.collection.DeleteMany(ctx, s.live(bson.D{
+		{Key: fieldUserID, Value: userID},
+		{Key: fieldTokenHash, Value: bson.D{{Key: "$in", Value: hashes}}},
+	}))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *tokenStore) Scan(ctx context.Context, count int, fn func(tokens []service.StoredToken) error) error {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	cursor, err := s.collection.Find(ctx, s.live(bson.D{}), options.Find().
+		SetSort(bson.D{{Key: fieldUserID, Value: 1}, {Key: fieldTokenHash, Value: 1}}).
+		SetBatchSize(int32(count)))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var batch []service.StoredToken
+	for cursor.Next(ctx) {
+		var doc tokenDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if batch = append(batch, doc.storedToken()); len(batch) == count {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
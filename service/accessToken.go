@@ -1,8 +1,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
@@ -18,16 +20,41 @@ import (
 // Architecture:
 //   - Stateless: No Redis/database storage required
 //   - Short-lived: Configured via JWTExpiry (typically 15 minutes)
-//   - Signed with HS256: Uses JWTSecret for signing and verification
+//   - Signed with Config.JWTAlgorithm (HS256 by default): Uses JWTSecret for signing and verification
 //   - Claims include: UserID, email (subject), issuer, expiration, UUID (jti)
+//   - Impersonation and exchanged tokens also carry the acting party (act)
+//   - Optional epoch claims checked against TokenEpochService (global and per-user revocation)
+//   - Optional one-time-use tokens, accepted once thanks to a JTIStore (CreateOneTimeAccessToken)
+//   - Optional application claims, within a size budget and name whitelist (CreateAccessTokenWithClaims)
+//   - Optional custom claim validators run on verification (AddClaimValidator)
+//   - Optional JSON Schema of the custom claims, checked on creation and verification (SetClaimSchema)
 type AccessTokenService struct {
 	config *lib.Config
+	now    func() time.Time
+
+	// mu guards the hooks, which can be set while tokens are verified.
+	mu         sync.RWMutex
+	audit      lib.AuditLogger
+	epochs     *TokenEpochService
+	validators []namedClaimValidator
+	schema     *ClaimSchema
+	replays    lib.ReplayMetricsHook
+	jtis       *JTIStore
+	actors     ActorAuthorizer
 }
 
 // AccessTokenServiceInterface defines the methods for JWT access token management.
 type AccessTokenServiceInterface interface {
 	CreateAccessToken(user *modelAuth.User) (string, error)
+	CreateAccessTokenWithAuthentication(user *modelAuth.User, authentication *modelAuth.Authentication) (string, error)
+	CreateImpersonationToken(ctx context.Context, actor *modelAuth.User, user *modelAuth.User) (string, error)
+	CreateAccessTokenForAudience(user *modelAuth.User, audience string) (string, error)
+	CreateAccessTokenWithClaims(user *modelAuth.User, claims map[string]any) (string, error)
+	CreateOneTimeAccessToken(user *modelAuth.User, ttl time.Duration) (string, error)
+	ExchangeToken(ctx context.Context, request TokenExchangeRequest) (*TokenExchangeResponse, error)
 	VerifyAccessToken(token string) (*modelAuth.Claim, error)
+	CreateIDToken(user *modelAuth.User, params IDTokenParams) (string, error)
+	VerifyIDToken(token string, audience string, nonce string) (*modelAuth.IDTokenClaim, error)
 }
 
 // NewAccessTokenService creates a new access token service instance.
@@ -35,6 +62,7 @@ type AccessTokenServiceInterface interface {
 //
 // Parameters:
 //   - config: Configuration containing Issuer, JWTSecret, and JWTExpiry
+//   - opts: Optional settings (WithLogger, WithClock)
 //
 // Returns:
 //   - *AccessTokenService: Service ready for token creation and verification
@@ -44,14 +72,146 @@ type AccessTokenServiceInterface interface {
 //	config := lib.NewConfig("myapp", "secret", "15m", ...)
 //	accessService := service.NewAccessTokenService(config)
 //	token, err := accessService.CreateAccessToken(user)
-func NewAccessTokenService(config *lib.Config) *AccessTokenService {
+func NewAccessTokenService(config *lib.Config, opts ...Option) *AccessTokenService {
+	options := newServiceOptions(opts)
 	return &AccessTokenService{
-		config: config,
+		config: config.Clone(),
+		now:    options.clock,
+		audit:  options.audit,
+	}
+}
+
+// SetAuditLogger configures the logger receiving the service audit events.
+// A nil logger disables auditing.
+func (at *AccessTokenService) SetAuditLogger(logger lib.AuditLogger) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.audit = logger
+}
+
+// SetEpochService enables token epochs: the current epoch is embedded in new
+// tokens and tokens issued before the current epoch are rejected with
+// ErrTokenEpochRevoked. Creation and verification then query Redis.
+// A nil service disables epochs.
+func (at *AccessTokenService) SetEpochService(epochs *TokenEpochService) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.epochs = epochs
+}
+
+// SetJTIStore enables one-time-use tokens (CreateOneTimeAccessToken): their jti is
+// recorded on the first successful verification and later verifications fail with
+// ErrTokenReplayed. Verifying them then writes to Redis. Without a store, one-time-use
+// tokens are rejected, so every replica verifying them needs one.
+// A nil store disables one-time-use tokens.
+func (at *AccessTokenService) SetJTIStore(store *JTIStore) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.jtis = store
+}
+
+func (at *AccessTokenService) jtiStore() *JTIStore {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.jtis
+}
+
+// SetReplayMetricsHook configures the hook receiving the tokens presented after
+// their revocation or expiration (attempted replays): expired tokens, tokens
+// revoked by an epoch (SetEpochService) and one-time-use tokens presented again
+// (SetJTIStore). A nil hook disables the reporting.
+func (at *AccessTokenService) SetReplayMetricsHook(hook lib.ReplayMetricsHook) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.replays = hook
+}
+
+func (at *AccessTokenService) replayMetricsHook() lib.ReplayMetricsHook {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.replays
+}
+
+// AddClaimValidator registers a validator run by VerifyAccessToken on valid tokens,
+// after the built-in checks and the previously registered validators.
+// The first failing validator rejects the token with a *ClaimValidationError
+// carrying its name. Validators can be registered while tokens are verified.
+//
+// Parameters:
+//   - name: Validator name, reported in ClaimValidationError.Validator
+//   - validator: The validator (StepUpRequirement is one)
+//
+// Example:
+//
+//	accessService.AddClaimValidator("tenant", requireTenant)
+//	accessService.AddClaimValidator("mfa", service.StepUpRequirement{ACR: modelAuth.AssuranceLevel2})
+//
+//	_, err := accessService.VerifyAccessToken(token)
+//	var rejected *service.ClaimValidationError
+//	if errors.As(err, &rejected) {
+//	    log.Printf("token rejected by %s: %v", rejected.Validator, rejected.Err)
+//	}
+func (at *AccessTokenService) AddClaimValidator(name string, validator ClaimValidator) {
+	if validator == nil {
+		return
 	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.validators = append(at.validators, namedClaimValidator{name: name, validator: validator})
+}
+
+// SetClaimSchema configures the JSON Schema of the custom claims (see NewClaimSchema).
+// Access tokens whose custom claims do not match it are not created (*ClaimSchemaError,
+// matching ErrClaimSchemaViolation) and are rejected on verification with a
+// *ClaimValidationError of the "schema" validator, matching both ErrClaimRejected
+// and ErrClaimSchemaViolation. A nil schema disables the checks.
+//
+// The schema applies to every access token, custom claims or not: tokens created
+// without custom claims are checked as an empty object.
+//
+// Example:
+//
+//	accessService.SetClaimSchema(schema)
+//
+//	_, err := accessService.CreateAccessTokenWithClaims(user, map[string]any{"tenant": "ACME"})
+//	var violation *service.ClaimSchemaError
+//	if errors.As(err, &violation) {
+//	    log.Printf("claim %s %s", violation.Path, violation.Reason) // claim /tenant must match ^[a-z0-9-]+$
+//	}
+func (at *AccessTokenService) SetClaimSchema(schema *ClaimSchema) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.schema = schema
+}
+
+func (at *AccessTokenService) claimSchema() *ClaimSchema {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.schema
+}
+
+func (at *AccessTokenService) auditLogger() lib.AuditLogger {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.audit
+}
+
+func (at *AccessTokenService) epochService() *TokenEpochService {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.epochs
+}
+
+// claimValidators returns the registered validators. Validators are only
+// appended, so the returned slice can be read after the lock is released.
+func (at *AccessTokenService) claimValidators() []namedClaimValidator {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.validators
 }
 
 // CreateAccessToken generates a new JWT access token for an authenticated user.
-// The token is signed with HS256 and includes standard JWT claims plus custom email field.
+// The token is signed with JWTAlgorithm (HS256 by default) and includes standard JWT claims plus custom email field.
 //
 // Token structure (RFC 7519 compliant):
 //   - KeyType: "access" (distinguishes from other token types)
@@ -78,41 +238,282 @@ func NewAccessTokenService(config *lib.Config) *AccessTokenService {
 //	}
 //	// Send token to client: {"access_token": "eyJhbGciOi..."}
 func (at *AccessTokenService) CreateAccessToken(user *modelAuth.User) (string, error) {
-	duration, err := time.ParseDuration(at.config.JWTExpiry)
+	return at.CreateAccessTokenWithAuthentication(user, nil)
+}
+
+// CreateAccessTokenWithAuthentication generates a JWT access token recording how
+// and when the user authenticated, so that sensitive routes can require step-up
+// authentication (see StepUpRequirement).
+//
+// Additional claims:
+//   - amr: Authentication methods used (e.g. ["pwd", "otp"])
+//   - acr: Assurance level, derived from the methods when not set
+//   - auth_time: Authentication time, token creation time when not set
+//
+// Parameters:
+//   - user: Authenticated user containing ID and Email
+//   - authentication: Factors used and authentication time (nil behaves like CreateAccessToken)
+//
+// Returns:
+//   - string: Signed JWT token (format: header.payload.signature)
+//   - error: Token generation or signing errors
+//
+// Example:
+//
+//	// User just confirmed an OTP after their password
+//	token, err := accessService.CreateAccessTokenWithAuthentication(user, &modelAuth.Authentication{
+//	    Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodOTP},
+//	})
+func (at *AccessTokenService) CreateAccessTokenWithAuthentication(user *modelAuth.User, authentication *modelAuth.Authentication) (string, error) {
+	claim, err := at.newClaim(user, authentication)
+	if err != nil {
+		return "", err
+	}
+	return at.sign(claim)
+}
+
+// CreateAccessTokenForAudience generates a JWT access token restricted to one audience
+// (API or service), recorded in the "aud" claim. APIs check it with RequireAudience.
+//
+// Parameters:
+//   - user: Authenticated user containing ID and Email
+//   - audience: The audience, e.g. "billing-api"
+//
+// Returns:
+//   - string: Signed JWT token (format: header.payload.signature)
+//   - error: Validation, token generation or signing errors
+//
+// Example:
+//
+//	token, err := accessService.CreateAccessTokenForAudience(user, "billing-api")
+func (at *AccessTokenService) CreateAccessTokenForAudience(user *modelAuth.User, audience string) (string, error) {
+	if audience == "" {
+		return "", errors.New("invalid audience")
+	}
+
+	claim, err := at.newClaim(user, nil)
+	if err != nil {
+		return "", err
+	}
+	claim.Audience = jwt.ClaimStrings{audience}
+	return at.sign(claim)
+}
+
+// CreateOneTimeAccessToken generates a JWT access token accepted once by
+// VerifyAccessToken, for short-lived actions (confirm an email change, approve a
+// payment...). The token carries the "one_time_use" claim and its jti is recorded
+// in the JTIStore on its first successful verification: later verifications fail
+// with ErrTokenReplayed, on every replica sharing the store.
+//
+// Parameters:
+//   - user: Authenticated user containing ID and Email
+//   - ttl: Token lifetime, capped to JWTExpiry (0 uses JWTExpiry)
+//
+// Returns:
+//   - string: Signed JWT token (format: header.payload.signature)
+//   - error: Missing JTIStore (SetJTIStore), validation, token generation or signing errors
+//
+// Example:
+//
+//	accessService.SetJTIStore(jtiStore)
+//	token, err := accessService.CreateOneTimeAccessToken(user, 5*time.Minute)
+//	// Later, on the action endpoint
+//	claim, err := accessService.VerifyAccessToken(token)
+//	if errors.Is(err, service.ErrTokenReplayed) {
+//	    return errors.New("action already confirmed")
+//	}
+func (at *AccessTokenService) CreateOneTimeAccessToken(user *modelAuth.User, ttl time.Duration) (string, error) {
+	if ttl < 0 {
+		return "", errors.New("invalid ttl")
+	}
+	if at.jtiStore() == nil {
+		return "", errors.New("jti store is not set")
+	}
+
+	claim, err := at.newClaim(user, nil)
+	if err != nil {
+		return "", err
+	}
+	if ttl > 0 {
+		if expiresAt := claim.IssuedAt.Add(ttl); expiresAt.Before(claim.ExpiresAt.Time) {
+			claim.ExpiresAt = jwt.NewNumericDate(expiresAt)
+		}
+	}
+	claim.OneTimeUse = true
+	return at.sign(claim)
+}
+
+// CreateImpersonationToken generates a JWT access token for user, issued to an
+// administrator (actor) acting on their behalf. The actor is recorded in the
+// "act" claim (RFC 8693) so that verifiers can detect impersonation with
+// Claim.IsImpersonated, and an audit event is emitted on issuance.
+//
+// Parameters:
+//   - ctx: Context for the audit event (uses Background if nil)
+//   - actor: The administrator acting as the user
+//   - user: The impersonated user, set as token subject
+//
+// Returns:
+//   - string: Signed JWT token (format: header.payload.signature)
+//   - error: Validation, token generation or signing errors
+//
+// Example:
+//
+//	token, err := accessService.CreateImpersonationToken(ctx, admin, targetUser)
+//	if err != nil {
+//	    return err
+//	}
+//	// Later, on verification
+//	if claim.IsImpersonated() {
+//	    log.Printf("request by %s on behalf of %s", claim.Actor.Subject, claim.Subject)
+//	}
+func (at *AccessTokenService) CreateImpersonationToken(ctx context.Context, actor *modelAuth.User, user *modelAuth.User) (string, error) {
+	if actor == nil || actor.ID == "" {
+		return "", errors.New("invalid actor")
+	}
+	if user == nil || user.ID == "" {
+		return "", ErrInvalidUserID
+	}
+	if actor.ID == user.ID {
+		return "", errors.New("actor cannot impersonate themselves")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	claim, err := at.newClaim(user, nil)
+	if err != nil {
+		return "", err
+	}
+	claim.Actor = &modelAuth.Actor{
+		Subject: actor.ID,
+		Email:   actor.Email,
+	}
+
+	token, err := at.sign(claim)
 	if err != nil {
 		return "", err
 	}
 
-	claim := modelAuth.Claim{
+	emitAudit(ctx, at.auditLogger(), AuditEventAccessTokenImpersonation, user.ID, map[string]string{
+		"actor_id":          actor.ID,
+		"jti":               claim.ID,
+		"token_fingerprint": lib.TokenFingerprint(token),
+	})
+
+	return token, nil
+}
+
+// newClaim builds the access token claims of user, with the step-up markers of authentication if any.
+func (at *AccessTokenService) newClaim(user *modelAuth.User, authentication *modelAuth.Authentication) (*modelAuth.Claim, error) {
+	duration, err := time.ParseDuration(at.config.JWTExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := at.now()
+	claim := &modelAuth.Claim{
 		KeyType: "access",
 		Email:   user.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    at.config.Issuer,
 			Subject:   user.ID,
 			ID:        uuid.New().String(),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	return token.SignedString([]byte(at.config.JWTSecret))
+	if authentication != nil {
+		for _, method := range authentication.Methods {
+			claim.AMR = append(claim.AMR, string(method))
+		}
+		claim.ACR = authentication.AssuranceLevel()
+		authTime := authentication.Time
+		if authTime.IsZero() {
+			authTime = now
+		}
+		claim.AuthTime = jwt.NewNumericDate(authTime)
+	}
+	if epochs := at.epochService(); epochs != nil {
+		// Access token methods take no context, epochs are single Redis reads
+		claim.Epoch, claim.UserEpoch, err = epochs.epochs(context.Background(), user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return claim, nil
+}
+
+// jwtSigningMethods are the algorithms of Config.JWTAlgorithm. Tokens are signed with
+// JWTSecret, so only HMAC algorithms are supported.
+var jwtSigningMethods = map[string]jwt.SigningMethod{
+	jwt.SigningMethodHS256.Alg(): jwt.SigningMethodHS256,
+	jwt.SigningMethodHS384.Alg(): jwt.SigningMethodHS384,
+	jwt.SigningMethodHS512.Alg(): jwt.SigningMethodHS512,
+}
+
+// jwtSigningMethod returns the algorithm of Config.JWTAlgorithm (default: HS256), the
+// only one accepted on verification, so that "none" and algorithm substitutions are
+// rejected whatever the token declares.
+func jwtSigningMethod(config *lib.Config) (jwt.SigningMethod, error) {
+	if config.JWTAlgorithm == "" {
+		return jwt.SigningMethodHS256, nil
+	}
+	method, ok := jwtSigningMethods[config.JWTAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %q", config.JWTAlgorithm)
+	}
+	return method, nil
+}
+
+// sign signs the claims with the configured JWT algorithm and secret,
+// enforcing the claim schema and Config.AccessTokenMaxSize when set.
+func (at *AccessTokenService) sign(claim *modelAuth.Claim) (string, error) {
+	if schema := at.claimSchema(); schema != nil {
+		if err := schema.Validate(claim.Custom); err != nil {
+			return "", err
+		}
+	}
+
+	method, err := jwtSigningMethod(at.config)
+	if err != nil {
+		return "", err
+	}
+	token, err := jwt.NewWithClaims(method, claim).SignedString([]byte(at.config.JWTSecret))
+	if err != nil {
+		return "", err
+	}
+	if at.config.AccessTokenMaxSize > 0 && len(token) > at.config.AccessTokenMaxSize {
+		return "", fmt.Errorf("%w: %d bytes, maximum is %d", ErrAccessTokenTooLarge, len(token), at.config.AccessTokenMaxSize)
+	}
+	return token, nil
 }
 
 // VerifyAccessToken validates and parses a JWT access token.
 // Verification includes signature check, expiration, and claim structure validation.
 //
 // Verification process:
-//  1. Parse JWT and verify HS256 signature using JWTSecret
+//  1. Parse JWT and verify the signature using JWTSecret, rejecting any algorithm but JWTAlgorithm
+//     ("none", RS256 with the secret as public key...)
 //  2. Check expiration with 5-second leeway (clock skew tolerance)
-//  3. Validate claim structure matches expected format
-//  4. Return parsed claims if valid
+//  3. Validate claim structure matches expected format (key_type "access", so ID tokens are rejected)
+//  4. Reject tokens issued before the current global or user epoch (ErrTokenEpochRevoked), when enabled
+//  5. Check the custom claims against the claim schema, when set (*ClaimValidationError of "schema")
+//  6. Run the registered claim validators in order (*ClaimValidationError, matching ErrClaimRejected)
+//  7. Consume the jti of one-time-use tokens (ErrTokenReplayed when already consumed), last so
+//     that tokens failing another check are not consumed
+//  8. Return parsed claims if valid
 //
 // Special handling:
 //   - If token is expired (jwt.ErrTokenExpired), claims are still returned
 //     along with the error, allowing the caller to extract Subject (user ID) for
 //     refresh token verification
 //
+// The epoch and jti lookups run with a background context; VerifyAccessTokenContext
+// bounds them with the context of the request.
+//
 // Parameters:
 //   - token: JWT access token string to verify
 //
@@ -134,9 +535,52 @@ func (at *AccessTokenService) CreateAccessToken(user *modelAuth.User) (string, e
 //	// Token valid - proceed with authenticated request
 //	userID := claim.Subject
 func (at *AccessTokenService) VerifyAccessToken(token string) (*modelAuth.Claim, error) {
+	return at.VerifyAccessTokenContext(context.Background(), token)
+}
+
+// VerifyAccessTokenContext is VerifyAccessToken with a context for the Redis lookups
+// of the verification: the token epochs, when enabled, and the JTIStore of one-time-use
+// tokens. Cancelling the context fails the verification instead of waiting on Redis.
+//
+// Parameters:
+//   - ctx: Context for the Redis lookups (uses Background if nil)
+//   - token: JWT access token string to verify
+//
+// Returns:
+//   - *modelAuth.Claim: Parsed token claims (nil if invalid)
+//   - error: The errors of VerifyAccessToken, or the context error
+//
+// Example:
+//
+//	claim, err := accessService.VerifyAccessTokenContext(r.Context(), tokenString)
+func (at *AccessTokenService) VerifyAccessTokenContext(ctx context.Context, token string) (*modelAuth.Claim, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	claim, err := at.verifyAccessToken(ctx, token)
+	if hook := at.replayMetricsHook(); hook != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			hook.ObserveReplay(lib.TokenTypeAccess, lib.ReplayReasonExpired)
+		case errors.Is(err, ErrTokenEpochRevoked):
+			hook.ObserveReplay(lib.TokenTypeAccess, lib.ReplayReasonRevoked)
+		case errors.Is(err, ErrTokenReplayed):
+			hook.ObserveReplay(lib.TokenTypeAccess, lib.ReplayReasonConsumed)
+		}
+	}
+	return claim, err
+}
+
+func (at *AccessTokenService) verifyAccessToken(ctx context.Context, token string) (*modelAuth.Claim, error) {
+	method, err := jwtSigningMethod(at.config)
+	if err != nil {
+		return nil, err
+	}
+
 	t, err := jwt.ParseWithClaims(token, &modelAuth.Claim{}, func(token *jwt.Token) (any, error) {
 		return []byte(at.config.JWTSecret), nil
-	}, jwt.WithLeeway(5*time.Second))
+	}, jwt.WithLeeway(accessTokenLeeway), jwt.WithTimeFunc(at.now), jwt.WithValidMethods([]string{method.Alg()}))
 
 	if err != nil {
 		// Specific case if the token is expired (to check if refresh is possible)
@@ -146,9 +590,65 @@ func (at *AccessTokenService) VerifyAccessToken(token string) (*modelAuth.Claim,
 		return nil, err
 	}
 
-	if claim, ok := t.Claims.(*modelAuth.Claim); ok && t.Valid {
+	if claim, ok := t.Claims.(*modelAuth.Claim); ok && t.Valid && claim.KeyType == "access" {
+		if err := at.checkEpoch(ctx, claim); err != nil {
+			return nil, err
+		}
+		if schema := at.claimSchema(); schema != nil {
+			if err := schema.Validate(claim.Custom); err != nil {
+				return nil, &ClaimValidationError{Validator: "schema", Err: err}
+			}
+		}
+		if err := validateClaim(at.claimValidators(), claim); err != nil {
+			return nil, err
+		}
+		if err := at.consumeOneTimeUse(ctx, claim); err != nil {
+			return nil, err
+		}
 		return claim, nil
 	}
 
-	return nil, fmt.Errorf("invalid token claim")
+	return nil, ErrInvalidTokenClaim
+}
+
+// checkEpoch rejects tokens issued before the current global or user epoch, when epochs are enabled.
+func (at *AccessTokenService) checkEpoch(ctx context.Context, claim *modelAuth.Claim) error {
+	epochs := at.epochService()
+	if epochs == nil {
+		return nil
+	}
+
+	epoch, userEpoch, err := epochs.epochs(ctx, claim.Subject)
+	if err != nil {
+		return err
+	}
+	if claim.Epoch < epoch || claim.UserEpoch < userEpoch {
+		return ErrTokenEpochRevoked
+	}
+	return nil
+}
+
+// consumeOneTimeUse records the jti of one-time-use tokens, rejecting those already
+// consumed, and those verified without a JTIStore.
+func (at *AccessTokenService) consumeOneTimeUse(ctx context.Context, claim *modelAuth.Claim) error {
+	if !claim.OneTimeUse {
+		return nil
+	}
+	jtis := at.jtiStore()
+	if jtis == nil {
+		return errors.New("one-time-use token verified without a jti store")
+	}
+	if claim.ExpiresAt == nil {
+		return ErrInvalidTokenClaim
+	}
+
+	ttl := max(claim.ExpiresAt.Sub(at.now()), 0) + accessTokenLeeway
+	first, err := jtis.Consume(ctx, claim.ID, ttl)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrTokenReplayed
+	}
+	return nil
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+// defaultAccessTokenMaxClaimsSize is the default JSON size budget of custom claims, in bytes.
+const defaultAccessTokenMaxClaimsSize int = 1024
+
+var (
+	// ErrReservedClaim is returned when a custom claim uses a name set by the
+	// library (see modelAuth.ReservedClaimNames).
+	ErrReservedClaim = errors.New("reserved claim name")
+	// ErrClaimNotAllowed is returned when a custom claim is not listed in
+	// Config.AccessTokenAllowedClaims.
+	ErrClaimNotAllowed = errors.New("claim not allowed")
+	// ErrClaimsTooLarge is returned when the custom claims exceed Config.AccessTokenMaxClaimsSize.
+	ErrClaimsTooLarge = errors.New("custom claims too large")
+	// ErrAccessTokenTooLarge is returned when a signed access token exceeds Config.AccessTokenMaxSize.
+	ErrAccessTokenTooLarge = errors.New("access token too large")
+)
+
+// CreateAccessTokenWithClaims generates a JWT access token carrying application
+// claims (e.g. tenant, roles) next to the standard ones, returned in Claim.Custom
+// by VerifyAccessToken.
+//
+// Guardrails, checked before signing:
+//   - Names must not be reserved (ErrReservedClaim) and, when Config.AccessTokenAllowedClaims
+//     is set, must be listed there (ErrClaimNotAllowed)
+//   - The JSON encoding of the claims must fit in Config.AccessTokenMaxClaimsSize (ErrClaimsTooLarge)
+//   - The signed token must fit in Config.AccessTokenMaxSize when set (ErrAccessTokenTooLarge)
+//
+// Parameters:
+//   - user: Authenticated user containing ID and Email
+//   - claims: Custom claims, JSON-serializable values
+//
+// Returns:
+//   - string: Signed JWT token (format: header.payload.signature)
+//   - error: Guardrail, token generation or signing errors
+//
+// Example:
+//
+//	token, err := accessService.CreateAccessTokenWithClaims(user, map[string]any{
+//	    "tenant": "acme",
+//	    "roles":  []string{"admin"},
+//	})
+//	if errors.Is(err, service.ErrClaimsTooLarge) {
+//	    // Keep the roles server-side
+//	}
+func (at *AccessTokenService) CreateAccessTokenWithClaims(user *modelAuth.User, claims map[string]any) (string, error) {
+	if user == nil || user.ID == "" {
+		return "", ErrInvalidUserID
+	}
+	if err := at.checkCustomClaims(claims); err != nil {
+		return "", err
+	}
+
+	claim, err := at.newClaim(user, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(claims) > 0 {
+		claim.Custom = claims
+	}
+	return at.sign(claim)
+}
+
+// checkCustomClaims applies the claim name and size guardrails.
+// Names are checked in sorted order so that errors are deterministic.
+func (at *AccessTokenService) checkCustomClaims(claims map[string]any) error {
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "" || modelAuth.IsReservedClaimName(name) {
+			return fmt.Errorf("%w: %q", ErrReservedClaim, name)
+		}
+		if len(at.config.AccessTokenAllowedClaims) > 0 && !slices.Contains(at.config.AccessTokenAllowedClaims, name) {
+			return fmt.Errorf("%w: %q", ErrClaimNotAllowed, name)
+		}
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return fmt.Errorf("invalid custom claims: %w", err)
+	}
+	budget := at.config.AccessTokenMaxClaimsSize
+	if budget <= 0 {
+		budget = defaultAccessTokenMaxClaimsSize
+	}
+	if len(data) > budget {
+		return fmt.Errorf("%w: %d bytes, budget is %d", ErrClaimsTooLarge, len(data), budget)
+	}
+
+	return nil
+}
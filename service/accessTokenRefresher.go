@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrAudienceNotAllowed is returned by AccessTokenRefresher when the refresh token
+// is valid but not scoped to the requested audience.
+var ErrAudienceNotAllowed = errors.New("audience not allowed")
+
+// ClaimsLoaderFunc loads the current custom claims of a user (e.g. roles, tenant,
+// plan) from the source of truth of the application, called on each refresh.
+// Returning nil claims mints a token without custom claims.
+type ClaimsLoaderFunc func(ctx context.Context, user *modelAuth.User) (map[string]any, error)
+
+// AccessTokenRefresher exchanges refresh tokens for access tokens, honoring the
+// audiences refresh tokens are scoped to (see CreateScopedRefreshToken), so that a
+// refresh token issued for one API cannot mint access tokens for another.
+//
+// With a claims loader (see SetClaimsLoader), the custom claims of each new access
+// token are loaded fresh, so that permission changes apply at the next refresh
+// instead of being carried forward from the previous token.
+type AccessTokenRefresher struct {
+	refresh *RefreshTokenService
+	access  *AccessTokenService
+
+	// mu guards the claims loader, which can be set while tokens are refreshed.
+	mu     sync.RWMutex
+	claims ClaimsLoaderFunc
+}
+
+// NewAccessTokenRefresher creates a refresher over the given services.
+// Returns an error if a service is nil.
+//
+// Example:
+//
+//	refresher, err := service.NewAccessTokenRefresher(refreshService, accessService)
+func NewAccessTokenRefresher(refresh *RefreshTokenService, access *AccessTokenService) (*AccessTokenRefresher, error) {
+	if refresh == nil {
+		return nil, errors.New("refresh token service is nil")
+	}
+	if access == nil {
+		return nil, errors.New("access token service is nil")
+	}
+
+	return &AccessTokenRefresher{
+		refresh: refresh,
+		access:  access,
+	}, nil
+}
+
+// SetClaimsLoader configures the loader of the custom claims of refreshed access
+// tokens, checked by the guardrails of CreateAccessTokenWithClaims. A nil loader
+// mints tokens without custom claims.
+//
+// Example:
+//
+//	refresher.SetClaimsLoader(func(ctx context.Context, user *modelAuth.User) (map[string]any, error) {
+//	    roles, err := users.Roles(ctx, user.ID)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return map[string]any{"roles": roles}, nil
+//	})
+func (atr *AccessTokenRefresher) SetClaimsLoader(loader ClaimsLoaderFunc) {
+	atr.mu.Lock()
+	defer atr.mu.Unlock()
+	atr.claims = loader
+}
+
+func (atr *AccessTokenRefresher) claimsLoader() ClaimsLoaderFunc {
+	atr.mu.RLock()
+	defer atr.mu.RUnlock()
+	return atr.claims
+}
+
+// Refresh verifies the refresh token and creates an access token for audience,
+// with the custom claims of the claims loader when one is set.
+//
+// Audience rules:
+//   - Empty audience: plain access token, refused for scoped refresh tokens
+//   - Otherwise: access token with the "aud" claim, refused when the refresh
+//     token is scoped to other audiences
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - user: The refresh token owner, subject of the access token
+//   - refreshToken: The refresh token presented by the client
+//   - audience: The API the access token is requested for, empty for none
+//
+// Returns:
+//   - string: Signed JWT access token
+//   - error: "invalid refresh token", ErrAudienceNotAllowed, or the errors of
+//     VerifyRefreshToken, the claims loader and access token creation
+//
+// Example:
+//
+//	accessToken, err := refresher.Refresh(ctx, user, refreshToken, r.FormValue("audience"))
+//	if errors.Is(err, service.ErrAudienceNotAllowed) {
+//	    w.WriteHeader(http.StatusForbidden)
+//	    return
+//	}
+func (atr *AccessTokenRefresher) Refresh(ctx context.Context, user *modelAuth.User, refreshToken string, audience string) (string, error) {
+	if user == nil || user.ID == "" {
+		return "", ErrInvalidUserID
+	}
+
+	record, err := atr.refresh.lookupRefreshToken(ctx, user.ID, refreshToken, "")
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		return "", errors.New("invalid refresh token")
+	}
+
+	if audience == "" {
+		if len(record.Audiences) > 0 {
+			return "", ErrAudienceNotAllowed
+		}
+	} else if !record.allowsAudience(audience) {
+		return "", ErrAudienceNotAllowed
+	}
+
+	loader := atr.claimsLoader()
+	if loader == nil {
+		if audience == "" {
+			return atr.access.CreateAccessToken(user)
+		}
+		return atr.access.CreateAccessTokenForAudience(user, audience)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	claims, err := loader(ctx, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to load claims: %w", err)
+	}
+	if err := atr.access.checkCustomClaims(claims); err != nil {
+		return "", err
+	}
+
+	claim, err := atr.access.newClaim(user, nil)
+	if err != nil {
+		return "", err
+	}
+	if audience != "" {
+		claim.Audience = jwt.ClaimStrings{audience}
+	}
+	if len(claims) > 0 {
+		claim.Custom = claims
+	}
+	return atr.access.sign(claim)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// redisACLCategories are the command categories granted to the application user:
+// the services read, write, expire and scan keys, run transactions and Lua scripts,
+// and publish issued tokens. Dangerous commands (FLUSHDB, KEYS, CONFIG, ...) are denied.
+var redisACLCategories = []string{
+	"-@all",
+	"+@read", "+@write", "+@keyspace", "+@transaction", "+@scripting", "+@pubsub", "+@connection",
+	"-@dangerous",
+}
+
+// RedisACLSetUser returns the Redis ACL SETUSER command restricting an application user
+// to the keys and Pub/Sub channels of the services, for databases shared with other
+// applications or operators. Passwords are left unchanged: set them separately
+// (">password" rule, or in the ACL file).
+//
+// The keys are restricted to the WithKeyPrefix namespace, which is required. Create
+// lib.DistributedLock (e.g. CleanupOptions.Lock) and lib.LeaderElection with the same
+// prefix (lib.WithLockKeyPrefix), or their keys are denied.
+//
+// Parameters:
+//   - username: Redis user of the application
+//   - opts: The options given to the services (WithKeyPrefix)
+//
+// Returns:
+//   - string: The command, e.g. "ACL SETUSER tokens on resetkeys ~myapp:* resetchannels &myapp:* -@all +@read ..."
+//   - error: Validation errors
+//
+// Example:
+//
+//	// Printed by a setup command, run by an operator with redis-cli
+//	command, err := service.RedisACLSetUser("tokens", service.WithKeyPrefix("myapp"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(command)
+func RedisACLSetUser(username string, opts ...Option) (string, error) {
+	if username == "" || strings.ContainsAny(username, " \t\r\n") {
+		return "", errors.New("invalid redis username")
+	}
+
+	prefix := newServiceOptions(opts).keyPrefix
+	if prefix == "" {
+		return "", errors.New("a key prefix is required to restrict the keys of the user")
+	}
+	if strings.ContainsAny(string(prefix), " \t\r\n*?[]\\") {
+		return "", fmt.Errorf("invalid key prefix: %q", prefix)
+	}
+
+	rules := []string{
+		"ACL", "SETUSER", username, "on",
+		"resetkeys", "~" + prefix.name("*"),
+		"resetchannels", "&" + prefix.name("*"),
+	}
+	rules = append(rules, redisACLCategories...)
+	return strings.Join(rules, " "), nil
+}
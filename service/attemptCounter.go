@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// attemptCounter tracks failed attempts per user in Redis within a time window.
+// It backs the OTP rate limiting and the login lockout logic.
+//
+// Redis key pattern:
+//   - Key: "{prefix}:{userID}", or "{prefix}:{user:{userID}}" with user hash tags
+//   - Value: counter (integer)
+//   - TTL: window, set when the first attempt is recorded
+type attemptCounter struct {
+	db       *redis.Client
+	prefix   string
+	window   time.Duration
+	hashTags bool
+}
+
+func newAttemptCounter(db *redis.Client, prefix string, window time.Duration) *attemptCounter {
+	return &attemptCounter{
+		db:     db,
+		prefix: prefix,
+		window: window,
+	}
+}
+
+func (ac *attemptCounter) key(userID string) string {
+	if ac.hashTags {
+		userID = userHashTag(userID)
+	}
+	return fmt.Sprintf("%s:%s", ac.prefix, userID)
+}
+
+// get returns the current number of attempts, 0 if none were recorded.
+func (ac *attemptCounter) get(ctx context.Context, userID string) (int, error) {
+	val, err := ac.db.Get(ctx, ac.key(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	attempts, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted attempts counter: %w", err)
+	}
+	return attempts, nil
+}
+
+// increment records one more attempt and returns the new count.
+// The first attempt creates the key with the window TTL; later attempts keep it.
+func (ac *attemptCounter) increment(ctx context.Context, userID string) (int, error) {
+	key := ac.key(userID)
+
+	// Create key with TTL atomically (no race condition between INCR and EXPIRE)
+	created, err := ac.db.SetNX(ctx, key, 1, ac.window).Result()
+	if err != nil {
+		return 0, err
+	}
+	if created {
+		return 1, nil
+	}
+
+	// Key exists with TTL already set, safe to increment
+	attempts, err := ac.db.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(attempts), nil
+}
+
+// incrementIn queues an increment of the counter in a transaction pipeline, creating
+// it with the window TTL like increment.
+func (ac *attemptCounter) incrementIn(ctx context.Context, pipe redis.Pipeliner, userID string) {
+	key := ac.key(userID)
+	pipe.SetNX(ctx, key, 0, ac.window)
+	pipe.Incr(ctx, key)
+}
+
+// reset sets the counter back to 0 with a fresh window TTL.
+func (ac *attemptCounter) reset(ctx context.Context, userID string) error {
+	return ac.db.Set(ctx, ac.key(userID), 0, ac.window).Err()
+}
+
+// revoke deletes the counter.
+func (ac *attemptCounter) revoke(ctx context.Context, userID string) error {
+	return ac.db.Del(ctx, ac.key(userID)).Err()
+}
+
+// revokeAll deletes the counters of all users.
+func (ac *attemptCounter) revokeAll(ctx context.Context) error {
+	_, err := ac.revokeAllInBatches(ctx, CleanupOptions{})
+	return err
+}
+
+// revokeAllInBatches deletes the counters of all users in paced batches and returns how many were deleted.
+func (ac *attemptCounter) revokeAllInBatches(ctx context.Context, opts CleanupOptions) (int64, error) {
+	opts.perKey = ac.hashTags
+	return deleteMatching(ctx, ac.db, fmt.Sprintf("%s:*", ac.prefix), opts)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+const (
+	AuditEventRefreshTokenGeoDenied      lib.AuditEventType = "refresh_token.geo_denied"
+	AuditEventRefreshTokenCountryChanged lib.AuditEventType = "refresh_token.country_changed"
+	AuditEventRefreshTokenCreated        lib.AuditEventType = "refresh_token.created"
+	AuditEventAccessTokenImpersonation   lib.AuditEventType = "access_token.impersonation_issued"
+	AuditEventAccessTokenExchanged       lib.AuditEventType = "access_token.exchanged"
+	AuditEventPasswordResetCreated       lib.AuditEventType = "password_reset.created"
+	AuditEventPasswordResetVerified      lib.AuditEventType = "password_reset.verified"
+	AuditEventRefreshTokenRevoked        lib.AuditEventType = "refresh_token.revoked"
+	AuditEventPasswordResetRevoked       lib.AuditEventType = "password_reset.revoked"
+	AuditEventTokenLeakReported          lib.AuditEventType = "token.leak_reported"
+	AuditEventEmergencyRevokeAll         lib.AuditEventType = "emergency.revoke_all"
+	AuditEventLoginLocked                lib.AuditEventType = "login.locked"
+	AuditEventLoginIPLocked              lib.AuditEventType = "login.ip_locked"
+
+	AuditEventPasswordResetQuotaExceeded lib.AuditEventType = "password_reset.quota_exceeded"
+	AuditEventCanaryTokenTriggered       lib.AuditEventType = "token.canary_triggered"
+	AuditEventUserDataErased             lib.AuditEventType = "user.data_erased"
+	AuditEventElevationGranted           lib.AuditEventType = "elevation.granted"
+	AuditEventOTPStaticCodeEnabled       lib.AuditEventType = "otp.static_code_enabled"
+)
+
+// emitAudit sends an audit event to the logger, enriched with the request
+// metadata found in ctx. A nil logger drops the event.
+func emitAudit(ctx context.Context, logger lib.AuditLogger, eventType lib.AuditEventType, userID string, details map[string]string) {
+	if logger == nil {
+		return
+	}
+
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	logger.LogAuditEvent(ctx, lib.AuditEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Timestamp: time.Now().UTC(),
+		Meta:      meta,
+		Details:   details,
+	})
+}
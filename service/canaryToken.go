@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// CanaryAlert describes a verification attempt against a canary token.
+//
+// Fields:
+//   - TokenType: Type of the canary token
+//   - UserID: User identifier the canary token was minted for
+//   - Label: Label given to the canary token, telling where it was planted
+//   - Fingerprint: Fingerprint of the presented token, see lib.TokenFingerprint
+//   - Timestamp: When the token was presented (UTC)
+//   - Meta: Client information from WithRequestMeta, zero value if absent
+type CanaryAlert struct {
+	TokenType   lib.TokenType
+	UserID      string
+	Label       string
+	Fingerprint string
+	Timestamp   time.Time
+	Meta        lib.RequestMeta
+}
+
+// CanaryHandler is called synchronously when a canary token is presented.
+// It should page or alert without blocking (e.g. send to a buffered channel).
+type CanaryHandler func(ctx context.Context, alert CanaryAlert)
+
+// CreateCanaryRefreshToken mints a refresh token that is never given to legitimate
+// users. Plant it where tokens could leak (database rows, logs, backups, configuration):
+// anyone presenting it learned it from that place. Verifications of a canary token
+// fail as for an unknown token, emit a "token.canary_triggered" audit event and call
+// the handler set with SetCanaryHandler.
+//
+// Canary tokens look like the other refresh tokens of the service, and are deleted by
+// the user-wide and global revocations like them.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier the token is minted for, ideally a dedicated decoy account
+//   - label: Where the token is planted (e.g. "users-table-backup"), reported in alerts
+//   - ttl: Lifetime of the token, e.g. the retention of the place it is planted in
+//
+// Returns:
+//   - *string: Pointer to the canary token
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	canary, err := refreshService.CreateCanaryRefreshToken(ctx, "decoy-admin", "db-backup-2026", 365*24*time.Hour)
+//	if err != nil {
+//	    return err
+//	}
+//	plantInBackup(*canary)
+func (rts *RefreshTokenService) CreateCanaryRefreshToken(ctx context.Context, userID string, label string, ttl time.Duration) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	if label == "" {
+		return nil, errors.New("canary label is empty")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("canary ttl must be positive")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, err := refreshTokenRecord{Canary: label}.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken(rts.config, lib.TokenTypeRefresh, rts.length.generated, lib.GenerateRandomString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rts.storeToken(ctx, userID, rts.storedToken(token), value, ttl); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// SetCanaryHandler configures the handler called when a canary token is presented,
+// in addition to the "token.canary_triggered" audit event. A nil handler disables it.
+func (rts *RefreshTokenService) SetCanaryHandler(handler CanaryHandler) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.canary = handler
+}
+
+func (rts *RefreshTokenService) canaryHandler() CanaryHandler {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.canary
+}
+
+// triggerCanary reports a verification attempt against a canary token.
+func (rts *RefreshTokenService) triggerCanary(ctx context.Context, userID string, token string, label string) {
+	fingerprint := lib.TokenFingerprint(rts.config.TokenNormalization.Normalize(token))
+	emitAudit(ctx, rts.auditLogger(), AuditEventCanaryTokenTriggered, userID, map[string]string{
+		"label":             label,
+		"token_type":        string(lib.TokenTypeRefresh),
+		"token_fingerprint": fingerprint,
+	})
+
+	if handler := rts.canaryHandler(); handler != nil {
+		meta, _ := lib.RequestMetaFromContext(ctx)
+		handler(ctx, CanaryAlert{
+			TokenType:   lib.TokenTypeRefresh,
+			UserID:      userID,
+			Label:       label,
+			Fingerprint: fingerprint,
+			Timestamp:   time.Now().UTC(),
+			Meta:        meta,
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrClaimSchemaViolation is matched (errors.Is) by every error returned when custom
+// claims do not match the claim schema of the access token service.
+var ErrClaimSchemaViolation = errors.New("claim schema violation")
+
+// claimSchemaAnnotations are the keywords accepted and ignored by NewClaimSchema.
+var claimSchemaAnnotations = []string{"$schema", "$id", "$comment", "title", "description", "default", "examples"}
+
+// claimSchemaTypes are the JSON Schema types.
+var claimSchemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// ClaimSchema is a compiled JSON Schema describing the custom claims of access tokens,
+// shared by the teams minting tokens (see AccessTokenService.SetClaimSchema).
+//
+// Supported keywords (a subset of JSON Schema 2020-12, enough for claims):
+//   - Any: type (name or list), enum, const
+//   - Objects: properties, required, additionalProperties (boolean or schema)
+//   - Arrays: items, minItems, maxItems
+//   - Strings: minLength, maxLength, pattern
+//   - Numbers: minimum, maximum
+//
+// Annotations ($schema, $id, $comment, title, description, default, examples) are
+// ignored. Other keywords are refused by NewClaimSchema rather than silently ignored,
+// so that a schema never validates less than its authors expect.
+type ClaimSchema struct {
+	root *claimSchemaNode
+}
+
+// claimSchemaNode is a compiled (sub)schema.
+type claimSchemaNode struct {
+	types      []string
+	enum       []any
+	constValue any
+	hasConst   bool
+
+	properties map[string]*claimSchemaNode
+	required   []string
+	additional *claimSchemaNode
+	closed     bool
+
+	items    *claimSchemaNode
+	minItems *int
+	maxItems *int
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum *float64
+	maximum *float64
+}
+
+// ClaimSchemaError tells which claim does not match the schema and why.
+// It matches ErrClaimSchemaViolation with errors.Is.
+//
+// Fields:
+//   - Path: JSON Pointer of the claim (e.g. "/roles/0"), empty for the claims object
+//   - Reason: What the schema expects (e.g. "must be a string")
+type ClaimSchemaError struct {
+	Path   string
+	Reason string
+}
+
+// Error returns "claim schema violation at {path}: {reason}".
+func (e *ClaimSchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s at %s: %s", ErrClaimSchemaViolation, path, e.Reason)
+}
+
+// Unwrap returns ErrClaimSchemaViolation.
+func (e *ClaimSchemaError) Unwrap() error {
+	return ErrClaimSchemaViolation
+}
+
+// NewClaimSchema compiles a JSON Schema of the custom claims. The root schema
+// describes the claims object.
+//
+// Parameters:
+//   - schema: The JSON Schema document
+//
+// Returns:
+//   - *ClaimSchema: The compiled schema
+//   - error: Invalid JSON, unsupported keywords or invalid keyword values
+//
+// Example:
+//
+//	schema, err := service.NewClaimSchema([]byte(`{
+//	    "type": "object",
+//	    "properties": {
+//	        "tenant": {"type": "string", "pattern": "^[a-z0-9-]+$"},
+//	        "roles": {"type": "array", "items": {"enum": ["viewer", "editor", "admin"]}}
+//	    },
+//	    "required": ["tenant"],
+//	    "additionalProperties": false
+//	}`))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	accessService.SetClaimSchema(schema)
+func NewClaimSchema(schema []byte) (*ClaimSchema, error) {
+	var document any
+	if err := json.Unmarshal(schema, &document); err != nil {
+		return nil, fmt.Errorf("invalid claim schema: %w", err)
+	}
+
+	root, err := compileClaimSchema(document, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(root.types) > 0 && !slices.Contains(root.types, "object") {
+		return nil, errors.New("invalid claim schema: the root schema must accept an object")
+	}
+	return &ClaimSchema{root: root}, nil
+}
+
+// Validate checks custom claims against the schema. Claims are compared in their
+// JSON form, as they appear in the token.
+//
+// Returns:
+//   - error: *ClaimSchemaError for the first violation (claims in sorted order), or
+//     JSON encoding errors
+func (s *ClaimSchema) Validate(claims map[string]any) error {
+	if claims == nil {
+		claims = map[string]any{}
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return fmt.Errorf("invalid custom claims: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid custom claims: %w", err)
+	}
+
+	return s.root.validate(value, "")
+}
+
+// compileClaimSchema compiles a schema found at path (a JSON Pointer in the schema).
+func compileClaimSchema(document any, path string) (*claimSchemaNode, error) {
+	if b, ok := document.(bool); ok {
+		// true accepts everything, false nothing
+		if b {
+			return &claimSchemaNode{}, nil
+		}
+		return &claimSchemaNode{types: []string{}}, nil
+	}
+	object, ok := document.(map[string]any)
+	if !ok {
+		return nil, schemaError(path, "a schema must be an object or a boolean")
+	}
+
+	node := &claimSchemaNode{}
+	keywords := make([]string, 0, len(object))
+	for keyword := range object {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		value := object[keyword]
+		at := path + "/" + keyword
+		var err error
+
+		switch keyword {
+		case "type":
+			node.types, err = compileSchemaTypes(value, at)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok {
+				return nil, schemaError(at, "must be an array")
+			}
+			node.enum = values
+		case "const":
+			node.constValue, node.hasConst = value, true
+		case "properties":
+			properties, ok := value.(map[string]any)
+			if !ok {
+				return nil, schemaError(at, "must be an object")
+			}
+			node.properties = make(map[string]*claimSchemaNode, len(properties))
+			for name, property := range properties {
+				if node.properties[name], err = compileClaimSchema(property, at+"/"+escapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			node.required, err = compileSchemaStrings(value, at)
+		case "additionalProperties":
+			if b, ok := value.(bool); ok {
+				node.closed = !b
+			} else {
+				node.additional, err = compileClaimSchema(value, at)
+			}
+		case "items":
+			node.items, err = compileClaimSchema(value, at)
+		case "minItems":
+			node.minItems, err = compileSchemaCount(value, at)
+		case "maxItems":
+			node.maxItems, err = compileSchemaCount(value, at)
+		case "minLength":
+			node.minLength, err = compileSchemaCount(value, at)
+		case "maxLength":
+			node.maxLength, err = compileSchemaCount(value, at)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, schemaError(at, "must be a string")
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, schemaError(at, err.Error())
+			}
+		case "minimum":
+			node.minimum, err = compileSchemaNumber(value, at)
+		case "maximum":
+			node.maximum, err = compileSchemaNumber(value, at)
+		default:
+			if !slices.Contains(claimSchemaAnnotations, keyword) {
+				return nil, schemaError(at, "unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func compileSchemaTypes(value any, path string) ([]string, error) {
+	if name, ok := value.(string); ok {
+		value = []any{name}
+	}
+	types, err := compileSchemaStrings(value, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range types {
+		if !slices.Contains(claimSchemaTypes, name) {
+			return nil, schemaError(path, fmt.Sprintf("unknown type %q", name))
+		}
+	}
+	return types, nil
+}
+
+func compileSchemaStrings(value any, path string) ([]string, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, schemaError(path, "must be an array of strings")
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, schemaError(path, "must be an array of strings")
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+func compileSchemaCount(value any, path string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, schemaError(path, "must be a non-negative integer")
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func compileSchemaNumber(value any, path string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, schemaError(path, "must be a number")
+	}
+	return &n, nil
+}
+
+// schemaError reports an invalid schema document.
+func schemaError(path string, reason string) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("invalid claim schema at %s: %s", path, reason)
+}
+
+// escapePointer escapes a name for a JSON Pointer (RFC 6901).
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// validate checks a JSON value (as decoded by encoding/json) found at path.
+func (n *claimSchemaNode) validate(value any, path string) error {
+	if n.types != nil && !slices.ContainsFunc(n.types, func(name string) bool { return hasSchemaType(value, name) }) {
+		if len(n.types) == 0 {
+			return &ClaimSchemaError{Path: path, Reason: "not allowed"}
+		}
+		return &ClaimSchemaError{Path: path, Reason: "must be of type " + strings.Join(n.types, " or ")}
+	}
+	if n.hasConst && !reflect.DeepEqual(value, n.constValue) {
+		return &ClaimSchemaError{Path: path, Reason: "must be " + schemaValue(n.constValue)}
+	}
+	if n.enum != nil && !slices.ContainsFunc(n.enum, func(allowed any) bool { return reflect.DeepEqual(value, allowed) }) {
+		values := make([]string, 0, len(n.enum))
+		for _, allowed := range n.enum {
+			values = append(values, schemaValue(allowed))
+		}
+		return &ClaimSchemaError{Path: path, Reason: "must be one of " + strings.Join(values, ", ")}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return n.validateObject(v, path)
+	case []any:
+		return n.validateArray(v, path)
+	case string:
+		return n.validateString(v, path)
+	case float64:
+		return n.validateNumber(v, path)
+	}
+	return nil
+}
+
+func (n *claimSchemaNode) validateObject(object map[string]any, path string) error {
+	for _, name := range n.required {
+		if _, ok := object[name]; !ok {
+			return &ClaimSchemaError{Path: path + "/" + escapePointer(name), Reason: "is required"}
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		at := path + "/" + escapePointer(name)
+		if property, ok := n.properties[name]; ok {
+			if err := property.validate(object[name], at); err != nil {
+				return err
+			}
+			continue
+		}
+		if n.closed {
+			return &ClaimSchemaError{Path: at, Reason: "is not allowed"}
+		}
+		if n.additional != nil {
+			if err := n.additional.validate(object[name], at); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n *claimSchemaNode) validateArray(array []any, path string) error {
+	if n.minItems != nil && len(array) < *n.minItems {
+		return &ClaimSchemaError{Path: path, Reason: fmt.Sprintf("must have at least %d items", *n.minItems)}
+	}
+	if n.maxItems != nil && len(array) > *n.maxItems {
+		return &ClaimSchemaError{Path: path, Reason: fmt.Sprintf("must have at most %d items", *n.maxItems)}
+	}
+	if n.items != nil {
+		for i, item := range array {
+			if err := n.items.validate(item, path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n *claimSchemaNode) validateString(s string, path string) error {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		return &ClaimSchemaError{Path: path, Reason: fmt.Sprintf("must be at least %d characters", *n.minLength)}
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		return &ClaimSchemaError{Path: path, Reason: fmt.Sprintf("must be at most %d characters", *n.maxLength)}
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		return &ClaimSchemaError{Path: path, Reason: "must match " + n.pattern.String()}
+	}
+	return nil
+}
+
+func (n *claimSchemaNode) validateNumber(f float64, path string) error {
+	if n.minimum != nil && f < *n.minimum {
+		return &ClaimSchemaError{Path: path, Reason: "must be >= " + strconv.FormatFloat(*n.minimum, 'g', -1, 64)}
+	}
+	if n.maximum != nil && f > *n.maximum {
+		return &ClaimSchemaError{Path: path, Reason: "must be <= " + strconv.FormatFloat(*n.maximum, 'g', -1, 64)}
+	}
+	return nil
+}
+
+// hasSchemaType tells whether a JSON value is of a JSON Schema type.
+func hasSchemaType(value any, name string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return name == "object"
+	case []any:
+		return name == "array"
+	case string:
+		return name == "string"
+	case float64:
+		return name == "number" || (name == "integer" && v == math.Trunc(v) && !math.IsInf(v, 0))
+	case bool:
+		return name == "boolean"
+	case nil:
+		return name == "null"
+	}
+	return false
+}
+
+// schemaValue formats a schema value for error messages.
+func schemaValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
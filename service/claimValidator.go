@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+// ErrClaimRejected is matched (errors.Is) by every error returned when a claim
+// validator rejects an access token.
+var ErrClaimRejected = errors.New("claim rejected")
+
+// ClaimValidator checks the claims of a verified access token, e.g. the tenant,
+// a token version held in a store or the authentication methods.
+// A non-nil error rejects the token.
+type ClaimValidator interface {
+	ValidateClaim(claim *modelAuth.Claim) error
+}
+
+// ClaimValidatorFunc adapts a function to the ClaimValidator interface.
+//
+// Example:
+//
+//	requireTenant := service.ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+//	    if !strings.HasSuffix(claim.Email, "@acme.com") {
+//	        return errors.New("wrong tenant")
+//	    }
+//	    return nil
+//	})
+type ClaimValidatorFunc func(claim *modelAuth.Claim) error
+
+// ValidateClaim calls f(claim).
+func (f ClaimValidatorFunc) ValidateClaim(claim *modelAuth.Claim) error {
+	return f(claim)
+}
+
+// RequireAudience returns a validator rejecting access tokens not issued for audience
+// (see CreateAccessTokenForAudience).
+//
+// Example:
+//
+//	accessService.AddClaimValidator("audience", service.RequireAudience("billing-api"))
+func RequireAudience(audience string) ClaimValidator {
+	return ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+		if !slices.Contains(claim.Audience, audience) {
+			return fmt.Errorf("audience %s required", audience)
+		}
+		return nil
+	})
+}
+
+// ClaimValidationError tells which validator rejected an access token and why.
+// It matches ErrClaimRejected as well as the validator error with errors.Is.
+//
+// Fields:
+//   - Validator: Name given to AddClaimValidator (e.g. "tenant")
+//   - Err: Error returned by the validator
+type ClaimValidationError struct {
+	Validator string
+	Err       error
+}
+
+// Error returns "claim rejected by {validator}: {err}".
+func (e *ClaimValidationError) Error() string {
+	return fmt.Sprintf("%s by %s: %v", ErrClaimRejected, e.Validator, e.Err)
+}
+
+// Unwrap returns ErrClaimRejected and the validator error.
+func (e *ClaimValidationError) Unwrap() []error {
+	return []error{ErrClaimRejected, e.Err}
+}
+
+// namedClaimValidator is a registered validator.
+type namedClaimValidator struct {
+	name      string
+	validator ClaimValidator
+}
+
+// validateClaim runs the validators in registration order and stops at the first failure.
+func validateClaim(validators []namedClaimValidator, claim *modelAuth.Claim) error {
+	for _, named := range validators {
+		if err := named.validator.ValidateClaim(claim); err != nil {
+			return &ClaimValidationError{Validator: named.name, Err: err}
+		}
+	}
+	return nil
+}
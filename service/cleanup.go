@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultCleanupBatchSize is the number of keys scanned and deleted per batch.
+const defaultCleanupBatchSize int = 500
+
+// CleanupOptions paces bulk revocations on large keyspaces, so that a single call
+// does not monopolize Redis.
+//
+// Fields:
+//   - BatchSize: Keys scanned and deleted per round trip (default: 500)
+//   - Pause: Sleep between two batches (default: none)
+//   - Progress: Called after each batch deleting keys with the total number of keys deleted so far
+//   - Report: Called after each SCAN page, even empty ones, with the keys scanned and deleted so far
+//     (with Parallelism above 1, once more at the end with the final counts)
+//   - Parallelism: Batches deleted concurrently while scanning goes on (default: 1, sequential)
+//   - Lock: Held during the revocation, so that a single replica runs it at a time; the call
+//     fails with lib.ErrLockNotAcquired if another replica holds it (default: none)
+//   - OperationTimeout: Deadline of each round trip (SCAN page, batch deletion), derived from
+//     the context of the call, so that a slow command fails the job instead of holding a
+//     pooled connection (default: none, the client timeouts apply)
+//
+// Example:
+//
+//	opts := service.CleanupOptions{
+//	    BatchSize: 1000,
+//	    Pause:     10 * time.Millisecond,
+//	    Progress:  func(deleted int64) { log.Printf("%d tokens revoked", deleted) },
+//	}
+type CleanupOptions struct {
+	BatchSize        int
+	Pause            time.Duration
+	Progress         func(deleted int64)
+	Report           func(stats CleanupStats)
+	Parallelism      int
+	Lock             *lib.DistributedLock
+	OperationTimeout time.Duration
+
+	// perKey deletes the keys of a batch with one DEL each (pipelined), for keys
+	// spread over Redis Cluster slots, where a multi-key DEL fails.
+	perKey bool
+	// except leaves the keys starting with it, for patterns also matching the keys
+	// of another store, e.g. "otp:*" matching "otp:attempts:{userID}".
+	except string
+}
+
+// operationContext returns the context of one round trip, bounded by OperationTimeout.
+func (opts CleanupOptions) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if opts.OperationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, opts.OperationTimeout)
+}
+
+// kept drops the keys starting with except.
+func (opts CleanupOptions) kept(keys []string) []string {
+	if opts.except == "" {
+		return keys
+	}
+	kept := keys[:0]
+	for _, key := range keys {
+		if !strings.HasPrefix(key, opts.except) {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
+// CleanupStats tells how far a bulk revocation went.
+//
+// Fields:
+//   - Scanned: Keys returned by SCAN so far (SCAN may return a key more than once)
+//   - Deleted: Keys deleted so far
+type CleanupStats struct {
+	Scanned int64
+	Deleted int64
+}
+
+// deleteMatching deletes the keys matching pattern batch by batch, following the
+// SCAN cursor until the end, and returns how many were deleted. It stops early
+// if ctx is cancelled, before each batch and during pauses, and stops scanning
+// after a failed batch, returning the errors of the batches in flight.
+func deleteMatching(ctx context.Context, db *redis.Client, pattern string, opts CleanupOptions) (int64, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	var lease *lib.Lease
+	if opts.Lock != nil {
+		var err error
+		if lease, err = opts.Lock.Acquire(ctx); err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = lease.Release(context.WithoutCancel(ctx))
+		}()
+	}
+
+	deletions := newBatchDeleter(ctx, db, opts)
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return deletions.wait(err)
+		}
+		if lease != nil {
+			if err := lease.Refresh(ctx); err != nil {
+				return deletions.wait(err)
+			}
+		}
+
+		opCtx, cancel := opts.operationContext(ctx)
+		keys, next, err := db.Scan(opCtx, cursor, pattern, int64(batchSize)).Result()
+		cancel()
+		if err != nil {
+			return deletions.wait(err)
+		}
+		if failed := deletions.scanned(keys); failed {
+			return deletions.wait(nil)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deletions.wait(nil)
+		}
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return deletions.wait(ctx.Err())
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+}
+
+// batchDeleter deletes the batches of keys found by deleteMatching, on up to
+// Parallelism goroutines, and keeps the statistics.
+type batchDeleter struct {
+	ctx  context.Context
+	db   *redis.Client
+	opts CleanupOptions
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mu    sync.Mutex
+	stats CleanupStats
+	errs  []error
+}
+
+func newBatchDeleter(ctx context.Context, db *redis.Client, opts CleanupOptions) *batchDeleter {
+	return &batchDeleter{ctx: ctx, db: db, opts: opts, sem: make(chan struct{}, max(opts.Parallelism, 1))}
+}
+
+// scanned deletes a SCAN page, in the background when Parallelism is above 1, then
+// reports the statistics. It returns true once a batch failed.
+func (d *batchDeleter) scanned(keys []string) bool {
+	keys = d.opts.kept(keys)
+	if len(keys) > 0 {
+		if cap(d.sem) == 1 {
+			d.delete(keys)
+		} else {
+			d.sem <- struct{}{}
+			d.wg.Add(1)
+			go func() {
+				defer func() {
+					<-d.sem
+					d.wg.Done()
+				}()
+				d.delete(keys)
+			}()
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stats.Scanned += int64(len(keys))
+	if d.opts.Report != nil {
+		d.opts.Report(d.stats)
+	}
+	return len(d.errs) > 0
+}
+
+func (d *batchDeleter) delete(keys []string) {
+	ctx, cancel := d.opts.operationContext(d.ctx)
+	defer cancel()
+
+	var n int64
+	var err error
+	if d.opts.perKey {
+		n, err = deleteEach(ctx, d.db, keys)
+	} else {
+		n, err = d.db.Del(ctx, keys...).Result()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		d.errs = append(d.errs, err)
+		return
+	}
+	d.stats.Deleted += n
+	if d.opts.Progress != nil {
+		d.opts.Progress(d.stats.Deleted)
+	}
+}
+
+// deleteEach deletes keys with a pipeline of single-key DEL commands.
+func deleteEach(ctx context.Context, db *redis.Client, keys []string) (int64, error) {
+	cmds, err := db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.(*redis.IntCmd).Val()
+	}
+	return n, nil
+}
+
+// wait waits for the batches in flight and returns the number of deleted keys with
+// err joined to the batch errors. The final statistics are reported once more when
+// batches ran in the background.
+func (d *batchDeleter) wait(err error) (int64, error) {
+	d.wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cap(d.sem) > 1 && d.opts.Report != nil {
+		d.opts.Report(d.stats)
+	}
+	return d.stats.Deleted, errors.Join(append(d.errs, err)...)
+}
+
+// escapeScanPattern escapes the glob characters of a Redis SCAN MATCH pattern,
+// for patterns built from untrusted input such as user IDs.
+func escapeScanPattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameDeletion is the Redis key prefix for deletion cancellation tokens.
+	// Key pattern: "account_deletion:{sha256(token)}" with the JSON record as value.
+	redisStoreNameDeletion string = "account_deletion"
+
+	// redisStoreNameDeletionPending is the Redis key prefix for the cancellation token of a user.
+	// Key pattern: "account_deletion_pending:{userID}" with the hash of the token as value.
+	redisStoreNameDeletionPending string = "account_deletion_pending"
+
+	// redisStoreNameDeletionSchedule is the Redis sorted set of scheduled deletions,
+	// user IDs scored by deletion time (Unix seconds). It has no TTL.
+	redisStoreNameDeletionSchedule string = "account_deletion_schedule"
+
+	defaultAccountDeletionGracePeriod string = "336h" // 14 days
+)
+
+// deletionPayload is the data stored with a cancellation token.
+type deletionPayload struct {
+	DeleteAt time.Time `json:"delete_at"`
+}
+
+// DeletionTokenService schedules account deletions after a grace period, during
+// which the user can cancel with the token sent to them (GDPR right to erasure).
+// The service only keeps the schedule: a periodic job lists the elapsed deletions
+// (ListElapsedDeletions), deletes the accounts and acknowledges them (CompleteDeletion),
+// which also erases the tokens of the user when SetUserDataService is configured.
+//
+// Key features:
+//   - Cancellation tokens valid for the grace period (StatefulToken)
+//   - One scheduled deletion per user: scheduling again replaces the token and date
+//   - Deletions listed in date order once their grace period elapsed
+//
+// Redis key patterns:
+//   - Token: "account_deletion:{sha256(token)}" → JSON record (user ID, deletion time)
+//   - Pending token: "account_deletion_pending:{userID}" → sha256 of the token
+//   - Schedule: "account_deletion_schedule" → sorted set of user IDs by deletion time, no TTL
+//   - TTL: Configured via AccountDeletionGracePeriod (default: 14 days)
+type DeletionTokenService struct {
+	db       *redis.Client
+	config   *lib.Config
+	now      func() time.Time
+	grace    time.Duration
+	tokens   *StatefulToken[deletionPayload]
+	pending  *ttlStore
+	schedule string
+
+	mu       sync.RWMutex
+	userData *UserDataService
+}
+
+// DeletionTokenServiceInterface defines the methods for scheduled account deletions.
+type DeletionTokenServiceInterface interface {
+	ScheduleDeletion(ctx context.Context, userID string) (*string, time.Time, error)
+	CancelDeletion(ctx context.Context, token string) (*string, error)
+	CancelUserDeletion(ctx context.Context, userID string) error
+	ScheduledDeletion(ctx context.Context, userID string) (time.Time, bool, error)
+	ListElapsedDeletions(ctx context.Context, limit int) ([]string, error)
+	CompleteDeletion(ctx context.Context, userID string) error
+}
+
+// NewDeletionTokenService creates a new deletion token service instance with Redis persistence.
+// Returns an error if the database client is nil or if AccountDeletionGracePeriod cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for tokens and schedule storage
+//   - config: Configuration containing AccountDeletionGracePeriod
+//   - opts: Optional settings (WithKeyPrefix, WithClock)
+//
+// Returns:
+//   - *DeletionTokenService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	deletionService, err := service.NewDeletionTokenService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewDeletionTokenService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*DeletionTokenService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	grace, err := parseDurationOrDefault(config.AccountDeletionGracePeriod, defaultAccountDeletionGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account deletion grace period format: %w", err)
+	}
+
+	tokens, err := NewStatefulToken[deletionPayload](ctx, db, redisStoreNameDeletion, grace, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newServiceOptions(opts)
+	service := &DeletionTokenService{
+		db:       db,
+		config:   config.Clone(),
+		now:      options.clock,
+		grace:    grace,
+		tokens:   tokens,
+		pending:  newTTLStore(db, options.keyPrefix.name(redisStoreNameDeletionPending), grace),
+		schedule: options.keyPrefix.name(redisStoreNameDeletionSchedule),
+	}
+
+	return service, nil
+}
+
+// SetUserDataService cascades account deletions to the token services: CompleteDeletion
+// erases the tokens, OTP state and revocation logs of the user (UserDataService.EraseUser)
+// before removing them from the schedule. A nil service disables the cascade, for
+// deployments erasing authentication data in their own deletion job.
+//
+// Example:
+//
+//	deletionService.SetUserDataService(service.NewUserDataService(refreshService, resetService, otpService))
+func (dts *DeletionTokenService) SetUserDataService(userData *UserDataService) {
+	dts.mu.Lock()
+	defer dts.mu.Unlock()
+	dts.userData = userData
+}
+
+func (dts *DeletionTokenService) userDataService() *UserDataService {
+	dts.mu.RLock()
+	defer dts.mu.RUnlock()
+	return dts.userData
+}
+
+// ScheduleDeletion schedules the deletion of the user account at the end of the
+// grace period and returns the token cancelling it. A deletion already scheduled
+// for the user is replaced (its token is revoked).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - *string: Pointer to the cancellation token, to be sent to the user
+//   - time.Time: When the account will be deleted
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	token, deleteAt, err := deletionService.ScheduleDeletion(ctx, userID)
+//	if err != nil {
+//	    return err
+//	}
+//	sendEmail(user.Email, fmt.Sprintf("Your account will be deleted on %s. Changed your mind? https://app.example.com/keep-account?token=%s",
+//	    deleteAt.Format(time.DateOnly), *token))
+func (dts *DeletionTokenService) ScheduleDeletion(ctx context.Context, userID string) (*string, time.Time, error) {
+	if userID == "" {
+		return nil, time.Time{}, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	deleteAt := dts.now().Add(dts.grace).UTC().Truncate(time.Second)
+	token, err := dts.tokens.Create(ctx, userID, deletionPayload{DeleteAt: deleteAt})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	previous, found, err := dts.pending.swap(ctx, userID, hashToken(*token))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if found {
+		if err := dts.tokens.revokeHash(ctx, previous); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to revoke previous deletion token: %w", err)
+		}
+	}
+
+	if err := dts.db.ZAdd(ctx, dts.schedule, redis.Z{Score: float64(deleteAt.Unix()), Member: userID}).Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return token, deleteAt, nil
+}
+
+// CancelDeletion cancels a scheduled deletion with the token sent to the user.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The cancellation token
+//
+// Returns:
+//   - *string: ID of the user whose deletion was cancelled, nil if the token is not valid
+//     (unknown, replaced, already used or grace period elapsed)
+//   - error: Storage errors
+//
+// Example:
+//
+//	userID, err := deletionService.CancelDeletion(ctx, r.URL.Query().Get("token"))
+//	if err != nil || userID == nil {
+//	    w.WriteHeader(http.StatusBadRequest)
+//	    return
+//	}
+func (dts *DeletionTokenService) CancelDeletion(ctx context.Context, token string) (*string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	record, err := dts.tokens.Consume(ctx, token)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	if !dts.now().Before(record.Payload.DeleteAt) {
+		// Too late, the deletion may already be in progress
+		return nil, nil
+	}
+
+	if err := dts.pending.revokeIf(ctx, record.UserID, hashToken(token)); err != nil {
+		return nil, err
+	}
+	if err := dts.db.ZRem(ctx, dts.schedule, record.UserID).Err(); err != nil {
+		return nil, err
+	}
+
+	return &record.UserID, nil
+}
+
+// CancelUserDeletion cancels the scheduled deletion of a user without token,
+// e.g. when the user logs in during the grace period or on support request.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation or storage errors
+func (dts *DeletionTokenService) CancelUserDeletion(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if userData := dts.userDataService(); userData != nil {
+		if err := userData.EraseUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return dts.forget(ctx, userID)
+}
+
+// ScheduledDeletion returns when the account of the user will be deleted.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - time.Time: Deletion time
+//   - bool: true if a deletion is scheduled
+//   - error: Validation or storage errors
+func (dts *DeletionTokenService) ScheduledDeletion(ctx context.Context, userID string) (time.Time, bool, error) {
+	if userID == "" {
+		return time.Time{}, false, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	score, err := dts.db.ZScore(ctx, dts.schedule, userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(int64(score), 0).UTC(), true, nil
+}
+
+// ListElapsedDeletions returns the users whose grace period elapsed, oldest
+// deletion first. They stay listed until CompleteDeletion is called.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - limit: Maximum number of users returned (100 when not positive)
+//
+// Returns:
+//   - []string: IDs of the accounts to delete
+//   - error: Storage errors
+//
+// Example:
+//
+//	// Daily job
+//	userIDs, err := deletionService.ListElapsedDeletions(ctx, 500)
+//	for _, userID := range userIDs {
+//	    if err := users.Delete(ctx, userID); err == nil {
+//	        deletionService.CompleteDeletion(ctx, userID)
+//	    }
+//	}
+func (dts *DeletionTokenService) ListElapsedDeletions(ctx context.Context, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return dts.db.ZRangeByScore(ctx, dts.schedule, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(dts.now().Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+}
+
+// CompleteDeletion removes a user from the schedule once their account was deleted.
+// With SetUserDataService, the authentication data of the user is erased first; the
+// user stays scheduled if the erasure fails, so that the next run retries it.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation, erasure or storage errors
+func (dts *DeletionTokenService) CompleteDeletion(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if userData := dts.userDataService(); userData != nil {
+		if err := userData.EraseUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return dts.forget(ctx, userID)
+}
+
+// forget removes the scheduled deletion and the cancellation token of a user.
+func (dts *DeletionTokenService) forget(ctx context.Context, userID string) error {
+	hash, found, err := dts.pending.consume(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if found {
+		if err := dts.tokens.revokeHash(ctx, hash); err != nil {
+			return err
+		}
+	}
+	return dts.db.ZRem(ctx, dts.schedule, userID).Err()
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// deviceCodeMaxLength defines the character length of device codes.
+	// Device codes are never typed by users, only polled by the device.
+	deviceCodeMaxLength int = 64
+
+	// redisStoreNameDeviceCode is the Redis key prefix for device authorizations.
+	// Key pattern: "device_code:{deviceCode}" holding a hash
+	// (user_code, client_id, status, user_id, interval_ms, last_poll_ms).
+	redisStoreNameDeviceCode string = "device_code"
+
+	// redisStoreNameDeviceUserCode is the Redis key prefix for user code lookups.
+	// Key pattern: "device_user_code:{userCode}" with the device code as value.
+	redisStoreNameDeviceUserCode string = "device_user_code"
+
+	// redisStoreNameDeviceCodeAttempts is the Redis key prefix for failed user code entries.
+	// Key pattern: "device_code_attempts:{userID}" with the failure count.
+	redisStoreNameDeviceCodeAttempts string = "device_code_attempts"
+
+	defaultDeviceCodeTTL          string = "10m"
+	defaultDeviceCodePollInterval string = "5s"
+
+	// deviceCodeSlowDownStep is added to the polling interval on each slow_down (RFC 8628 §3.5).
+	deviceCodeSlowDownStep time.Duration = 5 * time.Second
+
+	// deviceUserCodeRetries bounds the attempts to generate a user code not already in use.
+	deviceUserCodeRetries int = 3
+)
+
+// Device authorization statuses stored in the "status" hash field.
+const (
+	deviceCodeStatusPending  string = "pending"
+	deviceCodeStatusApproved string = "approved"
+	deviceCodeStatusDenied   string = "denied"
+)
+
+// decideDeviceCodeScript records the decision on a pending authorization, so that a
+// decision never recreates an expired hash (without TTL) nor overrides another one.
+// Returns 1 when the decision was recorded, 0 when the authorization is not pending.
+var decideDeviceCodeScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'status') ~= 'pending' then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'status', ARGV[1])
+if ARGV[2] ~= '' then
+	redis.call('HSET', KEYS[1], 'user_id', ARGV[2])
+end
+return 1
+`)
+
+// pollDeviceCodeScript handles a poll atomically: it checks the client and the
+// polling interval, then deletes decided authorizations, so that concurrent polls
+// never redeem an approval twice.
+// Returns {outcome, user_id} with outcome expired_token, invalid_grant, slow_down,
+// approved, access_denied or authorization_pending.
+var pollDeviceCodeScript = redis.NewScript(`
+local record = redis.call('HMGET', KEYS[1], 'status', 'client_id', 'user_id', 'interval_ms', 'last_poll_ms')
+if not record[1] then
+	return {'expired_token', ''}
+end
+if record[2] ~= ARGV[1] then
+	return {'invalid_grant', ''}
+end
+local now = tonumber(ARGV[2])
+local interval = tonumber(record[4])
+if not interval then
+	return redis.error_reply('corrupted device code interval')
+end
+if record[5] and now - tonumber(record[5]) < interval then
+	redis.call('HSET', KEYS[1], 'interval_ms', interval + tonumber(ARGV[3]), 'last_poll_ms', now)
+	return {'slow_down', ''}
+end
+if record[1] == 'approved' then
+	redis.call('DEL', KEYS[1])
+	return {'approved', record[3]}
+end
+if record[1] == 'denied' then
+	redis.call('DEL', KEYS[1])
+	return {'access_denied', ''}
+end
+redis.call('HSET', KEYS[1], 'last_poll_ms', now)
+return {'authorization_pending', ''}
+`)
+
+// Exchange errors, named after the RFC 8628 §3.5 error codes so they can be
+// returned as-is in the token endpoint response.
+var (
+	// ErrAuthorizationPending is returned while the user has not approved or denied the request yet.
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	// ErrSlowDown is returned when the device polls faster than the allowed interval.
+	// The interval is increased by 5 seconds for the following polls.
+	ErrSlowDown = errors.New("slow_down")
+	// ErrAccessDenied is returned when the user denied the request.
+	ErrAccessDenied = errors.New("access_denied")
+	// ErrExpiredToken is returned when the device code is unknown or has expired.
+	ErrExpiredToken = errors.New("expired_token")
+	// ErrInvalidGrant is returned when the device code was issued to another client
+	// (RFC 6749 §5.2). The authorization is left untouched for its client.
+	ErrInvalidGrant = errors.New("invalid_grant")
+)
+
+// DeviceAuthorization is the result of a device authorization request.
+//
+// Fields:
+//   - DeviceCode: Secret code polled by the device on Exchange (64 characters)
+//   - UserCode: Short code displayed by the device and typed by the user (e.g. "WDJB-MJHT")
+//   - ExpiresIn: Lifetime of both codes
+//   - Interval: Minimum time the device must wait between two polls
+type DeviceAuthorization struct {
+	DeviceCode string
+	UserCode   string
+	ExpiresIn  time.Duration
+	Interval   time.Duration
+}
+
+// DeviceCodeService implements the OAuth 2.0 device authorization grant (RFC 8628)
+// for input-constrained devices such as TVs and consoles.
+//
+// Flow:
+//  1. The device calls CreateDeviceCode and displays the user code
+//  2. The user signs in on another device and calls Approve (or Deny) with the user code
+//  3. The device polls Exchange with the device code until it gets the user ID
+//
+// Key features:
+//   - Short human-typable user codes without vowels or ambiguous characters
+//   - Polling rate limit: polls faster than the interval fail with ErrSlowDown
+//   - Client binding: only the client that requested a device code can exchange it (RFC 8628 §3.4)
+//   - User code entry rate limit per user (5 failed attempts max)
+//   - Single-use: codes are deleted once approved and exchanged, or denied, atomically (Lua scripts)
+//   - Automatic expiration via Redis TTL
+//
+// Redis key patterns:
+//   - Authorization: "device_code:{deviceCode}" → hash
+//   - User code lookup: "device_user_code:{userCode}" → device code
+//   - Attempts tracking: "device_code_attempts:{userID}" → counter (integer)
+type DeviceCodeService struct {
+	db       *redis.Client
+	config   *lib.Config
+	keys     keyPrefix
+	now      func() time.Time
+	duration time.Duration
+	interval time.Duration
+	attempts *attemptCounter
+}
+
+// DeviceCodeServiceInterface defines the methods for the device authorization flow.
+type DeviceCodeServiceInterface interface {
+	CreateDeviceCode(ctx context.Context, clientID string) (*DeviceAuthorization, error)
+	Approve(ctx context.Context, userCode string, userID string) (bool, error)
+	Deny(ctx context.Context, userCode string, userID string) (bool, error)
+	Exchange(ctx context.Context, deviceCode string, clientID string) (*string, error)
+	RevokeAllDeviceCodes(ctx context.Context) error
+}
+
+// NewDeviceCodeService creates a new device code service instance with Redis persistence.
+// Returns an error if the database client is nil or if a duration cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for codes storage
+//   - config: Configuration containing DeviceCodeTTL and DeviceCodePollInterval
+//   - opts: Optional settings (WithKeyPrefix, WithClock)
+//
+// Returns:
+//   - *DeviceCodeService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	deviceService, err := service.NewDeviceCodeService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewDeviceCodeService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*DeviceCodeService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration, err := parseDurationOrDefault(config.DeviceCodeTTL, defaultDeviceCodeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device code TTL format: %w", err)
+	}
+	interval, err := parseDurationOrDefault(config.DeviceCodePollInterval, defaultDeviceCodePollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device code poll interval format: %w", err)
+	}
+
+	options := newServiceOptions(opts)
+	service := &DeviceCodeService{
+		db:       db,
+		config:   config.Clone(),
+		keys:     options.keyPrefix,
+		now:      options.clock,
+		duration: duration,
+		interval: interval,
+		attempts: newAttemptCounter(db, options.keyPrefix.name(redisStoreNameDeviceCodeAttempts), duration),
+	}
+
+	return service, nil
+}
+
+// CreateDeviceCode starts a device authorization request for a client.
+// The device displays the user code and polls Exchange with the device code.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - clientID: Identifier of the client application requesting authorization
+//
+// Returns:
+//   - *DeviceAuthorization: Device code, user code, expiration and polling interval
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	auth, err := deviceService.CreateDeviceCode(ctx, "smart-tv-app")
+//	if err != nil {
+//	    return err
+//	}
+//	// Display on screen: "Go to example.com/device and enter WDJB-MJHT"
+//	display(auth.UserCode)
+func (dcs *DeviceCodeService) CreateDeviceCode(ctx context.Context, clientID string) (*DeviceAuthorization, error) {
+	if clientID == "" {
+		return nil, errors.New("invalid client id")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	deviceCode, err := lib.GenerateRandomString(deviceCodeMaxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve a user code not already in use by another pending authorization
+	var userCode string
+	for i := 0; i < deviceUserCodeRetries && userCode == ""; i++ {
+		code, err := lib.GenerateUserCode()
+		if err != nil {
+			return nil, err
+		}
+		reserved, err := dcs.db.SetNX(ctx, dcs.userCodeKey(normalizeUserCode(code)), deviceCode, dcs.duration).Result()
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			userCode = code
+		}
+	}
+	if userCode == "" {
+		return nil, errors.New("failed to generate a unique user code")
+	}
+
+	key := dcs.deviceCodeKey(deviceCode)
+	_, err = dcs.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]any{
+			"user_code":   userCode,
+			"client_id":   clientID,
+			"status":      deviceCodeStatusPending,
+			"interval_ms": dcs.interval.Milliseconds(),
+		})
+		pipe.Expire(ctx, key, dcs.duration)
+		return nil
+	})
+	if err != nil {
+		// Best effort rollback: release the user code we just reserved
+		_ = dcs.db.Del(ctx, dcs.userCodeKey(normalizeUserCode(userCode)))
+		return nil, err
+	}
+
+	return &DeviceAuthorization{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ExpiresIn:  dcs.duration,
+		Interval:   dcs.interval,
+	}, nil
+}
+
+// Approve grants the device authorization identified by the user code to the user.
+// The user code is case-insensitive and the dash is optional.
+// Failed entries are counted per user and rejected after 5 attempts.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userCode: The code typed by the user (e.g. "wdjb-mjht")
+//   - userID: The authenticated user granting the authorization
+//
+// Returns:
+//   - bool: true if the authorization was approved, false if the code is unknown, expired or already used
+//   - error: Validation errors, rate limit exceeded, or storage errors
+//
+// Example:
+//
+//	approved, err := deviceService.Approve(ctx, r.FormValue("user_code"), session.UserID)
+//	if err != nil {
+//	    return err
+//	}
+//	if !approved {
+//	    return errors.New("invalid or expired code")
+//	}
+func (dcs *DeviceCodeService) Approve(ctx context.Context, userCode string, userID string) (bool, error) {
+	return dcs.decide(ctx, userCode, userID, deviceCodeStatusApproved)
+}
+
+// Deny refuses the device authorization identified by the user code.
+// The next Exchange for the device code fails with ErrAccessDenied.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userCode: The code typed by the user (e.g. "WDJB-MJHT")
+//   - userID: The authenticated user refusing the authorization
+//
+// Returns:
+//   - bool: true if the authorization was denied, false if the code is unknown, expired or already used
+//   - error: Validation errors, rate limit exceeded, or storage errors
+func (dcs *DeviceCodeService) Deny(ctx context.Context, userCode string, userID string) (bool, error) {
+	return dcs.decide(ctx, userCode, userID, deviceCodeStatusDenied)
+}
+
+func (dcs *DeviceCodeService) decide(ctx context.Context, userCode string, userID string, status string) (bool, error) {
+	if userID == "" {
+		return false, ErrInvalidUserID
+	}
+
+	userCode = normalizeUserCode(userCode)
+	if len(userCode) != 8 {
+		return false, errors.New("invalid user code")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Check rate limit before the lookup, user codes are short enough to be guessed
+	attempts, err := dcs.attempts.get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if attempts >= maxAttempts {
+		return false, ErrMaxAttemptsExceeded
+	}
+
+	userCodeKey := dcs.userCodeKey(userCode)
+	deviceCode, err := dcs.db.Get(ctx, userCodeKey).Result()
+	if errors.Is(err, redis.Nil) {
+		// Unknown user code - increment attempts (best effort, ignore error)
+		_, _ = dcs.attempts.increment(ctx, userID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	approvedBy := ""
+	if status == deviceCodeStatusApproved {
+		approvedBy = userID
+	}
+	decided, err := decideDeviceCodeScript.Run(ctx, dcs.db, []string{dcs.deviceCodeKey(deviceCode)}, status, approvedBy).Int()
+	if err != nil {
+		return false, err
+	}
+	if decided == 0 {
+		return false, nil // Authorization expired or already decided
+	}
+
+	// The user code is single-use, the device code carries the decision from now on
+	if err := dcs.db.Del(ctx, userCodeKey).Err(); err != nil {
+		return true, err
+	}
+
+	return true, dcs.attempts.revoke(ctx, userID)
+}
+
+// Exchange is polled by the device until the user has approved or denied the request.
+// On approval the user ID is returned and the device code is deleted (single-use),
+// the caller then issues tokens for that user. A poll is handled atomically, so that
+// concurrent polls return the user ID once.
+//
+// Errors:
+//   - ErrAuthorizationPending: the user has not decided yet, poll again after the interval
+//   - ErrSlowDown: polled too fast, the interval is increased by 5 seconds
+//   - ErrAccessDenied: the user denied the request
+//   - ErrExpiredToken: the device code is unknown or expired
+//   - ErrInvalidGrant: the device code was issued to another client
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - deviceCode: The device code returned by CreateDeviceCode
+//   - clientID: The client_id of the token request, matched against the one given to CreateDeviceCode
+//
+// Returns:
+//   - *string: The ID of the user who approved the request
+//   - error: One of the errors above, validation or storage errors
+//
+// Example:
+//
+//	userID, err := deviceService.Exchange(ctx, r.FormValue("device_code"), r.FormValue("client_id"))
+//	switch {
+//	case errors.Is(err, service.ErrAuthorizationPending), errors.Is(err, service.ErrSlowDown):
+//	    return writeOAuthError(w, err.Error())
+//	case err != nil:
+//	    return err
+//	}
+//	token, err := refreshService.CreateRefreshToken(ctx, *userID)
+func (dcs *DeviceCodeService) Exchange(ctx context.Context, deviceCode string, clientID string) (*string, error) {
+	deviceCode = dcs.config.TokenNormalization.Normalize(deviceCode)
+	if err := validation.IsIncomingTokenValid(deviceCode, deviceCodeMaxLength); err != nil {
+		return nil, err
+	}
+	if clientID == "" {
+		return nil, errors.New("invalid client id")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	values, err := pollDeviceCodeScript.Run(ctx, dcs.db, []string{dcs.deviceCodeKey(deviceCode)},
+		clientID, dcs.now().UnixMilli(), deviceCodeSlowDownStep.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("corrupted %s record", redisStoreNameDeviceCode)
+	}
+
+	switch values[0] {
+	case deviceCodeStatusApproved:
+		userID := values[1]
+		return &userID, nil
+	case ErrAccessDenied.Error():
+		return nil, ErrAccessDenied
+	case ErrAuthorizationPending.Error():
+		return nil, ErrAuthorizationPending
+	case ErrSlowDown.Error():
+		return nil, ErrSlowDown
+	case ErrInvalidGrant.Error():
+		return nil, ErrInvalidGrant
+	case ErrExpiredToken.Error():
+		return nil, ErrExpiredToken
+	default:
+		return nil, fmt.Errorf("corrupted %s record", redisStoreNameDeviceCode)
+	}
+}
+
+// RevokeAllDeviceCodes revokes all device authorizations, user codes and attempt counters.
+// Used for emergency security measures or testing cleanup.
+//
+// Warning: This is a destructive operation that affects all users.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during revocation
+func (dcs *DeviceCodeService) RevokeAllDeviceCodes(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, prefix := range []string{dcs.keys.name(redisStoreNameDeviceCode), dcs.keys.name(redisStoreNameDeviceUserCode)} {
+		keys := dcs.db.Scan(ctx, 0, fmt.Sprintf("%s:*", prefix), 0).Iterator()
+		for keys.Next(ctx) {
+			key := keys.Val()
+			if err := dcs.db.Del(ctx, key).Err(); err != nil {
+				return fmt.Errorf("failed to delete device code key %s : %w", key, err)
+			}
+		}
+		if err := keys.Err(); err != nil {
+			return err
+		}
+	}
+
+	return dcs.attempts.revokeAll(ctx)
+}
+
+func (dcs *DeviceCodeService) deviceCodeKey(deviceCode string) string {
+	return fmt.Sprintf("%s:%s", dcs.keys.name(redisStoreNameDeviceCode), deviceCode)
+}
+
+func (dcs *DeviceCodeService) userCodeKey(userCode string) string {
+	return fmt.Sprintf("%s:%s", dcs.keys.name(redisStoreNameDeviceUserCode), userCode)
+}
+
+// normalizeUserCode makes user code entry forgiving: case-insensitive,
+// dashes and spaces ignored ("wdjb mjht" matches "WDJB-MJHT").
+func normalizeUserCode(userCode string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(userCode))
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// minJWTSecretLength is the length under which the JWT secret is reported weak:
+	// HS256 keys should have at least 256 bits.
+	minJWTSecretLength int = 32
+
+	// maxRecommendedJWTExpiry is the access token lifetime above which Doctor warns:
+	// access tokens cannot be revoked individually.
+	maxRecommendedJWTExpiry time.Duration = time.Hour
+)
+
+// DoctorStatus is the outcome of a Doctor check.
+type DoctorStatus string
+
+const (
+	// DoctorStatusOK is a passed check.
+	DoctorStatusOK DoctorStatus = "ok"
+	// DoctorStatusWarning is a check that passed with a risky setting.
+	DoctorStatusWarning DoctorStatus = "warning"
+	// DoctorStatusFailed is a check that would make the services fail.
+	DoctorStatusFailed DoctorStatus = "failed"
+	// DoctorStatusSkipped is a check not run because a check it depends on failed.
+	DoctorStatusSkipped DoctorStatus = "skipped"
+)
+
+// DoctorCheck is the result of a Doctor check.
+//
+// JSON serialization:
+//   - Example: {"name": "config.jwt_secret", "status": "warning", "message": "JWTSecret is 12 bytes long, use at least 32"}
+type DoctorCheck struct {
+	Name    string       `json:"name"`
+	Status  DoctorStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+}
+
+// DoctorReport is the structured report of Doctor, in the order the checks ran.
+//
+// JSON serialization:
+//   - Example: {"healthy": false, "checks": [{"name": "redis.setup", "status": "failed", "message": "..."}]}
+type DoctorReport struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []DoctorCheck `json:"checks"`
+}
+
+// Err returns the failed checks joined, nil when the report is healthy.
+// Warnings are not errors.
+func (r *DoctorReport) Err() error {
+	var errs []error
+	for _, check := range r.Checks {
+		if check.Status == DoctorStatusFailed {
+			errs = append(errs, fmt.Errorf("%s: %s", check.Name, check.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// String formats the report for terminals, one check per line:
+// "[failed] redis.setup: redis is unreachable...".
+func (r *DoctorReport) String() string {
+	var b strings.Builder
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s", check.Status, check.Name)
+		if check.Message != "" {
+			fmt.Fprintf(&b, ": %s", check.Message)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (r *DoctorReport) add(name string, status DoctorStatus, message string) {
+	r.Checks = append(r.Checks, DoctorCheck{Name: name, Status: status, Message: message})
+	if status == DoctorStatusFailed {
+		r.Healthy = false
+	}
+}
+
+// Doctor runs a startup self-test of the configuration and of Redis, and reports every
+// problem at once instead of the first one met by the services, at boot or from a
+// deployment command.
+//
+// Checks:
+//   - config.*: JWT secret, issuer and algorithm, access token lifetime, every duration of the
+//     configuration, token lengths, checksum, password reset policy and storage mode
+//   - redis.setup: Connectivity, credentials, server version and permissions (VerifyRedisSetup)
+//   - redis.schema_version: Compatibility of the stored data (CheckSchemaVersion, which
+//     records the version on first use), skipped when redis.setup failed
+//
+// Parameters:
+//   - ctx: Context for the Redis checks (uses Background if nil)
+//   - db: Redis client given to the services
+//   - config: Configuration given to the services
+//   - opts: The options given to the services (WithKeyPrefix)
+//
+// Returns:
+//   - *DoctorReport: Every check with its status, unhealthy when one failed
+//
+// Example:
+//
+//	report := service.Doctor(ctx, redisClient, config, service.WithKeyPrefix("myapp"))
+//	fmt.Print(report)
+//	if err := report.Err(); err != nil {
+//	    log.Fatalf("token services are not ready: %v", err)
+//	}
+func Doctor(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) *DoctorReport {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	report := &DoctorReport{Healthy: true}
+	if config == nil {
+		report.add("config", DoctorStatusFailed, "config is nil")
+	} else {
+		checkDoctorConfig(report, config)
+	}
+
+	if db == nil {
+		report.add("redis.setup", DoctorStatusFailed, "db is nil")
+		report.add("redis.schema_version", DoctorStatusSkipped, "redis.setup failed")
+		return report
+	}
+
+	if err := VerifyRedisSetup(ctx, db, opts...); err != nil {
+		report.add("redis.setup", DoctorStatusFailed, err.Error())
+		report.add("redis.schema_version", DoctorStatusSkipped, "redis.setup failed")
+		return report
+	}
+	report.add("redis.setup", DoctorStatusOK, "")
+
+	version, err := CheckSchemaVersion(ctx, db, opts...)
+	if err != nil {
+		report.add("redis.schema_version", DoctorStatusFailed, err.Error())
+	} else {
+		report.add("redis.schema_version", DoctorStatusOK, fmt.Sprintf("version %d", version))
+	}
+	return report
+}
+
+// checkDoctorConfig adds the configuration checks to the report.
+func checkDoctorConfig(report *DoctorReport, config *lib.Config) {
+	switch {
+	case config.JWTSecret == "":
+		report.add("config.jwt_secret", DoctorStatusFailed, "JWTSecret is empty")
+	case len(config.JWTSecret) < minJWTSecretLength:
+		report.add("config.jwt_secret", DoctorStatusWarning, fmt.Sprintf("JWTSecret is %d bytes long, use at least %d", len(config.JWTSecret), minJWTSecretLength))
+	default:
+		report.add("config.jwt_secret", DoctorStatusOK, "")
+	}
+
+	if config.Issuer == "" {
+		report.add("config.issuer", DoctorStatusWarning, "Issuer is empty, access tokens carry no iss claim")
+	} else {
+		report.add("config.issuer", DoctorStatusOK, "")
+	}
+
+	expiry, err := time.ParseDuration(config.JWTExpiry)
+	switch {
+	case err != nil || expiry <= 0:
+		report.add("config.jwt_expiry", DoctorStatusFailed, fmt.Sprintf("JWTExpiry %q is not a positive duration", config.JWTExpiry))
+	case expiry > maxRecommendedJWTExpiry:
+		report.add("config.jwt_expiry", DoctorStatusWarning, fmt.Sprintf("JWTExpiry %s is long for tokens that cannot be revoked individually, use refresh tokens", expiry))
+	default:
+		report.add("config.jwt_expiry", DoctorStatusOK, "")
+	}
+
+	if _, err := jwtSigningMethod(config); err != nil {
+		report.add("config.jwt_algorithm", DoctorStatusFailed, err.Error())
+	} else {
+		report.add("config.jwt_algorithm", DoctorStatusOK, "")
+	}
+
+	durations := []struct {
+		name  string
+		value *string
+	}{
+		{"RefreshTokenTTL", config.RefreshTokenTTL},
+		{"PasswordResetTTL", config.PasswordResetTTL},
+		{"OTPTTL", config.OTPTTL},
+		{"RedisConnMaxIdleTime", config.RedisConnMaxIdleTime},
+		{"RedisDialTimeout", config.RedisDialTimeout},
+		{"RedisReadTimeout", config.RedisReadTimeout},
+		{"RedisWriteTimeout", config.RedisWriteTimeout},
+		{"LoginAttemptWindow", config.LoginAttemptWindow},
+		{"LoginLockoutDuration", config.LoginLockoutDuration},
+		{"DeviceCodeTTL", config.DeviceCodeTTL},
+		{"DeviceCodePollInterval", config.DeviceCodePollInterval},
+		{"NonceTTL", config.NonceTTL},
+		{"EmailChangeTTL", config.EmailChangeTTL},
+		{"AccountDeletionGracePeriod", config.AccountDeletionGracePeriod},
+		{"ShareLinkTTL", config.ShareLinkTTL},
+	}
+	var invalid []string
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		if duration, err := time.ParseDuration(*d.value); err != nil || duration <= 0 {
+			invalid = append(invalid, fmt.Sprintf("%s %q", d.name, *d.value))
+		}
+	}
+	if len(invalid) > 0 {
+		report.add("config.durations", DoctorStatusFailed, "not positive durations: "+strings.Join(invalid, ", "))
+	} else {
+		report.add("config.durations", DoctorStatusOK, "")
+	}
+
+	_, refreshErr := newTokenLength("refresh", config.RefreshTokenLength, config.RefreshTokenMaxLength, refreshTokenMaxLength, refreshTokenMinLength)
+	_, resetErr := newTokenLength("password reset", config.PasswordResetTokenLength, config.PasswordResetTokenMaxLength, passwordResetTokenMaxLength, passwordResetTokenMinLength)
+	if err := errors.Join(refreshErr, resetErr); err != nil {
+		report.add("config.token_lengths", DoctorStatusFailed, strings.ReplaceAll(err.Error(), "\n", ", "))
+	} else {
+		report.add("config.token_lengths", DoctorStatusOK, "")
+	}
+
+	switch {
+	case config.TokenChecksum && config.TokenChecksumSecret == "":
+		report.add("config.token_checksum", DoctorStatusWarning, "TokenChecksum without TokenChecksumSecret uses CRC32, which anyone can compute")
+	case config.TokenChecksumSecret != "" && config.TokenChecksumSecret == config.JWTSecret:
+		report.add("config.token_checksum", DoctorStatusWarning, "TokenChecksumSecret reuses JWTSecret, use a separate key")
+	default:
+		report.add("config.token_checksum", DoctorStatusOK, "")
+	}
+
+	switch config.PasswordResetPolicy {
+	case "", lib.PasswordResetPolicyReplace, lib.PasswordResetPolicyReuse, lib.PasswordResetPolicyExtend:
+		report.add("config.password_reset_policy", DoctorStatusOK, "")
+	default:
+		report.add("config.password_reset_policy", DoctorStatusFailed, fmt.Sprintf("unknown PasswordResetPolicy %q", config.PasswordResetPolicy))
+	}
+
+	switch config.RefreshTokenStorage {
+	case "", lib.RefreshTokenStoragePlaintext, lib.RefreshTokenStorageHashed:
+		report.add("config.refresh_token_storage", DoctorStatusOK, "")
+	case lib.RefreshTokenStorageTransition:
+		report.add("config.refresh_token_storage", DoctorStatusWarning, "RefreshTokenStorage is in transition, run MigrateRefreshTokenStorage then switch to hashed")
+	default:
+		report.add("config.refresh_token_storage", DoctorStatusFailed, fmt.Sprintf("unknown RefreshTokenStorage %q", config.RefreshTokenStorage))
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameElevation is the Redis key prefix for elevation grants.
+	// Key pattern: "elevation:{userID}:{sha256(token)}" with a JSON record of the
+	// re-authentication, expiring after the elevation TTL.
+	redisStoreNameElevation string = "elevation"
+
+	// elevationTokenLength is the length of the elevation tokens.
+	elevationTokenLength int = 32
+
+	defaultElevationTTL time.Duration = 15 * time.Minute
+)
+
+// ElevationMethod tells how the user re-authenticated for an elevation.
+type ElevationMethod string
+
+const (
+	// ElevationMethodPassword is a re-authentication with the password.
+	ElevationMethodPassword ElevationMethod = "password"
+	// ElevationMethodOTP is a re-authentication with a one-time password.
+	ElevationMethodOTP ElevationMethod = "otp"
+)
+
+// ElevationGrant is a time-limited elevation ("sudo mode") of a user.
+//
+// Fields:
+//   - Token: The elevation token, sent by the client with the sensitive requests
+//     (empty in the grants returned by VerifyElevation)
+//   - UserID: The elevated user
+//   - Method: How the user re-authenticated
+//   - GrantedAt: When the user re-authenticated
+//   - ExpiresAt: When the grant expires
+type ElevationGrant struct {
+	Token     string
+	UserID    string
+	Method    ElevationMethod
+	GrantedAt time.Time
+	ExpiresAt time.Time
+}
+
+// elevationRecord is the Redis value of a grant.
+//
+// JSON serialization:
+//   - Example: {"method": "otp", "granted_at": 1767225600}
+type elevationRecord struct {
+	Method    ElevationMethod `json:"method"`
+	GrantedAt int64           `json:"granted_at"`
+}
+
+// ElevationService issues short-lived elevation grants after the user re-authenticated
+// (password or OTP), required by sensitive operations such as changing the email or
+// deleting the account, even within a valid session (see middleware.RequireRecentElevation).
+//
+// Each grant is an opaque token returned to the client that re-authenticated, so that
+// an elevation on one device does not elevate the other sessions of the user. Operations
+// can require a grant more recent than the grant TTL.
+//
+// Redis key pattern:
+//   - Grant: "elevation:{userID}:{sha256(token)}" → JSON record (method, granted_at), expiring after the TTL
+type ElevationService struct {
+	db   *redis.Client
+	keys keyPrefix
+	ttl  time.Duration
+
+	// mu guards the audit logger, which can be set while grants are issued.
+	mu    sync.RWMutex
+	audit lib.AuditLogger
+}
+
+// NewElevationService creates an elevation service.
+// Returns an error if the database client is nil.
+//
+// Parameters:
+//   - db: Redis client for the grants
+//   - ttl: Lifetime of the grants (default: 15 minutes)
+//   - opts: Optional settings (WithKeyPrefix, WithLogger)
+//
+// Returns:
+//   - *ElevationService: Initialized service ready for use
+//   - error: Validation errors
+//
+// Example:
+//
+//	elevationService, err := service.NewElevationService(redisClient, 10*time.Minute)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewElevationService(db *redis.Client, ttl time.Duration, opts ...Option) (*ElevationService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if ttl <= 0 {
+		ttl = defaultElevationTTL
+	}
+
+	options := newServiceOptions(opts)
+	return &ElevationService{
+		db:    db,
+		keys:  options.keyPrefix,
+		ttl:   ttl,
+		audit: options.audit,
+	}, nil
+}
+
+// SetAuditLogger configures the logger receiving the "elevation.granted" audit events.
+// A nil logger disables auditing.
+func (es *ElevationService) SetAuditLogger(logger lib.AuditLogger) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.audit = logger
+}
+
+func (es *ElevationService) auditLogger() lib.AuditLogger {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return es.audit
+}
+
+func (es *ElevationService) key(userID string, token string) string {
+	return fmt.Sprintf("%s:%s:%s", es.keys.name(redisStoreNameElevation), userID, hashToken(token))
+}
+
+// Elevate grants an elevation to a user who just re-authenticated. The application
+// checks the credentials (e.g. the password) before calling it; see ElevateWithOTP
+// for one-time passwords.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - method: How the user re-authenticated
+//
+// Returns:
+//   - *ElevationGrant: The grant, whose Token is returned to the client
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	if !passwordHash.CheckHash(form.Password, user.PasswordHash) {
+//	    return ErrInvalidCredentials
+//	}
+//	grant, err := elevationService.Elevate(ctx, user.ID, service.ElevationMethodPassword)
+//	// Respond with grant.Token, sent back in the X-Elevation-Token header
+func (es *ElevationService) Elevate(ctx context.Context, userID string, method ElevationMethod) (*ElevationGrant, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	if method == "" {
+		return nil, errors.New("elevation method is empty")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	token, err := lib.GenerateRandomString(elevationTokenLength)
+	if err != nil {
+		return nil, err
+	}
+
+	grantedAt := time.Now()
+	value, err := json.Marshal(elevationRecord{Method: method, GrantedAt: grantedAt.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	if err := es.db.Set(ctx, es.key(userID, token), value, es.ttl).Err(); err != nil {
+		return nil, err
+	}
+
+	expiresAt := grantedAt.Add(es.ttl)
+	emitAudit(ctx, es.auditLogger(), AuditEventElevationGranted, userID, map[string]string{
+		"method":     string(method),
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	return &ElevationGrant{
+		Token:     token,
+		UserID:    userID,
+		Method:    method,
+		GrantedAt: grantedAt,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ElevateWithOTP verifies a one-time password with the OTP service and grants an
+// elevation if it is valid.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - otps: OTP service the code was created with
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - otp: The code typed by the user
+//
+// Returns:
+//   - *ElevationGrant: The grant, nil if the code is not valid
+//   - error: The errors of OTPService.VerifyOTP, or storage errors
+func (es *ElevationService) ElevateWithOTP(ctx context.Context, otps *OTPService, userID string, otp string) (*ElevationGrant, error) {
+	if otps == nil {
+		return nil, errors.New("otp service is nil")
+	}
+
+	valid, err := otps.VerifyOTP(ctx, userID, otp)
+	if err != nil || !valid {
+		return nil, err
+	}
+	return es.Elevate(ctx, userID, ElevationMethodOTP)
+}
+
+// VerifyElevation checks that an elevation token was granted to the user less than
+// maxAge ago.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: The elevation token sent by the client
+//   - maxAge: Maximum age of the re-authentication (0 for the grant TTL)
+//
+// Returns:
+//   - *ElevationGrant: The grant, without its token
+//   - error: ErrElevationRequired if the token is missing, unknown, expired or too old,
+//     ErrInvalidUserID, or storage errors
+//
+// Example:
+//
+//	if _, err := elevationService.VerifyElevation(ctx, userID, r.Header.Get("X-Elevation-Token"), 5*time.Minute); err != nil {
+//	    return err // 403 ELEVATION_REQUIRED with apierror
+//	}
+func (es *ElevationService) VerifyElevation(ctx context.Context, userID string, token string, maxAge time.Duration) (*ElevationGrant, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	if token == "" || len(token) > elevationTokenLength {
+		return nil, ErrElevationRequired
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key := es.key(userID, token)
+	value, found, err := stringResult(es.db.Get(ctx, key))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrElevationRequired
+	}
+
+	var record elevationRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("corrupted elevation record: %w", err)
+	}
+
+	grantedAt := time.Unix(record.GrantedAt, 0)
+	if maxAge > 0 && time.Since(grantedAt) > maxAge {
+		return nil, fmt.Errorf("%w: re-authentication older than %s", ErrElevationRequired, maxAge)
+	}
+
+	return &ElevationGrant{
+		UserID:    userID,
+		Method:    record.Method,
+		GrantedAt: grantedAt,
+		ExpiresAt: grantedAt.Add(es.ttl),
+	}, nil
+}
+
+// RevokeElevation ends an elevation before it expires, e.g. once the sensitive
+// operation is done. Revoking an unknown or expired token is not an error.
+func (es *ElevationService) RevokeElevation(ctx context.Context, userID string, token string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+	if token == "" {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return es.db.Del(ctx, es.key(userID, token)).Err()
+}
+
+// RevokeAllUserElevations ends the elevations of a user on every device, e.g. on
+// logout or password change.
+//
+// Returns:
+//   - int64: Number of revoked grants
+//   - error: Validation or storage errors
+func (es *ElevationService) RevokeAllUserElevations(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pattern := escapeScanPattern(fmt.Sprintf("%s:%s:", es.keys.name(redisStoreNameElevation), userID)) + "*"
+	return deleteMatching(ctx, es.db, pattern, CleanupOptions{})
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameEmailChange is the Redis key prefix for email change tokens.
+	// Key pattern: "email_change:{sha256(token)}" with the JSON record as value.
+	redisStoreNameEmailChange string = "email_change"
+
+	// redisStoreNameEmailChangePending is the Redis key prefix for the pending change of a user.
+	// Key pattern: "email_change_pending:{userID}" with the hash of the last token as value.
+	redisStoreNameEmailChangePending string = "email_change_pending"
+
+	defaultEmailChangeTTL string = "24h"
+)
+
+// EmailChange is a confirmed email change.
+//
+// Fields:
+//   - UserID: User whose email address changes
+//   - NewEmail: The confirmed address
+type EmailChange struct {
+	UserID   string
+	NewEmail string
+}
+
+// emailChangePayload is the data stored with an email change token.
+type emailChangePayload struct {
+	NewEmail string `json:"new_email"`
+}
+
+// EmailChangeService issues the confirmation tokens sent to a new email address
+// before it replaces the current one.
+//
+// Key features:
+//   - Tokens bind the user and the new address (StatefulToken)
+//   - One pending change per user: a new request revokes the previous token
+//   - Single use: Confirm atomically consumes the token
+//   - Automatic expiration via Redis TTL
+//
+// Redis key patterns:
+//   - Token: "email_change:{sha256(token)}" → JSON record (user ID, new email)
+//   - Pending change: "email_change_pending:{userID}" → sha256 of the last token
+//   - TTL: Configured via EmailChangeTTL (default: 24 hours)
+type EmailChangeService struct {
+	db      *redis.Client
+	config  *lib.Config
+	tokens  *StatefulToken[emailChangePayload]
+	pending *ttlStore
+	emails  *validation.EmailValidation
+}
+
+// EmailChangeServiceInterface defines the methods for email change confirmation.
+type EmailChangeServiceInterface interface {
+	RequestEmailChange(ctx context.Context, userID string, newEmail string) (*string, error)
+	Confirm(ctx context.Context, token string) (*EmailChange, error)
+	CancelEmailChange(ctx context.Context, userID string) error
+	RevokeAllEmailChanges(ctx context.Context) error
+}
+
+// NewEmailChangeService creates a new email change service instance with Redis persistence.
+// Returns an error if the database client is nil or if EmailChangeTTL cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for token storage
+//   - config: Configuration containing EmailChangeTTL
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *EmailChangeService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	emailChangeService, err := service.NewEmailChangeService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewEmailChangeService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*EmailChangeService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration, err := parseDurationOrDefault(config.EmailChangeTTL, defaultEmailChangeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email change TTL format: %w", err)
+	}
+
+	tokens, err := NewStatefulToken[emailChangePayload](ctx, db, redisStoreNameEmailChange, duration, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &EmailChangeService{
+		db:      db,
+		config:  config.Clone(),
+		tokens:  tokens,
+		pending: newTTLStore(db, newServiceOptions(opts).keyPrefix.name(redisStoreNameEmailChangePending), duration),
+		emails:  validation.NewEmailValidation(),
+	}
+
+	return service, nil
+}
+
+// RequestEmailChange generates the token confirming that the user owns newEmail.
+// The previous pending change of the user, if any, is revoked.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - newEmail: The requested address, trimmed before use
+//
+// Returns:
+//   - *string: Pointer to the generated token, to be sent to newEmail
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	token, err := emailChangeService.RequestEmailChange(ctx, userID, form.NewEmail)
+//	if err != nil {
+//	    return err
+//	}
+//	sendEmail(form.NewEmail, "https://app.example.com/confirm-email?token="+*token)
+func (ecs *EmailChangeService) RequestEmailChange(ctx context.Context, userID string, newEmail string) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	newEmail = strings.TrimSpace(newEmail)
+	if !ecs.emails.IsValidEmail(newEmail) {
+		return nil, errors.New("invalid email")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	token, err := ecs.tokens.Create(ctx, userID, emailChangePayload{NewEmail: newEmail})
+	if err != nil {
+		return nil, err
+	}
+
+	previous, found, err := ecs.pending.swap(ctx, userID, hashToken(*token))
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if err := ecs.tokens.revokeHash(ctx, previous); err != nil {
+			return nil, fmt.Errorf("failed to revoke previous email change: %w", err)
+		}
+	}
+
+	return token, nil
+}
+
+// Confirm consumes an email change token and returns the change to apply.
+// Tokens replaced by a newer request, expired or already used are rejected.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The token received by email
+//
+// Returns:
+//   - *EmailChange: The user and the confirmed address, nil if the token is not valid
+//   - error: Storage errors
+//
+// Example:
+//
+//	change, err := emailChangeService.Confirm(ctx, r.URL.Query().Get("token"))
+//	if err != nil || change == nil {
+//	    w.WriteHeader(http.StatusBadRequest)
+//	    return
+//	}
+//	users.UpdateEmail(change.UserID, change.NewEmail)
+func (ecs *EmailChangeService) Confirm(ctx context.Context, token string) (*EmailChange, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	record, err := ecs.tokens.Consume(ctx, token)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	// Keep the pointer of a newer request made in the meantime
+	if err := ecs.pending.revokeIf(ctx, record.UserID, hashToken(token)); err != nil {
+		return nil, err
+	}
+
+	return &EmailChange{UserID: record.UserID, NewEmail: record.Payload.NewEmail}, nil
+}
+
+// CancelEmailChange revokes the pending email change of the user, if any.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation or storage errors
+func (ecs *EmailChangeService) CancelEmailChange(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	hash, found, err := ecs.pending.consume(ctx, userID)
+	if err != nil || !found {
+		return err
+	}
+	return ecs.tokens.revokeHash(ctx, hash)
+}
+
+// RevokeAllEmailChanges revokes the pending email changes of all users.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during revocation
+func (ecs *EmailChangeService) RevokeAllEmailChanges(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := ecs.tokens.RevokeAll(ctx); err != nil {
+		return err
+	}
+	return ecs.pending.revokeAll(ctx)
+}
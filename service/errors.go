@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidUserID is returned when the user ID given to a service is empty.
+	ErrInvalidUserID = errors.New("invalid user id")
+
+	// ErrInvalidOTP is returned when an OTP code is not 6 digits.
+	ErrInvalidOTP = errors.New("invalid otp")
+
+	// ErrInvalidTokenClaim is returned when a signed token does not carry the claims
+	// expected by the service (e.g. an ID token given to VerifyAccessToken).
+	ErrInvalidTokenClaim = errors.New("invalid token claim")
+
+	// ErrMaxAttemptsExceeded is returned when the verification attempts of a code
+	// (OTP, device code) are exhausted.
+	ErrMaxAttemptsExceeded = errors.New("max attempts exceeded")
+
+	// ErrPasswordResetQuotaExceeded is returned when a user requested more password
+	// reset tokens than Config.PasswordResetQuota within the token TTL.
+	ErrPasswordResetQuotaExceeded = errors.New("password reset quota exceeded")
+
+	// ErrElevationRequired is returned when a sensitive operation requires a recent
+	// re-authentication that the request does not carry: the elevation token is
+	// missing, unknown, expired, or older than the maximum age of the operation.
+	ErrElevationRequired = errors.New("elevation required")
+
+	// ErrInsufficientScope is returned when a token does not grant the scopes required
+	// by an operation (RFC 6750 "insufficient_scope").
+	ErrInsufficientScope = errors.New("insufficient_scope")
+)
+
+// PasswordResetQuotaError tells when a user can request a password reset again.
+// It matches ErrPasswordResetQuotaExceeded with errors.Is.
+//
+// Fields:
+//   - Limit: Reset tokens allowed per window (Config.PasswordResetQuota)
+//   - RetryAfter: Time until the window expires
+type PasswordResetQuotaError struct {
+	Limit      int
+	RetryAfter time.Duration
+}
+
+// Error returns "password reset quota exceeded: {limit} per window, retry in {retryAfter}".
+func (e *PasswordResetQuotaError) Error() string {
+	return fmt.Sprintf("%s: %d per window, retry in %s", ErrPasswordResetQuotaExceeded, e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap returns ErrPasswordResetQuotaExceeded.
+func (e *PasswordResetQuotaError) Unwrap() error {
+	return ErrPasswordResetQuotaExceeded
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// ErrGeoDenied is returned when a token is used from a denied country or network.
+var ErrGeoDenied = errors.New("denied by geo policy")
+
+// GeoPolicy rejects token use from configured countries or networks and can
+// flag country changes between consecutive uses of a user's tokens.
+// The client country and IP are read from lib.RequestMeta.
+//
+// Policy rules:
+//   - DeniedCountries: ISO 3166-1 alpha-2 codes, case-insensitive (e.g. "KP")
+//   - DeniedNetworks: CIDR prefixes (e.g. "198.51.100.0/24", "2001:db8::/32")
+//   - FlagCountryChange: emit an audit event when the country differs from the previous use
+type GeoPolicy struct {
+	deniedCountries   map[string]struct{}
+	deniedNetworks    []netip.Prefix
+	flagCountryChange bool
+}
+
+// NewGeoPolicy creates a geo policy from country codes and CIDR strings.
+// Returns an error if a CIDR cannot be parsed.
+//
+// Example:
+//
+//	policy, err := service.NewGeoPolicy([]string{"KP", "IR"}, []string{"198.51.100.0/24"}, true)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	refreshService.SetGeoPolicy(policy)
+func NewGeoPolicy(deniedCountries []string, deniedCIDRs []string, flagCountryChange bool) (*GeoPolicy, error) {
+	policy := &GeoPolicy{
+		deniedCountries:   make(map[string]struct{}, len(deniedCountries)),
+		deniedNetworks:    make([]netip.Prefix, 0, len(deniedCIDRs)),
+		flagCountryChange: flagCountryChange,
+	}
+
+	for _, country := range deniedCountries {
+		policy.deniedCountries[strings.ToUpper(strings.TrimSpace(country))] = struct{}{}
+	}
+	for _, cidr := range deniedCIDRs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+		}
+		policy.deniedNetworks = append(policy.deniedNetworks, prefix.Masked())
+	}
+
+	return policy, nil
+}
+
+// Denies reports whether the request is denied, with the reason ("country" or "network").
+// Requests without metadata are never denied.
+func (gp *GeoPolicy) Denies(meta lib.RequestMeta) (bool, string) {
+	if meta.Country != "" {
+		if _, denied := gp.deniedCountries[strings.ToUpper(meta.Country)]; denied {
+			return true, "country"
+		}
+	}
+	if meta.IP != "" {
+		addr, err := netip.ParseAddr(meta.IP)
+		if err == nil {
+			addr = addr.Unmap()
+			for _, network := range gp.deniedNetworks {
+				if network.Contains(addr) {
+					return true, "network"
+				}
+			}
+		}
+	}
+	return false, ""
+}
+
+// CountryChanged reports whether a change between the previous and the current
+// country should be flagged. Unknown countries are never flagged.
+func (gp *GeoPolicy) CountryChanged(previous, current string) bool {
+	return gp.flagCountryChange &&
+		previous != "" && current != "" &&
+		!strings.EqualFold(previous, current)
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameHOTP is the Redis key prefix for HOTP counters.
+	// Key pattern: "hotp:{userID}" with the next expected counter value, no TTL.
+	redisStoreNameHOTP string = "hotp"
+	// redisStoreNameHOTPAttempts is the Redis key prefix for failed HOTP verifications.
+	// Key pattern: "hotp:attempts:{userID}", expiring after the lockout window.
+	redisStoreNameHOTPAttempts string = "hotp:attempts"
+
+	defaultHOTPDigits        int           = 6
+	defaultHOTPLookAhead     int           = 10
+	defaultHOTPResyncWindow  int           = 100
+	defaultHOTPLockoutWindow time.Duration = 15 * time.Minute
+)
+
+// advanceHOTPCounterScript moves a counter forward only if it still holds the value
+// the code was checked against, so that a code accepted by a replica cannot be
+// accepted again by another one.
+var advanceHOTPCounterScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1]) or "0"
+if current == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// HOTPOptions configures an HOTPService.
+//
+// Fields:
+//   - Digits: Code length, 6 to 8 (default: 6)
+//   - LookAhead: Counter values checked ahead of the expected one, for codes generated
+//     on the token but never submitted (default: 10)
+//   - ResyncWindow: Counter values searched by ResynchronizeHOTP (default: 100)
+//   - MaxAttempts: Failed verifications before the user is locked out (default: 5)
+//   - LockoutWindow: Window of the failed verifications counter (default: 15 minutes)
+type HOTPOptions struct {
+	Digits        int
+	LookAhead     int
+	ResyncWindow  int
+	MaxAttempts   int
+	LockoutWindow time.Duration
+}
+
+// HOTPService verifies counter-based one-time passwords (HOTP, RFC 4226), as generated
+// by hardware tokens. The application keeps the shared secret of each token (e.g.
+// encrypted in its user table) and gives it to the service; the service keeps the
+// counter of each user in Redis.
+//
+// Key features:
+//   - Look-ahead window: codes generated on the token but never submitted are tolerated
+//   - Replay protection: a code moves the counter past it, atomically across replicas
+//   - Resynchronization with two consecutive codes, for tokens far ahead of the counter
+//   - Lockout after MaxAttempts failed verifications within LockoutWindow
+//
+// Redis key patterns:
+//   - Counter: "hotp:{userID}" → next expected counter value (integer), no TTL
+//   - Attempts tracking: "hotp:attempts:{userID}" → counter (integer), expiring after LockoutWindow
+type HOTPService struct {
+	db           *redis.Client
+	counterName  string
+	hashTags     bool
+	attempts     *attemptCounter
+	digits       int
+	lookAhead    int
+	resyncWindow int
+	maxAttempts  int
+}
+
+// NewHOTPService creates an HOTP service.
+// Returns an error if the database client is nil or an option is invalid.
+//
+// Parameters:
+//   - db: Redis client for the counters
+//   - opts: Code length, windows and lockout
+//   - serviceOpts: Optional settings (WithKeyPrefix, WithUserHashTags)
+//
+// Returns:
+//   - *HOTPService: Initialized service ready for use
+//   - error: Validation errors
+//
+// Example:
+//
+//	hotpService, err := service.NewHOTPService(redisClient, service.HOTPOptions{LookAhead: 20})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewHOTPService(db *redis.Client, opts HOTPOptions, serviceOpts ...Option) (*HOTPService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	digits := opts.Digits
+	if digits == 0 {
+		digits = defaultHOTPDigits
+	}
+	if digits < 6 || digits > 8 {
+		return nil, fmt.Errorf("invalid hotp digits: %d", digits)
+	}
+	lookAhead := opts.LookAhead
+	if lookAhead <= 0 {
+		lookAhead = defaultHOTPLookAhead
+	}
+	resyncWindow := opts.ResyncWindow
+	if resyncWindow <= 0 {
+		resyncWindow = defaultHOTPResyncWindow
+	}
+	if resyncWindow < lookAhead {
+		return nil, errors.New("hotp resync window must not be smaller than the look-ahead window")
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = maxAttempts
+	}
+	lockout := opts.LockoutWindow
+	if lockout <= 0 {
+		lockout = defaultHOTPLockoutWindow
+	}
+
+	options := newServiceOptions(serviceOpts)
+	counter := newAttemptCounter(db, options.keyPrefix.name(redisStoreNameHOTPAttempts), lockout)
+	counter.hashTags = options.userHashTags
+	return &HOTPService{
+		db:           db,
+		counterName:  options.keyPrefix.name(redisStoreNameHOTP),
+		hashTags:     options.userHashTags,
+		attempts:     counter,
+		digits:       digits,
+		lookAhead:    lookAhead,
+		resyncWindow: resyncWindow,
+		maxAttempts:  attempts,
+	}, nil
+}
+
+func (hs *HOTPService) counterKey(userID string) string {
+	if hs.hashTags {
+		userID = userHashTag(userID)
+	}
+	return fmt.Sprintf("%s:%s", hs.counterName, userID)
+}
+
+// Counter returns the next counter value expected from the token of a user,
+// 0 for users without counter.
+func (hs *HOTPService) Counter(ctx context.Context, userID string) (uint64, error) {
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+
+	val, found, err := stringResult(hs.db.Get(ctx, hs.counterKey(userID)))
+	if err != nil || !found {
+		return 0, err
+	}
+	counter, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted hotp counter: %w", err)
+	}
+	return counter, nil
+}
+
+// SetCounter sets the counter of a user, when a token is assigned to the user with a
+// counter other than 0, and resets the failed verifications.
+func (hs *HOTPService) SetCounter(ctx context.Context, userID string, counter uint64) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if err := hs.db.Set(ctx, hs.counterKey(userID), strconv.FormatUint(counter, 10), 0).Err(); err != nil {
+		return err
+	}
+	return hs.attempts.revoke(ctx, userID)
+}
+
+// VerifyHOTP checks a code against the counter of a user and the LookAhead values
+// following it. An accepted code moves the counter past it, so that it and the codes
+// generated before it are rejected from then on.
+//
+// Parameters:
+//   - ctx: Context for the Redis operations
+//   - userID: User identifier
+//   - secret: Shared secret of the user's token
+//   - code: Code displayed by the token
+//
+// Returns:
+//   - bool: true if the code is accepted
+//   - error: ErrInvalidUserID, ErrInvalidOTP for malformed codes, ErrMaxAttemptsExceeded
+//     when the user is locked out, or storage errors
+//
+// Example:
+//
+//	valid, err := hotpService.VerifyHOTP(ctx, user.ID, user.HOTPSecret, code)
+//	if errors.Is(err, service.ErrMaxAttemptsExceeded) {
+//	    return ErrTooManyAttempts
+//	}
+func (hs *HOTPService) VerifyHOTP(ctx context.Context, userID string, secret []byte, code string) (bool, error) {
+	if err := hs.validate(userID, secret, code); err != nil {
+		return false, err
+	}
+	counter, err := hs.checkAttempts(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range uint64(hs.lookAhead) {
+		matched, err := hs.matches(secret, counter+i, code)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return hs.advance(ctx, userID, counter, counter+i+1)
+		}
+	}
+
+	_, err = hs.attempts.increment(ctx, userID)
+	return false, err
+}
+
+// ResynchronizeHOTP moves the counter of a user to a token that drifted beyond the
+// look-ahead window (RFC 4226, section 7.4): the user submits two consecutive codes,
+// searched within ResyncWindow values of the counter. Failed resynchronizations count
+// as failed verifications.
+//
+// Parameters:
+//   - ctx: Context for the Redis operations
+//   - userID: User identifier
+//   - secret: Shared secret of the user's token
+//   - code: A code displayed by the token
+//   - next: The code displayed right after it
+//
+// Returns:
+//   - bool: true if the counter was resynchronized, past both codes
+//   - error: Same errors as VerifyHOTP
+func (hs *HOTPService) ResynchronizeHOTP(ctx context.Context, userID string, secret []byte, code string, next string) (bool, error) {
+	if err := hs.validate(userID, secret, code); err != nil {
+		return false, err
+	}
+	if err := hs.validate(userID, secret, next); err != nil {
+		return false, err
+	}
+	counter, err := hs.checkAttempts(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range uint64(hs.resyncWindow) {
+		matched, err := hs.matches(secret, counter+i, code)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			continue
+		}
+		if matched, err = hs.matches(secret, counter+i+1, next); err != nil {
+			return false, err
+		}
+		if matched {
+			return hs.advance(ctx, userID, counter, counter+i+2)
+		}
+	}
+
+	_, err = hs.attempts.increment(ctx, userID)
+	return false, err
+}
+
+// RevokeHOTP deletes the counter and the failed verifications of a user, when the
+// token is unassigned.
+func (hs *HOTPService) RevokeHOTP(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if err := hs.db.Del(ctx, hs.counterKey(userID)).Err(); err != nil {
+		return err
+	}
+	return hs.attempts.revoke(ctx, userID)
+}
+
+// validate checks the arguments of a verification.
+func (hs *HOTPService) validate(userID string, secret []byte, code string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+	if len(secret) == 0 {
+		return errors.New("hotp secret is empty")
+	}
+	if len(code) != hs.digits {
+		return ErrInvalidOTP
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return ErrInvalidOTP
+		}
+	}
+	return nil
+}
+
+// checkAttempts returns the counter of a user, or ErrMaxAttemptsExceeded if the user is locked out.
+func (hs *HOTPService) checkAttempts(ctx context.Context, userID string) (uint64, error) {
+	attempts, err := hs.attempts.get(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if attempts >= hs.maxAttempts {
+		return 0, ErrMaxAttemptsExceeded
+	}
+	return hs.Counter(ctx, userID)
+}
+
+// matches tells whether code is the code of the counter value, in constant time.
+func (hs *HOTPService) matches(secret []byte, counter uint64, code string) (bool, error) {
+	expected, err := lib.GenerateHOTP(secret, counter, hs.digits)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1, nil
+}
+
+// advance moves the counter from current to next and resets the failed verifications.
+// It returns false if the counter moved in between (the code was used concurrently).
+func (hs *HOTPService) advance(ctx context.Context, userID string, current uint64, next uint64) (bool, error) {
+	moved, err := advanceHOTPCounterScript.Run(ctx, hs.db, []string{hs.counterKey(userID)},
+		strconv.FormatUint(current, 10), strconv.FormatUint(next, 10)).Int()
+	if err != nil {
+		return false, err
+	}
+	if moved == 0 {
+		return false, nil
+	}
+	return true, hs.attempts.revoke(ctx, userID)
+}
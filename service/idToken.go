@@ -0,0 +1,160 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidNonce is returned by VerifyIDToken when the nonce claim does not
+// match the nonce of the authentication request.
+var ErrInvalidNonce = errors.New("invalid nonce")
+
+// IDTokenParams describes the authentication an ID token is issued for.
+//
+// Fields:
+//   - Audience: Client ID of the relying party (required)
+//   - Nonce: Nonce of the authentication request, copied into the token when set
+//   - Authentication: Factors used and authentication time (auth_time defaults to now)
+//   - EmailVerified: Whether the user email address was verified, omitted when nil
+type IDTokenParams struct {
+	Audience       string
+	Nonce          string
+	Authentication *modelAuth.Authentication
+	EmailVerified  *bool
+}
+
+// CreateIDToken generates an OpenID Connect ID token for an authenticated user,
+// to be returned alongside the access token by a minimal OIDC provider.
+// The token carries iss, sub, aud, exp, iat, auth_time and, when known, nonce,
+// email, email_verified, amr and acr.
+//
+// ID tokens are signed with the JWT algorithm (HS256 by default) and secret, like access
+// tokens: relying parties verify them with VerifyIDToken.
+//
+// Parameters:
+//   - user: Authenticated user containing ID and Email
+//   - params: Audience, nonce and authentication details
+//
+// Returns:
+//   - string: Signed JWT ID token
+//   - error: Validation, token generation or signing errors
+//
+// Example:
+//
+//	idToken, err := accessService.CreateIDToken(user, service.IDTokenParams{
+//	    Audience: clientID,
+//	    Nonce:    authRequest.Nonce,
+//	    Authentication: &modelAuth.Authentication{
+//	        Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword},
+//	    },
+//	})
+//	// {"access_token": "...", "token_type": "Bearer", "id_token": idToken}
+func (at *AccessTokenService) CreateIDToken(user *modelAuth.User, params IDTokenParams) (string, error) {
+	if user == nil || user.ID == "" {
+		return "", ErrInvalidUserID
+	}
+	if params.Audience == "" {
+		return "", errors.New("invalid audience")
+	}
+
+	duration, err := time.ParseDuration(at.config.JWTExpiry)
+	if err != nil {
+		return "", err
+	}
+
+	now := at.now()
+	claim := &modelAuth.IDTokenClaim{
+		KeyType:       "id",
+		Email:         user.Email,
+		EmailVerified: params.EmailVerified,
+		Nonce:         params.Nonce,
+		AuthTime:      jwt.NewNumericDate(now),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    at.config.Issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{params.Audience},
+			ID:        uuid.New().String(),
+		},
+	}
+	if params.Authentication != nil {
+		for _, method := range params.Authentication.Methods {
+			claim.AMR = append(claim.AMR, string(method))
+		}
+		claim.ACR = params.Authentication.AssuranceLevel()
+		if !params.Authentication.Time.IsZero() {
+			claim.AuthTime = jwt.NewNumericDate(params.Authentication.Time)
+		}
+	}
+
+	method, err := jwtSigningMethod(at.config)
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(method, claim).SignedString([]byte(at.config.JWTSecret))
+}
+
+// VerifyIDToken validates an ID token created by CreateIDToken for the given client.
+//
+// Verification process:
+//  1. Parse the JWT and verify the signature using JWTSecret, with the JWTAlgorithm only
+//  2. Check expiration with 5-second leeway, the issuer and the audience
+//  3. Check the token type (key_type "id"), so that access tokens are rejected, even those
+//     issued for the client ID with CreateAccessTokenForAudience
+//  4. Check the nonce when one was sent in the authentication request (ErrInvalidNonce)
+//
+// Parameters:
+//   - token: JWT ID token string to verify
+//   - audience: Client ID expected in the aud claim
+//   - nonce: Nonce of the authentication request, empty if none was sent
+//
+// Returns:
+//   - *modelAuth.IDTokenClaim: Parsed token claims (nil if invalid)
+//   - error: Signature, expiration, issuer, audience, token type (ErrInvalidTokenClaim) or nonce errors
+//
+// Example:
+//
+//	claim, err := accessService.VerifyIDToken(idToken, clientID, session.Nonce)
+//	if err != nil {
+//	    return errors.New("invalid id token")
+//	}
+func (at *AccessTokenService) VerifyIDToken(token string, audience string, nonce string) (*modelAuth.IDTokenClaim, error) {
+	if audience == "" {
+		return nil, errors.New("invalid audience")
+	}
+
+	method, err := jwtSigningMethod(at.config)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := jwt.ParseWithClaims(token, &modelAuth.IDTokenClaim{}, func(token *jwt.Token) (any, error) {
+		return []byte(at.config.JWTSecret), nil
+	},
+		jwt.WithLeeway(accessTokenLeeway),
+		jwt.WithTimeFunc(at.now),
+		jwt.WithValidMethods([]string{method.Alg()}),
+		jwt.WithIssuer(at.config.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claim, ok := t.Claims.(*modelAuth.IDTokenClaim)
+	if !ok || !t.Valid || claim.KeyType != "id" {
+		return nil, ErrInvalidTokenClaim
+	}
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(claim.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidNonce
+	}
+
+	return claim, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameInvalidTokens is the Redis key prefix for invalid token counters per source.
+	// Key pattern: "abuse:invalid_tokens:{source}" with the number of invalid tokens presented,
+	// expiring with the window opened by the first one.
+	redisStoreNameInvalidTokens string = "abuse:invalid_tokens"
+
+	defaultInvalidTokenThreshold int           = 20
+	defaultInvalidTokenWindow    time.Duration = 10 * time.Minute
+)
+
+// InvalidTokenReport describes a source crossing an invalid token threshold.
+//
+// Fields:
+//   - Source: The source identifier (client IP by default)
+//   - TokenType: Type of the token presented last
+//   - Count: Invalid tokens presented by the source within the window
+//   - Threshold: The threshold crossed
+//   - Window: Counting window
+type InvalidTokenReport struct {
+	Source    string
+	TokenType lib.TokenType
+	Count     int
+	Threshold int
+	Window    time.Duration
+}
+
+// InvalidTokenMonitorOptions configures an InvalidTokenMonitor.
+//
+// Fields:
+//   - Thresholds: Counts at which OnThreshold is called, e.g. [20, 100] to alert then block (default: [20])
+//   - Window: Counting window, opened by the first invalid token of a source (default: 10 minutes)
+//   - Source: Identifies the source of a request (default: IP of the lib.RequestMeta in the context);
+//     requests without source are not counted
+//   - OnThreshold: Called once per threshold and window when a source reaches it
+type InvalidTokenMonitorOptions struct {
+	Thresholds  []int
+	Window      time.Duration
+	Source      func(ctx context.Context) string
+	OnThreshold func(ctx context.Context, report InvalidTokenReport)
+}
+
+// InvalidTokenMonitor counts the nonexistent, expired or malformed tokens presented
+// per source, so that applications can block or alert on token guessing and credential
+// stuffing. Counters live in Redis, like the OTP attempts, and are shared by replicas.
+//
+// Services report to the monitor once plugged with SetInvalidTokenMonitor
+// (RefreshTokenService, PasswordResetService); other credentials checked by the
+// application (e.g. API keys) can be reported with Record.
+//
+// Redis key pattern:
+//   - Counter: "abuse:invalid_tokens:{source}" → counter (integer), expiring after Window
+type InvalidTokenMonitor struct {
+	counter     *attemptCounter
+	thresholds  []int
+	source      func(ctx context.Context) string
+	onThreshold func(ctx context.Context, report InvalidTokenReport)
+}
+
+// NewInvalidTokenMonitor creates an invalid token monitor.
+// Returns an error if the database client is nil or a threshold is not positive.
+//
+// Parameters:
+//   - db: Redis client for the counters
+//   - opts: Thresholds, window, source and callback
+//   - serviceOpts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *InvalidTokenMonitor: Initialized monitor, to plug in the services
+//   - error: Validation errors
+//
+// Example:
+//
+//	monitor, err := service.NewInvalidTokenMonitor(redisClient, service.InvalidTokenMonitorOptions{
+//	    Thresholds: []int{20, 100},
+//	    OnThreshold: func(ctx context.Context, report service.InvalidTokenReport) {
+//	        if report.Threshold >= 100 {
+//	            firewall.Block(report.Source, time.Hour)
+//	        }
+//	        alerts.Send("invalid tokens", report.Source, report.Count)
+//	    },
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	refreshService.SetInvalidTokenMonitor(monitor)
+//	resetService.SetInvalidTokenMonitor(monitor)
+func NewInvalidTokenMonitor(db *redis.Client, opts InvalidTokenMonitorOptions, serviceOpts ...Option) (*InvalidTokenMonitor, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	thresholds := slices.Clone(opts.Thresholds)
+	if len(thresholds) == 0 {
+		thresholds = []int{defaultInvalidTokenThreshold}
+	}
+	for _, threshold := range thresholds {
+		if threshold <= 0 {
+			return nil, errors.New("invalid token thresholds must be positive")
+		}
+	}
+
+	window := opts.Window
+	if window <= 0 {
+		window = defaultInvalidTokenWindow
+	}
+	source := opts.Source
+	if source == nil {
+		source = requestIP
+	}
+
+	options := newServiceOptions(serviceOpts)
+	return &InvalidTokenMonitor{
+		counter:     newAttemptCounter(db, options.keyPrefix.name(redisStoreNameInvalidTokens), window),
+		thresholds:  thresholds,
+		source:      source,
+		onThreshold: opts.OnThreshold,
+	}, nil
+}
+
+// Record counts an invalid token presented by the source of ctx, and calls OnThreshold
+// when the count reaches a threshold.
+//
+// Parameters:
+//   - ctx: Context of the request, carrying its source (uses Background if nil)
+//   - tokenType: Type of the invalid token, reported to OnThreshold
+//
+// Returns:
+//   - int: Invalid tokens presented by the source within the window, 0 without source
+//   - error: Storage errors
+//
+// Example:
+//
+//	if !apiKeys.Exists(key) {
+//	    _, _ = monitor.Record(r.Context(), lib.TokenType("ak"))
+//	    w.WriteHeader(http.StatusUnauthorized)
+//	    return
+//	}
+func (m *InvalidTokenMonitor) Record(ctx context.Context, tokenType lib.TokenType) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	source := m.source(ctx)
+	if source == "" {
+		return 0, nil
+	}
+
+	count, err := m.counter.increment(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+
+	if m.onThreshold != nil && slices.Contains(m.thresholds, count) {
+		m.onThreshold(ctx, InvalidTokenReport{
+			Source:    source,
+			TokenType: tokenType,
+			Count:     count,
+			Threshold: count,
+			Window:    m.counter.window,
+		})
+	}
+	return count, nil
+}
+
+// Count returns the invalid tokens presented by a source within the current window,
+// e.g. to reject its requests while it exceeds a threshold.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - source: The source identifier (client IP by default)
+//
+// Returns:
+//   - int: Invalid tokens presented, 0 if none
+//   - error: Storage errors
+func (m *InvalidTokenMonitor) Count(ctx context.Context, source string) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return m.counter.get(ctx, source)
+}
+
+// Reset clears the counter of a source, e.g. after unblocking it.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - source: The source identifier (client IP by default)
+//
+// Returns:
+//   - error: Storage errors
+func (m *InvalidTokenMonitor) Reset(ctx context.Context, source string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return m.counter.revoke(ctx, source)
+}
+
+// observe records the outcome of a token verification when the token was not found or
+// malformed. Policy and storage errors are not counted. Recording is best effort.
+func (m *InvalidTokenMonitor) observe(ctx context.Context, tokenType lib.TokenType, valid bool, err error) {
+	if m == nil || valid {
+		return
+	}
+
+	var validationError *validation.ValidationError
+	if err == nil || errors.As(err, &validationError) {
+		_, _ = m.Record(ctx, tokenType)
+	}
+}
+
+// requestIP returns the client IP of the request metadata in ctx.
+func requestIP(ctx context.Context) string {
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	return meta.IP
+}
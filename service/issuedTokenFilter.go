@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisChannelIssuedTokens is the Redis Pub/Sub channel prefix announcing issued tokens.
+	// Channel pattern: "issued_tokens:{tokenType}" with the SHA-256 hash of each new token.
+	redisChannelIssuedTokens string = "issued_tokens"
+
+	defaultIssuedTokenFilterCapacity          int           = 1_000_000
+	defaultIssuedTokenFilterFalsePositiveRate float64       = 0.001
+	defaultIssuedTokenFilterRebuildInterval   time.Duration = time.Hour
+)
+
+// errFilterBypassed is returned by a rebuild interrupted by a bypass of the filter.
+var errFilterBypassed = errors.New("issued token filter bypassed during rebuild")
+
+// IssuedTokenFilterOptions configures an IssuedTokenFilter.
+//
+// Fields:
+//   - Capacity: Expected number of live refresh tokens (default: 1 000 000); rebuilds
+//     size the filter for at least twice the tokens found by the previous one
+//   - FalsePositiveRate: Share of never-issued tokens still checked in Redis (default: 0.001)
+//   - RebuildInterval: Time between two rebuilds, which forget expired and revoked tokens (default: 1 hour)
+//   - BatchSize: SCAN count hint of the rebuilds (default: 500)
+//   - OnRebuild: Called after each rebuild with the number of tokens found, or the error
+type IssuedTokenFilterOptions struct {
+	Capacity          int
+	FalsePositiveRate float64
+	RebuildInterval   time.Duration
+	BatchSize         int
+	OnRebuild         func(tokens int64, err error)
+}
+
+// IssuedTokenFilter is an in-memory Bloom filter of the refresh tokens issued by a
+// RefreshTokenService. Verification rejects tokens absent from the filter without
+// querying Redis, so that credential stuffing with made-up tokens costs no database
+// round trip. Tokens present in the filter (issued, or false positives) are checked
+// in Redis as usual.
+//
+// Replicas share the issued tokens through Redis Pub/Sub: every replica creating
+// refresh tokens must enable the filter, otherwise its tokens are rejected by the
+// filtering replicas until their next rebuild. The filter is bypassed (every token is
+// checked in Redis) until its first rebuild completes, and after a Pub/Sub reconnection
+// until the following rebuild, as announcements may have been missed.
+//
+// Rebuilds scan the refresh token keys into a new filter, resized from the number of
+// live tokens, while new announcements go to both filters.
+type IssuedTokenFilter struct {
+	rts     *RefreshTokenService
+	opts    IssuedTokenFilterOptions
+	channel string
+
+	mu         sync.RWMutex
+	current    *lib.BloomFilter // nil while bypassed
+	next       *lib.BloomFilter // filter being rebuilt
+	live       int64            // tokens found by the last rebuild
+	generation uint64           // incremented by each bypass
+
+	rejected   atomic.Int64
+	rebuilding atomic.Bool
+}
+
+// EnableIssuedTokenFilter plugs an issued token filter in the service. The filter is
+// bypassed until Run completed a first rebuild.
+//
+// Parameters:
+//   - opts: Capacity, false positive rate and rebuild interval
+//
+// Returns:
+//   - *IssuedTokenFilter: The filter, to be maintained by Run
+//   - error: Validation errors
+//
+// Example:
+//
+//	filter, err := refreshService.EnableIssuedTokenFilter(service.IssuedTokenFilterOptions{
+//	    Capacity:          5_000_000,
+//	    FalsePositiveRate: 0.0001,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	go filter.Run(ctx)
+func (rts *RefreshTokenService) EnableIssuedTokenFilter(opts IssuedTokenFilterOptions) (*IssuedTokenFilter, error) {
+	if rts.db == nil {
+		return nil, fmt.Errorf("issued token filter: %w", ErrTokenStoreUnsupported)
+	}
+	if opts.FalsePositiveRate < 0 || opts.FalsePositiveRate >= 1 {
+		return nil, errors.New("false positive rate must be between 0 and 1")
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = defaultIssuedTokenFilterCapacity
+	}
+	if opts.FalsePositiveRate == 0 {
+		opts.FalsePositiveRate = defaultIssuedTokenFilterFalsePositiveRate
+	}
+	if opts.RebuildInterval <= 0 {
+		opts.RebuildInterval = defaultIssuedTokenFilterRebuildInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultCleanupBatchSize
+	}
+
+	filter := &IssuedTokenFilter{
+		rts:     rts,
+		opts:    opts,
+		channel: fmt.Sprintf("%s:%s", rts.keys.name(redisChannelIssuedTokens), lib.TokenTypeRefresh),
+	}
+
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.filter = filter
+	return filter, nil
+}
+
+// Run subscribes to the issued token announcements and rebuilds the filter on start,
+// on every Pub/Sub (re)connection and every RebuildInterval, until ctx is done.
+// It blocks: run it in its own goroutine.
+//
+// Parameters:
+//   - ctx: Stops the filter when done, which is then bypassed
+//
+// Returns:
+//   - error: The context error once ctx is done
+func (f *IssuedTokenFilter) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	defer f.bypass()
+
+	pubsub := f.rts.db.Subscribe(ctx, f.channel)
+	defer pubsub.Close()
+	messages := pubsub.ChannelWithSubscriptions()
+
+	ticker := time.NewTicker(f.opts.RebuildInterval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	rebuild := func() {
+		if !f.rebuilding.CompareAndSwap(false, true) {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer f.rebuilding.Store(false)
+			tokens, err := f.rebuild(ctx)
+			for errors.Is(err, errFilterBypassed) {
+				tokens, err = f.rebuild(ctx)
+			}
+			if f.opts.OnRebuild != nil && ctx.Err() == nil {
+				f.opts.OnRebuild(tokens, err)
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			rebuild()
+		case msg, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// Announcements may have been missed while disconnected
+				f.bypass()
+				rebuild()
+			case *redis.Message:
+				f.add(msg.Payload)
+			}
+		}
+	}
+}
+
+// Ready reports whether the filter is in use (not bypassed).
+func (f *IssuedTokenFilter) Ready() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.current != nil
+}
+
+// Rejected returns the number of tokens rejected by the filter without querying Redis.
+func (f *IssuedTokenFilter) Rejected() int64 {
+	return f.rejected.Load()
+}
+
+// FalsePositiveRate estimates the share of never-issued tokens the filter lets through,
+// to tune Capacity and FalsePositiveRate (0 while bypassed).
+func (f *IssuedTokenFilter) FalsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.current == nil {
+		return 0
+	}
+	return f.current.FalsePositiveRate()
+}
+
+// mightBeIssued reports whether token may have been issued, true while bypassed.
+func (f *IssuedTokenFilter) mightBeIssued(token string) bool {
+	f.mu.RLock()
+	current := f.current
+	f.mu.RUnlock()
+
+	if current == nil || current.MightContain(hashToken(token)) {
+		return true
+	}
+	f.rejected.Add(1)
+	return false
+}
+
+// add records the hash of an issued token, also in the filter being rebuilt.
+func (f *IssuedTokenFilter) add(hash string) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.current != nil {
+		f.current.Add(hash)
+	}
+	if f.next != nil {
+		f.next.Add(hash)
+	}
+}
+
+func (f *IssuedTokenFilter) bypass() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current = nil
+	f.generation++
+}
+
+// rebuild scans the refresh token keys into a new filter and swaps it in, unless
+// the filter was bypassed meanwhile (announcements may have been missed by both).
+func (f *IssuedTokenFilter) rebuild(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	generation := f.generation
+	next, err := lib.NewBloomFilter(max(f.opts.Capacity, int(2*f.live)), f.opts.FalsePositiveRate)
+	if err != nil {
+		f.mu.Unlock()
+		return 0, err
+	}
+	f.next = next
+	f.mu.Unlock()
+
+	tokens, err := f.scan(ctx, next)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next = nil
+	if err != nil {
+		return tokens, err
+	}
+	if f.generation != generation {
+		return tokens, errFilterBypassed
+	}
+	f.current = next
+	f.live = tokens
+	return tokens, nil
+}
+
+// scan adds the hashes of the stored refresh tokens to filter.
+func (f *IssuedTokenFilter) scan(ctx context.Context, filter *lib.BloomFilter) (int64, error) {
+	prefix := f.rts.keys.name(redisStoreNameRefreshToken) + ":"
+	var tokens int64
+	var cursor uint64
+	for {
+		keys, next, err := f.rts.db.Scan(ctx, cursor, escapeScanPattern(prefix)+"*", int64(f.opts.BatchSize)).Result()
+		if err != nil {
+			return tokens, err
+		}
+
+		for _, key := range keys {
+			// "refresh:{userID}:{token}", tokens never contain ':'
+			separator := strings.LastIndex(key, ":")
+			if separator < len(prefix) {
+				continue
+			}
+			filter.Add(storedTokenHash(key[separator+1:]))
+			tokens++
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return tokens, nil
+		}
+	}
+}
+
+// storeToken stores a token under "refresh:{userID}:{stored}" with its lookup entry,
+// and announces it to the issued token filters when one is enabled. Services on a
+// token store put its hash in the store.
+func (rts *RefreshTokenService) storeToken(ctx context.Context, userID string, stored string, value string, ttl time.Duration) error {
+	hash := storedTokenHash(stored)
+	if rts.store != nil {
+		return rts.store.Put(ctx, StoredToken{UserID: userID, Hash: hash, Value: value, ExpiresAt: time.Now().Add(ttl)})
+	}
+
+	filter := rts.issuedTokenFilter()
+	if filter != nil {
+		// This replica first, so that the token can be verified here right away
+		filter.add(hash)
+	}
+
+	_, err := rts.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, rts.tokenKey(userID, stored), value, ttl)
+		pipe.Set(ctx, rts.lookupKey(hash), userID, ttl)
+		if filter != nil {
+			pipe.Publish(ctx, filter.channel, hash)
+		}
+		return nil
+	})
+	return err
+}
+
+// storedTokenHash returns the SHA-256 hash of a token from the token part of its key,
+// stored in clear or hashed.
+func storedTokenHash(stored string) string {
+	if hash, hashed := strings.CutPrefix(stored, refreshTokenHashedMarker); hashed {
+		return hash
+	}
+	return hashToken(stored)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameConsumedJTI is the Redis key prefix for consumed jtis.
+	// Key pattern: "consumed_jti:{jti}" holding "1", expiring with the token.
+	redisStoreNameConsumedJTI string = "consumed_jti"
+
+	// accessTokenLeeway is the clock skew tolerated on access token expiration.
+	accessTokenLeeway time.Duration = 5 * time.Second
+)
+
+// ErrTokenReplayed is returned when a one-time-use access token is presented
+// after its first successful verification.
+var ErrTokenReplayed = errors.New("token already used")
+
+// JTIStore records the jtis of the one-time-use access tokens consumed by
+// VerifyAccessToken, so that short-lived action tokens (confirm an email change,
+// approve a payment...) are accepted once, although they are stateless. Entries
+// expire with the tokens: an expired token is rejected by its exp claim anyway.
+//
+// Redis key pattern:
+//   - Key: "consumed_jti:{jti}"
+//   - Value: "1"
+//   - TTL: Remaining lifetime of the token, plus the 5-second verification leeway
+type JTIStore struct {
+	db   *redis.Client
+	keys keyPrefix
+}
+
+// NewJTIStore creates a new jti store instance with Redis persistence.
+// Returns an error if the database client is nil. The context is unused: the store
+// does not touch Redis until the first Consume, which takes its own context.
+// Accepts WithKeyPrefix.
+//
+// Example:
+//
+//	jtiStore, err := service.NewJTIStore(ctx, redisClient)
+//	accessService.SetJTIStore(jtiStore)
+func NewJTIStore(ctx context.Context, db *redis.Client, opts ...Option) (*JTIStore, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	return &JTIStore{db: db, keys: newServiceOptions(opts).keyPrefix}, nil
+}
+
+// Consume records a jti as consumed for ttl, atomically (SET NX), so that
+// concurrent verifications of the same token on several replicas accept only one.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - jti: The token identifier
+//   - ttl: How long to remember the jti, at least the remaining lifetime of the token
+//
+// Returns:
+//   - bool: true on the first consumption, false when the jti was already consumed
+//   - error: Validation or storage errors
+func (js *JTIStore) Consume(ctx context.Context, jti string, ttl time.Duration) (bool, error) {
+	if jti == "" {
+		return false, errors.New("invalid jti")
+	}
+	if ttl <= 0 {
+		return false, errors.New("invalid ttl")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return js.db.SetNX(ctx, fmt.Sprintf("%s:%s", js.keys.name(redisStoreNameConsumedJTI), jti), "1", ttl).Result()
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// KillSwitch revokes every outstanding credential at once after a security incident.
+//
+// Revocation order:
+//  1. Bump the global token epoch: stateless access tokens are rejected immediately
+//  2. Revoke all refresh tokens, password reset tokens and OTPs, concurrently
+//
+// Every step runs even if a previous one failed, errors are joined.
+type KillSwitch struct {
+	refresh *RefreshTokenService
+	reset   *PasswordResetService
+	otp     *OTPService
+	epochs  *TokenEpochService
+
+	// mu guards the audit logger, which can be set while tokens are revoked.
+	mu    sync.RWMutex
+	audit lib.AuditLogger
+}
+
+// NewKillSwitch creates a kill switch over the configured services.
+// A nil service is skipped. Access tokens are only revoked when epochs are
+// enabled on the AccessTokenService with the same TokenEpochService.
+// Accepts WithLogger.
+//
+// Example:
+//
+//	killSwitch := service.NewKillSwitch(refreshService, resetService, otpService, epochService)
+//	if err := killSwitch.EmergencyRevokeAll(ctx); err != nil {
+//	    log.Printf("Emergency revocation incomplete: %v", err)
+//	}
+func NewKillSwitch(refresh *RefreshTokenService, reset *PasswordResetService, otp *OTPService, epochs *TokenEpochService, opts ...Option) *KillSwitch {
+	return &KillSwitch{
+		refresh: refresh,
+		reset:   reset,
+		otp:     otp,
+		epochs:  epochs,
+		audit:   newServiceOptions(opts).audit,
+	}
+}
+
+// SetAuditLogger configures the logger receiving the "emergency.revoke_all" audit event.
+// A nil logger disables auditing.
+func (ks *KillSwitch) SetAuditLogger(logger lib.AuditLogger) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.audit = logger
+}
+
+func (ks *KillSwitch) auditLogger() lib.AuditLogger {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.audit
+}
+
+// EmergencyRevokeAll revokes all tokens of all users for every configured service.
+//
+// Warning: This is a destructive operation, every user is logged out.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Joined errors of the failed steps, nil if everything was revoked
+func (ks *KillSwitch) EmergencyRevokeAll(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var errs []error
+	details := map[string]string{}
+
+	if ks.epochs != nil {
+		epoch, err := ks.epochs.BumpGlobalEpoch(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to bump token epoch: %w", err))
+		} else {
+			details["epoch"] = strconv.FormatInt(epoch, 10)
+		}
+	}
+	var steps []func(ctx context.Context) error
+	if ks.refresh != nil {
+		steps = append(steps, func(ctx context.Context) error {
+			if err := ks.refresh.RevokeAllRefreshTokens(ctx); err != nil {
+				return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+			}
+			return nil
+		})
+	}
+	if ks.reset != nil {
+		steps = append(steps, func(ctx context.Context) error {
+			if err := ks.reset.RevokeAllPasswordResetTokens(ctx); err != nil {
+				return fmt.Errorf("failed to revoke password reset tokens: %w", err)
+			}
+			return nil
+		})
+	}
+	if ks.otp != nil {
+		steps = append(steps, func(ctx context.Context) error {
+			if err := ks.otp.RevokeAllOTPs(ctx); err != nil {
+				return fmt.Errorf("failed to revoke otps: %w", err)
+			}
+			return nil
+		})
+	}
+	errs = append(errs, forEachParallel(ctx, steps, len(steps), func(ctx context.Context, step func(context.Context) error) error {
+		return step(ctx)
+	}))
+
+	err := errors.Join(errs...)
+	details["complete"] = strconv.FormatBool(err == nil)
+	emitAudit(ctx, ks.auditLogger(), AuditEventEmergencyRevokeAll, "", details)
+
+	return err
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KVStore is the key-value storage of an OTP service created with
+// NewOTPServiceWithStore, for deployments where go-redis is not the client of choice.
+// Implementations must be safe for concurrent use.
+//
+// Provided implementations:
+//   - NewRedisKVStore: Redis and Redis-compatible servers (Valkey, KeyDB, Dragonfly)
+//   - NewMemoryKVStore: In-process map, for tests and single-instance deployments
+//   - dynamostore.NewKVStore: Amazon DynamoDB table
+//   - mongostore.NewKVStore: MongoDB collection
+type KVStore interface {
+	// Get returns the value of key, false if it does not exist or expired.
+	Get(ctx context.Context, key string) (string, bool, error)
+	// SetEX stores value under key, expiring after ttl.
+	SetEX(ctx context.Context, key string, value string, ttl time.Duration) error
+	// Del deletes the keys and returns how many existed.
+	Del(ctx context.Context, keys ...string) (int64, error)
+	// Incr increments the integer under key and returns the new value. A missing key
+	// is created with the value 1, expiring after ttl; an existing key keeps its expiry.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// TTL returns how long key lives, 0 if it does not exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Scan calls fn with the keys starting with prefix, at most count at a time,
+	// and stops at the first error of fn.
+	Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error
+}
+
+// incrWithTTLScript increments KEYS[1] and sets its expiry to ARGV[1] milliseconds
+// when it has none, i.e. when INCR just created it. Both steps run atomically, so a
+// counter never lives without expiry, even if the client dies in between.
+var incrWithTTLScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// redisKVStore implements KVStore on a go-redis client.
+type redisKVStore struct {
+	db *redis.Client
+}
+
+// NewRedisKVStore returns a KVStore on a go-redis client, connected to Redis or to a
+// protocol-compatible server such as Valkey. Keys are deleted one by one, so that
+// they can be spread over Redis Cluster slots.
+//
+// Example:
+//
+//	valkey := redis.NewClient(&redis.Options{Addr: "valkey:6379"})
+//	otpService, err := service.NewOTPServiceWithStore(ctx, service.NewRedisKVStore(valkey), config)
+func NewRedisKVStore(db *redis.Client) KVStore {
+	return &redisKVStore{db: db}
+}
+
+func (s *redisKVStore) Get(ctx context.Context, key string) (string, bool, error) {
+	return stringResult(s.db.Get(ctx, key))
+}
+
+func (s *redisKVStore) SetEX(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return s.db.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisKVStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return deleteEach(ctx, s.db, keys)
+}
+
+func (s *redisKVStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+	return incrWithTTLScript.Run(ctx, s.db, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+func (s *redisKVStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.db.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Negative when the key doesn't exist (-2) or has no TTL (-1)
+	return max(ttl, 0), nil
+}
+
+func (s *redisKVStore) Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.db.Scan(ctx, cursor, escapeScanPattern(prefix)+"*", int64(count)).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// memoryEntry is a value of a memoryKVStore with its expiry.
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// memoryKVStore implements KVStore in process memory. Expired keys are dropped
+// when they are accessed or scanned.
+type memoryKVStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryKVStore returns a KVStore keeping its keys in process memory, for tests
+// and single-instance deployments: the keys are neither shared between instances nor
+// kept across restarts.
+//
+// Example:
+//
+//	otpService, err := service.NewOTPServiceWithStore(ctx, service.NewMemoryKVStore(), config)
+func NewMemoryKVStore() KVStore {
+	return &memoryKVStore{
+		now:     time.Now,
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+// entry returns the live entry of key, dropping it if expired. Callers hold mu.
+func (s *memoryKVStore) entry(key string) (memoryEntry, bool) {
+	e, ok := s.entries[key]
+	if ok && !s.now().Before(e.expires) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+func (s *memoryKVStore) Get(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entry(key)
+	return e.value, ok, nil
+}
+
+func (s *memoryKVStore) SetEX(_ context.Context, key string, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{value: value, expires: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryKVStore) Del(_ context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, key := range keys {
+		if _, ok := s.entry(key); ok {
+			delete(s.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryKVStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl for key %s: %s", key, ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entry(key)
+	if !ok {
+		s.entries[key] = memoryEntry{value: "1", expires: s.now().Add(ttl)}
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(e.value, 10, 64)
+	if err != nil {
+		return 0, errors.New("value is not an integer")
+	}
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	s.entries[key] = e
+	return n, nil
+}
+
+func (s *memoryKVStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entry(key)
+	if !ok {
+		return 0, nil
+	}
+	return e.expires.Sub(s.now()), nil
+}
+
+func (s *memoryKVStore) Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error {
+	if count <= 0 {
+		count = defaultCleanupBatchSize
+	}
+
+	s.mu.Lock()
+	var keys []string
+	for key := range s.entries {
+		if _, ok := s.entry(key); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.Unlock()
+
+	slices.Sort(keys)
+	for batch := range slices.Chunk(keys, count) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
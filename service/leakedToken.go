@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// LeakedTokenReport describes a token found in a public place, e.g. a
+// secret-scanning alert from a code hosting platform.
+//
+// Fields:
+//   - Token: The leaked token
+//   - Source: Who reported the leak (e.g. "github")
+//   - URL: Where the token was found, if known
+type LeakedTokenReport struct {
+	Token  string `json:"token"`
+	Source string `json:"source"`
+	URL    string `json:"url"`
+}
+
+// LeakedTokenResult is the outcome of a leak report.
+//
+// Fields:
+//   - Revoked: true if the token existed and has been revoked (true positive)
+//   - Type: Type of the revoked token, empty if not found
+//   - UserID: Owner of the revoked token, empty if not found
+type LeakedTokenResult struct {
+	Revoked bool
+	Type    lib.TokenType
+	UserID  string
+}
+
+// LeakedTokenResponder is the end-to-end response path for leaked tokens:
+// it authenticates the report, revokes the token wherever it exists and emits
+// a "token.leak_reported" audit event.
+//
+// Security features:
+//   - Reports are authenticated with an HMAC-SHA256 signature (shared secret)
+//   - Tokens are found through hashed lookup entries, without scanning the keyspace
+//   - Tokens are revoked immediately with the LEAKED reason, no verification side
+//     effects (risk evaluation, geo policy, last use) are triggered
+type LeakedTokenResponder struct {
+	refresh *RefreshTokenService
+	reset   *PasswordResetService
+	secret  []byte
+
+	// mu guards the audit logger, which can be set while reports are handled.
+	mu    sync.RWMutex
+	audit lib.AuditLogger
+}
+
+// NewLeakedTokenResponder creates a responder over the given services.
+// A nil service is skipped.
+//
+// Parameters:
+//   - refresh: Refresh token service
+//   - reset: Password reset service
+//   - secret: HMAC key shared with the reporter, used by VerifySignature
+//   - opts: Optional settings (WithLogger)
+//
+// Returns:
+//   - *LeakedTokenResponder: Responder ready for use
+//   - error: Empty secret
+//
+// Example:
+//
+//	responder, err := service.NewLeakedTokenResponder(refreshService, resetService, os.Getenv("LEAK_REPORT_SECRET"))
+func NewLeakedTokenResponder(refresh *RefreshTokenService, reset *PasswordResetService, secret string, opts ...Option) (*LeakedTokenResponder, error) {
+	if secret == "" {
+		return nil, errors.New("leak report secret is empty")
+	}
+
+	return &LeakedTokenResponder{
+		refresh: refresh,
+		reset:   reset,
+		secret:  []byte(secret),
+		audit:   newServiceOptions(opts).audit,
+	}, nil
+}
+
+// SetAuditLogger configures the logger receiving the leak audit events.
+// A nil logger disables auditing.
+func (ltr *LeakedTokenResponder) SetAuditLogger(logger lib.AuditLogger) {
+	ltr.mu.Lock()
+	defer ltr.mu.Unlock()
+	ltr.audit = logger
+}
+
+func (ltr *LeakedTokenResponder) auditLogger() lib.AuditLogger {
+	ltr.mu.RLock()
+	defer ltr.mu.RUnlock()
+	return ltr.audit
+}
+
+// VerifySignature checks the hex-encoded HMAC-SHA256 signature of a report payload
+// (constant-time comparison). Reports failing this check must be discarded.
+//
+// Parameters:
+//   - payload: The raw request body
+//   - signature: Hex-encoded signature sent by the reporter (an optional "sha256=" prefix is accepted)
+//
+// Returns:
+//   - bool: true if the payload was signed with the shared secret
+//
+// Example:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	if !responder.VerifySignature(body, r.Header.Get("X-Signature")) {
+//	    w.WriteHeader(http.StatusUnauthorized)
+//	    return
+//	}
+func (ltr *LeakedTokenResponder) VerifySignature(payload []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, ltr.secret)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// HandleLeak revokes the reported token if it exists and emits a "token.leak_reported"
+// audit event carrying the source, URL and outcome.
+//
+// The owner of the token is found through the hashed lookup entries of the services
+// ("refresh_lookup:{sha256(token)}", "password_reset_lookup:{sha256(token)}"): each
+// report runs one GET per service, whatever the token. A found token is then revoked
+// with RevokeRefreshTokenWithReason or RevokePasswordResetTokenWithReason and the
+// LEAKED reason, so that the revocation is logged, audited and, for refresh tokens,
+// propagated (see SetRevocationPropagator). The token is normalized as configured
+// by each service (Config.TokenNormalization).
+//
+// Tokens issued before their service kept lookup entries are not found: they are
+// reported as not revoked.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - report: The leaked token report, already authenticated with VerifySignature
+//
+// Returns:
+//   - *LeakedTokenResult: Whether a token was revoked, and its type and owner
+//   - error: Storage errors, or the propagation error of a revoked refresh token
+//
+// Example:
+//
+//	result, err := responder.HandleLeak(ctx, report)
+//	if err != nil {
+//	    return err
+//	}
+//	label := "false_positive"
+//	if result.Revoked {
+//	    label = "true_positive"
+//	}
+func (ltr *LeakedTokenResponder) HandleLeak(ctx context.Context, report LeakedTokenReport) (*LeakedTokenResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	token := strings.TrimSpace(report.Token)
+	result := &LeakedTokenResult{}
+
+	// Both lookups always run, one GET each
+	refreshUserID, err := ltr.refreshTokenOwner(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	resetUserID, err := ltr.passwordResetTokenOwner(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshUserID != "" {
+		revoked, err := ltr.refresh.revokeRefreshToken(ctx, token, refreshUserID, RevocationReasonLeaked)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			result = &LeakedTokenResult{Revoked: true, Type: lib.TokenTypeRefresh, UserID: refreshUserID}
+		}
+	}
+	if !result.Revoked && resetUserID != "" {
+		err := ltr.reset.RevokePasswordResetTokenWithReason(ctx, resetUserID, token, RevocationReasonLeaked)
+		switch {
+		case err == nil:
+			result = &LeakedTokenResult{Revoked: true, Type: lib.TokenTypePasswordReset, UserID: resetUserID}
+		case errors.Is(err, errPasswordResetTokenNotFound), errors.Is(err, errPasswordResetTokenMismatch):
+			// Stale lookup entry, the token itself is gone
+		default:
+			return nil, err
+		}
+	}
+
+	emitAudit(ctx, ltr.auditLogger(), AuditEventTokenLeakReported, result.UserID, map[string]string{
+		"source":            report.Source,
+		"url":               report.URL,
+		"type":              string(result.Type),
+		"revoked":           fmt.Sprintf("%t", result.Revoked),
+		"token_fingerprint": lib.TokenFingerprint(token),
+	})
+
+	return result, nil
+}
+
+// refreshTokenOwner returns the user of a refresh token from its lookup entry, empty if none.
+func (ltr *LeakedTokenResponder) refreshTokenOwner(ctx context.Context, token string) (string, error) {
+	if ltr.refresh == nil || token == "" {
+		return "", nil
+	}
+
+	normalized := ltr.refresh.config.TokenNormalization.Normalize(token)
+	if ltr.refresh.store != nil {
+		stored, _, err := ltr.refresh.store.Find(ctx, hashToken(normalized))
+		return stored.UserID, err
+	}
+	userID, _, err := stringResult(ltr.refresh.db.Get(ctx, ltr.refresh.lookupKey(hashToken(normalized))))
+	return userID, err
+}
+
+// passwordResetTokenOwner returns the user of a password reset token from its lookup
+// entry, empty if none.
+func (ltr *LeakedTokenResponder) passwordResetTokenOwner(ctx context.Context, token string) (string, error) {
+	if ltr.reset == nil || token == "" {
+		return "", nil
+	}
+
+	normalized := ltr.reset.normalize(token)
+	if ltr.reset.store != nil {
+		stored, _, err := ltr.reset.store.Find(ctx, hashToken(normalized))
+		return stored.UserID, err
+	}
+	userID, _, err := stringResult(ltr.reset.db.Get(ctx, ltr.reset.lookupKey(normalized)))
+	return userID, err
+}
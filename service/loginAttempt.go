@@ -0,0 +1,543 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameLoginAttempts is the Redis key prefix for failed login counters.
+	// Key pattern: "login:attempts:{userID}" with the failure count, expiring with the attempt window.
+	redisStoreNameLoginAttempts string = "login:attempts"
+
+	// redisStoreNameLoginLock is the Redis key prefix for account locks.
+	// Key pattern: "login:lock:{userID}" with value "1", expiring with the lockout duration.
+	redisStoreNameLoginLock string = "login:lock"
+
+	// redisStoreNameLoginIPAttempts is the Redis key prefix for failed login counters per client IP.
+	// Key pattern: "login:ip_attempts:{ip}" with the failure count, expiring with the attempt window.
+	redisStoreNameLoginIPAttempts string = "login:ip_attempts"
+
+	// redisStoreNameLoginIPLock is the Redis key prefix for client IP locks.
+	// Key pattern: "login:ip_lock:{ip}" with value "1", expiring with the lockout duration.
+	redisStoreNameLoginIPLock string = "login:ip_lock"
+
+	defaultLoginMaxAttempts     int    = 5
+	defaultLoginAttemptWindow   string = "15m"
+	defaultLoginLockoutDuration string = "15m"
+)
+
+// LoginStatus is the rate limiting state of a user, from least to most restrictive.
+type LoginStatus int
+
+const (
+	// LoginStatusAllowed means the login can proceed normally.
+	LoginStatusAllowed LoginStatus = iota
+	// LoginStatusChallengeRequired means the login can proceed only after an
+	// additional challenge (e.g. CAPTCHA) has been solved.
+	LoginStatusChallengeRequired
+	// LoginStatusLocked means the account is locked and the login must be refused.
+	LoginStatusLocked
+)
+
+// String returns a stable lowercase name, suitable for logs and API responses.
+func (ls LoginStatus) String() string {
+	switch ls {
+	case LoginStatusAllowed:
+		return "allowed"
+	case LoginStatusChallengeRequired:
+		return "challenge_required"
+	case LoginStatusLocked:
+		return "locked"
+	default:
+		return "unknown"
+	}
+}
+
+// LoginAttemptService locks accounts after repeated failed logins.
+// It generalizes the OTP attempt counter so that password logins can be guarded as well.
+//
+// Key features:
+//   - Failures are counted within a sliding window (LoginAttemptWindow)
+//   - Reaching LoginChallengeThreshold requires a challenge (e.g. CAPTCHA) before the next login
+//   - Reaching LoginMaxAttempts locks the account for LoginLockoutDuration
+//   - A successful login clears the failure counter
+//   - Locks expire automatically via Redis TTL
+//   - With LoginMaxAttemptsPerIP, failures are also counted per client IP (read from
+//     lib.RequestMeta in the context), locking the IP across all accounts
+//     (credential stuffing)
+//
+// Redis key patterns:
+//   - Failures: "login:attempts:{userID}" → counter (integer)
+//   - Lock: "login:lock:{userID}" → "1"
+//   - IP failures: "login:ip_attempts:{ip}" → counter (integer)
+//   - IP lock: "login:ip_lock:{ip}" → "1"
+type LoginAttemptService struct {
+	db               *redis.Client
+	config           *lib.Config
+	keys             keyPrefix
+	maxAttempts      int
+	maxAttemptsPerIP int
+	challenge        int
+	lockout          time.Duration
+	attempts         *attemptCounter
+	ipAttempts       *attemptCounter
+
+	// mu guards the audit logger, which can be set while logins are recorded.
+	mu    sync.RWMutex
+	audit lib.AuditLogger
+}
+
+// LoginAttemptServiceInterface defines the methods for login lockout management.
+type LoginAttemptServiceInterface interface {
+	RecordFailure(ctx context.Context, userID string) (bool, error)
+	RecordSuccess(ctx context.Context, userID string) error
+	IsLocked(ctx context.Context, userID string) (bool, error)
+	RemainingLockout(ctx context.Context, userID string) (time.Duration, error)
+	Status(ctx context.Context, userID string) (LoginStatus, error)
+	Unlock(ctx context.Context, userID string) error
+	RevokeAllLoginAttempts(ctx context.Context) error
+}
+
+// NewLoginAttemptService creates a new login lockout service instance with Redis persistence.
+// Returns an error if the database client is nil or if a duration cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for counters and locks
+//   - config: Configuration containing LoginMaxAttempts, LoginAttemptWindow, LoginLockoutDuration
+//   - opts: Optional settings (WithKeyPrefix, WithLogger)
+//     and LoginChallengeThreshold
+//
+// Returns:
+//   - *LoginAttemptService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	loginService, err := service.NewLoginAttemptService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewLoginAttemptService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*LoginAttemptService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if config.LoginMaxAttempts < 0 {
+		return nil, errors.New("login max attempts is negative")
+	}
+	if config.LoginChallengeThreshold < 0 {
+		return nil, errors.New("login challenge threshold is negative")
+	}
+	if config.LoginMaxAttemptsPerIP < 0 {
+		return nil, errors.New("login max attempts per ip is negative")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	maxAttempts := config.LoginMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultLoginMaxAttempts
+	}
+
+	window, err := parseDurationOrDefault(config.LoginAttemptWindow, defaultLoginAttemptWindow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login attempt window format: %w", err)
+	}
+	lockout, err := parseDurationOrDefault(config.LoginLockoutDuration, defaultLoginLockoutDuration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login lockout duration format: %w", err)
+	}
+
+	options := newServiceOptions(opts)
+	service := &LoginAttemptService{
+		db:               db,
+		config:           config.Clone(),
+		keys:             options.keyPrefix,
+		maxAttempts:      maxAttempts,
+		maxAttemptsPerIP: config.LoginMaxAttemptsPerIP,
+		challenge:        config.LoginChallengeThreshold,
+		lockout:          lockout,
+		attempts:         newAttemptCounter(db, options.keyPrefix.name(redisStoreNameLoginAttempts), window),
+		ipAttempts:       newAttemptCounter(db, options.keyPrefix.name(redisStoreNameLoginIPAttempts), window),
+		audit:            options.audit,
+	}
+
+	return service, nil
+}
+
+// SetAuditLogger configures the logger receiving the "login.locked" and "login.ip_locked" audit events.
+// A nil logger disables auditing.
+func (las *LoginAttemptService) SetAuditLogger(logger lib.AuditLogger) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+	las.audit = logger
+}
+
+func (las *LoginAttemptService) auditLogger() lib.AuditLogger {
+	las.mu.RLock()
+	defer las.mu.RUnlock()
+	return las.audit
+}
+
+// RecordFailure registers a failed login for the user.
+// When the number of failures within the window reaches LoginMaxAttempts,
+// the account is locked, the failure counter is cleared and a "login.locked"
+// audit event is emitted. The failure also counts against the client IP found in
+// ctx (see lib.WithRequestMeta) when LoginMaxAttemptsPerIP is set.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (any stable identifier, e.g. user ID or email)
+//
+// Returns:
+//   - bool: true if this failure locked the account
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	if !hasher.CheckHash(password, user.PasswordHash) {
+//	    locked, _ := loginService.RecordFailure(ctx, user.ID)
+//	    if locked {
+//	        notifyAccountLocked(user)
+//	    }
+//	    return errors.New("invalid credentials")
+//	}
+func (las *LoginAttemptService) RecordFailure(ctx context.Context, userID string) (bool, error) {
+	if userID == "" {
+		return false, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := las.recordIPFailure(ctx, userID); err != nil {
+		return false, err
+	}
+
+	attempts, err := las.attempts.increment(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if attempts < las.maxAttempts {
+		return false, nil
+	}
+
+	if err := las.db.Set(ctx, fmt.Sprintf("%s:%s", las.keys.name(redisStoreNameLoginLock), userID), "1", las.lockout).Err(); err != nil {
+		return false, err
+	}
+	emitAudit(ctx, las.auditLogger(), AuditEventLoginLocked, userID, map[string]string{
+		"attempts": fmt.Sprintf("%d", attempts),
+		"lockout":  las.lockout.String(),
+	})
+
+	// The lock now carries the state, start counting from zero once it expires
+	if err := las.attempts.revoke(ctx, userID); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// RecordSuccess clears the failure counter after a successful login.
+// An active lock is left untouched: callers must check IsLocked before authenticating.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - error: Validation or storage errors
+func (las *LoginAttemptService) RecordSuccess(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return las.attempts.revoke(ctx, userID)
+}
+
+// IsLocked reports whether the account is currently locked, or the client IP
+// found in ctx when LoginMaxAttemptsPerIP is set.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - bool: true if the account is locked
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	locked, err := loginService.IsLocked(ctx, user.ID)
+//	if err != nil {
+//	    return err
+//	}
+//	if locked {
+//	    return errors.New("account temporarily locked")
+//	}
+func (las *LoginAttemptService) IsLocked(ctx context.Context, userID string) (bool, error) {
+	if userID == "" {
+		return false, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	exists, err := las.db.Exists(ctx, las.lockKeys(ctx, userID)...).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// RemainingLockout returns how long the account (or the client IP found in ctx)
+// stays locked, 0 if it is not locked.
+// Useful to fill a Retry-After header or an "try again in 12 minutes" message.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - time.Duration: Remaining lockout duration
+//   - error: Validation or storage errors
+func (las *LoginAttemptService) RemainingLockout(ctx context.Context, userID string) (time.Duration, error) {
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var remaining time.Duration
+	for _, key := range las.lockKeys(ctx, userID) {
+		ttl, err := las.db.PTTL(ctx, key).Result()
+		if err != nil {
+			return 0, err
+		}
+		// Negative when the key doesn't exist (-2) or has no TTL (-1)
+		remaining = max(remaining, ttl)
+	}
+	return remaining, nil
+}
+
+// Status returns the rate limiting state of the user, so HTTP layers can
+// respond with the right challenge:
+//   - LoginStatusLocked: the account is locked
+//   - LoginStatusChallengeRequired: failures reached LoginChallengeThreshold
+//   - LoginStatusAllowed: otherwise (or when no challenge threshold is configured)
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - LoginStatus: Current state of the user
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	status, err := loginService.Status(ctx, user.ID)
+//	if err != nil {
+//	    return err
+//	}
+//	switch status {
+//	case service.LoginStatusLocked:
+//	    w.WriteHeader(http.StatusTooManyRequests)
+//	case service.LoginStatusChallengeRequired:
+//	    if !verifyCaptcha(r) {
+//	        w.WriteHeader(http.StatusForbidden)
+//	    }
+//	}
+func (las *LoginAttemptService) Status(ctx context.Context, userID string) (LoginStatus, error) {
+	locked, err := las.IsLocked(ctx, userID)
+	if err != nil {
+		return LoginStatusAllowed, err
+	}
+	if locked {
+		return LoginStatusLocked, nil
+	}
+	if las.challenge == 0 {
+		return LoginStatusAllowed, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attempts, err := las.attempts.get(ctx, userID)
+	if err != nil {
+		return LoginStatusAllowed, err
+	}
+	if attempts >= las.challenge {
+		return LoginStatusChallengeRequired, nil
+	}
+	return LoginStatusAllowed, nil
+}
+
+// RateLimit returns how many failed logins the user has left before the account
+// is locked, and when the failure window expires. While the account (or the
+// client IP found in ctx) is locked, no attempt is left until the lock expires.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - RateLimitState: Failures allowed (LoginMaxAttempts), left and time until reset
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	state, err := loginService.RateLimit(ctx, user.ID)
+//	if err == nil {
+//	    middleware.SetRateLimitHeaders(w, state)
+//	    if state.Exhausted() {
+//	        w.WriteHeader(http.StatusTooManyRequests)
+//	        return
+//	    }
+//	}
+func (las *LoginAttemptService) RateLimit(ctx context.Context, userID string) (RateLimitState, error) {
+	lockout, err := las.RemainingLockout(ctx, userID)
+	if err != nil {
+		return RateLimitState{}, err
+	}
+	if lockout > 0 {
+		return RateLimitState{Limit: las.maxAttempts, Remaining: 0, Reset: lockout}, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return las.attempts.state(ctx, userID, las.maxAttempts)
+}
+
+// Unlock removes the lock and the failure counter of a user (e.g. admin action
+// or after a successful password reset).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string
+//
+// Returns:
+//   - error: Validation or storage errors
+func (las *LoginAttemptService) Unlock(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := las.db.Del(ctx, fmt.Sprintf("%s:%s", las.keys.name(redisStoreNameLoginLock), userID)).Err(); err != nil {
+		return err
+	}
+	return las.attempts.revoke(ctx, userID)
+}
+
+// RevokeAllLoginAttempts removes every failure counter and lock for all users.
+//
+// Warning: This is a destructive operation that unlocks all accounts.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during deletion
+func (las *LoginAttemptService) RevokeAllLoginAttempts(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, prefix := range []string{las.keys.name(redisStoreNameLoginLock), las.keys.name(redisStoreNameLoginIPLock)} {
+		keys := las.db.Scan(ctx, 0, fmt.Sprintf("%s:*", prefix), 0).Iterator()
+		for keys.Next(ctx) {
+			key := keys.Val()
+			if err := las.db.Del(ctx, key).Err(); err != nil {
+				return fmt.Errorf("failed to delete key %s : %w", key, err)
+			}
+		}
+		if err := keys.Err(); err != nil {
+			return err
+		}
+	}
+
+	if err := las.ipAttempts.revokeAll(ctx); err != nil {
+		return err
+	}
+	return las.attempts.revokeAll(ctx)
+}
+
+// clientIP returns the client IP of the request metadata in ctx, empty when
+// per-IP rate limiting is disabled or no IP was attached.
+func (las *LoginAttemptService) clientIP(ctx context.Context) string {
+	if las.maxAttemptsPerIP == 0 {
+		return ""
+	}
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	return meta.IP
+}
+
+// lockKeys returns the lock keys applying to the login: the user lock, and the
+// client IP lock when available.
+func (las *LoginAttemptService) lockKeys(ctx context.Context, userID string) []string {
+	keys := []string{fmt.Sprintf("%s:%s", las.keys.name(redisStoreNameLoginLock), userID)}
+	if ip := las.clientIP(ctx); ip != "" {
+		keys = append(keys, fmt.Sprintf("%s:%s", las.keys.name(redisStoreNameLoginIPLock), ip))
+	}
+	return keys
+}
+
+// recordIPFailure counts a failed login against the client IP and locks the IP
+// when LoginMaxAttemptsPerIP is reached.
+func (las *LoginAttemptService) recordIPFailure(ctx context.Context, userID string) error {
+	ip := las.clientIP(ctx)
+	if ip == "" {
+		return nil
+	}
+
+	attempts, err := las.ipAttempts.increment(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if attempts < las.maxAttemptsPerIP {
+		return nil
+	}
+
+	if err := las.db.Set(ctx, fmt.Sprintf("%s:%s", las.keys.name(redisStoreNameLoginIPLock), ip), "1", las.lockout).Err(); err != nil {
+		return err
+	}
+	emitAudit(ctx, las.auditLogger(), AuditEventLoginIPLocked, userID, map[string]string{
+		"ip":       ip,
+		"attempts": fmt.Sprintf("%d", attempts),
+		"lockout":  las.lockout.String(),
+	})
+	return las.ipAttempts.revoke(ctx, ip)
+}
+
+// parseDurationOrDefault parses an optional duration configuration value,
+// falling back to the provided default when the value is nil.
+func parseDurationOrDefault(value *string, fallback string) (time.Duration, error) {
+	if value == nil {
+		return time.ParseDuration(fallback)
+	}
+	return time.ParseDuration(*value)
+}
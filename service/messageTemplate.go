@@ -0,0 +1,315 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageKind identifies the messages sent to users.
+type MessageKind string
+
+const (
+	// MessageKindOTP is the message delivering an OTP code.
+	MessageKindOTP MessageKind = "otp"
+	// MessageKindPasswordReset is the message delivering a password reset link.
+	MessageKindPasswordReset MessageKind = "password_reset"
+)
+
+// defaultMessageLocale is the locale of the messages whose locale has no template.
+const defaultMessageLocale string = "en"
+
+// MessageTemplate holds the text/template sources of a message. Templates read:
+//   - .AppName: Application name given to NewMessageRenderer
+//   - .Code: OTP code or reset token
+//   - .Link: Password reset link
+//   - .Expiry: Lifetime in the language of the message, e.g. "10 minutes", "10 Minuten"
+//     (English units for languages without built-in templates)
+//   - .Minutes: Lifetime in whole minutes
+type MessageTemplate struct {
+	Subject string
+	Body    string
+}
+
+// MessageData holds the values of a message.
+//
+// Fields:
+//   - Locale: BCP 47 language tag (e.g. "fr-FR"), falling back to its language ("fr") then "en"
+//   - Code: OTP code or reset token
+//   - Link: Password reset link
+//   - ExpiresIn: Lifetime of the code or link
+type MessageData struct {
+	Locale    string
+	Code      string
+	Link      string
+	ExpiresIn time.Duration
+}
+
+// RenderedMessage is a message ready to send.
+type RenderedMessage struct {
+	Subject string
+	Body    string
+}
+
+// messageView is the data given to the templates.
+type messageView struct {
+	AppName string
+	Code    string
+	Link    string
+	Expiry  string
+	Minutes int
+}
+
+// compiledMessage is a parsed MessageTemplate.
+type compiledMessage struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// expiryUnits names the units of a lifetime in a language: singular then plural
+// seconds, minutes and hours.
+type expiryUnits [6]string
+
+// builtinExpiryUnits are the units of the languages with built-in templates.
+var builtinExpiryUnits = map[string]expiryUnits{
+	"en": {"second", "seconds", "minute", "minutes", "hour", "hours"},
+	"fr": {"seconde", "secondes", "minute", "minutes", "heure", "heures"},
+	"de": {"Sekunde", "Sekunden", "Minute", "Minuten", "Stunde", "Stunden"},
+	"es": {"segundo", "segundos", "minuto", "minutos", "hora", "horas"},
+}
+
+// builtinMessages are the default templates, by language.
+var builtinMessages = map[string]map[MessageKind]MessageTemplate{
+	"en": {
+		MessageKindOTP: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Your verification code",
+			Body:    "{{if .AppName}}{{.AppName}}: {{end}}Your verification code is {{.Code}}. It expires in {{.Expiry}}.",
+		},
+		MessageKindPasswordReset: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Reset your password",
+			Body: "To reset your {{if .AppName}}{{.AppName}} {{end}}password, open this link: {{.Link}}\n" +
+				"It expires in {{.Expiry}}. If you did not request it, ignore this message.",
+		},
+	},
+	"fr": {
+		MessageKindOTP: {
+			Subject: "{{if .AppName}}{{.AppName}} : {{end}}Votre code de vérification",
+			Body:    "{{if .AppName}}{{.AppName}} : {{end}}Votre code de vérification est {{.Code}}. Il expire dans {{.Expiry}}.",
+		},
+		MessageKindPasswordReset: {
+			Subject: "{{if .AppName}}{{.AppName}} : {{end}}Réinitialisation de votre mot de passe",
+			Body: "Pour réinitialiser votre mot de passe{{if .AppName}} {{.AppName}}{{end}}, ouvrez ce lien : {{.Link}}\n" +
+				"Il expire dans {{.Expiry}}. Si vous n'êtes pas à l'origine de cette demande, ignorez ce message.",
+		},
+	},
+	"de": {
+		MessageKindOTP: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Ihr Bestätigungscode",
+			Body:    "{{if .AppName}}{{.AppName}}: {{end}}Ihr Bestätigungscode lautet {{.Code}}. Er läuft in {{.Expiry}} ab.",
+		},
+		MessageKindPasswordReset: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Passwort zurücksetzen",
+			Body: "Um Ihr {{if .AppName}}{{.AppName}}-{{end}}Passwort zurückzusetzen, öffnen Sie diesen Link: {{.Link}}\n" +
+				"Er läuft in {{.Expiry}} ab. Wenn Sie dies nicht angefordert haben, ignorieren Sie diese Nachricht.",
+		},
+	},
+	"es": {
+		MessageKindOTP: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Tu código de verificación",
+			Body:    "{{if .AppName}}{{.AppName}}: {{end}}Tu código de verificación es {{.Code}}. Caduca en {{.Expiry}}.",
+		},
+		MessageKindPasswordReset: {
+			Subject: "{{if .AppName}}{{.AppName}}: {{end}}Restablece tu contraseña",
+			Body: "Para restablecer tu contraseña{{if .AppName}} de {{.AppName}}{{end}}, abre este enlace: {{.Link}}\n" +
+				"Caduca en {{.Expiry}}. Si no lo has solicitado, ignora este mensaje.",
+		},
+	},
+}
+
+// defaultMessages renders the built-in templates without application name.
+var defaultMessages = mustMessageRenderer("", nil)
+
+// MessageRenderer renders the messages sent to users (OTP codes, password reset links)
+// from templates, in the language of each user. Built-in templates are provided in
+// English, French, German and Spanish; custom templates replace them per language and
+// kind, or add languages. Safe for concurrent use.
+type MessageRenderer struct {
+	appName   string
+	templates map[string]map[MessageKind]compiledMessage
+}
+
+// NewMessageRenderer creates a renderer from the built-in templates and custom ones.
+// Templates are checked by rendering them once.
+//
+// Parameters:
+//   - appName: Application name, read by the templates as .AppName (may be empty)
+//   - custom: Templates replacing or adding to the built-in ones, by language then kind (may be nil)
+//
+// Returns:
+//   - *MessageRenderer: Initialized renderer
+//   - error: Template parsing or execution errors
+//
+// Example:
+//
+//	messages, err := service.NewMessageRenderer("Acme", map[string]map[service.MessageKind]service.MessageTemplate{
+//	    "it": {service.MessageKindOTP: {
+//	        Subject: "Il tuo codice di verifica",
+//	        Body:    "Il tuo codice di verifica è {{.Code}}. Scade tra {{.Minutes}} minuti.",
+//	    }},
+//	})
+//	sender, err := service.NewTwilioSender(service.TwilioSenderConfig{..., Messages: messages})
+func NewMessageRenderer(appName string, custom map[string]map[MessageKind]MessageTemplate) (*MessageRenderer, error) {
+	mr := &MessageRenderer{
+		appName:   appName,
+		templates: make(map[string]map[MessageKind]compiledMessage),
+	}
+
+	for _, sources := range []map[string]map[MessageKind]MessageTemplate{builtinMessages, custom} {
+		for locale, kinds := range sources {
+			locale = normalizeLocale(locale)
+			if mr.templates[locale] == nil {
+				mr.templates[locale] = make(map[MessageKind]compiledMessage)
+			}
+			for kind, source := range kinds {
+				compiled, err := compileMessage(locale, kind, source)
+				if err != nil {
+					return nil, err
+				}
+				mr.templates[locale][kind] = compiled
+			}
+		}
+	}
+
+	// Check the templates: execution errors (e.g. unknown fields) show at runtime only
+	for locale, kinds := range mr.templates {
+		for kind := range kinds {
+			if _, err := mr.Render(kind, MessageData{Locale: locale, Code: "123456", Link: "https://example.com", ExpiresIn: time.Minute}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mr, nil
+}
+
+// mustMessageRenderer is NewMessageRenderer for templates known to be valid.
+func mustMessageRenderer(appName string, custom map[string]map[MessageKind]MessageTemplate) *MessageRenderer {
+	mr, err := NewMessageRenderer(appName, custom)
+	if err != nil {
+		panic(err)
+	}
+	return mr
+}
+
+func compileMessage(locale string, kind MessageKind, source MessageTemplate) (compiledMessage, error) {
+	name := locale + "/" + string(kind)
+	subject, err := template.New(name + "/subject").Option("missingkey=error").Parse(source.Subject)
+	if err != nil {
+		return compiledMessage{}, fmt.Errorf("invalid %s subject template: %w", name, err)
+	}
+	body, err := template.New(name + "/body").Option("missingkey=error").Parse(source.Body)
+	if err != nil {
+		return compiledMessage{}, fmt.Errorf("invalid %s body template: %w", name, err)
+	}
+	return compiledMessage{subject: subject, body: body}, nil
+}
+
+// Render renders a message in the locale of data, falling back to its language, then
+// to English.
+//
+// Returns:
+//   - RenderedMessage: Subject and body
+//   - error: An error for kinds without template, or template execution errors
+//
+// Example:
+//
+//	msg, err := messages.Render(service.MessageKindPasswordReset, service.MessageData{
+//	    Locale:    user.Locale,
+//	    Link:      "https://example.com/reset?token=" + *token,
+//	    ExpiresIn: time.Hour,
+//	})
+//	mailer.Send(user.Email, msg.Subject, msg.Body)
+func (mr *MessageRenderer) Render(kind MessageKind, data MessageData) (RenderedMessage, error) {
+	compiled, language, ok := mr.lookup(kind, data.Locale)
+	if !ok {
+		return RenderedMessage{}, fmt.Errorf("no template for message kind %q", kind)
+	}
+
+	view := messageView{
+		AppName: mr.appName,
+		Code:    data.Code,
+		Link:    data.Link,
+		Expiry:  formatExpiry(data.ExpiresIn, language),
+		Minutes: int(data.ExpiresIn / time.Minute),
+	}
+
+	var subject, body strings.Builder
+	if err := compiled.subject.Execute(&subject, view); err != nil {
+		return RenderedMessage{}, err
+	}
+	if err := compiled.body.Execute(&body, view); err != nil {
+		return RenderedMessage{}, err
+	}
+	return RenderedMessage{Subject: subject.String(), Body: body.String()}, nil
+}
+
+// OTPSubject returns the subject of the OTP message of msg, for the Text and subject
+// settings of the senders.
+func (mr *MessageRenderer) OTPSubject(msg OTPMessage) string {
+	return mr.renderOTP(msg).Subject
+}
+
+// OTPText returns the body of the OTP message of msg, for the Text settings of the senders.
+func (mr *MessageRenderer) OTPText(msg OTPMessage) string {
+	return mr.renderOTP(msg).Body
+}
+
+// renderOTP renders the OTP message of msg. Templates were checked by NewMessageRenderer:
+// on a failure anyway, the built-in English message is returned so that the code is delivered.
+func (mr *MessageRenderer) renderOTP(msg OTPMessage) RenderedMessage {
+	data := MessageData{Locale: msg.Locale, Code: msg.Code, ExpiresIn: msg.ExpiresIn}
+	rendered, err := mr.Render(MessageKindOTP, data)
+	if err != nil && mr != defaultMessages {
+		data.Locale = defaultMessageLocale
+		rendered, _ = defaultMessages.Render(MessageKindOTP, data)
+	}
+	return rendered
+}
+
+// lookup returns the template of kind for locale ("fr-ca", then "fr", then "en") and its language.
+func (mr *MessageRenderer) lookup(kind MessageKind, locale string) (compiledMessage, string, bool) {
+	locale = normalizeLocale(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	for _, candidate := range []string{locale, language, defaultMessageLocale} {
+		if compiled, ok := mr.templates[candidate][kind]; ok {
+			return compiled, language, true
+		}
+	}
+	return compiledMessage{}, "", false
+}
+
+// normalizeLocale returns a BCP 47 tag in lower case with "-" separators: "fr_FR" → "fr-fr".
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// formatExpiry returns a lifetime in the units of language: "10 minutes", "1 Stunde",
+// "30 segundos". Languages without built-in units use English.
+func formatExpiry(d time.Duration, language string) string {
+	units, ok := builtinExpiryUnits[language]
+	if !ok {
+		units = builtinExpiryUnits[defaultMessageLocale]
+	}
+
+	n, unit := int(d.Round(time.Second)/time.Second), 0
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		n, unit = int(d/time.Hour), 4
+	case d >= time.Minute && d%time.Minute == 0:
+		n, unit = int(d/time.Minute), 2
+	}
+	if n != 1 {
+		unit++
+	}
+	return fmt.Sprintf("%d %s", n, units[unit])
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameNonce is the Redis key prefix for nonces.
+	// Key pattern: "nonce:{sha256(nonce)}" with the binding (or "1") as value,
+	// expiring with NonceTTL.
+	redisStoreNameNonce string = "nonce"
+
+	// nonceLength is the character length of generated nonces.
+	nonceLength int = 32
+
+	// nonceUnboundValue is the value stored for nonces created without binding.
+	nonceUnboundValue string = "1"
+
+	defaultNonceTTL string = "10m"
+)
+
+// NonceService issues single-use values for OIDC/OAuth flows: the nonce of an
+// authentication request (checked against the ID token) and the state parameter
+// protecting the redirect against CSRF.
+//
+// Key features:
+//   - Cryptographically secure 32-character nonces
+//   - Optional binding (e.g. session ID), required again on consumption
+//   - Single use: ConsumeOnce atomically deletes the nonce (replays fail)
+//   - Automatic expiration via Redis TTL
+//
+// Redis key pattern:
+//   - Key: "nonce:{sha256(nonce)}" (nonces are not stored in clear)
+//   - Value: The binding, "1" when none
+//   - TTL: Configured via NonceTTL (default: 10 minutes)
+type NonceService struct {
+	db     *redis.Client
+	config *lib.Config
+	nonces *ttlStore
+}
+
+// NonceServiceInterface defines the methods for nonce management.
+type NonceServiceInterface interface {
+	CreateNonce(ctx context.Context, binding string) (*string, error)
+	ConsumeOnce(ctx context.Context, nonce string, binding string) (bool, error)
+	TTL(ctx context.Context, nonce string) (time.Duration, error)
+	RevokeAllNonces(ctx context.Context) error
+}
+
+// NewNonceService creates a new nonce service instance with Redis persistence.
+// Returns an error if the database client is nil or if NonceTTL cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for nonce storage
+//   - config: Configuration containing NonceTTL
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *NonceService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	nonceService, err := service.NewNonceService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewNonceService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*NonceService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration, err := parseDurationOrDefault(config.NonceTTL, defaultNonceTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce TTL format: %w", err)
+	}
+
+	options := newServiceOptions(opts)
+	service := &NonceService{
+		db:     db,
+		config: config.Clone(),
+		nonces: newTTLStore(db, options.keyPrefix.name(redisStoreNameNonce), duration),
+	}
+
+	return service, nil
+}
+
+// CreateNonce generates a new nonce, optionally bound to a value that must be
+// presented again to consume it (e.g. the session ID of the browser starting the flow).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - binding: Value required by ConsumeOnce, empty for none
+//
+// Returns:
+//   - *string: Pointer to the generated nonce
+//   - error: Generation or storage errors
+//
+// Example:
+//
+//	state, err := nonceService.CreateNonce(ctx, sessionID)
+//	nonce, err := nonceService.CreateNonce(ctx, sessionID)
+//	http.Redirect(w, r, authorizeURL+"?state="+*state+"&nonce="+*nonce, http.StatusFound)
+func (ns *NonceService) CreateNonce(ctx context.Context, binding string) (*string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	nonce, err := lib.GenerateRandomString(nonceLength)
+	if err != nil {
+		return nil, err
+	}
+
+	value := binding
+	if value == "" {
+		value = nonceUnboundValue
+	}
+	if err := ns.nonces.set(ctx, hashToken(nonce), value); err != nil {
+		return nil, err
+	}
+
+	return &nonce, nil
+}
+
+// ConsumeOnce checks a nonce and deletes it, so that it cannot be used twice.
+// The nonce is consumed even when the binding does not match.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - nonce: The nonce to consume
+//   - binding: The binding given to CreateNonce, empty for none
+//
+// Returns:
+//   - bool: true if the nonce existed, had not expired and matched the binding
+//   - error: Storage errors
+//
+// Example:
+//
+//	// OAuth callback
+//	valid, err := nonceService.ConsumeOnce(ctx, r.URL.Query().Get("state"), sessionID)
+//	if err != nil || !valid {
+//	    w.WriteHeader(http.StatusBadRequest)
+//	    return
+//	}
+func (ns *NonceService) ConsumeOnce(ctx context.Context, nonce string, binding string) (bool, error) {
+	if nonce == "" {
+		return false, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, found, err := ns.nonces.consume(ctx, hashToken(nonce))
+	if err != nil || !found {
+		return false, err
+	}
+
+	expected := binding
+	if expected == "" {
+		expected = nonceUnboundValue
+	}
+	return subtle.ConstantTimeCompare([]byte(value), []byte(expected)) == 1, nil
+}
+
+// TTL returns how long the nonce remains valid, 0 if it does not exist,
+// expired or was consumed.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - nonce: The nonce to check
+//
+// Returns:
+//   - time.Duration: Remaining lifetime
+//   - error: Storage errors
+func (ns *NonceService) TTL(ctx context.Context, nonce string) (time.Duration, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return ns.nonces.remaining(ctx, hashToken(nonce))
+}
+
+// RevokeAllNonces revokes all outstanding nonces, failing the flows in progress.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during revocation
+func (ns *NonceService) RevokeAllNonces(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return ns.nonces.revokeAll(ctx)
+}
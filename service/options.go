@@ -0,0 +1,188 @@
+package service
+
+import (
+	"maps"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// Option configures a service at construction, e.g.
+// service.NewOTPService(ctx, redisClient, config, service.WithKeyPrefix("myapp")).
+// Options that do not apply to a service are ignored.
+type Option func(*serviceOptions)
+
+// serviceOptions holds the values set by the options.
+type serviceOptions struct {
+	hasher       lib.PasswordHashInterface
+	audit        lib.AuditLogger
+	clock        func() time.Time
+	keyPrefix    keyPrefix
+	otpGenerator func() (string, error)
+	staticOTP    *string
+	userHashTags bool
+	retention    RetentionPolicy
+	cipher       *lib.ValueCipher
+	otpHasherID  string
+	otpHashers   map[string]lib.PasswordHashInterface
+	otpSender    OTPSender
+}
+
+// WithHasher replaces the bcrypt hasher (cost 14) used to store OTP codes,
+// e.g. with a lower cost in tests. Applies to OTPService.
+func WithHasher(hasher lib.PasswordHashInterface) Option {
+	return func(o *serviceOptions) {
+		o.hasher = hasher
+	}
+}
+
+// WithOTPHashers sets the hashers of the OTP codes by identifier, so that the hasher
+// can be changed without invalidating the codes in flight: new codes are hashed with
+// the current hasher and stored as "{id}:{hash}", and VerifyOTP picks the hasher from
+// the identifier. Codes stored without a known identifier are checked with the hasher
+// of WithHasher (bcrypt by default). Identifiers must not contain ":".
+// Applies to OTPService.
+//
+// Example:
+//
+//	// Codes hashed with bcrypt before the switch keep verifying until they expire
+//	service.WithOTPHashers("hmac1", map[string]lib.PasswordHashInterface{"hmac1": hmacHasher})
+func WithOTPHashers(current string, hashers map[string]lib.PasswordHashInterface) Option {
+	return func(o *serviceOptions) {
+		o.otpHasherID = current
+		o.otpHashers = maps.Clone(hashers)
+	}
+}
+
+// WithOTPGenerator replaces lib.GenerateOTP for the codes created by the service,
+// e.g. with a seeded generator in tests (see testkit.NewOTPGenerator). Never use a
+// predictable generator in production. Applies to OTPService.
+func WithOTPGenerator(generate func() (string, error)) Option {
+	return func(o *serviceOptions) {
+		if generate != nil {
+			o.otpGenerator = generate
+			o.staticOTP = nil
+		}
+	}
+}
+
+// WithStaticOTP makes every code created by the service the given 6-digit code, so
+// that staging environments and end-to-end suites can sign in without intercepting
+// emails. Anyone knowing the code can sign in as any user: the service emits an
+// "otp.static_code_enabled" audit event (with WithLogger) each time it is created
+// with it, route it to an alert. Attempt limits still apply. Applies to OTPService.
+//
+// Example:
+//
+//	opts := []service.Option{}
+//	if os.Getenv("E2E_STATIC_OTP") != "" {
+//	    opts = append(opts, service.WithStaticOTP(os.Getenv("E2E_STATIC_OTP")))
+//	}
+//	otpService, err := service.NewOTPService(ctx, redisClient, config, opts...)
+func WithStaticOTP(code string) Option {
+	return func(o *serviceOptions) {
+		o.staticOTP = &code
+		o.otpGenerator = func() (string, error) {
+			return code, nil
+		}
+	}
+}
+
+// WithOTPSender sets the sender of OTPService.DeliverOTP, which creates the codes
+// and delivers them by email or SMS without returning them to the application.
+// Applies to OTPService.
+func WithOTPSender(sender OTPSender) Option {
+	return func(o *serviceOptions) {
+		o.otpSender = sender
+	}
+}
+
+// WithLogger sets the audit logger, like SetAuditLogger. Applies to the services
+// emitting audit events: AccessTokenService, RefreshTokenService, PasswordResetService,
+// LoginAttemptService, KillSwitch, LeakedTokenResponder and OTPService (WithStaticOTP).
+func WithLogger(logger lib.AuditLogger) Option {
+	return func(o *serviceOptions) {
+		o.audit = logger
+	}
+}
+
+// WithClock replaces time.Now for the times computed by the service, e.g. to test
+// expirations without waiting. Applies to AccessTokenService (issuance and expiry of
+// access and ID tokens), DeviceCodeService (poll intervals), DeletionTokenService
+// (deletion dates) and OTPService (age of the codes of an OTPPool).
+// Redis expirations are not affected.
+func WithClock(now func() time.Time) Option {
+	return func(o *serviceOptions) {
+		if now != nil {
+			o.clock = now
+		}
+	}
+}
+
+// WithKeyPrefix namespaces the Redis keys of the service, so that several
+// applications can share a Redis database: with "myapp", refresh tokens are stored
+// under "myapp:refresh:{userID}:{token}". Applies to the services storing
+// data in Redis. Services used together (e.g. by a KillSwitch) must use the same prefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(o *serviceOptions) {
+		o.keyPrefix = keyPrefix(prefix)
+	}
+}
+
+// WithUserHashTags wraps the user identifier of the keys in a Redis Cluster hash tag,
+// "otp:{user:123}" and "otp:attempts:{user:123}", so that the keys of a user share a
+// cluster slot: OTPs are spread over the cluster by user, and the operations touching
+// several keys of a user are atomic. Keys stored without tags are not found anymore:
+// enable it on every instance at once, outstanding codes must be requested again.
+// Applies to OTPService.
+func WithUserHashTags() Option {
+	return func(o *serviceOptions) {
+		o.userHashTags = true
+	}
+}
+
+// WithRetentionPolicy sets how long the revocation records of the service are kept,
+// see RetentionPolicy. Passing a policy to each service sets it per token type.
+// Applies to RefreshTokenService and PasswordResetService.
+func WithRetentionPolicy(policy RetentionPolicy) Option {
+	return func(o *serviceOptions) {
+		o.retention = policy
+	}
+}
+
+// WithValueEncryption encrypts the OTP hashes stored by the service with cipher
+// (AES-GCM), bound to their user. Codes stored before encryption was enabled stay
+// valid. Attempt counters stay in clear, as Redis increments them.
+// Applies to OTPService.
+func WithValueEncryption(cipher *lib.ValueCipher) Option {
+	return func(o *serviceOptions) {
+		o.cipher = cipher
+	}
+}
+
+// userHashTag returns the Redis Cluster hash tag of a user, "{user:123}".
+// Redis hashes the tag up to the first '}', which is the same for every key of the user.
+func userHashTag(userID string) string {
+	return "{user:" + userID + "}"
+}
+
+func newServiceOptions(opts []Option) serviceOptions {
+	options := serviceOptions{clock: time.Now, otpGenerator: lib.GenerateOTP}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	return options
+}
+
+// keyPrefix is the namespace of the Redis keys of a service, empty for none.
+type keyPrefix string
+
+// name returns the namespaced name of a Redis store (key prefix or key).
+func (p keyPrefix) name(store string) string {
+	if p == "" {
+		return store
+	}
+	return string(p) + ":" + store
+}
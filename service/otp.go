@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
@@ -32,11 +32,26 @@ const (
 //   - OTP storage: "otp:{userID}" → bcrypt hash of OTP code
 //   - Attempts tracking: "otp:attempts:{userID}" → counter (integer)
 //   - Both keys have the same TTL and expire together
+//
+// Redis Cluster: with WithUserHashTags, keys are "otp:{user:{userID}}" and
+// "otp:attempts:{user:{userID}}", in the slot of the user. Storing a code with its
+// counter (MULTI) and revoking both (a single DEL) are then atomic; without hash tags
+// they are sequences of single-key commands. Bulk revocations delete keys one by one
+// (pipelined) instead of with multi-key DEL commands.
+//
+// Other stores: NewOTPServiceWithStore keeps the same keys in a KVStore.
 type OTPService struct {
-	db       *redis.Client
-	config   *lib.Config
-	hasher   lib.PasswordHashInterface
-	duration time.Duration
+	config    *lib.Config
+	hasher    lib.PasswordHashInterface
+	generate  func() (string, error)
+	now       func() time.Time
+	creations *userQueue
+	duration  time.Duration
+	storage   otpStorage
+	cipher    *lib.ValueCipher
+	hasherID  string
+	hashers   map[string]lib.PasswordHashInterface
+	sender    OTPSender
 }
 
 // OTPServiceInterface defines the methods for OTP management.
@@ -51,13 +66,15 @@ type OTPServiceInterface interface {
 // Returns an error if the database client is nil or if OTPTTL is not configured.
 //
 // The service is initialized with:
-//   - A bcrypt hasher (cost factor 14) for secure OTP storage
+//   - A bcrypt hasher (cost factor 14) for secure OTP storage, unless WithHasher is given
 //   - Pre-parsed TTL duration for performance
 //
 // Parameters:
 //   - ctx: Context for initialization (uses Background if nil)
 //   - db: Redis client for OTP storage
 //   - config: Configuration containing OTPTTL
+//   - opts: Optional settings (WithKeyPrefix, WithHasher, WithOTPHashers, WithOTPGenerator, WithUserHashTags,
+//     WithValueEncryption, WithOTPSender)
 //
 // Returns:
 //   - *OTPService: Initialized service ready for use
@@ -69,10 +86,50 @@ type OTPServiceInterface interface {
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func NewOTPService(ctx context.Context, db *redis.Client, config *lib.Config) (*OTPService, error) {
+func NewOTPService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*OTPService, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
+
+	return newOTPService(ctx, config, opts, func(options serviceOptions, duration time.Duration) otpStorage {
+		return newRedisOTPStorage(db, options.keyPrefix, duration, options.userHashTags)
+	})
+}
+
+// NewOTPServiceWithStore creates a new OTP service instance storing its codes and
+// attempt counters in a KVStore instead of a go-redis client, e.g. NewMemoryKVStore
+// in tests. The keys are the same as with NewOTPService.
+// OTPFailover and WithUserHashTags require NewOTPService.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - store: Key-value store for OTP storage
+//   - config: Configuration containing OTPTTL
+//   - opts: Optional settings (WithKeyPrefix, WithHasher, WithOTPHashers, WithOTPGenerator, WithValueEncryption,
+//     WithOTPSender)
+//
+// Returns:
+//   - *OTPService: Initialized service ready for use
+//   - error: Configuration or store validation errors
+//
+// Example:
+//
+//	otpService, err := service.NewOTPServiceWithStore(ctx, service.NewMemoryKVStore(), config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewOTPServiceWithStore(ctx context.Context, store KVStore, config *lib.Config, opts ...Option) (*OTPService, error) {
+	if store == nil {
+		return nil, errors.New("store is nil")
+	}
+
+	return newOTPService(ctx, config, opts, func(options serviceOptions, duration time.Duration) otpStorage {
+		return newKVOTPStorage(store, options.keyPrefix, duration)
+	})
+}
+
+// newOTPService creates an OTP service on the storage returned by storage.
+func newOTPService(ctx context.Context, config *lib.Config, opts []Option, storage func(options serviceOptions, duration time.Duration) otpStorage) (*OTPService, error) {
 	if config.OTPTTL == nil {
 		return nil, errors.New("one time password ttl is nil")
 	}
@@ -87,16 +144,59 @@ func NewOTPService(ctx context.Context, db *redis.Client, config *lib.Config) (*
 		return nil, fmt.Errorf("invalid OTP TTL format: %w", err)
 	}
 
+	options := newServiceOptions(opts)
+	hasher := options.hasher
+	if hasher == nil {
+		hasher = lib.NewPasswordHash()
+	}
+	if options.otpHashers != nil {
+		if options.otpHashers[options.otpHasherID] == nil {
+			return nil, fmt.Errorf("current otp hasher %q is not in the hashers", options.otpHasherID)
+		}
+		for id, h := range options.otpHashers {
+			if id == "" || strings.Contains(id, ":") || h == nil {
+				return nil, fmt.Errorf("invalid otp hasher %q", id)
+			}
+		}
+	}
+
+	if options.staticOTP != nil {
+		if err := enableStaticOTP(ctx, options); err != nil {
+			return nil, err
+		}
+	}
+
 	service := &OTPService{
-		db:       db,
-		config:   config,
-		hasher:   lib.NewPasswordHash(),
-		duration: duration,
+		config:    config.Clone(),
+		hasher:    hasher,
+		generate:  options.otpGenerator,
+		now:       options.clock,
+		creations: newUserQueue(),
+		duration:  duration,
+		storage:   storage(options, duration),
+		cipher:    options.cipher,
+		hasherID:  options.otpHasherID,
+		hashers:   options.otpHashers,
+		sender:    options.otpSender,
 	}
 
 	return service, nil
 }
 
+// enableStaticOTP checks the code of WithStaticOTP and announces it to the audit
+// logger, so that a static code enabled by mistake in production does not go unnoticed.
+func enableStaticOTP(ctx context.Context, options serviceOptions) error {
+	if !validation.NewOTPValidation().ISOTPValid(*options.staticOTP) {
+		return errors.New("static otp must be 6 digits")
+	}
+
+	emitAudit(ctx, options.audit, AuditEventOTPStaticCodeEnabled, "", map[string]string{
+		"key_prefix": string(options.keyPrefix),
+		"warning":    "every user can sign in with the same code, never enable it in production",
+	})
+	return nil
+}
+
 // CreateOTP generates a new 6-digit OTP code for the specified user.
 // The code is hashed with bcrypt before storage for security.
 // Creating a new OTP automatically invalidates any previous OTP for the user.
@@ -126,38 +226,69 @@ func NewOTPService(ctx context.Context, db *redis.Client, config *lib.Config) (*
 //	sendEmail(userEmail, *otp)
 func (otps *OTPService) CreateOTP(ctx context.Context, userID string) (*string, error) {
 	if userID == "" {
-		return nil, errors.New("invalid user id")
+		return nil, ErrInvalidUserID
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	key := fmt.Sprintf("%s:%s", redisStoreNameOTP, userID)
-
-	otp, err := lib.GenerateOTP()
+	otp, hash, err := otps.newCode()
 	if err != nil {
 		return nil, err
 	}
 
-	hash, err := otps.hasher.Hash(otp)
-	if err != nil {
+	if err := otps.store(ctx, userID, hash); err != nil {
 		return nil, err
 	}
 
-	err = otps.db.Set(ctx, key, hash, otps.duration).Err()
+	return &otp, nil
+}
+
+// newCode generates an OTP code and its hash.
+func (otps *OTPService) newCode() (string, string, error) {
+	otp, err := otps.generate()
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	// Reset attempts counter - if this fails, rollback OTP creation
-	if err := otps.resetAttempts(ctx, userID); err != nil {
-		// Best effort rollback: delete the OTP we just created
-		_ = otps.db.Del(ctx, key)
-		return nil, fmt.Errorf("failed to reset attempts counter: %w", err)
+	hasher, prefix := otps.hasher, ""
+	if otps.hashers != nil {
+		hasher, prefix = otps.hashers[otps.hasherID], otps.hasherID+":"
 	}
 
-	return &otp, nil
+	hash, err := hasher.Hash(otp)
+	if err != nil {
+		return "", "", err
+	}
+	return otp, prefix + hash, nil
+}
+
+// store makes hash the active OTP of the user and resets the attempts counter.
+func (otps *OTPService) store(ctx context.Context, userID string, hash string) error {
+	if otps.cipher != nil {
+		var err error
+		if hash, err = otps.cipher.Encrypt(hash, userID); err != nil {
+			return err
+		}
+	}
+	return otps.storage.store(ctx, userID, hash)
+}
+
+// checkCode reports whether otp matches the stored value of the user's active OTP.
+func (otps *OTPService) checkCode(userID string, otp string, stored string) (bool, error) {
+	if otps.cipher != nil {
+		var err error
+		if stored, err = otps.cipher.Decrypt(stored, userID); err != nil {
+			return false, err
+		}
+	}
+	if id, hash, found := strings.Cut(stored, ":"); found {
+		if hasher, ok := otps.hashers[id]; ok {
+			return hasher.CheckHash(otp, hash), nil
+		}
+	}
+	return otps.hasher.CheckHash(otp, stored), nil
 }
 
 // VerifyOTP checks if the provided OTP code is valid for the user.
@@ -192,7 +323,7 @@ func (otps *OTPService) CreateOTP(ctx context.Context, userID string) (*string,
 //
 //	valid, err := otpService.VerifyOTP(ctx, "550e8400-e29b-41d4-a716-446655440000", "387492")
 //	if err != nil {
-//	    if strings.Contains(err.Error(), "max attempts exceeded") {
+//	    if errors.Is(err, service.ErrMaxAttemptsExceeded) {
 //	        return errors.New("too many attempts, request new code")
 //	    }
 //	    return err
@@ -203,45 +334,45 @@ func (otps *OTPService) CreateOTP(ctx context.Context, userID string) (*string,
 //	// OTP verified, proceed with authentication
 func (otps *OTPService) VerifyOTP(ctx context.Context, userID string, otp string) (bool, error) {
 	if userID == "" {
-		return false, errors.New("invalid user id")
+		return false, ErrInvalidUserID
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	otp = otps.config.TokenNormalization.Normalize(otp)
+
 	otpValidation := validation.NewOTPValidation()
 	if !otpValidation.ISOTPValid(otp) {
-		return false, errors.New("invalid otp")
+		return false, ErrInvalidOTP
 	}
 
 	// Check rate limit before verification
-	attemptsStr, err := otps.getAttempts(ctx, userID)
+	attempts, err := otps.storage.failedAttempts(ctx, userID)
 	if err != nil {
 		return false, err
 	}
-	if attemptsStr != "" {
-		attempts, err := strconv.Atoi(attemptsStr)
-		if err != nil {
-			return false, fmt.Errorf("corrupted attempts counter: %w", err)
-		}
-		if attempts >= maxAttempts {
-			return false, errors.New("max attempts exceeded")
-		}
+	if attempts >= maxAttempts {
+		return false, ErrMaxAttemptsExceeded
 	}
 
-	val, err := otps.db.Get(ctx, fmt.Sprintf("%s:%s", redisStoreNameOTP, userID)).Result()
-	if errors.Is(err, redis.Nil) {
+	val, found, err := otps.storage.code(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !found {
 		// OTP not found - increment attempts (best effort, ignore error)
-		_, _ = otps.incrementAttempts(ctx, userID)
+		_, _ = otps.storage.fail(ctx, userID)
 		return false, nil
 	}
+
+	matches, err := otps.checkCode(userID, otp, val)
 	if err != nil {
 		return false, err
 	}
-
-	if !otps.hasher.CheckHash(otp, val) {
+	if !matches {
 		// Wrong OTP - increment attempts (best effort, ignore error)
-		_, _ = otps.incrementAttempts(ctx, userID)
+		_, _ = otps.storage.fail(ctx, userID)
 		return false, nil
 	}
 
@@ -253,6 +384,35 @@ func (otps *OTPService) VerifyOTP(ctx context.Context, userID string, otp string
 	return true, nil
 }
 
+// RateLimit returns how many verification attempts the user has left for the
+// current OTP, and when the counter expires (with the OTP).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - RateLimitState: Attempts allowed (5), left and time until reset
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	state, err := otpService.RateLimit(ctx, userID)
+//	if err == nil {
+//	    middleware.SetRateLimitHeaders(w, state)
+//	}
+func (otps *OTPService) RateLimit(ctx context.Context, userID string) (RateLimitState, error) {
+	if userID == "" {
+		return RateLimitState{}, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return otps.storage.rateLimit(ctx, userID, maxAttempts)
+}
+
 // RevokeOTP immediately invalidates the OTP and resets the attempt counter for a user.
 // Safe to call even if no OTP exists (idempotent operation).
 //
@@ -276,18 +436,13 @@ func (otps *OTPService) VerifyOTP(ctx context.Context, userID string, otp string
 //	}
 func (otps *OTPService) RevokeOTP(ctx context.Context, userID string) error {
 	if userID == "" {
-		return errors.New("invalid user id")
+		return ErrInvalidUserID
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	err := otps.db.Del(ctx, fmt.Sprintf("%s:%s", redisStoreNameOTP, userID)).Err()
-	if err != nil {
-		return err
-	}
-
-	return otps.revokeAttempts(ctx, userID)
+	return otps.storage.revoke(ctx, userID)
 }
 
 // RevokeAllOTPs revokes all OTP codes and attempt counters for all users.
@@ -315,116 +470,67 @@ func (otps *OTPService) RevokeOTP(ctx context.Context, userID string) error {
 //	}
 //	log.Println("All OTPs revoked successfully")
 func (otps *OTPService) RevokeAllOTPs(ctx context.Context) error {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	keys := otps.db.Scan(ctx, 0, fmt.Sprintf("%s:*", redisStoreNameOTP), 0).Iterator()
-	for keys.Next(ctx) {
-		key := keys.Val()
-		if err := otps.db.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("failed to delete otp key %s : %w", key, err)
-		}
-	}
-
-	err := otps.revokeAllAttempts(ctx)
-	if err != nil {
-		return err
-	}
-
-	return keys.Err()
-}
-
-func (otps *OTPService) getAttempts(ctx context.Context, userID string) (string, error) {
-	if userID == "" {
-		return "", errors.New("invalid user id")
-	}
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	val, err := otps.db.Get(ctx, fmt.Sprintf("%s:%s", redisStoreNameOTPAttempts, userID)).Result()
-	if errors.Is(err, redis.Nil) {
-		return "", nil
-	}
-	if err != nil {
-		return "", err
-	}
-
-	return val, nil
-}
-
-func (otps *OTPService) incrementAttempts(ctx context.Context, userID string) (int, error) {
-	if userID == "" {
-		return 0, errors.New("invalid user id")
-	}
-
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	key := fmt.Sprintf("%s:%s", redisStoreNameOTPAttempts, userID)
-
-	// Check if key exists to avoid race condition between INCR and EXPIRE
-	exists, err := otps.db.Exists(ctx, key).Result()
-	if err != nil {
-		return 0, err
-	}
-
-	if exists == 0 {
-		// Create key with TTL atomically (no race condition)
-		err = otps.db.Set(ctx, key, 1, otps.duration).Err()
-		if err != nil {
-			return 0, err
-		}
-		return 1, nil
-	}
-
-	// Key exists with TTL already set, safe to increment
-	newAttempts, err := otps.db.Incr(ctx, key).Result()
-	if err != nil {
-		return 0, err
-	}
-
-	return int(newAttempts), nil
+	_, err := otps.RevokeAllOTPsInBatches(ctx, CleanupOptions{Parallelism: defaultBulkParallelism})
+	return err
 }
 
-func (otps *OTPService) revokeAttempts(ctx context.Context, userID string) error {
-	if userID == "" {
-		return errors.New("invalid user id")
-	}
-
+// RevokeOTPsOfUsers revokes the OTP codes and attempt counters of several users,
+// running up to parallelism revocations at once.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops starting revocations
+//   - userIDs: Users whose OTPs are revoked
+//   - parallelism: Maximum concurrent revocations (default: 8 when not positive)
+//
+// Returns:
+//   - error: Joined errors of the failed revocations, each naming its user
+//
+// Example:
+//
+//	if err := otpService.RevokeOTPsOfUsers(ctx, compromisedUserIDs, 16); err != nil {
+//	    log.Printf("Some OTPs were not revoked: %v", err)
+//	}
+func (otps *OTPService) RevokeOTPsOfUsers(ctx context.Context, userIDs []string, parallelism int) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-
-	return otps.db.Del(ctx, fmt.Sprintf("%s:%s", redisStoreNameOTPAttempts, userID)).Err()
-}
-
-func (otps *OTPService) revokeAllAttempts(ctx context.Context) error {
-	if ctx == nil {
-		ctx = context.Background()
+	if parallelism <= 0 {
+		parallelism = defaultBulkParallelism
 	}
 
-	keys := otps.db.Scan(ctx, 0, fmt.Sprintf("%s:*", redisStoreNameOTPAttempts), 0).Iterator()
-	for keys.Next(ctx) {
-		key := keys.Val()
-		if err := otps.db.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("failed to delete otp attempt key %s : %w", key, err)
+	return forEachParallel(ctx, userIDs, parallelism, func(ctx context.Context, userID string) error {
+		if err := otps.RevokeOTP(ctx, userID); err != nil {
+			return fmt.Errorf("user %s: %w", userID, err)
 		}
-	}
-
-	return keys.Err()
+		return nil
+	})
 }
 
-func (otps *OTPService) resetAttempts(ctx context.Context, userID string) error {
-	if userID == "" {
-		return errors.New("invalid user id")
-	}
-
+// RevokeAllOTPsInBatches revokes all OTP codes like RevokeAllOTPs, deleting them in
+// paced batches for large keyspaces. The attempt counters are deleted afterwards,
+// with the same pacing.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops before the next batch
+//   - opts: Batch size, pause between batches and progress callbacks (reporting OTP codes)
+//
+// Returns:
+//   - int64: Number of revoked codes
+//   - error: Storage errors or context cancellation
+//
+// Example:
+//
+//	revoked, err := otpService.RevokeAllOTPsInBatches(ctx, service.CleanupOptions{
+//	    BatchSize: 1000,
+//	    Pause:     10 * time.Millisecond,
+//	    Report: func(stats service.CleanupStats) {
+//	        log.Printf("%d keys scanned, %d codes revoked", stats.Scanned, stats.Deleted)
+//	    },
+//	})
+func (otps *OTPService) RevokeAllOTPsInBatches(ctx context.Context, opts CleanupOptions) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	return otps.db.Set(ctx, fmt.Sprintf("%s:%s", redisStoreNameOTPAttempts, userID), 0, otps.duration).Err()
+	return otps.storage.revokeAll(ctx, opts)
 }
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// OTPResult is the outcome of an asynchronous OTP creation.
+//
+// Fields:
+//   - OTP: Pointer to the generated 6-digit OTP code, nil on error
+//   - Err: Validation, generation or storage error
+type OTPResult struct {
+	OTP *string
+	Err error
+}
+
+// CreateOTPAsync works as CreateOTP without blocking the caller for the bcrypt
+// hashing: the code is generated in a goroutine and the result is delivered on the
+// returned channel, which receives exactly one value.
+//
+// Creations for the same user are stored in call order: codes may be hashed in
+// parallel, but the code stored last (the only valid one) is the code of the
+// last call. Synchronous CreateOTP calls are not ordered with asynchronous ones.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - <-chan OTPResult: Receives the result, buffered so that it can be ignored
+//
+// Example:
+//
+//	result := otpService.CreateOTPAsync(ctx, userID)
+//	// ... other work, e.g. load the user profile
+//	created := <-result
+//	if created.Err != nil {
+//	    return created.Err
+//	}
+//	sendEmail(userEmail, *created.OTP)
+func (otps *OTPService) CreateOTPAsync(ctx context.Context, userID string) <-chan OTPResult {
+	result := make(chan OTPResult, 1)
+	if userID == "" {
+		result <- OTPResult{Err: ErrInvalidUserID}
+		return result
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Take the place in the queue of the user before returning, to keep the call order
+	previous, done := otps.creations.enter(userID)
+	go func() {
+		defer done()
+
+		otp, hash, err := otps.newCode()
+		if err == nil {
+			select {
+			case <-previous:
+				err = otps.store(ctx, userID, hash)
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			result <- OTPResult{Err: err}
+		} else {
+			result <- OTPResult{OTP: &otp}
+		}
+
+		// The next creation must not store its code before this one is settled
+		<-previous
+	}()
+
+	return result
+}
+
+// userQueue orders operations per user: each operation waits until the previous
+// operation of the same user is done.
+type userQueue struct {
+	mu    sync.Mutex
+	tails map[string]chan struct{}
+}
+
+func newUserQueue() *userQueue {
+	return &userQueue{tails: make(map[string]chan struct{})}
+}
+
+// enter appends an operation to the queue of the user. It returns a channel closed
+// when the previous operation is done, and the function marking this one done.
+func (q *userQueue) enter(userID string) (<-chan struct{}, func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	previous, found := q.tails[userID]
+	if !found {
+		previous = make(chan struct{})
+		close(previous)
+	}
+	current := make(chan struct{})
+	q.tails[userID] = current
+
+	return previous, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		close(current)
+		if q.tails[userID] == current {
+			delete(q.tails, userID)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+// OTPFailover wraps an OTPService to keep verifying OTP codes while the primary
+// Redis is down, reading them from a replica.
+//
+// Consistency caveats, when the primary is unavailable:
+//   - Codes are verified against the replica, which may lag behind the primary
+//     (a code created just before the outage can be missing)
+//   - The replica is read-only: failed attempts are not counted, only the attempts
+//     recorded before the outage apply
+//   - Revocations (including the single-use revocation of verified codes) are queued
+//     in memory and replayed once the primary is back; this instance rejects the codes
+//     it verified in the meantime, other instances do not know about them
+//   - The queue is lost if the process stops before the replay
+//
+// Strict mode disables the failover: primary errors are returned as is.
+//
+// Code creation always requires the primary.
+type OTPFailover struct {
+	primary  *OTPService
+	codes    *ttlStore
+	attempts *attemptCounter
+	strict   bool
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+// NewOTPFailover creates a failover wrapper over primary, reading from replica
+// (e.g. a client connected to a Redis replica or Sentinel replica) when the
+// primary Redis is unavailable.
+//
+// Parameters:
+//   - primary: OTP service connected to the primary Redis
+//   - replica: Redis client for read-only verification
+//   - strict: Disable the failover (primary errors are returned)
+//
+// Returns:
+//   - *OTPFailover: Initialized wrapper ready for use
+//   - error: Validation errors
+//
+// Example:
+//
+//	replica := redis.NewClient(&redis.Options{Addr: "redis-replica:6379", ReadOnly: true})
+//	otps, err := service.NewOTPFailover(otpService, replica, false)
+func NewOTPFailover(primary *OTPService, replica *redis.Client, strict bool) (*OTPFailover, error) {
+	if primary == nil {
+		return nil, errors.New("primary otp service is nil")
+	}
+	if replica == nil {
+		return nil, errors.New("replica db is nil")
+	}
+	storage, ok := primary.storage.(*redisOTPStorage)
+	if !ok {
+		return nil, errors.New("primary otp service must be created with NewOTPService")
+	}
+
+	failover := &OTPFailover{
+		primary:  primary,
+		codes:    newTTLStore(replica, storage.codes.prefix, storage.codes.ttl),
+		attempts: newAttemptCounter(replica, storage.attempts.prefix, storage.attempts.window),
+		strict:   strict,
+		pending:  make(map[string]struct{}),
+	}
+	failover.codes.hashTags = storage.codes.hashTags
+	failover.attempts.hashTags = storage.attempts.hashTags
+	return failover, nil
+}
+
+// CreateOTP generates a new OTP code on the primary, see OTPService.CreateOTP.
+func (of *OTPFailover) CreateOTP(ctx context.Context, userID string) (*string, error) {
+	otp, err := of.primary.CreateOTP(ctx, userID)
+	if err == nil {
+		// The new code replaces the revoked one on the primary
+		of.forget(userID)
+	}
+	return otp, err
+}
+
+// VerifyOTP checks an OTP code like OTPService.VerifyOTP, against the replica
+// when the primary is unavailable (see the consistency caveats of OTPFailover).
+// Queued revocations are replayed first when some are pending.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - otp: The OTP code to verify (must be 6 digits)
+//
+// Returns:
+//   - bool: true if OTP is valid and not rate-limited, false otherwise
+//   - error: Same errors as OTPService.VerifyOTP, primary errors in strict mode
+func (of *OTPFailover) VerifyOTP(ctx context.Context, userID string, otp string) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if of.Pending() > 0 {
+		_, _ = of.ReplayRevocations(ctx) // Best effort, kept queued on failure
+	}
+
+	valid, err := of.primary.VerifyOTP(ctx, userID, otp)
+	if err == nil || of.strict || !isUnavailable(err) {
+		return valid, err
+	}
+
+	return of.verifyOnReplica(ctx, userID, otp)
+}
+
+// RevokeOTP revokes the OTP of a user like OTPService.RevokeOTP. When the primary
+// is unavailable, the revocation is queued for replay and the code is rejected by
+// this instance in the meantime.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation or storage errors, primary errors in strict mode
+func (of *OTPFailover) RevokeOTP(ctx context.Context, userID string) error {
+	err := of.primary.RevokeOTP(ctx, userID)
+	if err == nil || of.strict || !isUnavailable(err) {
+		return err
+	}
+
+	of.queue(userID)
+	return nil
+}
+
+// Pending returns the number of revocations waiting for the primary.
+func (of *OTPFailover) Pending() int {
+	of.mu.Lock()
+	defer of.mu.Unlock()
+	return len(of.pending)
+}
+
+// ReplayRevocations applies the queued revocations on the primary.
+// Revocations that fail stay queued.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - int: Number of replayed revocations
+//   - error: The errors of the revocations still queued
+func (of *OTPFailover) ReplayRevocations(ctx context.Context) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	of.mu.Lock()
+	userIDs := make([]string, 0, len(of.pending))
+	for userID := range of.pending {
+		userIDs = append(userIDs, userID)
+	}
+	of.mu.Unlock()
+
+	var replayed atomic.Int64
+	err := forEachParallel(ctx, userIDs, defaultBulkParallelism, func(ctx context.Context, userID string) error {
+		if err := of.primary.RevokeOTP(ctx, userID); err != nil {
+			return err
+		}
+		of.forget(userID)
+		replayed.Add(1)
+		return nil
+	})
+
+	return int(replayed.Load()), err
+}
+
+// verifyOnReplica checks the code against the replica without writing to it.
+func (of *OTPFailover) verifyOnReplica(ctx context.Context, userID string, otp string) (bool, error) {
+	otp = of.primary.config.TokenNormalization.Normalize(otp)
+	if !validation.NewOTPValidation().ISOTPValid(otp) {
+		return false, ErrInvalidOTP
+	}
+
+	if of.isPending(userID) {
+		// Verified or revoked during the outage
+		return false, nil
+	}
+
+	attempts, err := of.attempts.get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if attempts >= maxAttempts {
+		return false, ErrMaxAttemptsExceeded
+	}
+
+	val, found, err := of.codes.get(ctx, userID)
+	if err != nil || !found {
+		return false, err
+	}
+	matches, err := of.primary.checkCode(userID, otp, val)
+	if err != nil || !matches {
+		return false, err
+	}
+
+	// Single use: revoked on the primary once it is back
+	of.queue(userID)
+	return true, nil
+}
+
+func (of *OTPFailover) queue(userID string) {
+	of.mu.Lock()
+	defer of.mu.Unlock()
+	of.pending[userID] = struct{}{}
+}
+
+func (of *OTPFailover) forget(userID string) {
+	of.mu.Lock()
+	defer of.mu.Unlock()
+	delete(of.pending, userID)
+}
+
+func (of *OTPFailover) isPending(userID string) bool {
+	of.mu.Lock()
+	defer of.mu.Unlock()
+	_, found := of.pending[userID]
+	return found
+}
+
+// isUnavailable reports whether err means that Redis could not be reached,
+// as opposed to a validation or a Redis command error.
+func isUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, redis.ErrClosed)
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOTPPoolSize    int           = 100
+	defaultOTPPoolMaxAge  time.Duration = 5 * time.Minute
+	defaultOTPPoolWorkers int           = 1
+)
+
+// OTPPoolOptions configures an OTPPool.
+//
+// Fields:
+//   - Size: Maximum number of pre-generated codes kept (default: 100)
+//   - MaxAge: Codes older than this are discarded unused (default: 5 minutes)
+//   - Workers: Goroutines generating codes in parallel (default: 1)
+type OTPPoolOptions struct {
+	Size    int
+	MaxAge  time.Duration
+	Workers int
+}
+
+// otpPair is a pre-generated OTP code with its hash.
+type otpPair struct {
+	code      string
+	hash      string
+	createdAt time.Time
+}
+
+// OTPPool wraps an OTPService to absorb bursts of OTP creations: code and hash
+// pairs are generated ahead of time in the background, so that CreateOTP does not
+// pay for bcrypt (about a second at cost 14) while the pool is not empty. When it
+// is, CreateOTP falls back to OTPService.CreateOTP.
+//
+// Security notes:
+//   - Pre-generated codes are held in clear in process memory until they are handed
+//     out (they are never written to Redis or logged). Anyone able to read the memory
+//     of the process learns codes that will be sent to users later.
+//   - MaxAge bounds this exposure: older codes are discarded and replaced, so a code
+//     is never issued more than MaxAge after its generation.
+//   - Codes are generated as by CreateOTP and are not tied to a user before issuance.
+//   - Keeping the pool fresh costs Size hashes every MaxAge, even without traffic.
+type OTPPool struct {
+	otps    *OTPService
+	size    int
+	maxAge  time.Duration
+	workers int
+
+	mu    sync.Mutex
+	pairs []otpPair // oldest first
+	wake  chan struct{}
+}
+
+// NewOTPPool creates a pre-generation pool over otps. The pool is empty until Run is started.
+//
+// Parameters:
+//   - otps: OTP service storing the codes (its hasher and generator are used)
+//   - opts: Pool size, maximum code age and number of workers
+//
+// Returns:
+//   - *OTPPool: Initialized pool, to be filled by Run
+//   - error: Validation errors
+//
+// Example:
+//
+//	pool, err := service.NewOTPPool(otpService, service.OTPPoolOptions{Size: 200, MaxAge: 2 * time.Minute, Workers: 4})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	go pool.Run(ctx)
+//	otp, err := pool.CreateOTP(ctx, userID)
+func NewOTPPool(otps *OTPService, opts OTPPoolOptions) (*OTPPool, error) {
+	if otps == nil {
+		return nil, errors.New("otp service is nil")
+	}
+
+	pool := &OTPPool{
+		otps:    otps,
+		size:    opts.Size,
+		maxAge:  opts.MaxAge,
+		workers: opts.Workers,
+	}
+	if pool.size <= 0 {
+		pool.size = defaultOTPPoolSize
+	}
+	if pool.maxAge <= 0 {
+		pool.maxAge = defaultOTPPoolMaxAge
+	}
+	if pool.workers <= 0 {
+		pool.workers = defaultOTPPoolWorkers
+	}
+	pool.wake = make(chan struct{}, pool.workers)
+
+	return pool, nil
+}
+
+// Run fills the pool and keeps it full of fresh codes until ctx is done.
+// It blocks: run it in its own goroutine.
+//
+// Parameters:
+//   - ctx: Stops the workers when done
+func (p *OTPPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// CreateOTP works as OTPService.CreateOTP, using a pre-generated code when one is available.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - *string: Pointer to the 6-digit OTP code (plaintext for sending via email/SMS)
+//   - error: Validation or storage errors
+func (p *OTPPool) CreateOTP(ctx context.Context, userID string) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pair, found := p.take()
+	if !found {
+		return p.otps.CreateOTP(ctx, userID)
+	}
+
+	if err := p.otps.store(ctx, userID, pair.hash); err != nil {
+		return nil, err
+	}
+	return &pair.code, nil
+}
+
+// Available returns the number of fresh codes in the pool.
+func (p *OTPPool) Available() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune()
+	return len(p.pairs)
+}
+
+// work generates codes while the pool has room, then waits for a code to be taken
+// or to expire.
+func (p *OTPPool) work(ctx context.Context) {
+	timer := time.NewTimer(p.maxAge / 2)
+	defer timer.Stop()
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if p.hasRoom() {
+			code, hash, err := p.otps.newCode()
+			if err == nil {
+				p.put(otpPair{code: code, hash: hash, createdAt: p.otps.now()})
+				continue
+			}
+			// Generation failures are transient (entropy source): retry on next tick
+		}
+
+		timer.Reset(p.maxAge / 2)
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// hasRoom reports whether the pool can take another code, after discarding stale ones.
+func (p *OTPPool) hasRoom() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune()
+	return len(p.pairs) < p.size
+}
+
+func (p *OTPPool) put(pair otpPair) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.pairs) < p.size {
+		p.pairs = append(p.pairs, pair)
+	}
+}
+
+// take removes the oldest fresh code from the pool and wakes a worker to replace it.
+func (p *OTPPool) take() (otpPair, bool) {
+	p.mu.Lock()
+	p.prune()
+	if len(p.pairs) == 0 {
+		p.mu.Unlock()
+		return otpPair{}, false
+	}
+	pair := p.pairs[0]
+	p.pairs[0] = otpPair{}
+	p.pairs = p.pairs[1:]
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return pair, true
+}
+
+// prune discards the codes older than maxAge. The caller must hold mu.
+func (p *OTPPool) prune() {
+	now := p.otps.now()
+	stale := 0
+	for stale < len(p.pairs) && now.Sub(p.pairs[stale].createdAt) >= p.maxAge {
+		p.pairs[stale] = otpPair{}
+		stale++
+	}
+	p.pairs = p.pairs[stale:]
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// OTPMessage is an OTP code to deliver to a user.
+//
+// Fields:
+//   - UserID: User the code was created for
+//   - Recipient: Email address or phone number (E.164) to deliver to
+//   - Code: The plaintext code
+//   - ExpiresIn: Lifetime of the code (Config.OTPTTL)
+//   - Locale: Language of the message, from the lib.RequestMeta of the context (may be empty)
+type OTPMessage struct {
+	UserID    string
+	Recipient string
+	Code      string
+	ExpiresIn time.Duration
+	Locale    string
+}
+
+// DeliveryReceipt describes a delivered OTP, without its code.
+//
+// Fields:
+//   - Provider: Name of the sender ("smtp", "twilio", "sns")
+//   - MessageID: Identifier of the message at the provider, for support and delivery tracking
+//   - Recipient: Email address or phone number the code was sent to
+//   - SentAt: Time the provider accepted the message
+//   - ExpiresAt: Expiry of the code
+type DeliveryReceipt struct {
+	Provider  string
+	MessageID string
+	Recipient string
+	SentAt    time.Time
+	ExpiresAt time.Time
+}
+
+// OTPSender delivers OTP codes to users, by email or SMS. Implementations must be
+// safe for concurrent use.
+//
+// Provided implementations:
+//   - NewSMTPSender: Email through an SMTP server
+//   - NewTwilioSender: SMS through the Twilio Messages API
+//   - NewSNSSender: SMS through Amazon SNS, with the application's AWS client
+type OTPSender interface {
+	// SendOTP delivers the code of msg and returns the provider message identifier.
+	SendOTP(ctx context.Context, msg OTPMessage) (string, error)
+	// Provider returns the name of the provider, reported in the receipts.
+	Provider() string
+}
+
+// DefaultOTPText is the text of the OTP messages of the provided senders, unless
+// configured otherwise: the built-in template in the locale of the message,
+// "Your verification code is 123456. It expires in 10 minutes." in English.
+func DefaultOTPText(msg OTPMessage) string {
+	return defaultMessages.OTPText(msg)
+}
+
+// DeliverOTP creates an OTP for the user and delivers it with the sender of
+// WithOTPSender, so that the plaintext code never reaches the application (and its
+// logs). If the delivery fails, the code is revoked and the user can request another.
+// The message is in the locale of the lib.RequestMeta of ctx, if any.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - recipient: Email address or phone number to deliver to
+//
+// Returns:
+//   - *DeliveryReceipt: The delivery, without the code
+//   - error: Validation, storage or delivery errors; an error if no sender is configured
+//
+// Example:
+//
+//	receipt, err := otpService.DeliverOTP(ctx, user.ID, user.Email)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("OTP sent to user %s: %s message %s", user.ID, receipt.Provider, receipt.MessageID)
+func (otps *OTPService) DeliverOTP(ctx context.Context, userID string, recipient string) (*DeliveryReceipt, error) {
+	if otps.sender == nil {
+		return nil, errors.New("no otp sender configured, see WithOTPSender")
+	}
+	if recipient == "" {
+		return nil, errors.New("recipient is empty")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	otp, err := otps.CreateOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	messageID, err := otps.sender.SendOTP(ctx, OTPMessage{
+		UserID:    userID,
+		Recipient: recipient,
+		Code:      *otp,
+		ExpiresIn: otps.duration,
+		Locale:    meta.Locale,
+	})
+	if err != nil {
+		// Best effort: a code the user never received must not stay valid
+		_ = otps.storage.revoke(context.WithoutCancel(ctx), userID)
+		return nil, fmt.Errorf("failed to deliver otp: %w", err)
+	}
+
+	sentAt := otps.now()
+	return &DeliveryReceipt{
+		Provider:  otps.sender.Provider(),
+		MessageID: messageID,
+		Recipient: recipient,
+		SentAt:    sentAt,
+		ExpiresAt: sentAt.Add(otps.duration),
+	}, nil
+}
+
+// SMTPSenderConfig configures an SMTP sender.
+//
+// Fields:
+//   - Addr: Address of the SMTP server, "host:port" (STARTTLS is used when offered)
+//   - Auth: Authentication, e.g. smtp.PlainAuth (nil for none)
+//   - From: Sender address
+//   - Subject: Subject of the emails (default: the subject of Messages)
+//   - Text: Plain text body of the emails (default: the text of Messages)
+//   - Messages: Localized templates of the emails (default: the built-in templates)
+type SMTPSenderConfig struct {
+	Addr     string
+	Auth     smtp.Auth
+	From     string
+	Subject  string
+	Text     func(msg OTPMessage) string
+	Messages *MessageRenderer
+}
+
+// smtpSender implements OTPSender with net/smtp.
+type smtpSender struct {
+	config   SMTPSenderConfig
+	hostname string
+}
+
+// NewSMTPSender returns an OTPSender emailing the codes through an SMTP server.
+// The context of SendOTP is not observed by net/smtp: configure the server timeouts.
+//
+// Example:
+//
+//	sender, err := service.NewSMTPSender(service.SMTPSenderConfig{
+//	    Addr: "smtp.example.com:587",
+//	    Auth: smtp.PlainAuth("", user, password, "smtp.example.com"),
+//	    From: "no-reply@example.com",
+//	})
+func NewSMTPSender(config SMTPSenderConfig) (OTPSender, error) {
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("invalid smtp address: %q", config.Addr)
+	}
+	if config.From == "" || strings.ContainsAny(config.From, "\r\n") {
+		return nil, fmt.Errorf("invalid smtp sender address: %q", config.From)
+	}
+	if strings.ContainsAny(config.Subject, "\r\n") {
+		return nil, errors.New("smtp subject must be a single line")
+	}
+	if config.Messages == nil {
+		config.Messages = defaultMessages
+	}
+	if config.Text == nil {
+		config.Text = config.Messages.OTPText
+	}
+
+	_, domain, _ := strings.Cut(config.From, "@")
+	if domain == "" {
+		domain = host
+	}
+	return &smtpSender{config: config, hostname: domain}, nil
+}
+
+func (s *smtpSender) Provider() string {
+	return "smtp"
+}
+
+func (s *smtpSender) SendOTP(_ context.Context, msg OTPMessage) (string, error) {
+	if strings.ContainsAny(msg.Recipient, "\r\n") {
+		return "", fmt.Errorf("invalid email address: %q", msg.Recipient)
+	}
+
+	random, err := lib.GenerateRandomString(24)
+	if err != nil {
+		return "", err
+	}
+	messageID := fmt.Sprintf("<%s@%s>", random, s.hostname)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.Recipient)
+	subject := s.config.Subject
+	if subject == "" {
+		subject = s.config.Messages.OTPSubject(msg)
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return "", errors.New("smtp subject must be a single line")
+	}
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&body, "Message-ID: %s\r\n", messageID)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(s.config.Text(msg), "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	if err := smtp.SendMail(s.config.Addr, s.config.Auth, s.config.From, []string{msg.Recipient}, []byte(body.String())); err != nil {
+		return "", err
+	}
+	return messageID, nil
+}
+
+// twilioAPIURL is the base URL of the Twilio REST API.
+const twilioAPIURL string = "https://api.twilio.com"
+
+// TwilioSenderConfig configures a Twilio sender.
+//
+// Fields:
+//   - AccountSID: Twilio account SID
+//   - AuthToken: Twilio auth token
+//   - From: Sending phone number (E.164), or a Messaging Service SID ("MG...")
+//   - Text: Body of the SMS (default: the text of Messages)
+//   - Messages: Localized templates of the SMS (default: the built-in templates)
+//   - HTTPClient: Client of the API calls (default: 10s timeout)
+//   - BaseURL: Base URL of the API (default: https://api.twilio.com), for tests
+type TwilioSenderConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	Text       func(msg OTPMessage) string
+	Messages   *MessageRenderer
+	HTTPClient *http.Client
+	BaseURL    string
+}
+
+// twilioSender implements OTPSender with the Twilio Messages API.
+type twilioSender struct {
+	config TwilioSenderConfig
+}
+
+// NewTwilioSender returns an OTPSender texting the codes through the Twilio Messages API.
+//
+// Example:
+//
+//	sender, err := service.NewTwilioSender(service.TwilioSenderConfig{
+//	    AccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+//	    AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+//	    From:       "+15005550006",
+//	})
+func NewTwilioSender(config TwilioSenderConfig) (OTPSender, error) {
+	if config.AccountSID == "" || config.AuthToken == "" {
+		return nil, errors.New("twilio account sid and auth token are required")
+	}
+	if config.From == "" {
+		return nil, errors.New("twilio sender is required")
+	}
+	if config.Messages == nil {
+		config.Messages = defaultMessages
+	}
+	if config.Text == nil {
+		config.Text = config.Messages.OTPText
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = twilioAPIURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &twilioSender{config: config}, nil
+}
+
+func (s *twilioSender) Provider() string {
+	return "twilio"
+}
+
+func (s *twilioSender) SendOTP(ctx context.Context, msg OTPMessage) (string, error) {
+	form := url.Values{}
+	form.Set("To", msg.Recipient)
+	form.Set("Body", s.config.Text(msg))
+	if strings.HasPrefix(s.config.From, "MG") {
+		form.Set("MessagingServiceSid", s.config.From)
+	} else {
+		form.Set("From", s.config.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.config.BaseURL, url.PathEscape(s.config.AccountSID))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(s.config.AccountSID, s.config.AuthToken)
+
+	response, err := s.config.HTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		SID     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	decodeErr := json.NewDecoder(response.Body).Decode(&result)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		if decodeErr == nil && result.Message != "" {
+			return "", fmt.Errorf("twilio: status %d: %s (code %d)", response.StatusCode, result.Message, result.Code)
+		}
+		return "", fmt.Errorf("twilio: status %d", response.StatusCode)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("twilio: invalid response: %w", decodeErr)
+	}
+	return result.SID, nil
+}
+
+// SNSPublishFunc publishes an SMS with Amazon SNS and returns the message ID, e.g.
+// with the Publish method of the AWS SDK client.
+type SNSPublishFunc func(ctx context.Context, phoneNumber string, message string) (string, error)
+
+// snsSender implements OTPSender on an SNSPublishFunc.
+type snsSender struct {
+	publish SNSPublishFunc
+	text    func(msg OTPMessage) string
+}
+
+// NewSNSSender returns an OTPSender texting the codes through Amazon SNS. This module
+// does not depend on the AWS SDK (only the separate dynamostore module does): publish
+// calls the SNS client of the application, configured with its credentials and region.
+//
+// Parameters:
+//   - publish: Publishes an SMS to a phone number
+//   - text: Body of the SMS, e.g. MessageRenderer.OTPText (nil for DefaultOTPText)
+//
+// Example:
+//
+//	client := sns.NewFromConfig(awsConfig)
+//	sender, err := service.NewSNSSender(func(ctx context.Context, phoneNumber string, message string) (string, error) {
+//	    out, err := client.Publish(ctx, &sns.PublishInput{
+//	        PhoneNumber: aws.String(phoneNumber),
+//	        Message:     aws.String(message),
+//	        MessageAttributes: map[string]types.MessageAttributeValue{
+//	            "AWS.SNS.SMS.SMSType": {DataType: aws.String("String"), StringValue: aws.String("Transactional")},
+//	        },
+//	    })
+//	    if err != nil {
+//	        return "", err
+//	    }
+//	    return aws.ToString(out.MessageId), nil
+//	}, nil)
+func NewSNSSender(publish SNSPublishFunc, text func(msg OTPMessage) string) (OTPSender, error) {
+	if publish == nil {
+		return nil, errors.New("sns publish function is nil")
+	}
+	if text == nil {
+		text = DefaultOTPText
+	}
+	return &snsSender{publish: publish, text: text}, nil
+}
+
+func (s *snsSender) Provider() string {
+	return "sns"
+}
+
+func (s *snsSender) SendOTP(ctx context.Context, msg OTPMessage) (string, error) {
+	return s.publish(ctx, msg.Recipient, s.text(msg))
+}
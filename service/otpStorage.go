@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// otpStorage keeps the OTP codes and attempt counters of an OTPService.
+type otpStorage interface {
+	// store makes hash the active OTP of the user and resets the attempts counter.
+	store(ctx context.Context, userID string, hash string) error
+	// code returns the hash of the active OTP, false if there is none.
+	code(ctx context.Context, userID string) (string, bool, error)
+	// remaining returns how long the active OTP lives, 0 if there is none.
+	remaining(ctx context.Context, userID string) (time.Duration, error)
+	// failedAttempts returns the number of failed attempts.
+	failedAttempts(ctx context.Context, userID string) (int, error)
+	// fail records a failed attempt and returns the new count.
+	fail(ctx context.Context, userID string) (int, error)
+	// rateLimit returns the rate limit state of the user.
+	rateLimit(ctx context.Context, userID string, limit int) (RateLimitState, error)
+	// revoke deletes the OTP and the attempts counter of the user.
+	revoke(ctx context.Context, userID string) error
+	// revokeAll deletes the OTPs, then the attempts counters, of all users.
+	revokeAll(ctx context.Context, opts CleanupOptions) (int64, error)
+}
+
+// redisOTPStorage is the default otpStorage, on go-redis.
+type redisOTPStorage struct {
+	db       *redis.Client
+	codes    *ttlStore
+	attempts *attemptCounter
+}
+
+func newRedisOTPStorage(db *redis.Client, prefix keyPrefix, ttl time.Duration, hashTags bool) *redisOTPStorage {
+	storage := &redisOTPStorage{
+		db:       db,
+		codes:    newTTLStore(db, prefix.name(redisStoreNameOTP), ttl),
+		attempts: newAttemptCounter(db, prefix.name(redisStoreNameOTPAttempts), ttl),
+	}
+	storage.codes.hashTags = hashTags
+	storage.attempts.hashTags = hashTags
+	return storage
+}
+
+func (s *redisOTPStorage) store(ctx context.Context, userID string, hash string) error {
+	if s.codes.hashTags {
+		// Same slot: store the code and reset the counter atomically
+		_, err := s.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, s.codes.key(userID), hash, s.codes.ttl)
+			pipe.Set(ctx, s.attempts.key(userID), 0, s.attempts.window)
+			return nil
+		})
+		return err
+	}
+
+	if err := s.codes.set(ctx, userID, hash); err != nil {
+		return err
+	}
+
+	// Reset attempts counter - if this fails, rollback OTP creation
+	if err := s.attempts.reset(ctx, userID); err != nil {
+		// Best effort rollback: delete the OTP we just created
+		_ = s.codes.revoke(ctx, userID)
+		return fmt.Errorf("failed to reset attempts counter: %w", err)
+	}
+	return nil
+}
+
+func (s *redisOTPStorage) code(ctx context.Context, userID string) (string, bool, error) {
+	return s.codes.get(ctx, userID)
+}
+
+func (s *redisOTPStorage) remaining(ctx context.Context, userID string) (time.Duration, error) {
+	return s.codes.remaining(ctx, userID)
+}
+
+func (s *redisOTPStorage) failedAttempts(ctx context.Context, userID string) (int, error) {
+	return s.attempts.get(ctx, userID)
+}
+
+func (s *redisOTPStorage) fail(ctx context.Context, userID string) (int, error) {
+	return s.attempts.increment(ctx, userID)
+}
+
+func (s *redisOTPStorage) rateLimit(ctx context.Context, userID string, limit int) (RateLimitState, error) {
+	return s.attempts.state(ctx, userID, limit)
+}
+
+func (s *redisOTPStorage) revoke(ctx context.Context, userID string) error {
+	if s.codes.hashTags {
+		// Same slot: a single DEL revokes both
+		return s.db.Del(ctx, s.codes.key(userID), s.attempts.key(userID)).Err()
+	}
+
+	if err := s.codes.revoke(ctx, userID); err != nil {
+		return err
+	}
+	return s.attempts.revoke(ctx, userID)
+}
+
+func (s *redisOTPStorage) revokeAll(ctx context.Context, opts CleanupOptions) (int64, error) {
+	// "otp:*" also matches the attempts counters, deleted below
+	codeOpts := opts
+	codeOpts.except = s.attempts.prefix + ":"
+	revoked, err := s.codes.revokeAllInBatches(ctx, codeOpts)
+	if err != nil {
+		return revoked, err
+	}
+
+	attemptOpts := opts
+	attemptOpts.Progress = nil
+	attemptOpts.Report = nil
+	_, err = s.attempts.revokeAllInBatches(ctx, attemptOpts)
+	return revoked, err
+}
+
+// kvOTPStorage is the otpStorage of NewOTPServiceWithStore, on a KVStore.
+// Keys are the same as with Redis: "otp:{userID}" and "otp:attempts:{userID}".
+type kvOTPStorage struct {
+	kv             KVStore
+	codePrefix     string
+	attemptsPrefix string
+	ttl            time.Duration
+}
+
+func newKVOTPStorage(kv KVStore, prefix keyPrefix, ttl time.Duration) *kvOTPStorage {
+	return &kvOTPStorage{
+		kv:             kv,
+		codePrefix:     prefix.name(redisStoreNameOTP),
+		attemptsPrefix: prefix.name(redisStoreNameOTPAttempts),
+		ttl:            ttl,
+	}
+}
+
+func (s *kvOTPStorage) codeKey(userID string) string {
+	return fmt.Sprintf("%s:%s", s.codePrefix, userID)
+}
+
+func (s *kvOTPStorage) attemptsKey(userID string) string {
+	return fmt.Sprintf("%s:%s", s.attemptsPrefix, userID)
+}
+
+func (s *kvOTPStorage) store(ctx context.Context, userID string, hash string) error {
+	if err := s.kv.SetEX(ctx, s.codeKey(userID), hash, s.ttl); err != nil {
+		return err
+	}
+
+	// Reset attempts counter - if this fails, rollback OTP creation
+	if err := s.kv.SetEX(ctx, s.attemptsKey(userID), "0", s.ttl); err != nil {
+		// Best effort rollback: delete the OTP we just created
+		_, _ = s.kv.Del(ctx, s.codeKey(userID))
+		return fmt.Errorf("failed to reset attempts counter: %w", err)
+	}
+	return nil
+}
+
+func (s *kvOTPStorage) code(ctx context.Context, userID string) (string, bool, error) {
+	return s.kv.Get(ctx, s.codeKey(userID))
+}
+
+func (s *kvOTPStorage) remaining(ctx context.Context, userID string) (time.Duration, error) {
+	return s.kv.TTL(ctx, s.codeKey(userID))
+}
+
+func (s *kvOTPStorage) failedAttempts(ctx context.Context, userID string) (int, error) {
+	val, found, err := s.kv.Get(ctx, s.attemptsKey(userID))
+	if err != nil || !found {
+		return 0, err
+	}
+
+	attempts, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted attempts counter: %w", err)
+	}
+	return attempts, nil
+}
+
+func (s *kvOTPStorage) fail(ctx context.Context, userID string) (int, error) {
+	attempts, err := s.kv.Incr(ctx, s.attemptsKey(userID), s.ttl)
+	return int(attempts), err
+}
+
+func (s *kvOTPStorage) rateLimit(ctx context.Context, userID string, limit int) (RateLimitState, error) {
+	attempts, err := s.failedAttempts(ctx, userID)
+	if err != nil {
+		return RateLimitState{}, err
+	}
+	reset, err := s.kv.TTL(ctx, s.attemptsKey(userID))
+	if err != nil {
+		return RateLimitState{}, err
+	}
+	return RateLimitState{
+		Limit:     limit,
+		Remaining: max(limit-attempts, 0),
+		Reset:     reset,
+	}, nil
+}
+
+func (s *kvOTPStorage) revoke(ctx context.Context, userID string) error {
+	_, err := s.kv.Del(ctx, s.codeKey(userID), s.attemptsKey(userID))
+	return err
+}
+
+func (s *kvOTPStorage) revokeAll(ctx context.Context, opts CleanupOptions) (int64, error) {
+	// The "otp:" prefix also holds the attempts counters, deleted below
+	codeOpts := opts
+	codeOpts.except = s.attemptsPrefix + ":"
+	revoked, err := deleteKVPrefix(ctx, s.kv, s.codePrefix+":", codeOpts)
+	if err != nil {
+		return revoked, err
+	}
+
+	attemptOpts := opts
+	attemptOpts.Progress = nil
+	attemptOpts.Report = nil
+	_, err = deleteKVPrefix(ctx, s.kv, s.attemptsPrefix+":", attemptOpts)
+	return revoked, err
+}
+
+// deleteKVPrefix deletes the keys starting with prefix batch by batch, like
+// deleteMatching on Redis. Batches are deleted sequentially (Parallelism is ignored),
+// and OperationTimeout bounds each deletion, the store driving the scan.
+func deleteKVPrefix(ctx context.Context, kv KVStore, prefix string, opts CleanupOptions) (int64, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	var lease *lib.Lease
+	if opts.Lock != nil {
+		var err error
+		if lease, err = opts.Lock.Acquire(ctx); err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = lease.Release(context.WithoutCancel(ctx))
+		}()
+	}
+
+	var stats CleanupStats
+	err := kv.Scan(ctx, prefix, batchSize, func(keys []string) error {
+		keys = opts.kept(keys)
+		if lease != nil {
+			if err := lease.Refresh(ctx); err != nil {
+				return err
+			}
+		}
+
+		opCtx, cancel := opts.operationContext(ctx)
+		deleted, err := kv.Del(opCtx, keys...)
+		cancel()
+		if err != nil {
+			return err
+		}
+		stats.Scanned += int64(len(keys))
+		stats.Deleted += deleted
+		if opts.Progress != nil && deleted > 0 {
+			opts.Progress(stats.Deleted)
+		}
+		if opts.Report != nil {
+			opts.Report(stats)
+		}
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+		return nil
+	})
+	return stats.Deleted, err
+}
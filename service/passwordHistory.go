@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNamePasswordHistory is the Redis key prefix for password history storage.
+	// Key pattern: "password_history:{userID}" holding a list of bcrypt hashes, newest first.
+	redisStoreNamePasswordHistory string = "password_history"
+
+	// defaultPasswordHistorySize is the number of hashes kept when PasswordHistorySize is not configured.
+	defaultPasswordHistorySize int = 5
+)
+
+// PasswordHistoryService keeps the last N password hashes of each user so that
+// "cannot reuse your last 5 passwords" policies can be enforced.
+//
+// Key features:
+//   - Bounded history: only the N most recent hashes are kept (LPUSH + LTRIM)
+//   - Hashes only: plaintext passwords are never stored
+//   - No expiration: history lives until explicitly cleared
+//
+// Redis key pattern:
+//   - Key: "password_history:{userID}"
+//   - Value: List of password hashes, newest first
+type PasswordHistoryService struct {
+	db     *redis.Client
+	config *lib.Config
+	keys   keyPrefix
+	size   int
+}
+
+// PasswordHistoryServiceInterface defines the methods for password history management.
+type PasswordHistoryServiceInterface interface {
+	AddPasswordHash(ctx context.Context, userID string, hash string) error
+	WasRecentlyUsed(ctx context.Context, userID string, newPassword string, hasher lib.PasswordHashInterface) (bool, error)
+	ClearPasswordHistory(ctx context.Context, userID string) error
+	ClearAllPasswordHistories(ctx context.Context) error
+}
+
+// NewPasswordHistoryService creates a new password history service instance with Redis persistence.
+// Returns an error if the database client is nil or if PasswordHistorySize is negative.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for history storage
+//   - config: Configuration containing PasswordHistorySize (default: 5 when 0)
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *PasswordHistoryService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	historyService, err := service.NewPasswordHistoryService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewPasswordHistoryService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*PasswordHistoryService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if config.PasswordHistorySize < 0 {
+		return nil, errors.New("password history size is negative")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	size := config.PasswordHistorySize
+	if size == 0 {
+		size = defaultPasswordHistorySize
+	}
+
+	service := &PasswordHistoryService{
+		db:     db,
+		config: config.Clone(),
+		keys:   newServiceOptions(opts).keyPrefix,
+		size:   size,
+	}
+
+	return service, nil
+}
+
+// AddPasswordHash records a new password hash for the user, typically right
+// after a successful password change. Only the N most recent hashes are kept.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - hash: The hash of the new password (as produced by lib.PasswordHash.Hash)
+//
+// Returns:
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	hash, _ := hasher.Hash(newPassword)
+//	saveUserPassword(userID, hash)
+//	err := historyService.AddPasswordHash(ctx, userID, hash)
+func (phs *PasswordHistoryService) AddPasswordHash(ctx context.Context, userID string, hash string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+	if hash == "" {
+		return errors.New("empty hash")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key := fmt.Sprintf("%s:%s", phs.keys.name(redisStoreNamePasswordHistory), userID)
+
+	// Push and trim atomically so the list never grows beyond the configured size
+	pipe := phs.db.TxPipeline()
+	pipe.LPush(ctx, key, hash)
+	pipe.LTrim(ctx, key, 0, int64(phs.size-1))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// WasRecentlyUsed checks whether the new password matches one of the user's
+// N most recent password hashes.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - newPassword: The plaintext password the user wants to set
+//   - hasher: Hasher used to compare the password against stored hashes
+//
+// Returns:
+//   - bool: true if the password matches a recent hash, false otherwise
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	used, err := historyService.WasRecentlyUsed(ctx, userID, newPassword, lib.NewPasswordHash())
+//	if err != nil {
+//	    return err
+//	}
+//	if used {
+//	    return errors.New("cannot reuse one of your last 5 passwords")
+//	}
+func (phs *PasswordHistoryService) WasRecentlyUsed(ctx context.Context, userID string, newPassword string, hasher lib.PasswordHashInterface) (bool, error) {
+	if userID == "" {
+		return false, ErrInvalidUserID
+	}
+	if hasher == nil {
+		return false, errors.New("hasher is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	hashes, err := phs.db.LRange(ctx, fmt.Sprintf("%s:%s", phs.keys.name(redisStoreNamePasswordHistory), userID), 0, int64(phs.size-1)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		if hasher.CheckHash(newPassword, hash) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ClearPasswordHistory removes the whole password history of a user.
+// Safe to call even if no history exists (idempotent operation).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation or storage errors
+func (phs *PasswordHistoryService) ClearPasswordHistory(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return phs.db.Del(ctx, fmt.Sprintf("%s:%s", phs.keys.name(redisStoreNamePasswordHistory), userID)).Err()
+}
+
+// ClearAllPasswordHistories removes the password history of all users.
+//
+// Warning: This is a destructive operation that affects all users.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during deletion
+func (phs *PasswordHistoryService) ClearAllPasswordHistories(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	keys := phs.db.Scan(ctx, 0, fmt.Sprintf("%s:*", phs.keys.name(redisStoreNamePasswordHistory)), 0).Iterator()
+	for keys.Next(ctx) {
+		key := keys.Val()
+		if err := phs.db.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("failed to delete key %s : %w", key, err)
+		}
+	}
+
+	return keys.Err()
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
@@ -12,38 +17,91 @@ import (
 )
 
 const (
-	// passwordResetTokenMaxLength defines the maximum character length for password reset tokens.
-	// Tokens are 32-character cryptographically secure random strings.
+	// passwordResetTokenMaxLength defines the default character length for password reset tokens.
+	// Tokens are 32-character cryptographically secure random strings unless
+	// Config.PasswordResetTokenLength is set.
 	passwordResetTokenMaxLength int = 32
 
+	// passwordResetTokenMinLength is the shortest configurable password reset token length.
+	passwordResetTokenMinLength int = 16
+
 	// redisStoreNamePasswordReset is the Redis key prefix for password reset token storage.
-	// Key pattern: "password_reset:{userID}" with token value stored directly.
+	// Key pattern: "password_reset:{userID}" with token value stored directly,
+	// or a JSON record for resets not requested by the user (see PasswordResetScope).
 	// Single-token pattern: creating a new token invalidates the previous one.
 	redisStoreNamePasswordReset string = "password_reset"
+
+	// redisStoreNamePasswordResetQuota is the Redis key prefix for the per-user quota counters.
+	// Key pattern: "password_reset_quota:{userID}" with the number of tokens issued,
+	// expiring PasswordResetTTL after the first one (see Config.PasswordResetQuota).
+	redisStoreNamePasswordResetQuota string = "password_reset_quota"
+
+	// redisStoreNamePasswordResetLookup is the Redis key prefix for token to user lookups.
+	// Key pattern: "password_reset_lookup:{sha256(token)}" with the user ID as value,
+	// expiring with the token.
+	redisStoreNamePasswordResetLookup string = "password_reset_lookup"
+)
+
+var (
+	// errPasswordResetTokenNotFound is returned when revoking a token that does not exist.
+	errPasswordResetTokenNotFound = errors.New("token not found or already revoked")
+	// errPasswordResetTokenMismatch is returned when revoking a token replaced by another one.
+	errPasswordResetTokenMismatch = errors.New("token mismatch")
 )
 
+// PasswordResetTokenInfo describes a valid password reset token.
+//
+// Fields:
+//   - UserID: The user the token was issued for
+//   - Scope: Why the reset was issued
+//   - ExpiresAt: When the token expires
+type PasswordResetTokenInfo struct {
+	UserID    string
+	Scope     PasswordResetScope
+	ExpiresAt time.Time
+}
+
 // PasswordResetService manages temporary password reset tokens with Redis persistence.
 // Enforces single active token per user (security measure).
 //
 // Key features:
-//   - Single-token enforcement: Creating new token invalidates previous one
+//   - Single-token enforcement: Creating new token invalidates previous one,
+//     or reuses it depending on PasswordResetPolicy
 //   - Short TTL: Default 10 minutes (configurable via PasswordResetTTL)
 //   - Cryptographically secure 32-character tokens
 //   - Revocation requires token match (prevents unauthorized revocation)
 //   - Automatic expiration via Redis TTL
 //
-// Redis key pattern:
+// Redis key patterns:
+//   - Lookup: "password_reset_lookup:{sha256(token)}" → user ID (see IdentifyPasswordResetToken)
+//   - Code attempts: "password_reset_code_attempts:{userID}" → failed short code attempts
 //   - Key: "password_reset:{userID}"
-//   - Value: The actual token string (compared during verification)
+//   - Value: The actual token string (compared during verification), or a JSON
+//     record holding the token and its scope for admin-forced and breach resets,
+//     and the hash of the short code (see CreatePasswordResetTokenWithCode)
 //   - TTL: Configured via PasswordResetTTL (default: 10 minutes)
 //
 // Security rationale:
 //   - Single-token prevents multiple concurrent reset attempts
 //   - Short TTL limits exposure window for stolen tokens
 //   - Token match on revocation prevents malicious invalidation
+//
+// Services created with NewPasswordResetServiceWithStore keep the token records in a
+// TokenStore instead, indexed by token hash, and db is nil.
 type PasswordResetService struct {
-	db     *redis.Client
-	config *lib.Config
+	db        *redis.Client
+	store     TokenStore
+	config    *lib.Config
+	keys      keyPrefix
+	length    tokenLength
+	retention RetentionPolicy
+
+	// mu guards the hooks, which can be set while tokens are verified.
+	mu      sync.RWMutex
+	risk    RiskEvaluator
+	audit   lib.AuditLogger
+	invalid *InvalidTokenMonitor
+	replays lib.ReplayMetricsHook
 }
 
 // NewPasswordResetService creates a new password reset service instance with Redis persistence.
@@ -53,6 +111,7 @@ type PasswordResetService struct {
 //   - ctx: Context for initialization (uses Background if nil)
 //   - db: Redis client for token storage
 //   - config: Configuration containing PasswordResetTTL
+//   - opts: Optional settings (WithKeyPrefix, WithLogger)
 //
 // Returns:
 //   - *PasswordResetService: Initialized service ready for use
@@ -64,23 +123,159 @@ type PasswordResetService struct {
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func NewPasswordResetService(ctx context.Context, db *redis.Client, config *lib.Config) (*PasswordResetService, error) {
+func NewPasswordResetService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*PasswordResetService, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
+
+	service, err := newPasswordResetService(ctx, config, opts)
+	if err != nil {
+		return nil, err
+	}
+	service.db = db
+	return service, nil
+}
+
+// NewPasswordResetServiceWithStore creates a new password reset service instance storing
+// its tokens in a TokenStore instead of Redis, e.g. a DynamoDB table (see dynamostore).
+// The single-token pattern and resend policies apply as with Redis.
+//
+// Creating, verifying, identifying and revoking tokens, user data export and erasure,
+// leak handling and token import/export work as with Redis. The revocation log is not
+// kept: ListRevokedPasswordResetTokens returns nothing and replays are not reported.
+// Short codes fail with ErrTokenStoreUnsupported, and a PasswordResetQuota is refused.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - store: Token store for password reset token storage
+//   - config: Configuration containing PasswordResetTTL
+//   - opts: Optional settings (WithLogger)
+//
+// Returns:
+//   - *PasswordResetService: Initialized service ready for use
+//   - error: Configuration or store validation errors
+//
+// Example:
+//
+//	resetService, err := service.NewPasswordResetServiceWithStore(ctx, service.NewMemoryTokenStore(), config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewPasswordResetServiceWithStore(ctx context.Context, store TokenStore, config *lib.Config, opts ...Option) (*PasswordResetService, error) {
+	if store == nil {
+		return nil, errors.New("store is nil")
+	}
+	if config.PasswordResetQuota > 0 {
+		return nil, fmt.Errorf("password reset quota: %w", ErrTokenStoreUnsupported)
+	}
+
+	service, err := newPasswordResetService(ctx, config, opts)
+	if err != nil {
+		return nil, err
+	}
+	service.store = store
+	return service, nil
+}
+
+// newPasswordResetService validates the configuration and creates a service without storage.
+func newPasswordResetService(ctx context.Context, config *lib.Config, opts []Option) (*PasswordResetService, error) {
 	if config.PasswordResetTTL == nil {
 		return nil, errors.New("password reset ttl is nil") // Should no go further
 	}
+	switch config.PasswordResetPolicy {
+	case "", lib.PasswordResetPolicyReplace, lib.PasswordResetPolicyReuse, lib.PasswordResetPolicyExtend:
+	default:
+		return nil, fmt.Errorf("invalid password reset policy: %s", config.PasswordResetPolicy)
+	}
+	if config.PasswordResetQuota < 0 {
+		return nil, errors.New("password reset quota must not be negative")
+	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	service := &PasswordResetService{db, config}
+	length, err := newTokenLength("password reset", config.PasswordResetTokenLength, config.PasswordResetTokenMaxLength, passwordResetTokenMaxLength, passwordResetTokenMinLength)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newServiceOptions(opts)
+	retention, err := options.retention.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	service := &PasswordResetService{
+		config:    config.Clone(),
+		keys:      options.keyPrefix,
+		length:    length,
+		retention: retention,
+		audit:     options.audit,
+	}
 
 	return service, nil
 }
 
+// SetRiskEvaluator plugs a risk evaluator invoked on token creation and verification.
+// Its verdict can require step-up authentication (ErrStepUpRequired) or deny the
+// operation (ErrRiskDenied). A nil evaluator disables risk evaluation.
+func (prs *PasswordResetService) SetRiskEvaluator(evaluator RiskEvaluator) {
+	prs.mu.Lock()
+	defer prs.mu.Unlock()
+	prs.risk = evaluator
+}
+
+// SetAuditLogger configures the logger receiving the service audit events.
+// A nil logger disables auditing.
+func (prs *PasswordResetService) SetAuditLogger(logger lib.AuditLogger) {
+	prs.mu.Lock()
+	defer prs.mu.Unlock()
+	prs.audit = logger
+}
+
+// SetInvalidTokenMonitor configures the monitor counting the nonexistent, expired or
+// malformed tokens presented per source. A nil monitor disables the counting.
+func (prs *PasswordResetService) SetInvalidTokenMonitor(monitor *InvalidTokenMonitor) {
+	prs.mu.Lock()
+	defer prs.mu.Unlock()
+	prs.invalid = monitor
+}
+
+// SetReplayMetricsHook configures the hook receiving the tokens presented after
+// their revocation or expiration (attempted replays): revoked tokens still in the
+// revocation feed (see RetentionPolicy), and expired tokens when they carry an HMAC
+// checksum (TokenChecksumSecret). A nil hook disables the reporting.
+func (prs *PasswordResetService) SetReplayMetricsHook(hook lib.ReplayMetricsHook) {
+	prs.mu.Lock()
+	defer prs.mu.Unlock()
+	prs.replays = hook
+}
+
+func (prs *PasswordResetService) replayMetricsHook() lib.ReplayMetricsHook {
+	prs.mu.RLock()
+	defer prs.mu.RUnlock()
+	return prs.replays
+}
+
+func (prs *PasswordResetService) riskEvaluator() RiskEvaluator {
+	prs.mu.RLock()
+	defer prs.mu.RUnlock()
+	return prs.risk
+}
+
+func (prs *PasswordResetService) auditLogger() lib.AuditLogger {
+	prs.mu.RLock()
+	defer prs.mu.RUnlock()
+	return prs.audit
+}
+
+func (prs *PasswordResetService) invalidTokenMonitor() *InvalidTokenMonitor {
+	prs.mu.RLock()
+	defer prs.mu.RUnlock()
+	return prs.invalid
+}
+
 // CreatePasswordResetToken generates a new password reset token for the specified user.
 // Creating a new token automatically invalidates any previous token for the user.
 // The token is a 32-character cryptographically secure random string.
@@ -90,49 +285,273 @@ func NewPasswordResetService(ctx context.Context, db *redis.Client, config *lib.
 //   - Automatically expires via Redis TTL
 //   - Replaces any existing reset token for the user (single-token enforcement)
 //
+// Resend handling (Config.PasswordResetPolicy), when an unexpired token with the same scope exists:
+//   - replace (default): a new token is issued, the previous link stops working
+//   - reuse: the existing token is returned with its remaining lifetime
+//   - extend: the existing token is returned with a fresh TTL
+//
+// Quota (Config.PasswordResetQuota): at most that many new tokens are issued per user
+// within PasswordResetTTL of the first one, so that repeated "forgot password" requests
+// cannot flood a mailbox. Further requests fail with a *PasswordResetQuotaError
+// (errors.Is ErrPasswordResetQuotaExceeded) telling when to retry. Reused and extended
+// tokens do not count.
+//
 // Parameters:
 //   - ctx: Context for the operation (uses Background if nil)
 //   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
 //
 // Returns:
-//   - *string: Pointer to the generated reset token (32 characters)
-//   - error: Validation or storage errors
+//   - *string: Pointer to the generated reset token (32 characters by default)
+//   - error: Validation, quota or storage errors
 //
 // Example:
 //
 //	token, err := resetService.CreatePasswordResetToken(ctx, "550e8400-e29b-41d4-a716-446655440000")
+//	if errors.Is(err, service.ErrPasswordResetQuotaExceeded) {
+//	    return nil // Answer as if the email was sent, without sending it
+//	}
 //	if err != nil {
 //	    return err
 //	}
 //	// Send token via email: "Reset link: /reset?token=abc123..."
 //	sendResetEmail(userEmail, *token)
 func (prs *PasswordResetService) CreatePasswordResetToken(ctx context.Context, userID string) (*string, error) {
+	return prs.CreateScopedPasswordResetToken(ctx, userID, PasswordResetScopeSelfService)
+}
+
+// CreateScopedPasswordResetToken generates a new password reset token recording why
+// the reset was issued. The scope is returned by VerifyScopedPasswordResetToken and
+// reported in the "password_reset.created" audit event.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - scope: Why the reset is issued (SELF_SERVICE, ADMIN_FORCED or BREACH_RESET)
+//
+// Returns:
+//   - *string: Pointer to the generated reset token (32 characters by default)
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	// Credentials found in a breach corpus, force a reset
+//	token, err := resetService.CreateScopedPasswordResetToken(ctx, userID, service.PasswordResetScopeBreachReset)
+func (prs *PasswordResetService) CreateScopedPasswordResetToken(ctx context.Context, userID string, scope PasswordResetScope) (*string, error) {
+	token, _, err := prs.create(ctx, userID, scope, false)
+	return token, err
+}
+
+// create issues a reset token, with a short code when withCode is set. Short codes are
+// only stored hashed: an existing reset is never reused for them.
+func (prs *PasswordResetService) create(ctx context.Context, userID string, scope PasswordResetScope, withCode bool) (*string, string, error) {
 	if userID == "" {
-		return nil, errors.New("invalid user id")
+		return nil, "", ErrInvalidUserID
+	}
+	if !scope.IsValid() {
+		return nil, "", errors.New("invalid password reset scope")
+	}
+	if withCode && prs.store != nil {
+		return nil, "", fmt.Errorf("password reset code: %w", ErrTokenStoreUnsupported)
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	if err := evaluateRisk(ctx, prs.riskEvaluator(), RiskOperationPasswordResetCreate, userID); err != nil {
+		return nil, "", err
+	}
+
 	// Parse duration from configuration
 	duration, err := time.ParseDuration(*prs.config.PasswordResetTTL)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Create a random token
-	token, err := lib.GenerateRandomString(passwordResetTokenMaxLength)
+	key := fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID)
+
+	previous, err := prs.storedRecord(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if previous != nil && previous.Scope == scope && !withCode {
+		if token, err := prs.reuseToken(ctx, key, userID, previous, duration); err != nil || token != nil {
+			return token, "", err
+		}
+	}
+
+	quota := prs.quota(duration)
+	if err := prs.checkQuota(ctx, quota, userID); err != nil {
+		return nil, "", err
+	}
+
+	// Create a random token, case-insensitive when tokens are case folded
+	generate := lib.GenerateRandomString
+	if prs.config.TokenNormalization.FoldCase {
+		generate = lib.GenerateBase32String
+	}
+	token, err := generateToken(prs.config, lib.TokenTypePasswordReset, prs.length.generated, generate)
+	if err != nil {
+		return nil, "", err
+	}
+
+	record := passwordResetRecord{Token: token, Scope: scope}
+	var code string
+	if withCode {
+		if code, err = lib.GenerateUserCode(); err != nil {
+			return nil, "", err
+		}
+		record.CodeHash = hashToken(normalizeUserCode(code))
+	}
+
+	value, err := record.encode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if prs.store != nil {
+		err = prs.replaceStoredToken(ctx, userID, previous, token, value, duration)
+	} else {
+		err = prs.replaceToken(ctx, key, userID, previous, token, value, duration, quota, withCode)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	emitAudit(ctx, prs.auditLogger(), AuditEventPasswordResetCreated, userID, map[string]string{
+		"scope":             string(scope),
+		"token_hash":        hashToken(token),
+		"token_fingerprint": lib.TokenFingerprint(token),
+		"expires_at":        time.Now().Add(duration).UTC().Format(time.RFC3339),
+	})
+
+	return &token, code, nil
+}
+
+// replaceToken stores a new token of a user at key with its lookup entry, dropping
+// the lookup of the replaced token, and counts it towards the quota.
+func (prs *PasswordResetService) replaceToken(ctx context.Context, key string, userID string, previous *passwordResetRecord, token string, value string, duration time.Duration, quota *attemptCounter, withCode bool) error {
+	_, err := prs.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, duration)
+		pipe.Set(ctx, prs.lookupKey(token), userID, duration)
+		if previous != nil {
+			pipe.Del(ctx, prs.lookupKey(previous.Token))
+		}
+		if quota != nil {
+			quota.incrementIn(ctx, pipe, userID)
+		}
+		if withCode {
+			pipe.Del(ctx, prs.codeAttempts(duration).key(userID))
+		}
+		return nil
+	})
+	return err
+}
+
+// replaceStoredToken puts a new token of a user in the token store, then deletes the
+// replaced one. Should the deletion fail, storedRecord keeps only the newest token current.
+func (prs *PasswordResetService) replaceStoredToken(ctx context.Context, userID string, previous *passwordResetRecord, token string, value string, duration time.Duration) error {
+	if err := prs.store.Put(ctx, StoredToken{UserID: userID, Hash: hashToken(token), Value: value, ExpiresAt: time.Now().Add(duration)}); err != nil {
+		return err
+	}
+	if previous == nil || previous.Token == token {
+		return nil
+	}
+	_, err := prs.store.Delete(ctx, userID, hashToken(previous.Token))
+	return err
+}
+
+// quota returns the counter of the tokens issued per user, nil without quota.
+func (prs *PasswordResetService) quota(window time.Duration) *attemptCounter {
+	if prs.config.PasswordResetQuota <= 0 {
+		return nil
+	}
+	return newAttemptCounter(prs.db, prs.keys.name(redisStoreNamePasswordResetQuota), window)
+}
+
+// checkQuota returns a *PasswordResetQuotaError once the user was issued the quota.
+// The check is not atomic with the issuance: concurrent requests may exceed it slightly.
+func (prs *PasswordResetService) checkQuota(ctx context.Context, quota *attemptCounter, userID string) error {
+	if quota == nil {
+		return nil
+	}
+
+	state, err := quota.state(ctx, userID, prs.config.PasswordResetQuota)
+	if err != nil {
+		return err
+	}
+	if !state.Exhausted() {
+		return nil
+	}
+
+	emitAudit(ctx, prs.auditLogger(), AuditEventPasswordResetQuotaExceeded, userID, map[string]string{
+		"limit": strconv.Itoa(state.Limit),
+	})
+	return &PasswordResetQuotaError{Limit: state.Limit, RetryAfter: state.Reset}
+}
+
+// storedRecord returns the unexpired token record of a user, nil if none.
+func (prs *PasswordResetService) storedRecord(ctx context.Context, userID string) (*passwordResetRecord, error) {
+	if prs.store != nil {
+		stored, err := prs.storedToken(ctx, userID)
+		if err != nil || stored == nil {
+			return nil, err
+		}
+		record, err := decodePasswordResetRecord(stored.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &record, nil
 	}
 
-	// Add the token to Redis
-	if err := prs.db.Set(ctx, fmt.Sprintf("%s:%s", redisStoreNamePasswordReset, userID), token, duration).Err(); err != nil {
+	val, err := prs.db.Get(ctx, fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
+	record, err := decodePasswordResetRecord(val)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// reuseToken returns the token of the existing record when the resend policy
+// allows reusing it, nil when a new token must be issued.
+func (prs *PasswordResetService) reuseToken(ctx context.Context, key string, userID string, record *passwordResetRecord, duration time.Duration) (*string, error) {
+	switch prs.config.PasswordResetPolicy {
+	case lib.PasswordResetPolicyReuse:
+		return &record.Token, nil
+	case lib.PasswordResetPolicyExtend:
+		if prs.store != nil {
+			value, err := record.encode()
+			if err != nil {
+				return nil, err
+			}
+			stored := StoredToken{UserID: userID, Hash: hashToken(record.Token), Value: value, ExpiresAt: time.Now().Add(duration)}
+			if err := prs.store.Put(ctx, stored); err != nil {
+				return nil, err
+			}
+			return &record.Token, nil
+		}
 
-	return &token, nil
+		extended, err := prs.db.Expire(ctx, key, duration).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !extended {
+			return nil, nil // Expired in the meantime
+		}
+		// Lookup entries of tokens created by earlier versions do not exist, nothing to extend
+		if err := prs.db.Expire(ctx, prs.lookupKey(record.Token), duration).Err(); err != nil {
+			return nil, err
+		}
+		return &record.Token, nil
+	default:
+		return nil, nil
+	}
 }
 
 // VerifyPasswordResetToken checks if the provided reset token is valid for the user.
@@ -147,11 +566,12 @@ func (prs *PasswordResetService) CreatePasswordResetToken(ctx context.Context, u
 // Parameters:
 //   - ctx: Context for the operation (uses Background if nil)
 //   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
-//   - token: The reset token to verify (32 characters)
+//   - token: The reset token to verify (32 characters by default)
 //
 // Returns:
 //   - bool: true if token is valid and matches stored token, false otherwise
-//   - error: Validation errors or Redis connection errors
+//   - error: Validation errors, Redis connection errors, or risk evaluation
+//     errors (ErrStepUpRequired, ErrRiskDenied) for otherwise valid tokens
 //
 // Example:
 //
@@ -164,26 +584,157 @@ func (prs *PasswordResetService) CreatePasswordResetToken(ctx context.Context, u
 //	}
 //	// Token valid - allow user to set new password
 func (prs *PasswordResetService) VerifyPasswordResetToken(ctx context.Context, userID string, token string) (bool, error) {
+	_, valid, err := prs.VerifyScopedPasswordResetToken(ctx, userID, token)
+	return valid, err
+}
+
+// VerifyScopedPasswordResetToken checks the reset token like VerifyPasswordResetToken
+// and returns the scope it was created with, so the application can adapt the reset
+// form (e.g. explain that an administrator or a breach forced the reset).
+// A "password_reset.verified" audit event carrying the scope is emitted for valid tokens.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: The reset token to verify (32 characters by default)
+//
+// Returns:
+//   - PasswordResetScope: The token scope, empty if the token is not valid
+//   - bool: true if token is valid and matches stored token, false otherwise
+//   - error: Same errors as VerifyPasswordResetToken
+//
+// Example:
+//
+//	scope, valid, err := resetService.VerifyScopedPasswordResetToken(ctx, userID, tokenFromURL)
+//	if err != nil || !valid {
+//	    return errors.New("invalid or expired reset token")
+//	}
+//	if scope == service.PasswordResetScopeBreachReset {
+//	    showBreachNotice(w)
+//	}
+func (prs *PasswordResetService) VerifyScopedPasswordResetToken(ctx context.Context, userID string, token string) (PasswordResetScope, bool, error) {
+	scope, valid, err := prs.verifyScoped(ctx, userID, token)
+	prs.invalidTokenMonitor().observe(ctx, lib.TokenTypePasswordReset, valid, err)
+	observeReplay(ctx, prs.replayMetricsHook(), prs.config, prs.revocations(), prs.normalize(token), valid, err)
+	return scope, valid, err
+}
+
+func (prs *PasswordResetService) verifyScoped(ctx context.Context, userID string, token string) (PasswordResetScope, bool, error) {
 	if userID == "" {
-		return false, errors.New("invalid user id")
+		return "", false, ErrInvalidUserID
+	}
+
+	token = prs.normalize(token)
+
+	if err := validation.IsIncomingTokenValid(token, prs.length.max+tokenOverhead(prs.config, lib.TokenTypePasswordReset)); err != nil {
+		return "", false, err
+	}
+	if !hasValidChecksum(prs.config, token) {
+		return "", false, nil // Malformed token - no need to query Redis
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	if err := validation.IsIncomingTokenValid(token, passwordResetTokenMaxLength); err != nil {
-		return false, err
+	record, err := prs.storedRecord(ctx, userID)
+	if err != nil || record == nil {
+		return "", false, err // Token doesn't exist or expired - not an error
+	}
+	if record.Token != token {
+		return "", false, nil
+	}
+
+	if err := evaluateRisk(ctx, prs.riskEvaluator(), RiskOperationPasswordResetVerify, userID); err != nil {
+		return "", false, err
+	}
+
+	emitAudit(ctx, prs.auditLogger(), AuditEventPasswordResetVerified, userID, map[string]string{"scope": string(record.Scope)})
+
+	return record.Scope, true, nil
+}
+
+// IdentifyPasswordResetToken verifies a reset token without knowing its owner and
+// returns the user it was issued for, its scope and expiration. The reset form can
+// then greet the user and check that the token belongs to the account being reset.
+// Tokens created by earlier versions have no lookup entry and are not identified.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The reset token to verify (32 characters by default)
+//
+// Returns:
+//   - *PasswordResetTokenInfo: Owner, scope and expiration, nil if the token is not valid
+//   - error: Same errors as VerifyPasswordResetToken
+//
+// Example:
+//
+//	info, err := resetService.IdentifyPasswordResetToken(ctx, r.URL.Query().Get("token"))
+//	if err != nil {
+//	    return err
+//	}
+//	if info == nil || info.UserID != userIDFromEmailLink {
+//	    return errors.New("invalid or expired reset token")
+//	}
+//	renderResetForm(w, info.UserID, info.ExpiresAt)
+func (prs *PasswordResetService) IdentifyPasswordResetToken(ctx context.Context, token string) (*PasswordResetTokenInfo, error) {
+	info, err := prs.identify(ctx, token)
+	prs.invalidTokenMonitor().observe(ctx, lib.TokenTypePasswordReset, info != nil, err)
+	observeReplay(ctx, prs.replayMetricsHook(), prs.config, prs.revocations(), prs.normalize(token), info != nil, err)
+	return info, err
+}
+
+func (prs *PasswordResetService) identify(ctx context.Context, token string) (*PasswordResetTokenInfo, error) {
+	token = prs.normalize(token)
+	if err := validation.IsIncomingTokenValid(token, prs.length.max+tokenOverhead(prs.config, lib.TokenTypePasswordReset)); err != nil {
+		return nil, err
+	}
+	if !hasValidChecksum(prs.config, token) {
+		return nil, nil // Malformed token - no need to query Redis
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	val, err := prs.db.Get(ctx, fmt.Sprintf("%s:%s", redisStoreNamePasswordReset, userID)).Result()
+	if prs.store != nil {
+		stored, found, err := prs.store.Find(ctx, hashToken(token))
+		if err != nil || !found {
+			return nil, err
+		}
+		scope, valid, err := prs.verifyScoped(ctx, stored.UserID, token)
+		if err != nil || !valid {
+			return nil, err
+		}
+		return &PasswordResetTokenInfo{UserID: stored.UserID, Scope: scope, ExpiresAt: stored.ExpiresAt}, nil
+	}
+
+	userID, err := prs.db.Get(ctx, prs.lookupKey(token)).Result()
 	if errors.Is(err, redis.Nil) {
-		return false, nil // Token doesn't exist or expired - not an error
+		return nil, nil // Token doesn't exist or expired - not an error
 	}
 	if err != nil {
-		return false, err // Real Redis error
+		return nil, err
 	}
-	return val == token, nil
+
+	scope, valid, err := prs.verifyScoped(ctx, userID, token)
+	if err != nil || !valid {
+		return nil, err
+	}
+
+	ttl, err := prs.db.PTTL(ctx, fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if ttl < 0 {
+		return nil, nil // Expired in the meantime
+	}
+
+	return &PasswordResetTokenInfo{
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
 }
 
 // RevokePasswordResetToken immediately invalidates a password reset token.
@@ -214,11 +765,37 @@ func (prs *PasswordResetService) VerifyPasswordResetToken(ctx context.Context, u
 //	    log.Printf("Failed to revoke reset token: %v", err)
 //	}
 func (prs *PasswordResetService) RevokePasswordResetToken(ctx context.Context, userID string, token string) error {
+	return prs.RevokePasswordResetTokenWithReason(ctx, userID, token, RevocationReasonUserLogout)
+}
+
+// RevokePasswordResetTokenWithReason invalidates a password reset token like RevokePasswordResetToken,
+// recording why it was revoked. The reason is kept in the user revocation log
+// (see ListRevokedPasswordResetTokens) and sent in a "password_reset.revoked" audit event.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: The reset token to revoke (must match stored token)
+//   - reason: Why the token is revoked (USER_LOGOUT, ADMIN, ROTATION, SUSPICIOUS or LEAKED)
+//
+// Returns:
+//   - error: Validation errors, token mismatch, or storage errors
+//
+// Example:
+//
+//	// Support cancels a reset requested from a compromised mailbox
+//	err := resetService.RevokePasswordResetTokenWithReason(ctx, userID, token, service.RevocationReasonAdmin)
+func (prs *PasswordResetService) RevokePasswordResetTokenWithReason(ctx context.Context, userID string, token string, reason RevocationReason) error {
 	if userID == "" {
-		return errors.New("invalid user id")
+		return ErrInvalidUserID
+	}
+	if !reason.IsValid() {
+		return errors.New("invalid revocation reason")
 	}
 
-	if err := validation.IsIncomingTokenValid(token, passwordResetTokenMaxLength); err != nil {
+	token = prs.normalize(token)
+
+	if err := validation.IsIncomingTokenValid(token, prs.length.max+tokenOverhead(prs.config, lib.TokenTypePasswordReset)); err != nil {
 		return err
 	}
 
@@ -227,22 +804,67 @@ func (prs *PasswordResetService) RevokePasswordResetToken(ctx context.Context, u
 	}
 
 	// Get the stored token to verify it matches before revoking
-	key := fmt.Sprintf("%s:%s", redisStoreNamePasswordReset, userID)
-	storedToken, err := prs.db.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		return errors.New("token not found or already revoked")
-	}
+	record, err := prs.storedRecord(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if record == nil {
+		return errPasswordResetTokenNotFound
+	}
 
 	// Verify the token matches
-	if storedToken != token {
-		return errors.New("token mismatch")
+	if record.Token != token {
+		return errPasswordResetTokenMismatch
 	}
 
-	// Delete the token
-	return prs.db.Del(ctx, key).Err()
+	// Delete the token and its lookup entry
+	if prs.store != nil {
+		_, err = prs.store.Delete(ctx, userID, hashToken(token))
+	} else {
+		err = prs.db.Del(ctx, fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID), prs.lookupKey(token)).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := prs.revocations().record(ctx, userID, token, reason); err != nil {
+		return err
+	}
+	emitAudit(ctx, prs.auditLogger(), AuditEventPasswordResetRevoked, userID, map[string]string{
+		"reason":            string(reason),
+		"scope":             string(record.Scope),
+		"token_hash":        hashToken(token),
+		"token_fingerprint": lib.TokenFingerprint(token),
+	})
+	return nil
+}
+
+// ListRevokedPasswordResetTokens returns the password reset tokens revoked with
+// RevokePasswordResetTokenWithReason for a user, most recent first.
+// The log keeps the last 100 revocations for the AnonymizeAfter duration of the
+// retention policy (30 days by default).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - []RevocationRecord: Token hashes, reasons and revocation times
+//   - error: Validation or storage errors
+func (prs *PasswordResetService) ListRevokedPasswordResetTokens(ctx context.Context, userID string) ([]RevocationRecord, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return prs.revocations().list(ctx, userID)
+}
+
+func (prs *PasswordResetService) revocations() revocationLog {
+	return revocationLog{db: prs.db, keys: prs.keys, tokenType: lib.TokenTypePasswordReset, retention: prs.retention}
 }
 
 // RevokeAllPasswordResetTokens revokes all password reset tokens for all users.
@@ -270,17 +892,67 @@ func (prs *PasswordResetService) RevokePasswordResetToken(ctx context.Context, u
 //	}
 //	log.Println("All password reset requests invalidated")
 func (prs *PasswordResetService) RevokeAllPasswordResetTokens(ctx context.Context) error {
+	_, err := prs.RevokeAllPasswordResetTokensInBatches(ctx, CleanupOptions{})
+	return err
+}
+
+// RevokeAllPasswordResetTokensInBatches revokes all password reset tokens like
+// RevokeAllPasswordResetTokens, deleting them in paced batches for large deployments.
+// Their lookup entries are deleted afterwards, with the same pacing.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops between batches
+//   - opts: Batch size, pause between batches and progress callback (reporting revoked tokens)
+//
+// Returns:
+//   - int64: Number of revoked tokens
+//   - error: Storage errors or context cancellation
+func (prs *PasswordResetService) RevokeAllPasswordResetTokensInBatches(ctx context.Context, opts CleanupOptions) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	keys := prs.db.Scan(ctx, 0, fmt.Sprintf("%s:*", redisStoreNamePasswordReset), 0).Iterator()
-	for keys.Next(ctx) {
-		key := keys.Val()
-		if err := prs.db.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("failed to delete key %s : %w", key, err)
-		}
+	if prs.store != nil {
+		return deleteStoredTokens(ctx, prs.store, opts)
 	}
 
-	return keys.Err()
+	revoked, err := deleteMatching(ctx, prs.db, fmt.Sprintf("%s:*", prs.keys.name(redisStoreNamePasswordReset)), opts)
+	if err != nil {
+		return revoked, err
+	}
+
+	lookupOpts := opts
+	lookupOpts.Progress = nil
+	lookupOpts.Report = nil
+	_, err = deleteMatching(ctx, prs.db, fmt.Sprintf("%s:*", prs.keys.name(redisStoreNamePasswordResetLookup)), lookupOpts)
+	return revoked, err
+}
+
+// storedToken returns the current token of a user in the token store, nil if none. A
+// replacement interrupted before deleting the previous token leaves several: the one
+// expiring last was stored last and is the current one.
+func (prs *PasswordResetService) storedToken(ctx context.Context, userID string) (*StoredToken, error) {
+	tokens, err := prs.store.List(ctx, userID)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	current := slices.MaxFunc(tokens, func(a, b StoredToken) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return &current, nil
+}
+
+// normalize applies the configured token normalization, including case folding
+// (reset tokens are generated uppercase when it is enabled).
+func (prs *PasswordResetService) normalize(token string) string {
+	token = prs.config.TokenNormalization.Normalize(token)
+	if prs.config.TokenNormalization.FoldCase {
+		token = foldTokenCase(lib.TokenTypePasswordReset, token)
+	}
+	return token
+}
+
+// lookupKey returns the lookup key of a token. The token is hashed
+// so that the key space does not expose usable tokens.
+func (prs *PasswordResetService) lookupKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordResetLookup), hex.EncodeToString(sum[:]))
 }
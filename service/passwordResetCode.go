@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// redisStoreNamePasswordResetCodeAttempts is the Redis key prefix for the failed short
+// code attempts. Key pattern: "password_reset_code_attempts:{userID}" with the number
+// of failed attempts, expiring PasswordResetTTL after the first one.
+const redisStoreNamePasswordResetCodeAttempts string = "password_reset_code_attempts"
+
+// passwordResetCodeLength is the length of the short codes, separators excluded.
+const passwordResetCodeLength int = 8
+
+// PasswordResetCredentials holds the two forms of a password reset.
+//
+// Fields:
+//   - Token: The reset token, for the link of the email
+//   - Code: A short code such as "WDJB-MJHT", typed by the user on another device
+type PasswordResetCredentials struct {
+	Token string
+	Code  string
+}
+
+// CreatePasswordResetTokenWithCode issues a self-service reset token together with an
+// 8-character code for the same reset, so that a user reading the email on another
+// device can type the code instead of opening the link. Both expire with the reset, and
+// revoking or replacing the token invalidates the code.
+//
+// The code is stored hashed, so an existing reset is never reused (see
+// Config.PasswordResetPolicy): a new token and code are issued on each call, counting
+// towards Config.PasswordResetQuota. Services on a token store do not support short
+// codes (ErrTokenStoreUnsupported).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - *PasswordResetCredentials: The token and the code
+//   - error: Same errors as CreatePasswordResetToken
+//
+// Example:
+//
+//	credentials, err := resetService.CreatePasswordResetTokenWithCode(ctx, user.ID)
+//	if err != nil {
+//	    return err
+//	}
+//	sendResetEmail(user.Email, link(credentials.Token), credentials.Code)
+func (prs *PasswordResetService) CreatePasswordResetTokenWithCode(ctx context.Context, userID string) (*PasswordResetCredentials, error) {
+	token, code, err := prs.create(ctx, userID, PasswordResetScopeSelfService, true)
+	if err != nil {
+		return nil, err
+	}
+	return &PasswordResetCredentials{Token: *token, Code: code}, nil
+}
+
+// VerifyPasswordResetCode checks a short code typed by the user and returns the token
+// of the reset, to continue as with a link (e.g. RevokePasswordResetToken once the
+// password is changed). Codes are case-insensitive, dashes and spaces are ignored.
+//
+// Short codes are far easier to guess than tokens: failed attempts are counted per user,
+// separately from the tokens, and after 5 within PasswordResetTTL the code is refused
+// with ErrMaxAttemptsExceeded. The link keeps working.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - code: The code typed by the user
+//
+// Returns:
+//   - *string: The reset token, nil if the code is not valid
+//   - error: ErrInvalidUserID, ErrMaxAttemptsExceeded, risk evaluation or storage errors
+//
+// Example:
+//
+//	token, err := resetService.VerifyPasswordResetCode(ctx, user.ID, form.Code)
+//	if errors.Is(err, service.ErrMaxAttemptsExceeded) {
+//	    return errors.New("too many attempts, use the link of the email")
+//	}
+//	if err != nil || token == nil {
+//	    return errors.New("invalid or expired code")
+//	}
+func (prs *PasswordResetService) VerifyPasswordResetCode(ctx context.Context, userID string, code string) (*string, error) {
+	token, err := prs.verifyCode(ctx, userID, code)
+	prs.invalidTokenMonitor().observe(ctx, lib.TokenTypePasswordReset, token != nil, err)
+	return token, err
+}
+
+func (prs *PasswordResetService) verifyCode(ctx context.Context, userID string, code string) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	if prs.store != nil {
+		return nil, fmt.Errorf("password reset code: %w", ErrTokenStoreUnsupported)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration, err := time.ParseDuration(*prs.config.PasswordResetTTL)
+	if err != nil {
+		return nil, err
+	}
+	attempts := prs.codeAttempts(duration)
+	failed, err := attempts.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if failed >= maxAttempts {
+		return nil, ErrMaxAttemptsExceeded
+	}
+
+	record, err := prs.storedRecord(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	code = normalizeUserCode(code)
+	if record == nil || record.CodeHash == "" || len(code) != passwordResetCodeLength ||
+		subtle.ConstantTimeCompare([]byte(record.CodeHash), []byte(hashToken(code))) != 1 {
+		if _, err := attempts.increment(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if err := evaluateRisk(ctx, prs.riskEvaluator(), RiskOperationPasswordResetVerify, userID); err != nil {
+		return nil, err
+	}
+	if err := attempts.revoke(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	emitAudit(ctx, prs.auditLogger(), AuditEventPasswordResetVerified, userID, map[string]string{
+		"scope":  string(record.Scope),
+		"method": "code",
+	})
+	return &record.Token, nil
+}
+
+// codeAttempts returns the counter of the failed short code attempts.
+func (prs *PasswordResetService) codeAttempts(window time.Duration) *attemptCounter {
+	return newAttemptCounter(prs.db, prs.keys.name(redisStoreNamePasswordResetCodeAttempts), window)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PasswordResetScope tells why a password reset token was issued, so the
+// consuming application can branch behavior (e.g. skip "current password"
+// for admin-forced resets) and reporting.
+type PasswordResetScope string
+
+const (
+	// PasswordResetScopeSelfService is a reset requested by the user ("forgot password").
+	PasswordResetScopeSelfService PasswordResetScope = "SELF_SERVICE"
+	// PasswordResetScopeAdminForced is a reset forced by an administrator.
+	PasswordResetScopeAdminForced PasswordResetScope = "ADMIN_FORCED"
+	// PasswordResetScopeBreachReset is a reset required after a credential breach.
+	PasswordResetScopeBreachReset PasswordResetScope = "BREACH_RESET"
+)
+
+// IsValid reports whether the scope is one of the known scopes.
+func (s PasswordResetScope) IsValid() bool {
+	switch s {
+	case PasswordResetScopeSelfService, PasswordResetScopeAdminForced, PasswordResetScopeBreachReset:
+		return true
+	default:
+		return false
+	}
+}
+
+// passwordResetRecord holds a password reset token and its attributes.
+//
+// JSON serialization:
+//   - Example: {"token": "aB3-...", "scope": "ADMIN_FORCED"}
+//   - With a short code: {"token": "aB3-...", "scope": "SELF_SERVICE", "code_hash": "9f86d0..."}
+type passwordResetRecord struct {
+	Token    string             `json:"token"`
+	Scope    PasswordResetScope `json:"scope"`
+	CodeHash string             `json:"code_hash,omitempty"`
+}
+
+// encode returns the Redis value of the record: the bare token for self-service
+// resets without short code, its JSON form otherwise.
+func (r passwordResetRecord) encode() (string, error) {
+	if r.Scope == PasswordResetScopeSelfService && r.CodeHash == "" {
+		return r.Token, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodePasswordResetRecord parses a Redis value written by encode.
+// Bare tokens, including those created by earlier versions, are self-service resets.
+func decodePasswordResetRecord(value string) (passwordResetRecord, error) {
+	// Tokens never contain '{' (see lib.GenerateRandomString)
+	if !strings.HasPrefix(value, "{") {
+		return passwordResetRecord{Token: value, Scope: PasswordResetScopeSelfService}, nil
+	}
+	var record passwordResetRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return passwordResetRecord{}, fmt.Errorf("corrupted password reset record: %w", err)
+	}
+	return record, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStoreNamePreflight is the Redis key prefix of the VerifyRedisSetup probes.
+// Key pattern: "preflight:{random}", deleted at the end of the check.
+const redisStoreNamePreflight string = "preflight"
+
+// preflightScript reads its key, like the compare-and-delete scripts of the services.
+var preflightScript = redis.NewScript(`return redis.call("GET", KEYS[1])`)
+
+// VerifyRedisSetup checks at startup that the Redis server and user can run the services,
+// so that a missing permission fails the deployment instead of the first login.
+// The services never create or alter anything at runtime beyond their own keys: no
+// setup step is needed, and they run under the restricted user of RedisACLSetUser.
+//
+// Checks, on a probe key under the WithKeyPrefix namespace:
+//   - Connection and authentication (PING)
+//   - Redis 6.2 or later (GETDEL, SET with GET)
+//   - Key commands: SET with TTL, GET, PTTL, SCAN, DEL
+//   - Transactions (MULTI/EXEC), Lua scripts (EVALSHA/EVAL) and Pub/Sub (PUBLISH)
+//
+// Parameters:
+//   - ctx: Context for the checks (uses Background if nil)
+//   - db: Redis client given to the services
+//   - opts: The options given to the services (WithKeyPrefix)
+//
+// Returns:
+//   - error: Joined errors of the failed checks, each naming the missing command or permission
+//
+// Example:
+//
+//	if err := service.VerifyRedisSetup(ctx, redisClient, service.WithKeyPrefix("myapp")); err != nil {
+//	    log.Fatalf("Redis is not ready for the token services: %v", err)
+//	}
+func VerifyRedisSetup(ctx context.Context, db *redis.Client, opts ...Option) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := db.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis is unreachable or rejected the credentials: %w", err)
+	}
+
+	prefix := newServiceOptions(opts).keyPrefix
+	id, err := lib.GenerateRandomString(16)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s:%s", prefix.name(redisStoreNamePreflight), id)
+	defer db.Del(context.WithoutCancel(ctx), key)
+
+	checks := []struct {
+		command string
+		run     func() error
+	}{
+		{"SET", func() error { return db.Set(ctx, key, "1", time.Minute).Err() }},
+		{"GET", func() error { return db.Get(ctx, key).Err() }},
+		{"PTTL", func() error { return db.PTTL(ctx, key).Err() }},
+		{"SET GET", func() error {
+			return ignoreNil(db.SetArgs(ctx, key, "1", redis.SetArgs{TTL: time.Minute, Get: true}).Err())
+		}},
+		{"GETDEL", func() error { return ignoreNil(db.GetDel(ctx, key).Err()) }},
+		{"SCAN", func() error {
+			return db.Scan(ctx, 0, escapeScanPattern(prefix.name(redisStoreNamePreflight)+":")+"*", 10).Err()
+		}},
+		{"MULTI/EXEC", func() error {
+			_, err := db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, "1", time.Minute)
+				pipe.Expire(ctx, key, time.Minute)
+				return nil
+			})
+			return err
+		}},
+		{"EVALSHA/EVAL", func() error { return ignoreNil(preflightScript.Run(ctx, db, []string{key}).Err()) }},
+		{"PUBLISH", func() error { return db.Publish(ctx, prefix.name(redisStoreNamePreflight), "1").Err() }},
+		{"DEL", func() error { return db.Del(ctx, key).Err() }},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if err := check.run(); err != nil {
+			errs = append(errs, preflightError(check.command, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// preflightError explains a failed check.
+func preflightError(command string, key string, err error) error {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "NOPERM"):
+		return fmt.Errorf("redis user may not run %s on %q, grant the rules of RedisACLSetUser: %w", command, key, err)
+	case strings.Contains(strings.ToLower(message), "unknown command"), strings.Contains(message, "syntax error"):
+		return fmt.Errorf("redis server does not support %s, Redis 6.2 or later is required: %w", command, err)
+	default:
+		return fmt.Errorf("redis %s failed: %w", command, err)
+	}
+}
+
+// ignoreNil maps redis.Nil (missing key) to success.
+func ignoreNil(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitState is the rate limiting state of a user for one kind of attempt,
+// e.g. to fill the RateLimit-* HTTP headers (see middleware.SetRateLimitHeaders).
+//
+// Fields:
+//   - Limit: Attempts allowed within the window
+//   - Remaining: Attempts left before being blocked
+//   - Reset: Time until the window (or the lock) expires, 0 when nothing was recorded
+type RateLimitState struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// Exhausted reports whether no attempt is left.
+func (s RateLimitState) Exhausted() bool {
+	return s.Remaining <= 0
+}
+
+// state returns the rate limiting state of the user for limit attempts per window.
+func (ac *attemptCounter) state(ctx context.Context, userID string, limit int) (RateLimitState, error) {
+	key := ac.key(userID)
+	pipe := ac.db.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return RateLimitState{}, err
+	}
+
+	attempts, err := get.Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return RateLimitState{}, err
+	}
+	return RateLimitState{
+		Limit:     limit,
+		Remaining: max(limit-attempts, 0),
+		// Negative when the key doesn't exist (-2) or has no TTL (-1)
+		Reset: max(pttl.Val(), 0),
+	}, nil
+}
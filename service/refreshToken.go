@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"errors"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
@@ -14,14 +16,29 @@ import (
 )
 
 const (
-	// refreshTokenMaxLength defines the maximum character length for refresh tokens.
-	// Tokens are 255-character cryptographically secure random strings.
+	// refreshTokenMaxLength defines the default character length for refresh tokens.
+	// Tokens are 255-character cryptographically secure random strings unless
+	// Config.RefreshTokenLength is set.
 	refreshTokenMaxLength int = 255
 
+	// refreshTokenMinLength is the shortest configurable refresh token length.
+	refreshTokenMinLength int = 32
+
 	// redisStoreNameRefreshToken is the Redis key prefix for refresh token storage.
-	// Key pattern: "refresh:{userID}:{token}" with value "1" (existence check).
+	// Key pattern: "refresh:{userID}:{token}" with value "1" (existence check),
+	// or a JSON record for certificate-bound tokens.
 	// Multiple tokens per user are supported (multi-device sessions).
 	redisStoreNameRefreshToken string = "refresh"
+
+	// redisStoreNameRefreshTokenMeta is the Redis key prefix for last-use metadata.
+	// Key pattern: "refresh_meta:{userID}" holding a hash (country, ip, last_used),
+	// written on each successful verification when a geo policy is configured.
+	redisStoreNameRefreshTokenMeta string = "refresh_meta"
+
+	// redisStoreNameRefreshTokenLookup is the Redis key prefix for token to user lookups.
+	// Key pattern: "refresh_lookup:{sha256(token)}" with the user ID as value,
+	// expiring with the token (see LeakedTokenResponder).
+	redisStoreNameRefreshTokenLookup string = "refresh_lookup"
 )
 
 // RefreshTokenService manages long-lived refresh tokens with Redis persistence.
@@ -34,8 +51,10 @@ const (
 //   - User-scoped revocation (logout single device) and global revocation (logout all)
 //
 // Redis key pattern:
-//   - Key: "refresh:{userID}:{token}"
-//   - Value: "1" (existence indicates validity)
+//   - Key: "refresh:{userID}:{token}", or "refresh:{userID}:sha256.{hex}" with hashed
+//     storage (see lib.RefreshTokenStorage)
+//   - Value: "1" (existence indicates validity), or a JSON record for tokens
+//     carrying attributes (e.g. certificate binding)
 //   - TTL: Configured via RefreshTokenTTL (default: 1 hour)
 //
 // Multi-device support example:
@@ -43,9 +62,27 @@ const (
 //	User 123 logs in on phone → refresh:123:abc...
 //	Same user logs in on laptop → refresh:123:def...
 //	Both tokens remain valid until expiration or explicit revocation
+//
+// Services created with NewRefreshTokenServiceWithStore keep their tokens in a
+// TokenStore instead, always hashed, and db is nil.
 type RefreshTokenService struct {
-	db     *redis.Client
-	config *lib.Config
+	db        *redis.Client
+	store     TokenStore
+	config    *lib.Config
+	keys      keyPrefix
+	length    tokenLength
+	retention RetentionPolicy
+
+	// mu guards the hooks, which can be set while tokens are verified.
+	mu      sync.RWMutex
+	risk    RiskEvaluator
+	geo     *GeoPolicy
+	audit   lib.AuditLogger
+	filter  *IssuedTokenFilter
+	invalid *InvalidTokenMonitor
+	canary  CanaryHandler
+	revoked *RevocationPropagator
+	replays lib.ReplayMetricsHook
 }
 
 // NewRefreshTokenService creates a new refresh token service instance with Redis persistence.
@@ -55,6 +92,7 @@ type RefreshTokenService struct {
 //   - ctx: Context for initialization (uses Background if nil)
 //   - db: Redis client for token storage
 //   - config: Configuration containing RefreshTokenTTL
+//   - opts: Optional settings (WithKeyPrefix, WithLogger)
 //
 // Returns:
 //   - *RefreshTokenService: Initialized service ready for use
@@ -66,10 +104,60 @@ type RefreshTokenService struct {
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func NewRefreshTokenService(ctx context.Context, db *redis.Client, config *lib.Config) (*RefreshTokenService, error) {
+func NewRefreshTokenService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*RefreshTokenService, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
+
+	service, err := newRefreshTokenService(ctx, config, opts)
+	if err != nil {
+		return nil, err
+	}
+	service.db = db
+	return service, nil
+}
+
+// NewRefreshTokenServiceWithStore creates a new refresh token service instance storing
+// its tokens in a TokenStore instead of Redis, e.g. a DynamoDB table (see dynamostore).
+// Tokens are stored hashed whatever Config.RefreshTokenStorage says.
+//
+// Creating, verifying, revoking (per token, per user and all), canary tokens, user data
+// export and erasure, leak handling and token import/export work as with Redis. The
+// revocation log is not kept: ListRevokedRefreshTokens returns nothing and replays are
+// not reported. The geo policy, the issued token filter and MigrateRefreshTokenStorage
+// fail with ErrTokenStoreUnsupported.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - store: Token store for refresh token storage
+//   - config: Configuration containing RefreshTokenTTL
+//   - opts: Optional settings (WithLogger)
+//
+// Returns:
+//   - *RefreshTokenService: Initialized service ready for use
+//   - error: Configuration or store validation errors
+//
+// Example:
+//
+//	refreshService, err := service.NewRefreshTokenServiceWithStore(ctx, service.NewMemoryTokenStore(), config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewRefreshTokenServiceWithStore(ctx context.Context, store TokenStore, config *lib.Config, opts ...Option) (*RefreshTokenService, error) {
+	if store == nil {
+		return nil, errors.New("store is nil")
+	}
+
+	service, err := newRefreshTokenService(ctx, config, opts)
+	if err != nil {
+		return nil, err
+	}
+	service.store = store
+	return service, nil
+}
+
+// newRefreshTokenService validates the configuration and creates a service without storage.
+func newRefreshTokenService(ctx context.Context, config *lib.Config, opts []Option) (*RefreshTokenService, error) {
 	if config.RefreshTokenTTL == nil {
 		return nil, errors.New("refresh token ttl is nil") // Should no go further
 	}
@@ -78,11 +166,134 @@ func NewRefreshTokenService(ctx context.Context, db *redis.Client, config *lib.C
 		ctx = context.Background()
 	}
 
-	service := &RefreshTokenService{db, config}
+	length, err := newTokenLength("refresh", config.RefreshTokenLength, config.RefreshTokenMaxLength, refreshTokenMaxLength, refreshTokenMinLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidRefreshTokenStorage(config.RefreshTokenStorage) {
+		return nil, fmt.Errorf("invalid refresh token storage: %s", config.RefreshTokenStorage)
+	}
+
+	options := newServiceOptions(opts)
+	retention, err := options.retention.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	service := &RefreshTokenService{
+		config:    config.Clone(),
+		keys:      options.keyPrefix,
+		length:    length,
+		retention: retention,
+		audit:     options.audit,
+	}
 
 	return service, nil
 }
 
+// SetRiskEvaluator plugs a risk evaluator invoked on token creation and verification.
+// Its verdict can require step-up authentication (ErrStepUpRequired) or deny the
+// operation (ErrRiskDenied). A nil evaluator disables risk evaluation.
+//
+// Example:
+//
+//	refreshService.SetRiskEvaluator(service.RiskEvaluatorFunc(func(ctx context.Context, risk service.RiskContext) (service.RiskVerdict, error) {
+//	    return fraudClient.Score(ctx, risk.UserID, risk.Meta.IP)
+//	}))
+func (rts *RefreshTokenService) SetRiskEvaluator(evaluator RiskEvaluator) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.risk = evaluator
+}
+
+// SetGeoPolicy configures the geo/IP policy enforced on token verification.
+// Tokens used from a denied country or network are rejected with ErrGeoDenied,
+// and country changes between consecutive uses can be flagged through audit events.
+// A nil policy disables the checks.
+func (rts *RefreshTokenService) SetGeoPolicy(policy *GeoPolicy) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.geo = policy
+}
+
+// SetAuditLogger configures the logger receiving the service audit events.
+// A nil logger disables auditing.
+func (rts *RefreshTokenService) SetAuditLogger(logger lib.AuditLogger) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.audit = logger
+}
+
+// SetInvalidTokenMonitor configures the monitor counting the nonexistent, expired or
+// malformed tokens presented per source. A nil monitor disables the counting.
+func (rts *RefreshTokenService) SetInvalidTokenMonitor(monitor *InvalidTokenMonitor) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.invalid = monitor
+}
+
+// SetRevocationPropagator announces the revocations of the service to every replica
+// (RevocationKindRefreshToken, RevocationKindUserRefreshTokens, RevocationKindAllRefreshTokens).
+// A nil propagator disables the announcements.
+func (rts *RefreshTokenService) SetRevocationPropagator(propagator *RevocationPropagator) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.revoked = propagator
+}
+
+// SetReplayMetricsHook configures the hook receiving the tokens presented after
+// their revocation or expiration (attempted replays): revoked tokens still in the
+// revocation feed (see RetentionPolicy), and expired tokens when they carry an HMAC
+// checksum (TokenChecksumSecret). A nil hook disables the reporting.
+func (rts *RefreshTokenService) SetReplayMetricsHook(hook lib.ReplayMetricsHook) {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	rts.replays = hook
+}
+
+func (rts *RefreshTokenService) replayMetricsHook() lib.ReplayMetricsHook {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.replays
+}
+
+func (rts *RefreshTokenService) revocationPropagator() *RevocationPropagator {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.revoked
+}
+
+func (rts *RefreshTokenService) riskEvaluator() RiskEvaluator {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.risk
+}
+
+func (rts *RefreshTokenService) geoPolicy() *GeoPolicy {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.geo
+}
+
+func (rts *RefreshTokenService) auditLogger() lib.AuditLogger {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.audit
+}
+
+func (rts *RefreshTokenService) issuedTokenFilter() *IssuedTokenFilter {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.filter
+}
+
+func (rts *RefreshTokenService) invalidTokenMonitor() *InvalidTokenMonitor {
+	rts.mu.RLock()
+	defer rts.mu.RUnlock()
+	return rts.invalid
+}
+
 // CreateRefreshToken generates a new refresh token for the specified user.
 // Multiple tokens can exist per user (multi-device sessions).
 // The token is a 255-character cryptographically secure random string.
@@ -97,7 +308,7 @@ func NewRefreshTokenService(ctx context.Context, db *redis.Client, config *lib.C
 //   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
 //
 // Returns:
-//   - *string: Pointer to the generated refresh token (255 characters)
+//   - *string: Pointer to the generated refresh token (255 characters by default)
 //   - error: Validation or storage errors
 //
 // Example:
@@ -109,31 +320,97 @@ func NewRefreshTokenService(ctx context.Context, db *redis.Client, config *lib.C
 //	// Send token to client (store securely, httpOnly cookie recommended)
 //	setRefreshTokenCookie(w, *token)
 func (rts *RefreshTokenService) CreateRefreshToken(ctx context.Context, userID string) (*string, error) {
+	return rts.createRefreshToken(ctx, userID, refreshTokenRecord{})
+}
+
+// CreateBoundRefreshToken generates a new refresh token bound to a client
+// certificate (mTLS deployments). The token is only accepted by
+// VerifyBoundRefreshToken when the same certificate thumbprint is presented.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - thumbprint: Client certificate thumbprint, see lib.CertificateThumbprint
+//
+// Returns:
+//   - *string: Pointer to the generated refresh token (255 characters by default)
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	thumbprint := lib.CertificateThumbprint(r.TLS.PeerCertificates[0])
+//	token, err := refreshService.CreateBoundRefreshToken(ctx, userID, thumbprint)
+func (rts *RefreshTokenService) CreateBoundRefreshToken(ctx context.Context, userID string, thumbprint string) (*string, error) {
+	if thumbprint == "" {
+		return nil, errors.New("empty certificate thumbprint")
+	}
+	return rts.createRefreshToken(ctx, userID, refreshTokenRecord{Thumbprint: thumbprint})
+}
+
+// CreateScopedRefreshToken generates a new refresh token only usable to obtain access
+// tokens for the given audiences (APIs or services), see AccessTokenRefresher.
+// VerifyRefreshToken still accepts the token, VerifyRefreshTokenForAudience checks the scope.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - audiences: Allowed audiences, at least one
+//
+// Returns:
+//   - *string: Pointer to the generated refresh token (255 characters by default)
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	token, err := refreshService.CreateScopedRefreshToken(ctx, userID, []string{"billing-api"})
+func (rts *RefreshTokenService) CreateScopedRefreshToken(ctx context.Context, userID string, audiences []string) (*string, error) {
+	if len(audiences) == 0 || slices.Contains(audiences, "") {
+		return nil, errors.New("invalid audiences")
+	}
+	return rts.createRefreshToken(ctx, userID, refreshTokenRecord{Audiences: slices.Clone(audiences)})
+}
+
+func (rts *RefreshTokenService) createRefreshToken(ctx context.Context, userID string, record refreshTokenRecord) (*string, error) {
 	if userID == "" {
-		return nil, errors.New("invalid user id")
+		return nil, ErrInvalidUserID
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	if err := evaluateRisk(ctx, rts.riskEvaluator(), RiskOperationRefreshTokenCreate, userID); err != nil {
+		return nil, err
+	}
+
 	// Parse duration from configuration
 	duration, err := time.ParseDuration(*rts.config.RefreshTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
+	value, err := record.encode()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a random token
-	token, err := lib.GenerateRandomString(refreshTokenMaxLength)
+	token, err := generateToken(rts.config, lib.TokenTypeRefresh, rts.length.generated, lib.GenerateRandomString)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add the token to Redis
-	if err := rts.db.Set(ctx, fmt.Sprintf("%s:%s:%s", redisStoreNameRefreshToken, userID, token), "1", duration).Err(); err != nil {
+	if err := rts.storeToken(ctx, userID, rts.storedToken(token), value, duration); err != nil {
 		return nil, err
 	}
 
+	emitAudit(ctx, rts.auditLogger(), AuditEventRefreshTokenCreated, userID, map[string]string{
+		"token_hash":        hashToken(token),
+		"token_fingerprint": lib.TokenFingerprint(token),
+		"expires_at":        time.Now().Add(duration).UTC().Format(time.RFC3339),
+	})
+
 	return &token, nil
 }
 
@@ -148,11 +425,12 @@ func (rts *RefreshTokenService) CreateRefreshToken(ctx context.Context, userID s
 // Parameters:
 //   - ctx: Context for the operation (uses Background if nil)
 //   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
-//   - token: The refresh token to verify (255 characters)
+//   - token: The refresh token to verify (255 characters by default)
 //
 // Returns:
 //   - bool: true if token is valid and not expired, false otherwise
-//   - error: Validation errors or Redis connection errors
+//   - error: Validation errors, Redis connection errors, or policy errors
+//     (ErrGeoDenied, ErrStepUpRequired, ErrRiskDenied) for otherwise valid tokens
 //
 // Example:
 //
@@ -165,26 +443,152 @@ func (rts *RefreshTokenService) CreateRefreshToken(ctx context.Context, userID s
 //	}
 //	// Token valid - generate new access token
 func (rts *RefreshTokenService) VerifyRefreshToken(ctx context.Context, userID string, token string) (bool, error) {
-	if userID == "" {
-		return false, errors.New("invalid user id")
+	return rts.verifyRefreshToken(ctx, userID, token, "")
+}
+
+// VerifyBoundRefreshToken checks if the provided refresh token is valid for the
+// user and bound to the presented client certificate.
+// Unbound tokens are rejected when a thumbprint is presented, and bound tokens
+// are rejected by VerifyRefreshToken, so a stolen bound token is useless
+// without the matching certificate private key.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: The refresh token to verify (255 characters by default)
+//   - thumbprint: Thumbprint of the certificate presented on this connection
+//
+// Returns:
+//   - bool: true if token is valid, not expired and bound to the certificate
+//   - error: Same errors as VerifyRefreshToken
+func (rts *RefreshTokenService) VerifyBoundRefreshToken(ctx context.Context, userID string, token string, thumbprint string) (bool, error) {
+	if thumbprint == "" {
+		return false, errors.New("empty certificate thumbprint")
+	}
+	return rts.verifyRefreshToken(ctx, userID, token, thumbprint)
+}
+
+// VerifyRefreshTokenForAudience checks if the provided refresh token is valid for the
+// user and allowed to obtain access tokens for audience. Tokens created without
+// audiences are allowed for every audience.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: The refresh token to verify (255 characters by default)
+//   - audience: The audience an access token is requested for
+//
+// Returns:
+//   - bool: true if token is valid, not expired and allows the audience
+//   - error: Same errors as VerifyRefreshToken
+func (rts *RefreshTokenService) VerifyRefreshTokenForAudience(ctx context.Context, userID string, token string, audience string) (bool, error) {
+	if audience == "" {
+		return false, errors.New("invalid audience")
 	}
 
-	if err := validation.IsIncomingTokenValid(token, refreshTokenMaxLength); err != nil {
+	record, err := rts.lookupRefreshToken(ctx, userID, token, "")
+	if err != nil || record == nil {
 		return false, err
 	}
+	return record.allowsAudience(audience), nil
+}
+
+func (rts *RefreshTokenService) verifyRefreshToken(ctx context.Context, userID string, token string, thumbprint string) (bool, error) {
+	record, err := rts.lookupRefreshToken(ctx, userID, token, thumbprint)
+	return record != nil, err
+}
+
+// lookupRefreshToken returns the record of a valid token, nil if the token is
+// invalid, expired or not bound to thumbprint. Invalid tokens are reported to the
+// invalid token monitor.
+func (rts *RefreshTokenService) lookupRefreshToken(ctx context.Context, userID string, token string, thumbprint string) (*refreshTokenRecord, error) {
+	record, err := rts.findRefreshToken(ctx, userID, token, thumbprint)
+	rts.invalidTokenMonitor().observe(ctx, lib.TokenTypeRefresh, record != nil, err)
+	observeReplay(ctx, rts.replayMetricsHook(), rts.config, rts.revocations(), rts.config.TokenNormalization.Normalize(token), record != nil, err)
+	return record, err
+}
+
+func (rts *RefreshTokenService) findRefreshToken(ctx context.Context, userID string, token string, thumbprint string) (*refreshTokenRecord, error) {
+	keys, err := rts.refreshTokenKeys(userID, token)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	val, err := rts.db.Get(ctx, fmt.Sprintf("%s:%s:%s", redisStoreNameRefreshToken, userID, token)).Result()
-	if errors.Is(err, redis.Nil) {
-		return false, nil // Token doesn't exist or expired - not an error
+	if rts.store != nil {
+		stored, found, err := rts.store.Get(ctx, userID, hashToken(rts.config.TokenNormalization.Normalize(token)))
+		if err != nil || !found {
+			return nil, err
+		}
+		return rts.acceptRefreshToken(ctx, userID, token, stored.Value, thumbprint)
+	}
+
+	for _, key := range keys {
+		val, err := rts.db.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			continue // Token doesn't exist or expired - not an error
+		}
+		if err != nil {
+			return nil, err // Real Redis error
+		}
+		return rts.acceptRefreshToken(ctx, userID, token, val, thumbprint)
+	}
+	return nil, nil
+}
+
+// refreshTokenKeys validates an incoming token and returns the Redis keys it may
+// be stored under (see storedTokens), none if the token is malformed (no need to query Redis).
+func (rts *RefreshTokenService) refreshTokenKeys(userID string, token string) ([]string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	token = rts.config.TokenNormalization.Normalize(token)
+
+	if err := validation.IsIncomingTokenValid(token, rts.length.max+tokenOverhead(rts.config, lib.TokenTypeRefresh)); err != nil {
+		return nil, err
+	}
+	if !hasValidChecksum(rts.config, token) {
+		return nil, nil
+	}
+	if filter := rts.issuedTokenFilter(); filter != nil && !filter.mightBeIssued(token) {
+		return nil, nil
+	}
+
+	stored := rts.storedTokens(token)
+	keys := make([]string, len(stored))
+	for i, s := range stored {
+		keys[i] = rts.tokenKey(userID, s)
 	}
+	return keys, nil
+}
+
+// acceptRefreshToken decodes the stored value of an existing token and applies
+// the canary, certificate binding, geo and risk policies.
+func (rts *RefreshTokenService) acceptRefreshToken(ctx context.Context, userID string, token string, val string, thumbprint string) (*refreshTokenRecord, error) {
+	record, err := decodeRefreshTokenRecord(val)
 	if err != nil {
-		return false, err // Real Redis error
+		return nil, err
+	}
+	if record.Canary != "" {
+		rts.triggerCanary(ctx, userID, token, record.Canary)
+		return nil, nil
+	}
+	if !record.matchesThumbprint(thumbprint) {
+		return nil, nil
 	}
-	return val == "1", nil
+
+	if err := rts.enforceGeoPolicy(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := evaluateRisk(ctx, rts.riskEvaluator(), RiskOperationRefreshTokenVerify, userID); err != nil {
+		return nil, err
+	}
+	return &record, nil
 }
 
 // RevokeRefreshToken immediately invalidates a specific refresh token.
@@ -196,7 +600,7 @@ func (rts *RefreshTokenService) VerifyRefreshToken(ctx context.Context, userID s
 //
 // Parameters:
 //   - ctx: Context for the operation (uses Background if nil)
-//   - token: The refresh token to revoke (255 characters)
+//   - token: The refresh token to revoke (255 characters by default)
 //   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
 //
 // Returns:
@@ -211,19 +615,104 @@ func (rts *RefreshTokenService) VerifyRefreshToken(ctx context.Context, userID s
 //	}
 //	// Clear client-side cookie
 func (rts *RefreshTokenService) RevokeRefreshToken(ctx context.Context, token string, userID string) error {
+	return rts.RevokeRefreshTokenWithReason(ctx, token, userID, RevocationReasonUserLogout)
+}
+
+// RevokeRefreshTokenWithReason invalidates a specific refresh token like RevokeRefreshToken,
+// recording why it was revoked. The reason is kept in the user revocation log
+// (see ListRevokedRefreshTokens) and sent in a "refresh_token.revoked" audit event.
+// Revoking an unknown or expired token records nothing.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The refresh token to revoke
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - reason: Why the token is revoked (USER_LOGOUT, ADMIN, ROTATION, SUSPICIOUS or LEAKED)
+//
+// Returns:
+//   - error: Validation or storage errors, or the propagation error of an
+//     announced revocation (see SetRevocationPropagator)
+//
+// Example:
+//
+//	// Token used from an unexpected network
+//	err := refreshService.RevokeRefreshTokenWithReason(ctx, token, userID, service.RevocationReasonSuspicious)
+func (rts *RefreshTokenService) RevokeRefreshTokenWithReason(ctx context.Context, token string, userID string, reason RevocationReason) error {
+	_, err := rts.revokeRefreshToken(ctx, token, userID, reason)
+	return err
+}
+
+// revokeRefreshToken implements RevokeRefreshTokenWithReason and reports whether the
+// token existed.
+func (rts *RefreshTokenService) revokeRefreshToken(ctx context.Context, token string, userID string, reason RevocationReason) (bool, error) {
 	if userID == "" {
-		return errors.New("invalid user id")
+		return false, ErrInvalidUserID
+	}
+	if !reason.IsValid() {
+		return false, errors.New("invalid revocation reason")
 	}
 
-	if err := validation.IsIncomingTokenValid(token, refreshTokenMaxLength); err != nil {
-		return err
+	token = rts.config.TokenNormalization.Normalize(token)
+
+	if err := validation.IsIncomingTokenValid(token, rts.length.max+tokenOverhead(rts.config, lib.TokenTypeRefresh)); err != nil {
+		return false, err
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	return rts.db.Del(ctx, fmt.Sprintf("%s:%s:%s", redisStoreNameRefreshToken, userID, token)).Err()
+	if deleted, err := rts.deleteToken(ctx, userID, token); err != nil || !deleted {
+		return false, err
+	}
+
+	if err := rts.revocations().record(ctx, userID, token, reason); err != nil {
+		return true, err
+	}
+	emitAudit(ctx, rts.auditLogger(), AuditEventRefreshTokenRevoked, userID, map[string]string{
+		"reason":            string(reason),
+		"token_hash":        hashToken(token),
+		"token_fingerprint": lib.TokenFingerprint(token),
+	})
+	return true, publishRevocation(ctx, rts.revocationPropagator(), RevocationEvent{
+		Kind:      RevocationKindRefreshToken,
+		UserID:    userID,
+		TokenHash: hashToken(token),
+	})
+}
+
+// ListRevokedRefreshTokens returns the refresh tokens revoked with RevokeRefreshTokenWithReason
+// for a user, most recent first. The log keeps the last 100 revocations for the
+// AnonymizeAfter duration of the retention policy (30 days by default).
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - []RevocationRecord: Token hashes, reasons and revocation times
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	records, err := refreshService.ListRevokedRefreshTokens(ctx, userID)
+//	for _, record := range records {
+//	    fmt.Println(record.RevokedAt, record.Reason)
+//	}
+func (rts *RefreshTokenService) ListRevokedRefreshTokens(ctx context.Context, userID string) ([]RevocationRecord, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return rts.revocations().list(ctx, userID)
+}
+
+func (rts *RefreshTokenService) revocations() revocationLog {
+	return revocationLog{db: rts.db, keys: rts.keys, tokenType: lib.TokenTypeRefresh, retention: rts.retention}
 }
 
 // RevokeAllUserRefreshTokens invalidates all refresh tokens for a specific user.
@@ -250,22 +739,47 @@ func (rts *RefreshTokenService) RevokeRefreshToken(ctx context.Context, token st
 //	}
 func (rts *RefreshTokenService) RevokeAllUserRefreshTokens(ctx context.Context, userID string) error {
 	if userID == "" {
-		return errors.New("invalid user id")
+		return ErrInvalidUserID
 	}
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	keys := rts.db.Scan(ctx, 0, fmt.Sprintf("%s:%s:*", redisStoreNameRefreshToken, userID), 0).Iterator()
-	for keys.Next(ctx) {
-		key := keys.Val()
-		if err := rts.db.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("failed to delete key %s : %w", key, err)
-		}
+	if err := rts.deleteUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens of user %s: %w", userID, err)
+	}
+	return publishRevocation(ctx, rts.revocationPropagator(), RevocationEvent{
+		Kind:   RevocationKindUserRefreshTokens,
+		UserID: userID,
+	})
+}
+
+// RevokeRefreshTokensOfUsers revokes all refresh tokens of several users, e.g.
+// the members of a deleted organization, running up to parallelism revocations at once.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops starting revocations
+//   - userIDs: Users logged out from all devices
+//   - parallelism: Maximum concurrent revocations (default: 8 when not positive)
+//
+// Returns:
+//   - error: Joined errors of the failed revocations
+//
+// Example:
+//
+//	if err := refreshService.RevokeRefreshTokensOfUsers(ctx, memberIDs, 0); err != nil {
+//	    log.Printf("Some sessions were not revoked: %v", err)
+//	}
+func (rts *RefreshTokenService) RevokeRefreshTokensOfUsers(ctx context.Context, userIDs []string, parallelism int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if parallelism <= 0 {
+		parallelism = defaultBulkParallelism
 	}
 
-	return keys.Err()
+	return forEachParallel(ctx, userIDs, parallelism, rts.RevokeAllUserRefreshTokens)
 }
 
 // RevokeAllRefreshTokens revokes all refresh tokens for all users.
@@ -293,17 +807,94 @@ func (rts *RefreshTokenService) RevokeAllUserRefreshTokens(ctx context.Context,
 //	}
 //	log.Println("All users logged out - system secure")
 func (rts *RefreshTokenService) RevokeAllRefreshTokens(ctx context.Context) error {
+	_, err := rts.RevokeAllRefreshTokensInBatches(ctx, CleanupOptions{Parallelism: defaultBulkParallelism})
+	return err
+}
+
+// RevokeAllRefreshTokensInBatches revokes all refresh tokens like RevokeAllRefreshTokens,
+// deleting them in paced batches for large deployments.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops between batches
+//   - opts: Batch size, pause between batches and progress callback
+//
+// Returns:
+//   - int64: Number of revoked tokens
+//   - error: Storage errors or context cancellation
+//
+// Example:
+//
+//	revoked, err := refreshService.RevokeAllRefreshTokensInBatches(ctx, service.CleanupOptions{
+//	    BatchSize: 1000,
+//	    Pause:     10 * time.Millisecond,
+//	})
+//	log.Printf("%d sessions revoked", revoked)
+func (rts *RefreshTokenService) RevokeAllRefreshTokensInBatches(ctx context.Context, opts CleanupOptions) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	keys := rts.db.Scan(ctx, 0, fmt.Sprintf("%s:*", redisStoreNameRefreshToken), 0).Iterator()
-	for keys.Next(ctx) {
-		key := keys.Val()
-		if err := rts.db.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("failed to delete key %s : %w", key, err)
-		}
+	// Announced even after a failure: some tokens may have been revoked
+	if rts.store != nil {
+		revoked, err := deleteStoredTokens(ctx, rts.store, opts)
+		return revoked, errors.Join(err, publishRevocation(ctx, rts.revocationPropagator(), RevocationEvent{Kind: RevocationKindAllRefreshTokens}))
+	}
+	revoked, err := deleteMatching(ctx, rts.db, fmt.Sprintf("%s:*", rts.keys.name(redisStoreNameRefreshToken)), opts)
+	if err == nil {
+		lookupOpts := opts
+		lookupOpts.Progress = nil
+		lookupOpts.Report = nil
+		_, err = deleteMatching(ctx, rts.db, fmt.Sprintf("%s:*", rts.keys.name(redisStoreNameRefreshTokenLookup)), lookupOpts)
+	}
+	return revoked, errors.Join(err, publishRevocation(ctx, rts.revocationPropagator(), RevocationEvent{Kind: RevocationKindAllRefreshTokens}))
+}
+
+// enforceGeoPolicy applies the geo policy to a valid token use: denied requests
+// are rejected and audited, country changes are audited, and the last-use
+// metadata is refreshed.
+func (rts *RefreshTokenService) enforceGeoPolicy(ctx context.Context, userID string) error {
+	geo := rts.geoPolicy()
+	if geo == nil {
+		return nil
+	}
+
+	// The last-use metadata lives in Redis
+	if rts.db == nil {
+		return fmt.Errorf("geo policy: %w", ErrTokenStoreUnsupported)
+	}
+
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	if denied, reason := geo.Denies(meta); denied {
+		emitAudit(ctx, rts.auditLogger(), AuditEventRefreshTokenGeoDenied, userID, map[string]string{"reason": reason})
+		return ErrGeoDenied
+	}
+
+	key := fmt.Sprintf("%s:%s", rts.keys.name(redisStoreNameRefreshTokenMeta), userID)
+	previousCountry, err := rts.db.HGet(ctx, key, "country").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if geo.CountryChanged(previousCountry, meta.Country) {
+		emitAudit(ctx, rts.auditLogger(), AuditEventRefreshTokenCountryChanged, userID, map[string]string{
+			"previous_country": previousCountry,
+			"country":          meta.Country,
+		})
+	}
+
+	duration, err := time.ParseDuration(*rts.config.RefreshTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	// Keep the last known country when the request carries none
+	fields := []any{"ip", meta.IP, "last_used", time.Now().UTC().Format(time.RFC3339)}
+	if meta.Country != "" {
+		fields = append(fields, "country", meta.Country)
 	}
 
-	return keys.Err()
+	pipe := rts.db.TxPipeline()
+	pipe.HSet(ctx, key, fields...)
+	pipe.Expire(ctx, key, duration)
+	_, err = pipe.Exec(ctx)
+	return err
 }
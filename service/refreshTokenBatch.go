@@ -0,0 +1,128 @@
+package service
+
+import "context"
+
+// RefreshTokenCredential is a refresh token presented by a user, as verified by
+// VerifyRefreshToken. Refresh tokens are stored per user, so the user is needed
+// to look them up.
+//
+// Fields:
+//   - UserID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - Token: The refresh token
+type RefreshTokenCredential struct {
+	UserID string
+	Token  string
+}
+
+// RefreshTokenResult is the verification result of one token of a batch.
+//
+// Fields:
+//   - Valid: true if the token is valid and not expired, as returned by VerifyRefreshToken
+//   - Err: Validation or policy error (ErrGeoDenied, ErrStepUpRequired, ErrRiskDenied)
+//     for this token, the token is not valid when set
+type RefreshTokenResult struct {
+	Valid bool
+	Err   error
+}
+
+// VerifyRefreshTokens verifies many refresh tokens with a single Redis round
+// trip (MGET), for gateway components validating many tokens at once.
+// Each token is checked like VerifyRefreshToken: certificate-bound tokens are
+// rejected and the geo and risk policies apply. Services on a token store look
+// the tokens up one by one.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - credentials: The user and token pairs to verify
+//
+// Returns:
+//   - map[string]RefreshTokenResult: Result of each token, keyed by token as given
+//   - error: Redis or token store errors failing the whole lookup
+//
+// Example:
+//
+//	results, err := refreshService.VerifyRefreshTokens(ctx, []service.RefreshTokenCredential{
+//	    {UserID: "123", Token: tokenA},
+//	    {UserID: "456", Token: tokenB},
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	if !results[tokenA].Valid {
+//	    // reject the first request
+//	}
+func (rts *RefreshTokenService) VerifyRefreshTokens(ctx context.Context, credentials []RefreshTokenCredential) (map[string]RefreshTokenResult, error) {
+	results := make(map[string]RefreshTokenResult, len(credentials))
+	if len(credentials) == 0 {
+		return results, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if rts.store != nil {
+		for _, credential := range credentials {
+			tokenKeys, err := rts.refreshTokenKeys(credential.UserID, credential.Token)
+			if err != nil || len(tokenKeys) == 0 {
+				results[credential.Token] = RefreshTokenResult{Err: err}
+				continue
+			}
+			stored, found, err := rts.store.Get(ctx, credential.UserID, hashToken(rts.config.TokenNormalization.Normalize(credential.Token)))
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				results[credential.Token] = RefreshTokenResult{}
+				continue
+			}
+			record, err := rts.acceptRefreshToken(ctx, credential.UserID, credential.Token, stored.Value, "")
+			results[credential.Token] = RefreshTokenResult{Valid: err == nil && record != nil, Err: err}
+		}
+		return results, nil
+	}
+
+	// Only well-formed tokens are looked up, under each key they may be stored
+	type lookup struct {
+		credential RefreshTokenCredential
+		first, end int
+	}
+	keys := make([]string, 0, len(credentials))
+	pending := make([]lookup, 0, len(credentials))
+	for _, credential := range credentials {
+		tokenKeys, err := rts.refreshTokenKeys(credential.UserID, credential.Token)
+		if err != nil || len(tokenKeys) == 0 {
+			results[credential.Token] = RefreshTokenResult{Err: err}
+			continue
+		}
+		pending = append(pending, lookup{credential: credential, first: len(keys), end: len(keys) + len(tokenKeys)})
+		keys = append(keys, tokenKeys...)
+	}
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	values, err := rts.db.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lookup := range pending {
+		credential := lookup.credential
+		val, ok := "", false
+		for _, value := range values[lookup.first:lookup.end] {
+			if val, ok = value.(string); ok {
+				break
+			}
+		}
+		if !ok {
+			// Token doesn't exist or expired
+			results[credential.Token] = RefreshTokenResult{}
+			continue
+		}
+		record, err := rts.acceptRefreshToken(ctx, credential.UserID, credential.Token, val, "")
+		results[credential.Token] = RefreshTokenResult{Valid: err == nil && record != nil, Err: err}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// refreshTokenUnboundValue is the value stored for refresh tokens without attributes.
+// Kept as "1" so that tokens created by earlier versions remain valid.
+const refreshTokenUnboundValue string = "1"
+
+// refreshTokenRecord holds the attributes stored alongside a refresh token.
+//
+// JSON serialization:
+//   - Example: {"x5t#S256": "bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2"}
+//   - Example: {"aud": ["billing-api", "reports-api"]}
+//   - Example: {"canary": "db-backup-2026"}
+type refreshTokenRecord struct {
+	Thumbprint string   `json:"x5t#S256,omitempty"`
+	Audiences  []string `json:"aud,omitempty"`
+	Canary     string   `json:"canary,omitempty"` // Label of a canary token, see CreateCanaryRefreshToken
+}
+
+// encode returns the Redis value of the record: "1" when it has no attribute,
+// its JSON form otherwise.
+func (r refreshTokenRecord) encode() (string, error) {
+	if r.Thumbprint == "" && len(r.Audiences) == 0 && r.Canary == "" {
+		return refreshTokenUnboundValue, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeRefreshTokenRecord parses a Redis value written by encode.
+func decodeRefreshTokenRecord(value string) (refreshTokenRecord, error) {
+	if value == refreshTokenUnboundValue {
+		return refreshTokenRecord{}, nil
+	}
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return refreshTokenRecord{}, fmt.Errorf("corrupted refresh token record: %w", err)
+	}
+	return record, nil
+}
+
+// matchesThumbprint reports whether the presented certificate thumbprint
+// satisfies the record binding. Unbound tokens require no thumbprint;
+// bound tokens require the exact thumbprint (constant-time comparison).
+func (r refreshTokenRecord) matchesThumbprint(thumbprint string) bool {
+	if r.Thumbprint == "" {
+		return thumbprint == ""
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Thumbprint), []byte(thumbprint)) == 1
+}
+
+// allowsAudience reports whether access tokens can be minted for audience.
+// Tokens created without audiences allow every audience.
+func (r refreshTokenRecord) allowsAudience(audience string) bool {
+	return len(r.Audiences) == 0 || slices.Contains(r.Audiences, audience)
+}
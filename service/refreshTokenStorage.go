@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// refreshTokenHashedMarker starts the token part of hashed refresh token keys:
+// "refresh:{userID}:sha256.{hex}". Tokens never contain '.', so the marker tells
+// the storage version of a key without reading it.
+const refreshTokenHashedMarker string = "sha256."
+
+// moveRefreshTokenScript renames KEYS[1] to KEYS[2], keeping its value and TTL.
+// The source is dropped if the destination exists, and skipped if it expired since the scan.
+var moveRefreshTokenScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if redis.call('RENAMENX', KEYS[1], KEYS[2]) == 0 then
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+// isValidRefreshTokenStorage reports whether storage is a known storage mode.
+func isValidRefreshTokenStorage(storage lib.RefreshTokenStorage) bool {
+	switch storage {
+	case "", lib.RefreshTokenStoragePlaintext, lib.RefreshTokenStorageTransition, lib.RefreshTokenStorageHashed:
+		return true
+	}
+	return false
+}
+
+// storedToken returns the token part of the key of a new token.
+func (rts *RefreshTokenService) storedToken(token string) string {
+	switch rts.config.RefreshTokenStorage {
+	case lib.RefreshTokenStorageTransition, lib.RefreshTokenStorageHashed:
+		return refreshTokenHashedMarker + hashToken(token)
+	}
+	return token
+}
+
+// storedTokens returns the token parts under which an incoming token may be
+// stored, the current format first.
+func (rts *RefreshTokenService) storedTokens(token string) []string {
+	if rts.config.RefreshTokenStorage == lib.RefreshTokenStorageTransition {
+		return []string{refreshTokenHashedMarker + hashToken(token), token}
+	}
+	return []string{rts.storedToken(token)}
+}
+
+// lookupKey returns "refresh_lookup:{hash}", hash being the SHA-256 of the token.
+func (rts *RefreshTokenService) lookupKey(hash string) string {
+	return fmt.Sprintf("%s:%s", rts.keys.name(redisStoreNameRefreshTokenLookup), hash)
+}
+
+// tokenKey returns "refresh:{userID}:{stored}".
+func (rts *RefreshTokenService) tokenKey(userID string, stored string) string {
+	return fmt.Sprintf("%s:%s:%s", rts.keys.name(redisStoreNameRefreshToken), userID, stored)
+}
+
+// deleteToken deletes a normalized token of a user with its lookup entry and reports
+// whether it existed.
+func (rts *RefreshTokenService) deleteToken(ctx context.Context, userID string, token string) (bool, error) {
+	if rts.store != nil {
+		deleted, err := rts.store.Delete(ctx, userID, hashToken(token))
+		return deleted > 0, err
+	}
+
+	keys := make([]string, 0, 2)
+	for _, stored := range rts.storedTokens(token) {
+		keys = append(keys, rts.tokenKey(userID, stored))
+	}
+	var deleted *redis.IntCmd
+	if _, err := rts.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, keys...)
+		pipe.Del(ctx, rts.lookupKey(hashToken(token)))
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	return deleted.Val() > 0, nil
+}
+
+// deleteUserTokens deletes the refresh tokens of a user with their lookup entries,
+// batch by batch, so that no lookup outlives its token.
+func (rts *RefreshTokenService) deleteUserTokens(ctx context.Context, userID string) error {
+	if rts.store != nil {
+		_, err := deleteUserStoredTokens(ctx, rts.store, userID)
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s:%s:", rts.keys.name(redisStoreNameRefreshToken), userID)
+	var cursor uint64
+	for {
+		keys, next, err := rts.db.Scan(ctx, cursor, escapeScanPattern(prefix)+"*", int64(defaultCleanupBatchSize)).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			deleted := make([]string, 0, 2*len(keys))
+			for _, key := range keys {
+				deleted = append(deleted, key, rts.lookupKey(storedTokenHash(strings.TrimPrefix(key, prefix))))
+			}
+			pipe := rts.db.Pipeline()
+			for _, key := range deleted {
+				pipe.Del(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// MigrateRefreshTokenStorage rewrites the refresh tokens stored in clear to hashed
+// keys, in paced batches, keeping their value and remaining TTL. It runs online:
+// with RefreshTokenStorageTransition, tokens are accepted in both formats while the
+// job runs. Once it completed, switch the configuration to RefreshTokenStorageHashed.
+// The job can be interrupted and run again, migrated keys are skipped.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), cancelling it stops between batches
+//   - opts: Batch size, pause between batches and progress callback (called with the number of migrated tokens)
+//
+// Returns:
+//   - int64: Number of migrated tokens
+//   - error: Configuration, storage errors or context cancellation
+//
+// Example:
+//
+//	// config.RefreshTokenStorage = lib.RefreshTokenStorageTransition on every instance
+//	migrated, err := refreshService.MigrateRefreshTokenStorage(ctx, service.CleanupOptions{
+//	    BatchSize: 1000,
+//	    Pause:     10 * time.Millisecond,
+//	})
+//	log.Printf("%d refresh tokens hashed", migrated)
+//	// then deploy config.RefreshTokenStorage = lib.RefreshTokenStorageHashed
+func (rts *RefreshTokenService) MigrateRefreshTokenStorage(ctx context.Context, opts CleanupOptions) (int64, error) {
+	if rts.store != nil {
+		return 0, fmt.Errorf("refresh token storage migration: %w", ErrTokenStoreUnsupported)
+	}
+	if rts.config.RefreshTokenStorage != lib.RefreshTokenStorageTransition {
+		return 0, errors.New("refresh token storage migration requires the transition storage")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	prefix := rts.keys.name(redisStoreNameRefreshToken) + ":"
+	var migrated int64
+	var cursor uint64
+	for {
+		opCtx, cancel := opts.operationContext(ctx)
+		keys, next, err := rts.db.Scan(opCtx, cursor, prefix+"*", int64(batchSize)).Result()
+		cancel()
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, key := range keys {
+			// "refresh:{userID}:{token}", tokens never contain ':'
+			separator := strings.LastIndex(key, ":")
+			if separator < len(prefix) {
+				continue
+			}
+			userID, token := key[len(prefix):separator], key[separator+1:]
+			if strings.HasPrefix(token, refreshTokenHashedMarker) {
+				continue
+			}
+
+			opCtx, cancel := opts.operationContext(ctx)
+			moved, err := moveRefreshTokenScript.Run(opCtx, rts.db, []string{key, rts.tokenKey(userID, rts.storedToken(token))}).Int()
+			cancel()
+			if err != nil {
+				return migrated, fmt.Errorf("failed to migrate key %s : %w", key, err)
+			}
+			migrated += int64(moved)
+		}
+		if opts.Progress != nil {
+			opts.Progress(migrated)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return migrated, nil
+		}
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return migrated, ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// regionSeparator separates the home region from the token. It is not part of the
+// token alphabets, so the first separator always ends the region.
+const regionSeparator string = "."
+
+// ErrUnknownRegion is returned by RegionalRefreshTokens for tokens issued by a region
+// it has no service for.
+var ErrUnknownRegion = errors.New("unknown token region")
+
+// RegionalRefreshTokens shares refresh tokens between regions of an active-active
+// deployment, each region running its own Redis. Tokens are prefixed with the region
+// that issued them ("eu1.{token}") and stay stored there only: verifications and
+// revocations in another region are sent to the Redis of the home region.
+//
+// Each token has a single writer, its home region, so regions never hold conflicting
+// copies and no reconciliation is needed: a revocation is effective in every region as
+// soon as it is written. The cost is a cross-region round trip for tokens used away
+// from home, and their verification fails while the home region is unreachable.
+//
+// Tokens without a region prefix (issued before the deployment became multi-region)
+// are handled by the local region.
+type RegionalRefreshTokens struct {
+	local   string
+	regions map[string]*RefreshTokenService
+}
+
+// NewRegionalRefreshTokens creates a router over the refresh token services of every
+// region, each connected to the Redis of its region.
+//
+// Parameters:
+//   - local: Region of this instance, issuing the new tokens
+//   - regions: Refresh token service of each region, by region name (must include local)
+//
+// Returns:
+//   - *RegionalRefreshTokens: Initialized router ready for use
+//   - error: Validation errors
+//
+// Example:
+//
+//	eu, _ := service.NewRefreshTokenService(ctx, euRedis, config)
+//	us, _ := service.NewRefreshTokenService(ctx, usRedis, config)
+//	tokens, err := service.NewRegionalRefreshTokens(os.Getenv("REGION"), map[string]*service.RefreshTokenService{
+//	    "eu1": eu,
+//	    "us1": us,
+//	})
+func NewRegionalRefreshTokens(local string, regions map[string]*RefreshTokenService) (*RegionalRefreshTokens, error) {
+	for name, rts := range regions {
+		if name == "" || strings.Contains(name, regionSeparator) {
+			return nil, fmt.Errorf("invalid region name: %q", name)
+		}
+		if rts == nil {
+			return nil, fmt.Errorf("refresh token service of region %s is nil", name)
+		}
+	}
+	if _, ok := regions[local]; !ok {
+		return nil, fmt.Errorf("no refresh token service for the local region %q", local)
+	}
+
+	return &RegionalRefreshTokens{
+		local:   local,
+		regions: maps.Clone(regions),
+	}, nil
+}
+
+// Region returns the home region of a token, the local region for tokens without
+// region prefix.
+func (r *RegionalRefreshTokens) Region(token string) string {
+	region, _ := r.split(token)
+	return region
+}
+
+// CreateRefreshToken creates a refresh token in the local region, see
+// RefreshTokenService.CreateRefreshToken.
+//
+// Returns:
+//   - *string: Pointer to the token, prefixed with the local region ("eu1.{token}")
+//   - error: The errors of the local service
+func (r *RegionalRefreshTokens) CreateRefreshToken(ctx context.Context, userID string) (*string, error) {
+	token, err := r.regions[r.local].CreateRefreshToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	regional := r.local + regionSeparator + *token
+	return &regional, nil
+}
+
+// VerifyRefreshToken checks a token with the service of its home region, see
+// RefreshTokenService.VerifyRefreshToken.
+//
+// Returns:
+//   - bool: true if the home region accepts the token
+//   - error: ErrUnknownRegion, otherwise the errors of the home region service
+func (r *RegionalRefreshTokens) VerifyRefreshToken(ctx context.Context, userID string, token string) (bool, error) {
+	rts, token, err := r.home(token)
+	if err != nil {
+		return false, err
+	}
+	return rts.VerifyRefreshToken(ctx, userID, token)
+}
+
+// RevokeRefreshToken revokes a token in its home region, see
+// RefreshTokenService.RevokeRefreshToken.
+//
+// Returns:
+//   - error: ErrUnknownRegion, otherwise the errors of the home region service
+func (r *RegionalRefreshTokens) RevokeRefreshToken(ctx context.Context, token string, userID string) error {
+	rts, token, err := r.home(token)
+	if err != nil {
+		return err
+	}
+	return rts.RevokeRefreshToken(ctx, token, userID)
+}
+
+// RevokeAllUserRefreshTokens revokes the tokens of a user in every region, see
+// RefreshTokenService.RevokeAllUserRefreshTokens. All regions are attempted, the
+// errors of unreachable regions are joined, each naming its region.
+func (r *RegionalRefreshTokens) RevokeAllUserRefreshTokens(ctx context.Context, userID string) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(r.regions)) {
+		if err := r.regions[name].RevokeAllUserRefreshTokens(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// split returns the home region of a token and the token without its region.
+func (r *RegionalRefreshTokens) split(token string) (string, string) {
+	region, rest, found := strings.Cut(token, regionSeparator)
+	if !found {
+		return r.local, token
+	}
+	return region, rest
+}
+
+// home returns the service of the home region of a token and the token without its region.
+func (r *RegionalRefreshTokens) home(token string) (*RefreshTokenService, string, error) {
+	region, token := r.split(token)
+	rts, ok := r.regions[region]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+	return rts, token, nil
+}
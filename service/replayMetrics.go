@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// observeReplay reports a refresh or password reset token rejected by a lookup to
+// the replay metrics hook, when the token is known to have been issued:
+//   - lib.ReplayReasonRevoked: the token is in the revocation feed
+//   - lib.ReplayReasonExpired: the token is not in the feed but carries a valid HMAC
+//     checksum (TokenChecksumSecret), so it was issued and has expired (or was revoked
+//     in bulk, which is not recorded)
+//
+// Other unknown tokens may be guesses and are left to the InvalidTokenMonitor. The
+// token must be normalized. Storage errors are ignored: metrics never fail a lookup.
+func observeReplay(ctx context.Context, hook lib.ReplayMetricsHook, config *lib.Config, revocations revocationLog, token string, valid bool, err error) {
+	if hook == nil || valid || err != nil || !hasValidChecksum(config, token) {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	revoked, err := revocations.isRevoked(ctx, token)
+	switch {
+	case err != nil:
+		return
+	case revoked:
+		hook.ObserveReplay(revocations.tokenType, lib.ReplayReasonRevoked)
+	case config.TokenChecksum && config.TokenChecksumSecret != "":
+		hook.ObserveReplay(revocations.tokenType, lib.ReplayReasonExpired)
+	}
+}
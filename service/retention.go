@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetentionPolicy sets how long the revocation records of a token type are kept,
+// once the tokens themselves are gone. Tokens are deleted by Redis when they expire;
+// revocation records remain for incident response (ListRevokedRefreshTokens) and
+// edge caches (ExportRevokedRefreshTokens).
+//
+// Fields:
+//   - KeepFor: How long token hashes are kept after revocation in the revocation feed,
+//     not linked to users (default: 30 days)
+//   - AnonymizeAfter: How long revocation records stay linked to their user in the user
+//     revocation logs, at most KeepFor (default: KeepFor)
+//
+// Example:
+//
+//	// Keep revoked hashes 90 days, linked to the user for 7 days only
+//	policy := service.RetentionPolicy{KeepFor: 90 * 24 * time.Hour, AnonymizeAfter: 7 * 24 * time.Hour}
+//	refreshService, err := service.NewRefreshTokenService(ctx, redisClient, config, service.WithRetentionPolicy(policy))
+type RetentionPolicy struct {
+	KeepFor        time.Duration
+	AnonymizeAfter time.Duration
+}
+
+// resolve validates the policy and applies the defaults.
+func (p RetentionPolicy) resolve() (RetentionPolicy, error) {
+	if p.KeepFor < 0 || p.AnonymizeAfter < 0 {
+		return RetentionPolicy{}, errors.New("retention durations must not be negative")
+	}
+	if p.KeepFor == 0 {
+		p.KeepFor = max(defaultRevocationLogRetention, p.AnonymizeAfter)
+	}
+	if p.AnonymizeAfter == 0 {
+		p.AnonymizeAfter = p.KeepFor
+	}
+	if p.AnonymizeAfter > p.KeepFor {
+		return RetentionPolicy{}, errors.New("retention anonymize after must not exceed keep for")
+	}
+	return p, nil
+}
+
+// ApplyRetentionPolicy removes the revocation records older than the retention policy:
+// user revocation logs are trimmed to AnonymizeAfter and the revocation feed to KeepFor.
+// Redis expires whole logs on its own; run it periodically (e.g. daily, on the leader
+// of a lib.LeaderElection) to also prune the old records of logs still written to.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - opts: BatchSize, Pause, Progress (records removed so far) and Lock; Parallelism and Report are ignored
+//
+// Returns:
+//   - int64: Number of revocation records removed
+//   - error: Storage errors, or lib.ErrLockNotAcquired
+//
+// Example:
+//
+//	removed, err := refreshService.ApplyRetentionPolicy(ctx, service.CleanupOptions{Lock: retentionLock})
+//	if err != nil {
+//	    log.Printf("Retention policy not applied: %v", err)
+//	}
+func (rts *RefreshTokenService) ApplyRetentionPolicy(ctx context.Context, opts CleanupOptions) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return rts.revocations().prune(ctx, opts)
+}
+
+// ApplyRetentionPolicy removes the revocation records older than the retention policy,
+// like RefreshTokenService.ApplyRetentionPolicy.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - opts: BatchSize, Pause, Progress (records removed so far) and Lock; Parallelism and Report are ignored
+//
+// Returns:
+//   - int64: Number of revocation records removed
+//   - error: Storage errors, or lib.ErrLockNotAcquired
+func (prs *PasswordResetService) ApplyRetentionPolicy(ctx context.Context, opts CleanupOptions) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return prs.revocations().prune(ctx, opts)
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameRevocation is the Redis key prefix for revocation logs.
+	// Key pattern: "revocation:{tokenType}:{userID}" holding a list of JSON
+	// revocation records, most recent first.
+	redisStoreNameRevocation string = "revocation"
+
+	// redisStoreNameRevocationFeed is the Redis key prefix for the revocation feeds.
+	// Key pattern: "revocation_feed:{tokenType}" holding a sorted set of token hashes
+	// scored by revocation time (Unix milliseconds), across all users.
+	redisStoreNameRevocationFeed string = "revocation_feed"
+
+	// revocationLogMaxEntries is the number of revocation records kept per user and token type.
+	revocationLogMaxEntries int64 = 100
+
+	// defaultRevocationLogRetention is how long revocation records are kept by default.
+	defaultRevocationLogRetention time.Duration = 30 * 24 * time.Hour
+)
+
+// RevocationReason tells why a token was revoked, so incident responders can
+// tell a logout from an administrative or security revocation.
+type RevocationReason string
+
+const (
+	// RevocationReasonUserLogout is a revocation requested by the user (default).
+	RevocationReasonUserLogout RevocationReason = "USER_LOGOUT"
+	// RevocationReasonAdmin is a revocation performed by an administrator.
+	RevocationReasonAdmin RevocationReason = "ADMIN"
+	// RevocationReasonRotation is a revocation of a token replaced by a new one.
+	RevocationReasonRotation RevocationReason = "ROTATION"
+	// RevocationReasonSuspicious is a revocation following suspicious activity.
+	RevocationReasonSuspicious RevocationReason = "SUSPICIOUS"
+	// RevocationReasonLeaked is a revocation of a token found in a public place
+	// (see LeakedTokenResponder).
+	RevocationReasonLeaked RevocationReason = "LEAKED"
+)
+
+// IsValid reports whether the reason is one of the known reasons.
+func (r RevocationReason) IsValid() bool {
+	switch r {
+	case RevocationReasonUserLogout, RevocationReasonAdmin, RevocationReasonRotation, RevocationReasonSuspicious, RevocationReasonLeaked:
+		return true
+	default:
+		return false
+	}
+}
+
+// RevocationRecord describes a revoked token. The token itself is never stored,
+// only its SHA-256 hash, which responders can compare with a known token.
+//
+// JSON serialization:
+//   - Example: {"token_hash": "9f86d0...", "reason": "SUSPICIOUS", "revoked_at": "2025-01-01T12:00:00Z"}
+type RevocationRecord struct {
+	TokenHash string           `json:"token_hash"`
+	Reason    RevocationReason `json:"reason"`
+	RevokedAt time.Time        `json:"revoked_at"`
+}
+
+// revocationLog stores the revocation records of a token type, per user and in
+// the feed, for the durations of the retention policy. Services on a token store
+// have no db: nothing is recorded and the log reads empty.
+type revocationLog struct {
+	db        *redis.Client
+	keys      keyPrefix
+	tokenType lib.TokenType
+	retention RetentionPolicy
+}
+
+// record prepends a revocation record to the user log, capped to revocationLogMaxEntries
+// and kept for AnonymizeAfter, and adds the token hash to the feed, kept for KeepFor.
+func (rl revocationLog) record(ctx context.Context, userID string, token string, reason RevocationReason) error {
+	if rl.db == nil {
+		return nil
+	}
+
+	record := RevocationRecord{
+		TokenHash: hashToken(token),
+		Reason:    reason,
+		RevokedAt: time.Now().UTC(),
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	key := rl.key(userID)
+	feedKey := rl.feedKey()
+	_, err = rl.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, data)
+		pipe.LTrim(ctx, key, 0, revocationLogMaxEntries-1)
+		pipe.Expire(ctx, key, rl.retention.AnonymizeAfter)
+
+		pipe.ZAdd(ctx, feedKey, redis.Z{Score: float64(record.RevokedAt.UnixMilli()), Member: record.TokenHash})
+		rl.trimFeed(ctx, pipe, record.RevokedAt)
+		pipe.Expire(ctx, feedKey, rl.retention.KeepFor)
+		return nil
+	})
+	return err
+}
+
+// revokedSince returns the hashes of the tokens revoked at or after since, by any user.
+func (rl revocationLog) revokedSince(ctx context.Context, since time.Time) ([]string, error) {
+	if rl.db == nil {
+		return nil, nil
+	}
+	return rl.db.ZRangeByScore(ctx, rl.feedKey(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// isRevoked reports whether a token is in the feed, revoked less than KeepFor ago.
+func (rl revocationLog) isRevoked(ctx context.Context, token string) (bool, error) {
+	if rl.db == nil {
+		return false, nil
+	}
+	err := rl.db.ZScore(ctx, rl.feedKey(), hashToken(token)).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// trimFeed removes the feed entries older than KeepFor.
+func (rl revocationLog) trimFeed(ctx context.Context, cmd redis.Cmdable, now time.Time) *redis.IntCmd {
+	return cmd.ZRemRangeByScore(ctx, rl.feedKey(), "-inf", fmt.Sprintf("(%d", now.Add(-rl.retention.KeepFor).UnixMilli()))
+}
+
+// list returns the revocation records of a user, most recent first. Records older
+// than AnonymizeAfter not pruned yet are left out.
+func (rl revocationLog) list(ctx context.Context, userID string) ([]RevocationRecord, error) {
+	if rl.db == nil {
+		return []RevocationRecord{}, nil
+	}
+	values, err := rl.db.LRange(ctx, rl.key(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records, _, err := rl.decode(values, time.Now().Add(-rl.retention.AnonymizeAfter))
+	return records, err
+}
+
+// decode parses the records of a user log revoked at or after cutoff, and returns the
+// index of the first older record (len(values) if none). Records are most recent first.
+func (rl revocationLog) decode(values []string, cutoff time.Time) ([]RevocationRecord, int, error) {
+	records := make([]RevocationRecord, 0, len(values))
+	for i, value := range values {
+		var record RevocationRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return nil, 0, fmt.Errorf("corrupted revocation record: %w", err)
+		}
+		if record.RevokedAt.Before(cutoff) {
+			return records, i, nil
+		}
+		records = append(records, record)
+	}
+	return records, len(values), nil
+}
+
+// prune removes the records older than AnonymizeAfter from the user logs and the
+// hashes older than KeepFor from the feed, and returns the number of records removed.
+func (rl revocationLog) prune(ctx context.Context, opts CleanupOptions) (int64, error) {
+	if rl.db == nil {
+		return 0, nil
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	var lease *lib.Lease
+	if opts.Lock != nil {
+		var err error
+		if lease, err = opts.Lock.Acquire(ctx); err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = lease.Release(context.WithoutCancel(ctx))
+		}()
+	}
+
+	now := time.Now()
+	opCtx, cancel := opts.operationContext(ctx)
+	removed, err := rl.trimFeed(opCtx, rl.db, now).Result()
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	pattern := escapeScanPattern(fmt.Sprintf("%s:%s:", rl.keys.name(redisStoreNameRevocation), rl.tokenType)) + "*"
+	cutoff := now.Add(-rl.retention.AnonymizeAfter)
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if lease != nil {
+			if err := lease.Refresh(ctx); err != nil {
+				return removed, err
+			}
+		}
+
+		opCtx, cancel := opts.operationContext(ctx)
+		keys, next, err := rl.db.Scan(opCtx, cursor, pattern, int64(batchSize)).Result()
+		cancel()
+		if err != nil {
+			return removed, err
+		}
+		for _, key := range keys {
+			opCtx, cancel := opts.operationContext(ctx)
+			n, err := rl.pruneKey(opCtx, key, cutoff)
+			cancel()
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		if opts.Progress != nil && len(keys) > 0 {
+			opts.Progress(removed)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return removed, nil
+		}
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return removed, ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+	}
+}
+
+// pruneKey trims the records of a user log revoked before cutoff. Records pushed
+// meanwhile shift the list: the trim then keeps an old record, removed next time.
+func (rl revocationLog) pruneKey(ctx context.Context, key string, cutoff time.Time) (int64, error) {
+	values, err := rl.db.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+	_, first, err := rl.decode(values, cutoff)
+	if err != nil || first == len(values) {
+		return 0, err
+	}
+
+	if first == 0 {
+		return int64(len(values)), rl.db.Del(ctx, key).Err()
+	}
+	return int64(len(values) - first), rl.db.LTrim(ctx, key, 0, int64(first-1)).Err()
+}
+
+func (rl revocationLog) key(userID string) string {
+	return fmt.Sprintf("%s:%s:%s", rl.keys.name(redisStoreNameRevocation), rl.tokenType, userID)
+}
+
+func (rl revocationLog) feedKey() string {
+	return fmt.Sprintf("%s:%s", rl.keys.name(redisStoreNameRevocationFeed), rl.tokenType)
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
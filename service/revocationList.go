@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ExportRevokedRefreshTokens returns the refresh tokens revoked since a given time as a
+// compact signed list (an HS256 JWT, see modelAuth.RevocationListClaim), to be pulled
+// periodically by edge proxies or CDN workers so they can reject revoked tokens
+// without calling back to Redis on every request.
+//
+// Only tokens revoked one by one (RevokeRefreshToken, RevokeRefreshTokenWithReason) are
+// listed, as token hashes; user-wide and global revocations are not. Revocations are
+// kept for the KeepFor duration of the retention policy (30 days by default).
+//
+// The secret must not be the JWTSecret: edge caches holding it could forge access tokens.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - since: Start of the window; the zero time exports every kept revocation
+//   - secret: HMAC key shared with the edge caches, verified by ParseRevocationList
+//
+// Returns:
+//   - string: The signed list
+//   - error: Validation, storage or signing errors
+//
+// Example:
+//
+//	// Edge caches poll GET /revocations?since=... every minute
+//	list, err := refreshService.ExportRevokedRefreshTokens(ctx, since, revocationListSecret)
+//	if err != nil {
+//	    return err
+//	}
+//	w.Header().Set("Content-Type", "application/jwt")
+//	w.Write([]byte(list))
+func (rts *RefreshTokenService) ExportRevokedRefreshTokens(ctx context.Context, since time.Time, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("signing secret is empty")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now()
+	if oldest := now.Add(-rts.retention.KeepFor); since.Before(oldest) {
+		since = oldest
+	}
+	hashes, err := rts.revocations().revokedSince(ctx, since)
+	if err != nil {
+		return "", err
+	}
+	slices.Sort(hashes)
+
+	claim := &modelAuth.RevocationListClaim{
+		TokenType: string(lib.TokenTypeRefresh),
+		Since:     jwt.NewNumericDate(since),
+		Revoked:   hashes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   rts.config.Issuer,
+			IssuedAt: jwt.NewNumericDate(now),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claim).SignedString([]byte(secret))
+}
+
+// ParseRevocationList verifies a list exported by ExportRevokedRefreshTokens, on the
+// edge side. Lists older than maxAge are rejected, so that a cache that cannot reach
+// the exporter anymore stops trusting its stale copy.
+//
+// Parameters:
+//   - list: The signed list
+//   - secret: HMAC key given to ExportRevokedRefreshTokens
+//   - issuer: Expected issuer (Config.Issuer of the exporter)
+//   - maxAge: Maximum age of the list, unchecked when not positive
+//
+// Returns:
+//   - *modelAuth.RevocationListClaim: The list, to check tokens with IsRevoked
+//   - error: Signature, issuer or age errors
+//
+// Example:
+//
+//	revoked, err := service.ParseRevocationList(body, revocationListSecret, "myapp", 5*time.Minute)
+//	if err != nil {
+//	    return err // Keep the previous list
+//	}
+//	if revoked.IsRevoked(refreshToken) {
+//	    w.WriteHeader(http.StatusUnauthorized)
+//	    return
+//	}
+func ParseRevocationList(list string, secret string, issuer string, maxAge time.Duration) (*modelAuth.RevocationListClaim, error) {
+	if secret == "" {
+		return nil, errors.New("signing secret is empty")
+	}
+
+	t, err := jwt.ParseWithClaims(list, &modelAuth.RevocationListClaim{}, func(token *jwt.Token) (any, error) {
+		return []byte(secret), nil
+	},
+		jwt.WithLeeway(5*time.Second),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claim, ok := t.Claims.(*modelAuth.RevocationListClaim)
+	if !ok || !t.Valid || claim.IssuedAt == nil || claim.TokenType != string(lib.TokenTypeRefresh) {
+		return nil, ErrInvalidTokenClaim
+	}
+	if maxAge > 0 && time.Since(claim.IssuedAt.Time) > maxAge {
+		return nil, errors.New("revocation list is stale")
+	}
+	if !slices.IsSorted(claim.Revoked) {
+		slices.Sort(claim.Revoked)
+	}
+
+	return claim, nil
+}
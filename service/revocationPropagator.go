@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannelRevocations is the Redis Pub/Sub channel announcing revocations.
+// Channel pattern: "revocations" with a JSON RevocationEvent per revocation.
+const redisChannelRevocations string = "revocations"
+
+// RevocationKind tells what a revocation invalidates.
+type RevocationKind string
+
+const (
+	// RevocationKindRefreshToken is a single refresh token, identified by TokenHash.
+	RevocationKindRefreshToken RevocationKind = "refresh_token"
+	// RevocationKindUserRefreshTokens is every refresh token of UserID.
+	RevocationKindUserRefreshTokens RevocationKind = "user_refresh_tokens"
+	// RevocationKindAllRefreshTokens is every refresh token.
+	RevocationKindAllRefreshTokens RevocationKind = "all_refresh_tokens"
+	// RevocationKindUserEpoch is a bump of the epoch of UserID.
+	RevocationKindUserEpoch RevocationKind = "user_epoch"
+	// RevocationKindGlobalEpoch is a bump of the global epoch.
+	RevocationKindGlobalEpoch RevocationKind = "global_epoch"
+	// RevocationKindResync is dispatched locally when the subscription (re)connects:
+	// revocations may have been missed, caches must forget everything.
+	RevocationKindResync RevocationKind = "resync"
+)
+
+// RevocationEvent is a revocation announced to every replica.
+//
+// JSON serialization:
+//   - Example: {"kind": "refresh_token", "user_id": "123", "token_hash": "9f86d0..."}
+type RevocationEvent struct {
+	Kind      RevocationKind `json:"kind"`
+	UserID    string         `json:"user_id,omitempty"`
+	TokenHash string         `json:"token_hash,omitempty"`
+}
+
+// RevocationHandler receives the revocations, typically to evict entries of an
+// in-process cache. It must not block.
+type RevocationHandler func(event RevocationEvent)
+
+// RevocationPropagator announces revocations to the in-process verification caches of
+// every replica through Redis Pub/Sub, so that a revocation takes effect everywhere
+// within milliseconds instead of the cache TTL.
+//
+// Services publish through it once plugged (RefreshTokenService.SetRevocationPropagator,
+// TokenEpochService.SetRevocationPropagator); caches, the epoch cache of
+// TokenEpochService.EnableCache or those of the application, register with OnRevocation.
+// Pub/Sub is fire-and-forget: a replica disconnected from Redis misses announcements,
+// so its handlers receive RevocationKindResync when the subscription is restored.
+type RevocationPropagator struct {
+	db      *redis.Client
+	channel string
+
+	// mu guards the handlers, which can be registered while revocations are received.
+	mu       sync.RWMutex
+	handlers []RevocationHandler
+}
+
+// NewRevocationPropagator creates a revocation propagator.
+// Returns an error if the database client is nil. Accepts WithKeyPrefix: replicas
+// must use the same prefix to share revocations.
+//
+// Example:
+//
+//	propagator, err := service.NewRevocationPropagator(redisClient)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	refreshService.SetRevocationPropagator(propagator)
+//	epochService.SetRevocationPropagator(propagator)
+//	go propagator.Run(ctx)
+func NewRevocationPropagator(db *redis.Client, opts ...Option) (*RevocationPropagator, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	return &RevocationPropagator{
+		db:      db,
+		channel: newServiceOptions(opts).keyPrefix.name(redisChannelRevocations),
+	}, nil
+}
+
+// OnRevocation registers a handler called with every revocation, published by this
+// replica or received from the others. A nil handler is ignored.
+//
+// Example:
+//
+//	propagator.OnRevocation(func(event service.RevocationEvent) {
+//	    if event.Kind == service.RevocationKindUserRefreshTokens {
+//	        sessionCache.Delete(event.UserID)
+//	    }
+//	})
+func (p *RevocationPropagator) OnRevocation(handler RevocationHandler) {
+	if handler == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+// Publish announces a revocation to every replica. The handlers of this replica are
+// called right away, without waiting for the announcement to come back.
+//
+// Returns:
+//   - error: Validation, encoding or storage errors
+func (p *RevocationPropagator) Publish(ctx context.Context, event RevocationEvent) error {
+	if event.Kind == "" || event.Kind == RevocationKindResync {
+		return fmt.Errorf("invalid revocation kind: %q", event.Kind)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p.dispatch(event)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.db.Publish(ctx, p.channel, payload).Err()
+}
+
+// Run subscribes to the revocations of the other replicas and dispatches them to the
+// handlers until ctx is done, with RevocationKindResync on every (re)connection.
+// It blocks: run it in its own goroutine.
+//
+// Returns:
+//   - error: The context error once ctx is done
+func (p *RevocationPropagator) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pubsub := p.db.Subscribe(ctx, p.channel)
+	defer pubsub.Close()
+	messages := pubsub.ChannelWithSubscriptions()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// Revocations may have been missed while disconnected
+				p.dispatch(RevocationEvent{Kind: RevocationKindResync})
+			case *redis.Message:
+				var event RevocationEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil || event.Kind == "" {
+					// Unknown announcement: forgetting everything is the safe choice
+					event = RevocationEvent{Kind: RevocationKindResync}
+				}
+				p.dispatch(event)
+			}
+		}
+	}
+}
+
+func (p *RevocationPropagator) dispatch(event RevocationEvent) {
+	p.mu.RLock()
+	handlers := p.handlers
+	p.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// publishRevocation publishes a revocation when a propagator is plugged. The
+// revocation is already stored: the error tells that other replicas may keep
+// accepting the revoked tokens until their caches expire.
+func publishRevocation(ctx context.Context, propagator *RevocationPropagator, event RevocationEvent) error {
+	if propagator == nil {
+		return nil
+	}
+	if err := propagator.Publish(ctx, event); err != nil {
+		return fmt.Errorf("revocation stored but not propagated: %w", err)
+	}
+	return nil
+}
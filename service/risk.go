@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+var (
+	// ErrRiskDenied is returned when the risk evaluator denies an operation.
+	ErrRiskDenied = errors.New("denied by risk evaluation")
+	// ErrStepUpRequired is returned when the risk evaluator requires an additional
+	// authentication factor (e.g. OTP) before the operation can proceed.
+	ErrStepUpRequired = errors.New("step-up authentication required")
+)
+
+// RiskOperation identifies the operation submitted to the risk evaluator.
+type RiskOperation string
+
+const (
+	RiskOperationRefreshTokenCreate  RiskOperation = "refresh_token.create"
+	RiskOperationRefreshTokenVerify  RiskOperation = "refresh_token.verify"
+	RiskOperationPasswordResetCreate RiskOperation = "password_reset.create"
+	RiskOperationPasswordResetVerify RiskOperation = "password_reset.verify"
+)
+
+// RiskVerdict is the decision returned by a RiskEvaluator.
+type RiskVerdict int
+
+const (
+	// RiskAllow lets the operation proceed.
+	RiskAllow RiskVerdict = iota
+	// RiskStepUp requires an additional factor: the operation fails with ErrStepUpRequired.
+	RiskStepUp
+	// RiskDeny refuses the operation: it fails with ErrRiskDenied.
+	RiskDeny
+)
+
+// RiskContext describes the operation being evaluated.
+//
+// Fields:
+//   - Operation: What is being attempted (e.g. refresh_token.verify)
+//   - UserID: User the token belongs to
+//   - Meta: Client information (IP, geo, device) from lib.WithRequestMeta, zero value if absent
+type RiskContext struct {
+	Operation RiskOperation
+	UserID    string
+	Meta      lib.RequestMeta
+}
+
+// RiskEvaluator scores token operations, typically by calling a fraud-scoring system.
+// Errors abort the operation (fail closed).
+type RiskEvaluator interface {
+	Evaluate(ctx context.Context, risk RiskContext) (RiskVerdict, error)
+}
+
+// RiskEvaluatorFunc adapts a function to the RiskEvaluator interface.
+type RiskEvaluatorFunc func(ctx context.Context, risk RiskContext) (RiskVerdict, error)
+
+// Evaluate calls f(ctx, risk).
+func (f RiskEvaluatorFunc) Evaluate(ctx context.Context, risk RiskContext) (RiskVerdict, error) {
+	return f(ctx, risk)
+}
+
+// evaluateRisk submits the operation to the evaluator and converts its verdict
+// into an error. A nil evaluator allows every operation.
+func evaluateRisk(ctx context.Context, evaluator RiskEvaluator, operation RiskOperation, userID string) error {
+	if evaluator == nil {
+		return nil
+	}
+
+	meta, _ := lib.RequestMetaFromContext(ctx)
+	verdict, err := evaluator.Evaluate(ctx, RiskContext{
+		Operation: operation,
+		UserID:    userID,
+		Meta:      meta,
+	})
+	if err != nil {
+		return fmt.Errorf("risk evaluation failed: %w", err)
+	}
+
+	switch verdict {
+	case RiskAllow:
+		return nil
+	case RiskStepUp:
+		return ErrStepUpRequired
+	default:
+		return ErrRiskDenied
+	}
+}
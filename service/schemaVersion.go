@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StorageSchemaVersion is the version of the Redis storage layout (key patterns and
+// stored values) written by this module. It is increased when a release changes the
+// layout in a way earlier releases cannot read.
+const StorageSchemaVersion int = 1
+
+// minStorageSchemaVersion is the oldest storage layout this module can read.
+const minStorageSchemaVersion int = 1
+
+// redisStoreNameSchemaVersion is the Redis key holding the storage schema version.
+// Key pattern: "schema_version", no TTL.
+const redisStoreNameSchemaVersion string = "schema_version"
+
+// CheckSchemaVersion records the storage schema version on first use and checks at
+// startup that the data in Redis can be read by this release, so that an upgrade or a
+// downgrade against incompatible data fails with an explanation instead of tokens
+// being rejected as invalid.
+//
+// The recorded version is never overwritten: a compatible older version is kept, so that
+// replicas running the previous release keep working during a rolling upgrade.
+//
+// Parameters:
+//   - ctx: Context for the check (uses Background if nil)
+//   - db: Redis client given to the services
+//   - opts: The options given to the services (WithKeyPrefix)
+//
+// Returns:
+//   - int: The recorded storage schema version
+//   - error: Storage errors, or an incompatible recorded version with the upgrade to perform
+//
+// Example:
+//
+//	if _, err := service.CheckSchemaVersion(ctx, redisClient, service.WithKeyPrefix("myapp")); err != nil {
+//	    log.Fatalf("Redis data is not compatible with the token services: %v", err)
+//	}
+func CheckSchemaVersion(ctx context.Context, db *redis.Client, opts ...Option) (int, error) {
+	if db == nil {
+		return 0, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key := newServiceOptions(opts).keyPrefix.name(redisStoreNameSchemaVersion)
+	recorded, err := db.SetNX(ctx, key, StorageSchemaVersion, 0).Result()
+	if err != nil {
+		return 0, err
+	}
+	if recorded {
+		return StorageSchemaVersion, nil
+	}
+
+	stored, err := db.Get(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(stored)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid storage schema version %q in %s", stored, key)
+	}
+
+	switch {
+	case version > StorageSchemaVersion:
+		return version, fmt.Errorf("redis data uses storage schema version %d, newer than version %d of this release: upgrade github.com/bcetienne/tools-go-token", version, StorageSchemaVersion)
+	case version < minStorageSchemaVersion:
+		return version, fmt.Errorf("redis data uses storage schema version %d, older than version %d required by this release: migrate the tokens with a release supporting both (TokenMigrator), then update %s", version, minStorageSchemaVersion, key)
+	}
+	return version, nil
+}
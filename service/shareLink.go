@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameShareLink is the Redis key prefix for share links.
+	// Key pattern: "share_link:{sha256(token)}" with a hash (user_id, resource, remaining) as value.
+	redisStoreNameShareLink string = "share_link"
+
+	// shareLinkTokenLength is the character length of generated share link tokens.
+	shareLinkTokenLength int = 32
+
+	defaultShareLinkTTL string = "168h" // 7 days
+)
+
+// useShareLinkScript decrements the remaining uses of a share link and deletes it
+// on its last use, so that concurrent downloads never exceed the limit.
+// Returns {user_id, resource, remaining} or nil when the link does not exist.
+var useShareLinkScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local remaining = redis.call('HINCRBY', KEYS[1], 'remaining', -1)
+local record = redis.call('HMGET', KEYS[1], 'user_id', 'resource')
+if remaining <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+if remaining < 0 then
+	return false
+end
+return {record[1], record[2], remaining}
+`)
+
+// ShareLinkUse is an accepted use of a share link.
+//
+// Fields:
+//   - UserID: User who created the link
+//   - Resource: Shared resource given on creation (e.g. a file ID)
+//   - RemainingUses: Uses left after this one, 0 when the link is exhausted
+type ShareLinkUse struct {
+	UserID        string
+	Resource      string
+	RemainingUses int
+}
+
+// ShareLinkService issues tokens valid for a limited number of uses, e.g. a file
+// share link valid for 5 downloads.
+//
+// Key features:
+//   - Cryptographically secure 32-character tokens
+//   - Use counter decremented atomically (Lua script), the link is deleted on its last use
+//   - Automatic expiration via Redis TTL, whatever the remaining uses
+//
+// Redis key pattern:
+//   - Key: "share_link:{sha256(token)}" (tokens are not stored in clear)
+//   - Value: Hash with user_id, resource and remaining fields
+//   - TTL: Configured via ShareLinkTTL (default: 7 days)
+type ShareLinkService struct {
+	db     *redis.Client
+	config *lib.Config
+	links  *ttlStore
+}
+
+// ShareLinkServiceInterface defines the methods for share link management.
+type ShareLinkServiceInterface interface {
+	CreateShareLink(ctx context.Context, userID string, resource string, maxUses int) (*string, error)
+	UseShareLink(ctx context.Context, token string) (*ShareLinkUse, error)
+	RemainingUses(ctx context.Context, token string) (int, error)
+	RevokeShareLink(ctx context.Context, token string) error
+	RevokeAllShareLinks(ctx context.Context) error
+}
+
+// NewShareLinkService creates a new share link service instance with Redis persistence.
+// Returns an error if the database client is nil or if ShareLinkTTL cannot be parsed.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for share link storage
+//   - config: Configuration containing ShareLinkTTL
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *ShareLinkService: Initialized service ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	shareLinkService, err := service.NewShareLinkService(ctx, redisClient, config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewShareLinkService(ctx context.Context, db *redis.Client, config *lib.Config, opts ...Option) (*ShareLinkService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	duration, err := parseDurationOrDefault(config.ShareLinkTTL, defaultShareLinkTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid share link TTL format: %w", err)
+	}
+
+	options := newServiceOptions(opts)
+	service := &ShareLinkService{
+		db:     db,
+		config: config.Clone(),
+		links:  newTTLStore(db, options.keyPrefix.name(redisStoreNameShareLink), duration),
+	}
+
+	return service, nil
+}
+
+// CreateShareLink generates a token giving access to resource maxUses times.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - resource: Shared resource returned by UseShareLink (e.g. a file ID)
+//   - maxUses: Number of times the link can be used, must be positive
+//
+// Returns:
+//   - *string: Pointer to the generated token
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	token, err := shareLinkService.CreateShareLink(ctx, userID, fileID, 5)
+//	if err != nil {
+//	    return err
+//	}
+//	link := "https://app.example.com/download?token=" + *token
+func (sls *ShareLinkService) CreateShareLink(ctx context.Context, userID string, resource string, maxUses int) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+	if maxUses <= 0 {
+		return nil, errors.New("max uses must be positive")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	token, err := lib.GenerateRandomString(shareLinkTokenLength)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sls.links.key(hashToken(token))
+	_, err = sls.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", userID, "resource", resource, "remaining", maxUses)
+		pipe.Expire(ctx, key, sls.links.ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UseShareLink counts one use of a share link. The link is deleted on its last use.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The share link token
+//
+// Returns:
+//   - *ShareLinkUse: The shared resource and the uses left, nil if the token is not valid
+//     (unknown, expired, revoked or exhausted)
+//   - error: Storage errors
+//
+// Example:
+//
+//	use, err := shareLinkService.UseShareLink(ctx, r.URL.Query().Get("token"))
+//	if err != nil || use == nil {
+//	    w.WriteHeader(http.StatusNotFound)
+//	    return
+//	}
+//	serveFile(w, use.Resource)
+func (sls *ShareLinkService) UseShareLink(ctx context.Context, token string) (*ShareLinkUse, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	values, err := useShareLinkScript.Run(ctx, sls.db, []string{sls.links.key(hashToken(token))}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("corrupted %s record", redisStoreNameShareLink)
+	}
+
+	userID, _ := values[0].(string)
+	resource, _ := values[1].(string)
+	remaining, _ := values[2].(int64)
+	return &ShareLinkUse{UserID: userID, Resource: resource, RemainingUses: int(remaining)}, nil
+}
+
+// RemainingUses returns how many times a share link can still be used,
+// 0 if it does not exist, expired or was revoked.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The share link token
+//
+// Returns:
+//   - int: Uses left
+//   - error: Storage errors
+func (sls *ShareLinkService) RemainingUses(ctx context.Context, token string) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, err := sls.db.HGet(ctx, sls.links.key(hashToken(token)), "remaining").Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	remaining, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted %s record: %w", redisStoreNameShareLink, err)
+	}
+	return max(remaining, 0), nil
+}
+
+// RevokeShareLink invalidates a share link. Revoking an unknown link is not an error.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The share link token
+//
+// Returns:
+//   - error: Storage errors
+func (sls *ShareLinkService) RevokeShareLink(ctx context.Context, token string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return sls.links.revoke(ctx, hashToken(token))
+}
+
+// RevokeAllShareLinks invalidates the share links of all users.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during revocation
+func (sls *ShareLinkService) RevokeAllShareLinks(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return sls.links.revokeAll(ctx)
+}
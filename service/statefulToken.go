@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// statefulTokenLength is the character length of generated stateful tokens.
+const statefulTokenLength int = 32
+
+// StatefulTokenRecord is the data stored with a stateful token.
+//
+// Fields:
+//   - UserID: Owner of the token
+//   - Payload: Application data given on creation
+//   - CreatedAt: Creation time (UTC)
+type StatefulTokenRecord[T any] struct {
+	UserID    string    `json:"user_id"`
+	Payload   T         `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StatefulToken issues random tokens carrying a typed payload, for flows that
+// need data attached to the token: invitations (team, role), email changes
+// (new address), account deletions, etc. The payload is serialized as JSON with
+// the token and returned typed on verification.
+//
+// Key features:
+//   - Cryptographically secure 32-character tokens
+//   - Any JSON-serializable payload type
+//   - Verify leaves the token valid, Consume deletes it atomically (single use)
+//   - Automatic expiration via Redis TTL
+//
+// Redis key pattern:
+//   - Key: "{name}:{sha256(token)}" (tokens are not stored in clear)
+//   - Value: JSON StatefulTokenRecord
+//   - TTL: Given to NewStatefulToken
+type StatefulToken[T any] struct {
+	db     *redis.Client
+	name   string
+	ttl    time.Duration
+	tokens *ttlStore
+}
+
+// NewStatefulToken creates a stateful token store for payloads of type T.
+// Returns an error if the database client is nil, the name is empty or the TTL is not positive.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for token storage
+//   - name: Redis key prefix of the tokens, unique per token kind (e.g. "invite")
+//   - ttl: Token lifetime
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *StatefulToken[T]: Initialized store ready for use
+//   - error: Validation errors
+//
+// Example:
+//
+//	type Invite struct {
+//	    TeamID string `json:"team_id"`
+//	    Role   string `json:"role"`
+//	}
+//	invites, err := service.NewStatefulToken[Invite](ctx, redisClient, "invite", 72*time.Hour)
+func NewStatefulToken[T any](ctx context.Context, db *redis.Client, name string, ttl time.Duration, opts ...Option) (*StatefulToken[T], error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if name == "" {
+		return nil, errors.New("stateful token name is empty")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("stateful token ttl must be positive")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	options := newServiceOptions(opts)
+	return &StatefulToken[T]{
+		db:     db,
+		name:   name,
+		ttl:    ttl,
+		tokens: newTTLStore(db, options.keyPrefix.name(name), ttl),
+	}, nil
+}
+
+// Create generates a token for the user carrying payload.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - payload: Data returned by Verify and Consume
+//
+// Returns:
+//   - *string: Pointer to the generated token
+//   - error: Validation, serialization or storage errors
+//
+// Example:
+//
+//	token, err := invites.Create(ctx, inviterID, Invite{TeamID: "42", Role: "editor"})
+//	sendEmail(guestEmail, "https://app.example.com/join?token="+*token)
+func (st *StatefulToken[T]) Create(ctx context.Context, userID string, payload T) (*string, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	data, err := json.Marshal(StatefulTokenRecord[T]{
+		UserID:    userID,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", st.name, err)
+	}
+
+	token, err := lib.GenerateRandomString(statefulTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.tokens.set(ctx, hashToken(token), string(data)); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Verify returns the record of a token without consuming it, e.g. to display an
+// invitation before it is accepted.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The token to verify
+//
+// Returns:
+//   - *StatefulTokenRecord[T]: The token record, nil if the token does not exist or expired
+//   - error: Storage or deserialization errors
+func (st *StatefulToken[T]) Verify(ctx context.Context, token string) (*StatefulTokenRecord[T], error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, found, err := st.tokens.get(ctx, hashToken(token))
+	if err != nil || !found {
+		return nil, err
+	}
+	return st.decode(value)
+}
+
+// Consume returns the record of a token and deletes it, so that it cannot be used twice.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The token to consume
+//
+// Returns:
+//   - *StatefulTokenRecord[T]: The token record, nil if the token does not exist, expired or was consumed
+//   - error: Storage or deserialization errors
+//
+// Example:
+//
+//	record, err := invites.Consume(ctx, r.URL.Query().Get("token"))
+//	if err != nil || record == nil {
+//	    w.WriteHeader(http.StatusBadRequest)
+//	    return
+//	}
+//	addMember(record.Payload.TeamID, currentUserID, record.Payload.Role)
+func (st *StatefulToken[T]) Consume(ctx context.Context, token string) (*StatefulTokenRecord[T], error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value, found, err := st.tokens.consume(ctx, hashToken(token))
+	if err != nil || !found {
+		return nil, err
+	}
+	return st.decode(value)
+}
+
+// Revoke invalidates a token. Revoking an unknown token is not an error.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - token: The token to revoke
+//
+// Returns:
+//   - error: Storage errors
+func (st *StatefulToken[T]) Revoke(ctx context.Context, token string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return st.tokens.revoke(ctx, hashToken(token))
+}
+
+// RevokeAll invalidates all tokens of this kind.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - error: Storage errors encountered during revocation
+func (st *StatefulToken[T]) RevokeAll(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return st.tokens.revokeAll(ctx)
+}
+
+// TTL returns the token lifetime given on construction.
+func (st *StatefulToken[T]) TTL() time.Duration {
+	return st.ttl
+}
+
+// revokeHash revokes a token from its hash, for services keeping hashes only.
+func (st *StatefulToken[T]) revokeHash(ctx context.Context, hash string) error {
+	return st.tokens.revoke(ctx, hash)
+}
+
+func (st *StatefulToken[T]) decode(value string) (*StatefulTokenRecord[T], error) {
+	var record StatefulTokenRecord[T]
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("corrupted %s record: %w", st.name, err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+// ErrInsufficientUserAuthentication is returned when an access token does not meet
+// a step-up requirement. The message is the RFC 9470 error code.
+var ErrInsufficientUserAuthentication = errors.New("insufficient_user_authentication")
+
+// StepUpRequirement describes the authentication a sensitive operation requires.
+// Zero fields are not checked.
+//
+// Fields:
+//   - ACR: Minimum assurance level (e.g. modelAuth.AssuranceLevel2)
+//   - MaxAuthAge: Maximum time elapsed since the user authenticated
+//
+// Example:
+//
+//	// Changing the email requires a second factor within the last 5 minutes
+//	requirement := service.StepUpRequirement{ACR: modelAuth.AssuranceLevel2, MaxAuthAge: 5 * time.Minute}
+//	if err := requirement.Check(claim); err != nil {
+//	    return err
+//	}
+type StepUpRequirement struct {
+	ACR        string
+	MaxAuthAge time.Duration
+}
+
+// Check verifies the claims of a verified access token against the requirement.
+// Tokens without acr or auth_time fail the corresponding checks.
+//
+// Parameters:
+//   - claim: Claims returned by AccessTokenService.VerifyAccessToken
+//
+// Returns:
+//   - error: ErrInsufficientUserAuthentication (wrapped with the reason), nil if satisfied
+func (r StepUpRequirement) Check(claim *modelAuth.Claim) error {
+	if claim == nil {
+		return fmt.Errorf("%w: missing claims", ErrInsufficientUserAuthentication)
+	}
+
+	if !modelAuth.IsAssuranceLevelAtLeast(claim.ACR, r.ACR) {
+		return fmt.Errorf("%w: assurance level %s required", ErrInsufficientUserAuthentication, r.ACR)
+	}
+
+	if r.MaxAuthAge > 0 {
+		age, ok := claim.AuthAge(time.Now())
+		if !ok || age > r.MaxAuthAge {
+			return fmt.Errorf("%w: authentication older than %s", ErrInsufficientUserAuthentication, r.MaxAuthAge)
+		}
+	}
+
+	return nil
+}
+
+// ValidateClaim implements ClaimValidator, so that a requirement can be enforced on
+// every token with AccessTokenService.AddClaimValidator.
+func (r StepUpRequirement) ValidateClaim(claim *modelAuth.Claim) error {
+	return r.Check(claim)
+}
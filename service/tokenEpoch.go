@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameTokenEpoch is the Redis key prefix for token epochs.
+	// Key patterns: "token_epoch:global" holding the global epoch and
+	// "token_epoch:user:{userID}" holding the user epochs (integers, no TTL).
+	redisStoreNameTokenEpoch string = "token_epoch"
+
+	// maxEpochCacheEntries bounds the user epochs held by the epoch cache, which is
+	// cleared when full.
+	maxEpochCacheEntries int = 100_000
+)
+
+// ErrTokenEpochRevoked is returned when an access token was issued before the
+// current global or user epoch, i.e. before an emergency or user-wide revocation.
+var ErrTokenEpochRevoked = errors.New("token revoked by epoch")
+
+// TokenEpochService stores the token epochs, generation numbers embedded in access
+// tokens at issuance (epoch and user_epoch claims). Bumping an epoch rejects the
+// access tokens issued before, although they are stateless and not yet expired:
+// the global epoch for all users, a user epoch for a single user (e.g. "log out
+// everywhere" after a password change), without a denylist of individual jtis.
+//
+// Redis key patterns:
+//   - Global: "token_epoch:global" → epoch (integer, 0 when absent)
+//   - Per user: "token_epoch:user:{userID}" → epoch (integer, 0 when absent)
+//   - TTL: none
+//
+// Access tokens read the epochs on every creation and verification. EnableCache keeps
+// them in memory for a TTL; with a RevocationPropagator, bumps evict them from the
+// caches of every replica right away.
+type TokenEpochService struct {
+	db   *redis.Client
+	keys keyPrefix
+
+	// mu guards the hooks, which can be set while tokens are verified.
+	mu         sync.RWMutex
+	cache      *epochCache
+	propagator *RevocationPropagator
+}
+
+// cachedEpoch is an epoch held by the epoch cache.
+type cachedEpoch struct {
+	epoch   int64
+	expires time.Time
+}
+
+// epochCache holds the epochs read from Redis for a TTL. Its generation is
+// incremented by each eviction, so that values read before an eviction are not
+// cached after it.
+type epochCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	generation uint64
+	global     cachedEpoch
+	users      map[string]cachedEpoch
+}
+
+// NewTokenEpochService creates a new token epoch service instance with Redis persistence.
+// Returns an error if the database client is nil.
+// Accepts WithKeyPrefix.
+//
+// Example:
+//
+//	epochService, err := service.NewTokenEpochService(ctx, redisClient)
+//	accessService.SetEpochService(epochService)
+func NewTokenEpochService(ctx context.Context, db *redis.Client, opts ...Option) (*TokenEpochService, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &TokenEpochService{db: db, keys: newServiceOptions(opts).keyPrefix}, nil
+}
+
+// EnableCache keeps the epochs in memory for ttl, saving a Redis round trip on most
+// access token creations and verifications. A bump on another replica is seen after
+// at most ttl, or right away with SetRevocationPropagator. A non-positive ttl disables
+// the cache.
+//
+// Example:
+//
+//	epochService.EnableCache(30 * time.Second)
+//	epochService.SetRevocationPropagator(propagator)
+//	go propagator.Run(ctx)
+func (tes *TokenEpochService) EnableCache(ttl time.Duration) {
+	var cache *epochCache
+	if ttl > 0 {
+		cache = &epochCache{ttl: ttl, users: make(map[string]cachedEpoch)}
+	}
+
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	tes.cache = cache
+}
+
+// SetRevocationPropagator announces the epoch bumps to every replica
+// (RevocationKindGlobalEpoch, RevocationKindUserEpoch) and evicts the announced
+// epochs from the cache of the service. A nil propagator disables the announcements.
+func (tes *TokenEpochService) SetRevocationPropagator(propagator *RevocationPropagator) {
+	if propagator != nil {
+		propagator.OnRevocation(tes.evict)
+	}
+
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	tes.propagator = propagator
+}
+
+func (tes *TokenEpochService) epochCache() *epochCache {
+	tes.mu.RLock()
+	defer tes.mu.RUnlock()
+	return tes.cache
+}
+
+func (tes *TokenEpochService) revocationPropagator() *RevocationPropagator {
+	tes.mu.RLock()
+	defer tes.mu.RUnlock()
+	return tes.propagator
+}
+
+// evict removes the epochs invalidated by a revocation from the cache.
+func (tes *TokenEpochService) evict(event RevocationEvent) {
+	cache := tes.epochCache()
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	switch event.Kind {
+	case RevocationKindUserEpoch:
+		delete(cache.users, event.UserID)
+	case RevocationKindGlobalEpoch:
+		cache.global = cachedEpoch{}
+	case RevocationKindResync:
+		cache.global = cachedEpoch{}
+		clear(cache.users)
+	default:
+		return
+	}
+	cache.generation++
+}
+
+// GlobalEpoch returns the current global epoch, 0 if it was never bumped.
+func (tes *TokenEpochService) GlobalEpoch(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tes.get(ctx, tes.globalKey())
+}
+
+// BumpGlobalEpoch increments the global epoch: every access token issued before
+// is rejected by AccessTokenService.VerifyAccessToken.
+//
+// Returns:
+//   - int64: The new global epoch
+//   - error: Storage errors
+func (tes *TokenEpochService) BumpGlobalEpoch(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	epoch, err := tes.db.Incr(ctx, tes.globalKey()).Result()
+	if err != nil {
+		return 0, err
+	}
+	return epoch, tes.announce(ctx, RevocationEvent{Kind: RevocationKindGlobalEpoch})
+}
+
+// UserEpoch returns the current epoch of a user, 0 if it was never bumped.
+func (tes *TokenEpochService) UserEpoch(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tes.get(ctx, tes.userKey(userID))
+}
+
+// BumpUserEpoch increments the epoch of a user: every access token issued to the
+// user before is rejected by AccessTokenService.VerifyAccessToken.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - int64: The new user epoch
+//   - error: Validation or storage errors
+//
+// Example:
+//
+//	// Password changed: log out every session, access tokens included
+//	_, err := epochService.BumpUserEpoch(ctx, userID)
+//	err = refreshService.RevokeAllUserRefreshTokens(ctx, userID)
+func (tes *TokenEpochService) BumpUserEpoch(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrInvalidUserID
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	epoch, err := tes.db.Incr(ctx, tes.userKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	return epoch, tes.announce(ctx, RevocationEvent{Kind: RevocationKindUserEpoch, UserID: userID})
+}
+
+// announce evicts a bumped epoch from the local cache, then from the caches of the
+// other replicas when a propagator is plugged.
+func (tes *TokenEpochService) announce(ctx context.Context, event RevocationEvent) error {
+	tes.evict(event)
+	return publishRevocation(ctx, tes.revocationPropagator(), event)
+}
+
+// epochs returns the global and user epochs, from the cache when enabled, otherwise
+// in a single round trip.
+func (tes *TokenEpochService) epochs(ctx context.Context, userID string) (int64, int64, error) {
+	cache := tes.epochCache()
+	if cache == nil {
+		return tes.readEpochs(ctx, userID)
+	}
+
+	now := time.Now()
+	cache.mu.Lock()
+	global, user := cache.global, cache.users[userID]
+	generation := cache.generation
+	cache.mu.Unlock()
+	if now.Before(global.expires) && now.Before(user.expires) {
+		return global.epoch, user.epoch, nil
+	}
+
+	epoch, userEpoch, err := tes.readEpochs(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	// Skipped when evicted meanwhile: the values may predate the bump
+	if cache.generation == generation {
+		if len(cache.users) >= maxEpochCacheEntries {
+			clear(cache.users)
+		}
+		expires := now.Add(cache.ttl)
+		cache.global = cachedEpoch{epoch: epoch, expires: expires}
+		cache.users[userID] = cachedEpoch{epoch: userEpoch, expires: expires}
+	}
+	return epoch, userEpoch, nil
+}
+
+// readEpochs reads the global and user epochs in a single round trip.
+func (tes *TokenEpochService) readEpochs(ctx context.Context, userID string) (int64, int64, error) {
+	values, err := tes.db.MGet(ctx, tes.globalKey(), tes.userKey(userID)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	epochs := make([]int64, len(values))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		epochs[i], err = parseEpoch(fmt.Sprint(value))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return epochs[0], epochs[1], nil
+}
+
+func (tes *TokenEpochService) get(ctx context.Context, key string) (int64, error) {
+	val, err := tes.db.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseEpoch(val)
+}
+
+func (tes *TokenEpochService) globalKey() string {
+	return fmt.Sprintf("%s:global", tes.keys.name(redisStoreNameTokenEpoch))
+}
+
+func (tes *TokenEpochService) userKey(userID string) string {
+	return fmt.Sprintf("%s:user:%s", tes.keys.name(redisStoreNameTokenEpoch), userID)
+}
+
+func parseEpoch(value string) (int64, error) {
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted token epoch: %w", err)
+	}
+	return epoch, nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenTypeAccessToken is the RFC 8693 identifier of access tokens, the type of the
+// subject, actor and issued tokens of ExchangeToken.
+const TokenTypeAccessToken string = "urn:ietf:params:oauth:token-type:access_token"
+
+// DelegationScope lets a scoped access token be the actor token of ExchangeToken.
+// Scoped tokens were narrowed for a given use, so they act on behalf of others only
+// when granted this scope; unscoped tokens always can.
+const DelegationScope string = "delegate"
+
+var (
+	// ErrInvalidTarget is returned by ExchangeToken when the requested audience is
+	// not one of the audiences of the subject token (RFC 8693 "invalid_target").
+	ErrInvalidTarget = errors.New("invalid target")
+	// ErrInvalidScope is returned by ExchangeToken when the requested scopes are not
+	// granted by the subject token (RFC 6749 "invalid_scope").
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrActorNotAllowed is returned by ExchangeToken when the party of the actor token
+	// may not act on behalf of the subject, when no ActorAuthorizer is set, or when the
+	// actor token was narrowed to other audiences or scopes than delegation.
+	ErrActorNotAllowed = errors.New("actor not allowed")
+)
+
+// ActorAuthorizer decides whether the party of a verified actor token may act on
+// behalf of the subject of a token exchange. It returns nil to allow the delegation,
+// ErrActorNotAllowed (possibly wrapped) to refuse it; other errors are returned by
+// ExchangeToken as they are.
+//
+// Example:
+//
+//	accessService.SetActorAuthorizer(func(ctx context.Context, subject, actor *modelAuth.Claim) error {
+//	    if !delegations.Allowed(ctx, actor.Subject, subject.Subject) {
+//	        return service.ErrActorNotAllowed
+//	    }
+//	    return nil
+//	})
+type ActorAuthorizer func(ctx context.Context, subject *modelAuth.Claim, actor *modelAuth.Claim) error
+
+// AllowActors returns an ActorAuthorizer allowing the given actor subjects, typically
+// the service accounts of the gateways, to act on behalf of any subject.
+//
+// Example:
+//
+//	accessService.SetActorAuthorizer(service.AllowActors("gateway"))
+func AllowActors(subjects ...string) ActorAuthorizer {
+	allowed := slices.Clone(subjects)
+	return func(_ context.Context, _ *modelAuth.Claim, actor *modelAuth.Claim) error {
+		if !slices.Contains(allowed, actor.Subject) {
+			return ErrActorNotAllowed
+		}
+		return nil
+	}
+}
+
+// SetActorAuthorizer configures the authorizer of the actor tokens of ExchangeToken.
+// Any valid access token would otherwise let its holder act on behalf of anyone whose
+// token it obtained, so exchanges with an actor token are refused with
+// ErrActorNotAllowed until an authorizer is set. A nil authorizer refuses them again.
+func (at *AccessTokenService) SetActorAuthorizer(authorizer ActorAuthorizer) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.actors = authorizer
+}
+
+func (at *AccessTokenService) actorAuthorizer() ActorAuthorizer {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.actors
+}
+
+// TokenExchangeRequest is an RFC 8693 token exchange request.
+//
+// Fields:
+//   - SubjectToken: Access token of the party the new token represents
+//   - ActorToken: Access token of the party acting on behalf of the subject, for
+//     delegation; empty for impersonation (the new token carries no new actor)
+//   - Audience: API the new token is for, empty to keep the audiences of the subject token
+//   - Scopes: Scopes of the new token, empty to keep the scopes of the subject token
+type TokenExchangeRequest struct {
+	SubjectToken string
+	ActorToken   string
+	Audience     string
+	Scopes       []string
+}
+
+// TokenExchangeResponse is the RFC 8693 token exchange response.
+//
+// JSON serialization:
+//   - Example: {"access_token": "eyJhbGciOi...", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+//     "token_type": "Bearer", "expires_in": 900, "scope": "invoices:read"}
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// ExchangeToken exchanges a valid access token for a new one with a narrowed audience
+// and scopes (RFC 8693), e.g. so that a gateway calls a backend service on behalf of a
+// user with only the permissions the call needs.
+//
+// Narrowing rules:
+//   - Audience: must be one of the audiences of the subject token, when it has some
+//     (ErrInvalidTarget)
+//   - Scopes: must be granted by the subject token, when it is limited to scopes
+//     (ErrInvalidScope)
+//   - Expiration: never later than the subject token
+//
+// With an actor token, the ActorAuthorizer set with SetActorAuthorizer must allow the
+// actor to act on behalf of the subject (ErrActorNotAllowed without an authorizer).
+// The actor token must be unscoped or grant DelegationScope, and, when restricted to
+// audiences, list the issuer: a token narrowed for an API cannot delegate.
+// The new token then records the actor in its "act" claim, the prior
+// actors of the subject token nested inside (delegation chain, see Actor.Chain).
+// Without one, the actors of the subject token are kept. Authentication markers (amr,
+// acr, auth_time), custom claims and one-time use are carried over: the token
+// exchanged for a one-time-use token is one-time-use too. An "access_token.exchanged"
+// audit event records each exchange.
+//
+// Parameters:
+//   - ctx: Context for the verifications and the audit event (uses Background if nil)
+//   - request: The exchange request
+//
+// Returns:
+//   - *TokenExchangeResponse: The new token, ready to be serialized
+//   - error: The errors of VerifyAccessToken for the subject and actor tokens,
+//     ErrActorNotAllowed, ErrInvalidTarget, ErrInvalidScope, or token creation errors
+//
+// Example:
+//
+//	accessService.SetActorAuthorizer(service.AllowActors("gateway"))
+//	response, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{
+//	    SubjectToken: userToken,
+//	    ActorToken:   gatewayToken,
+//	    Audience:     "billing-api",
+//	    Scopes:       []string{"invoices:read"},
+//	})
+//	if err != nil {
+//	    apierror.WriteProblem(w, err)
+//	    return
+//	}
+//	json.NewEncoder(w).Encode(response)
+func (at *AccessTokenService) ExchangeToken(ctx context.Context, request TokenExchangeRequest) (*TokenExchangeResponse, error) {
+	if request.SubjectToken == "" {
+		return nil, errors.New("subject token is empty")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	subject, err := at.VerifyAccessTokenContext(ctx, request.SubjectToken)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := subject.Actor
+	if request.ActorToken != "" {
+		actorClaim, err := at.VerifyAccessTokenContext(ctx, request.ActorToken)
+		if err != nil {
+			return nil, err
+		}
+		if actorClaim.IsScoped() && !actorClaim.HasScope(DelegationScope) {
+			return nil, ErrActorNotAllowed
+		}
+		if len(actorClaim.Audience) > 0 && !slices.Contains(actorClaim.Audience, at.config.Issuer) {
+			return nil, ErrActorNotAllowed
+		}
+		authorize := at.actorAuthorizer()
+		if authorize == nil {
+			return nil, ErrActorNotAllowed
+		}
+		if err := authorize(ctx, subject, actorClaim); err != nil {
+			return nil, err
+		}
+		actor = &modelAuth.Actor{
+			Subject: actorClaim.Subject,
+			Email:   actorClaim.Email,
+			Actor:   subject.Actor,
+		}
+	}
+
+	audience := subject.Audience
+	if request.Audience != "" {
+		if len(subject.Audience) > 0 && !slices.Contains(subject.Audience, request.Audience) {
+			return nil, ErrInvalidTarget
+		}
+		audience = jwt.ClaimStrings{request.Audience}
+	}
+
+	scope := subject.Scope
+	if len(request.Scopes) > 0 {
+		if slices.ContainsFunc(request.Scopes, func(s string) bool { return len(strings.Fields(s)) != 1 }) {
+			return nil, ErrInvalidScope
+		}
+		scopes, err := lib.ParseScope(strings.Join(request.Scopes, " "))
+		if err != nil {
+			return nil, ErrInvalidScope
+		}
+		// An unscoped subject token is a full user token: it can be narrowed to any scope
+		for _, s := range scopes {
+			if subject.IsScoped() && !subject.HasScope(s) {
+				return nil, ErrInvalidScope
+			}
+		}
+		slices.Sort(scopes)
+		scope = strings.Join(scopes, " ")
+	}
+
+	claim, err := at.newClaim(modelAuth.NewUser(subject.Subject, subject.Email), nil)
+	if err != nil {
+		return nil, err
+	}
+	claim.Audience = audience
+	claim.Scope = scope
+	claim.Actor = actor
+	claim.AMR = subject.AMR
+	claim.ACR = subject.ACR
+	claim.AuthTime = subject.AuthTime
+	claim.Custom = subject.Custom
+	claim.OneTimeUse = subject.OneTimeUse
+	if subject.ExpiresAt != nil && subject.ExpiresAt.Before(claim.ExpiresAt.Time) {
+		claim.ExpiresAt = subject.ExpiresAt
+	}
+
+	token, err := at.sign(claim)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{
+		"subject_jti":       subject.ID,
+		"jti":               claim.ID,
+		"token_fingerprint": lib.TokenFingerprint(token),
+		"audience":          strings.Join(audience, " "),
+		"scope":             scope,
+	}
+	if actor != nil {
+		details["actor_chain"] = strings.Join(actor.Chain(), " ")
+	}
+	emitAudit(ctx, at.auditLogger(), AuditEventAccessTokenExchanged, subject.Subject, details)
+
+	return &TokenExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(claim.ExpiresAt.Sub(at.now()).Round(time.Second) / time.Second),
+		Scope:           scope,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// generateToken creates a token with length random characters generated by generate,
+// preceded by the structured prefix when Config.TokenPrefix is enabled and followed
+// by a checksum when Config.TokenChecksum is enabled.
+func generateToken(config *lib.Config, tokenType lib.TokenType, length int, generate func(int) (string, error)) (string, error) {
+	token, err := generate(length)
+	if err != nil {
+		return "", err
+	}
+	if config.TokenPrefix {
+		token = lib.TokenPrefix(tokenType) + token
+	}
+	if config.TokenChecksum {
+		token = lib.AppendTokenChecksum(token, config.TokenChecksumSecret)
+	}
+	return token, nil
+}
+
+// tokenOverhead returns the number of characters added by generateToken to the random part.
+func tokenOverhead(config *lib.Config, tokenType lib.TokenType) int {
+	overhead := 0
+	if config.TokenPrefix {
+		overhead += len(lib.TokenPrefix(tokenType))
+	}
+	if config.TokenChecksum {
+		overhead += lib.TokenChecksumLength
+	}
+	return overhead
+}
+
+// hasValidChecksum reports whether an incoming token may exist, so that malformed
+// tokens are rejected before any Redis query. Always true when checksums are disabled.
+func hasValidChecksum(config *lib.Config, token string) bool {
+	return !config.TokenChecksum || lib.HasValidTokenChecksum(token, config.TokenChecksumSecret)
+}
+
+// foldTokenCase uppercases token, keeping its structured prefix lowercase.
+func foldTokenCase(tokenType lib.TokenType, token string) string {
+	token = strings.ToUpper(token)
+	prefix := lib.TokenPrefix(tokenType)
+	if upperPrefix := strings.ToUpper(prefix); strings.HasPrefix(token, upperPrefix) {
+		token = prefix + token[len(upperPrefix):]
+	}
+	return token
+}
@@ -0,0 +1,38 @@
+package service
+
+import "fmt"
+
+// tokenLength holds the generated and maximum accepted lengths of a token type.
+type tokenLength struct {
+	generated int
+	max       int
+}
+
+// newTokenLength resolves the configured lengths of a token type.
+//
+// Rules:
+//   - A zero generated length uses the default length
+//   - The generated length cannot be below minLength (entropy floor)
+//   - A zero max length accepts the longest of the generated and default lengths,
+//     so that tokens issued before shortening them remain valid
+//   - The max length cannot be below the generated length
+func newTokenLength(name string, generated, max, defaultLength, minLength int) (tokenLength, error) {
+	if generated == 0 {
+		generated = defaultLength
+	}
+	if generated < minLength {
+		return tokenLength{}, fmt.Errorf("%s token length must be at least %d", name, minLength)
+	}
+
+	if max == 0 {
+		max = generated
+		if defaultLength > max {
+			max = defaultLength
+		}
+	}
+	if max < generated {
+		return tokenLength{}, fmt.Errorf("%s token max length must be at least the token length %d", name, generated)
+	}
+
+	return tokenLength{generated: generated, max: max}, nil
+}
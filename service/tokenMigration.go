@@ -0,0 +1,416 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// TokenExportRecord is one line of a token export (NDJSON).
+//
+// Fields:
+//   - Type: Token type ("rt" or "prt")
+//   - UserID: Owner of the token
+//   - Token: The token, as stored by the Redis backend ("sha256.{hex}" for hashed refresh tokens)
+//   - Value: The stored value ("1" or a JSON record for refresh tokens, the token or
+//     a JSON record for password reset tokens)
+//   - ExpiresAt: When the token expires
+//
+// JSON serialization:
+//   - Example: {"type": "rt", "user_id": "123", "token": "aB3-...", "value": "1", "expires_at": "2025-01-01T13:00:00Z"}
+type TokenExportRecord struct {
+	Type      lib.TokenType `json:"type"`
+	UserID    string        `json:"user_id"`
+	Token     string        `json:"token"`
+	Value     string        `json:"value"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// defaultTokenPageSize is the number of keys loaded per page by IterateTokens.
+const defaultTokenPageSize int = 500
+
+// TokenFilter selects the tokens visited by IterateTokens.
+//
+// Fields:
+//   - Types: Token types to visit (lib.TokenTypeRefresh, lib.TokenTypePasswordReset), all if empty
+//   - UserID: Owner of the tokens, all users if empty
+//   - PageSize: Number of keys loaded per Redis round trip (default: 500)
+type TokenFilter struct {
+	Types    []lib.TokenType
+	UserID   string
+	PageSize int
+}
+
+// includes reports whether tokens of tokenType are selected.
+func (f TokenFilter) includes(tokenType lib.TokenType) bool {
+	return len(f.Types) == 0 || slices.Contains(f.Types, tokenType)
+}
+
+// TokenMigrator streams the refresh and password reset tokens in and out of Redis
+// as NDJSON, one TokenExportRecord per line, for migrations between Redis instances.
+// Services on a token store are supported as well, so tokens can move between Redis
+// and a store. Refresh tokens are exported from a store hashed ("sha256.{hex}").
+//
+// Security warning: the Redis backend stores tokens as they are issued, so an
+// export contains live tokens. Protect it like a Redis dump (encrypt it at rest
+// and delete it once imported).
+type TokenMigrator struct {
+	refresh *RefreshTokenService
+	reset   *PasswordResetService
+}
+
+// NewTokenMigrator creates a migrator over the given services.
+// A nil service is skipped on export and rejects its records on import.
+//
+// Example:
+//
+//	migrator := service.NewTokenMigrator(refreshService, resetService)
+//	count, err := migrator.ExportTokens(ctx, file)
+func NewTokenMigrator(refresh *RefreshTokenService, reset *PasswordResetService) *TokenMigrator {
+	return &TokenMigrator{
+		refresh: refresh,
+		reset:   reset,
+	}
+}
+
+// ExportTokens writes every unexpired refresh and password reset token to w,
+// one JSON record per line.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - w: Destination of the NDJSON stream
+//
+// Returns:
+//   - int: Number of exported tokens
+//   - error: Storage or write errors
+//
+// Example:
+//
+//	file, _ := os.Create("tokens.ndjson")
+//	defer file.Close()
+//	count, err := migrator.ExportTokens(ctx, file)
+func (tm *TokenMigrator) ExportTokens(ctx context.Context, w io.Writer) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	encoder := json.NewEncoder(w)
+	return tm.IterateTokens(ctx, TokenFilter{}, func(record TokenExportRecord) error {
+		return encoder.Encode(record)
+	})
+}
+
+// IterateTokens calls fn with every unexpired refresh and password reset token
+// matching filter, loading them one page at a time so that jobs over millions
+// of tokens (exports, re-hashing, audits) run with bounded memory.
+// Tokens created or revoked during the iteration may or may not be visited.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil), checked before each token
+//   - filter: Token types, user and page size
+//   - fn: Called for each token; a non-nil error stops the iteration and is returned
+//
+// Returns:
+//   - int: Number of tokens passed to fn without error
+//   - error: Storage, context or fn errors
+//
+// Example:
+//
+//	count, err := migrator.IterateTokens(ctx, service.TokenFilter{
+//	    Types: []lib.TokenType{lib.TokenTypeRefresh},
+//	}, func(record service.TokenExportRecord) error {
+//	    return archive.Write(record.UserID, record.ExpiresAt)
+//	})
+func (tm *TokenMigrator) IterateTokens(ctx context.Context, filter TokenFilter, fn func(TokenExportRecord) error) (int, error) {
+	if fn == nil {
+		return 0, errors.New("token callback is nil")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultTokenPageSize
+	}
+
+	// Stop as soon as the context is done
+	visit := func(record TokenExportRecord) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(record)
+	}
+
+	count := 0
+	if tm.refresh != nil && filter.includes(lib.TokenTypeRefresh) {
+		pattern := fmt.Sprintf("%s:*", tm.refresh.keys.name(redisStoreNameRefreshToken))
+		if filter.UserID != "" {
+			pattern = fmt.Sprintf("%s:%s:*", tm.refresh.keys.name(redisStoreNameRefreshToken), escapeScanPattern(filter.UserID))
+		}
+		var n int
+		var err error
+		if tm.refresh.store != nil {
+			n, err = tm.iterateStore(ctx, tm.refresh.store, lib.TokenTypeRefresh, filter.UserID, pageSize, visit)
+		} else {
+			n, err = tm.iterate(ctx, tm.refresh.db, lib.TokenTypeRefresh, pattern, pageSize, visit)
+		}
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	if tm.reset != nil && filter.includes(lib.TokenTypePasswordReset) {
+		pattern := fmt.Sprintf("%s:*", tm.reset.keys.name(redisStoreNamePasswordReset))
+		if filter.UserID != "" {
+			pattern = fmt.Sprintf("%s:%s", tm.reset.keys.name(redisStoreNamePasswordReset), escapeScanPattern(filter.UserID))
+		}
+		var n int
+		var err error
+		if tm.reset.store != nil {
+			n, err = tm.iterateStore(ctx, tm.reset.store, lib.TokenTypePasswordReset, filter.UserID, pageSize, visit)
+		} else {
+			n, err = tm.iterate(ctx, tm.reset.db, lib.TokenTypePasswordReset, pattern, pageSize, visit)
+		}
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// ImportTokens reads an export written by ExportTokens and stores its tokens with
+// their remaining TTL. Expired records are skipped, existing tokens are overwritten.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - r: Source of the NDJSON stream
+//
+// Returns:
+//   - int: Number of imported tokens
+//   - error: Malformed records (with their line number), read or storage errors
+//
+// Example:
+//
+//	file, _ := os.Open("tokens.ndjson")
+//	defer file.Close()
+//	count, err := migrator.ImportTokens(ctx, file)
+func (tm *TokenMigrator) ImportTokens(ctx context.Context, r io.Reader) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Records are a few hundred bytes, lines up to 1MB are accepted
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	count, line := 0, 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var record TokenExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		imported, err := tm.importRecord(ctx, record)
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		if imported {
+			count++
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+// iterate visits the keys matching pattern, one SCAN page at a time.
+func (tm *TokenMigrator) iterate(ctx context.Context, db *redis.Client, tokenType lib.TokenType, pattern string, pageSize int, fn func(TokenExportRecord) error) (int, error) {
+	count := 0
+	var cursor uint64
+	for {
+		keys, next, err := db.Scan(ctx, cursor, pattern, int64(pageSize)).Result()
+		if err != nil {
+			return count, err
+		}
+
+		n, err := tm.visitPage(ctx, db, tokenType, keys, fn)
+		count += n
+		if err != nil {
+			return count, err
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
+
+// iterateStore visits the tokens of a token store, those of userID only if set.
+func (tm *TokenMigrator) iterateStore(ctx context.Context, store TokenStore, tokenType lib.TokenType, userID string, pageSize int, fn func(TokenExportRecord) error) (int, error) {
+	count := 0
+	visit := func(tokens []StoredToken) error {
+		for _, token := range tokens {
+			record := TokenExportRecord{Type: tokenType, UserID: token.UserID, Value: token.Value, ExpiresAt: token.ExpiresAt.UTC()}
+			switch tokenType {
+			case lib.TokenTypeRefresh:
+				record.Token = refreshTokenHashedMarker + token.Hash
+			case lib.TokenTypePasswordReset:
+				reset, err := decodePasswordResetRecord(token.Value)
+				if err != nil {
+					return err
+				}
+				record.Token = reset.Token
+			}
+
+			if err := fn(record); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}
+
+	if userID == "" {
+		return count, store.Scan(ctx, pageSize, visit)
+	}
+	tokens, err := store.List(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return count, visit(tokens)
+}
+
+// visitPage loads the values and TTLs of a page of keys in one round trip and
+// passes the unexpired tokens to fn.
+func (tm *TokenMigrator) visitPage(ctx context.Context, db *redis.Client, tokenType lib.TokenType, keys []string, fn func(TokenExportRecord) error) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	values := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			values[i] = pipe.Get(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	count := 0
+	for i, key := range keys {
+		value, found, err := stringResult(values[i])
+		if err != nil {
+			return count, err
+		}
+		if !found {
+			// Expired since the scan
+			continue
+		}
+		ttl, err := ttls[i].Result()
+		if err != nil {
+			return count, err
+		}
+		if ttl <= 0 {
+			continue
+		}
+
+		record, err := tm.exportRecord(tokenType, key, value)
+		if err != nil {
+			return count, err
+		}
+		record.ExpiresAt = time.Now().Add(ttl).UTC()
+
+		if err := fn(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// exportRecord parses the owner and token out of a stored key and value.
+func (tm *TokenMigrator) exportRecord(tokenType lib.TokenType, key string, value string) (TokenExportRecord, error) {
+	record := TokenExportRecord{Type: tokenType, Value: value}
+
+	switch tokenType {
+	case lib.TokenTypeRefresh:
+		// "refresh:{userID}:{token}", tokens never contain ':'
+		rest := strings.TrimPrefix(key, tm.refresh.keys.name(redisStoreNameRefreshToken)+":")
+		separator := strings.LastIndex(rest, ":")
+		if separator < 0 {
+			return record, fmt.Errorf("malformed refresh token key %s", key)
+		}
+		record.UserID, record.Token = rest[:separator], rest[separator+1:]
+	case lib.TokenTypePasswordReset:
+		reset, err := decodePasswordResetRecord(value)
+		if err != nil {
+			return record, err
+		}
+		record.UserID = strings.TrimPrefix(key, tm.reset.keys.name(redisStoreNamePasswordReset)+":")
+		record.Token = reset.Token
+	}
+
+	return record, nil
+}
+
+// importRecord stores a record, returning false if it expired.
+func (tm *TokenMigrator) importRecord(ctx context.Context, record TokenExportRecord) (bool, error) {
+	if record.UserID == "" || record.Token == "" || record.Value == "" {
+		return false, fmt.Errorf("incomplete %s record", record.Type)
+	}
+
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	switch {
+	case record.Type == lib.TokenTypeRefresh && tm.refresh != nil:
+		return true, tm.refresh.storeToken(ctx, record.UserID, record.Token, record.Value, ttl)
+	case record.Type == lib.TokenTypePasswordReset && tm.reset != nil:
+		reset, err := decodePasswordResetRecord(record.Value)
+		if err != nil {
+			return false, err
+		}
+		if reset.Token != record.Token {
+			return false, errors.New("password reset record does not match token")
+		}
+		if tm.reset.store != nil {
+			stored := StoredToken{UserID: record.UserID, Hash: hashToken(record.Token), Value: record.Value, ExpiresAt: record.ExpiresAt}
+			err = tm.reset.store.Put(ctx, stored)
+			return err == nil, err
+		}
+		key := fmt.Sprintf("%s:%s", tm.reset.keys.name(redisStoreNamePasswordReset), record.UserID)
+		_, err = tm.reset.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, record.Value, ttl)
+			pipe.Set(ctx, tm.reset.lookupKey(record.Token), record.UserID, ttl)
+			return nil
+		})
+		return err == nil, err
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnknownTokenType, record.Type)
+	}
+}
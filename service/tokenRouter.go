@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// ErrUnknownTokenType is returned by TokenRouter when the token prefix does not
+// match a supported type and version.
+var ErrUnknownTokenType = errors.New("unknown token type")
+
+// TokenRouter dispatches the verification of structured tokens (Config.TokenPrefix)
+// to the service owning their type, so that an endpoint can accept any token kind.
+//
+// Routes:
+//   - "rt_v1_..." → RefreshTokenService.VerifyRefreshToken
+//   - "prt_v1_..." → PasswordResetService.VerifyPasswordResetToken
+type TokenRouter struct {
+	refresh *RefreshTokenService
+	reset   *PasswordResetService
+}
+
+// NewTokenRouter creates a router over the given services.
+// A nil service disables the routing of its token type.
+//
+// Example:
+//
+//	router := service.NewTokenRouter(refreshService, resetService)
+//	tokenType, valid, err := router.Verify(ctx, userID, token)
+func NewTokenRouter(refresh *RefreshTokenService, reset *PasswordResetService) *TokenRouter {
+	return &TokenRouter{
+		refresh: refresh,
+		reset:   reset,
+	}
+}
+
+// Verify checks the token with the service matching its prefix.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//   - token: A structured token (e.g. "prt_v1_...")
+//
+// Returns:
+//   - lib.TokenType: The type read from the prefix
+//   - bool: true if the owning service accepts the token
+//   - error: ErrUnknownTokenType for unprefixed, unknown or unrouted tokens,
+//     otherwise the errors of the owning service
+func (tr *TokenRouter) Verify(ctx context.Context, userID string, token string) (lib.TokenType, bool, error) {
+	tokenType, version, ok := lib.ParseTokenPrefix(token)
+	if !ok {
+		return "", false, ErrUnknownTokenType
+	}
+	if version != lib.TokenFormatVersion {
+		return tokenType, false, fmt.Errorf("%w: unsupported version %s", ErrUnknownTokenType, version)
+	}
+
+	switch {
+	case tokenType == lib.TokenTypeRefresh && tr.refresh != nil:
+		valid, err := tr.refresh.VerifyRefreshToken(ctx, userID, token)
+		return tokenType, valid, err
+	case tokenType == lib.TokenTypePasswordReset && tr.reset != nil:
+		valid, err := tr.reset.VerifyPasswordResetToken(ctx, userID, token)
+		return tokenType, valid, err
+	default:
+		return tokenType, false, fmt.Errorf("%w: %s", ErrUnknownTokenType, tokenType)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// ErrTokenStoreUnsupported is returned by the features relying on Redis (geo policy,
+// issued token filter, storage migration, short reset codes, quotas) when the service
+// was created on a TokenStore.
+var ErrTokenStoreUnsupported = errors.New("not supported by services created on a token store")
+
+// StoredToken is a token held by a TokenStore. Stores index the SHA-256 hash of the
+// token, never the token itself.
+//
+// Fields:
+//   - UserID: Owner of the token
+//   - Hash: Hex-encoded SHA-256 hash of the token
+//   - Value: Record of the token, opaque to the store (refresh token attributes, or
+//     the password reset record)
+//   - ExpiresAt: When the token expires; expired tokens are never returned
+type StoredToken struct {
+	UserID    string
+	Hash      string
+	Value     string
+	ExpiresAt time.Time
+}
+
+// TokenStore is the storage of the refresh and password reset services created with
+// NewRefreshTokenServiceWithStore and NewPasswordResetServiceWithStore, for deployments
+// keeping their tokens in a database rather than Redis. A token is identified by its
+// user and its hash; each service needs its own store (table or collection).
+// Implementations must be safe for concurrent use.
+//
+// Provided implementations:
+//   - NewMemoryTokenStore: In-process map, for tests and single-instance deployments
+//   - dynamostore.NewTokenStore: Amazon DynamoDB table (module github.com/bcetienne/tools-go-token/v4/dynamostore)
+//   - mongostore.NewTokenStore: MongoDB collection (module github.com/bcetienne/tools-go-token/v4/mongostore)
+type TokenStore interface {
+	// Put stores token, replacing the token of the same user and hash.
+	Put(ctx context.Context, token StoredToken) error
+	// Get returns the unexpired token of userID with hash, false if none.
+	Get(ctx context.Context, userID string, hash string) (StoredToken, bool, error)
+	// Find returns the unexpired token with hash whatever its user, false if none.
+	Find(ctx context.Context, hash string) (StoredToken, bool, error)
+	// List returns the unexpired tokens of userID.
+	List(ctx context.Context, userID string) ([]StoredToken, error)
+	// Delete deletes the tokens of userID with the given hashes and returns how many existed.
+	Delete(ctx context.Context, userID string, hashes ...string) (int64, error)
+	// Scan calls fn with the unexpired tokens of all users, at most count at a time,
+	// and stops at the first error of fn.
+	Scan(ctx context.Context, count int, fn func(tokens []StoredToken) error) error
+}
+
+// memoryTokenStore implements TokenStore in process memory. Expired tokens are
+// dropped when they are accessed or scanned.
+type memoryTokenStore struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]map[string]StoredToken // userID → hash → token
+	owners map[string]string                 // hash → userID
+}
+
+// NewMemoryTokenStore returns a TokenStore keeping its tokens in process memory, for
+// tests and single-instance deployments: the tokens are neither shared between
+// instances nor kept across restarts.
+//
+// Example:
+//
+//	refreshService, err := service.NewRefreshTokenServiceWithStore(ctx, service.NewMemoryTokenStore(), config)
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{
+		now:    time.Now,
+		tokens: make(map[string]map[string]StoredToken),
+		owners: make(map[string]string),
+	}
+}
+
+// token returns the live token of userID with hash, dropping it if expired. Callers hold mu.
+func (s *memoryTokenStore) token(userID string, hash string) (StoredToken, bool) {
+	token, ok := s.tokens[userID][hash]
+	if ok && !s.now().Before(token.ExpiresAt) {
+		s.remove(userID, hash)
+		return StoredToken{}, false
+	}
+	return token, ok
+}
+
+// remove deletes the token of userID with hash. Callers hold mu.
+func (s *memoryTokenStore) remove(userID string, hash string) {
+	delete(s.tokens[userID], hash)
+	if len(s.tokens[userID]) == 0 {
+		delete(s.tokens, userID)
+	}
+	if s.owners[hash] == userID {
+		delete(s.owners, hash)
+	}
+}
+
+func (s *memoryTokenStore) Put(_ context.Context, token StoredToken) error {
+	if token.UserID == "" || token.Hash == "" {
+		return errors.New("incomplete token")
+	}
+	if !token.ExpiresAt.After(s.now()) {
+		return fmt.Errorf("token of user %s already expired", token.UserID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens[token.UserID] == nil {
+		s.tokens[token.UserID] = make(map[string]StoredToken)
+	}
+	s.tokens[token.UserID][token.Hash] = token
+	s.owners[token.Hash] = token.UserID
+	return nil
+}
+
+func (s *memoryTokenStore) Get(_ context.Context, userID string, hash string) (StoredToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.token(userID, hash)
+	return token, ok, nil
+}
+
+func (s *memoryTokenStore) Find(_ context.Context, hash string) (StoredToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID, ok := s.owners[hash]
+	if !ok {
+		return StoredToken{}, false, nil
+	}
+	token, ok := s.token(userID, hash)
+	return token, ok, nil
+}
+
+func (s *memoryTokenStore) List(_ context.Context, userID string) ([]StoredToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []StoredToken
+	for hash := range s.tokens[userID] {
+		if token, ok := s.token(userID, hash); ok {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b StoredToken) int { return strings.Compare(a.Hash, b.Hash) })
+	return tokens, nil
+}
+
+func (s *memoryTokenStore) Delete(_ context.Context, userID string, hashes ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, hash := range hashes {
+		if _, ok := s.token(userID, hash); ok {
+			s.remove(userID, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryTokenStore) Scan(ctx context.Context, count int, fn func(tokens []StoredToken) error) error {
+	if count <= 0 {
+		count = defaultCleanupBatchSize
+	}
+
+	s.mu.Lock()
+	var tokens []StoredToken
+	for userID, hashes := range s.tokens {
+		for hash := range hashes {
+			if token, ok := s.token(userID, hash); ok {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(tokens, cmpStoredTokens)
+	for batch := range slices.Chunk(tokens, count) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cmpStoredTokens orders tokens by user, then hash.
+func cmpStoredTokens(a, b StoredToken) int {
+	if c := strings.Compare(a.UserID, b.UserID); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Hash, b.Hash)
+}
+
+// deleteStoredTokens deletes every token of a store batch by batch, like
+// deleteMatching on Redis. Batches are deleted sequentially (Parallelism is ignored),
+// and OperationTimeout bounds each deletion, the store driving the scan.
+func deleteStoredTokens(ctx context.Context, store TokenStore, opts CleanupOptions) (int64, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	var lease *lib.Lease
+	if opts.Lock != nil {
+		var err error
+		if lease, err = opts.Lock.Acquire(ctx); err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = lease.Release(context.WithoutCancel(ctx))
+		}()
+	}
+
+	var stats CleanupStats
+	err := store.Scan(ctx, batchSize, func(tokens []StoredToken) error {
+		if lease != nil {
+			if err := lease.Refresh(ctx); err != nil {
+				return err
+			}
+		}
+
+		hashes := make(map[string][]string)
+		for _, token := range tokens {
+			hashes[token.UserID] = append(hashes[token.UserID], token.Hash)
+		}
+		var deleted int64
+		for userID, userHashes := range hashes {
+			opCtx, cancel := opts.operationContext(ctx)
+			n, err := store.Delete(opCtx, userID, userHashes...)
+			cancel()
+			if err != nil {
+				return err
+			}
+			deleted += n
+		}
+		stats.Scanned += int64(len(tokens))
+		stats.Deleted += deleted
+		if opts.Progress != nil && deleted > 0 {
+			opts.Progress(stats.Deleted)
+		}
+		if opts.Report != nil {
+			opts.Report(stats)
+		}
+
+		if opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+		return nil
+	})
+	return stats.Deleted, err
+}
+
+// deleteUserStoredTokens deletes every token of a user from a store.
+func deleteUserStoredTokens(ctx context.Context, store TokenStore, userID string) (int64, error) {
+	tokens, err := store.List(ctx, userID)
+	if err != nil || len(tokens) == 0 {
+		return 0, err
+	}
+
+	hashes := make([]string, len(tokens))
+	for i, token := range tokens {
+		hashes[i] = token.Hash
+	}
+	return store.Delete(ctx, userID, hashes...)
+}
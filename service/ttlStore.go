@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revokeIfScript deletes KEYS[1] if its value is ARGV[1] (compare-and-delete).
+var revokeIfScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// ttlStore holds short-lived single values under a key prefix, all expiring
+// after the same TTL. It backs the OTP codes, the nonces and the stateful tokens.
+//
+// Redis key pattern:
+//   - Key: "{prefix}:{id}", or "{prefix}:{user:{id}}" with user hash tags
+//   - Value: set by the owning service
+//   - TTL: ttl, reset on each write
+type ttlStore struct {
+	db       *redis.Client
+	prefix   string
+	ttl      time.Duration
+	hashTags bool
+}
+
+func newTTLStore(db *redis.Client, prefix string, ttl time.Duration) *ttlStore {
+	return &ttlStore{
+		db:     db,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (ts *ttlStore) key(id string) string {
+	if ts.hashTags {
+		id = userHashTag(id)
+	}
+	return fmt.Sprintf("%s:%s", ts.prefix, id)
+}
+
+// set stores the value with a fresh TTL.
+func (ts *ttlStore) set(ctx context.Context, id string, value string) error {
+	return ts.db.Set(ctx, ts.key(id), value, ts.ttl).Err()
+}
+
+// get returns the value, false if it does not exist or expired.
+func (ts *ttlStore) get(ctx context.Context, id string) (string, bool, error) {
+	return stringResult(ts.db.Get(ctx, ts.key(id)))
+}
+
+// consume returns and deletes the value atomically, so that only one caller gets it.
+func (ts *ttlStore) consume(ctx context.Context, id string) (string, bool, error) {
+	return stringResult(ts.db.GetDel(ctx, ts.key(id)))
+}
+
+// swap stores the value with a fresh TTL and returns the previous value atomically.
+func (ts *ttlStore) swap(ctx context.Context, id string, value string) (string, bool, error) {
+	previous, err := ts.db.SetArgs(ctx, ts.key(id), value, redis.SetArgs{TTL: ts.ttl, Get: true}).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return previous, true, nil
+}
+
+// remaining returns how long the value lives, 0 if it does not exist.
+func (ts *ttlStore) remaining(ctx context.Context, id string) (time.Duration, error) {
+	ttl, err := ts.db.PTTL(ctx, ts.key(id)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Negative when the key doesn't exist (-2) or has no TTL (-1)
+	return max(ttl, 0), nil
+}
+
+// revoke deletes the value.
+func (ts *ttlStore) revoke(ctx context.Context, id string) error {
+	return ts.db.Del(ctx, ts.key(id)).Err()
+}
+
+// revokeIf deletes the value if it still equals value, so that a newer value is kept.
+func (ts *ttlStore) revokeIf(ctx context.Context, id string, value string) error {
+	return revokeIfScript.Run(ctx, ts.db, []string{ts.key(id)}, value).Err()
+}
+
+// revokeAll deletes the values of all ids.
+func (ts *ttlStore) revokeAll(ctx context.Context) error {
+	_, err := ts.revokeAllInBatches(ctx, CleanupOptions{})
+	return err
+}
+
+// revokeAllInBatches deletes the values of all ids in paced batches and returns how many were deleted.
+func (ts *ttlStore) revokeAllInBatches(ctx context.Context, opts CleanupOptions) (int64, error) {
+	opts.perKey = ts.hashTags
+	return deleteMatching(ctx, ts.db, fmt.Sprintf("%s:*", ts.prefix), opts)
+}
+
+// stringResult maps redis.Nil to a missing value.
+func stringResult(cmd *redis.StringCmd) (string, bool, error) {
+	val, err := cmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
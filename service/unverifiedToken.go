@@ -0,0 +1,65 @@
+package service
+
+import (
+	"encoding/json"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UnverifiedClaim holds the claims of a token decoded by ParseUnverified. Its
+// content is not authenticated: anyone can forge it. It is a distinct type from
+// modelAuth.Claim, without its methods (HasScope...), so that it cannot be passed
+// where verified claims are expected, e.g. to StepUpRequirement.Check.
+type UnverifiedClaim modelAuth.Claim
+
+// MarshalJSON serializes the claims like modelAuth.Claim, custom claims included.
+func (c UnverifiedClaim) MarshalJSON() ([]byte, error) {
+	return json.Marshal(modelAuth.Claim(c))
+}
+
+// UnverifiedToken is a token decoded by ParseUnverified.
+//
+// Fields:
+//   - Header: The JOSE header (alg, typ, kid...)
+//   - Claim: The claims, not verified
+type UnverifiedToken struct {
+	Header map[string]any
+	Claim  *UnverifiedClaim
+}
+
+// ParseUnverified decodes an access token without verifying its signature, its
+// expiration or its epochs, for debugging tools and for routers that need a claim
+// to select the verification key (e.g. the tenant from "iss"). Never authorize a
+// request with its result: verify the token with VerifyAccessToken afterwards.
+//
+// Parameters:
+//   - token: The token to decode
+//
+// Returns:
+//   - *UnverifiedToken: The header and claims of the token
+//   - error: jwt.ErrTokenMalformed if the token cannot be decoded
+//
+// Example:
+//
+//	unverified, err := service.ParseUnverified(token)
+//	if err != nil {
+//	    return err
+//	}
+//	accessService, ok := tenants[unverified.Claim.Issuer] // select the tenant
+//	if !ok {
+//	    return errors.New("unknown issuer")
+//	}
+//	claim, err := accessService.VerifyAccessToken(token)
+func ParseUnverified(token string) (*UnverifiedToken, error) {
+	claim := &modelAuth.Claim{}
+	t, _, err := jwt.NewParser().ParseUnverified(token, claim)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnverifiedToken{
+		Header: t.Header,
+		Claim:  (*UnverifiedClaim)(claim),
+	}, nil
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+// UserAuthData is the authentication data held about a user, for data-subject access
+// requests (GDPR article 15). Token values are never included, only their fingerprints
+// (see lib.TokenFingerprint).
+//
+// JSON serialization:
+//   - Example: {"user_id": "123", "exported_at": "2026-01-01T12:00:00Z",
+//     "refresh_tokens": [{"fingerprint": "9f86d081884c7d65", "expires_at": "2026-01-08T12:00:00Z"}],
+//     "refresh_token_last_use": {"country": "FR", "ip": "203.0.113.7", "last_used": "1767268800"},
+//     "refresh_token_revocations": [], "password_reset_revocations": [],
+//     "otp": {"failed_attempts": 2}}
+type UserAuthData struct {
+	UserID                   string             `json:"user_id"`
+	ExportedAt               time.Time          `json:"exported_at"`
+	RefreshTokens            []UserRefreshToken `json:"refresh_tokens"`
+	RefreshTokenLastUse      map[string]string  `json:"refresh_token_last_use,omitempty"`
+	RefreshTokenRevocations  []RevocationRecord `json:"refresh_token_revocations"`
+	PasswordReset            *UserPasswordReset `json:"password_reset,omitempty"`
+	PasswordResetRevocations []RevocationRecord `json:"password_reset_revocations"`
+	OTP                      *UserOTP           `json:"otp,omitempty"`
+}
+
+// UserRefreshToken describes a live refresh token of a user.
+//
+// Fields:
+//   - Fingerprint: Token fingerprint, see lib.TokenFingerprint
+//   - ExpiresAt: Expiration time
+//   - CertificateBound: true for tokens bound to a client certificate
+//   - Audiences: Audiences of scoped tokens, empty otherwise
+type UserRefreshToken struct {
+	Fingerprint      string    `json:"fingerprint"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CertificateBound bool      `json:"certificate_bound,omitempty"`
+	Audiences        []string  `json:"audiences,omitempty"`
+}
+
+// UserPasswordReset describes the pending password reset token of a user.
+//
+// Fields:
+//   - Fingerprint: Token fingerprint, see lib.TokenFingerprint
+//   - Scope: Why the token was issued
+//   - ExpiresAt: Expiration time
+type UserPasswordReset struct {
+	Fingerprint string             `json:"fingerprint"`
+	Scope       PasswordResetScope `json:"scope"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// UserOTP describes the OTP state of a user.
+//
+// Fields:
+//   - ExpiresAt: Expiration of the pending code, nil if none
+//   - FailedAttempts: Failed verifications of the current window
+type UserOTP struct {
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	FailedAttempts int        `json:"failed_attempts"`
+}
+
+// UserDataService answers data-subject requests across the token services: it exports
+// the token metadata of a user and erases it in one call.
+//
+// The revocation feeds read by ExportRevokedRefreshTokens only hold token hashes, not
+// user identifiers; they are kept by EraseUser so that edge caches keep rejecting the
+// revoked tokens, and expire with the RetentionPolicy of the services. Audit events sent
+// to an AuditLogger are stored by the application and must be erased there.
+type UserDataService struct {
+	refresh *RefreshTokenService
+	reset   *PasswordResetService
+	otp     *OTPService
+
+	// mu guards the audit logger, which can be set while requests are handled.
+	mu    sync.RWMutex
+	audit lib.AuditLogger
+}
+
+// NewUserDataService creates a data-subject request service over the configured
+// services. A nil service is skipped. Accepts WithLogger.
+//
+// Example:
+//
+//	userData := service.NewUserDataService(refreshService, resetService, otpService)
+//	data, err := userData.ExportUserAuthData(ctx, userID)
+//	if err != nil {
+//	    return err
+//	}
+//	json.NewEncoder(w).Encode(data)
+func NewUserDataService(refresh *RefreshTokenService, reset *PasswordResetService, otp *OTPService, opts ...Option) *UserDataService {
+	return &UserDataService{
+		refresh: refresh,
+		reset:   reset,
+		otp:     otp,
+		audit:   newServiceOptions(opts).audit,
+	}
+}
+
+// SetAuditLogger configures the logger receiving the "user.data_erased" audit event.
+// A nil logger disables auditing.
+func (uds *UserDataService) SetAuditLogger(logger lib.AuditLogger) {
+	uds.mu.Lock()
+	defer uds.mu.Unlock()
+	uds.audit = logger
+}
+
+func (uds *UserDataService) auditLogger() lib.AuditLogger {
+	uds.mu.RLock()
+	defer uds.mu.RUnlock()
+	return uds.audit
+}
+
+// ExportUserAuthData returns the token metadata held about a user by every configured
+// service: live refresh tokens, last refresh token use, pending password reset, OTP
+// state and revocation logs.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - *UserAuthData: The user data, with empty lists when nothing is held
+//   - error: Validation or storage errors
+func (uds *UserDataService) ExportUserAuthData(ctx context.Context, userID string) (*UserAuthData, error) {
+	if userID == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	data := &UserAuthData{
+		UserID:                   userID,
+		ExportedAt:               time.Now().UTC(),
+		RefreshTokens:            []UserRefreshToken{},
+		RefreshTokenRevocations:  []RevocationRecord{},
+		PasswordResetRevocations: []RevocationRecord{},
+	}
+
+	if uds.refresh != nil {
+		if err := uds.refresh.exportUserData(ctx, userID, data); err != nil {
+			return nil, fmt.Errorf("failed to export refresh tokens: %w", err)
+		}
+	}
+	if uds.reset != nil {
+		if err := uds.reset.exportUserData(ctx, userID, data); err != nil {
+			return nil, fmt.Errorf("failed to export password reset tokens: %w", err)
+		}
+	}
+	if uds.otp != nil {
+		if err := uds.otp.exportUserData(ctx, userID, data); err != nil {
+			return nil, fmt.Errorf("failed to export otp: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+// EraseUser hard-deletes the data held about a user by every configured service
+// (GDPR article 17): refresh tokens and their last use, password reset token and quota,
+// OTP code and attempts, and revocation logs. A "user.data_erased" audit event records
+// the erasure, without other personal data than the user identifier.
+//
+// Every service is erased even if a previous one failed, errors are joined.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - userID: User identifier as string (UUID, numeric ID, or any unique identifier)
+//
+// Returns:
+//   - error: Validation errors, or joined storage errors of the failed services
+//
+// Example:
+//
+//	if err := userData.EraseUser(ctx, userID); err != nil {
+//	    return fmt.Errorf("erasure incomplete, retry: %w", err)
+//	}
+func (uds *UserDataService) EraseUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var errs []error
+	if uds.refresh != nil {
+		if err := uds.refresh.eraseUserData(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to erase refresh tokens: %w", err))
+		}
+	}
+	if uds.reset != nil {
+		if err := uds.reset.eraseUserData(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to erase password reset tokens: %w", err))
+		}
+	}
+	if uds.otp != nil {
+		if err := uds.otp.RevokeOTP(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to erase otp: %w", err))
+		}
+	}
+
+	err := errors.Join(errs...)
+	emitAudit(ctx, uds.auditLogger(), AuditEventUserDataErased, userID, map[string]string{
+		"complete": strconv.FormatBool(err == nil),
+	})
+	return err
+}
+
+// exportUserData adds the live refresh tokens, last use and revocation log of a user.
+func (rts *RefreshTokenService) exportUserData(ctx context.Context, userID string, data *UserAuthData) error {
+	if rts.store != nil {
+		tokens, err := rts.store.List(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if err := exportRefreshToken(data, token.Hash, token.Value, token.ExpiresAt.UTC()); err != nil {
+				return err
+			}
+		}
+		// Neither last use nor revocation log without Redis
+		data.RefreshTokenRevocations = []RevocationRecord{}
+		return nil
+	}
+
+	prefix := fmt.Sprintf("%s:%s:", rts.keys.name(redisStoreNameRefreshToken), userID)
+	var cursor uint64
+	for {
+		keys, next, err := rts.db.Scan(ctx, cursor, escapeScanPattern(prefix)+"*", int64(defaultCleanupBatchSize)).Result()
+		if err != nil {
+			return err
+		}
+		if err := rts.exportUserTokens(ctx, prefix, keys, data); err != nil {
+			return err
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	lastUse, err := rts.db.HGetAll(ctx, fmt.Sprintf("%s:%s", rts.keys.name(redisStoreNameRefreshTokenMeta), userID)).Result()
+	if err != nil {
+		return err
+	}
+	if len(lastUse) > 0 {
+		data.RefreshTokenLastUse = lastUse
+	}
+
+	revocations, err := rts.revocations().list(ctx, userID)
+	if err != nil {
+		return err
+	}
+	data.RefreshTokenRevocations = revocations
+	return nil
+}
+
+// exportUserTokens loads a page of refresh token keys in one round trip. Canary
+// tokens are not user data and are skipped.
+func (rts *RefreshTokenService) exportUserTokens(ctx context.Context, prefix string, keys []string, data *UserAuthData) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	values := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := rts.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			values[i] = pipe.Get(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i, key := range keys {
+		value, found, err := stringResult(values[i])
+		if err != nil {
+			return err
+		}
+		ttl, err := ttls[i].Result()
+		if err != nil {
+			return err
+		}
+		if !found || ttl <= 0 {
+			continue // Expired since the scan
+		}
+
+		if err := exportRefreshToken(data, storedTokenHash(strings.TrimPrefix(key, prefix)), value, now.Add(ttl)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportRefreshToken adds a refresh token, identified by its hash, to the user data.
+// Canary tokens are skipped.
+func exportRefreshToken(data *UserAuthData, hash string, value string, expiresAt time.Time) error {
+	record, err := decodeRefreshTokenRecord(value)
+	if err != nil {
+		return err
+	}
+	if record.Canary != "" {
+		return nil
+	}
+	data.RefreshTokens = append(data.RefreshTokens, UserRefreshToken{
+		Fingerprint:      hash[:lib.TokenFingerprintLength],
+		ExpiresAt:        expiresAt,
+		CertificateBound: record.Thumbprint != "",
+		Audiences:        record.Audiences,
+	})
+	return nil
+}
+
+// eraseUserData deletes the refresh tokens with their lookup entries, the last use
+// and the revocation log of a user.
+func (rts *RefreshTokenService) eraseUserData(ctx context.Context, userID string) error {
+	if err := rts.RevokeAllUserRefreshTokens(ctx, userID); err != nil || rts.db == nil {
+		return err
+	}
+	return rts.db.Del(ctx,
+		fmt.Sprintf("%s:%s", rts.keys.name(redisStoreNameRefreshTokenMeta), userID),
+		rts.revocations().key(userID),
+	).Err()
+}
+
+// exportUserData adds the pending reset token and revocation log of a user.
+func (prs *PasswordResetService) exportUserData(ctx context.Context, userID string, data *UserAuthData) error {
+	if prs.store != nil {
+		stored, err := prs.storedToken(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			record, err := decodePasswordResetRecord(stored.Value)
+			if err != nil {
+				return err
+			}
+			data.PasswordReset = &UserPasswordReset{
+				Fingerprint: lib.TokenFingerprint(record.Token),
+				Scope:       record.Scope,
+				ExpiresAt:   stored.ExpiresAt.UTC(),
+			}
+		}
+		data.PasswordResetRevocations = []RevocationRecord{}
+		return nil
+	}
+
+	key := fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID)
+	value, found, err := stringResult(prs.db.Get(ctx, key))
+	if err != nil {
+		return err
+	}
+	if found {
+		ttl, err := prs.db.PTTL(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		record, err := decodePasswordResetRecord(value)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			data.PasswordReset = &UserPasswordReset{
+				Fingerprint: lib.TokenFingerprint(record.Token),
+				Scope:       record.Scope,
+				ExpiresAt:   time.Now().UTC().Add(ttl),
+			}
+		}
+	}
+
+	revocations, err := prs.revocations().list(ctx, userID)
+	if err != nil {
+		return err
+	}
+	data.PasswordResetRevocations = revocations
+	return nil
+}
+
+// eraseUserData deletes the reset token, its lookup entry, the quota counter and the
+// revocation log of a user.
+func (prs *PasswordResetService) eraseUserData(ctx context.Context, userID string) error {
+	if prs.store != nil {
+		_, err := deleteUserStoredTokens(ctx, prs.store, userID)
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordReset), userID)
+	keys := []string{
+		key,
+		fmt.Sprintf("%s:%s", prs.keys.name(redisStoreNamePasswordResetQuota), userID),
+		prs.revocations().key(userID),
+	}
+
+	value, found, err := stringResult(prs.db.Get(ctx, key))
+	if err != nil {
+		return err
+	}
+	if found {
+		record, err := decodePasswordResetRecord(value)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, prs.lookupKey(record.Token))
+	}
+
+	return prs.db.Del(ctx, keys...).Err()
+}
+
+// exportUserData adds the OTP state of a user, if any.
+func (otps *OTPService) exportUserData(ctx context.Context, userID string, data *UserAuthData) error {
+	remaining, err := otps.storage.remaining(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attempts, err := otps.storage.failedAttempts(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if remaining <= 0 && attempts == 0 {
+		return nil
+	}
+
+	data.OTP = &UserOTP{FailedAttempts: attempts}
+	if remaining > 0 {
+		expiresAt := time.Now().UTC().Add(remaining)
+		data.OTP.ExpiresAt = &expiresAt
+	}
+	return nil
+}
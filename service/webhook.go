@@ -0,0 +1,445 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStoreNameWebhookOutbox is the Redis key of the webhook outbox.
+	// Key pattern: "webhook_outbox", a sorted set of JSON deliveries scored by
+	// their next attempt time (Unix milliseconds).
+	redisStoreNameWebhookOutbox string = "webhook_outbox"
+
+	// redisStoreNameWebhookDeadLetter is the Redis key of the undeliverable webhooks.
+	// Key pattern: "webhook_outbox:dead", a list of JSON deliveries, most recent first.
+	redisStoreNameWebhookDeadLetter string = "webhook_outbox:dead"
+
+	defaultWebhookMaxAttempts int           = 8
+	defaultWebhookBackoff     time.Duration = 30 * time.Second
+	defaultWebhookTimeout     time.Duration = 10 * time.Second
+
+	// webhookLease is how long a claimed delivery is hidden from other workers.
+	// A worker crashing mid-delivery releases it once the lease expires.
+	webhookLease time.Duration = time.Minute
+
+	// webhookBatchSize is the number of due deliveries claimed by ProcessDue.
+	webhookBatchSize int64 = 100
+)
+
+// DefaultWebhookEvents are the events delivered to endpoints not listing their own:
+// revocations and rate limiting.
+var DefaultWebhookEvents = []lib.AuditEventType{
+	AuditEventRefreshTokenRevoked,
+	AuditEventPasswordResetRevoked,
+	AuditEventTokenLeakReported,
+	AuditEventEmergencyRevokeAll,
+	AuditEventLoginLocked,
+	AuditEventLoginIPLocked,
+}
+
+// claimWebhookScript leases a delivery if it is still due, so that concurrent
+// workers never deliver the same entry at the same time.
+var claimWebhookScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score and tonumber(score) <= tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// WebhookEndpoint is an external URL notified about token lifecycle events.
+//
+// Fields:
+//   - URL: Endpoint receiving POST requests
+//   - Secret: HMAC-SHA256 key used to sign the request bodies
+//   - Events: Events delivered to the endpoint (DefaultWebhookEvents if empty)
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+	Events []lib.AuditEventType
+}
+
+// webhookDelivery is an outbox entry: one event for one endpoint.
+//
+// JSON serialization:
+//   - Example: {"id": "aB3-...", "url": "https://...", "event": {...}, "attempts": 2, "last_error": "status 503"}
+type webhookDelivery struct {
+	ID        string         `json:"id"`
+	URL       string         `json:"url"`
+	Event     lib.AuditEvent `json:"event"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"last_error,omitempty"`
+}
+
+// WebhookOutbox reliably notifies external URLs about token lifecycle events.
+// It is an AuditLogger: plugged into the services with SetAuditLogger, it stores
+// the matching events in a Redis outbox, delivered by a worker (Run or ProcessDue).
+//
+// Delivery guarantees:
+//   - Events are persisted before delivery, surviving process restarts
+//   - At-least-once: receivers should deduplicate on the X-Webhook-ID header
+//   - Failed deliveries (network error or non-2xx status) are retried with
+//     exponential backoff, then moved to the dead-letter list
+//   - Several workers can share the outbox, each delivery is leased to one worker
+//
+// Each request is a JSON POST of the audit event, with headers:
+//   - X-Webhook-ID: Delivery identifier, identical across retries
+//   - X-Webhook-Signature: "sha256=" followed by the hex HMAC-SHA256 of the body
+//
+// Redis key patterns:
+//   - Outbox: "webhook_outbox" → sorted set of deliveries scored by next attempt time
+//   - Dead letters: "webhook_outbox:dead" → list of deliveries (no TTL)
+type WebhookOutbox struct {
+	db        *redis.Client
+	keys      keyPrefix
+	endpoints []WebhookEndpoint
+
+	// mu guards the delivery settings, which can be changed while Run is delivering.
+	mu          sync.RWMutex
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewWebhookOutbox creates a webhook outbox delivering to the given endpoints.
+// Returns an error if the database client is nil or an endpoint has no URL or secret.
+//
+// Parameters:
+//   - ctx: Context for initialization (uses Background if nil)
+//   - db: Redis client for the outbox
+//   - endpoints: Endpoints to notify
+//   - opts: Optional settings (WithKeyPrefix)
+//
+// Returns:
+//   - *WebhookOutbox: Outbox ready for use
+//   - error: Configuration or database validation errors
+//
+// Example:
+//
+//	outbox, err := service.NewWebhookOutbox(ctx, redisClient, []service.WebhookEndpoint{
+//	    {URL: "https://siem.example.com/hooks/tokens", Secret: os.Getenv("WEBHOOK_SECRET")},
+//	})
+//	refreshService.SetAuditLogger(outbox)
+//	loginService.SetAuditLogger(outbox)
+//	go outbox.Run(ctx, 5*time.Second)
+func NewWebhookOutbox(ctx context.Context, db *redis.Client, endpoints []WebhookEndpoint, opts ...Option) (*WebhookOutbox, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			return nil, errors.New("webhook url is empty")
+		}
+		if endpoint.Secret == "" {
+			return nil, fmt.Errorf("webhook secret is empty for %s", endpoint.URL)
+		}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &WebhookOutbox{
+		db:          db,
+		keys:        newServiceOptions(opts).keyPrefix,
+		endpoints:   endpoints,
+		client:      &http.Client{Timeout: defaultWebhookTimeout},
+		maxAttempts: defaultWebhookMaxAttempts,
+		backoff:     defaultWebhookBackoff,
+	}, nil
+}
+
+// SetHTTPClient replaces the HTTP client used for deliveries (default: 10s timeout).
+func (wo *WebhookOutbox) SetHTTPClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+	wo.mu.Lock()
+	defer wo.mu.Unlock()
+	wo.client = client
+}
+
+// SetRetryPolicy configures the retries: a failed delivery is retried after backoff,
+// doubled on each attempt, and dead-lettered after maxAttempts attempts
+// (default: 8 attempts, 30s backoff).
+func (wo *WebhookOutbox) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	wo.mu.Lock()
+	defer wo.mu.Unlock()
+	if maxAttempts > 0 {
+		wo.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		wo.backoff = backoff
+	}
+}
+
+// LogAuditEvent implements lib.AuditLogger by enqueuing the event.
+// Enqueue errors are dropped, use Enqueue to handle them.
+func (wo *WebhookOutbox) LogAuditEvent(ctx context.Context, event lib.AuditEvent) {
+	_ = wo.Enqueue(ctx, event)
+}
+
+// Enqueue stores a delivery of the event for every endpoint subscribed to its type.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//   - event: The audit event to deliver
+//
+// Returns:
+//   - error: Storage errors
+func (wo *WebhookOutbox) Enqueue(ctx context.Context, event lib.AuditEvent) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	members := []redis.Z{}
+	now := float64(time.Now().UnixMilli())
+	for _, endpoint := range wo.endpoints {
+		if !endpoint.subscribes(event.Type) {
+			continue
+		}
+
+		id, err := lib.GenerateRandomString(32)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(webhookDelivery{ID: id, URL: endpoint.URL, Event: event})
+		if err != nil {
+			return err
+		}
+		members = append(members, redis.Z{Score: now, Member: data})
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+	return wo.db.ZAdd(ctx, wo.keys.name(redisStoreNameWebhookOutbox), members...).Err()
+}
+
+// ProcessDue delivers the deliveries whose next attempt time has come, up to 100 per call.
+//
+// Parameters:
+//   - ctx: Context for the operation (uses Background if nil)
+//
+// Returns:
+//   - int: Number of successful deliveries
+//   - error: Storage errors (delivery failures are retried, not returned)
+//
+// Example:
+//
+//	// From a cron job instead of Run
+//	delivered, err := outbox.ProcessDue(ctx)
+func (wo *WebhookOutbox) ProcessDue(ctx context.Context) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now()
+	members, err := wo.db.ZRangeByScore(ctx, wo.keys.name(redisStoreNameWebhookOutbox), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: webhookBatchSize,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, member := range members {
+		claimed, err := claimWebhookScript.Run(ctx, wo.db, []string{wo.keys.name(redisStoreNameWebhookOutbox)},
+			member, now.UnixMilli(), now.Add(webhookLease).UnixMilli()).Int()
+		if err != nil {
+			return delivered, err
+		}
+		if claimed == 0 {
+			// Claimed by another worker
+			continue
+		}
+
+		var delivery webhookDelivery
+		if err := json.Unmarshal([]byte(member), &delivery); err != nil {
+			if err := wo.deadLetter(ctx, member, member); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		deliveryErr := wo.deliver(ctx, delivery)
+		if deliveryErr == nil {
+			if err := wo.db.ZRem(ctx, wo.keys.name(redisStoreNameWebhookOutbox), member).Err(); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		if err := wo.retry(ctx, member, delivery, deliveryErr); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// Run calls ProcessDue every interval until ctx is cancelled.
+// Storage errors are retried on the next tick, call ProcessDue directly to observe them.
+//
+// Returns:
+//   - error: The context error once cancelled, or an invalid interval
+func (wo *WebhookOutbox) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("webhook interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = wo.ProcessDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pending returns the number of deliveries waiting in the outbox, retries included.
+func (wo *WebhookOutbox) Pending(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return wo.db.ZCard(ctx, wo.keys.name(redisStoreNameWebhookOutbox)).Result()
+}
+
+// DeadLetters returns the number of deliveries abandoned after the last retry.
+func (wo *WebhookOutbox) DeadLetters(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return wo.db.LLen(ctx, wo.keys.name(redisStoreNameWebhookDeadLetter)).Result()
+}
+
+// deliver posts the signed event to the endpoint.
+func (wo *WebhookOutbox) deliver(ctx context.Context, delivery webhookDelivery) error {
+	endpoint, ok := wo.endpoint(delivery.URL)
+	if !ok {
+		return fmt.Errorf("webhook endpoint %s is not configured", delivery.URL)
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-ID", delivery.ID)
+	request.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(endpoint.Secret, body))
+
+	wo.mu.RLock()
+	client := wo.client
+	wo.mu.RUnlock()
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// retry reschedules a failed delivery with exponential backoff, or dead-letters
+// it after the last attempt.
+func (wo *WebhookOutbox) retry(ctx context.Context, member string, delivery webhookDelivery, deliveryErr error) error {
+	delivery.Attempts++
+	delivery.LastError = deliveryErr.Error()
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	wo.mu.RLock()
+	maxAttempts, backoff := wo.maxAttempts, wo.backoff
+	wo.mu.RUnlock()
+
+	if delivery.Attempts >= maxAttempts {
+		return wo.deadLetter(ctx, member, string(data))
+	}
+
+	// Capped shift, the backoff stops doubling after 16 attempts
+	next := time.Now().Add(backoff << min(delivery.Attempts-1, 16))
+	_, err = wo.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, wo.keys.name(redisStoreNameWebhookOutbox), member)
+		pipe.ZAdd(ctx, wo.keys.name(redisStoreNameWebhookOutbox), redis.Z{Score: float64(next.UnixMilli()), Member: data})
+		return nil
+	})
+	return err
+}
+
+// deadLetter moves an outbox entry to the dead-letter list.
+func (wo *WebhookOutbox) deadLetter(ctx context.Context, member string, value string) error {
+	_, err := wo.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, wo.keys.name(redisStoreNameWebhookOutbox), member)
+		pipe.LPush(ctx, wo.keys.name(redisStoreNameWebhookDeadLetter), value)
+		return nil
+	})
+	return err
+}
+
+func (wo *WebhookOutbox) endpoint(url string) (WebhookEndpoint, bool) {
+	for _, endpoint := range wo.endpoints {
+		if endpoint.URL == url {
+			return endpoint, true
+		}
+	}
+	return WebhookEndpoint{}, false
+}
+
+func (we WebhookEndpoint) subscribes(eventType lib.AuditEventType) bool {
+	if len(we.Events) == 0 {
+		return slices.Contains(DefaultWebhookEvents, eventType)
+	}
+	return slices.Contains(we.Events, eventType)
+}
+
+// SignWebhookPayload returns the hex-encoded HMAC-SHA256 signature of a webhook body,
+// for receivers checking the X-Webhook-Signature header (constant-time comparison).
+//
+// Example:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	expected := "sha256=" + service.SignWebhookPayload(secret, body)
+//	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Webhook-Signature"))) {
+//	    w.WriteHeader(http.StatusUnauthorized)
+//	    return
+//	}
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// defaultBulkParallelism is the number of concurrent operations of the bulk
+// revocations when the caller does not choose.
+const defaultBulkParallelism int = 8
+
+// forEachParallel calls fn for each item with at most parallelism calls running at
+// once (1 when not positive) and returns the joined errors of the failed calls, in
+// item order. Once ctx is done, the remaining items are not started and the context
+// error is returned with the others.
+func forEachParallel[T any](ctx context.Context, items []T, parallelism int, fn func(ctx context.Context, item T) error) error {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	errs := make([]error, len(items)+1)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if err := ctx.Err(); err != nil {
+			errs[len(items)] = err
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(ctx, item)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
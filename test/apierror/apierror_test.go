@@ -0,0 +1,127 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/apierror"
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/validation"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   apierror.Code
+	}{
+		{"Expired token", fmt.Errorf("%w: %w", jwt.ErrTokenInvalidClaims, jwt.ErrTokenExpired), http.StatusUnauthorized, apierror.CodeTokenExpired},
+		{"Malformed token", jwt.ErrTokenMalformed, http.StatusUnauthorized, apierror.CodeTokenInvalid},
+		{"Revoked by epoch", service.ErrTokenEpochRevoked, http.StatusUnauthorized, apierror.CodeTokenRevoked},
+		{"One-time-use token replayed", service.ErrTokenReplayed, http.StatusUnauthorized, apierror.CodeTokenRevoked},
+		{"Step-up", fmt.Errorf("%w: assurance level aal2 required", service.ErrInsufficientUserAuthentication), http.StatusUnauthorized, apierror.CodeInsufficientUserAuthentication},
+		{"Claim rejected", &service.ClaimValidationError{Validator: "tenant", Err: errors.New("wrong tenant")}, http.StatusForbidden, apierror.CodeClaimRejected},
+		{"Geo denied", service.ErrGeoDenied, http.StatusForbidden, apierror.CodeAccessDenied},
+		{"Rate limited", service.ErrMaxAttemptsExceeded, http.StatusTooManyRequests, apierror.CodeRateLimited},
+		{"Reset quota", &service.PasswordResetQuotaError{Limit: 3, RetryAfter: time.Minute}, http.StatusTooManyRequests, apierror.CodeRateLimited},
+		{"Invalid user", service.ErrInvalidUserID, http.StatusBadRequest, apierror.CodeInvalidRequest},
+		{"Lock held", lib.ErrLockNotAcquired, http.StatusConflict, apierror.CodeConflict},
+		{"Unknown", errors.New("boom"), http.StatusInternalServerError, apierror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := apierror.From(tt.err)
+			require.NotNil(t, apiErr)
+			assert.Equal(t, tt.status, apiErr.Status)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.NotEmpty(t, apiErr.Message)
+			assert.Equal(t, tt.status, apierror.Status(tt.err))
+		})
+	}
+
+	t.Run("Should keep the code of validation errors", func(t *testing.T) {
+		apiErr := apierror.From(validation.IsIncomingTokenValid("", 64))
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+		assert.Equal(t, apierror.Code(validation.CodeTokenEmpty), apiErr.Code)
+	})
+
+	t.Run("Should return nil without error", func(t *testing.T) {
+		assert.Nil(t, apierror.From(nil))
+		assert.Equal(t, http.StatusOK, apierror.Status(nil))
+	})
+}
+
+func TestWriteJSONError(t *testing.T) {
+	t.Run("Should write the status and the code without internal details", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		apierror.WriteJSONError(rec, fmt.Errorf("redis key otp:123: %w", service.ErrMaxAttemptsExceeded))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, map[string]string{"code": "RATE_LIMITED", "message": "too many attempts"}, body)
+	})
+
+	t.Run("Should write nothing without error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		apierror.WriteJSONError(rec, nil)
+		assert.Empty(t, rec.Body.String())
+	})
+}
+
+func TestWriteProblem(t *testing.T) {
+	t.Run("Should write problem details with the rate limiting members", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		apierror.WriteProblem(rec, service.ErrMaxAttemptsExceeded,
+			apierror.WithRateLimit(service.RateLimitState{Limit: 5, Remaining: 0, Reset: 539500 * time.Millisecond}),
+			apierror.WithInstance("/otp/verify"),
+		)
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, apierror.ProblemContentType, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "540", rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Too Many Requests",
+			"status": 429,
+			"detail": "too many attempts",
+			"instance": "/otp/verify",
+			"code": "RATE_LIMITED",
+			"remaining_attempts": 0,
+			"retry_after": 540
+		}`, rec.Body.String())
+	})
+
+	t.Run("Should omit retry_after while attempts are left", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		apierror.WriteProblem(rec, service.ErrInvalidOTP,
+			apierror.WithRateLimit(service.RateLimitState{Limit: 5, Remaining: 3, Reset: time.Minute}),
+		)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "invalid one-time password", "code": "OTP_INVALID", "remaining_attempts": 3}`, rec.Body.String())
+	})
+
+	t.Run("Should write nothing without error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		apierror.WriteProblem(rec, nil)
+		assert.Empty(t, rec.Body.String())
+		assert.Nil(t, apierror.NewProblem(nil))
+	})
+}
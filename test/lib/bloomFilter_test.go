@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_BloomFilter(t *testing.T) {
+	t.Run("Success: No false negative", func(t *testing.T) {
+		filter, err := lib.NewBloomFilter(1000, 0.01)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i := range 1000 {
+			filter.Add(fmt.Sprintf("token-%d", i))
+		}
+		for i := range 1000 {
+			if !filter.MightContain(fmt.Sprintf("token-%d", i)) {
+				t.Fatalf("token-%d was added but is reported absent", i)
+			}
+		}
+		if filter.Count() != 1000 {
+			t.Fatalf("Expected 1000 items, got %d", filter.Count())
+		}
+	})
+
+	t.Run("Success: False positive rate close to the target", func(t *testing.T) {
+		filter, err := lib.NewBloomFilter(10_000, 0.01)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i := range 10_000 {
+			filter.Add(fmt.Sprintf("issued-%d", i))
+		}
+
+		falsePositives := 0
+		for i := range 100_000 {
+			if filter.MightContain(fmt.Sprintf("never-issued-%d", i)) {
+				falsePositives++
+			}
+		}
+		if rate := float64(falsePositives) / 100_000; rate > 0.02 {
+			t.Fatalf("Expected a false positive rate around 0.01, got %f", rate)
+		}
+		if estimate := filter.FalsePositiveRate(); estimate < 0.005 || estimate > 0.015 {
+			t.Fatalf("Expected an estimated rate around 0.01, got %f", estimate)
+		}
+
+		for i := range 10_000 {
+			filter.Add(fmt.Sprintf("overflow-%d", i))
+		}
+		if estimate := filter.FalsePositiveRate(); estimate < 0.1 {
+			t.Fatalf("Expected the estimated rate to grow beyond the capacity, got %f", estimate)
+		}
+	})
+
+	t.Run("Error: Invalid sizing", func(t *testing.T) {
+		if _, err := lib.NewBloomFilter(0, 0.01); err == nil {
+			t.Fatal("Expected an error for a zero capacity")
+		}
+		for _, rate := range []float64{0, 1, -0.1} {
+			if _, err := lib.NewBloomFilter(100, rate); err == nil {
+				t.Fatalf("Expected an error for rate %f", rate)
+			}
+		}
+	})
+}
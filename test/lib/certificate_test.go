@@ -0,0 +1,31 @@
+package lib
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_CertificateThumbprint(t *testing.T) {
+	t.Run("Success: SHA-256 base64url thumbprint", func(t *testing.T) {
+		cert := &x509.Certificate{Raw: []byte("certificate")}
+		thumbprint := lib.CertificateThumbprint(cert)
+		// 32 bytes SHA-256 digest, base64url without padding
+		if len(thumbprint) != 43 {
+			t.Fatalf("Unexpected thumbprint length %d", len(thumbprint))
+		}
+		if thumbprint != lib.CertificateThumbprint(&x509.Certificate{Raw: []byte("certificate")}) {
+			t.Fatal("The thumbprint should be deterministic")
+		}
+		if thumbprint == lib.CertificateThumbprint(&x509.Certificate{Raw: []byte("other")}) {
+			t.Fatal("Different certificates should have different thumbprints")
+		}
+	})
+
+	t.Run("Fail: Nil certificate", func(t *testing.T) {
+		if lib.CertificateThumbprint(nil) != "" {
+			t.Fatal("A nil certificate should have an empty thumbprint")
+		}
+	})
+}
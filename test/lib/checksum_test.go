@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_TokenChecksum(t *testing.T) {
+	for _, secret := range []string{"", "checksum-secret"} {
+		t.Run("Success: Valid checksum with secret "+secret, func(t *testing.T) {
+			token := lib.AppendTokenChecksum("aB3-random-token", secret)
+			if len(token) != len("aB3-random-token")+lib.TokenChecksumLength {
+				t.Fatalf("Unexpected token length %d", len(token))
+			}
+			if !lib.HasValidTokenChecksum(token, secret) {
+				t.Fatal("The checksum should be valid")
+			}
+		})
+
+		t.Run("Fail: Corrupted token with secret "+secret, func(t *testing.T) {
+			token := lib.AppendTokenChecksum("aB3-random-token", secret)
+			if lib.HasValidTokenChecksum("x"+token[1:], secret) {
+				t.Fatal("A typo should invalidate the checksum")
+			}
+			if lib.HasValidTokenChecksum(token[:len(token)-1], secret) {
+				t.Fatal("A truncated token should invalidate the checksum")
+			}
+		})
+	}
+
+	t.Run("Fail: Checksum computed with another secret", func(t *testing.T) {
+		token := lib.AppendTokenChecksum("aB3-random-token", "secret")
+		if lib.HasValidTokenChecksum(token, "other-secret") {
+			t.Fatal("The checksum should depend on the secret")
+		}
+	})
+
+	t.Run("Fail: Token shorter than a checksum", func(t *testing.T) {
+		if lib.HasValidTokenChecksum("ABC", "") {
+			t.Fatal("A short token cannot carry a checksum")
+		}
+	})
+}
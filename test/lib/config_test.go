@@ -396,7 +396,44 @@ func Test_Config_ZeroValues(t *testing.T) {
 	}
 }
 
+func TestTokenNormalization(t *testing.T) {
+	if got := (lib.TokenNormalization{}).Normalize(" token\n"); got != " token\n" {
+		t.Errorf("Tokens should be left untouched by default, got %q", got)
+	}
+	if got := (lib.TokenNormalization{TrimSpace: true}).Normalize(" token\n"); got != "token" {
+		t.Errorf("Whitespace should be trimmed, got %q", got)
+	}
+}
+
 // Utility function to create a pointer to a string
 func stringPtr(s string) *string {
 	return &s
 }
+
+func Test_Config_Clone(t *testing.T) {
+	config := lib.NewConfig("original", "secret", "15m", "localhost:6379", "", "", 0, nil, nil, nil)
+	config.AccessTokenAllowedClaims = []string{"tenant"}
+
+	clone := config.Clone()
+	*config.RefreshTokenTTL = "modified"
+	config.AccessTokenAllowedClaims[0] = "modified"
+	config.Issuer = "modified"
+
+	if *clone.RefreshTokenTTL != "1h" {
+		t.Errorf("Clone RefreshTokenTTL should be unchanged, got %s", *clone.RefreshTokenTTL)
+	}
+	if clone.AccessTokenAllowedClaims[0] != "tenant" {
+		t.Errorf("Clone AccessTokenAllowedClaims should be unchanged, got %v", clone.AccessTokenAllowedClaims)
+	}
+	if clone.Issuer != "original" {
+		t.Errorf("Clone Issuer should be unchanged, got %s", clone.Issuer)
+	}
+	if clone.OTPTTL == config.OTPTTL {
+		t.Error("Clone OTPTTL should not share the pointer")
+	}
+
+	var nilConfig *lib.Config
+	if nilConfig.Clone() != nil {
+		t.Error("Clone of a nil config should be nil")
+	}
+}
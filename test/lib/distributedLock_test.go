@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+func Test_Lib_NewDistributedLock(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	defer rdb.Close()
+
+	tests := []struct {
+		name      string
+		db        *redis.Client
+		lockName  string
+		ttl       time.Duration
+		expectErr bool
+	}{
+		{"Success: Valid lock", rdb, "cleanup", time.Second, false},
+		{"Failure: Nil db", nil, "cleanup", time.Second, true},
+		{"Failure: Empty name", rdb, "", time.Second, true},
+		{"Failure: TTL below a millisecond", rdb, "cleanup", time.Microsecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lib.NewDistributedLock(tt.db, tt.lockName, tt.ttl)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func Test_Lib_DistributedLock_Unavailable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:1", MaxRetries: -1})
+	defer rdb.Close()
+
+	lock, err := lib.NewDistributedLock(rdb, "cleanup", time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Failure: Acquire reports the storage error", func(t *testing.T) {
+		_, err := lock.Acquire(context.Background())
+		if err == nil || errors.Is(err, lib.ErrLockNotAcquired) {
+			t.Fatalf("Expected a storage error, got: %v", err)
+		}
+	})
+
+	t.Run("Failure: Run does not call the job", func(t *testing.T) {
+		called := false
+		err := lock.Run(context.Background(), func(context.Context, int64) error {
+			called = true
+			return nil
+		})
+		if err == nil || called {
+			t.Fatalf("Expected an error without calling the job, got: %v (called: %v)", err, called)
+		}
+	})
+}
+
+func Test_Lib_NewLeaderElection(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	defer rdb.Close()
+
+	t.Run("Success: Valid election", func(t *testing.T) {
+		election, err := lib.NewLeaderElection(rdb, "maintenance", time.Second, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if election.IsLeader() {
+			t.Fatal("The instance should not lead before Run")
+		}
+	})
+
+	t.Run("Failure: Invalid lock settings", func(t *testing.T) {
+		if _, err := lib.NewLeaderElection(nil, "maintenance", time.Second, 0); err == nil {
+			t.Fatal("Expected an error for a nil db")
+		}
+		if _, err := lib.NewLeaderElection(rdb, "", time.Second, 0); err == nil {
+			t.Fatal("Expected an error for an empty name")
+		}
+	})
+}
+
+func Test_Lib_LeaderElection_Unavailable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:1", MaxRetries: -1})
+	defer rdb.Close()
+
+	election, err := lib.NewLeaderElection(rdb, "maintenance", 30*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Success: Keep campaigning while Redis is down", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := election.Run(ctx, func(context.Context, int64) {
+			t.Error("The instance should not lead without Redis")
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the context error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_TokenFingerprint(t *testing.T) {
+	t.Run("Success: Known fingerprint", func(t *testing.T) {
+		if fingerprint := lib.TokenFingerprint("test"); fingerprint != "9f86d081884c7d65" {
+			t.Fatalf("Unexpected fingerprint %q", fingerprint)
+		}
+	})
+
+	t.Run("Success: Prefix of the token hash", func(t *testing.T) {
+		token := strings.Repeat("aB3", 85)
+		sum := sha256.Sum256([]byte(token))
+		fingerprint := lib.TokenFingerprint(token)
+		if len(fingerprint) != lib.TokenFingerprintLength {
+			t.Fatalf("Unexpected fingerprint length %d", len(fingerprint))
+		}
+		if !strings.HasPrefix(hex.EncodeToString(sum[:]), fingerprint) {
+			t.Fatal("The fingerprint should be a prefix of the SHA-256 hash")
+		}
+		if lib.TokenFingerprint(token) != fingerprint {
+			t.Fatal("The fingerprint should be stable")
+		}
+	})
+
+	t.Run("Success: Distinct tokens", func(t *testing.T) {
+		if lib.TokenFingerprint("token-a") == lib.TokenFingerprint("token-b") {
+			t.Fatal("Distinct tokens should have distinct fingerprints")
+		}
+	})
+
+	t.Run("Success: Empty token", func(t *testing.T) {
+		if fingerprint := lib.TokenFingerprint(""); fingerprint != "" {
+			t.Fatalf("Unexpected fingerprint %q", fingerprint)
+		}
+	})
+}
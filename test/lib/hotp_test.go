@@ -0,0 +1,47 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_GenerateHOTP(t *testing.T) {
+	// RFC 4226, appendix D
+	secret := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	t.Run("Success: RFC 4226 test values", func(t *testing.T) {
+		for counter, want := range expected {
+			code, err := lib.GenerateHOTP(secret, uint64(counter), 6)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if code != want {
+				t.Fatalf("Counter %d: expected %s, got %s", counter, want, code)
+			}
+		}
+	})
+
+	t.Run("Success: 8 digits", func(t *testing.T) {
+		code, err := lib.GenerateHOTP(secret, 0, 8)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(code) != 8 || code[2:] != expected[0] {
+			t.Fatalf("Unexpected code: %s", code)
+		}
+	})
+
+	t.Run("Fail: Invalid digits", func(t *testing.T) {
+		if _, err := lib.GenerateHOTP(secret, 0, 5); err == nil {
+			t.Fatal("The code should not be generated")
+		}
+	})
+
+	t.Run("Fail: Empty secret", func(t *testing.T) {
+		if _, err := lib.GenerateHOTP(nil, 0, 6); err == nil {
+			t.Fatal("The code should not be generated")
+		}
+	})
+}
@@ -53,3 +53,39 @@ func Test_Lib_Misc_GenerateRandomString_ValidChars(t *testing.T) {
 		}
 	})
 }
+
+func Test_Lib_Misc_GenerateUserCode(t *testing.T) {
+	t.Run("Success: User code is formatted XXXX-XXXX", func(t *testing.T) {
+		validChars := "BCDFGHJKLMNPQRSTVWXZ"
+		code, err := lib.GenerateUserCode()
+		if err != nil {
+			t.Fatal("The user code should not be an error")
+		}
+		if len(code) != 9 || code[4] != '-' {
+			t.Fatalf("The user code %s is not formatted XXXX-XXXX", code)
+		}
+		for _, char := range strings.Replace(code, "-", "", 1) {
+			if !strings.Contains(validChars, string(char)) {
+				t.Fatalf("Invalid character %c in user code", char)
+			}
+		}
+	})
+}
+
+func Test_Lib_Misc_GenerateBase32String(t *testing.T) {
+	t.Run("Success: String contains only base32 characters", func(t *testing.T) {
+		validChars := "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+		str, err := lib.GenerateBase32String(32)
+		if err != nil {
+			t.Fatal("The string should not be an error")
+		}
+		if len(str) != 32 {
+			t.Fatalf("The string %s does not have the asked length", str)
+		}
+		for _, char := range str {
+			if !strings.Contains(validChars, string(char)) {
+				t.Fatalf("Invalid character %c in generated string", char)
+			}
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// fakeQRCode returns the content as image, to check what is encoded.
+type fakeQRCode struct {
+	err error
+}
+
+func (f fakeQRCode) EncodeQRCode(content string) ([]byte, string, error) {
+	return []byte(content), "image/svg+xml", f.err
+}
+
+func Test_Lib_HOTPKey_URI(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	t.Run("Success: Key URI", func(t *testing.T) {
+		uri, err := lib.HOTPKey{Issuer: "Acme Inc", AccountName: "alice@example.com", Secret: secret, Counter: 3}.URI()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := "otpauth://hotp/Acme%20Inc:alice@example.com?algorithm=SHA1&counter=3&digits=6&issuer=Acme%20Inc&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		if uri != expected {
+			t.Fatalf("Expected %s, got %s", expected, uri)
+		}
+	})
+
+	t.Run("Fail: Issuer with colon", func(t *testing.T) {
+		if _, err := (lib.HOTPKey{Issuer: "Acme:EU", AccountName: "alice", Secret: secret}).URI(); err == nil {
+			t.Fatal("The URI should not be built")
+		}
+	})
+
+	t.Run("Fail: Empty secret", func(t *testing.T) {
+		if _, err := (lib.HOTPKey{AccountName: "alice"}).URI(); err == nil {
+			t.Fatal("The URI should not be built")
+		}
+	})
+}
+
+func Test_Lib_NewProvisioningImage(t *testing.T) {
+	uri := "otpauth://hotp/Acme:alice?secret=GEZDGNBV&counter=0"
+
+	t.Run("Success: Image and data URL", func(t *testing.T) {
+		image, err := lib.NewProvisioningImage(uri, fakeQRCode{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(image.Image) != uri || image.URI != uri {
+			t.Fatalf("Unexpected image: %+v", image)
+		}
+		if !strings.HasPrefix(image.DataURL(), "data:image/svg+xml;base64,") {
+			t.Fatalf("Unexpected data URL: %s", image.DataURL())
+		}
+	})
+
+	t.Run("Fail: Encoder error", func(t *testing.T) {
+		if _, err := lib.NewProvisioningImage(uri, fakeQRCode{err: errors.New("too long")}); err == nil {
+			t.Fatal("The image should not be created")
+		}
+	})
+
+	t.Run("Fail: Nil encoder", func(t *testing.T) {
+		if _, err := lib.NewProvisioningImage(uri, nil); err == nil {
+			t.Fatal("The image should not be created")
+		}
+	})
+}
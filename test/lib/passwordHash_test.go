@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func Test_Lib_PasswordHash_Hash(t *testing.T) {
@@ -117,3 +118,161 @@ func Test_Lib_PasswordHash_CheckHash_Fail_EmptyPassword(t *testing.T) {
 		}
 	})
 }
+
+func Test_Lib_PasswordHash_NeedsRehash(t *testing.T) {
+	t.Run("Success: Current cost does not need rehash", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		hash, err := passwordHash.Hash("SecurePassw0rd!")
+		if err != nil {
+			t.Fatalf("Hash trigger an error %v", err)
+		}
+		if passwordHash.NeedsRehash(hash) {
+			t.Fatal("A freshly generated hash should not need a rehash")
+		}
+	})
+
+	t.Run("Success: Lower cost needs rehash", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		legacy, err := bcrypt.GenerateFromPassword([]byte("SecurePassw0rd!"), 10)
+		if err != nil {
+			t.Fatalf("Legacy hash trigger an error %v", err)
+		}
+		if !passwordHash.NeedsRehash(string(legacy)) {
+			t.Fatal("A cost 10 hash should need a rehash")
+		}
+	})
+
+	t.Run("Success: Unknown format needs rehash", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		if !passwordHash.NeedsRehash("$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$aGFzaA") {
+			t.Fatal("A non bcrypt hash should need a rehash")
+		}
+	})
+}
+
+func Test_Lib_PasswordHash_CheckAndUpgrade(t *testing.T) {
+	password := "SecurePassw0rd!"
+
+	t.Run("Success: Outdated hash is upgraded", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		legacy, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+		if err != nil {
+			t.Fatalf("Legacy hash trigger an error %v", err)
+		}
+		ok, upgraded, err := passwordHash.CheckAndUpgrade(password, string(legacy))
+		if err != nil {
+			t.Fatalf("CheckAndUpgrade trigger an error %v", err)
+		}
+		if !ok {
+			t.Fatal("The password should match the legacy hash")
+		}
+		if len(upgraded) == 0 {
+			t.Fatal("An upgraded hash should be returned")
+		}
+		if passwordHash.NeedsRehash(upgraded) {
+			t.Fatal("The upgraded hash should not need a rehash")
+		}
+		if !passwordHash.CheckHash(password, upgraded) {
+			t.Fatal("The upgraded hash should match the password")
+		}
+	})
+
+	t.Run("Success: Current hash is not upgraded", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		hash, err := passwordHash.Hash(password)
+		if err != nil {
+			t.Fatalf("Hash trigger an error %v", err)
+		}
+		ok, upgraded, err := passwordHash.CheckAndUpgrade(password, hash)
+		if err != nil || !ok {
+			t.Fatalf("The password should match without error, got %v", err)
+		}
+		if len(upgraded) != 0 {
+			t.Fatal("No upgraded hash should be returned")
+		}
+	})
+
+	t.Run("Fail: Bad password is not upgraded", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		legacy, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+		if err != nil {
+			t.Fatalf("Legacy hash trigger an error %v", err)
+		}
+		ok, upgraded, err := passwordHash.CheckAndUpgrade("BadPassw0rd!", string(legacy))
+		if err != nil {
+			t.Fatalf("CheckAndUpgrade trigger an error %v", err)
+		}
+		if ok || len(upgraded) != 0 {
+			t.Fatal("A bad password should neither match nor be upgraded")
+		}
+	})
+}
+
+func Test_Lib_PasswordHash_CheckAndUpgradeLegacy(t *testing.T) {
+	password := "SecurePassw0rd!"
+	legacy := func(password, hash string) bool { return hash == "legacy:"+password }
+
+	t.Run("Success: Legacy hash is upgraded to bcrypt", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		ok, upgraded, err := passwordHash.CheckAndUpgradeLegacy(password, "legacy:"+password, legacy)
+		if err != nil {
+			t.Fatalf("CheckAndUpgradeLegacy trigger an error %v", err)
+		}
+		if !ok {
+			t.Fatal("The password should match the legacy hash")
+		}
+		if !passwordHash.CheckHash(password, upgraded) || passwordHash.NeedsRehash(upgraded) {
+			t.Fatal("The upgraded hash should be a current bcrypt hash of the password")
+		}
+	})
+
+	t.Run("Fail: Bad password is not upgraded", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		ok, upgraded, err := passwordHash.CheckAndUpgradeLegacy("BadPassw0rd!", "legacy:"+password, legacy)
+		if err != nil || ok || len(upgraded) != 0 {
+			t.Fatal("A bad password should neither match nor be upgraded")
+		}
+	})
+
+	t.Run("Fail: Legacy hash without verifier", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		ok, _, err := passwordHash.CheckAndUpgrade(password, "legacy:"+password)
+		if err != nil || ok {
+			t.Fatal("A legacy hash should not match without verifier")
+		}
+	})
+
+	t.Run("Success: Bcrypt hashes ignore the verifier", func(t *testing.T) {
+		passwordHash := lib.NewPasswordHash()
+		hash, err := passwordHash.Hash(password)
+		if err != nil {
+			t.Fatalf("Hash trigger an error %v", err)
+		}
+		ok, upgraded, err := passwordHash.CheckAndUpgradeLegacy(password, hash, func(string, string) bool { return false })
+		if err != nil || !ok || len(upgraded) != 0 {
+			t.Fatal("A current bcrypt hash should match without upgrade")
+		}
+	})
+}
+
+// legacyHasher implements the two methods of PasswordHashInterface only.
+type legacyHasher struct{}
+
+func (legacyHasher) Hash(password string) (string, error) { return "legacy:" + password, nil }
+func (legacyHasher) CheckHash(password, hash string) bool { return hash == "legacy:"+password }
+
+func Test_Lib_PasswordHash_RehashChecker(t *testing.T) {
+	t.Run("Success: Hashers without rehash support remain valid", func(t *testing.T) {
+		var hasher lib.PasswordHashInterface = legacyHasher{}
+		if _, ok := hasher.(lib.RehashChecker); ok {
+			t.Fatal("legacyHasher should not implement RehashChecker")
+		}
+	})
+
+	t.Run("Success: PasswordHash implements RehashChecker", func(t *testing.T) {
+		var hasher lib.PasswordHashInterface = lib.NewPasswordHash()
+		if _, ok := hasher.(lib.RehashChecker); !ok {
+			t.Fatal("PasswordHash should implement RehashChecker")
+		}
+	})
+}
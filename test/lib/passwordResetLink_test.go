@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_NewPasswordResetLinkBuilder(t *testing.T) {
+	t.Run("Fail: Relative base URL", func(t *testing.T) {
+		if _, err := lib.NewPasswordResetLinkBuilder("/reset-password", "secret"); err == nil {
+			t.Fatal("The builder should not be created")
+		}
+	})
+}
+
+func Test_Lib_PasswordResetLinkBuilder(t *testing.T) {
+	links, err := lib.NewPasswordResetLinkBuilder("https://app.example.com/reset-password?lang=fr", "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Success: Link without user hint", func(t *testing.T) {
+		link, err := links.Build("abc", "", time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if link != "https://app.example.com/reset-password?lang=fr&token=abc" {
+			t.Fatalf("Unexpected link: %s", link)
+		}
+
+		parsed, err := links.Parse(link)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if parsed.Token != "abc" || parsed.UserID != "" {
+			t.Fatalf("Unexpected parsed link: %+v", parsed)
+		}
+	})
+
+	t.Run("Success: Link with signed user hint", func(t *testing.T) {
+		link, err := links.Build("abc", "42", time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		parsed, err := links.Parse(link)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if parsed.Token != "abc" || parsed.UserID != "42" {
+			t.Fatalf("Unexpected parsed link: %+v", parsed)
+		}
+	})
+
+	t.Run("Success: Query of the link", func(t *testing.T) {
+		link, err := links.Build("abc", "42", time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		u, _ := url.Parse(link)
+
+		for _, query := range []string{u.RawQuery, "?" + u.RawQuery} {
+			parsed, err := links.Parse(query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if parsed.Token != "abc" || parsed.UserID != "42" {
+				t.Fatalf("Unexpected parsed link: %+v", parsed)
+			}
+		}
+	})
+
+	t.Run("Fail: Tampered user hint", func(t *testing.T) {
+		link, err := links.Build("abc", "42", time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		_, err = links.Parse(strings.Replace(link, "user=42", "user=43", 1))
+		if !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Unsigned user hint", func(t *testing.T) {
+		_, err := links.Parse("https://app.example.com/reset-password?lang=fr&token=abc&user=42")
+		if !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Missing token", func(t *testing.T) {
+		_, err := links.Parse("https://app.example.com/reset-password?lang=fr")
+		if !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Fail: User hint without secret", func(t *testing.T) {
+		unsigned, err := lib.NewPasswordResetLinkBuilder("https://app.example.com/reset-password", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := unsigned.Build("abc", "42", time.Hour); err == nil {
+			t.Fatal("The link should not be built")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_PseudonymizeUserID(t *testing.T) {
+	key := []byte("pseudonymization-key")
+
+	t.Run("Success: Stable keyed pseudonym", func(t *testing.T) {
+		pseudonym := lib.PseudonymizeUserID(key, "123")
+		if len(pseudonym) != 64 {
+			t.Fatalf("Unexpected pseudonym length %d", len(pseudonym))
+		}
+		if pseudonym != lib.PseudonymizeUserID(key, "123") {
+			t.Fatal("The pseudonym should be stable")
+		}
+		if pseudonym == lib.PseudonymizeUserID([]byte("other-key"), "123") {
+			t.Fatal("The pseudonym should depend on the key")
+		}
+		if pseudonym == lib.PseudonymizeUserID(key, "124") {
+			t.Fatal("Distinct users should have distinct pseudonyms")
+		}
+	})
+
+	t.Run("Success: Empty user ID", func(t *testing.T) {
+		if pseudonym := lib.PseudonymizeUserID(key, ""); pseudonym != "" {
+			t.Fatalf("Unexpected pseudonym %q", pseudonym)
+		}
+	})
+}
+
+func Test_Lib_PseudonymizingAuditLogger(t *testing.T) {
+	key := []byte("pseudonymization-key")
+
+	t.Run("Fail: Empty key", func(t *testing.T) {
+		_, err := lib.NewPseudonymizingAuditLogger(lib.AuditLoggerFunc(func(context.Context, lib.AuditEvent) {}), nil)
+		if err == nil {
+			t.Fatal("An empty key should be rejected")
+		}
+	})
+
+	t.Run("Fail: Nil logger", func(t *testing.T) {
+		if _, err := lib.NewPseudonymizingAuditLogger(nil, key); err == nil {
+			t.Fatal("A nil logger should be rejected")
+		}
+	})
+
+	t.Run("Success: User identifiers pseudonymized", func(t *testing.T) {
+		var received lib.AuditEvent
+		logger, err := lib.NewPseudonymizingAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+			received = event
+		}), key, "owner_id")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		details := map[string]string{"actor_id": "admin-1", "owner_id": "42", "reason": "ADMIN"}
+		logger.LogAuditEvent(t.Context(), lib.AuditEvent{
+			Type:    "access_token.impersonation_issued",
+			UserID:  "123",
+			Meta:    lib.RequestMeta{IP: "203.0.113.7"},
+			Details: details,
+		})
+
+		if received.UserID != lib.PseudonymizeUserID(key, "123") {
+			t.Fatalf("Unexpected user ID %q", received.UserID)
+		}
+		if received.Details["actor_id"] != lib.PseudonymizeUserID(key, "admin-1") {
+			t.Fatalf("Unexpected actor ID %q", received.Details["actor_id"])
+		}
+		if received.Details["owner_id"] != lib.PseudonymizeUserID(key, "42") {
+			t.Fatalf("Unexpected owner ID %q", received.Details["owner_id"])
+		}
+		if received.Details["reason"] != "ADMIN" || received.Meta.IP != "203.0.113.7" {
+			t.Fatal("Other fields should be unchanged")
+		}
+		if details["actor_id"] != "admin-1" {
+			t.Fatal("The details of the caller should not be modified")
+		}
+	})
+
+	t.Run("Success: Events without user", func(t *testing.T) {
+		var received lib.AuditEvent
+		logger, _ := lib.NewPseudonymizingAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+			received = event
+		}), key)
+		logger.LogAuditEvent(t.Context(), lib.AuditEvent{Type: "emergency.revoke_all"})
+		if received.UserID != "" || received.Details != nil {
+			t.Fatalf("Unexpected event %+v", received)
+		}
+	})
+}
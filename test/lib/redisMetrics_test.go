@@ -0,0 +1,60 @@
+package lib
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/redis/go-redis/v9"
+)
+
+func Test_Lib_ReportRedisPoolStats(t *testing.T) {
+	t.Run("Success: Report until the context is done", func(t *testing.T) {
+		rdb := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+		defer rdb.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		reports := make(chan lib.RedisPoolStats, 10)
+		done := make(chan struct{})
+		go func() {
+			lib.ReportRedisPoolStats(ctx, rdb, time.Millisecond, lib.RedisMetricsHookFunc(func(stats lib.RedisPoolStats) {
+				select {
+				case reports <- stats:
+				default:
+				}
+			}))
+			close(done)
+		}()
+
+		select {
+		case stats := <-reports:
+			if stats.TotalConns != 0 {
+				t.Fatalf("Unexpected open connections: %d", stats.TotalConns)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("The hook should be called")
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("The reporting should stop with the context")
+		}
+	})
+}
+
+func Test_Lib_RedisClient_Options(t *testing.T) {
+	t.Run("Fail: Invalid pool duration", func(t *testing.T) {
+		config := lib.NewConfig("issuer", "secret", "15m", "localhost:0", "", "", 0, nil, nil, nil)
+		timeout := "fast"
+		config.RedisReadTimeout = &timeout
+
+		_, err := lib.NewRedisClient(config).InitRedisClient(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "invalid Redis read timeout format") {
+			t.Fatalf("Expected a read timeout format error, got %v", err)
+		}
+	})
+}
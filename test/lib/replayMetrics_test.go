@@ -0,0 +1,54 @@
+package lib
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_ReplayStats(t *testing.T) {
+	t.Run("Success: Count concurrent replays per token type and reason", func(t *testing.T) {
+		stats := &lib.ReplayStats{}
+		var hook lib.ReplayMetricsHook = stats
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				hook.ObserveReplay(lib.TokenTypeRefresh, lib.ReplayReasonRevoked)
+			}()
+		}
+		wg.Wait()
+		hook.ObserveReplay(lib.TokenTypeAccess, lib.ReplayReasonExpired)
+
+		if count := stats.Count(lib.TokenTypeRefresh, lib.ReplayReasonRevoked); count != 50 {
+			t.Fatalf("Expected 50 revoked refresh token replays, got %d", count)
+		}
+		if count := stats.Count(lib.TokenTypePasswordReset, lib.ReplayReasonRevoked); count != 0 {
+			t.Fatalf("Expected no password reset token replay, got %d", count)
+		}
+	})
+
+	t.Run("Success: Snapshot is a copy", func(t *testing.T) {
+		stats := &lib.ReplayStats{}
+		stats.ObserveReplay(lib.TokenTypeAccess, lib.ReplayReasonExpired)
+
+		snapshot := stats.Snapshot()
+		snapshot[lib.TokenTypeAccess][lib.ReplayReasonExpired] = 10
+		if count := stats.Count(lib.TokenTypeAccess, lib.ReplayReasonExpired); count != 1 {
+			t.Fatalf("The snapshot should not change the counts, got %d", count)
+		}
+	})
+
+	t.Run("Success: Adapt a function", func(t *testing.T) {
+		var observed lib.ReplayReason
+		lib.ReplayMetricsHookFunc(func(tokenType lib.TokenType, reason lib.ReplayReason) {
+			observed = reason
+		}).ObserveReplay(lib.TokenTypeRefresh, lib.ReplayReasonRevoked)
+		if observed != lib.ReplayReasonRevoked {
+			t.Fatalf("The function should be called, got %q", observed)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_RequestMeta_RoundTrip(t *testing.T) {
+	t.Run("Success: Metadata is read back from context", func(t *testing.T) {
+		meta := lib.RequestMeta{IP: "203.0.113.7", UserAgent: "curl/8.0", Country: "FR", DeviceID: "device-1"}
+		ctx := lib.WithRequestMeta(context.Background(), meta)
+
+		got, ok := lib.RequestMetaFromContext(ctx)
+		if !ok {
+			t.Fatal("The metadata should be found")
+		}
+		if got != meta {
+			t.Fatalf("Expected %+v, got %+v", meta, got)
+		}
+	})
+}
+
+func Test_Lib_RequestMeta_Missing(t *testing.T) {
+	t.Run("Success: Missing metadata is reported", func(t *testing.T) {
+		if _, ok := lib.RequestMetaFromContext(context.Background()); ok {
+			t.Fatal("No metadata should be found")
+		}
+		if _, ok := lib.RequestMetaFromContext(nil); ok {
+			t.Fatal("No metadata should be found in a nil context")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_ParseScope(t *testing.T) {
+	t.Run("Success: Scopes without duplicates", func(t *testing.T) {
+		scopes, err := lib.ParseScope("  repo:* user:read repo:*\tadmin ")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(scopes, []string{"repo:*", "user:read", "admin"}) {
+			t.Fatalf("Unexpected scopes: %v", scopes)
+		}
+	})
+
+	t.Run("Success: Empty scope", func(t *testing.T) {
+		scopes, err := lib.ParseScope("")
+		if err != nil || len(scopes) != 0 {
+			t.Fatalf("Unexpected result: %v, %v", scopes, err)
+		}
+	})
+
+	for _, scope := range []string{"repo:", ":read", "repo::read", "repo:re*", `repo:"read"`, "repo:réad"} {
+		t.Run("Fail: "+scope, func(t *testing.T) {
+			if _, err := lib.ParseScope(scope); !errors.Is(err, lib.ErrInvalidScopeSyntax) {
+				t.Fatalf("Expected ErrInvalidScopeSyntax, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_Lib_ScopeMatches(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+		expected bool
+	}{
+		{"repo:read", "repo:read", true},
+		{"repo:*", "repo:read", true},
+		{"repo:*", "repo:read:private", true},
+		{"repo", "repo:read", true},
+		{"*", "user:email", true},
+		{"*:read", "repo:read", true},
+		{"*:read", "repo:write", false},
+		{"repo:*", "repo", false},
+		{"repo:read", "repo", false},
+		{"repo:read", "repo:*", false},
+		{"repo:*", "repo:*", true},
+		{"repo:read", "repository:read", false},
+		{"repo:", "repo:read", false},
+	}
+	for _, c := range cases {
+		if got := lib.ScopeMatches(c.granted, c.required); got != c.expected {
+			t.Errorf("ScopeMatches(%q, %q) = %v, expected %v", c.granted, c.required, got, c.expected)
+		}
+	}
+}
+
+func Test_Lib_ScopesAllow(t *testing.T) {
+	granted := []string{"repo:*", "user:read"}
+	if !lib.ScopesAllow(granted, "repo:write", "user:read") {
+		t.Fatal("The scopes should be allowed")
+	}
+	if lib.ScopesAllow(granted, "repo:write", "user:write") {
+		t.Fatal("user:write should not be allowed")
+	}
+	if !lib.ScopesAllow(nil) {
+		t.Fatal("No required scope should be allowed")
+	}
+}
+
+// randomScope is a well-formed scope of 1 to 4 segments from a small alphabet,
+// so that generated scopes often share segments.
+type randomScope string
+
+func (randomScope) Generate(r *rand.Rand, _ int) reflect.Value {
+	segments := []string{"repo", "user", "read", "write", "admin", "*"}
+	scope := make([]string, 1+r.Intn(4))
+	for i := range scope {
+		scope[i] = segments[r.Intn(len(segments))]
+	}
+	return reflect.ValueOf(randomScope(strings.Join(scope, ":")))
+}
+
+func Test_Lib_ScopeMatches_Properties(t *testing.T) {
+	t.Run("Property: A scope implies itself", func(t *testing.T) {
+		if err := quick.Check(func(s randomScope) bool {
+			return lib.ScopeMatches(string(s), string(s))
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Property: A scope implies its sub-scopes", func(t *testing.T) {
+		if err := quick.Check(func(s randomScope, sub randomScope) bool {
+			return lib.ScopeMatches(string(s), string(s)+":"+string(sub))
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Property: Implication is transitive", func(t *testing.T) {
+		if err := quick.Check(func(a, b, c randomScope) bool {
+			if lib.ScopeMatches(string(a), string(b)) && lib.ScopeMatches(string(b), string(c)) {
+				return lib.ScopeMatches(string(a), string(c))
+			}
+			return true
+		}, &quick.Config{MaxCount: 5000}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Property: Sub-scopes never imply their parent", func(t *testing.T) {
+		if err := quick.Check(func(s randomScope, sub randomScope) bool {
+			return !lib.ScopeMatches(string(s)+":"+string(sub), string(s))
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Property: Parsed scopes round-trip", func(t *testing.T) {
+		if err := quick.Check(func(a, b randomScope) bool {
+			scopes, err := lib.ParseScope(string(a) + " " + string(b))
+			if err != nil {
+				return false
+			}
+			reparsed, err := lib.ParseScope(strings.Join(scopes, " "))
+			return err == nil && reflect.DeepEqual(scopes, reparsed)
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_SignedURL(t *testing.T) {
+	const secret = "url-signing-secret"
+
+	t.Run("Success: Verify a signed URL", func(t *testing.T) {
+		link, err := lib.SignURL(secret, "https://files.example.com/reports/42.pdf?version=2", time.Minute, "download")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, param := range []string{"version", lib.SignedURLExpiresParam, lib.SignedURLScopeParam, lib.SignedURLSignatureParam} {
+			if u.Query().Get(param) == "" {
+				t.Fatalf("Missing query parameter %s in %s", param, link)
+			}
+		}
+		if err := lib.VerifySignedURL(secret, link, "download"); err != nil {
+			t.Fatalf("The URL should be valid: %v", err)
+		}
+	})
+
+	t.Run("Fail: Modified URL", func(t *testing.T) {
+		link, _ := lib.SignURL(secret, "https://files.example.com/reports/42.pdf", time.Minute, "download")
+		for _, modified := range []string{
+			strings.Replace(link, "42.pdf", "43.pdf", 1),
+			strings.Replace(link, "files.example.com", "evil.example.com", 1),
+			link + "&extra=1",
+		} {
+			if err := lib.VerifySignedURL(secret, modified, "download"); !errors.Is(err, lib.ErrSignedURLInvalid) {
+				t.Fatalf("Expected ErrSignedURLInvalid for %s, got %v", modified, err)
+			}
+		}
+	})
+
+	t.Run("Fail: Other secret or scope", func(t *testing.T) {
+		link, _ := lib.SignURL(secret, "https://files.example.com/reports/42.pdf", time.Minute, "download")
+		if err := lib.VerifySignedURL("other-secret", link, "download"); !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+		if err := lib.VerifySignedURL(secret, link, "upload"); !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Unsigned URL", func(t *testing.T) {
+		if err := lib.VerifySignedURL(secret, "https://files.example.com/reports/42.pdf", "download"); !errors.Is(err, lib.ErrSignedURLInvalid) {
+			t.Fatalf("Expected ErrSignedURLInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Expired URL", func(t *testing.T) {
+		link, _ := lib.SignURL(secret, "https://files.example.com/reports/42.pdf", time.Second, "download")
+		time.Sleep(1100 * time.Millisecond)
+		if err := lib.VerifySignedURL(secret, link, "download"); !errors.Is(err, lib.ErrSignedURLExpired) {
+			t.Fatalf("Expected ErrSignedURLExpired, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Invalid arguments", func(t *testing.T) {
+		if _, err := lib.SignURL("", "https://files.example.com/a", time.Minute, "download"); err == nil {
+			t.Fatal("An empty secret should be rejected")
+		}
+		if _, err := lib.SignURL(secret, "https://files.example.com/a", 0, "download"); err == nil {
+			t.Fatal("A zero ttl should be rejected")
+		}
+		if _, err := lib.SignURL(secret, "/relative/path", time.Minute, "download"); err == nil {
+			t.Fatal("A relative URL should be rejected")
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_TokenPrefix(t *testing.T) {
+	t.Run("Success: Prefix with type and version", func(t *testing.T) {
+		if prefix := lib.TokenPrefix(lib.TokenTypePasswordReset); prefix != "prt_v1_" {
+			t.Fatalf("Unexpected prefix %s", prefix)
+		}
+	})
+
+	t.Run("Success: Parse a structured token", func(t *testing.T) {
+		tokenType, version, ok := lib.ParseTokenPrefix("rt_v1_aB3-random")
+		if !ok || tokenType != lib.TokenTypeRefresh || version != "v1" {
+			t.Fatalf("Unexpected parse result %s %s %t", tokenType, version, ok)
+		}
+	})
+
+	t.Run("Fail: Unprefixed token", func(t *testing.T) {
+		if _, _, ok := lib.ParseTokenPrefix("aB3-random"); ok {
+			t.Fatal("An unprefixed token should not be parsed")
+		}
+		if _, _, ok := lib.ParseTokenPrefix("_v1_aB3-random"); ok {
+			t.Fatal("A token without type should not be parsed")
+		}
+	})
+}
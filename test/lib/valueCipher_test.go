@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+func Test_Lib_NewValueCipher(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+
+	t.Run("Fail: Current key not in the keyring", func(t *testing.T) {
+		if _, err := lib.NewValueCipher(map[string][]byte{"a": key}, "b"); err == nil {
+			t.Fatal("The cipher should not be created")
+		}
+	})
+
+	t.Run("Fail: Invalid key size", func(t *testing.T) {
+		if _, err := lib.NewValueCipher(map[string][]byte{"a": []byte("short")}, "a"); err == nil {
+			t.Fatal("The cipher should not be created")
+		}
+	})
+
+	t.Run("Fail: Invalid key id", func(t *testing.T) {
+		if _, err := lib.NewValueCipher(map[string][]byte{"a:b": key}, "a:b"); err == nil {
+			t.Fatal("The cipher should not be created")
+		}
+	})
+}
+
+func Test_Lib_ValueCipher(t *testing.T) {
+	oldKey := bytes.Repeat([]byte("o"), 32)
+	newKey := bytes.Repeat([]byte("n"), 32)
+	old, err := lib.NewValueCipher(map[string][]byte{"old": oldKey}, "old")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rotated, err := lib.NewValueCipher(map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Success: Round trip", func(t *testing.T) {
+		encrypted, err := rotated.Encrypt("$2a$14$hash", "123")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(encrypted, "enc:v1:new:") || strings.Contains(encrypted, "hash") {
+			t.Fatalf("Unexpected encrypted value %q", encrypted)
+		}
+		other, _ := rotated.Encrypt("$2a$14$hash", "123")
+		if other == encrypted {
+			t.Fatal("Encryptions should use distinct nonces")
+		}
+
+		decrypted, err := rotated.Decrypt(encrypted, "123")
+		if err != nil || decrypted != "$2a$14$hash" {
+			t.Fatalf("Unexpected decryption %q: %v", decrypted, err)
+		}
+	})
+
+	t.Run("Success: Values of the previous key", func(t *testing.T) {
+		encrypted, _ := old.Encrypt("value", "123")
+		decrypted, err := rotated.Decrypt(encrypted, "123")
+		if err != nil || decrypted != "value" {
+			t.Fatalf("Unexpected decryption %q: %v", decrypted, err)
+		}
+	})
+
+	t.Run("Success: Values stored in clear", func(t *testing.T) {
+		decrypted, err := rotated.Decrypt("$2a$14$hash", "123")
+		if err != nil || decrypted != "$2a$14$hash" {
+			t.Fatalf("Unexpected decryption %q: %v", decrypted, err)
+		}
+	})
+
+	t.Run("Fail: Unknown key", func(t *testing.T) {
+		encrypted, _ := rotated.Encrypt("value", "123")
+		if _, err := old.Decrypt(encrypted, "123"); !errors.Is(err, lib.ErrUnknownEncryptionKey) {
+			t.Fatalf("Expected ErrUnknownEncryptionKey, got %v", err)
+		}
+	})
+
+	t.Run("Fail: Other associated data", func(t *testing.T) {
+		encrypted, _ := rotated.Encrypt("value", "123")
+		if _, err := rotated.Decrypt(encrypted, "456"); err == nil {
+			t.Fatal("A value moved to another user should not decrypt")
+		}
+	})
+
+	t.Run("Fail: Tampered value", func(t *testing.T) {
+		encrypted, _ := rotated.Encrypt("value", "123")
+		tampered := encrypted[:len(encrypted)-2] + "AA"
+		if tampered == encrypted {
+			tampered = encrypted[:len(encrypted)-2] + "BB"
+		}
+		if _, err := rotated.Decrypt(tampered, "123"); err == nil {
+			t.Fatal("A tampered value should not decrypt")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/middleware"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticElevation accepts a single elevation token of a user, granted at a fixed time.
+type staticElevation struct {
+	userID    string
+	token     string
+	grantedAt time.Time
+}
+
+func (s staticElevation) VerifyElevation(_ context.Context, userID string, token string, maxAge time.Duration) (*service.ElevationGrant, error) {
+	if userID != s.userID || token != s.token || (maxAge > 0 && time.Since(s.grantedAt) > maxAge) {
+		return nil, service.ErrElevationRequired
+	}
+	return &service.ElevationGrant{UserID: userID, Method: service.ElevationMethodPassword, GrantedAt: s.grantedAt}, nil
+}
+
+func TestRequireRecentElevation(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	})
+	user := modelAuth.NewUser("123", "user@example.com")
+	accessToken, err := accessService.CreateAccessToken(user)
+	require.NoError(t, err)
+
+	serve := func(elevation staticElevation, accessToken string, elevationToken string) *httptest.ResponseRecorder {
+		handler := middleware.RequireRecentElevation(accessService, elevation, 5*time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claim, ok := middleware.ClaimFromContext(r.Context())
+			require.True(t, ok)
+			assert.Equal(t, "123", claim.Subject)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		req := httptest.NewRequest(http.MethodDelete, "/account", nil)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		if elevationToken != "" {
+			req.Header.Set(middleware.ElevationHeader, elevationToken)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Should accept a recent elevation", func(t *testing.T) {
+		elevation := staticElevation{userID: "123", token: "sudo", grantedAt: time.Now()}
+		assert.Equal(t, http.StatusNoContent, serve(elevation, accessToken, "sudo").Code)
+	})
+
+	t.Run("Should refuse a missing or old elevation", func(t *testing.T) {
+		elevation := staticElevation{userID: "123", token: "sudo", grantedAt: time.Now()}
+		rec := serve(elevation, accessToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Forbidden", "status": 403, "detail": "re-authentication required", "instance": "/account", "code": "ELEVATION_REQUIRED"}`, rec.Body.String())
+
+		elevation.grantedAt = time.Now().Add(-10 * time.Minute)
+		assert.Equal(t, http.StatusForbidden, serve(elevation, accessToken, "sudo").Code)
+	})
+
+	t.Run("Should refuse the elevation of another user", func(t *testing.T) {
+		elevation := staticElevation{userID: "456", token: "sudo", grantedAt: time.Now()}
+		assert.Equal(t, http.StatusForbidden, serve(elevation, accessToken, "sudo").Code)
+	})
+
+	t.Run("Should reject a missing access token", func(t *testing.T) {
+		elevation := staticElevation{userID: "123", token: "sudo", grantedAt: time.Now()}
+		rec := serve(elevation, "", "sudo")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+	})
+}
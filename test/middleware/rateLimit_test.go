@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/middleware"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	t.Run("Should set the remaining attempts and the reset in seconds", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		middleware.SetRateLimitHeaders(w, service.RateLimitState{Limit: 5, Remaining: 3, Reset: 90*time.Second + time.Millisecond})
+
+		assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "91", w.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Should tell when to retry once exhausted", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		middleware.SetRateLimitHeaders(w, service.RateLimitState{Limit: 5, Remaining: 0, Reset: 15 * time.Minute})
+
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "900", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Should report a fresh window without retry", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Set("Retry-After", "30")
+		middleware.SetRateLimitHeaders(w, service.RateLimitState{Limit: 5, Remaining: 5})
+
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/middleware"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireScope(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	})
+	userToken, err := accessService.CreateAccessToken(modelAuth.NewUser("123", "user@example.com"))
+	require.NoError(t, err)
+
+	scoped := func(scopes ...string) string {
+		response, err := accessService.ExchangeToken(context.Background(), service.TokenExchangeRequest{SubjectToken: userToken, Scopes: scopes})
+		require.NoError(t, err)
+		return response.AccessToken
+	}
+
+	handler := middleware.RequireScope(accessService, "repo:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := middleware.ClaimFromContext(r.Context())
+		require.True(t, ok)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/repos", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Should accept granted scopes", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve(scoped("repo:read")).Code)
+		assert.Equal(t, http.StatusNoContent, serve(scoped("repo:*")).Code)
+	})
+
+	t.Run("Should refuse tokens without a scope claim", func(t *testing.T) {
+		rec := serve(userToken)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, `Bearer error="insufficient_scope", scope="repo:read"`, rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Should accept tokens without a scope claim when allowed", func(t *testing.T) {
+		allowUnscoped := middleware.RequireScopeWith(accessService, middleware.ScopeRequirement{Scopes: []string{"repo:read"}, AllowUnscoped: true})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+		for token, code := range map[string]int{userToken: http.StatusNoContent, scoped("user:read"): http.StatusForbidden} {
+			req := httptest.NewRequest(http.MethodGet, "/repos", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			allowUnscoped.ServeHTTP(rec, req)
+			assert.Equal(t, code, rec.Code)
+		}
+	})
+
+	t.Run("Should refuse missing scopes", func(t *testing.T) {
+		rec := serve(scoped("repo:write", "user:read"))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, `Bearer error="insufficient_scope", scope="repo:read"`, rec.Header().Get("WWW-Authenticate"))
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Forbidden", "status": 403, "detail": "token scope insufficient", "instance": "/repos", "code": "INSUFFICIENT_SCOPE"}`, rec.Body.String())
+	})
+
+	t.Run("Should reject a missing token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("").Code)
+	})
+
+	t.Run("Should panic on a malformed required scope", func(t *testing.T) {
+		assert.Panics(t, func() { middleware.RequireScope(accessService, "repo:") })
+	})
+}
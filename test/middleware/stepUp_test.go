@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/middleware"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireStepUp(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	})
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	handler := middleware.RequireStepUp(accessService, service.StepUpRequirement{
+		ACR:        modelAuth.AssuranceLevel2,
+		MaxAuthAge: 5 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claim, ok := middleware.ClaimFromContext(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, "123", claim.Subject)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/account/email", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Should accept a recent multi-factor token", func(t *testing.T) {
+		token, err := accessService.CreateAccessTokenWithAuthentication(user, &modelAuth.Authentication{
+			Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodOTP},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNoContent, serve(token).Code)
+	})
+
+	t.Run("Should challenge a single-factor token", func(t *testing.T) {
+		token, err := accessService.CreateAccessTokenWithAuthentication(user, &modelAuth.Authentication{
+			Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword},
+		})
+		require.NoError(t, err)
+
+		rec := serve(token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer error="insufficient_user_authentication", acr_values="aal2", max_age=300`, rec.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Unauthorized", "status": 401, "detail": "stronger or more recent authentication required", "instance": "/account/email", "code": "INSUFFICIENT_USER_AUTHENTICATION"}`, rec.Body.String())
+	})
+
+	t.Run("Should challenge an old authentication", func(t *testing.T) {
+		token, err := accessService.CreateAccessTokenWithAuthentication(user, &modelAuth.Authentication{
+			Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodOTP},
+			Time:    time.Now().Add(-time.Hour),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, serve(token).Code)
+	})
+
+	t.Run("Should reject a missing or invalid token", func(t *testing.T) {
+		rec := serve("")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Unauthorized", "status": 401, "detail": "missing token", "instance": "/account/email", "code": "TOKEN_MISSING"}`, rec.Body.String())
+
+		rec = serve("not-a-jwt")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.JSONEq(t, `{"type": "about:blank", "title": "Unauthorized", "status": 401, "detail": "invalid token", "instance": "/account/email", "code": "TOKEN_INVALID"}`, rec.Body.String())
+	})
+}
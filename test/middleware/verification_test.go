@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/middleware"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingVerifier counts the verifications of the access token service.
+type countingVerifier struct {
+	*service.AccessTokenService
+	calls atomic.Int32
+	ctx   context.Context
+}
+
+func (v *countingVerifier) VerifyAccessTokenContext(ctx context.Context, token string) (*modelAuth.Claim, error) {
+	v.calls.Add(1)
+	v.ctx = ctx
+	return v.AccessTokenService.VerifyAccessTokenContext(ctx, token)
+}
+
+func TestShareVerification(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	})
+	token, err := accessService.CreateAccessToken(modelAuth.NewUser("123", "user@example.com"))
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Should verify the token once per request", func(t *testing.T) {
+		verifier := &countingVerifier{AccessTokenService: accessService}
+		handler := middleware.ShareVerification()(
+			middleware.RequireStepUp(verifier, service.StepUpRequirement{})(
+				middleware.RequireStepUp(verifier, service.StepUpRequirement{})(ok)))
+
+		assert.Equal(t, http.StatusNoContent, serve(handler, token).Code)
+		assert.Equal(t, http.StatusNoContent, serve(handler, token).Code)
+		assert.Equal(t, int32(2), verifier.calls.Load())
+	})
+
+	t.Run("Should verify every request without the middleware", func(t *testing.T) {
+		verifier := &countingVerifier{AccessTokenService: accessService}
+		handler := middleware.RequireStepUp(verifier, service.StepUpRequirement{})(
+			middleware.RequireStepUp(verifier, service.StepUpRequirement{})(ok))
+
+		assert.Equal(t, http.StatusNoContent, serve(handler, token).Code)
+		assert.Equal(t, int32(2), verifier.calls.Load())
+	})
+
+	t.Run("Should verify with the request context", func(t *testing.T) {
+		type requestKey struct{}
+		verifier := &countingVerifier{AccessTokenService: accessService}
+		handler := middleware.RequireStepUp(verifier, service.StepUpRequirement{})(ok)
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req = req.WithContext(context.WithValue(req.Context(), requestKey{}, "request"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.NotNil(t, verifier.ctx)
+		assert.Equal(t, "request", verifier.ctx.Value(requestKey{}))
+	})
+
+	t.Run("Should not share verifications between verifiers", func(t *testing.T) {
+		first := &countingVerifier{AccessTokenService: accessService}
+		second := &countingVerifier{AccessTokenService: accessService}
+		handler := middleware.ShareVerification()(
+			middleware.RequireStepUp(first, service.StepUpRequirement{})(
+				middleware.RequireStepUp(second, service.StepUpRequirement{})(ok)))
+
+		assert.Equal(t, http.StatusNoContent, serve(handler, token).Code)
+		assert.Equal(t, int32(1), first.calls.Load())
+		assert.Equal(t, int32(1), second.calls.Load())
+	})
+
+	t.Run("Should set the default caching headers on 401 responses", func(t *testing.T) {
+		handler := middleware.ShareVerification()(middleware.RequireScope(accessService, "repo:read")(ok))
+		exchanged, err := accessService.ExchangeToken(t.Context(), service.TokenExchangeRequest{SubjectToken: token, Scopes: []string{"repo:read"}})
+		require.NoError(t, err)
+
+		rec := serve(handler, "invalid")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, []string{"Authorization"}, rec.Header().Values("Vary"))
+
+		rec = serve(handler, exchanged.AccessToken)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Should set the configured caching headers", func(t *testing.T) {
+		handler := middleware.ShareVerification(
+			middleware.WithUnauthorizedCacheControl("private, max-age=0"),
+			middleware.WithUnauthorizedVary("Authorization", "Cookie"),
+		)(middleware.RequireScope(accessService, "repo:read")(ok))
+
+		rec := serve(handler, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "private, max-age=0", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, []string{"Authorization", "Cookie"}, rec.Header().Values("Vary"))
+
+		handler = middleware.ShareVerification(middleware.WithUnauthorizedCacheControl(""), middleware.WithUnauthorizedVary())(
+			middleware.RequireScope(accessService, "repo:read")(ok))
+		rec = serve(handler, "")
+		assert.Empty(t, rec.Header().Get("Cache-Control"))
+		assert.Empty(t, rec.Header().Values("Vary"))
+	})
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"testing"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+func Test_Authentication_AssuranceLevel(t *testing.T) {
+	tests := []struct {
+		testName string
+		auth     modelAuth.Authentication
+		expected string
+	}{
+		{
+			testName: "No factor",
+			auth:     modelAuth.Authentication{},
+			expected: "",
+		},
+		{
+			testName: "Single factor",
+			auth:     modelAuth.Authentication{Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword}},
+			expected: modelAuth.AssuranceLevel1,
+		},
+		{
+			testName: "Same factor twice",
+			auth:     modelAuth.Authentication{Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodOTP, modelAuth.AuthenticationMethodOTP}},
+			expected: modelAuth.AssuranceLevel1,
+		},
+		{
+			testName: "Password and OTP",
+			auth:     modelAuth.Authentication{Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodOTP}},
+			expected: modelAuth.AssuranceLevel2,
+		},
+		{
+			testName: "Password and WebAuthn",
+			auth:     modelAuth.Authentication{Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodWebAuthn}},
+			expected: modelAuth.AssuranceLevel3,
+		},
+		{
+			testName: "Explicit level",
+			auth:     modelAuth.Authentication{Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword}, Level: modelAuth.AssuranceLevel2},
+			expected: modelAuth.AssuranceLevel2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if level := tt.auth.AssuranceLevel(); level != tt.expected {
+				t.Fatalf("Expected assurance level %q, got %q", tt.expected, level)
+			}
+		})
+	}
+}
+
+func Test_IsAssuranceLevelAtLeast(t *testing.T) {
+	if !modelAuth.IsAssuranceLevelAtLeast(modelAuth.AssuranceLevel3, modelAuth.AssuranceLevel2) {
+		t.Fatal("aal3 should satisfy aal2")
+	}
+	if modelAuth.IsAssuranceLevelAtLeast(modelAuth.AssuranceLevel1, modelAuth.AssuranceLevel2) {
+		t.Fatal("aal1 should not satisfy aal2")
+	}
+	if modelAuth.IsAssuranceLevelAtLeast("unknown", modelAuth.AssuranceLevel1) {
+		t.Fatal("Unknown levels should not satisfy a requirement")
+	}
+	if !modelAuth.IsAssuranceLevelAtLeast("", "") {
+		t.Fatal("An empty requirement should always be satisfied")
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+)
+
+func Test_Claim_HasScope(t *testing.T) {
+	tests := []struct {
+		testName string
+		scope    string
+		required string
+		expected bool
+	}{
+		{
+			testName: "No scope claim",
+			scope:    "",
+			required: "admin:write",
+			expected: false,
+		},
+		{
+			testName: "Granted scope",
+			scope:    "repo:read admin:write",
+			required: "admin:write",
+			expected: true,
+		},
+		{
+			testName: "Wildcard scope",
+			scope:    "repo:*",
+			required: "repo:read",
+			expected: true,
+		},
+		{
+			testName: "Other scope",
+			scope:    "repo:read",
+			required: "admin:write",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			claim := &modelAuth.Claim{Scope: tt.scope}
+			if got := claim.HasScope(tt.required); got != tt.expected {
+				t.Fatalf("Expected HasScope(%q) %v, got %v", tt.required, tt.expected, got)
+			}
+			if got := claim.IsScoped(); got != (tt.scope != "") {
+				t.Fatalf("Expected IsScoped %v, got %v", tt.scope != "", got)
+			}
+		})
+	}
+}
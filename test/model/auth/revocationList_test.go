@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RevocationListClaim_IsRevoked(t *testing.T) {
+	hash := func(token string) string {
+		sum := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(sum[:])
+	}
+
+	claim := &modelAuth.RevocationListClaim{Revoked: []string{hash("revoked-1"), hash("revoked-2")}}
+	if claim.Revoked[0] > claim.Revoked[1] {
+		claim.Revoked[0], claim.Revoked[1] = claim.Revoked[1], claim.Revoked[0]
+	}
+
+	assert.True(t, claim.IsRevoked("revoked-1"))
+	assert.True(t, claim.IsRevoked("revoked-2"))
+	assert.False(t, claim.IsRevoked("active"))
+	assert.False(t, (&modelAuth.RevocationListClaim{}).IsRevoked("active"))
+}
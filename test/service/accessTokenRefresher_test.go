@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessTokenRefresher(t *testing.T) {
+	rts := setupService(t)
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	refresher, err := service.NewAccessTokenRefresher(rts, accessService)
+	require.NoError(t, err)
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	t.Run("Should mint access tokens for allowed audiences only", func(t *testing.T) {
+		refreshToken, err := rts.CreateScopedRefreshToken(context.Background(), user.ID, []string{"billing-api"})
+		require.NoError(t, err)
+
+		accessToken, err := refresher.Refresh(context.Background(), user, *refreshToken, "billing-api")
+		require.NoError(t, err)
+		claim, err := accessService.VerifyAccessToken(accessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"billing-api"}, []string(claim.Audience))
+
+		_, err = refresher.Refresh(context.Background(), user, *refreshToken, "reports-api")
+		assert.ErrorIs(t, err, service.ErrAudienceNotAllowed)
+
+		_, err = refresher.Refresh(context.Background(), user, *refreshToken, "")
+		assert.ErrorIs(t, err, service.ErrAudienceNotAllowed)
+
+		allowed, err := rts.VerifyRefreshTokenForAudience(context.Background(), user.ID, *refreshToken, "reports-api")
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("Should allow every audience for unscoped tokens", func(t *testing.T) {
+		refreshToken, err := rts.CreateRefreshToken(context.Background(), user.ID)
+		require.NoError(t, err)
+
+		_, err = refresher.Refresh(context.Background(), user, *refreshToken, "reports-api")
+		require.NoError(t, err)
+		_, err = refresher.Refresh(context.Background(), user, *refreshToken, "")
+		require.NoError(t, err)
+	})
+
+	t.Run("Should fail with an invalid refresh token", func(t *testing.T) {
+		_, err := refresher.Refresh(context.Background(), user, "unknown-token", "billing-api")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid refresh token")
+	})
+
+	t.Run("Should fail without audiences", func(t *testing.T) {
+		_, err := rts.CreateScopedRefreshToken(context.Background(), user.ID, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid audiences")
+	})
+}
+
+func TestAccessTokenRefresher_ClaimsLoader(t *testing.T) {
+	rts := setupService(t)
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	refresher, err := service.NewAccessTokenRefresher(rts, accessService)
+	require.NoError(t, err)
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	roles := []any{"viewer"}
+	refresher.SetClaimsLoader(func(ctx context.Context, user *modelAuth.User) (map[string]any, error) {
+		return map[string]any{"roles": roles}, nil
+	})
+
+	refreshToken, err := rts.CreateRefreshToken(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	t.Run("Should mint access tokens with the current claims", func(t *testing.T) {
+		accessToken, err := refresher.Refresh(context.Background(), user, *refreshToken, "")
+		require.NoError(t, err)
+		claim, err := accessService.VerifyAccessToken(accessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []any{"viewer"}, claim.Custom["roles"])
+
+		roles = []any{"viewer", "admin"}
+		accessToken, err = refresher.Refresh(context.Background(), user, *refreshToken, "billing-api")
+		require.NoError(t, err)
+		claim, err = accessService.VerifyAccessToken(accessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []any{"viewer", "admin"}, claim.Custom["roles"])
+		assert.Equal(t, []string{"billing-api"}, []string(claim.Audience))
+	})
+
+	t.Run("Should fail when the claims cannot be loaded", func(t *testing.T) {
+		refresher.SetClaimsLoader(func(ctx context.Context, user *modelAuth.User) (map[string]any, error) {
+			return nil, errors.New("user store unavailable")
+		})
+		_, err := refresher.Refresh(context.Background(), user, *refreshToken, "")
+		assert.ErrorContains(t, err, "user store unavailable")
+	})
+
+	t.Run("Should apply the claim guardrails", func(t *testing.T) {
+		refresher.SetClaimsLoader(func(ctx context.Context, user *modelAuth.User) (map[string]any, error) {
+			return map[string]any{"sub": "456"}, nil
+		})
+		_, err := refresher.Refresh(context.Background(), user, *refreshToken, "")
+		assert.ErrorIs(t, err, service.ErrReservedClaim)
+	})
+}
@@ -1,16 +1,18 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
 	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
 
-	"log"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -136,15 +138,12 @@ func Test_Auth_AccessToken_VerifyAccessToken_Expired(t *testing.T) {
 			JWTExpiry: "1s",
 		}
 		accessTokenService := service.NewAccessTokenService(&config)
-		token, err := accessTokenService.CreateAccessToken(&user)
+		// Issued far enough in the past to be beyond the 5 seconds leeway
+		token, err := testkit.ExpiredAccessToken(&config, &user)
 		if err != nil {
 			t.Fatalf("The test expect no error on access token creation, got : %v", err)
 		}
 
-		// Do not go under 5 seconds. The leeway authorize an expired token for 5 seconds after the expiration time
-		log.Printf("Sleeping test for 7 seconds...")
-		time.Sleep(7 * time.Second)
-
 		verified, err := accessTokenService.VerifyAccessToken(token)
 		if err == nil {
 			t.Fatal("The error should not be nil")
@@ -189,3 +188,483 @@ func Test_Auth_AccessToken_VerifyAccessToken_TwoDifferentTokens(t *testing.T) {
 		}
 	})
 }
+
+func Test_Auth_AccessToken_CreateAccessTokenWithAuthentication(t *testing.T) {
+	user := modelAuth.User{
+		ID:    "1",
+		Email: "user@mail.com",
+	}
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	accessTokenService := service.NewAccessTokenService(&config)
+
+	t.Run("Success - Step-up markers in claims", func(t *testing.T) {
+		authTime := time.Now().Add(-2 * time.Minute)
+		token, err := accessTokenService.CreateAccessTokenWithAuthentication(&user, &modelAuth.Authentication{
+			Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword, modelAuth.AuthenticationMethodOTP},
+			Time:    authTime,
+		})
+		if err != nil {
+			t.Fatalf("The test expect no error on access token creation, got : %v", err)
+		}
+
+		claim, err := accessTokenService.VerifyAccessToken(token)
+		if err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if len(claim.AMR) != 2 || claim.AMR[0] != "pwd" || claim.AMR[1] != "otp" {
+			t.Fatalf("Unexpected amr claim %v", claim.AMR)
+		}
+		if claim.ACR != modelAuth.AssuranceLevel2 {
+			t.Fatalf("The acr claim should be %s, got %s", modelAuth.AssuranceLevel2, claim.ACR)
+		}
+		if claim.AuthTime == nil || claim.AuthTime.Unix() != authTime.Unix() {
+			t.Fatal("The auth_time claim should be the authentication time")
+		}
+	})
+
+	t.Run("Success - Step-up requirement", func(t *testing.T) {
+		token, _ := accessTokenService.CreateAccessTokenWithAuthentication(&user, &modelAuth.Authentication{
+			Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword},
+		})
+		claim, _ := accessTokenService.VerifyAccessToken(token)
+
+		if err := (service.StepUpRequirement{ACR: modelAuth.AssuranceLevel1, MaxAuthAge: time.Minute}).Check(claim); err != nil {
+			t.Fatalf("The requirement should be satisfied, got : %v", err)
+		}
+		err := service.StepUpRequirement{ACR: modelAuth.AssuranceLevel2}.Check(claim)
+		if !errors.Is(err, service.ErrInsufficientUserAuthentication) {
+			t.Fatalf("The error should be an insufficient user authentication error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - No authentication recorded", func(t *testing.T) {
+		token, _ := accessTokenService.CreateAccessToken(&user)
+		claim, _ := accessTokenService.VerifyAccessToken(token)
+
+		err := service.StepUpRequirement{MaxAuthAge: time.Hour}.Check(claim)
+		if !errors.Is(err, service.ErrInsufficientUserAuthentication) {
+			t.Fatalf("The error should be an insufficient user authentication error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_CreateImpersonationToken(t *testing.T) {
+	admin := modelAuth.NewUser("42", "admin@mail.com")
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+
+	t.Run("Success - Actor claim and audit event", func(t *testing.T) {
+		var events []lib.AuditEvent
+		accessTokenService := service.NewAccessTokenService(&config)
+		accessTokenService.SetAuditLogger(lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+			events = append(events, event)
+		}))
+
+		token, err := accessTokenService.CreateImpersonationToken(context.Background(), admin, user)
+		if err != nil {
+			t.Fatalf("The test expect no error on impersonation token creation, got : %v", err)
+		}
+
+		claim, err := accessTokenService.VerifyAccessToken(token)
+		if err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if !claim.IsImpersonated() {
+			t.Fatal("The token should be an impersonation token")
+		}
+		if claim.Subject != user.ID || claim.Actor.Subject != admin.ID {
+			t.Fatalf("Unexpected subject %s or actor %s", claim.Subject, claim.Actor.Subject)
+		}
+
+		if len(events) != 1 || events[0].Type != service.AuditEventAccessTokenImpersonation {
+			t.Fatalf("An impersonation audit event should be emitted, got %v", events)
+		}
+		if events[0].UserID != user.ID || events[0].Details["actor_id"] != admin.ID {
+			t.Fatalf("Unexpected audit event %v", events[0])
+		}
+	})
+
+	t.Run("Success - Regular token is not impersonated", func(t *testing.T) {
+		accessTokenService := service.NewAccessTokenService(&config)
+		token, _ := accessTokenService.CreateAccessToken(user)
+		claim, _ := accessTokenService.VerifyAccessToken(token)
+		if claim.IsImpersonated() {
+			t.Fatal("A regular token should not be an impersonation token")
+		}
+	})
+
+	t.Run("Fail - Self impersonation", func(t *testing.T) {
+		accessTokenService := service.NewAccessTokenService(&config)
+		_, err := accessTokenService.CreateImpersonationToken(context.Background(), user, user)
+		if err == nil || err.Error() != "actor cannot impersonate themselves" {
+			t.Fatalf("The test expect a self impersonation error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Missing actor", func(t *testing.T) {
+		accessTokenService := service.NewAccessTokenService(&config)
+		_, err := accessTokenService.CreateImpersonationToken(context.Background(), nil, user)
+		if err == nil || err.Error() != "invalid actor" {
+			t.Fatalf("The test expect an invalid actor error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_ClaimValidators(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	errWrongTenant := errors.New("wrong tenant")
+
+	t.Run("Success - Validators pass", func(t *testing.T) {
+		var calls []string
+		accessTokenService := service.NewAccessTokenService(&config)
+		accessTokenService.AddClaimValidator("first", service.ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+			calls = append(calls, "first")
+			return nil
+		}))
+		accessTokenService.AddClaimValidator("second", service.ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+			calls = append(calls, "second")
+			return nil
+		}))
+
+		token, _ := accessTokenService.CreateAccessToken(user)
+		if _, err := accessTokenService.VerifyAccessToken(token); err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+			t.Fatalf("The validators should run in registration order, got %v", calls)
+		}
+	})
+
+	t.Run("Fail - First failing validator rejects the token", func(t *testing.T) {
+		called := false
+		accessTokenService := service.NewAccessTokenService(&config)
+		accessTokenService.AddClaimValidator("tenant", service.ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+			return errWrongTenant
+		}))
+		accessTokenService.AddClaimValidator("never", service.ClaimValidatorFunc(func(claim *modelAuth.Claim) error {
+			called = true
+			return nil
+		}))
+
+		token, _ := accessTokenService.CreateAccessToken(user)
+		claim, err := accessTokenService.VerifyAccessToken(token)
+		if claim != nil {
+			t.Fatal("No claims should be returned for a rejected token")
+		}
+		var rejected *service.ClaimValidationError
+		if !errors.As(err, &rejected) || rejected.Validator != "tenant" {
+			t.Fatalf("The error should be a claim validation error from the tenant validator, got : %v", err)
+		}
+		if !errors.Is(err, service.ErrClaimRejected) || !errors.Is(err, errWrongTenant) {
+			t.Fatalf("The error should match ErrClaimRejected and the validator error, got : %v", err)
+		}
+		if called {
+			t.Fatal("Validators after the failing one should not run")
+		}
+	})
+
+	t.Run("Fail - Step-up requirement as validator", func(t *testing.T) {
+		accessTokenService := service.NewAccessTokenService(&config)
+		accessTokenService.AddClaimValidator("mfa", service.StepUpRequirement{ACR: modelAuth.AssuranceLevel2})
+
+		token, _ := accessTokenService.CreateAccessToken(user)
+		_, err := accessTokenService.VerifyAccessToken(token)
+		if !errors.Is(err, service.ErrInsufficientUserAuthentication) {
+			t.Fatalf("The error should be an insufficient user authentication error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_CreateIDToken(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	accessTokenService := service.NewAccessTokenService(&config)
+	verified := true
+
+	t.Run("Success - OIDC claims", func(t *testing.T) {
+		authTime := time.Now().Add(-time.Minute)
+		token, err := accessTokenService.CreateIDToken(user, service.IDTokenParams{
+			Audience:      "client-1",
+			Nonce:         "n-0S6_WzA2Mj",
+			EmailVerified: &verified,
+			Authentication: &modelAuth.Authentication{
+				Methods: []modelAuth.AuthenticationMethod{modelAuth.AuthenticationMethodPassword},
+				Time:    authTime,
+			},
+		})
+		if err != nil {
+			t.Fatalf("The test expect no error on id token creation, got : %v", err)
+		}
+
+		claim, err := accessTokenService.VerifyIDToken(token, "client-1", "n-0S6_WzA2Mj")
+		if err != nil {
+			t.Fatalf("The test expect no error on id token verification, got : %v", err)
+		}
+		if claim.Subject != user.ID || claim.Issuer != config.Issuer || claim.Email != user.Email {
+			t.Fatalf("Unexpected subject %s, issuer %s or email %s", claim.Subject, claim.Issuer, claim.Email)
+		}
+		if claim.AuthTime == nil || claim.AuthTime.Unix() != authTime.Unix() {
+			t.Fatal("The auth_time claim should be the authentication time")
+		}
+		if claim.EmailVerified == nil || !*claim.EmailVerified || claim.ACR != modelAuth.AssuranceLevel1 {
+			t.Fatalf("Unexpected email_verified or acr claim %v", claim)
+		}
+	})
+
+	t.Run("Fail - Wrong audience or nonce", func(t *testing.T) {
+		token, _ := accessTokenService.CreateIDToken(user, service.IDTokenParams{Audience: "client-1", Nonce: "nonce"})
+
+		if _, err := accessTokenService.VerifyIDToken(token, "client-2", "nonce"); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+			t.Fatalf("The test expect an invalid audience error, got : %v", err)
+		}
+		if _, err := accessTokenService.VerifyIDToken(token, "client-1", "other"); !errors.Is(err, service.ErrInvalidNonce) {
+			t.Fatalf("The test expect an invalid nonce error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - ID and access tokens are not interchangeable", func(t *testing.T) {
+		idToken, _ := accessTokenService.CreateIDToken(user, service.IDTokenParams{Audience: "client-1"})
+		if _, err := accessTokenService.VerifyAccessToken(idToken); err == nil {
+			t.Fatal("An id token should not be accepted as access token")
+		}
+
+		accessToken, _ := accessTokenService.CreateAccessToken(user)
+		if _, err := accessTokenService.VerifyIDToken(accessToken, "client-1", ""); err == nil {
+			t.Fatal("An access token should not be accepted as id token")
+		}
+
+		audienceToken, _ := accessTokenService.CreateAccessTokenForAudience(user, "client-1")
+		if _, err := accessTokenService.VerifyIDToken(audienceToken, "client-1", ""); !errors.Is(err, service.ErrInvalidTokenClaim) {
+			t.Fatalf("An access token for the client audience should not be accepted as id token, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Missing audience", func(t *testing.T) {
+		_, err := accessTokenService.CreateIDToken(user, service.IDTokenParams{})
+		if err == nil || err.Error() != "invalid audience" {
+			t.Fatalf("The test expect an invalid audience error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_CreateAccessTokenForAudience(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	accessTokenService := service.NewAccessTokenService(&config)
+	accessTokenService.AddClaimValidator("audience", service.RequireAudience("billing-api"))
+
+	t.Run("Success - Audience required", func(t *testing.T) {
+		token, err := accessTokenService.CreateAccessTokenForAudience(user, "billing-api")
+		if err != nil {
+			t.Fatalf("The test expect no error on access token creation, got : %v", err)
+		}
+		if _, err := accessTokenService.VerifyAccessToken(token); err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Other audience", func(t *testing.T) {
+		token, _ := accessTokenService.CreateAccessTokenForAudience(user, "reports-api")
+		if _, err := accessTokenService.VerifyAccessToken(token); !errors.Is(err, service.ErrClaimRejected) {
+			t.Fatalf("The test expect a claim rejected error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_CreateAccessTokenWithClaims(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	accessTokenService := service.NewAccessTokenService(&config)
+
+	t.Run("Success - Custom claims returned on verification", func(t *testing.T) {
+		token, err := accessTokenService.CreateAccessTokenWithClaims(user, map[string]any{"tenant": "acme", "roles": []string{"admin"}})
+		if err != nil {
+			t.Fatalf("The test expect no error on access token creation, got : %v", err)
+		}
+		claim, err := accessTokenService.VerifyAccessToken(token)
+		if err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if claim.Custom["tenant"] != "acme" {
+			t.Fatalf("The test expect the tenant claim to be acme, got : %v", claim.Custom["tenant"])
+		}
+		if claim.Subject != user.ID || claim.Email != user.Email {
+			t.Fatalf("The test expect the standard claims to be kept, got : %s %s", claim.Subject, claim.Email)
+		}
+	})
+
+	t.Run("Success - No custom claims", func(t *testing.T) {
+		token, _ := accessTokenService.CreateAccessToken(user)
+		claim, err := accessTokenService.VerifyAccessToken(token)
+		if err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if claim.Custom != nil {
+			t.Fatalf("The test expect no custom claims, got : %v", claim.Custom)
+		}
+	})
+
+	t.Run("Fail - Reserved claim name", func(t *testing.T) {
+		_, err := accessTokenService.CreateAccessTokenWithClaims(user, map[string]any{"sub": "2"})
+		if !errors.Is(err, service.ErrReservedClaim) {
+			t.Fatalf("The test expect a reserved claim error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Claims over budget", func(t *testing.T) {
+		_, err := accessTokenService.CreateAccessTokenWithClaims(user, map[string]any{"blob": strings.Repeat("a", 2048)})
+		if !errors.Is(err, service.ErrClaimsTooLarge) {
+			t.Fatalf("The test expect a claims too large error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Claim not allowed", func(t *testing.T) {
+		restricted := config
+		restricted.AccessTokenAllowedClaims = []string{"tenant"}
+		_, err := service.NewAccessTokenService(&restricted).CreateAccessTokenWithClaims(user, map[string]any{"roles": "admin"})
+		if !errors.Is(err, service.ErrClaimNotAllowed) {
+			t.Fatalf("The test expect a claim not allowed error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Token over maximum size", func(t *testing.T) {
+		restricted := config
+		restricted.AccessTokenMaxSize = 64
+		_, err := service.NewAccessTokenService(&restricted).CreateAccessToken(user)
+		if !errors.Is(err, service.ErrAccessTokenTooLarge) {
+			t.Fatalf("The test expect an access token too large error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_WithClock(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	past := func() time.Time { return time.Now().Add(-time.Hour) }
+	pastService := service.NewAccessTokenService(&config, service.WithClock(past))
+	accessTokenService := service.NewAccessTokenService(&config)
+
+	t.Run("Success - Verified with the same clock", func(t *testing.T) {
+		token, _ := pastService.CreateAccessToken(user)
+		if _, err := pastService.VerifyAccessToken(token); err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Expired for the real clock", func(t *testing.T) {
+		token, _ := pastService.CreateAccessToken(user)
+		if _, err := accessTokenService.VerifyAccessToken(token); !errors.Is(err, jwt.ErrTokenExpired) {
+			t.Fatalf("The test expect an expired token error, got : %v", err)
+		}
+	})
+}
+
+func Test_Auth_AccessToken_AlgorithmAllowList(t *testing.T) {
+	user := modelAuth.NewUser("1", "user@mail.com")
+	config := lib.Config{
+		Issuer:    "test_auth.com",
+		JWTSecret: "rand0mString_",
+		JWTExpiry: "15m",
+	}
+	accessTokenService := service.NewAccessTokenService(&config)
+	forge := func(method jwt.SigningMethod, key any) string {
+		claim := &modelAuth.Claim{
+			KeyType: "access",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   user.ID,
+				Issuer:    config.Issuer,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		token, err := jwt.NewWithClaims(method, claim).SignedString(key)
+		if err != nil {
+			t.Fatalf("The test expect no error on token forging, got : %v", err)
+		}
+		return token
+	}
+
+	t.Run("Fail - alg none", func(t *testing.T) {
+		token := forge(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)
+		if _, err := accessTokenService.VerifyAccessToken(token); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatalf("The test expect an invalid signature error, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Other HMAC algorithm with the secret", func(t *testing.T) {
+		token := forge(jwt.SigningMethodHS512, []byte(config.JWTSecret))
+		if _, err := accessTokenService.VerifyAccessToken(token); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatalf("The test expect an invalid signature error, got : %v", err)
+		}
+	})
+
+	t.Run("Success - Configured algorithm", func(t *testing.T) {
+		hs512Config := config
+		hs512Config.JWTAlgorithm = "HS512"
+		hs512Service := service.NewAccessTokenService(&hs512Config)
+
+		token, err := hs512Service.CreateAccessToken(user)
+		if err != nil {
+			t.Fatalf("The test expect no error on access token creation, got : %v", err)
+		}
+		unverified, err := service.ParseUnverified(token)
+		if err != nil || unverified.Header["alg"] != "HS512" {
+			t.Fatalf("The test expect an HS512 token, got : %v (%v)", unverified, err)
+		}
+		if _, err := hs512Service.VerifyAccessToken(token); err != nil {
+			t.Fatalf("The test expect no error on access token verification, got : %v", err)
+		}
+		if _, err := accessTokenService.VerifyAccessToken(token); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatalf("The test expect the HS256 service to reject HS512 tokens, got : %v", err)
+		}
+
+		idToken, err := hs512Service.CreateIDToken(user, service.IDTokenParams{Audience: "client"})
+		if err != nil {
+			t.Fatalf("The test expect no error on id token creation, got : %v", err)
+		}
+		if _, err := hs512Service.VerifyIDToken(idToken, "client", ""); err != nil {
+			t.Fatalf("The test expect no error on id token verification, got : %v", err)
+		}
+	})
+
+	t.Run("Fail - Unsupported algorithm", func(t *testing.T) {
+		rsaConfig := config
+		rsaConfig.JWTAlgorithm = "RS256"
+		rsaService := service.NewAccessTokenService(&rsaConfig)
+
+		if _, err := rsaService.CreateAccessToken(user); err == nil || !strings.Contains(err.Error(), "unsupported JWT algorithm") {
+			t.Fatalf("The test expect an unsupported algorithm error, got : %v", err)
+		}
+		if _, err := rsaService.VerifyAccessToken(forge(jwt.SigningMethodHS256, []byte(config.JWTSecret))); err == nil {
+			t.Fatal("The test expect an error on access token verification")
+		}
+	})
+}
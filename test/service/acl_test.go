@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisACLSetUser(t *testing.T) {
+	t.Run("Should restrict the user to the key prefix", func(t *testing.T) {
+		command, err := service.RedisACLSetUser("tokens", service.WithKeyPrefix("myapp"))
+		require.NoError(t, err)
+		assert.Equal(t, "ACL SETUSER tokens on resetkeys ~myapp:* resetchannels &myapp:* "+
+			"-@all +@read +@write +@keyspace +@transaction +@scripting +@pubsub +@connection -@dangerous", command)
+	})
+
+	t.Run("Should fail without key prefix", func(t *testing.T) {
+		_, err := service.RedisACLSetUser("tokens")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "key prefix is required")
+	})
+
+	t.Run("Should fail with invalid username or prefix", func(t *testing.T) {
+		_, err := service.RedisACLSetUser("", service.WithKeyPrefix("myapp"))
+		require.Error(t, err)
+		_, err = service.RedisACLSetUser("tokens on", service.WithKeyPrefix("myapp"))
+		require.Error(t, err)
+		_, err = service.RedisACLSetUser("tokens", service.WithKeyPrefix("my*app"))
+		require.Error(t, err)
+	})
+
+	t.Run("Should let the services work with the restricted user", func(t *testing.T) {
+		command, err := service.RedisACLSetUser("acl-test", service.WithKeyPrefix("acltest"))
+		require.NoError(t, err)
+		restricted := restrictedClient(t, "acl-test", command)
+
+		rts, err := service.NewRefreshTokenService(t.Context(), restricted, config, service.WithKeyPrefix("acltest"))
+		require.NoError(t, err)
+		token, err := rts.CreateRefreshToken(t.Context(), "1")
+		require.NoError(t, err)
+		valid, err := rts.VerifyRefreshToken(t.Context(), "1", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		require.NoError(t, rts.RevokeAllRefreshTokens(t.Context()))
+
+		require.Error(t, restricted.Get(t.Context(), "refresh:other-app").Err(), "keys outside the prefix are denied")
+
+		lock, err := lib.NewDistributedLock(restricted, "acl-cleanup", time.Second, lib.WithLockKeyPrefix("acltest"))
+		require.NoError(t, err)
+		lease, err := lock.Acquire(t.Context())
+		require.NoError(t, err, "locks under the prefix are allowed")
+		require.NoError(t, lease.Release(t.Context()))
+
+		election, err := lib.NewLeaderElection(restricted, "acl-maintenance", time.Second, 0, lib.WithLockKeyPrefix("acltest"))
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+		defer cancel()
+		led := false
+		_ = election.Run(ctx, func(ctx context.Context, term int64) {
+			led = true
+			cancel()
+		})
+		assert.True(t, led, "leader election under the prefix is allowed")
+	})
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCanaryRefreshToken(t *testing.T) {
+	rts := setupService(t)
+
+	t.Run("Should fail with empty label", func(t *testing.T) {
+		_, err := rts.CreateCanaryRefreshToken(t.Context(), "decoy", "", time.Hour)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "canary label is empty")
+	})
+
+	t.Run("Should fail with non-positive ttl", func(t *testing.T) {
+		_, err := rts.CreateCanaryRefreshToken(t.Context(), "decoy", "backup", 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "canary ttl must be positive")
+	})
+
+	t.Run("Should fail with empty user ID", func(t *testing.T) {
+		_, err := rts.CreateCanaryRefreshToken(t.Context(), "", "backup", time.Hour)
+		require.ErrorIs(t, err, service.ErrInvalidUserID)
+	})
+}
+
+func TestCanaryRefreshTokenTriggered(t *testing.T) {
+	rts := setupService(t)
+
+	var mu sync.Mutex
+	var alerts []service.CanaryAlert
+	var events []lib.AuditEvent
+	rts.SetCanaryHandler(func(_ context.Context, alert service.CanaryAlert) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, alert)
+	})
+	rts.SetAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+
+	canary, err := rts.CreateCanaryRefreshToken(t.Context(), "decoy", "db-backup", time.Hour)
+	require.NoError(t, err)
+
+	ctx := lib.WithRequestMeta(t.Context(), lib.RequestMeta{IP: "198.51.100.4"})
+	valid, err := rts.VerifyRefreshToken(ctx, "decoy", *canary)
+	require.NoError(t, err)
+	assert.False(t, valid, "canary tokens are never valid")
+
+	require.Len(t, alerts, 1)
+	assert.Equal(t, lib.TokenTypeRefresh, alerts[0].TokenType)
+	assert.Equal(t, "decoy", alerts[0].UserID)
+	assert.Equal(t, "db-backup", alerts[0].Label)
+	assert.Equal(t, "198.51.100.4", alerts[0].Meta.IP)
+	assert.Equal(t, lib.TokenFingerprint(*canary), alerts[0].Fingerprint)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, service.AuditEventCanaryTokenTriggered, events[0].Type)
+	assert.Equal(t, "db-backup", events[0].Details["label"])
+	assert.Equal(t, lib.TokenFingerprint(*canary), events[0].Details["token_fingerprint"])
+
+	t.Run("Should trigger on batch verification", func(t *testing.T) {
+		results, err := rts.VerifyRefreshTokens(t.Context(), []service.RefreshTokenCredential{{UserID: "decoy", Token: *canary}})
+		require.NoError(t, err)
+		assert.False(t, results[*canary].Valid)
+		assert.Len(t, alerts, 2)
+	})
+
+	t.Run("Should not affect regular tokens", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(t.Context(), "decoy")
+		require.NoError(t, err)
+		valid, err := rts.VerifyRefreshToken(t.Context(), "decoy", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Len(t, alerts, 2)
+	})
+}
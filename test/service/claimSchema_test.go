@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClaimSchema(t *testing.T) {
+	t.Run("Should refuse unsupported keywords", func(t *testing.T) {
+		_, err := service.NewClaimSchema([]byte(`{"properties": {"email": {"format": "email"}}}`))
+		assert.ErrorContains(t, err, "/properties/email/format: unsupported keyword")
+	})
+
+	t.Run("Should refuse a root schema that is not an object", func(t *testing.T) {
+		_, err := service.NewClaimSchema([]byte(`{"type": "string"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Should refuse invalid JSON", func(t *testing.T) {
+		_, err := service.NewClaimSchema([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
+
+func TestAccessTokenService_ClaimSchema(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	unchecked, err := accessService.CreateAccessTokenWithClaims(user, map[string]any{"tenant": "ACME"})
+	require.NoError(t, err)
+
+	schema, err := service.NewClaimSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"tenant": {"type": "string", "pattern": "^[a-z0-9-]+$"},
+			"roles": {"type": "array", "items": {"enum": ["viewer", "admin"]}, "maxItems": 2},
+			"level": {"type": "integer", "minimum": 1}
+		},
+		"required": ["tenant"],
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+	accessService.SetClaimSchema(schema)
+
+	t.Run("Should create and verify matching claims", func(t *testing.T) {
+		token, err := accessService.CreateAccessTokenWithClaims(user, map[string]any{
+			"tenant": "acme",
+			"roles":  []string{"admin"},
+			"level":  3,
+		})
+		require.NoError(t, err)
+
+		claim, err := accessService.VerifyAccessToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", claim.Custom["tenant"])
+	})
+
+	t.Run("Should refuse to create tokens with malformed claims", func(t *testing.T) {
+		cases := []struct {
+			claims map[string]any
+			path   string
+		}{
+			{map[string]any{"tenant": "ACME"}, "/tenant"},
+			{map[string]any{"tenant": "acme", "roles": []string{"root"}}, "/roles/0"},
+			{map[string]any{"tenant": "acme", "level": 1.5}, "/level"},
+			{map[string]any{"tenant": "acme", "plan": "pro"}, "/plan"},
+			{nil, "/tenant"},
+		}
+		for _, c := range cases {
+			_, err := accessService.CreateAccessTokenWithClaims(user, c.claims)
+			assert.ErrorIs(t, err, service.ErrClaimSchemaViolation)
+
+			var violation *service.ClaimSchemaError
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, c.path, violation.Path)
+		}
+
+		_, err := accessService.CreateAccessToken(user)
+		assert.ErrorIs(t, err, service.ErrClaimSchemaViolation, "The schema applies to tokens without custom claims")
+	})
+
+	t.Run("Should reject tokens with malformed claims", func(t *testing.T) {
+		_, err := accessService.VerifyAccessToken(unchecked)
+		assert.ErrorIs(t, err, service.ErrClaimRejected)
+		assert.ErrorIs(t, err, service.ErrClaimSchemaViolation)
+
+		var rejected *service.ClaimValidationError
+		require.ErrorAs(t, err, &rejected)
+		assert.Equal(t, "schema", rejected.Validator)
+	})
+}
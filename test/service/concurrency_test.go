@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests share one service instance across goroutines while its hooks are
+// reconfigured; run them with -race.
+
+const concurrencyWorkers = 16
+
+func TestAccessTokenService_Concurrency(t *testing.T) {
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	var wg sync.WaitGroup
+	for i := range concurrencyWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%4 == 0 {
+				accessService.SetAuditLogger(lib.AuditLoggerFunc(func(context.Context, lib.AuditEvent) {}))
+				accessService.AddClaimValidator("noop", service.ClaimValidatorFunc(func(*modelAuth.Claim) error { return nil }))
+				return
+			}
+			token, err := accessService.CreateAccessToken(user)
+			assert.NoError(t, err)
+			_, err = accessService.VerifyAccessToken(token)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRefreshTokenService_Concurrency(t *testing.T) {
+	rts := setupService(t)
+
+	var wg sync.WaitGroup
+	for i := range concurrencyWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%4 == 0 {
+				rts.SetAuditLogger(lib.AuditLoggerFunc(func(context.Context, lib.AuditEvent) {}))
+				rts.SetRiskEvaluator(nil)
+				rts.SetGeoPolicy(nil)
+				return
+			}
+			token, err := rts.CreateRefreshToken(context.Background(), "123")
+			if !assert.NoError(t, err) {
+				return
+			}
+			valid, err := rts.VerifyRefreshToken(context.Background(), "123", *token)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+			assert.NoError(t, rts.RevokeRefreshToken(context.Background(), *token, "123"))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestServices_KeepConfigSnapshot(t *testing.T) {
+	shared := config.Clone()
+	rts, err := service.NewRefreshTokenService(context.Background(), redisDB, shared)
+	require.NoError(t, err)
+
+	shared.TokenPrefix = true
+	token, err := rts.CreateRefreshToken(context.Background(), "123")
+	require.NoError(t, err)
+	assert.NotContains(t, *token, "rt_v1_")
+}
+
+func TestServices_ConcurrentStartup(t *testing.T) {
+	// Constructors never reach Redis: replicas can start concurrently, even before Redis is up.
+	unreachable := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() { _ = unreachable.Close() })
+
+	var wg sync.WaitGroup
+	for range concurrencyWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.NewRefreshTokenService(t.Context(), unreachable, config)
+			assert.NoError(t, err)
+			_, err = service.NewPasswordResetService(t.Context(), unreachable, config)
+			assert.NoError(t, err)
+			_, err = service.NewOTPService(t.Context(), unreachable, config)
+			assert.NoError(t, err)
+			_, err = service.NewTokenEpochService(t.Context(), unreachable)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDeletionTokenService(t *testing.T, opts ...service.Option) *service.DeletionTokenService {
+	dts, err := service.NewDeletionTokenService(t.Context(), redisDB, config, opts...)
+	require.NoError(t, err)
+	require.NoError(t, redisDB.Del(t.Context(), "account_deletion_schedule").Err())
+	return dts
+}
+
+func TestNewDeletionTokenService(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewDeletionTokenService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+}
+
+func TestDeletionTokenService(t *testing.T) {
+	dts := setupDeletionTokenService(t)
+
+	t.Run("Should schedule the deletion after the grace period", func(t *testing.T) {
+		token, deleteAt, err := dts.ScheduleDeletion(context.Background(), "123")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), deleteAt, 2*time.Second)
+
+		scheduled, found, err := dts.ScheduledDeletion(context.Background(), "123")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, deleteAt, scheduled)
+
+		elapsed, err := dts.ListElapsedDeletions(context.Background(), 10)
+		require.NoError(t, err)
+		assert.NotContains(t, elapsed, "123")
+	})
+
+	t.Run("Should cancel the deletion with the token", func(t *testing.T) {
+		token, _, err := dts.ScheduleDeletion(context.Background(), "123")
+		require.NoError(t, err)
+
+		userID, err := dts.CancelDeletion(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, userID)
+		assert.Equal(t, "123", *userID)
+
+		_, found, err := dts.ScheduledDeletion(context.Background(), "123")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		userID, err = dts.CancelDeletion(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, userID)
+	})
+
+	t.Run("Should revoke the previous token when rescheduling", func(t *testing.T) {
+		first, _, err := dts.ScheduleDeletion(context.Background(), "123")
+		require.NoError(t, err)
+		_, _, err = dts.ScheduleDeletion(context.Background(), "123")
+		require.NoError(t, err)
+
+		userID, err := dts.CancelDeletion(context.Background(), *first)
+		require.NoError(t, err)
+		assert.Nil(t, userID)
+
+		require.NoError(t, dts.CancelUserDeletion(context.Background(), "123"))
+		_, found, err := dts.ScheduledDeletion(context.Background(), "123")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		_, _, err := dts.ScheduleDeletion(context.Background(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+}
+
+func TestDeletionTokenService_Elapsed(t *testing.T) {
+	dts := setupDeletionTokenService(t)
+	past := setupDeletionTokenService(t, service.WithClock(func() time.Time {
+		return time.Now().Add(-15 * 24 * time.Hour)
+	}))
+
+	token, _, err := past.ScheduleDeletion(context.Background(), "123")
+	require.NoError(t, err)
+	_, _, err = dts.ScheduleDeletion(context.Background(), "456")
+	require.NoError(t, err)
+
+	t.Run("Should list the elapsed deletions only", func(t *testing.T) {
+		elapsed, err := dts.ListElapsedDeletions(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"123"}, elapsed)
+	})
+
+	t.Run("Should refuse cancellation once the grace period elapsed", func(t *testing.T) {
+		userID, err := dts.CancelDeletion(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, userID)
+	})
+
+	t.Run("Should complete the deletion", func(t *testing.T) {
+		require.NoError(t, dts.CompleteDeletion(context.Background(), "123"))
+
+		elapsed, err := dts.ListElapsedDeletions(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, elapsed)
+	})
+}
+
+func TestDeletionTokenService_SetUserDataService(t *testing.T) {
+	dts := setupDeletionTokenService(t)
+	rts := setupService(t)
+	dts.SetUserDataService(service.NewUserDataService(rts, nil, nil))
+
+	t.Run("Should erase the tokens of the deleted user", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(t.Context(), "deleted-123")
+		require.NoError(t, err)
+		otherToken, err := rts.CreateRefreshToken(t.Context(), "kept-456")
+		require.NoError(t, err)
+		_, _, err = dts.ScheduleDeletion(t.Context(), "deleted-123")
+		require.NoError(t, err)
+
+		require.NoError(t, dts.CompleteDeletion(t.Context(), "deleted-123"))
+
+		valid, err := rts.VerifyRefreshToken(t.Context(), "deleted-123", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		valid, err = rts.VerifyRefreshToken(t.Context(), "kept-456", *otherToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		_, scheduled, err := dts.ScheduledDeletion(t.Context(), "deleted-123")
+		require.NoError(t, err)
+		assert.False(t, scheduled)
+	})
+
+	t.Run("Should keep the tokens without cascade", func(t *testing.T) {
+		dts.SetUserDataService(nil)
+		token, err := rts.CreateRefreshToken(t.Context(), "deleted-789")
+		require.NoError(t, err)
+
+		require.NoError(t, dts.CompleteDeletion(t.Context(), "deleted-789"))
+
+		valid, err := rts.VerifyRefreshToken(t.Context(), "deleted-789", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
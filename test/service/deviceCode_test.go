@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDeviceCodeService(t *testing.T) *service.DeviceCodeService {
+	interval := "50ms"
+	dcs, err := service.NewDeviceCodeService(t.Context(), redisDB, &lib.Config{DeviceCodePollInterval: &interval})
+	require.NoError(t, err)
+
+	// Clear all device codes to ensure clean state
+	err = dcs.RevokeAllDeviceCodes(t.Context())
+	require.NoError(t, err)
+
+	return dcs
+}
+
+func TestNewDeviceCodeService(t *testing.T) {
+	t.Run("Should create service successfully", func(t *testing.T) {
+		_, err := service.NewDeviceCodeService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewDeviceCodeService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with invalid poll interval", func(t *testing.T) {
+		interval := "invalid-duration"
+		_, err := service.NewDeviceCodeService(context.Background(), redisDB, &lib.Config{DeviceCodePollInterval: &interval})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid device code poll interval format")
+	})
+}
+
+func TestDeviceCodeFlow(t *testing.T) {
+	dcs := setupDeviceCodeService(t)
+	userID := "123"
+
+	t.Run("Should create a device authorization", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+		assert.Len(t, auth.DeviceCode, 64)
+		assert.Len(t, auth.UserCode, 9)
+		assert.Equal(t, 10*time.Minute, auth.ExpiresIn)
+		assert.Equal(t, 50*time.Millisecond, auth.Interval)
+	})
+
+	t.Run("Should exchange after approval", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+
+		// User code entry is case-insensitive and the dash is optional
+		approved, err := dcs.Approve(context.Background(), strings.ToLower(strings.Replace(auth.UserCode, "-", "", 1)), userID)
+		require.NoError(t, err)
+		assert.True(t, approved)
+
+		time.Sleep(60 * time.Millisecond)
+		exchanged, err := dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		require.NoError(t, err)
+		assert.Equal(t, userID, *exchanged)
+
+		// Single-use
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		assert.ErrorIs(t, err, service.ErrExpiredToken)
+	})
+
+	t.Run("Should fail exchange after denial", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+
+		denied, err := dcs.Deny(context.Background(), auth.UserCode, userID)
+		require.NoError(t, err)
+		assert.True(t, denied)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		assert.ErrorIs(t, err, service.ErrAccessDenied)
+	})
+
+	t.Run("Should not approve a user code twice", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+
+		approved, err := dcs.Approve(context.Background(), auth.UserCode, userID)
+		require.NoError(t, err)
+		assert.True(t, approved)
+
+		approved, err = dcs.Approve(context.Background(), auth.UserCode, "456")
+		require.NoError(t, err)
+		assert.False(t, approved)
+	})
+
+	t.Run("Should slow down fast polling", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		assert.ErrorIs(t, err, service.ErrSlowDown)
+	})
+
+	t.Run("Should exchange an approval once under concurrent polls", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+		approved, err := dcs.Approve(context.Background(), auth.UserCode, userID)
+		require.NoError(t, err)
+		require.True(t, approved)
+
+		var exchanged atomic.Int32
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				if _, err := dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app"); err == nil {
+					exchanged.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), exchanged.Load())
+	})
+
+	t.Run("Should refuse the device code of another client", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+		approved, err := dcs.Approve(context.Background(), auth.UserCode, userID)
+		require.NoError(t, err)
+		require.True(t, approved)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "other-app")
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+
+		// The authorization is left for its client
+		exchanged, err := dcs.Exchange(context.Background(), auth.DeviceCode, "tv-app")
+		require.NoError(t, err)
+		assert.Equal(t, userID, *exchanged)
+
+		_, err = dcs.Exchange(context.Background(), auth.DeviceCode, "")
+		require.Error(t, err)
+		assert.Equal(t, "invalid client id", err.Error())
+	})
+
+	t.Run("Should not approve an expired authorization", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+		keys, err := redisDB.Keys(context.Background(), "device_code:*").Result()
+		require.NoError(t, err)
+		require.NoError(t, redisDB.Del(context.Background(), keys...).Err())
+
+		approved, err := dcs.Approve(context.Background(), auth.UserCode, userID)
+		require.NoError(t, err)
+		assert.False(t, approved)
+
+		exists, err := redisDB.Exists(context.Background(), keys...).Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists, "A decision never recreates the authorization")
+	})
+
+	t.Run("Should fail with unknown device code", func(t *testing.T) {
+		_, err := dcs.Exchange(context.Background(), "unknown", "tv-app")
+		assert.ErrorIs(t, err, service.ErrExpiredToken)
+	})
+
+	t.Run("Should fail with empty client id", func(t *testing.T) {
+		_, err := dcs.CreateDeviceCode(context.Background(), "")
+		require.Error(t, err)
+		assert.Equal(t, "invalid client id", err.Error())
+	})
+}
+
+func TestDeviceCodeRateLimit(t *testing.T) {
+	dcs := setupDeviceCodeService(t)
+	userID := "123"
+
+	t.Run("Should block user code entry after max attempts", func(t *testing.T) {
+		auth, err := dcs.CreateDeviceCode(context.Background(), "tv-app")
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			approved, err := dcs.Approve(context.Background(), "AAAA-AAAA", userID)
+			require.NoError(t, err)
+			assert.False(t, approved)
+		}
+
+		_, err = dcs.Approve(context.Background(), auth.UserCode, userID)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max attempts exceeded")
+	})
+
+	t.Run("Should fail with malformed user code", func(t *testing.T) {
+		_, err := dcs.Approve(context.Background(), "ABC", "456")
+		require.Error(t, err)
+		assert.Equal(t, "invalid user code", err.Error())
+	})
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistributedLock(t *testing.T) {
+	lock, err := lib.NewDistributedLock(redisDB, "test-lock", time.Second)
+	require.NoError(t, err)
+
+	t.Run("Should let a single owner in and increase the fencing token", func(t *testing.T) {
+		first, err := lock.Acquire(context.Background())
+		require.NoError(t, err)
+
+		_, err = lock.Acquire(context.Background())
+		assert.ErrorIs(t, err, lib.ErrLockNotAcquired)
+
+		require.NoError(t, first.Refresh(context.Background()))
+		require.NoError(t, first.Release(context.Background()))
+
+		second, err := lock.Acquire(context.Background())
+		require.NoError(t, err)
+		defer second.Release(context.Background())
+		assert.Greater(t, second.Fence, first.Fence)
+
+		assert.ErrorIs(t, first.Refresh(context.Background()), lib.ErrLockLost)
+		require.NoError(t, first.Release(context.Background()))
+		_, err = lock.Acquire(context.Background())
+		assert.ErrorIs(t, err, lib.ErrLockNotAcquired, "a stale lease should not release the lock")
+	})
+
+	t.Run("Should namespace the keys with the key prefix", func(t *testing.T) {
+		prefixed, err := lib.NewDistributedLock(redisDB, "test-lock-prefixed", time.Second, lib.WithLockKeyPrefix("myapp"))
+		require.NoError(t, err)
+		lease, err := prefixed.Acquire(context.Background())
+		require.NoError(t, err)
+		defer lease.Release(context.Background())
+
+		exists, err := redisDB.Exists(context.Background(), "myapp:lock:test-lock-prefixed", "myapp:lock:test-lock-prefixed:fence").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), exists)
+	})
+
+	t.Run("Should run the job while holding the lock", func(t *testing.T) {
+		short, err := lib.NewDistributedLock(redisDB, "test-lock-run", 150*time.Millisecond)
+		require.NoError(t, err)
+
+		err = short.Run(context.Background(), func(ctx context.Context, fence int64) error {
+			assert.Positive(t, fence)
+			// Outlive the TTL: the lease must be refreshed
+			time.Sleep(400 * time.Millisecond)
+			_, err := short.Acquire(ctx)
+			assert.ErrorIs(t, err, lib.ErrLockNotAcquired)
+			return nil
+		})
+		require.NoError(t, err)
+
+		lease, err := short.Acquire(context.Background())
+		require.NoError(t, err, "the lock should be released after the job")
+		require.NoError(t, lease.Release(context.Background()))
+	})
+
+	t.Run("Should cancel the job when the lease is lost", func(t *testing.T) {
+		short, err := lib.NewDistributedLock(redisDB, "test-lock-lost", 150*time.Millisecond)
+		require.NoError(t, err)
+
+		err = short.Run(context.Background(), func(ctx context.Context, _ int64) error {
+			require.NoError(t, redisDB.Del(ctx, "lock:test-lock-lost").Err())
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, lib.ErrLockLost)
+	})
+}
+
+func TestCleanupLock(t *testing.T) {
+	rts := setupService(t)
+	lock, err := lib.NewDistributedLock(redisDB, "test-cleanup", time.Second)
+	require.NoError(t, err)
+
+	t.Run("Should skip the revocation while another replica holds the lock", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		lease, err := lock.Acquire(context.Background())
+		require.NoError(t, err)
+
+		_, err = rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{Lock: lock})
+		assert.ErrorIs(t, err, lib.ErrLockNotAcquired)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		require.NoError(t, lease.Release(context.Background()))
+		revoked, err := rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{Lock: lock})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+
+		// Released after the revocation
+		lease, err = lock.Acquire(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, lease.Release(context.Background()))
+	})
+}
+
+func TestLeaderElection(t *testing.T) {
+	t.Run("Should elect a single leader and hand over when it stops", func(t *testing.T) {
+		first, err := lib.NewLeaderElection(redisDB, "test-election", 300*time.Millisecond, 20*time.Millisecond)
+		require.NoError(t, err)
+		second, err := lib.NewLeaderElection(redisDB, "test-election", 300*time.Millisecond, 20*time.Millisecond)
+		require.NoError(t, err)
+
+		terms := make(chan int64, 2)
+		lead := func(ctx context.Context, term int64) {
+			terms <- term
+			<-ctx.Done()
+		}
+
+		firstCtx, stopFirst := context.WithCancel(context.Background())
+		firstDone := make(chan struct{})
+		go func() {
+			_ = first.Run(firstCtx, lead)
+			close(firstDone)
+		}()
+		require.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+		firstTerm := <-terms
+
+		secondCtx, stopSecond := context.WithCancel(context.Background())
+		secondDone := make(chan struct{})
+		go func() {
+			_ = second.Run(secondCtx, lead)
+			close(secondDone)
+		}()
+		t.Cleanup(func() {
+			stopSecond()
+			<-secondDone
+		})
+
+		// The follower waits while the leader refreshes its lease beyond the TTL
+		time.Sleep(500 * time.Millisecond)
+		assert.False(t, second.IsLeader())
+
+		stopFirst()
+		<-firstDone
+		assert.False(t, first.IsLeader())
+
+		require.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+		assert.Greater(t, <-terms, firstTerm)
+	})
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doctorStatuses(report *service.DoctorReport) map[string]service.DoctorStatus {
+	statuses := make(map[string]service.DoctorStatus)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+	secret := "a-very-long-secret-of-at-least-32-bytes"
+
+	t.Run("Should report a healthy setup", func(t *testing.T) {
+		config := lib.NewConfig("doctor", secret, "15m", "", "", "", 0, nil, nil, nil)
+
+		report := service.Doctor(t.Context(), redisDB, config, service.WithKeyPrefix("doctor-app"))
+		require.NoError(t, report.Err())
+		assert.True(t, report.Healthy)
+
+		statuses := doctorStatuses(report)
+		assert.Equal(t, service.DoctorStatusOK, statuses["redis.setup"])
+		assert.Equal(t, service.DoctorStatusOK, statuses["redis.schema_version"])
+		for name, status := range statuses {
+			assert.Equal(t, service.DoctorStatusOK, status, name)
+		}
+	})
+
+	t.Run("Should report every configuration problem", func(t *testing.T) {
+		ttl := "soon"
+		config := lib.NewConfig("", "short", "24h", "", "", "", 0, &ttl, nil, nil)
+		config.RefreshTokenLength = 8
+		config.JWTAlgorithm = "RS256"
+		config.PasswordResetPolicy = "forget"
+		config.TokenChecksum = true
+
+		report := service.Doctor(t.Context(), redisDB, config)
+		assert.False(t, report.Healthy)
+
+		statuses := doctorStatuses(report)
+		assert.Equal(t, service.DoctorStatusWarning, statuses["config.jwt_secret"])
+		assert.Equal(t, service.DoctorStatusWarning, statuses["config.issuer"])
+		assert.Equal(t, service.DoctorStatusWarning, statuses["config.jwt_expiry"])
+		assert.Equal(t, service.DoctorStatusFailed, statuses["config.jwt_algorithm"])
+		assert.Equal(t, service.DoctorStatusFailed, statuses["config.durations"])
+		assert.Equal(t, service.DoctorStatusFailed, statuses["config.token_lengths"])
+		assert.Equal(t, service.DoctorStatusWarning, statuses["config.token_checksum"])
+		assert.Equal(t, service.DoctorStatusFailed, statuses["config.password_reset_policy"])
+		assert.Equal(t, service.DoctorStatusOK, statuses["redis.setup"])
+
+		err := report.Err()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `RefreshTokenTTL "soon"`)
+		assert.Contains(t, err.Error(), "refresh token length must be at least")
+		assert.NotContains(t, err.Error(), "config.jwt_secret")
+	})
+
+	t.Run("Should skip the schema check without Redis", func(t *testing.T) {
+		config := lib.NewConfig("doctor", "", "15m", "", "", "", 0, nil, nil, nil)
+
+		report := service.Doctor(t.Context(), nil, config)
+		assert.False(t, report.Healthy)
+
+		statuses := doctorStatuses(report)
+		assert.Equal(t, service.DoctorStatusFailed, statuses["config.jwt_secret"])
+		assert.Equal(t, service.DoctorStatusFailed, statuses["redis.setup"])
+		assert.Equal(t, service.DoctorStatusSkipped, statuses["redis.schema_version"])
+		assert.Contains(t, report.String(), "[skipped] redis.schema_version: redis.setup failed\n")
+	})
+
+	t.Run("Should fail with nil config", func(t *testing.T) {
+		report := service.Doctor(t.Context(), redisDB, nil)
+		assert.Contains(t, report.Err().Error(), "config is nil")
+	})
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewElevationService(t *testing.T) {
+	t.Run("Should fail with nil db", func(t *testing.T) {
+		_, err := service.NewElevationService(nil, time.Minute)
+		assert.Error(t, err)
+	})
+}
+
+func TestElevationService(t *testing.T) {
+	ctx := context.Background()
+	elevationService, err := service.NewElevationService(redisDB, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("Should verify a grant of the user", func(t *testing.T) {
+		grant, err := elevationService.Elevate(ctx, "elevation-1", service.ElevationMethodPassword)
+		require.NoError(t, err)
+		assert.NotEmpty(t, grant.Token)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), grant.ExpiresAt, time.Second)
+
+		verified, err := elevationService.VerifyElevation(ctx, "elevation-1", grant.Token, 0)
+		require.NoError(t, err)
+		assert.Equal(t, service.ElevationMethodPassword, verified.Method)
+
+		_, err = elevationService.VerifyElevation(ctx, "elevation-2", grant.Token, 0)
+		assert.ErrorIs(t, err, service.ErrElevationRequired)
+	})
+
+	t.Run("Should refuse a grant older than the max age", func(t *testing.T) {
+		grant, err := elevationService.Elevate(ctx, "elevation-3", service.ElevationMethodOTP)
+		require.NoError(t, err)
+
+		time.Sleep(1100 * time.Millisecond)
+		_, err = elevationService.VerifyElevation(ctx, "elevation-3", grant.Token, time.Millisecond)
+		assert.ErrorIs(t, err, service.ErrElevationRequired)
+
+		_, err = elevationService.VerifyElevation(ctx, "elevation-3", grant.Token, time.Minute)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should revoke grants", func(t *testing.T) {
+		first, err := elevationService.Elevate(ctx, "elevation-4", service.ElevationMethodPassword)
+		require.NoError(t, err)
+		second, err := elevationService.Elevate(ctx, "elevation-4", service.ElevationMethodPassword)
+		require.NoError(t, err)
+
+		require.NoError(t, elevationService.RevokeElevation(ctx, "elevation-4", first.Token))
+		_, err = elevationService.VerifyElevation(ctx, "elevation-4", first.Token, 0)
+		assert.ErrorIs(t, err, service.ErrElevationRequired)
+
+		revoked, err := elevationService.RevokeAllUserElevations(ctx, "elevation-4")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+		_, err = elevationService.VerifyElevation(ctx, "elevation-4", second.Token, 0)
+		assert.ErrorIs(t, err, service.ErrElevationRequired)
+	})
+
+	t.Run("Should fail with empty user ID", func(t *testing.T) {
+		_, err := elevationService.Elevate(ctx, "", service.ElevationMethodPassword)
+		assert.ErrorIs(t, err, service.ErrInvalidUserID)
+	})
+}
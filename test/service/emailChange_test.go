@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEmailChangeService(t *testing.T) *service.EmailChangeService {
+	ecs, err := service.NewEmailChangeService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+	require.NoError(t, ecs.RevokeAllEmailChanges(t.Context()))
+	return ecs
+}
+
+func TestNewEmailChangeService(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewEmailChangeService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+}
+
+func TestEmailChangeService(t *testing.T) {
+	ecs := setupEmailChangeService(t)
+
+	t.Run("Should confirm the new email once", func(t *testing.T) {
+		token, err := ecs.RequestEmailChange(context.Background(), "123", " new@example.com ")
+		require.NoError(t, err)
+
+		change, err := ecs.Confirm(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, change)
+		assert.Equal(t, "123", change.UserID)
+		assert.Equal(t, "new@example.com", change.NewEmail)
+
+		change, err = ecs.Confirm(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, change)
+	})
+
+	t.Run("Should revoke the previous pending change", func(t *testing.T) {
+		first, err := ecs.RequestEmailChange(context.Background(), "123", "first@example.com")
+		require.NoError(t, err)
+		second, err := ecs.RequestEmailChange(context.Background(), "123", "second@example.com")
+		require.NoError(t, err)
+
+		change, err := ecs.Confirm(context.Background(), *first)
+		require.NoError(t, err)
+		assert.Nil(t, change)
+
+		change, err = ecs.Confirm(context.Background(), *second)
+		require.NoError(t, err)
+		require.NotNil(t, change)
+		assert.Equal(t, "second@example.com", change.NewEmail)
+	})
+
+	t.Run("Should keep the changes of other users", func(t *testing.T) {
+		token, err := ecs.RequestEmailChange(context.Background(), "123", "mine@example.com")
+		require.NoError(t, err)
+		_, err = ecs.RequestEmailChange(context.Background(), "456", "other@example.com")
+		require.NoError(t, err)
+
+		change, err := ecs.Confirm(context.Background(), *token)
+		require.NoError(t, err)
+		assert.NotNil(t, change)
+	})
+
+	t.Run("Should cancel the pending change", func(t *testing.T) {
+		token, err := ecs.RequestEmailChange(context.Background(), "123", "new@example.com")
+		require.NoError(t, err)
+		require.NoError(t, ecs.CancelEmailChange(context.Background(), "123"))
+
+		change, err := ecs.Confirm(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, change)
+	})
+
+	t.Run("Should fail with invalid email", func(t *testing.T) {
+		_, err := ecs.RequestEmailChange(context.Background(), "123", "not-an-email")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid email")
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		_, err := ecs.RequestEmailChange(context.Background(), "", "new@example.com")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGeoPolicy(t *testing.T) {
+	t.Run("Should fail with invalid CIDR", func(t *testing.T) {
+		_, err := service.NewGeoPolicy(nil, []string{"not-a-cidr"}, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid cidr")
+	})
+
+	t.Run("Should deny countries and networks", func(t *testing.T) {
+		policy, err := service.NewGeoPolicy([]string{"kp"}, []string{"198.51.100.0/24", "2001:db8::/32"}, false)
+		require.NoError(t, err)
+
+		denied, reason := policy.Denies(lib.RequestMeta{Country: "KP"})
+		assert.True(t, denied)
+		assert.Equal(t, "country", reason)
+
+		denied, reason = policy.Denies(lib.RequestMeta{IP: "198.51.100.42"})
+		assert.True(t, denied)
+		assert.Equal(t, "network", reason)
+
+		denied, _ = policy.Denies(lib.RequestMeta{IP: "2001:db8::1"})
+		assert.True(t, denied)
+
+		denied, _ = policy.Denies(lib.RequestMeta{IP: "203.0.113.7", Country: "FR"})
+		assert.False(t, denied)
+
+		denied, _ = policy.Denies(lib.RequestMeta{})
+		assert.False(t, denied)
+	})
+}
+
+func TestRefreshTokenGeoPolicy(t *testing.T) {
+	rts := setupService(t)
+	t.Cleanup(func() {
+		rts.SetGeoPolicy(nil)
+		rts.SetAuditLogger(nil)
+	})
+
+	var events []lib.AuditEvent
+	rts.SetAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	policy, err := service.NewGeoPolicy([]string{"KP"}, []string{"198.51.100.0/24"}, true)
+	require.NoError(t, err)
+	rts.SetGeoPolicy(policy)
+
+	t.Run("Should reject denied country and emit an audit event", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		events = nil
+
+		ctx := lib.WithRequestMeta(context.Background(), lib.RequestMeta{Country: "KP", IP: "203.0.113.7"})
+		valid, err := rts.VerifyRefreshToken(ctx, "123", *token)
+		require.ErrorIs(t, err, service.ErrGeoDenied)
+		assert.False(t, valid)
+
+		require.Len(t, events, 1)
+		assert.Equal(t, service.AuditEventRefreshTokenGeoDenied, events[0].Type)
+		assert.Equal(t, "country", events[0].Details["reason"])
+		assert.Equal(t, "203.0.113.7", events[0].Meta.IP)
+	})
+
+	t.Run("Should reject denied network", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		ctx := lib.WithRequestMeta(context.Background(), lib.RequestMeta{IP: "198.51.100.9"})
+		_, err = rts.VerifyRefreshToken(ctx, "123", *token)
+		require.ErrorIs(t, err, service.ErrGeoDenied)
+	})
+
+	t.Run("Should flag country change between uses", func(t *testing.T) {
+		userID := "456"
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		events = nil
+
+		frCtx := lib.WithRequestMeta(context.Background(), lib.RequestMeta{Country: "FR"})
+		valid, err := rts.VerifyRefreshToken(frCtx, userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Empty(t, events)
+
+		// Same country again: nothing to flag
+		_, err = rts.VerifyRefreshToken(frCtx, userID, *token)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		usCtx := lib.WithRequestMeta(context.Background(), lib.RequestMeta{Country: "US"})
+		valid, err = rts.VerifyRefreshToken(usCtx, userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		require.Len(t, events, 1)
+		assert.Equal(t, service.AuditEventRefreshTokenCountryChanged, events[0].Type)
+		assert.Equal(t, "FR", events[0].Details["previous_country"])
+		assert.Equal(t, "US", events[0].Details["country"])
+	})
+}
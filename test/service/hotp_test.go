@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHOTPService(t *testing.T) {
+	t.Run("Should fail with nil db", func(t *testing.T) {
+		_, err := service.NewHOTPService(nil, service.HOTPOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should fail with invalid digits", func(t *testing.T) {
+		_, err := service.NewHOTPService(redisDB, service.HOTPOptions{Digits: 9})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should fail with a resync window smaller than the look-ahead", func(t *testing.T) {
+		_, err := service.NewHOTPService(redisDB, service.HOTPOptions{LookAhead: 20, ResyncWindow: 10})
+		assert.Error(t, err)
+	})
+}
+
+func TestHOTPService(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("12345678901234567890")
+	hotpService, err := service.NewHOTPService(redisDB, service.HOTPOptions{LookAhead: 3, MaxAttempts: 3})
+	require.NoError(t, err)
+
+	code := func(counter uint64) string {
+		c, err := lib.GenerateHOTP(secret, counter, 6)
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("Should accept the code of the counter once", func(t *testing.T) {
+		userID := "hotp-user-1"
+		t.Cleanup(func() { _ = hotpService.RevokeHOTP(ctx, userID) })
+
+		valid, err := hotpService.VerifyHOTP(ctx, userID, secret, code(0))
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = hotpService.VerifyHOTP(ctx, userID, secret, code(0))
+		require.NoError(t, err)
+		assert.False(t, valid, "A code must not be replayed")
+
+		counter, err := hotpService.Counter(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), counter)
+	})
+
+	t.Run("Should accept codes within the look-ahead window", func(t *testing.T) {
+		userID := "hotp-user-2"
+		t.Cleanup(func() { _ = hotpService.RevokeHOTP(ctx, userID) })
+
+		valid, err := hotpService.VerifyHOTP(ctx, userID, secret, code(2))
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = hotpService.VerifyHOTP(ctx, userID, secret, code(1))
+		require.NoError(t, err)
+		assert.False(t, valid, "Codes before an accepted code must be rejected")
+
+		valid, err = hotpService.VerifyHOTP(ctx, userID, secret, code(6))
+		require.NoError(t, err)
+		assert.False(t, valid, "Codes beyond the look-ahead window must be rejected")
+	})
+
+	t.Run("Should resynchronize with two consecutive codes", func(t *testing.T) {
+		userID := "hotp-user-3"
+		t.Cleanup(func() { _ = hotpService.RevokeHOTP(ctx, userID) })
+
+		ok, err := hotpService.ResynchronizeHOTP(ctx, userID, secret, code(7), code(9))
+		require.NoError(t, err)
+		assert.False(t, ok, "Codes must be consecutive")
+
+		ok, err = hotpService.ResynchronizeHOTP(ctx, userID, secret, code(7), code(8))
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		counter, err := hotpService.Counter(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(9), counter)
+
+		valid, err := hotpService.VerifyHOTP(ctx, userID, secret, code(9))
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should lock out after max attempts", func(t *testing.T) {
+		userID := "hotp-user-4"
+		t.Cleanup(func() { _ = hotpService.RevokeHOTP(ctx, userID) })
+
+		for range 3 {
+			valid, err := hotpService.VerifyHOTP(ctx, userID, secret, "000000")
+			require.NoError(t, err)
+			assert.False(t, valid)
+		}
+
+		_, err := hotpService.VerifyHOTP(ctx, userID, secret, code(0))
+		assert.ErrorIs(t, err, service.ErrMaxAttemptsExceeded)
+
+		require.NoError(t, hotpService.SetCounter(ctx, userID, 5))
+		valid, err := hotpService.VerifyHOTP(ctx, userID, secret, code(5))
+		require.NoError(t, err)
+		assert.True(t, valid, "SetCounter must reset the lockout")
+	})
+
+	t.Run("Should reject malformed codes", func(t *testing.T) {
+		_, err := hotpService.VerifyHOTP(ctx, "hotp-user-5", secret, "12345")
+		assert.ErrorIs(t, err, service.ErrInvalidOTP)
+
+		_, err = hotpService.VerifyHOTP(ctx, "hotp-user-5", secret, "12345a")
+		assert.ErrorIs(t, err, service.ErrInvalidOTP)
+
+		_, err = hotpService.VerifyHOTP(ctx, "", secret, code(0))
+		assert.ErrorIs(t, err, service.ErrInvalidUserID)
+	})
+}
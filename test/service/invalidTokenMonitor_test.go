@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInvalidTokenMonitor(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewInvalidTokenMonitor(nil, service.InvalidTokenMonitorOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with non-positive threshold", func(t *testing.T) {
+		_, err := service.NewInvalidTokenMonitor(redisDB, service.InvalidTokenMonitorOptions{Thresholds: []int{5, 0}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "thresholds must be positive")
+	})
+}
+
+func TestInvalidTokenMonitor(t *testing.T) {
+	source := "203.0.113.7"
+	ctx := lib.WithRequestMeta(t.Context(), lib.RequestMeta{IP: source})
+
+	var mu sync.Mutex
+	var reports []service.InvalidTokenReport
+	monitor, err := service.NewInvalidTokenMonitor(redisDB, service.InvalidTokenMonitorOptions{
+		Thresholds: []int{2, 4},
+		Window:     time.Minute,
+		OnThreshold: func(_ context.Context, report service.InvalidTokenReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, report)
+		},
+	})
+	require.NoError(t, err)
+	reset := func(t *testing.T) {
+		require.NoError(t, monitor.Reset(t.Context(), source))
+		mu.Lock()
+		defer mu.Unlock()
+		reports = nil
+	}
+
+	t.Run("Should call the callback once per threshold", func(t *testing.T) {
+		reset(t)
+		for i := 1; i <= 5; i++ {
+			count, err := monitor.Record(ctx, lib.TokenTypeRefresh)
+			require.NoError(t, err)
+			assert.Equal(t, i, count)
+		}
+
+		require.Len(t, reports, 2)
+		assert.Equal(t, service.InvalidTokenReport{
+			Source: source, TokenType: lib.TokenTypeRefresh, Count: 2, Threshold: 2, Window: time.Minute,
+		}, reports[0])
+		assert.Equal(t, 4, reports[1].Threshold)
+
+		count, err := monitor.Count(t.Context(), source)
+		require.NoError(t, err)
+		assert.Equal(t, 5, count)
+	})
+
+	t.Run("Should ignore requests without source", func(t *testing.T) {
+		reset(t)
+		count, err := monitor.Record(t.Context(), lib.TokenTypeRefresh)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Should count unknown refresh tokens", func(t *testing.T) {
+		reset(t)
+		rts := setupService(t)
+		rts.SetInvalidTokenMonitor(monitor)
+
+		token, err := rts.CreateRefreshToken(ctx, "1")
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(ctx, "1", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = rts.VerifyRefreshToken(ctx, "1", strings.Repeat("a", len(*token)))
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		count, err := monitor.Count(t.Context(), source)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Should count unknown password reset tokens", func(t *testing.T) {
+		reset(t)
+		prs := setupPasswordResetService(t)
+		prs.SetInvalidTokenMonitor(monitor)
+
+		_, err := prs.IdentifyPasswordResetToken(ctx, strings.Repeat("a", 32))
+		require.NoError(t, err)
+		_, err = prs.VerifyPasswordResetToken(ctx, "1", strings.Repeat("a", 32))
+		require.NoError(t, err)
+
+		count, err := monitor.Count(t.Context(), source)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		require.Len(t, reports, 1)
+		assert.Equal(t, lib.TokenTypePasswordReset, reports[0].TokenType)
+	})
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuedTokenFilter(t *testing.T) {
+	existing := setupService(t)
+	userID := "filter-" + time.Now().Format("150405.000000")
+	before, err := existing.CreateRefreshToken(context.Background(), userID)
+	require.NoError(t, err)
+
+	rts, err := service.NewRefreshTokenService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+	rebuilds := make(chan int64, 10)
+	filter, err := rts.EnableIssuedTokenFilter(service.IssuedTokenFilterOptions{
+		Capacity: 1000,
+		OnRebuild: func(tokens int64, err error) {
+			assert.NoError(t, err)
+			rebuilds <- tokens
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Should be bypassed before the first rebuild", func(t *testing.T) {
+		assert.False(t, filter.Ready())
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *before)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go filter.Run(ctx)
+	select {
+	case tokens := <-rebuilds:
+		assert.GreaterOrEqual(t, tokens, int64(1))
+	case <-time.After(5 * time.Second):
+		t.Fatal("filter not rebuilt")
+	}
+	require.True(t, filter.Ready())
+
+	t.Run("Should accept tokens issued before and after the rebuild", func(t *testing.T) {
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *before)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		after, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		valid, err = rts.VerifyRefreshToken(context.Background(), userID, *after)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should learn the tokens issued by other replicas", func(t *testing.T) {
+		other, err := service.NewRefreshTokenService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+		_, err = other.EnableIssuedTokenFilter(service.IssuedTokenFilterOptions{})
+		require.NoError(t, err)
+
+		token, err := other.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			valid, err := rts.VerifyRefreshToken(context.Background(), userID, *token)
+			return err == nil && valid
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Should reject never-issued tokens without querying Redis", func(t *testing.T) {
+		forged, err := lib.GenerateRandomString(255)
+		require.NoError(t, err)
+
+		rejected := filter.Rejected()
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, forged)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Equal(t, rejected+1, filter.Rejected())
+		assert.Less(t, filter.FalsePositiveRate(), 0.001)
+	})
+
+	t.Run("Should be bypassed once stopped", func(t *testing.T) {
+		cancel()
+		assert.Eventually(t, func() bool { return !filter.Ready() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Should fail with an invalid false positive rate", func(t *testing.T) {
+		_, err := rts.EnableIssuedTokenFilter(service.IssuedTokenFilterOptions{FalsePositiveRate: 1})
+		require.Error(t, err)
+	})
+}
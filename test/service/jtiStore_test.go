@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneTimeAccessToken(t *testing.T) {
+	jtiStore, err := service.NewJTIStore(t.Context(), redisDB)
+	require.NoError(t, err)
+
+	config := &lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"}
+	accessService := service.NewAccessTokenService(config)
+	accessService.SetJTIStore(jtiStore)
+	stats := &lib.ReplayStats{}
+	accessService.SetReplayMetricsHook(stats)
+
+	user := modelAuth.NewUser("one-time-user", "user@example.com")
+
+	t.Run("Should accept a one-time-use token once", func(t *testing.T) {
+		token, err := accessService.CreateOneTimeAccessToken(user, 5*time.Minute)
+		require.NoError(t, err)
+
+		claim, err := accessService.VerifyAccessToken(token)
+		require.NoError(t, err)
+		assert.True(t, claim.OneTimeUse)
+		assert.WithinDuration(t, claim.IssuedAt.Add(5*time.Minute), claim.ExpiresAt.Time, time.Second)
+
+		_, err = accessService.VerifyAccessToken(token)
+		assert.ErrorIs(t, err, service.ErrTokenReplayed)
+		assert.Equal(t, int64(1), stats.Count(lib.TokenTypeAccess, lib.ReplayReasonConsumed))
+	})
+
+	t.Run("Should accept only one of concurrent verifications", func(t *testing.T) {
+		token, err := accessService.CreateOneTimeAccessToken(user, 0)
+		require.NoError(t, err)
+
+		var accepted atomic.Int32
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				if _, err := accessService.VerifyAccessToken(token); err == nil {
+					accepted.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), accepted.Load())
+	})
+
+	t.Run("Should cap the lifetime to JWTExpiry", func(t *testing.T) {
+		token, err := accessService.CreateOneTimeAccessToken(user, 24*time.Hour)
+		require.NoError(t, err)
+
+		claim, err := accessService.VerifyAccessToken(token)
+		require.NoError(t, err)
+		assert.WithinDuration(t, claim.IssuedAt.Add(15*time.Minute), claim.ExpiresAt.Time, time.Second)
+	})
+
+	t.Run("Should not consume regular tokens", func(t *testing.T) {
+		token, err := accessService.CreateAccessToken(user)
+		require.NoError(t, err)
+
+		for range 2 {
+			claim, err := accessService.VerifyAccessToken(token)
+			require.NoError(t, err)
+			assert.False(t, claim.OneTimeUse)
+		}
+	})
+
+	t.Run("Should not consume a token verified with a cancelled context", func(t *testing.T) {
+		token, err := accessService.CreateOneTimeAccessToken(user, 0)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err = accessService.VerifyAccessTokenContext(ctx, token)
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = accessService.VerifyAccessTokenContext(t.Context(), token)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should reject one-time-use tokens without a jti store", func(t *testing.T) {
+		token, err := accessService.CreateOneTimeAccessToken(user, 0)
+		require.NoError(t, err)
+
+		withoutStore := service.NewAccessTokenService(config)
+		_, err = withoutStore.VerifyAccessToken(token)
+		require.Error(t, err)
+
+		_, err = withoutStore.CreateOneTimeAccessToken(user, 0)
+		require.Error(t, err)
+		assert.Equal(t, "jti store is not set", err.Error())
+	})
+
+	t.Run("Should fail with a negative ttl", func(t *testing.T) {
+		_, err := accessService.CreateOneTimeAccessToken(user, -time.Minute)
+		require.Error(t, err)
+		assert.Equal(t, "invalid ttl", err.Error())
+	})
+
+	t.Run("Should fail with a nil db", func(t *testing.T) {
+		_, err := service.NewJTIStore(t.Context(), nil)
+		require.Error(t, err)
+		assert.Equal(t, "db is nil", err.Error())
+	})
+}
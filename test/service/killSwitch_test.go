@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKillSwitch(t *testing.T) {
+	rts := setupService(t)
+	prs := setupPasswordResetService(t)
+	otps := setupOTPService(t)
+	epochs, err := service.NewTokenEpochService(t.Context(), redisDB)
+	require.NoError(t, err)
+
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	accessService.SetEpochService(epochs)
+
+	var events []lib.AuditEvent
+	killSwitch := service.NewKillSwitch(rts, prs, otps, epochs)
+	killSwitch.SetAuditLogger(lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+	userID := "123"
+
+	t.Run("Should revoke every outstanding token", func(t *testing.T) {
+		accessToken, err := accessService.CreateAccessToken(modelAuth.NewUser(userID, "user@example.com"))
+		require.NoError(t, err)
+		_, err = accessService.VerifyAccessToken(accessToken)
+		require.NoError(t, err)
+
+		refreshToken, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		resetToken, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		otp, err := otps.CreateOTP(context.Background(), userID)
+		require.NoError(t, err)
+
+		require.NoError(t, killSwitch.EmergencyRevokeAll(context.Background()))
+
+		_, err = accessService.VerifyAccessToken(accessToken)
+		assert.ErrorIs(t, err, service.ErrTokenEpochRevoked)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *refreshToken)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		valid, err = prs.VerifyPasswordResetToken(context.Background(), userID, *resetToken)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		valid, err = otps.VerifyOTP(context.Background(), userID, *otp)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		require.Len(t, events, 1)
+		assert.Equal(t, service.AuditEventEmergencyRevokeAll, events[0].Type)
+		assert.Equal(t, "true", events[0].Details["complete"])
+	})
+
+	t.Run("Should accept tokens issued after the revocation", func(t *testing.T) {
+		accessToken, err := accessService.CreateAccessToken(modelAuth.NewUser(userID, "user@example.com"))
+		require.NoError(t, err)
+
+		claim, err := accessService.VerifyAccessToken(accessToken)
+		require.NoError(t, err)
+		assert.Positive(t, claim.Epoch)
+	})
+}
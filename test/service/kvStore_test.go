@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVStore(t *testing.T) {
+	stores := map[string]service.KVStore{
+		"memory": service.NewMemoryKVStore(),
+		"redis":  service.NewRedisKVStore(redisDB),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			prefix := "kv-test-" + name + ":"
+			t.Cleanup(func() {
+				_ = store.Scan(ctx, prefix, 100, func(keys []string) error {
+					_, err := store.Del(ctx, keys...)
+					return err
+				})
+			})
+
+			t.Run("Should set, get and delete values", func(t *testing.T) {
+				require.NoError(t, store.SetEX(ctx, prefix+"a", "1", time.Minute))
+
+				value, found, err := store.Get(ctx, prefix+"a")
+				require.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, "1", value)
+
+				ttl, err := store.TTL(ctx, prefix+"a")
+				require.NoError(t, err)
+				assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+				deleted, err := store.Del(ctx, prefix+"a", prefix+"missing")
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), deleted)
+
+				_, found, err = store.Get(ctx, prefix+"a")
+				require.NoError(t, err)
+				assert.False(t, found)
+
+				ttl, err = store.TTL(ctx, prefix+"a")
+				require.NoError(t, err)
+				assert.Zero(t, ttl)
+			})
+
+			t.Run("Should increment counters", func(t *testing.T) {
+				n, err := store.Incr(ctx, prefix+"counter", time.Minute)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), n)
+				n, err = store.Incr(ctx, prefix+"counter", time.Hour)
+				require.NoError(t, err)
+				assert.Equal(t, int64(2), n)
+
+				ttl, err := store.TTL(ctx, prefix+"counter")
+				require.NoError(t, err)
+				assert.LessOrEqual(t, ttl, time.Minute, "an existing counter keeps its expiry")
+			})
+
+			t.Run("Should increment counters concurrently", func(t *testing.T) {
+				var wg sync.WaitGroup
+				for range 10 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, err := store.Incr(ctx, prefix+"concurrent", time.Minute)
+						assert.NoError(t, err)
+					}()
+				}
+				wg.Wait()
+
+				value, _, err := store.Get(ctx, prefix+"concurrent")
+				require.NoError(t, err)
+				assert.Equal(t, "10", value)
+
+				ttl, err := store.TTL(ctx, prefix+"concurrent")
+				require.NoError(t, err)
+				assert.Positive(t, ttl)
+			})
+
+			t.Run("Should expire values", func(t *testing.T) {
+				require.NoError(t, store.SetEX(ctx, prefix+"short", "1", 50*time.Millisecond))
+				time.Sleep(100 * time.Millisecond)
+
+				_, found, err := store.Get(ctx, prefix+"short")
+				require.NoError(t, err)
+				assert.False(t, found)
+			})
+
+			t.Run("Should scan keys by prefix", func(t *testing.T) {
+				for _, key := range []string{"s:1", "s:2", "s:3", "other"} {
+					require.NoError(t, store.SetEX(ctx, prefix+key, "1", time.Minute))
+				}
+
+				var keys []string
+				require.NoError(t, store.Scan(ctx, prefix+"s:", 2, func(batch []string) error {
+					keys = append(keys, batch...)
+					return nil
+				}))
+				assert.ElementsMatch(t, []string{prefix + "s:1", prefix + "s:2", prefix + "s:3"}, keys)
+			})
+		})
+	}
+}
+
+func TestOTPServiceWithStore(t *testing.T) {
+	t.Run("Should fail with nil store", func(t *testing.T) {
+		_, err := service.NewOTPServiceWithStore(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "store is nil")
+	})
+
+	store := service.NewMemoryKVStore()
+	otps, err := service.NewOTPServiceWithStore(context.Background(), store, config, service.WithHasher(&plainHasher{}), service.WithOTPGenerator(testkit.OTPSequence("000123", "000456")))
+	require.NoError(t, err)
+
+	t.Run("Should create and verify a single-use code", func(t *testing.T) {
+		otp, err := otps.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, "000123", *otp)
+
+		value, found, err := store.Get(context.Background(), "otp:123")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "plain:000123", value)
+
+		valid, err := otps.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = otps.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should limit the attempts", func(t *testing.T) {
+		_, err := otps.CreateOTP(context.Background(), "456")
+		require.NoError(t, err)
+
+		for range 5 {
+			valid, err := otps.VerifyOTP(context.Background(), "456", "999999")
+			require.NoError(t, err)
+			assert.False(t, valid)
+		}
+		_, err = otps.VerifyOTP(context.Background(), "456", "000456")
+		require.ErrorIs(t, err, service.ErrMaxAttemptsExceeded)
+
+		state, err := otps.RateLimit(context.Background(), "456")
+		require.NoError(t, err)
+		assert.Zero(t, state.Remaining)
+		assert.Positive(t, state.Reset)
+	})
+
+	t.Run("Should revoke all codes", func(t *testing.T) {
+		revoked, err := otps.RevokeAllOTPsInBatches(context.Background(), service.CleanupOptions{BatchSize: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked, "attempts counters are not counted as codes")
+
+		_, found, err := store.Get(context.Background(), "otp:456")
+		require.NoError(t, err)
+		assert.False(t, found)
+		_, found, err = store.Get(context.Background(), "otp:attempts:456")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Should refuse the failover", func(t *testing.T) {
+		_, err := service.NewOTPFailover(otps, redisDB, false)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeakedTokenResponder(t *testing.T) {
+	rts := setupService(t)
+	prs := setupPasswordResetService(t)
+	responder, err := service.NewLeakedTokenResponder(rts, prs, "leak-secret")
+	require.NoError(t, err)
+
+	var events []lib.AuditEvent
+	responder.SetAuditLogger(lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+	userID := "123"
+
+	t.Run("Should revoke a leaked refresh token", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		result, err := responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: *token, Source: "github"})
+		require.NoError(t, err)
+		assert.True(t, result.Revoked)
+		assert.Equal(t, lib.TokenTypeRefresh, result.Type)
+		assert.Equal(t, userID, result.UserID)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		event := events[len(events)-1]
+		assert.Equal(t, service.AuditEventTokenLeakReported, event.Type)
+		assert.Equal(t, "github", event.Details["source"])
+		assert.Equal(t, "true", event.Details["revoked"])
+
+		records, err := rts.ListRevokedRefreshTokens(context.Background(), userID)
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, service.RevocationReasonLeaked, records[0].Reason)
+
+		result, err = responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: *token, Source: "github"})
+		require.NoError(t, err)
+		assert.False(t, result.Revoked, "a token is revoked once")
+	})
+
+	t.Run("Should delete the lookup entry with the token", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		keys, err := redisDB.Keys(context.Background(), "refresh_lookup:*").Result()
+		require.NoError(t, err)
+		assert.NotEmpty(t, keys)
+
+		_, err = responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: *token, Source: "github"})
+		require.NoError(t, err)
+
+		keys, err = redisDB.Keys(context.Background(), "refresh_lookup:*").Result()
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("Should revoke a leaked password reset token", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		result, err := responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: *token, Source: "github"})
+		require.NoError(t, err)
+		assert.True(t, result.Revoked)
+		assert.Equal(t, lib.TokenTypePasswordReset, result.Type)
+
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		records, err := prs.ListRevokedPasswordResetTokens(context.Background(), userID)
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, service.RevocationReasonLeaked, records[0].Reason)
+	})
+
+	t.Run("Should normalize the reported token", func(t *testing.T) {
+		ttl := "1h"
+		folding, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{
+			PasswordResetTTL:   &ttl,
+			TokenNormalization: lib.TokenNormalization{TrimSpace: true, FoldCase: true},
+		})
+		require.NoError(t, err)
+		responder, err := service.NewLeakedTokenResponder(nil, folding, "leak-secret")
+		require.NoError(t, err)
+
+		token, err := folding.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		result, err := responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: strings.ToLower(*token), Source: "github"})
+		require.NoError(t, err)
+		assert.True(t, result.Revoked)
+		assert.Equal(t, lib.TokenTypePasswordReset, result.Type)
+	})
+
+	t.Run("Should report unknown tokens as not revoked", func(t *testing.T) {
+		result, err := responder.HandleLeak(context.Background(), service.LeakedTokenReport{Token: "unknown*token", Source: "github"})
+		require.NoError(t, err)
+		assert.False(t, result.Revoked)
+		assert.Equal(t, "false", events[len(events)-1].Details["revoked"])
+	})
+
+	t.Run("Should verify report signatures", func(t *testing.T) {
+		payload := []byte(`[{"token":"abc","source":"github"}]`)
+		mac := hmac.New(sha256.New, []byte("leak-secret"))
+		mac.Write(payload)
+		signature := hex.EncodeToString(mac.Sum(nil))
+
+		assert.True(t, responder.VerifySignature(payload, signature))
+		assert.True(t, responder.VerifySignature(payload, "sha256="+signature))
+		assert.False(t, responder.VerifySignature([]byte("tampered"), signature))
+		assert.False(t, responder.VerifySignature(payload, "not-hex"))
+	})
+
+	t.Run("Should fail with empty secret", func(t *testing.T) {
+		_, err := service.NewLeakedTokenResponder(rts, prs, "")
+		require.Error(t, err)
+		assert.Equal(t, "leak report secret is empty", err.Error())
+	})
+}
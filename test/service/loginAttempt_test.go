@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLoginAttemptService(t *testing.T) *service.LoginAttemptService {
+	las, err := service.NewLoginAttemptService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+
+	// Clear all counters and locks to ensure clean state
+	err = las.RevokeAllLoginAttempts(t.Context())
+	require.NoError(t, err)
+
+	return las
+}
+
+func TestNewLoginAttemptService(t *testing.T) {
+	t.Run("Should create service successfully", func(t *testing.T) {
+		_, err := service.NewLoginAttemptService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should handle nil context", func(t *testing.T) {
+		_, err := service.NewLoginAttemptService(nil, redisDB, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewLoginAttemptService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with invalid lockout duration", func(t *testing.T) {
+		lockout := "invalid-duration"
+		invalidConfig := &lib.Config{LoginLockoutDuration: &lockout}
+		_, err := service.NewLoginAttemptService(context.Background(), redisDB, invalidConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid login lockout duration format")
+	})
+}
+
+func TestLoginAttemptLockout(t *testing.T) {
+	las := setupLoginAttemptService(t)
+
+	t.Run("Should lock after max attempts", func(t *testing.T) {
+		userID := "123"
+		for i := 0; i < 4; i++ {
+			locked, err := las.RecordFailure(context.Background(), userID)
+			require.NoError(t, err)
+			assert.False(t, locked)
+		}
+
+		locked, err := las.IsLocked(context.Background(), userID)
+		require.NoError(t, err)
+		assert.False(t, locked)
+
+		locked, err = las.RecordFailure(context.Background(), userID)
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		locked, err = las.IsLocked(context.Background(), userID)
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		remaining, err := las.RemainingLockout(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Greater(t, remaining, 14*time.Minute)
+	})
+
+	t.Run("Should reset failures on success", func(t *testing.T) {
+		userID := "456"
+		for i := 0; i < 4; i++ {
+			_, err := las.RecordFailure(context.Background(), userID)
+			require.NoError(t, err)
+		}
+		require.NoError(t, las.RecordSuccess(context.Background(), userID))
+
+		locked, err := las.RecordFailure(context.Background(), userID)
+		require.NoError(t, err)
+		assert.False(t, locked)
+	})
+
+	t.Run("Should unlock account", func(t *testing.T) {
+		userID := "789"
+		for i := 0; i < 5; i++ {
+			_, err := las.RecordFailure(context.Background(), userID)
+			require.NoError(t, err)
+		}
+		require.NoError(t, las.Unlock(context.Background(), userID))
+
+		locked, err := las.IsLocked(context.Background(), userID)
+		require.NoError(t, err)
+		assert.False(t, locked)
+
+		remaining, err := las.RemainingLockout(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), remaining)
+	})
+
+	t.Run("Should use configured threshold", func(t *testing.T) {
+		lockout := "1m"
+		customConfig := &lib.Config{LoginMaxAttempts: 2, LoginLockoutDuration: &lockout}
+		customService, err := service.NewLoginAttemptService(context.Background(), redisDB, customConfig)
+		require.NoError(t, err)
+
+		locked, err := customService.RecordFailure(context.Background(), "321")
+		require.NoError(t, err)
+		assert.False(t, locked)
+
+		locked, err = customService.RecordFailure(context.Background(), "321")
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		remaining, err := customService.RemainingLockout(context.Background(), "321")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, remaining, time.Minute)
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		_, err := las.RecordFailure(context.Background(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+
+		_, err = las.IsLocked(context.Background(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+
+	t.Run("Should handle nil context", func(t *testing.T) {
+		_, err := las.RecordFailure(nil, "999")
+		require.NoError(t, err)
+
+		_, err = las.IsLocked(nil, "999")
+		require.NoError(t, err)
+	})
+}
+
+func TestLoginAttemptStatus(t *testing.T) {
+	setupLoginAttemptService(t)
+
+	lockout := "1m"
+	challengeConfig := &lib.Config{LoginMaxAttempts: 4, LoginChallengeThreshold: 2, LoginLockoutDuration: &lockout}
+	las, err := service.NewLoginAttemptService(context.Background(), redisDB, challengeConfig)
+	require.NoError(t, err)
+
+	t.Run("Should move from allowed to challenge to locked", func(t *testing.T) {
+		userID := "123"
+		expected := []service.LoginStatus{
+			service.LoginStatusAllowed,
+			service.LoginStatusChallengeRequired,
+			service.LoginStatusChallengeRequired,
+			service.LoginStatusLocked,
+		}
+
+		status, err := las.Status(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, service.LoginStatusAllowed, status)
+
+		for _, expectedStatus := range expected {
+			_, err := las.RecordFailure(context.Background(), userID)
+			require.NoError(t, err)
+
+			status, err := las.Status(context.Background(), userID)
+			require.NoError(t, err)
+			assert.Equal(t, expectedStatus, status, "got %s", status)
+		}
+	})
+
+	t.Run("Should never require challenge when disabled", func(t *testing.T) {
+		defaultService := setupLoginAttemptService(t)
+		for i := 0; i < 4; i++ {
+			_, err := defaultService.RecordFailure(context.Background(), "456")
+			require.NoError(t, err)
+		}
+
+		status, err := defaultService.Status(context.Background(), "456")
+		require.NoError(t, err)
+		assert.Equal(t, service.LoginStatusAllowed, status)
+	})
+
+	t.Run("Should expose stable names", func(t *testing.T) {
+		assert.Equal(t, "allowed", service.LoginStatusAllowed.String())
+		assert.Equal(t, "challenge_required", service.LoginStatusChallengeRequired.String())
+		assert.Equal(t, "locked", service.LoginStatusLocked.String())
+	})
+}
+
+func TestLoginAttemptRateLimit(t *testing.T) {
+	setupLoginAttemptService(t)
+
+	lockout := "1m"
+	las, err := service.NewLoginAttemptService(context.Background(), redisDB, &lib.Config{LoginMaxAttempts: 3, LoginLockoutDuration: &lockout})
+	require.NoError(t, err)
+
+	t.Run("Should count down the failures then report the lock", func(t *testing.T) {
+		state, err := las.RateLimit(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, service.RateLimitState{Limit: 3, Remaining: 3}, state)
+
+		_, err = las.RecordFailure(context.Background(), "123")
+		require.NoError(t, err)
+		state, err = las.RateLimit(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, 2, state.Remaining)
+		assert.Positive(t, state.Reset)
+
+		for i := 0; i < 2; i++ {
+			_, err = las.RecordFailure(context.Background(), "123")
+			require.NoError(t, err)
+		}
+		state, err = las.RateLimit(context.Background(), "123")
+		require.NoError(t, err)
+		assert.True(t, state.Exhausted())
+		assert.InDelta(t, float64(time.Minute), float64(state.Reset), float64(time.Second))
+	})
+
+	t.Run("Should reject empty user ID", func(t *testing.T) {
+		_, err := las.RateLimit(context.Background(), "")
+		require.Error(t, err)
+	})
+}
+
+func TestLoginAttemptAudit(t *testing.T) {
+	las := setupLoginAttemptService(t)
+
+	var events []lib.AuditEvent
+	las.SetAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	t.Run("Should emit a login.locked event when locking", func(t *testing.T) {
+		userID := "audited"
+		for i := 0; i < 5; i++ {
+			_, err := las.RecordFailure(context.Background(), userID)
+			require.NoError(t, err)
+		}
+
+		require.Len(t, events, 1)
+		assert.Equal(t, service.AuditEventLoginLocked, events[0].Type)
+		assert.Equal(t, userID, events[0].UserID)
+		assert.Equal(t, "5", events[0].Details["attempts"])
+	})
+}
+
+func TestLoginAttemptPerIP(t *testing.T) {
+	las, err := service.NewLoginAttemptService(t.Context(), redisDB, &lib.Config{LoginMaxAttemptsPerIP: 3})
+	require.NoError(t, err)
+	require.NoError(t, las.RevokeAllLoginAttempts(t.Context()))
+
+	attacker := lib.WithRequestMeta(context.Background(), lib.RequestMeta{IP: "203.0.113.7"})
+	other := lib.WithRequestMeta(context.Background(), lib.RequestMeta{IP: "198.51.100.1"})
+
+	t.Run("Should lock the client IP across users", func(t *testing.T) {
+		for _, userID := range []string{"alice", "bob", "carol"} {
+			locked, err := las.RecordFailure(attacker, userID)
+			require.NoError(t, err)
+			assert.False(t, locked)
+		}
+
+		locked, err := las.IsLocked(attacker, "dave")
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		status, err := las.Status(attacker, "dave")
+		require.NoError(t, err)
+		assert.Equal(t, service.LoginStatusLocked, status)
+
+		remaining, err := las.RemainingLockout(attacker, "dave")
+		require.NoError(t, err)
+		assert.Greater(t, remaining, time.Duration(0))
+	})
+
+	t.Run("Should not lock other clients or requests without metadata", func(t *testing.T) {
+		locked, err := las.IsLocked(other, "dave")
+		require.NoError(t, err)
+		assert.False(t, locked)
+
+		locked, err = las.IsLocked(context.Background(), "dave")
+		require.NoError(t, err)
+		assert.False(t, locked)
+	})
+
+	t.Run("Should fail with a negative limit", func(t *testing.T) {
+		_, err := service.NewLoginAttemptService(t.Context(), redisDB, &lib.Config{LoginMaxAttemptsPerIP: -1})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "login max attempts per ip is negative")
+	})
+}
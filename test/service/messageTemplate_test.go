@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRenderer(t *testing.T) {
+	messages, err := service.NewMessageRenderer("Acme", map[string]map[service.MessageKind]service.MessageTemplate{
+		"it": {service.MessageKindOTP: {
+			Subject: "Il tuo codice di verifica",
+			Body:    "Il tuo codice di verifica è {{.Code}}. Scade tra {{.Minutes}} minuti.",
+		}},
+	})
+	require.NoError(t, err)
+
+	otp := service.MessageData{Code: "123456", ExpiresIn: 10 * time.Minute}
+
+	t.Run("Should render the built-in templates", func(t *testing.T) {
+		msg, err := messages.Render(service.MessageKindOTP, otp)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme: Your verification code", msg.Subject)
+		assert.Equal(t, "Acme: Your verification code is 123456. It expires in 10 minutes.", msg.Body)
+	})
+
+	t.Run("Should render in the language of the locale", func(t *testing.T) {
+		data := otp
+		data.Locale = "de_DE"
+		msg, err := messages.Render(service.MessageKindOTP, data)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme: Ihr Bestätigungscode lautet 123456. Er läuft in 10 Minuten ab.", msg.Body)
+
+		reset, err := messages.Render(service.MessageKindPasswordReset, service.MessageData{
+			Locale:    "fr-FR",
+			Link:      "https://example.com/reset",
+			ExpiresIn: time.Hour,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, reset.Body, "https://example.com/reset")
+		assert.Contains(t, reset.Body, "Il expire dans 1 heure.")
+	})
+
+	t.Run("Should use custom templates, then English", func(t *testing.T) {
+		data := otp
+		data.Locale = "it-IT"
+		msg, err := messages.Render(service.MessageKindOTP, data)
+		require.NoError(t, err)
+		assert.Equal(t, "Il tuo codice di verifica è 123456. Scade tra 10 minuti.", msg.Body)
+
+		reset, err := messages.Render(service.MessageKindPasswordReset, service.MessageData{Locale: "it", Link: "https://example.com/reset", ExpiresIn: time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, "Acme: Reset your password", reset.Subject)
+	})
+
+	t.Run("Should render the OTP messages of the senders", func(t *testing.T) {
+		msg := service.OTPMessage{Code: "123456", ExpiresIn: time.Minute, Locale: "es"}
+		assert.Equal(t, "Acme: Tu código de verificación", messages.OTPSubject(msg))
+		assert.Equal(t, "Acme: Tu código de verificación es 123456. Caduca en 1 minuto.", messages.OTPText(msg))
+		assert.Equal(t, "Tu código de verificación es 123456. Caduca en 1 minuto.", service.DefaultOTPText(msg))
+	})
+
+	t.Run("Should fail with invalid templates", func(t *testing.T) {
+		_, err := service.NewMessageRenderer("", map[string]map[service.MessageKind]service.MessageTemplate{
+			"en": {service.MessageKindOTP: {Body: "{{.Code"}},
+		})
+		assert.Error(t, err)
+
+		_, err = service.NewMessageRenderer("", map[string]map[service.MessageKind]service.MessageTemplate{
+			"en": {service.MessageKindOTP: {Body: "{{.Unknown}}"}},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should fail for kinds without template", func(t *testing.T) {
+		_, err := messages.Render("welcome", otp)
+		assert.Error(t, err)
+	})
+}
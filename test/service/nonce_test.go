@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNonceService(t *testing.T) *service.NonceService {
+	ns, err := service.NewNonceService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+
+	// Clear all nonces to ensure clean state
+	err = ns.RevokeAllNonces(t.Context())
+	require.NoError(t, err)
+
+	return ns
+}
+
+func TestNewNonceService(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewNonceService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with invalid ttl", func(t *testing.T) {
+		ttl := "invalid-duration"
+		_, err := service.NewNonceService(context.Background(), redisDB, &lib.Config{NonceTTL: &ttl})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid nonce TTL format")
+	})
+}
+
+func TestNonceConsumeOnce(t *testing.T) {
+	ns := setupNonceService(t)
+
+	t.Run("Should consume a nonce only once", func(t *testing.T) {
+		nonce, err := ns.CreateNonce(context.Background(), "")
+		require.NoError(t, err)
+		assert.Len(t, *nonce, 32)
+
+		ttl, err := ns.TTL(context.Background(), *nonce)
+		require.NoError(t, err)
+		assert.Greater(t, ttl, time.Duration(0))
+
+		valid, err := ns.ConsumeOnce(context.Background(), *nonce, "")
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = ns.ConsumeOnce(context.Background(), *nonce, "")
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		ttl, err = ns.TTL(context.Background(), *nonce)
+		require.NoError(t, err)
+		assert.Zero(t, ttl)
+	})
+
+	t.Run("Should require the binding", func(t *testing.T) {
+		nonce, err := ns.CreateNonce(context.Background(), "session-1")
+		require.NoError(t, err)
+
+		valid, err := ns.ConsumeOnce(context.Background(), *nonce, "session-2")
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		// Consumed by the failed attempt
+		valid, err = ns.ConsumeOnce(context.Background(), *nonce, "session-1")
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should reject unknown nonces", func(t *testing.T) {
+		valid, err := ns.ConsumeOnce(context.Background(), "unknown", "")
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainHasher stores codes as is, to check that WithHasher replaces bcrypt.
+type plainHasher struct {
+	lib.PasswordHash
+	hashed int
+}
+
+func (h *plainHasher) Hash(password string) (string, error) {
+	h.hashed++
+	return "plain:" + password, nil
+}
+
+func (h *plainHasher) CheckHash(password, hash string) bool {
+	return hash == "plain:"+password
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	rts := setupService(t)
+	prefixed, err := service.NewRefreshTokenService(context.Background(), redisDB, config, service.WithKeyPrefix("myapp"))
+	require.NoError(t, err)
+	require.NoError(t, prefixed.RevokeAllRefreshTokens(context.Background()))
+
+	t.Run("Should store keys under the prefix", func(t *testing.T) {
+		token, err := prefixed.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		exists, err := redisDB.Exists(context.Background(), "myapp:refresh:123:"+*token).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists)
+
+		valid, err := prefixed.VerifyRefreshToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should isolate prefixed and unprefixed services", func(t *testing.T) {
+		token, err := prefixed.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		require.NoError(t, rts.RevokeAllRefreshTokens(context.Background()))
+		valid, err = prefixed.VerifyRefreshToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should isolate token types sharing a prefix", func(t *testing.T) {
+		reset, err := service.NewPasswordResetService(context.Background(), redisDB, config, service.WithKeyPrefix("myapp"))
+		require.NoError(t, err)
+		resetToken, err := reset.CreatePasswordResetToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		require.NoError(t, prefixed.RevokeAllRefreshTokens(context.Background()))
+		valid, err := reset.VerifyPasswordResetToken(context.Background(), "123", *resetToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		refreshToken, err := prefixed.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		require.NoError(t, reset.RevokeAllPasswordResetTokens(context.Background()))
+		valid, err = prefixed.VerifyRefreshToken(context.Background(), "123", *refreshToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
+
+func TestWithHasher(t *testing.T) {
+	hasher := &plainHasher{}
+	otpService, err := service.NewOTPService(context.Background(), redisDB, config, service.WithHasher(hasher))
+	require.NoError(t, err)
+
+	otp, err := otpService.CreateOTP(context.Background(), "123")
+	require.NoError(t, err)
+	assert.Equal(t, 1, hasher.hashed)
+
+	valid, err := otpService.VerifyOTP(context.Background(), "123", *otp)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestWithUserHashTags(t *testing.T) {
+	otpService, err := service.NewOTPService(context.Background(), redisDB, config, service.WithHasher(&plainHasher{}), service.WithUserHashTags())
+	require.NoError(t, err)
+	require.NoError(t, otpService.RevokeAllOTPs(context.Background()))
+
+	t.Run("Should store the keys of a user in its hash tag", func(t *testing.T) {
+		otp, err := otpService.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+
+		exists, err := redisDB.Exists(context.Background(), "otp:{user:123}", "otp:attempts:{user:123}").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), exists)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "123", "000000")
+		require.NoError(t, err)
+		assert.False(t, valid)
+		state, err := otpService.RateLimit(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, 4, state.Remaining)
+
+		valid, err = otpService.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		exists, err = redisDB.Exists(context.Background(), "otp:{user:123}", "otp:attempts:{user:123}").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), exists)
+	})
+
+	t.Run("Should revoke all hash-tagged keys", func(t *testing.T) {
+		for _, userID := range []string{"1", "2", "3"} {
+			_, err := otpService.CreateOTP(context.Background(), userID)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, otpService.RevokeAllOTPs(context.Background()))
+		keys, err := redisDB.Keys(context.Background(), "otp:*").Result()
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+}
+
+func TestWithLogger(t *testing.T) {
+	var events []lib.AuditEventType
+	rts, err := service.NewRefreshTokenService(context.Background(), redisDB, config, service.WithLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event.Type)
+	})))
+	require.NoError(t, err)
+
+	token, err := rts.CreateRefreshToken(context.Background(), "123")
+	require.NoError(t, err)
+	require.NoError(t, rts.RevokeRefreshToken(context.Background(), *token, "123"))
+	assert.Equal(t, []lib.AuditEventType{service.AuditEventRefreshTokenCreated, service.AuditEventRefreshTokenRevoked}, events)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowFirstHasher delays its first hash, so that the first creation finishes last.
+type slowFirstHasher struct {
+	plainHasher
+	once sync.Once
+}
+
+func (h *slowFirstHasher) Hash(password string) (string, error) {
+	h.once.Do(func() { time.Sleep(100 * time.Millisecond) })
+	return "plain:" + password, nil
+}
+
+func TestCreateOTPAsync(t *testing.T) {
+	t.Run("Should deliver a valid OTP", func(t *testing.T) {
+		otpService, err := service.NewOTPService(t.Context(), redisDB, config, service.WithHasher(&plainHasher{}))
+		require.NoError(t, err)
+
+		result := <-otpService.CreateOTPAsync(context.Background(), "123")
+		require.NoError(t, result.Err)
+		require.NotNil(t, result.OTP)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "123", *result.OTP)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should keep the OTP of the last call for the same user", func(t *testing.T) {
+		otpService, err := service.NewOTPService(t.Context(), redisDB, config,
+			service.WithHasher(&slowFirstHasher{}),
+			service.WithOTPGenerator(testkit.OTPSequence("111111", "222222")))
+		require.NoError(t, err)
+
+		first := otpService.CreateOTPAsync(context.Background(), "456")
+		last := otpService.CreateOTPAsync(context.Background(), "456")
+		firstResult, lastResult := <-first, <-last
+		require.NoError(t, firstResult.Err)
+		require.NoError(t, lastResult.Err)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "456", *firstResult.OTP)
+		require.NoError(t, err)
+		assert.False(t, valid, "OTP of the first call should have been replaced")
+
+		valid, err = otpService.VerifyOTP(context.Background(), "456", *lastResult.OTP)
+		require.NoError(t, err)
+		assert.True(t, valid, "OTP of the last call should be active")
+	})
+
+	t.Run("Should report cancelled context", func(t *testing.T) {
+		otpService, err := service.NewOTPService(t.Context(), redisDB, config, service.WithHasher(&plainHasher{}))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result := <-otpService.CreateOTPAsync(ctx, "789")
+		require.Error(t, result.Err)
+		assert.Nil(t, result.OTP)
+	})
+
+	t.Run("Should reject empty user ID", func(t *testing.T) {
+		otpService, err := service.NewOTPService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+
+		result := <-otpService.CreateOTPAsync(context.Background(), "")
+		require.Error(t, result.Err)
+	})
+}
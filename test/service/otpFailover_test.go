@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupOTPFailover returns a healthy OTP service and a failover whose primary is down,
+// reading from the test Redis as replica.
+func setupOTPFailover(t *testing.T, strict bool) (*service.OTPService, *service.OTPFailover) {
+	healthy, err := service.NewOTPService(t.Context(), redisDB, config, service.WithHasher(&plainHasher{}))
+	require.NoError(t, err)
+	require.NoError(t, healthy.RevokeAllOTPs(t.Context()))
+
+	down := redis.NewClient(&redis.Options{Addr: "localhost:1", MaxRetries: -1})
+	t.Cleanup(func() { _ = down.Close() })
+	primary, err := service.NewOTPService(t.Context(), down, config, service.WithHasher(&plainHasher{}))
+	require.NoError(t, err)
+
+	failover, err := service.NewOTPFailover(primary, redisDB, strict)
+	require.NoError(t, err)
+	return healthy, failover
+}
+
+func TestNewOTPFailover(t *testing.T) {
+	t.Run("Should fail with nil arguments", func(t *testing.T) {
+		_, err := service.NewOTPFailover(nil, redisDB, false)
+		require.Error(t, err)
+
+		primary, err := service.NewOTPService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+		_, err = service.NewOTPFailover(primary, nil, false)
+		require.Error(t, err)
+	})
+}
+
+func TestOTPFailover(t *testing.T) {
+	t.Run("Should verify on the replica once when the primary is down", func(t *testing.T) {
+		healthy, failover := setupOTPFailover(t, false)
+		otp, err := healthy.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+
+		valid, err := failover.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, 1, failover.Pending())
+
+		valid, err = failover.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		_, err = failover.ReplayRevocations(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, 1, failover.Pending())
+	})
+
+	t.Run("Should queue revocations when the primary is down", func(t *testing.T) {
+		healthy, failover := setupOTPFailover(t, false)
+		otp, err := healthy.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+
+		require.NoError(t, failover.RevokeOTP(context.Background(), "123"))
+		assert.Equal(t, 1, failover.Pending())
+
+		valid, err := failover.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should return primary errors in strict mode", func(t *testing.T) {
+		healthy, failover := setupOTPFailover(t, true)
+		otp, err := healthy.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+
+		_, err = failover.VerifyOTP(context.Background(), "123", *otp)
+		require.Error(t, err)
+		require.Error(t, failover.RevokeOTP(context.Background(), "123"))
+		assert.Zero(t, failover.Pending())
+	})
+
+	t.Run("Should use the primary when it is up", func(t *testing.T) {
+		primary, err := service.NewOTPService(t.Context(), redisDB, config, service.WithHasher(&plainHasher{}))
+		require.NoError(t, err)
+		failover, err := service.NewOTPFailover(primary, redisDB, false)
+		require.NoError(t, err)
+
+		otp, err := failover.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+		valid, err := failover.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Zero(t, failover.Pending())
+	})
+}
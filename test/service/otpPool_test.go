@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOTPPool(t *testing.T) {
+	t.Run("Should fail without OTP service", func(t *testing.T) {
+		_, err := service.NewOTPPool(nil, service.OTPPoolOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestOTPPool(t *testing.T) {
+	clock := testkit.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	otpService, err := service.NewOTPService(t.Context(), redisDB, config, service.WithHasher(&plainHasher{}), service.WithClock(clock.Now))
+	require.NoError(t, err)
+	require.NoError(t, otpService.RevokeAllOTPs(t.Context()))
+
+	pool, err := service.NewOTPPool(otpService, service.OTPPoolOptions{Size: 3, MaxAge: time.Hour})
+	require.NoError(t, err)
+
+	t.Run("Should fall back to CreateOTP while the pool is empty", func(t *testing.T) {
+		otp, err := pool.CreateOTP(context.Background(), "123")
+		require.NoError(t, err)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	t.Run("Should fill the pool up to its size", func(t *testing.T) {
+		require.Eventually(t, func() bool { return pool.Available() == 3 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Should issue pre-generated codes", func(t *testing.T) {
+		otp, err := pool.CreateOTP(context.Background(), "456")
+		require.NoError(t, err)
+		assert.Regexp(t, `^\d{6}$`, *otp)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "456", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		// The worker replaces the code taken
+		require.Eventually(t, func() bool { return pool.Available() == 3 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Should discard codes older than the maximum age", func(t *testing.T) {
+		clock.Advance(time.Hour)
+		assert.Equal(t, 0, pool.Available())
+
+		otp, err := pool.CreateOTP(context.Background(), "789")
+		require.NoError(t, err)
+
+		valid, err := otpService.VerifyOTP(context.Background(), "789", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject empty user ID", func(t *testing.T) {
+		_, err := pool.CreateOTP(context.Background(), "")
+		require.Error(t, err)
+	})
+}
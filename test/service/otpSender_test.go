@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultOTPText(t *testing.T) {
+	text := service.DefaultOTPText(service.OTPMessage{Code: "123456", ExpiresIn: 10 * time.Minute})
+	assert.Equal(t, "Your verification code is 123456. It expires in 10 minutes.", text)
+}
+
+func TestOTPService_DeliverOTP(t *testing.T) {
+	ctx := context.Background()
+
+	var sent []service.OTPMessage
+	sender, err := service.NewSNSSender(func(_ context.Context, phoneNumber string, message string) (string, error) {
+		if phoneNumber == "+15550000000" {
+			return "", errors.New("unreachable number")
+		}
+		return "sns-message-1", nil
+	}, func(msg service.OTPMessage) string {
+		sent = append(sent, msg)
+		return service.DefaultOTPText(msg)
+	})
+	require.NoError(t, err)
+
+	otpService, err := service.NewOTPServiceWithStore(ctx, service.NewMemoryKVStore(), config,
+		service.WithHasher(&plainHasher{}), service.WithOTPSender(sender))
+	require.NoError(t, err)
+
+	t.Run("Should deliver the code and return a receipt", func(t *testing.T) {
+		receipt, err := otpService.DeliverOTP(ctx, "deliver-1", "+15551234567")
+		require.NoError(t, err)
+		assert.Equal(t, "sns", receipt.Provider)
+		assert.Equal(t, "sns-message-1", receipt.MessageID)
+		assert.Equal(t, "+15551234567", receipt.Recipient)
+		assert.True(t, receipt.ExpiresAt.After(receipt.SentAt))
+
+		require.Len(t, sent, 1)
+		valid, err := otpService.VerifyOTP(ctx, "deliver-1", sent[0].Code)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should revoke the code when the delivery fails", func(t *testing.T) {
+		sent = nil
+		_, err := otpService.DeliverOTP(ctx, "deliver-2", "+15550000000")
+		require.Error(t, err)
+
+		require.Len(t, sent, 1)
+		valid, err := otpService.VerifyOTP(ctx, "deliver-2", sent[0].Code)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should fail without sender", func(t *testing.T) {
+		plain, err := service.NewOTPServiceWithStore(ctx, service.NewMemoryKVStore(), config)
+		require.NoError(t, err)
+
+		_, err = plain.DeliverOTP(ctx, "deliver-3", "user@example.com")
+		assert.Error(t, err)
+	})
+}
+
+func TestTwilioSender(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "AC123" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code": 20003, "message": "Authenticate"}`))
+			return
+		}
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "+15551234567", r.PostForm.Get("To"))
+		assert.Equal(t, "+15005550006", r.PostForm.Get("From"))
+		assert.Contains(t, r.PostForm.Get("Body"), "654321")
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid": "SM123"}`))
+	}))
+	defer server.Close()
+
+	msg := service.OTPMessage{UserID: "1", Recipient: "+15551234567", Code: "654321", ExpiresIn: 5 * time.Minute}
+
+	t.Run("Should send the code", func(t *testing.T) {
+		sender, err := service.NewTwilioSender(service.TwilioSenderConfig{
+			AccountSID: "AC123",
+			AuthToken:  "secret",
+			From:       "+15005550006",
+			BaseURL:    server.URL,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "twilio", sender.Provider())
+
+		messageID, err := sender.SendOTP(ctx, msg)
+		require.NoError(t, err)
+		assert.Equal(t, "SM123", messageID)
+	})
+
+	t.Run("Should report API errors", func(t *testing.T) {
+		sender, err := service.NewTwilioSender(service.TwilioSenderConfig{
+			AccountSID: "AC123",
+			AuthToken:  "wrong",
+			From:       "+15005550006",
+			BaseURL:    server.URL,
+		})
+		require.NoError(t, err)
+
+		_, err = sender.SendOTP(ctx, msg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Authenticate")
+	})
+
+	t.Run("Should fail without credentials", func(t *testing.T) {
+		_, err := service.NewTwilioSender(service.TwilioSenderConfig{From: "+15005550006"})
+		assert.Error(t, err)
+	})
+}
+
+func TestNewSMTPSender(t *testing.T) {
+	t.Run("Should fail with an invalid address", func(t *testing.T) {
+		_, err := service.NewSMTPSender(service.SMTPSenderConfig{Addr: "smtp.example.com", From: "no-reply@example.com"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should fail with header injection in the subject", func(t *testing.T) {
+		_, err := service.NewSMTPSender(service.SMTPSenderConfig{
+			Addr:    "smtp.example.com:587",
+			From:    "no-reply@example.com",
+			Subject: "Code\r\nBcc: attacker@example.com",
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should reject recipients with line breaks", func(t *testing.T) {
+		sender, err := service.NewSMTPSender(service.SMTPSenderConfig{Addr: "smtp.example.com:587", From: "no-reply@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "smtp", sender.Provider())
+
+		_, err = sender.SendOTP(context.Background(), service.OTPMessage{Recipient: "user@example.com\r\nBcc: attacker@example.com"})
+		assert.Error(t, err)
+	})
+}
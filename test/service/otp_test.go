@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
 	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,19 +126,12 @@ func TestCreateOTP(t *testing.T) {
 	})
 
 	t.Run("Should generate OTP with leading zeros", func(t *testing.T) {
-		// Generate multiple OTPs to increase chance of getting one with leading zeros
-		hasLeadingZero := false
-		for i := 0; i < 100; i++ {
-			otp, err := os.CreateOTP(context.Background(), strconv.Itoa(1000+i))
-			require.NoError(t, err)
-			if (*otp)[0] == '0' {
-				hasLeadingZero = true
-				assert.Regexp(t, `^\d{6}$`, *otp, "OTP with leading zero should still be 6 digits")
-				break
-			}
-		}
-		// This test may occasionally fail due to randomness (~9% chance each iteration)
-		assert.True(t, hasLeadingZero, "Should generate OTPs with leading zeros (e.g., '000123')")
+		seeded, err := service.NewOTPService(t.Context(), redisDB, config, service.WithOTPGenerator(testkit.OTPSequence("000123")))
+		require.NoError(t, err)
+
+		otp, err := seeded.CreateOTP(context.Background(), "1000")
+		require.NoError(t, err)
+		assert.Equal(t, "000123", *otp, "OTP with leading zero should still be 6 digits")
 	})
 }
 
@@ -274,23 +270,15 @@ func TestVerifyOTP(t *testing.T) {
 
 	t.Run("Should verify OTP with leading zeros", func(t *testing.T) {
 		userID := "555"
-		// Keep creating OTPs until we get one with leading zero
-		var otp *string
-		var err error
-		for i := 0; i < 100; i++ {
-			otp, err = os.CreateOTP(context.Background(), userID)
-			require.NoError(t, err)
-			if (*otp)[0] == '0' {
-				break
-			}
-		}
+		seeded, err := service.NewOTPService(t.Context(), redisDB, config, service.WithOTPGenerator(testkit.OTPSequence("000042")))
+		require.NoError(t, err)
 
-		// Verify the zero-padded OTP works
-		if (*otp)[0] == '0' {
-			valid, err := os.VerifyOTP(context.Background(), userID, *otp)
-			require.NoError(t, err)
-			assert.True(t, valid, "OTP with leading zeros should be valid")
-		}
+		otp, err := seeded.CreateOTP(context.Background(), userID)
+		require.NoError(t, err)
+
+		valid, err := seeded.VerifyOTP(context.Background(), userID, *otp)
+		require.NoError(t, err)
+		assert.True(t, valid, "OTP with leading zeros should be valid")
 	})
 }
 
@@ -319,6 +307,26 @@ func TestOTPRateLimiting(t *testing.T) {
 		assert.Contains(t, err.Error(), "max attempts exceeded")
 	})
 
+	t.Run("Should report the attempts left", func(t *testing.T) {
+		userID := "rate-limit"
+		_, err := os.CreateOTP(context.Background(), userID)
+		require.NoError(t, err)
+
+		state, err := os.RateLimit(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, 5, state.Limit)
+		assert.Equal(t, 5, state.Remaining)
+		assert.Positive(t, state.Reset)
+
+		for i := 0; i < 5; i++ {
+			_, err := os.VerifyOTP(context.Background(), userID, "999999")
+			require.NoError(t, err)
+		}
+		state, err = os.RateLimit(context.Background(), userID)
+		require.NoError(t, err)
+		assert.True(t, state.Exhausted())
+	})
+
 	t.Run("Should allow verification before reaching rate limit", func(t *testing.T) {
 		userID := "789"
 		otp, err := os.CreateOTP(context.Background(), userID)
@@ -528,6 +536,61 @@ func TestRevokeAllOTPs(t *testing.T) {
 		err := os.RevokeAllOTPs(nil)
 		require.NoError(t, err)
 	})
+
+	t.Run("Should report progress in batches", func(t *testing.T) {
+		for i := 0; i < 12; i++ {
+			_, err := os.CreateOTP(context.Background(), "batch-"+strconv.Itoa(i))
+			require.NoError(t, err)
+		}
+
+		var reports []service.CleanupStats
+		revoked, err := os.RevokeAllOTPsInBatches(context.Background(), service.CleanupOptions{
+			BatchSize: 5,
+			Report:    func(stats service.CleanupStats) { reports = append(reports, stats) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), revoked)
+		require.NotEmpty(t, reports)
+		last := reports[len(reports)-1]
+		assert.Equal(t, int64(12), last.Deleted)
+		assert.GreaterOrEqual(t, last.Scanned, int64(12))
+	})
+
+	t.Run("Should stop when the context is cancelled", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			_, err := os.CreateOTP(context.Background(), "batch-"+strconv.Itoa(i))
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := os.RevokeAllOTPsInBatches(ctx, service.CleanupOptions{
+			BatchSize: 1,
+			Report:    func(service.CleanupStats) { cancel() },
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Should revoke the OTPs of the given users only", func(t *testing.T) {
+		otps := map[string]string{}
+		for _, userID := range []string{"u1", "u2", "u3"} {
+			otp, err := os.CreateOTP(context.Background(), userID)
+			require.NoError(t, err)
+			otps[userID] = *otp
+		}
+
+		require.NoError(t, os.RevokeOTPsOfUsers(context.Background(), []string{"u1", "u2"}, 2))
+
+		for userID, expected := range map[string]bool{"u1": false, "u2": false, "u3": true} {
+			valid, err := os.VerifyOTP(context.Background(), userID, otps[userID])
+			require.NoError(t, err)
+			assert.Equal(t, expected, valid, "user %s", userID)
+		}
+	})
+
+	t.Run("Should report the failed users", func(t *testing.T) {
+		err := os.RevokeOTPsOfUsers(context.Background(), []string{"u1", ""}, 0)
+		require.Error(t, err)
+	})
 }
 
 // ========================================
@@ -629,3 +692,148 @@ func TestOTPExpiration(t *testing.T) {
 		assert.True(t, valid, "New OTP should work after expiration")
 	})
 }
+
+func TestOTPService_ValueEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	valueCipher, err := lib.NewValueCipher(map[string][]byte{"k1": key}, "k1")
+	require.NoError(t, err)
+	plain := setupOTPService(t)
+	encrypted, err := service.NewOTPService(t.Context(), redisDB, config, service.WithValueEncryption(valueCipher))
+	require.NoError(t, err)
+
+	t.Run("Should store encrypted hashes", func(t *testing.T) {
+		otp, err := encrypted.CreateOTP(t.Context(), "enc-123")
+		require.NoError(t, err)
+
+		stored, err := redisDB.Get(t.Context(), "otp:enc-123").Result()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored, "enc:v1:k1:"))
+
+		valid, err := encrypted.VerifyOTP(t.Context(), "enc-123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should accept codes stored before encryption", func(t *testing.T) {
+		otp, err := plain.CreateOTP(t.Context(), "enc-456")
+		require.NoError(t, err)
+
+		valid, err := encrypted.VerifyOTP(t.Context(), "enc-456", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject an encrypted hash moved to another user", func(t *testing.T) {
+		_, err := encrypted.CreateOTP(t.Context(), "enc-789")
+		require.NoError(t, err)
+		stored, err := redisDB.Get(t.Context(), "otp:enc-789").Result()
+		require.NoError(t, err)
+		require.NoError(t, redisDB.Set(t.Context(), "otp:enc-000", stored, time.Minute).Err())
+
+		_, err = encrypted.VerifyOTP(t.Context(), "enc-000", "123456")
+		require.Error(t, err)
+	})
+}
+
+// reversedHasher stores codes reversed, as a second hashing algorithm.
+type reversedHasher struct {
+	lib.PasswordHash
+}
+
+func (*reversedHasher) Hash(password string) (string, error) {
+	reversed := []byte(password)
+	slices.Reverse(reversed)
+	return string(reversed), nil
+}
+
+func (h *reversedHasher) CheckHash(password, hash string) bool {
+	reversed, _ := h.Hash(password)
+	return hash == reversed
+}
+
+func TestOTPService_WithOTPHashers(t *testing.T) {
+	store := service.NewMemoryKVStore()
+	generator := service.WithOTPGenerator(testkit.OTPSequence("000123", "000456"))
+	before, err := service.NewOTPServiceWithStore(t.Context(), store, config, service.WithHasher(&plainHasher{}), generator)
+	require.NoError(t, err)
+	after, err := service.NewOTPServiceWithStore(t.Context(), store, config, service.WithHasher(&plainHasher{}), generator,
+		service.WithOTPHashers("rev1", map[string]lib.PasswordHashInterface{"rev1": &reversedHasher{}}))
+	require.NoError(t, err)
+
+	t.Run("Should fail when the current hasher is missing", func(t *testing.T) {
+		_, err := service.NewOTPServiceWithStore(t.Context(), store, config,
+			service.WithOTPHashers("rev2", map[string]lib.PasswordHashInterface{"rev1": &reversedHasher{}}))
+		require.Error(t, err)
+	})
+
+	t.Run("Should store the hasher identifier", func(t *testing.T) {
+		otp, err := after.CreateOTP(t.Context(), "123")
+		require.NoError(t, err)
+
+		stored, found, err := store.Get(t.Context(), "otp:123")
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, "rev1:321000", stored)
+
+		valid, err := after.VerifyOTP(t.Context(), "123", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should verify codes in flight with the previous hasher", func(t *testing.T) {
+		otp, err := before.CreateOTP(t.Context(), "456")
+		require.NoError(t, err)
+
+		valid, err := after.VerifyOTP(t.Context(), "456", *otp)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
+
+func TestOTPService_WithStaticOTP(t *testing.T) {
+	t.Run("Should refuse a code that is not 6 digits", func(t *testing.T) {
+		_, err := service.NewOTPServiceWithStore(t.Context(), service.NewMemoryKVStore(), config, service.WithStaticOTP("1234"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "6 digits")
+	})
+
+	t.Run("Should announce the static code", func(t *testing.T) {
+		recorder := testkit.NewAuditRecorder()
+		_, err := service.NewOTPServiceWithStore(t.Context(), service.NewMemoryKVStore(), config,
+			service.WithStaticOTP("000000"), service.WithLogger(recorder), service.WithKeyPrefix("staging"))
+		require.NoError(t, err)
+
+		events := recorder.EventsOfType(service.AuditEventOTPStaticCodeEnabled)
+		require.Len(t, events, 1)
+		assert.Equal(t, "staging", events[0].Details["key_prefix"])
+		assert.NotEmpty(t, events[0].Details["warning"])
+	})
+
+	t.Run("Should create and verify the static code for every user", func(t *testing.T) {
+		os, err := service.NewOTPServiceWithStore(t.Context(), service.NewMemoryKVStore(), config,
+			service.WithHasher(&plainHasher{}), service.WithStaticOTP("000000"))
+		require.NoError(t, err)
+
+		for _, userID := range []string{"123", "456"} {
+			otp, err := os.CreateOTP(t.Context(), userID)
+			require.NoError(t, err)
+			assert.Equal(t, "000000", *otp)
+
+			valid, err := os.VerifyOTP(t.Context(), userID, "000000")
+			require.NoError(t, err)
+			assert.True(t, valid)
+		}
+	})
+
+	t.Run("Should be replaced by a later generator", func(t *testing.T) {
+		recorder := testkit.NewAuditRecorder()
+		os, err := service.NewOTPServiceWithStore(t.Context(), service.NewMemoryKVStore(), config, service.WithHasher(&plainHasher{}),
+			service.WithStaticOTP("000000"), service.WithOTPGenerator(testkit.OTPSequence("000123")), service.WithLogger(recorder))
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Events())
+
+		otp, err := os.CreateOTP(t.Context(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, "000123", *otp)
+	})
+}
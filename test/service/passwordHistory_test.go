@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lowCostHasher mirrors lib.PasswordHash with the minimum bcrypt cost to keep tests fast.
+type lowCostHasher struct {
+	lib.PasswordHash
+}
+
+func (h *lowCostHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(bytes), err
+}
+
+func setupPasswordHistoryService(t *testing.T) *service.PasswordHistoryService {
+	phs, err := service.NewPasswordHistoryService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+
+	// Clear all histories to ensure clean state
+	err = phs.ClearAllPasswordHistories(t.Context())
+	require.NoError(t, err)
+
+	return phs
+}
+
+func TestNewPasswordHistoryService(t *testing.T) {
+	t.Run("Should create service successfully", func(t *testing.T) {
+		_, err := service.NewPasswordHistoryService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should handle nil context", func(t *testing.T) {
+		_, err := service.NewPasswordHistoryService(nil, redisDB, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewPasswordHistoryService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with negative size", func(t *testing.T) {
+		invalidConfig := &lib.Config{PasswordHistorySize: -1}
+		_, err := service.NewPasswordHistoryService(context.Background(), redisDB, invalidConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "password history size is negative")
+	})
+}
+
+func TestWasRecentlyUsed(t *testing.T) {
+	phs := setupPasswordHistoryService(t)
+	hasher := &lowCostHasher{}
+
+	t.Run("Should detect a recently used password", func(t *testing.T) {
+		hash, err := hasher.Hash("Old_Passw0rd")
+		require.NoError(t, err)
+		require.NoError(t, phs.AddPasswordHash(context.Background(), "123", hash))
+
+		used, err := phs.WasRecentlyUsed(context.Background(), "123", "Old_Passw0rd", hasher)
+		require.NoError(t, err)
+		assert.True(t, used)
+
+		used, err = phs.WasRecentlyUsed(context.Background(), "123", "New_Passw0rd", hasher)
+		require.NoError(t, err)
+		assert.False(t, used)
+	})
+
+	t.Run("Should forget passwords beyond the history size", func(t *testing.T) {
+		userID := "456"
+		for i := 0; i < 6; i++ {
+			hash, err := hasher.Hash("Passw0rd_" + strconv.Itoa(i))
+			require.NoError(t, err)
+			require.NoError(t, phs.AddPasswordHash(context.Background(), userID, hash))
+		}
+
+		// Default size is 5: the first password has been pushed out
+		used, err := phs.WasRecentlyUsed(context.Background(), userID, "Passw0rd_0", hasher)
+		require.NoError(t, err)
+		assert.False(t, used)
+
+		used, err = phs.WasRecentlyUsed(context.Background(), userID, "Passw0rd_1", hasher)
+		require.NoError(t, err)
+		assert.True(t, used)
+
+		length, err := redisDB.LLen(context.Background(), "password_history:"+userID).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), length)
+	})
+
+	t.Run("Should return false for user without history", func(t *testing.T) {
+		used, err := phs.WasRecentlyUsed(context.Background(), "999", "Any_Passw0rd", hasher)
+		require.NoError(t, err)
+		assert.False(t, used)
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		_, err := phs.WasRecentlyUsed(context.Background(), "", "Any_Passw0rd", hasher)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+
+	t.Run("Should fail with nil hasher", func(t *testing.T) {
+		_, err := phs.WasRecentlyUsed(context.Background(), "123", "Any_Passw0rd", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "hasher is nil")
+	})
+}
+
+func TestAddPasswordHash(t *testing.T) {
+	phs := setupPasswordHistoryService(t)
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		err := phs.AddPasswordHash(context.Background(), "", "hash")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+
+	t.Run("Should fail with empty hash", func(t *testing.T) {
+		err := phs.AddPasswordHash(context.Background(), "123", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty hash")
+	})
+
+	t.Run("Should handle nil context", func(t *testing.T) {
+		err := phs.AddPasswordHash(nil, "123", "hash")
+		require.NoError(t, err)
+	})
+}
+
+func TestClearPasswordHistory(t *testing.T) {
+	phs := setupPasswordHistoryService(t)
+	hasher := &lowCostHasher{}
+
+	t.Run("Should clear user history only", func(t *testing.T) {
+		hash, err := hasher.Hash("Old_Passw0rd")
+		require.NoError(t, err)
+		require.NoError(t, phs.AddPasswordHash(context.Background(), "123", hash))
+		require.NoError(t, phs.AddPasswordHash(context.Background(), "456", hash))
+
+		require.NoError(t, phs.ClearPasswordHistory(context.Background(), "123"))
+
+		used, err := phs.WasRecentlyUsed(context.Background(), "123", "Old_Passw0rd", hasher)
+		require.NoError(t, err)
+		assert.False(t, used)
+
+		used, err = phs.WasRecentlyUsed(context.Background(), "456", "Old_Passw0rd", hasher)
+		require.NoError(t, err)
+		assert.True(t, used)
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		err := phs.ClearPasswordHistory(context.Background(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetService_ShortCode(t *testing.T) {
+	prs := setupPasswordResetService(t)
+	ctx := t.Context()
+
+	t.Run("Should verify the code and return the token", func(t *testing.T) {
+		userID := "reset-code-1"
+		credentials, err := prs.CreatePasswordResetTokenWithCode(ctx, userID)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, credentials.Code)
+
+		token, err := prs.VerifyPasswordResetCode(ctx, userID, strings.ToLower(strings.ReplaceAll(credentials.Code, "-", " ")))
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.Equal(t, credentials.Token, *token)
+
+		valid, err := prs.VerifyPasswordResetToken(ctx, userID, credentials.Token)
+		require.NoError(t, err)
+		assert.True(t, valid, "The link must keep working")
+
+		require.NoError(t, prs.RevokePasswordResetToken(ctx, userID, *token))
+		token, err = prs.VerifyPasswordResetCode(ctx, userID, credentials.Code)
+		require.NoError(t, err)
+		assert.Nil(t, token, "Revoking the token must invalidate the code")
+	})
+
+	t.Run("Should invalidate the code of a replaced reset", func(t *testing.T) {
+		userID := "reset-code-2"
+		credentials, err := prs.CreatePasswordResetTokenWithCode(ctx, userID)
+		require.NoError(t, err)
+
+		_, err = prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+
+		token, err := prs.VerifyPasswordResetCode(ctx, userID, credentials.Code)
+		require.NoError(t, err)
+		assert.Nil(t, token)
+	})
+
+	t.Run("Should limit the code attempts separately from the link", func(t *testing.T) {
+		userID := "reset-code-3"
+		credentials, err := prs.CreatePasswordResetTokenWithCode(ctx, userID)
+		require.NoError(t, err)
+
+		for range 5 {
+			token, err := prs.VerifyPasswordResetCode(ctx, userID, "BCDF-GHJK")
+			require.NoError(t, err)
+			assert.Nil(t, token)
+		}
+
+		_, err = prs.VerifyPasswordResetCode(ctx, userID, credentials.Code)
+		assert.ErrorIs(t, err, service.ErrMaxAttemptsExceeded)
+
+		valid, err := prs.VerifyPasswordResetToken(ctx, userID, credentials.Token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		// A new reset resets the attempts
+		credentials, err = prs.CreatePasswordResetTokenWithCode(ctx, userID)
+		require.NoError(t, err)
+		token, err := prs.VerifyPasswordResetCode(ctx, userID, credentials.Code)
+		require.NoError(t, err)
+		assert.NotNil(t, token)
+	})
+
+	t.Run("Should fail with empty user ID", func(t *testing.T) {
+		_, err := prs.VerifyPasswordResetCode(ctx, "", "BCDF-GHJK")
+		assert.ErrorIs(t, err, service.ErrInvalidUserID)
+	})
+}
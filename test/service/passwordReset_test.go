@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -452,3 +453,310 @@ func TestPasswordResetConcurrentOperations(t *testing.T) {
 		assert.Equal(t, 1, validCount, "Only one token should be valid after concurrent creation")
 	})
 }
+
+func TestScopedPasswordResetToken(t *testing.T) {
+	prs := setupPasswordResetService(t)
+	userID := "123"
+
+	t.Run("Should carry the scope through verification and audit", func(t *testing.T) {
+		var events []lib.AuditEvent
+		prs.SetAuditLogger(lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+			events = append(events, event)
+		}))
+		defer prs.SetAuditLogger(nil)
+
+		token, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeAdminForced)
+		require.NoError(t, err)
+
+		scope, valid, err := prs.VerifyScopedPasswordResetToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, service.PasswordResetScopeAdminForced, scope)
+
+		require.Len(t, events, 2)
+		assert.Equal(t, service.AuditEventPasswordResetCreated, events[0].Type)
+		assert.Equal(t, service.AuditEventPasswordResetVerified, events[1].Type)
+		assert.Equal(t, "ADMIN_FORCED", events[1].Details["scope"])
+
+		// Scoped tokens can be revoked like self-service ones
+		require.NoError(t, prs.RevokePasswordResetToken(context.Background(), userID, *token))
+	})
+
+	t.Run("Should default to self-service", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		scope, valid, err := prs.VerifyScopedPasswordResetToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, service.PasswordResetScopeSelfService, scope)
+	})
+
+	t.Run("Should not return a scope for an invalid token", func(t *testing.T) {
+		_, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeBreachReset)
+		require.NoError(t, err)
+
+		scope, valid, err := prs.VerifyScopedPasswordResetToken(context.Background(), userID, "wrongtoken")
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Empty(t, scope)
+	})
+
+	t.Run("Should fail with unknown scope", func(t *testing.T) {
+		_, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, "UNKNOWN")
+		require.Error(t, err)
+		assert.Equal(t, "invalid password reset scope", err.Error())
+	})
+}
+
+func TestPasswordResetPolicy(t *testing.T) {
+	newService := func(t *testing.T, policy lib.PasswordResetPolicy) *service.PasswordResetService {
+		ttl := "1h"
+		prs, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetPolicy: policy})
+		require.NoError(t, err)
+		require.NoError(t, prs.RevokeAllPasswordResetTokens(t.Context()))
+		return prs
+	}
+	userID := "123"
+
+	t.Run("Should replace the previous token by default", func(t *testing.T) {
+		prs := newService(t, "")
+		first, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.NotEqual(t, *first, *second)
+
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), userID, *first)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should reuse the existing token", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyReuse)
+		first, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, *first, *second)
+	})
+
+	t.Run("Should extend the existing token", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyExtend)
+		first, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		require.NoError(t, redisDB.Expire(context.Background(), "password_reset:"+userID, time.Minute).Err())
+
+		second, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, *first, *second)
+
+		ttl, err := redisDB.TTL(context.Background(), "password_reset:"+userID).Result()
+		require.NoError(t, err)
+		assert.Greater(t, ttl, 59*time.Minute)
+	})
+
+	t.Run("Should replace a token issued for another scope", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyReuse)
+		first, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		second, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeBreachReset)
+		require.NoError(t, err)
+		assert.NotEqual(t, *first, *second)
+	})
+
+	t.Run("Should fail with unknown policy", func(t *testing.T) {
+		ttl := "1h"
+		_, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetPolicy: "unknown"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid password reset policy")
+	})
+}
+
+func TestPasswordResetQuota(t *testing.T) {
+	ttl := "1h"
+	newService := func(t *testing.T, policy lib.PasswordResetPolicy) *service.PasswordResetService {
+		prs, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetPolicy: policy, PasswordResetQuota: 3})
+		require.NoError(t, err)
+		return prs
+	}
+
+	t.Run("Should reject requests beyond the quota", func(t *testing.T) {
+		prs := newService(t, "")
+		userID := "quota-" + time.Now().Format("150405.000000")
+
+		var last *string
+		for range 3 {
+			token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+			require.NoError(t, err)
+			last = token
+		}
+
+		_, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.ErrorIs(t, err, service.ErrPasswordResetQuotaExceeded)
+		var quotaErr *service.PasswordResetQuotaError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, 3, quotaErr.Limit)
+		assert.Greater(t, quotaErr.RetryAfter, 59*time.Minute)
+
+		// The last token issued is still valid
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), userID, *last)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		// Other users are not affected
+		_, err = prs.CreatePasswordResetToken(context.Background(), userID+"-other")
+		require.NoError(t, err)
+	})
+
+	t.Run("Should not count reused tokens", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyReuse)
+		userID := "quota-reuse-" + time.Now().Format("150405.000000")
+
+		for range 5 {
+			_, err := prs.CreatePasswordResetToken(context.Background(), userID)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Should fail with a negative quota", func(t *testing.T) {
+		_, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetQuota: -1})
+		require.Error(t, err)
+	})
+}
+
+func TestIdentifyPasswordResetToken(t *testing.T) {
+	prs := setupPasswordResetService(t)
+	userID := "123"
+
+	t.Run("Should return the owner, scope and expiry", func(t *testing.T) {
+		token, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeAdminForced)
+		require.NoError(t, err)
+
+		info, err := prs.IdentifyPasswordResetToken(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, userID, info.UserID)
+		assert.Equal(t, service.PasswordResetScopeAdminForced, info.Scope)
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), info.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Should not identify a replaced token", func(t *testing.T) {
+		first, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		_, err = prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		info, err := prs.IdentifyPasswordResetToken(context.Background(), *first)
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	t.Run("Should not identify a revoked token", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		require.NoError(t, prs.RevokePasswordResetToken(context.Background(), userID, *token))
+
+		info, err := prs.IdentifyPasswordResetToken(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	t.Run("Should fail with empty token", func(t *testing.T) {
+		_, err := prs.IdentifyPasswordResetToken(context.Background(), "")
+		require.Error(t, err)
+	})
+}
+
+func TestPasswordResetTokenNormalization(t *testing.T) {
+	ttl := "1h"
+	prs, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{
+		PasswordResetTTL:   &ttl,
+		TokenNormalization: lib.TokenNormalization{TrimSpace: true, FoldCase: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, prs.RevokeAllPasswordResetTokens(t.Context()))
+	userID := "123"
+
+	t.Run("Should accept a mangled token", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(*token), *token)
+
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), userID, "  "+strings.ToLower(*token)+"\n")
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		info, err := prs.IdentifyPasswordResetToken(context.Background(), strings.ToLower(*token))
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, userID, info.UserID)
+	})
+
+	t.Run("Should keep tokens case-sensitive by default", func(t *testing.T) {
+		prs := setupPasswordResetService(t)
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), userID, strings.ToLower(*token))
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
+
+func TestPasswordResetTokenLength(t *testing.T) {
+	ttl := "1h"
+
+	t.Run("Should generate tokens of the configured length", func(t *testing.T) {
+		prs, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetTokenLength: 64})
+		require.NoError(t, err)
+
+		token, err := prs.CreatePasswordResetToken(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Len(t, *token, 64)
+
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should fail below the minimum length", func(t *testing.T) {
+		_, err := service.NewPasswordResetService(t.Context(), redisDB, &lib.Config{PasswordResetTTL: &ttl, PasswordResetTokenLength: 8})
+		require.Error(t, err)
+		assert.Equal(t, "password reset token length must be at least 16", err.Error())
+	})
+}
+
+func TestRevokePasswordResetTokenWithReason(t *testing.T) {
+	prs := setupPasswordResetService(t)
+
+	var events []lib.AuditEvent
+	prs.SetAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	t.Run("Should record the reason in the revocation log and audit event", func(t *testing.T) {
+		userID := "revoked-reason-" + time.Now().Format("150405.000000")
+		token, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeAdminForced)
+		require.NoError(t, err)
+
+		err = prs.RevokePasswordResetTokenWithReason(context.Background(), userID, *token, service.RevocationReasonAdmin)
+		require.NoError(t, err)
+
+		records, err := prs.ListRevokedPasswordResetTokens(context.Background(), userID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, service.RevocationReasonAdmin, records[0].Reason)
+
+		event := events[len(events)-1]
+		assert.Equal(t, service.AuditEventPasswordResetRevoked, event.Type)
+		assert.Equal(t, "ADMIN", event.Details["reason"])
+		assert.Equal(t, "ADMIN_FORCED", event.Details["scope"])
+	})
+
+	t.Run("Should fail with an unknown reason", func(t *testing.T) {
+		err := prs.RevokePasswordResetTokenWithReason(context.Background(), "123", "some-token", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid revocation reason")
+	})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restrictedClient creates a Redis user with the given ACL SETUSER command and returns a client for it.
+func restrictedClient(t *testing.T, username string, command string) *redis.Client {
+	args := []any{}
+	for _, arg := range strings.Fields(command) {
+		args = append(args, arg)
+	}
+	require.NoError(t, redisDB.Do(t.Context(), append(args, ">"+username+"-password")...).Err())
+	t.Cleanup(func() { redisDB.Do(t.Context(), "ACL", "DELUSER", username) })
+
+	options := *redisDB.Options()
+	options.Username = username
+	options.Password = username + "-password"
+	client := redis.NewClient(&options)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestVerifyRedisSetup(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		err := service.VerifyRedisSetup(t.Context(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should pass with full access", func(t *testing.T) {
+		require.NoError(t, service.VerifyRedisSetup(t.Context(), redisDB))
+	})
+
+	t.Run("Should pass with the restricted user", func(t *testing.T) {
+		command, err := service.RedisACLSetUser("preflight-ok", service.WithKeyPrefix("preflight-app"))
+		require.NoError(t, err)
+		client := restrictedClient(t, "preflight-ok", command)
+
+		require.NoError(t, service.VerifyRedisSetup(t.Context(), client, service.WithKeyPrefix("preflight-app")))
+	})
+
+	t.Run("Should name the missing permissions", func(t *testing.T) {
+		client := restrictedClient(t, "preflight-noscript",
+			"ACL SETUSER preflight-noscript on resetkeys ~preflight-app:* resetchannels -@all +@read +@write +@keyspace +@transaction +@connection -@dangerous")
+
+		err := service.VerifyRedisSetup(t.Context(), client, service.WithKeyPrefix("preflight-app"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "EVALSHA/EVAL")
+		assert.Contains(t, err.Error(), "PUBLISH")
+		assert.Contains(t, err.Error(), "RedisACLSetUser")
+		assert.NotContains(t, err.Error(), "GETDEL")
+	})
+
+	t.Run("Should reject keys outside the prefix", func(t *testing.T) {
+		command, err := service.RedisACLSetUser("preflight-prefix", service.WithKeyPrefix("preflight-app"))
+		require.NoError(t, err)
+		client := restrictedClient(t, "preflight-prefix", command)
+
+		err = service.VerifyRedisSetup(t.Context(), client, service.WithKeyPrefix("other-app"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "may not run SET")
+	})
+}
@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/lib"
 	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -296,6 +300,24 @@ func TestRevokeAllUserRefreshTokens(t *testing.T) {
 		assert.True(t, validOther)
 	})
 
+	t.Run("Should delete the lookup entries with the tokens", func(t *testing.T) {
+		userID := "lookup-user"
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		sum := sha256.Sum256([]byte(*token))
+		lookup := "refresh_lookup:" + hex.EncodeToString(sum[:])
+		exists, err := redisDB.Exists(context.Background(), lookup).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists)
+
+		require.NoError(t, rts.RevokeAllUserRefreshTokens(context.Background(), userID))
+
+		exists, err = redisDB.Exists(context.Background(), lookup).Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists)
+	})
+
 	t.Run("Should fail with invalid user ID", func(t *testing.T) {
 		err := rts.RevokeAllUserRefreshTokens(context.Background(), "")
 		require.Error(t, err)
@@ -365,3 +387,458 @@ func TestInvalidConfig(t *testing.T) {
 		assert.Contains(t, err.Error(), "time: invalid duration")
 	})
 }
+
+func TestBoundRefreshToken(t *testing.T) {
+	rts := setupService(t)
+	userID := "123"
+	thumbprint := "bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2"
+
+	t.Run("Should verify a token with the bound certificate", func(t *testing.T) {
+		token, err := rts.CreateBoundRefreshToken(context.Background(), userID, thumbprint)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyBoundRefreshToken(context.Background(), userID, *token, thumbprint)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject another certificate", func(t *testing.T) {
+		token, err := rts.CreateBoundRefreshToken(context.Background(), userID, thumbprint)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyBoundRefreshToken(context.Background(), userID, *token, "another-thumbprint")
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should reject a bound token verified without certificate", func(t *testing.T) {
+		token, err := rts.CreateBoundRefreshToken(context.Background(), userID, thumbprint)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should reject an unbound token verified with a certificate", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyBoundRefreshToken(context.Background(), userID, *token, thumbprint)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should fail with empty thumbprint", func(t *testing.T) {
+		_, err := rts.CreateBoundRefreshToken(context.Background(), userID, "")
+		require.Error(t, err)
+		assert.Equal(t, "empty certificate thumbprint", err.Error())
+	})
+}
+
+func TestRefreshTokenLength(t *testing.T) {
+	ttl := "1h"
+
+	t.Run("Should generate tokens of the configured length", func(t *testing.T) {
+		rts, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenLength: 64})
+		require.NoError(t, err)
+
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		assert.Len(t, *token, 64)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject tokens longer than the max length", func(t *testing.T) {
+		rts, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenLength: 64, RefreshTokenMaxLength: 64})
+		require.NoError(t, err)
+
+		_, err = rts.VerifyRefreshToken(context.Background(), "123", strings.Repeat("a", 65))
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail below the minimum length", func(t *testing.T) {
+		_, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenLength: 16})
+		require.Error(t, err)
+		assert.Equal(t, "refresh token length must be at least 32", err.Error())
+	})
+
+	t.Run("Should fail with a max length below the length", func(t *testing.T) {
+		_, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenLength: 64, RefreshTokenMaxLength: 48})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "refresh token max length must be at least the token length 64")
+	})
+}
+
+func TestRefreshTokenChecksum(t *testing.T) {
+	ttl := "1h"
+	rts, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, TokenChecksum: true, TokenChecksumSecret: "secret"})
+	require.NoError(t, err)
+	userID := "123"
+
+	t.Run("Should verify a checksummed token", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Len(t, *token, 255+lib.TokenChecksumLength)
+		assert.True(t, lib.HasValidTokenChecksum(*token, "secret"))
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject a truncated token", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, (*token)[:200])
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
+
+func TestRevokeRefreshTokenWithReason(t *testing.T) {
+	rts := setupService(t)
+
+	var events []lib.AuditEvent
+	rts.SetAuditLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	t.Run("Should record the reason in the revocation log and audit event", func(t *testing.T) {
+		userID := "revoked-reason-" + time.Now().Format("150405.000000")
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		err = rts.RevokeRefreshTokenWithReason(context.Background(), *token, userID, service.RevocationReasonSuspicious)
+		require.NoError(t, err)
+
+		records, err := rts.ListRevokedRefreshTokens(context.Background(), userID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, service.RevocationReasonSuspicious, records[0].Reason)
+		assert.Len(t, records[0].TokenHash, 64)
+
+		require.NotEmpty(t, events)
+		event := events[len(events)-1]
+		assert.Equal(t, service.AuditEventRefreshTokenRevoked, event.Type)
+		assert.Equal(t, "SUSPICIOUS", event.Details["reason"])
+		assert.Equal(t, records[0].TokenHash, event.Details["token_hash"])
+		assert.Equal(t, lib.TokenFingerprint(*token), event.Details["token_fingerprint"])
+		assert.True(t, strings.HasPrefix(records[0].TokenHash, event.Details["token_fingerprint"]))
+
+		created := events[len(events)-2]
+		assert.Equal(t, service.AuditEventRefreshTokenCreated, created.Type)
+		assert.Equal(t, userID, created.UserID)
+		assert.Equal(t, event.Details["token_hash"], created.Details["token_hash"])
+		assert.NotEmpty(t, created.Details["expires_at"])
+	})
+
+	t.Run("Should default to USER_LOGOUT and skip unknown tokens", func(t *testing.T) {
+		userID := "revoked-default-" + time.Now().Format("150405.000000")
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		require.NoError(t, rts.RevokeRefreshToken(context.Background(), *token, userID))
+		require.NoError(t, rts.RevokeRefreshToken(context.Background(), *token, userID))
+
+		records, err := rts.ListRevokedRefreshTokens(context.Background(), userID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, service.RevocationReasonUserLogout, records[0].Reason)
+	})
+
+	t.Run("Should fail with an unknown reason", func(t *testing.T) {
+		err := rts.RevokeRefreshTokenWithReason(context.Background(), "some-token", "123", "EXPIRED")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid revocation reason")
+	})
+}
+
+func TestRevokeAllRefreshTokensInBatches(t *testing.T) {
+	rts := setupService(t)
+
+	t.Run("Should revoke every token in batches and report progress", func(t *testing.T) {
+		tokens := make([]string, 0, 12)
+		for i := 0; i < 12; i++ {
+			token, err := rts.CreateRefreshToken(context.Background(), "batch")
+			require.NoError(t, err)
+			tokens = append(tokens, *token)
+		}
+
+		var progress []int64
+		revoked, err := rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{
+			BatchSize: 5,
+			Pause:     time.Millisecond,
+			Progress:  func(deleted int64) { progress = append(progress, deleted) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), revoked)
+		require.NotEmpty(t, progress)
+		assert.Equal(t, int64(12), progress[len(progress)-1])
+
+		for _, token := range tokens {
+			valid, err := rts.VerifyRefreshToken(context.Background(), "batch", token)
+			require.NoError(t, err)
+			assert.False(t, valid)
+		}
+	})
+
+	t.Run("Should stop when the context is cancelled", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			_, err := rts.CreateRefreshToken(context.Background(), "batch")
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := rts.RevokeAllRefreshTokensInBatches(ctx, service.CleanupOptions{
+			BatchSize: 1,
+			Pause:     time.Second,
+			Progress:  func(int64) { cancel() },
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Should bound each round trip with the operation timeout", func(t *testing.T) {
+		_, err := rts.CreateRefreshToken(context.Background(), "batch")
+		require.NoError(t, err)
+
+		_, err = rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{OperationTimeout: time.Nanosecond})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		revoked, err := rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{OperationTimeout: time.Second})
+		require.NoError(t, err)
+		assert.Positive(t, revoked, "the deadline applies to each round trip, not to the whole job")
+	})
+
+	t.Run("Should delete batches in parallel", func(t *testing.T) {
+		require.NoError(t, rts.RevokeAllRefreshTokens(context.Background()))
+		for i := 0; i < 12; i++ {
+			_, err := rts.CreateRefreshToken(context.Background(), "batch")
+			require.NoError(t, err)
+		}
+
+		var last service.CleanupStats
+		revoked, err := rts.RevokeAllRefreshTokensInBatches(context.Background(), service.CleanupOptions{
+			BatchSize:   2,
+			Parallelism: 4,
+			Report:      func(stats service.CleanupStats) { last = stats },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), revoked)
+		assert.Equal(t, int64(12), last.Deleted)
+	})
+}
+
+func TestRevokeRefreshTokensOfUsers(t *testing.T) {
+	rts := setupService(t)
+
+	t.Run("Should revoke the tokens of the given users only", func(t *testing.T) {
+		tokens := map[string]string{}
+		for _, userID := range []string{"1", "2", "3"} {
+			token, err := rts.CreateRefreshToken(context.Background(), userID)
+			require.NoError(t, err)
+			tokens[userID] = *token
+		}
+
+		require.NoError(t, rts.RevokeRefreshTokensOfUsers(context.Background(), []string{"1", "2"}, 2))
+
+		for userID, expected := range map[string]bool{"1": false, "2": false, "3": true} {
+			valid, err := rts.VerifyRefreshToken(context.Background(), userID, tokens[userID])
+			require.NoError(t, err)
+			assert.Equal(t, expected, valid, "user %s", userID)
+		}
+	})
+
+	t.Run("Should report the failed users", func(t *testing.T) {
+		err := rts.RevokeRefreshTokensOfUsers(context.Background(), []string{"1", ""}, 0)
+		require.Error(t, err)
+	})
+}
+
+func TestVerifyRefreshTokens(t *testing.T) {
+	rts := setupService(t)
+
+	t.Run("Should verify every token of the batch", func(t *testing.T) {
+		valid, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		other, err := rts.CreateRefreshToken(context.Background(), "456")
+		require.NoError(t, err)
+		revoked, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeRefreshToken(context.Background(), *revoked, "123"))
+		bound, err := rts.CreateBoundRefreshToken(context.Background(), "123", "bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2")
+		require.NoError(t, err)
+
+		results, err := rts.VerifyRefreshTokens(context.Background(), []service.RefreshTokenCredential{
+			{UserID: "123", Token: *valid},
+			{UserID: "456", Token: *other},
+			{UserID: "123", Token: *revoked},
+			{UserID: "123", Token: *bound},
+			{UserID: "123", Token: ""},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+
+		assert.True(t, results[*valid].Valid)
+		assert.True(t, results[*other].Valid)
+		assert.False(t, results[*revoked].Valid)
+		assert.NoError(t, results[*revoked].Err)
+		assert.False(t, results[*bound].Valid)
+		assert.False(t, results[""].Valid)
+		assert.ErrorContains(t, results[""].Err, "empty token")
+	})
+
+	t.Run("Should reject a token presented for another user", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		results, err := rts.VerifyRefreshTokens(context.Background(), []service.RefreshTokenCredential{
+			{UserID: "456", Token: *token},
+		})
+		require.NoError(t, err)
+		assert.False(t, results[*token].Valid)
+	})
+
+	t.Run("Should return an empty map for an empty batch", func(t *testing.T) {
+		results, err := rts.VerifyRefreshTokens(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
+func TestRefreshTokenStorage(t *testing.T) {
+	ttl := "1h"
+	plaintext := setupService(t)
+	transition, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenStorage: lib.RefreshTokenStorageTransition})
+	require.NoError(t, err)
+	hashed, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenStorage: lib.RefreshTokenStorageHashed})
+	require.NoError(t, err)
+	userID := "123"
+
+	t.Run("Should not store hashed tokens in clear", func(t *testing.T) {
+		token, err := hashed.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		keys, err := redisDB.Keys(context.Background(), "refresh:"+userID+":*").Result()
+		require.NoError(t, err)
+		for _, key := range keys {
+			assert.NotContains(t, key, *token)
+		}
+
+		valid, err := hashed.VerifyRefreshToken(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should accept both formats during the transition", func(t *testing.T) {
+		legacy, err := plaintext.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		token, err := transition.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		for _, tok := range []string{*legacy, *token} {
+			valid, err := transition.VerifyRefreshToken(context.Background(), userID, tok)
+			require.NoError(t, err)
+			assert.True(t, valid)
+		}
+
+		valid, err := hashed.VerifyRefreshToken(context.Background(), userID, *legacy)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should migrate plaintext tokens to hashed storage", func(t *testing.T) {
+		require.NoError(t, plaintext.RevokeAllRefreshTokens(context.Background()))
+		tokens := make([]string, 0, 5)
+		for i := 0; i < 5; i++ {
+			token, err := plaintext.CreateRefreshToken(context.Background(), userID)
+			require.NoError(t, err)
+			tokens = append(tokens, *token)
+		}
+
+		_, err := plaintext.MigrateRefreshTokenStorage(context.Background(), service.CleanupOptions{})
+		require.Error(t, err)
+
+		migrated, err := transition.MigrateRefreshTokenStorage(context.Background(), service.CleanupOptions{BatchSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), migrated)
+
+		migrated, err = transition.MigrateRefreshTokenStorage(context.Background(), service.CleanupOptions{})
+		require.NoError(t, err)
+		assert.Zero(t, migrated)
+
+		for _, token := range tokens {
+			valid, err := hashed.VerifyRefreshToken(context.Background(), userID, token)
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			exists, err := redisDB.Exists(context.Background(), "refresh:"+userID+":"+token).Result()
+			require.NoError(t, err)
+			assert.Zero(t, exists)
+		}
+
+		require.NoError(t, hashed.RevokeRefreshToken(context.Background(), tokens[0], userID))
+		valid, err := hashed.VerifyRefreshToken(context.Background(), userID, tokens[0])
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should fail with an unknown storage", func(t *testing.T) {
+		_, err := service.NewRefreshTokenService(t.Context(), redisDB, &lib.Config{RefreshTokenTTL: &ttl, RefreshTokenStorage: "encrypted"})
+		require.Error(t, err)
+		assert.Equal(t, "invalid refresh token storage: encrypted", err.Error())
+	})
+}
+
+func TestExportRevokedRefreshTokens(t *testing.T) {
+	rts := setupService(t)
+	secret := "revocation-list-secret"
+
+	t.Run("Should export the tokens revoked since the given time", func(t *testing.T) {
+		userID := "revocation-list-" + time.Now().Format("150405.000000")
+		old, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeRefreshToken(context.Background(), *old, userID))
+
+		time.Sleep(5 * time.Millisecond)
+		since := time.Now()
+		revoked, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		active, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeRefreshTokenWithReason(context.Background(), *revoked, userID, service.RevocationReasonAdmin))
+
+		list, err := rts.ExportRevokedRefreshTokens(context.Background(), since, secret)
+		require.NoError(t, err)
+
+		claim, err := service.ParseRevocationList(list, secret, config.Issuer, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, string(lib.TokenTypeRefresh), claim.TokenType)
+		assert.True(t, claim.IsRevoked(*revoked))
+		assert.False(t, claim.IsRevoked(*old))
+		assert.False(t, claim.IsRevoked(*active))
+
+		list, err = rts.ExportRevokedRefreshTokens(context.Background(), time.Time{}, secret)
+		require.NoError(t, err)
+		claim, err = service.ParseRevocationList(list, secret, config.Issuer, 0)
+		require.NoError(t, err)
+		assert.True(t, claim.IsRevoked(*old))
+	})
+
+	t.Run("Should reject a list signed with another secret", func(t *testing.T) {
+		list, err := rts.ExportRevokedRefreshTokens(context.Background(), time.Now(), secret)
+		require.NoError(t, err)
+
+		_, err = service.ParseRevocationList(list, "other-secret", config.Issuer, time.Minute)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+
+	t.Run("Should fail without secret", func(t *testing.T) {
+		_, err := rts.ExportRevokedRefreshTokens(context.Background(), time.Now(), "")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRegions simulates two regions with key prefixes on the test Redis.
+func setupRegions(t *testing.T) (*service.RefreshTokenService, *service.RefreshTokenService) {
+	eu, err := service.NewRefreshTokenService(t.Context(), redisDB, config, service.WithKeyPrefix("region-eu1"))
+	require.NoError(t, err)
+	us, err := service.NewRefreshTokenService(t.Context(), redisDB, config, service.WithKeyPrefix("region-us1"))
+	require.NoError(t, err)
+	return eu, us
+}
+
+func TestNewRegionalRefreshTokens(t *testing.T) {
+	eu, _ := setupRegions(t)
+
+	t.Run("Should fail without the local region", func(t *testing.T) {
+		_, err := service.NewRegionalRefreshTokens("us1", map[string]*service.RefreshTokenService{"eu1": eu})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "local region")
+	})
+
+	t.Run("Should fail with invalid region names", func(t *testing.T) {
+		_, err := service.NewRegionalRefreshTokens("eu.1", map[string]*service.RefreshTokenService{"eu.1": eu})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid region name")
+	})
+
+	t.Run("Should fail with nil service", func(t *testing.T) {
+		_, err := service.NewRegionalRefreshTokens("eu1", map[string]*service.RefreshTokenService{"eu1": eu, "us1": nil})
+		require.Error(t, err)
+	})
+}
+
+func TestRegionalRefreshTokens(t *testing.T) {
+	eu, us := setupRegions(t)
+	regions := map[string]*service.RefreshTokenService{"eu1": eu, "us1": us}
+	euTokens, err := service.NewRegionalRefreshTokens("eu1", regions)
+	require.NoError(t, err)
+	usTokens, err := service.NewRegionalRefreshTokens("us1", regions)
+	require.NoError(t, err)
+
+	t.Run("Should verify a token in every region", func(t *testing.T) {
+		token, err := euTokens.CreateRefreshToken(t.Context(), "123")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(*token, "eu1."))
+		assert.Equal(t, "eu1", usTokens.Region(*token))
+
+		for _, tokens := range []*service.RegionalRefreshTokens{euTokens, usTokens} {
+			valid, err := tokens.VerifyRefreshToken(t.Context(), "123", *token)
+			require.NoError(t, err)
+			assert.True(t, valid)
+		}
+	})
+
+	t.Run("Should revoke a token in its home region", func(t *testing.T) {
+		token, err := euTokens.CreateRefreshToken(t.Context(), "123")
+		require.NoError(t, err)
+
+		require.NoError(t, usTokens.RevokeRefreshToken(t.Context(), *token, "123"))
+
+		valid, err := euTokens.VerifyRefreshToken(t.Context(), "123", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should handle tokens without region locally", func(t *testing.T) {
+		token, err := us.CreateRefreshToken(t.Context(), "123")
+		require.NoError(t, err)
+		assert.Equal(t, "us1", usTokens.Region(*token))
+
+		valid, err := usTokens.VerifyRefreshToken(t.Context(), "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should reject tokens of unknown regions", func(t *testing.T) {
+		_, err := euTokens.VerifyRefreshToken(t.Context(), "123", "ap1.token")
+		require.ErrorIs(t, err, service.ErrUnknownRegion)
+	})
+
+	t.Run("Should revoke the tokens of a user in every region", func(t *testing.T) {
+		euToken, err := euTokens.CreateRefreshToken(t.Context(), "456")
+		require.NoError(t, err)
+		usToken, err := usTokens.CreateRefreshToken(t.Context(), "456")
+		require.NoError(t, err)
+
+		require.NoError(t, euTokens.RevokeAllUserRefreshTokens(t.Context(), "456"))
+
+		for _, token := range []string{*euToken, *usToken} {
+			valid, err := usTokens.VerifyRefreshToken(t.Context(), "456", token)
+			require.NoError(t, err)
+			assert.False(t, valid)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayMetrics_AccessToken(t *testing.T) {
+	epochs, err := service.NewTokenEpochService(t.Context(), redisDB)
+	require.NoError(t, err)
+
+	config := &lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"}
+	accessService := service.NewAccessTokenService(config)
+	accessService.SetEpochService(epochs)
+	stats := &lib.ReplayStats{}
+	accessService.SetReplayMetricsHook(stats)
+
+	user := modelAuth.NewUser("replay-user", "user@example.com")
+
+	t.Run("Should count expired tokens", func(t *testing.T) {
+		past := service.NewAccessTokenService(config, service.WithClock(func() time.Time { return time.Now().Add(-time.Hour) }))
+		token, err := past.CreateAccessToken(user)
+		require.NoError(t, err)
+
+		_, err = accessService.VerifyAccessToken(token)
+		require.Error(t, err)
+		assert.Equal(t, int64(1), stats.Count(lib.TokenTypeAccess, lib.ReplayReasonExpired))
+	})
+
+	t.Run("Should count tokens revoked by an epoch", func(t *testing.T) {
+		token, err := accessService.CreateAccessToken(user)
+		require.NoError(t, err)
+		_, err = epochs.BumpUserEpoch(context.Background(), user.ID)
+		require.NoError(t, err)
+
+		_, err = accessService.VerifyAccessToken(token)
+		assert.ErrorIs(t, err, service.ErrTokenEpochRevoked)
+		assert.Equal(t, int64(1), stats.Count(lib.TokenTypeAccess, lib.ReplayReasonRevoked))
+	})
+
+	t.Run("Should not count forged tokens", func(t *testing.T) {
+		_, err := accessService.VerifyAccessToken("not.a.token")
+		require.Error(t, err)
+		assert.Equal(t, map[lib.TokenType]map[lib.ReplayReason]int64{
+			lib.TokenTypeAccess: {lib.ReplayReasonExpired: 1, lib.ReplayReasonRevoked: 1},
+		}, stats.Snapshot())
+	})
+}
+
+func TestReplayMetrics_RefreshToken(t *testing.T) {
+	checksumConfig := config.Clone()
+	checksumConfig.TokenChecksum = true
+	checksumConfig.TokenChecksumSecret = "checksum-secret"
+
+	rts, err := service.NewRefreshTokenService(t.Context(), redisDB, checksumConfig)
+	require.NoError(t, err)
+	stats := &lib.ReplayStats{}
+	rts.SetReplayMetricsHook(stats)
+
+	t.Run("Should count revoked tokens", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(t.Context(), "replay-user")
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeRefreshTokenWithReason(t.Context(), *token, "replay-user", service.RevocationReasonRotation))
+
+		valid, err := rts.VerifyRefreshToken(t.Context(), "replay-user", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Equal(t, int64(1), stats.Count(lib.TokenTypeRefresh, lib.ReplayReasonRevoked))
+	})
+
+	t.Run("Should count issued tokens no longer stored as expired", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(t.Context(), "replay-user")
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeAllUserRefreshTokens(t.Context(), "replay-user"))
+
+		valid, err := rts.VerifyRefreshToken(t.Context(), "replay-user", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Equal(t, int64(1), stats.Count(lib.TokenTypeRefresh, lib.ReplayReasonExpired))
+	})
+
+	t.Run("Should not count unknown tokens without an HMAC checksum", func(t *testing.T) {
+		plain, err := service.NewRefreshTokenService(t.Context(), redisDB, config)
+		require.NoError(t, err)
+		plainStats := &lib.ReplayStats{}
+		plain.SetReplayMetricsHook(plainStats)
+
+		guessed, err := lib.GenerateRandomString(255)
+		require.NoError(t, err)
+		valid, err := plain.VerifyRefreshToken(t.Context(), "replay-user", guessed)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Empty(t, plainStats.Snapshot())
+	})
+}
+
+func TestReplayMetrics_PasswordResetToken(t *testing.T) {
+	prs := setupPasswordResetService(t)
+	stats := &lib.ReplayStats{}
+	prs.SetReplayMetricsHook(stats)
+
+	token, err := prs.CreatePasswordResetToken(t.Context(), "replay-user")
+	require.NoError(t, err)
+	require.NoError(t, prs.RevokePasswordResetTokenWithReason(t.Context(), "replay-user", *token, service.RevocationReasonAdmin))
+
+	valid, err := prs.VerifyPasswordResetToken(t.Context(), "replay-user", *token)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	info, err := prs.IdentifyPasswordResetToken(t.Context(), *token)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+	assert.Equal(t, int64(2), stats.Count(lib.TokenTypePasswordReset, lib.ReplayReasonRevoked))
+}
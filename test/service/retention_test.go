@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRetentionPolicy(t *testing.T) {
+	t.Run("Should fail with negative durations", func(t *testing.T) {
+		_, err := service.NewRefreshTokenService(t.Context(), redisDB, config, service.WithRetentionPolicy(service.RetentionPolicy{KeepFor: -time.Hour}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not be negative")
+	})
+
+	t.Run("Should fail when anonymizing after keep for", func(t *testing.T) {
+		_, err := service.NewPasswordResetService(t.Context(), redisDB, config, service.WithRetentionPolicy(service.RetentionPolicy{
+			KeepFor:        24 * time.Hour,
+			AnonymizeAfter: 48 * time.Hour,
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not exceed keep for")
+	})
+}
+
+func TestApplyRetentionPolicy(t *testing.T) {
+	rts, err := service.NewRefreshTokenService(t.Context(), redisDB, config, service.WithRetentionPolicy(service.RetentionPolicy{
+		KeepFor:        10 * 24 * time.Hour,
+		AnonymizeAfter: 24 * time.Hour,
+	}))
+	require.NoError(t, err)
+
+	userID := "retention-" + time.Now().Format("150405.000000")
+	token, err := rts.CreateRefreshToken(t.Context(), userID)
+	require.NoError(t, err)
+	require.NoError(t, rts.RevokeRefreshTokenWithReason(t.Context(), *token, userID, service.RevocationReasonAdmin))
+
+	// Records revoked 2 and 3 days ago, older than AnonymizeAfter
+	key := "revocation:rt:" + userID
+	for _, age := range []time.Duration{48 * time.Hour, 72 * time.Hour} {
+		data, err := json.Marshal(service.RevocationRecord{
+			TokenHash: "old",
+			Reason:    service.RevocationReasonUserLogout,
+			RevokedAt: time.Now().Add(-age).UTC(),
+		})
+		require.NoError(t, err)
+		require.NoError(t, redisDB.RPush(t.Context(), key, data).Err())
+	}
+
+	t.Run("Should hide records older than AnonymizeAfter", func(t *testing.T) {
+		records, err := rts.ListRevokedRefreshTokens(t.Context(), userID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, service.RevocationReasonAdmin, records[0].Reason)
+	})
+
+	t.Run("Should prune records older than AnonymizeAfter", func(t *testing.T) {
+		removed, err := rts.ApplyRetentionPolicy(t.Context(), service.CleanupOptions{})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, removed, int64(2))
+
+		length, err := redisDB.LLen(t.Context(), key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), length)
+	})
+
+	t.Run("Should keep the revoked hash in the feed", func(t *testing.T) {
+		list, err := rts.ExportRevokedRefreshTokens(t.Context(), time.Time{}, "revocation-list-secret")
+		require.NoError(t, err)
+		revoked, err := service.ParseRevocationList(list, "revocation-list-secret", config.Issuer, 0)
+		require.NoError(t, err)
+		assert.True(t, revoked.IsRevoked(*token))
+	})
+}
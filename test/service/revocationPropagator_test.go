@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRevocationPropagator(t *testing.T) {
+	t.Run("Should fail with nil db", func(t *testing.T) {
+		_, err := service.NewRevocationPropagator(nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestRevocationPropagator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two replicas sharing Redis, each with its own cache
+	replica := func() (*service.TokenEpochService, *service.AccessTokenService, *service.RevocationPropagator) {
+		epochs, err := service.NewTokenEpochService(ctx, redisDB, service.WithKeyPrefix("propagation"))
+		require.NoError(t, err)
+		epochs.EnableCache(time.Hour)
+
+		propagator, err := service.NewRevocationPropagator(redisDB, service.WithKeyPrefix("propagation"))
+		require.NoError(t, err)
+		epochs.SetRevocationPropagator(propagator)
+		go propagator.Run(ctx)
+
+		accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+		accessService.SetEpochService(epochs)
+		return epochs, accessService, propagator
+	}
+	epochsA, accessA, _ := replica()
+	_, accessB, propagatorB := replica()
+
+	var mu sync.Mutex
+	var received []service.RevocationEvent
+	propagatorB.OnRevocation(func(event service.RevocationEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event)
+	})
+	// Wait for the subscription of replica B
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	t.Run("Should reject the tokens of a user on every replica after a bump", func(t *testing.T) {
+		user := modelAuth.NewUser("propagation-user", "user@example.com")
+		token, err := accessA.CreateAccessToken(user)
+		require.NoError(t, err)
+
+		// Replica B caches the epochs
+		_, err = accessB.VerifyAccessToken(token)
+		require.NoError(t, err)
+
+		_, err = epochsA.BumpUserEpoch(ctx, user.ID)
+		require.NoError(t, err)
+
+		_, err = accessA.VerifyAccessToken(token)
+		assert.ErrorIs(t, err, service.ErrTokenEpochRevoked, "The local cache is evicted right away")
+		assert.Eventually(t, func() bool {
+			_, err := accessB.VerifyAccessToken(token)
+			return err != nil
+		}, time.Second, 5*time.Millisecond, "The cache of replica B must be evicted long before its TTL")
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, received, service.RevocationEvent{Kind: service.RevocationKindUserEpoch, UserID: user.ID})
+	})
+
+	t.Run("Should announce refresh token revocations", func(t *testing.T) {
+		refreshService, err := service.NewRefreshTokenService(ctx, redisDB, config, service.WithKeyPrefix("propagation"))
+		require.NoError(t, err)
+		propagator, err := service.NewRevocationPropagator(redisDB, service.WithKeyPrefix("propagation"))
+		require.NoError(t, err)
+		refreshService.SetRevocationPropagator(propagator)
+
+		token, err := refreshService.CreateRefreshToken(ctx, "propagation-user")
+		require.NoError(t, err)
+		require.NoError(t, refreshService.RevokeRefreshToken(ctx, *token, "propagation-user"))
+		require.NoError(t, refreshService.RevokeAllUserRefreshTokens(ctx, "propagation-user"))
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			var kinds []service.RevocationKind
+			for _, event := range received {
+				kinds = append(kinds, event.Kind)
+			}
+			return slices.Contains(kinds, service.RevocationKindRefreshToken) &&
+				slices.Contains(kinds, service.RevocationKindUserRefreshTokens)
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("Should refuse to publish resyncs", func(t *testing.T) {
+		err := propagatorB.Publish(ctx, service.RevocationEvent{Kind: service.RevocationKindResync})
+		assert.Error(t, err)
+	})
+}
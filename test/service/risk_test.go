@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticRiskEvaluator(verdict service.RiskVerdict, seen *[]service.RiskContext) service.RiskEvaluator {
+	return service.RiskEvaluatorFunc(func(_ context.Context, risk service.RiskContext) (service.RiskVerdict, error) {
+		if seen != nil {
+			*seen = append(*seen, risk)
+		}
+		return verdict, nil
+	})
+}
+
+func TestRefreshTokenRiskEvaluation(t *testing.T) {
+	rts := setupService(t)
+	t.Cleanup(func() { rts.SetRiskEvaluator(nil) })
+
+	t.Run("Should pass request metadata to the evaluator", func(t *testing.T) {
+		var seen []service.RiskContext
+		rts.SetRiskEvaluator(staticRiskEvaluator(service.RiskAllow, &seen))
+
+		ctx := lib.WithRequestMeta(context.Background(), lib.RequestMeta{IP: "203.0.113.7", Country: "FR"})
+		token, err := rts.CreateRefreshToken(ctx, "123")
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(ctx, "123", *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		require.Len(t, seen, 2)
+		assert.Equal(t, service.RiskOperationRefreshTokenCreate, seen[0].Operation)
+		assert.Equal(t, service.RiskOperationRefreshTokenVerify, seen[1].Operation)
+		assert.Equal(t, "123", seen[1].UserID)
+		assert.Equal(t, "FR", seen[1].Meta.Country)
+	})
+
+	t.Run("Should deny token creation", func(t *testing.T) {
+		rts.SetRiskEvaluator(staticRiskEvaluator(service.RiskDeny, nil))
+
+		_, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.ErrorIs(t, err, service.ErrRiskDenied)
+	})
+
+	t.Run("Should require step-up on verification", func(t *testing.T) {
+		rts.SetRiskEvaluator(nil)
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		rts.SetRiskEvaluator(staticRiskEvaluator(service.RiskStepUp, nil))
+		valid, err := rts.VerifyRefreshToken(context.Background(), "123", *token)
+		require.ErrorIs(t, err, service.ErrStepUpRequired)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should not evaluate unknown tokens", func(t *testing.T) {
+		var seen []service.RiskContext
+		rts.SetRiskEvaluator(staticRiskEvaluator(service.RiskDeny, &seen))
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), "123", "unknown-token")
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Empty(t, seen)
+	})
+
+	t.Run("Should fail closed on evaluator error", func(t *testing.T) {
+		rts.SetRiskEvaluator(service.RiskEvaluatorFunc(func(_ context.Context, _ service.RiskContext) (service.RiskVerdict, error) {
+			return service.RiskAllow, errors.New("scoring unavailable")
+		}))
+
+		_, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "scoring unavailable")
+	})
+}
+
+func TestPasswordResetRiskEvaluation(t *testing.T) {
+	prs := setupPasswordResetService(t)
+	t.Cleanup(func() { prs.SetRiskEvaluator(nil) })
+
+	t.Run("Should deny token creation", func(t *testing.T) {
+		prs.SetRiskEvaluator(staticRiskEvaluator(service.RiskDeny, nil))
+
+		_, err := prs.CreatePasswordResetToken(context.Background(), "123")
+		require.ErrorIs(t, err, service.ErrRiskDenied)
+	})
+
+	t.Run("Should require step-up on verification", func(t *testing.T) {
+		prs.SetRiskEvaluator(nil)
+		token, err := prs.CreatePasswordResetToken(context.Background(), "123")
+		require.NoError(t, err)
+
+		prs.SetRiskEvaluator(staticRiskEvaluator(service.RiskStepUp, nil))
+		valid, err := prs.VerifyPasswordResetToken(context.Background(), "123", *token)
+		require.ErrorIs(t, err, service.ErrStepUpRequired)
+		assert.False(t, valid)
+	})
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	prefix := service.WithKeyPrefix("schema-test")
+	key := "schema-test:schema_version"
+	t.Cleanup(func() { redisDB.Del(t.Context(), key) })
+
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.CheckSchemaVersion(t.Context(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should record the version on first start", func(t *testing.T) {
+		redisDB.Del(t.Context(), key)
+
+		version, err := service.CheckSchemaVersion(t.Context(), redisDB, prefix)
+		require.NoError(t, err)
+		assert.Equal(t, service.StorageSchemaVersion, version)
+
+		stored, err := redisDB.Get(t.Context(), key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(service.StorageSchemaVersion), stored)
+
+		ttl, err := redisDB.TTL(t.Context(), key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(-1), int64(ttl))
+	})
+
+	t.Run("Should accept the recorded version", func(t *testing.T) {
+		version, err := service.CheckSchemaVersion(t.Context(), redisDB, prefix)
+		require.NoError(t, err)
+		assert.Equal(t, service.StorageSchemaVersion, version)
+	})
+
+	t.Run("Should refuse a newer version", func(t *testing.T) {
+		require.NoError(t, redisDB.Set(t.Context(), key, service.StorageSchemaVersion+1, 0).Err())
+
+		version, err := service.CheckSchemaVersion(t.Context(), redisDB, prefix)
+		require.Error(t, err)
+		assert.Equal(t, service.StorageSchemaVersion+1, version)
+		assert.Contains(t, err.Error(), "upgrade github.com/bcetienne/tools-go-token")
+
+		stored, err := redisDB.Get(t.Context(), key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(service.StorageSchemaVersion+1), stored, "recorded version must not be overwritten")
+	})
+
+	t.Run("Should refuse an invalid version", func(t *testing.T) {
+		require.NoError(t, redisDB.Set(t.Context(), key, "v1", 0).Err())
+
+		_, err := service.CheckSchemaVersion(t.Context(), redisDB, prefix)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid storage schema version")
+	})
+}
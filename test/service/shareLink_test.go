@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShareLinkService(t *testing.T) *service.ShareLinkService {
+	sls, err := service.NewShareLinkService(t.Context(), redisDB, config)
+	require.NoError(t, err)
+	require.NoError(t, sls.RevokeAllShareLinks(t.Context()))
+	return sls
+}
+
+func TestNewShareLinkService(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewShareLinkService(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+}
+
+func TestShareLinkService(t *testing.T) {
+	sls := setupShareLinkService(t)
+
+	t.Run("Should accept the link maxUses times", func(t *testing.T) {
+		token, err := sls.CreateShareLink(context.Background(), "123", "file-42", 2)
+		require.NoError(t, err)
+
+		remaining, err := sls.RemainingUses(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Equal(t, 2, remaining)
+
+		use, err := sls.UseShareLink(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, use)
+		assert.Equal(t, "123", use.UserID)
+		assert.Equal(t, "file-42", use.Resource)
+		assert.Equal(t, 1, use.RemainingUses)
+
+		use, err = sls.UseShareLink(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, use)
+		assert.Equal(t, 0, use.RemainingUses)
+
+		use, err = sls.UseShareLink(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, use)
+
+		remaining, err = sls.RemainingUses(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Equal(t, 0, remaining)
+	})
+
+	t.Run("Should never exceed the limit under concurrent uses", func(t *testing.T) {
+		token, err := sls.CreateShareLink(context.Background(), "123", "file-42", 5)
+		require.NoError(t, err)
+
+		var accepted atomic.Int32
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				use, err := sls.UseShareLink(context.Background(), *token)
+				if err == nil && use != nil {
+					accepted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(5), accepted.Load())
+	})
+
+	t.Run("Should reject a revoked link", func(t *testing.T) {
+		token, err := sls.CreateShareLink(context.Background(), "123", "file-42", 5)
+		require.NoError(t, err)
+		require.NoError(t, sls.RevokeShareLink(context.Background(), *token))
+
+		use, err := sls.UseShareLink(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, use)
+	})
+
+	t.Run("Should fail with invalid arguments", func(t *testing.T) {
+		_, err := sls.CreateShareLink(context.Background(), "", "file-42", 5)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+
+		_, err = sls.CreateShareLink(context.Background(), "123", "file-42", 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max uses must be positive")
+	})
+}
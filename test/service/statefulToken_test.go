@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invitePayload struct {
+	TeamID string   `json:"team_id"`
+	Roles  []string `json:"roles"`
+}
+
+func TestNewStatefulToken(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewStatefulToken[invitePayload](context.Background(), nil, "invite", time.Hour)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail with empty name", func(t *testing.T) {
+		_, err := service.NewStatefulToken[invitePayload](context.Background(), redisDB, "", time.Hour)
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail with non positive ttl", func(t *testing.T) {
+		_, err := service.NewStatefulToken[invitePayload](context.Background(), redisDB, "invite", 0)
+		require.Error(t, err)
+	})
+}
+
+func TestStatefulToken(t *testing.T) {
+	invites, err := service.NewStatefulToken[invitePayload](context.Background(), redisDB, "invite", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, invites.RevokeAll(context.Background()))
+	payload := invitePayload{TeamID: "42", Roles: []string{"editor"}}
+
+	t.Run("Should return the typed payload on verification", func(t *testing.T) {
+		token, err := invites.Create(context.Background(), "123", payload)
+		require.NoError(t, err)
+		assert.Len(t, *token, 32)
+
+		record, err := invites.Verify(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, "123", record.UserID)
+		assert.Equal(t, payload, record.Payload)
+
+		// Verify does not consume the token
+		record, err = invites.Verify(context.Background(), *token)
+		require.NoError(t, err)
+		assert.NotNil(t, record)
+	})
+
+	t.Run("Should consume the token once", func(t *testing.T) {
+		token, err := invites.Create(context.Background(), "123", payload)
+		require.NoError(t, err)
+
+		record, err := invites.Consume(context.Background(), *token)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, payload, record.Payload)
+
+		record, err = invites.Consume(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Should not store the token in clear", func(t *testing.T) {
+		token, err := invites.Create(context.Background(), "123", payload)
+		require.NoError(t, err)
+
+		keys, err := redisDB.Keys(context.Background(), "invite:*").Result()
+		require.NoError(t, err)
+		for _, key := range keys {
+			assert.NotContains(t, key, *token)
+		}
+	})
+
+	t.Run("Should revoke tokens", func(t *testing.T) {
+		token, err := invites.Create(context.Background(), "123", payload)
+		require.NoError(t, err)
+		require.NoError(t, invites.Revoke(context.Background(), *token))
+
+		record, err := invites.Verify(context.Background(), *token)
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Should fail with invalid user ID", func(t *testing.T) {
+		_, err := invites.Create(context.Background(), "", payload)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid user id")
+	})
+
+	t.Run("Should return nil for unknown tokens", func(t *testing.T) {
+		record, err := invites.Verify(context.Background(), "unknown")
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+}
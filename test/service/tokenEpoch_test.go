@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTokenEpoch(t *testing.T) {
+	epochs, err := service.NewTokenEpochService(t.Context(), redisDB)
+	require.NoError(t, err)
+
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	accessService.SetEpochService(epochs)
+
+	user := modelAuth.NewUser("epoch-user", "user@example.com")
+	otherUser := modelAuth.NewUser("epoch-other-user", "other@example.com")
+
+	t.Run("Should reject the tokens of a user after a bump", func(t *testing.T) {
+		token, err := accessService.CreateAccessToken(user)
+		require.NoError(t, err)
+		otherToken, err := accessService.CreateAccessToken(otherUser)
+		require.NoError(t, err)
+
+		epoch, err := epochs.BumpUserEpoch(context.Background(), user.ID)
+		require.NoError(t, err)
+
+		_, err = accessService.VerifyAccessToken(token)
+		assert.ErrorIs(t, err, service.ErrTokenEpochRevoked)
+
+		// Other users are not affected
+		_, err = accessService.VerifyAccessToken(otherToken)
+		require.NoError(t, err)
+
+		// New tokens embed the new epoch
+		token, err = accessService.CreateAccessToken(user)
+		require.NoError(t, err)
+		claim, err := accessService.VerifyAccessToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, epoch, claim.UserEpoch)
+
+		current, err := epochs.UserEpoch(context.Background(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, epoch, current)
+	})
+
+	t.Run("Should fail with empty user id", func(t *testing.T) {
+		_, err := epochs.BumpUserEpoch(context.Background(), "")
+		require.Error(t, err)
+		assert.Equal(t, "invalid user id", err.Error())
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessTokenService_ExchangeToken(t *testing.T) {
+	ctx := context.Background()
+	var events []lib.AuditEvent
+	accessService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+	accessService.SetAuditLogger(lib.AuditLoggerFunc(func(ctx context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	userToken, err := accessService.CreateAccessTokenWithClaims(modelAuth.NewUser("123", "user@example.com"), map[string]any{"tenant": "acme"})
+	require.NoError(t, err)
+	gatewayToken, err := accessService.CreateAccessToken(modelAuth.NewUser("gateway", ""))
+	require.NoError(t, err)
+
+	t.Run("Should refuse actor tokens without an authorizer", func(t *testing.T) {
+		_, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: gatewayToken})
+		assert.ErrorIs(t, err, service.ErrActorNotAllowed)
+	})
+
+	accessService.SetActorAuthorizer(service.AllowActors("gateway", "billing-api"))
+
+	t.Run("Should refuse actors the authorizer does not allow", func(t *testing.T) {
+		otherToken, err := accessService.CreateAccessToken(modelAuth.NewUser("other-user", "other@example.com"))
+		require.NoError(t, err)
+
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: otherToken})
+		assert.ErrorIs(t, err, service.ErrActorNotAllowed)
+	})
+
+	t.Run("Should refuse actor tokens narrowed for another use", func(t *testing.T) {
+		narrowed, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: gatewayToken, Scopes: []string{"invoices:read"}})
+		require.NoError(t, err)
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: narrowed.AccessToken})
+		assert.ErrorIs(t, err, service.ErrActorNotAllowed)
+
+		apiToken, err := accessService.CreateAccessTokenForAudience(modelAuth.NewUser("gateway", ""), "billing-api")
+		require.NoError(t, err)
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: apiToken})
+		assert.ErrorIs(t, err, service.ErrActorNotAllowed)
+	})
+
+	t.Run("Should accept actor tokens granted delegation", func(t *testing.T) {
+		delegation, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: gatewayToken, Scopes: []string{service.DelegationScope}})
+		require.NoError(t, err)
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: delegation.AccessToken})
+		assert.NoError(t, err)
+
+		issuerToken, err := accessService.CreateAccessTokenForAudience(modelAuth.NewUser("gateway", ""), "test_auth.com")
+		require.NoError(t, err)
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: userToken, ActorToken: issuerToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should issue a narrowed delegation token", func(t *testing.T) {
+		response, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{
+			SubjectToken: userToken,
+			ActorToken:   gatewayToken,
+			Audience:     "billing-api",
+			Scopes:       []string{"invoices:read", "invoices:write"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, service.TokenTypeAccessToken, response.IssuedTokenType)
+		assert.Equal(t, "invoices:read invoices:write", response.Scope)
+		assert.InDelta(t, 900, response.ExpiresIn, 1)
+
+		claim, err := accessService.VerifyAccessToken(response.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "123", claim.Subject)
+		assert.Equal(t, []string{"billing-api"}, []string(claim.Audience))
+		assert.Equal(t, []string{"gateway"}, claim.Actor.Chain())
+		assert.Equal(t, "acme", claim.Custom["tenant"])
+		assert.True(t, claim.HasScope("invoices:read"))
+		assert.False(t, claim.HasScope("invoices:delete"))
+
+		require.NotEmpty(t, events)
+		event := events[len(events)-1]
+		assert.Equal(t, service.AuditEventAccessTokenExchanged, event.Type)
+		assert.Equal(t, "gateway", event.Details["actor_chain"])
+
+		t.Run("Should only narrow further", func(t *testing.T) {
+			billingToken, err := accessService.CreateAccessToken(modelAuth.NewUser("billing-api", ""))
+			require.NoError(t, err)
+
+			next, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{
+				SubjectToken: response.AccessToken,
+				ActorToken:   billingToken,
+				Scopes:       []string{"invoices:read"},
+			})
+			require.NoError(t, err)
+			claim, err := accessService.VerifyAccessToken(next.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"billing-api", "gateway"}, claim.Actor.Chain())
+			assert.Equal(t, []string{"billing-api"}, []string(claim.Audience))
+
+			_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: response.AccessToken, Audience: "reports-api"})
+			assert.ErrorIs(t, err, service.ErrInvalidTarget)
+
+			_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: response.AccessToken, Scopes: []string{"invoices:delete"}})
+			assert.ErrorIs(t, err, service.ErrInvalidScope)
+		})
+	})
+
+	t.Run("Should not outlive the subject token", func(t *testing.T) {
+		shortService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "1m"})
+		shortToken, err := shortService.CreateAccessToken(modelAuth.NewUser("123", "user@example.com"))
+		require.NoError(t, err)
+
+		response, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: shortToken})
+		require.NoError(t, err)
+		claim, err := accessService.VerifyAccessToken(response.AccessToken)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), claim.ExpiresAt.Time, 2*time.Second)
+		assert.Nil(t, claim.Actor)
+	})
+
+	t.Run("Should keep one-time use", func(t *testing.T) {
+		jtiStore, err := service.NewJTIStore(t.Context(), redisDB)
+		require.NoError(t, err)
+		oneTimeService := service.NewAccessTokenService(&lib.Config{Issuer: "test_auth.com", JWTSecret: "rand0mString_", JWTExpiry: "15m"})
+		oneTimeService.SetJTIStore(jtiStore)
+
+		oneTimeToken, err := oneTimeService.CreateOneTimeAccessToken(modelAuth.NewUser("123", "user@example.com"), time.Minute)
+		require.NoError(t, err)
+		response, err := oneTimeService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: oneTimeToken, Scopes: []string{"invoices:read"}})
+		require.NoError(t, err)
+
+		claim, err := oneTimeService.VerifyAccessTokenContext(ctx, response.AccessToken)
+		require.NoError(t, err)
+		assert.True(t, claim.OneTimeUse)
+		_, err = oneTimeService.VerifyAccessTokenContext(ctx, response.AccessToken)
+		assert.ErrorIs(t, err, service.ErrTokenReplayed)
+	})
+
+	t.Run("Should fail with an invalid subject token", func(t *testing.T) {
+		_, err := accessService.ExchangeToken(ctx, service.TokenExchangeRequest{SubjectToken: "not-a-jwt"})
+		assert.Error(t, err)
+
+		_, err = accessService.ExchangeToken(ctx, service.TokenExchangeRequest{})
+		assert.Error(t, err)
+	})
+}
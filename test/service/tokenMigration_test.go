@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenMigrator(t *testing.T) {
+	rts := setupService(t)
+	prs := setupPasswordResetService(t)
+	migrator := service.NewTokenMigrator(rts, prs)
+	userID := "123"
+
+	t.Run("Should export and import tokens", func(t *testing.T) {
+		refreshToken, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		resetToken, err := prs.CreateScopedPasswordResetToken(context.Background(), userID, service.PasswordResetScopeAdminForced)
+		require.NoError(t, err)
+
+		var export bytes.Buffer
+		count, err := migrator.ExportTokens(context.Background(), &export)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, 2, strings.Count(export.String(), "\n"))
+
+		require.NoError(t, rts.RevokeAllRefreshTokens(context.Background()))
+		require.NoError(t, prs.RevokeAllPasswordResetTokens(context.Background()))
+
+		count, err = migrator.ImportTokens(context.Background(), &export)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		valid, err := rts.VerifyRefreshToken(context.Background(), userID, *refreshToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		info, err := prs.IdentifyPasswordResetToken(context.Background(), *resetToken)
+		require.NoError(t, err)
+		assert.Equal(t, userID, info.UserID)
+		assert.Equal(t, service.PasswordResetScopeAdminForced, info.Scope)
+	})
+
+	t.Run("Should skip expired records", func(t *testing.T) {
+		line := `{"type":"rt","user_id":"123","token":"expired","value":"1","expires_at":"2020-01-01T00:00:00Z"}`
+		count, err := migrator.ImportTokens(context.Background(), strings.NewReader(line))
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Should report malformed lines", func(t *testing.T) {
+		_, err := migrator.ImportTokens(context.Background(), strings.NewReader("\n{not json}\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+	})
+
+	t.Run("Should reject unknown token types", func(t *testing.T) {
+		line := `{"type":"otp","user_id":"123","token":"123456","value":"1","expires_at":"2999-01-01T00:00:00Z"}`
+		_, err := migrator.ImportTokens(context.Background(), strings.NewReader(line))
+		assert.ErrorIs(t, err, service.ErrUnknownTokenType)
+	})
+}
+
+func TestTokenMigrator_IterateTokens(t *testing.T) {
+	rts := setupService(t)
+	prs := setupPasswordResetService(t)
+	migrator := service.NewTokenMigrator(rts, prs)
+
+	for i := 0; i < 25; i++ {
+		_, err := rts.CreateRefreshToken(context.Background(), "iterate")
+		require.NoError(t, err)
+	}
+	_, err := rts.CreateRefreshToken(context.Background(), "other")
+	require.NoError(t, err)
+	_, err = prs.CreatePasswordResetToken(context.Background(), "iterate")
+	require.NoError(t, err)
+
+	t.Run("Should visit every token page by page", func(t *testing.T) {
+		count, err := migrator.IterateTokens(context.Background(), service.TokenFilter{PageSize: 10}, func(record service.TokenExportRecord) error {
+			assert.NotEmpty(t, record.Token)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 27, count)
+	})
+
+	t.Run("Should filter by type and user", func(t *testing.T) {
+		count, err := migrator.IterateTokens(context.Background(), service.TokenFilter{
+			Types:  []lib.TokenType{lib.TokenTypeRefresh},
+			UserID: "iterate",
+		}, func(record service.TokenExportRecord) error {
+			assert.Equal(t, lib.TokenTypeRefresh, record.Type)
+			assert.Equal(t, "iterate", record.UserID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 25, count)
+
+		count, err = migrator.IterateTokens(context.Background(), service.TokenFilter{
+			Types:  []lib.TokenType{lib.TokenTypePasswordReset},
+			UserID: "iterate",
+		}, func(service.TokenExportRecord) error { return nil })
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Should stop on callback error", func(t *testing.T) {
+		stop := errors.New("stop")
+		count, err := migrator.IterateTokens(context.Background(), service.TokenFilter{}, func(service.TokenExportRecord) error {
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Should fail with nil callback", func(t *testing.T) {
+		_, err := migrator.IterateTokens(context.Background(), service.TokenFilter{}, nil)
+		require.Error(t, err)
+	})
+}
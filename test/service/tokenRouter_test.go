@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRouter(t *testing.T) {
+	ttl := "1h"
+	prefixedConfig := &lib.Config{RefreshTokenTTL: &ttl, PasswordResetTTL: &ttl, TokenPrefix: true, TokenChecksum: true}
+	rts, err := service.NewRefreshTokenService(t.Context(), redisDB, prefixedConfig)
+	require.NoError(t, err)
+	prs, err := service.NewPasswordResetService(t.Context(), redisDB, prefixedConfig)
+	require.NoError(t, err)
+	router := service.NewTokenRouter(rts, prs)
+	userID := "123"
+
+	t.Run("Should route refresh tokens", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(*token, "rt_v1_"))
+
+		tokenType, valid, err := router.Verify(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.Equal(t, lib.TokenTypeRefresh, tokenType)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should route password reset tokens", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(*token, "prt_v1_"))
+		assert.Len(t, *token, len("prt_v1_")+32+lib.TokenChecksumLength)
+
+		tokenType, valid, err := router.Verify(context.Background(), userID, *token)
+		require.NoError(t, err)
+		assert.Equal(t, lib.TokenTypePasswordReset, tokenType)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should fail with unknown prefixes", func(t *testing.T) {
+		_, _, err := router.Verify(context.Background(), userID, "unprefixed")
+		assert.ErrorIs(t, err, service.ErrUnknownTokenType)
+
+		_, _, err = router.Verify(context.Background(), userID, "xyz_v1_token")
+		assert.ErrorIs(t, err, service.ErrUnknownTokenType)
+
+		_, _, err = router.Verify(context.Background(), userID, "rt_v2_token")
+		assert.ErrorIs(t, err, service.ErrUnknownTokenType)
+	})
+
+	t.Run("Should fail when the service is not routed", func(t *testing.T) {
+		token, err := prs.CreatePasswordResetToken(context.Background(), userID)
+		require.NoError(t, err)
+
+		_, _, err = service.NewTokenRouter(rts, nil).Verify(context.Background(), userID, *token)
+		assert.ErrorIs(t, err, service.ErrUnknownTokenType)
+	})
+}
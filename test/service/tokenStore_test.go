@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewMemoryTokenStore()
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("Should put, get, find and delete tokens", func(t *testing.T) {
+		token := service.StoredToken{UserID: "123", Hash: "h1", Value: "v1", ExpiresAt: expiresAt}
+		require.NoError(t, store.Put(ctx, token))
+
+		stored, found, err := store.Get(ctx, "123", "h1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "v1", stored.Value)
+
+		stored, found, err = store.Find(ctx, "h1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "123", stored.UserID)
+
+		_, found, err = store.Get(ctx, "456", "h1")
+		require.NoError(t, err)
+		assert.False(t, found, "tokens are per user")
+
+		deleted, err := store.Delete(ctx, "123", "h1", "missing")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, found, err = store.Find(ctx, "h1")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Should list and scan tokens", func(t *testing.T) {
+		for i := range 3 {
+			require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "list", Hash: fmt.Sprintf("l%d", i), Value: "v", ExpiresAt: expiresAt}))
+		}
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "other", Hash: "o", Value: "v", ExpiresAt: expiresAt}))
+
+		tokens, err := store.List(ctx, "list")
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+
+		var batches, scanned int
+		require.NoError(t, store.Scan(ctx, 2, func(tokens []service.StoredToken) error {
+			batches++
+			scanned += len(tokens)
+			return nil
+		}))
+		assert.Equal(t, 2, batches)
+		assert.Equal(t, 4, scanned)
+	})
+
+	t.Run("Should not return expired tokens", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, service.StoredToken{UserID: "short", Hash: "s", Value: "v", ExpiresAt: time.Now().Add(50 * time.Millisecond)}))
+		time.Sleep(100 * time.Millisecond)
+
+		_, found, err := store.Get(ctx, "short", "s")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		tokens, err := store.List(ctx, "short")
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+	})
+
+	t.Run("Should refuse incomplete or expired tokens", func(t *testing.T) {
+		require.Error(t, store.Put(ctx, service.StoredToken{Hash: "h", ExpiresAt: expiresAt}))
+		require.Error(t, store.Put(ctx, service.StoredToken{UserID: "123", Hash: "h", ExpiresAt: time.Now().Add(-time.Second)}))
+	})
+}
+
+func TestNewServicesWithStore(t *testing.T) {
+	t.Run("Should fail with nil store", func(t *testing.T) {
+		_, err := service.NewRefreshTokenServiceWithStore(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "store is nil")
+
+		_, err = service.NewPasswordResetServiceWithStore(context.Background(), nil, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "store is nil")
+	})
+
+	t.Run("Should refuse a password reset quota", func(t *testing.T) {
+		ttl := "1h"
+		_, err := service.NewPasswordResetServiceWithStore(context.Background(), service.NewMemoryTokenStore(), &lib.Config{PasswordResetTTL: &ttl, PasswordResetQuota: 3})
+		require.ErrorIs(t, err, service.ErrTokenStoreUnsupported)
+	})
+}
+
+func TestRefreshTokenServiceWithStore(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewMemoryTokenStore()
+	rts, err := service.NewRefreshTokenServiceWithStore(ctx, store, config)
+	require.NoError(t, err)
+	userID := "123"
+
+	t.Run("Should create, verify and revoke tokens", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+
+		valid, err := rts.VerifyRefreshToken(ctx, userID, *token)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = rts.VerifyRefreshToken(ctx, "456", *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		sum := sha256.Sum256([]byte(*token))
+		stored, found, err := store.Get(ctx, userID, hex.EncodeToString(sum[:]))
+		require.NoError(t, err)
+		assert.True(t, found, "tokens are stored hashed")
+		assert.NotContains(t, stored.Value, *token)
+
+		require.NoError(t, rts.RevokeRefreshToken(ctx, *token, userID))
+		valid, err = rts.VerifyRefreshToken(ctx, userID, *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		records, err := rts.ListRevokedRefreshTokens(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, records, "no revocation log without Redis")
+	})
+
+	t.Run("Should verify tokens in batch", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+		other, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+
+		results, err := rts.VerifyRefreshTokens(ctx, []service.RefreshTokenCredential{
+			{UserID: userID, Token: *token},
+			{UserID: "456", Token: *other},
+		})
+		require.NoError(t, err)
+		assert.True(t, results[*token].Valid)
+		assert.False(t, results[*other].Valid)
+	})
+
+	t.Run("Should revoke all tokens of a user", func(t *testing.T) {
+		first, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+		other, err := rts.CreateRefreshToken(ctx, "456")
+		require.NoError(t, err)
+
+		require.NoError(t, rts.RevokeAllUserRefreshTokens(ctx, userID))
+
+		valid, err := rts.VerifyRefreshToken(ctx, userID, *first)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		valid, err = rts.VerifyRefreshToken(ctx, "456", *other)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should revoke all tokens in batches", func(t *testing.T) {
+		for i := range 3 {
+			_, err := rts.CreateRefreshToken(ctx, fmt.Sprintf("batch-%d", i))
+			require.NoError(t, err)
+		}
+
+		revoked, err := rts.RevokeAllRefreshTokensInBatches(ctx, service.CleanupOptions{BatchSize: 2})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, revoked, int64(3))
+
+		var remaining int
+		require.NoError(t, store.Scan(ctx, 10, func(tokens []service.StoredToken) error {
+			remaining += len(tokens)
+			return nil
+		}))
+		assert.Zero(t, remaining)
+	})
+
+	t.Run("Should handle leaked tokens", func(t *testing.T) {
+		token, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+		responder, err := service.NewLeakedTokenResponder(rts, nil, "leak-secret")
+		require.NoError(t, err)
+
+		result, err := responder.HandleLeak(ctx, service.LeakedTokenReport{Token: *token, Source: "github"})
+		require.NoError(t, err)
+		assert.True(t, result.Revoked)
+		assert.Equal(t, userID, result.UserID)
+	})
+
+	t.Run("Should export and erase user data", func(t *testing.T) {
+		_, err := rts.CreateRefreshToken(ctx, "gdpr")
+		require.NoError(t, err)
+		uds := service.NewUserDataService(rts, nil, nil)
+
+		data, err := uds.ExportUserAuthData(ctx, "gdpr")
+		require.NoError(t, err)
+		assert.Len(t, data.RefreshTokens, 1)
+
+		require.NoError(t, uds.EraseUser(ctx, "gdpr"))
+		data, err = uds.ExportUserAuthData(ctx, "gdpr")
+		require.NoError(t, err)
+		assert.Empty(t, data.RefreshTokens)
+	})
+
+	t.Run("Should refuse the features relying on Redis", func(t *testing.T) {
+		_, err := rts.EnableIssuedTokenFilter(service.IssuedTokenFilterOptions{})
+		require.ErrorIs(t, err, service.ErrTokenStoreUnsupported)
+
+		_, err = rts.MigrateRefreshTokenStorage(ctx, service.CleanupOptions{})
+		require.ErrorIs(t, err, service.ErrTokenStoreUnsupported)
+	})
+}
+
+func TestPasswordResetServiceWithStore(t *testing.T) {
+	ctx := context.Background()
+	ttl := "10m"
+	newService := func(t *testing.T, policy lib.PasswordResetPolicy) *service.PasswordResetService {
+		prs, err := service.NewPasswordResetServiceWithStore(ctx, service.NewMemoryTokenStore(), &lib.Config{PasswordResetTTL: &ttl, PasswordResetPolicy: policy})
+		require.NoError(t, err)
+		return prs
+	}
+	userID := "123"
+
+	t.Run("Should keep a single token per user", func(t *testing.T) {
+		prs := newService(t, "")
+		first, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		assert.NotEqual(t, *first, *second)
+
+		valid, err := prs.VerifyPasswordResetToken(ctx, userID, *first)
+		require.NoError(t, err)
+		assert.False(t, valid, "the replaced token stops working")
+		valid, err = prs.VerifyPasswordResetToken(ctx, userID, *second)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		info, err := prs.IdentifyPasswordResetToken(ctx, *second)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, userID, info.UserID)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), info.ExpiresAt, time.Second)
+
+		info, err = prs.IdentifyPasswordResetToken(ctx, *first)
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	t.Run("Should reuse the existing token", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyReuse)
+		first, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, *first, *second)
+	})
+
+	t.Run("Should extend the existing token", func(t *testing.T) {
+		prs := newService(t, lib.PasswordResetPolicyExtend)
+		first, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, *first, *second)
+
+		valid, err := prs.VerifyPasswordResetToken(ctx, userID, *second)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Should revoke a matching token only", func(t *testing.T) {
+		prs := newService(t, "")
+		first, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+		second, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+
+		err = prs.RevokePasswordResetToken(ctx, userID, *first)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "token mismatch")
+
+		require.NoError(t, prs.RevokePasswordResetToken(ctx, userID, *second))
+		err = prs.RevokePasswordResetToken(ctx, userID, *second)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "token not found")
+	})
+
+	t.Run("Should revoke all tokens", func(t *testing.T) {
+		prs := newService(t, "")
+		token, err := prs.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+
+		revoked, err := prs.RevokeAllPasswordResetTokensInBatches(ctx, service.CleanupOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revoked)
+
+		valid, err := prs.VerifyPasswordResetToken(ctx, userID, *token)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Should refuse short codes", func(t *testing.T) {
+		prs := newService(t, "")
+		_, err := prs.CreatePasswordResetTokenWithCode(ctx, userID)
+		require.ErrorIs(t, err, service.ErrTokenStoreUnsupported)
+	})
+
+	t.Run("Should migrate tokens from Redis to a store", func(t *testing.T) {
+		rts := setupService(t)
+		source := setupPasswordResetService(t)
+		refreshToken, err := rts.CreateRefreshToken(ctx, userID)
+		require.NoError(t, err)
+		resetToken, err := source.CreatePasswordResetToken(ctx, userID)
+		require.NoError(t, err)
+
+		var export bytes.Buffer
+		_, err = service.NewTokenMigrator(rts, source).ExportTokens(ctx, &export)
+		require.NoError(t, err)
+
+		storeRTS, err := service.NewRefreshTokenServiceWithStore(ctx, service.NewMemoryTokenStore(), config)
+		require.NoError(t, err)
+		storePRS := newService(t, "")
+		count, err := service.NewTokenMigrator(storeRTS, storePRS).ImportTokens(ctx, &export)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		valid, err := storeRTS.VerifyRefreshToken(ctx, userID, *refreshToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = storePRS.VerifyPasswordResetToken(ctx, userID, *resetToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnverified(t *testing.T) {
+	config := &lib.Config{Issuer: "tenant-a", JWTSecret: "rand0mString_", JWTExpiry: "15m"}
+	accessService := service.NewAccessTokenService(config)
+	user := modelAuth.NewUser("123", "user@example.com")
+
+	t.Run("Should decode the header and claims", func(t *testing.T) {
+		token, err := accessService.CreateAccessTokenWithClaims(user, map[string]any{"tenant": "acme"})
+		require.NoError(t, err)
+
+		unverified, err := service.ParseUnverified(token)
+		require.NoError(t, err)
+		assert.Equal(t, "HS256", unverified.Header["alg"])
+		assert.Equal(t, "tenant-a", unverified.Claim.Issuer)
+		assert.Equal(t, "123", unverified.Claim.Subject)
+		assert.Equal(t, "acme", unverified.Claim.Custom["tenant"])
+
+		data, err := json.Marshal(unverified.Claim)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"tenant":"acme"`)
+	})
+
+	t.Run("Should decode tokens that do not verify", func(t *testing.T) {
+		past := service.NewAccessTokenService(&lib.Config{Issuer: "tenant-b", JWTSecret: "other-secret", JWTExpiry: "15m"},
+			service.WithClock(func() time.Time { return time.Now().Add(-time.Hour) }))
+		token, err := past.CreateAccessToken(user)
+		require.NoError(t, err)
+
+		_, err = accessService.VerifyAccessToken(token)
+		require.Error(t, err)
+
+		unverified, err := service.ParseUnverified(token)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-b", unverified.Claim.Issuer)
+	})
+
+	t.Run("Should fail on malformed tokens", func(t *testing.T) {
+		_, err := service.ParseUnverified("not-a-token")
+		assert.ErrorIs(t, err, jwt.ErrTokenMalformed)
+	})
+}
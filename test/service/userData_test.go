@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserDataService(t *testing.T) {
+	rts := setupService(t)
+	prs := setupPasswordResetService(t)
+	otps := setupOTPService(t)
+
+	var events []lib.AuditEvent
+	userData := service.NewUserDataService(rts, prs, otps, service.WithLogger(lib.AuditLoggerFunc(func(_ context.Context, event lib.AuditEvent) {
+		events = append(events, event)
+	})))
+
+	userID := "gdpr-" + time.Now().Format("150405.000000")
+	otherID := userID + "-other"
+
+	refreshToken, err := rts.CreateRefreshToken(t.Context(), userID)
+	require.NoError(t, err)
+	_, err = rts.CreateScopedRefreshToken(t.Context(), userID, []string{"billing-api"})
+	require.NoError(t, err)
+	revokedToken, err := rts.CreateRefreshToken(t.Context(), userID)
+	require.NoError(t, err)
+	require.NoError(t, rts.RevokeRefreshToken(t.Context(), *revokedToken, userID))
+	_, err = rts.CreateCanaryRefreshToken(t.Context(), userID, "backup", time.Hour)
+	require.NoError(t, err)
+	otherToken, err := rts.CreateRefreshToken(t.Context(), otherID)
+	require.NoError(t, err)
+
+	resetToken, err := prs.CreateScopedPasswordResetToken(t.Context(), userID, service.PasswordResetScopeAdminForced)
+	require.NoError(t, err)
+
+	_, err = otps.CreateOTP(t.Context(), userID)
+	require.NoError(t, err)
+	_, err = otps.VerifyOTP(t.Context(), userID, "000000")
+	require.NoError(t, err)
+
+	t.Run("Should fail with empty user ID", func(t *testing.T) {
+		_, err := userData.ExportUserAuthData(t.Context(), "")
+		require.ErrorIs(t, err, service.ErrInvalidUserID)
+		require.ErrorIs(t, userData.EraseUser(t.Context(), ""), service.ErrInvalidUserID)
+	})
+
+	t.Run("Should export metadata without token values", func(t *testing.T) {
+		data, err := userData.ExportUserAuthData(t.Context(), userID)
+		require.NoError(t, err)
+
+		assert.Equal(t, userID, data.UserID)
+		require.Len(t, data.RefreshTokens, 2, "revoked and canary tokens are not exported")
+		fingerprints := []string{data.RefreshTokens[0].Fingerprint, data.RefreshTokens[1].Fingerprint}
+		assert.Contains(t, fingerprints, lib.TokenFingerprint(*refreshToken))
+		for _, token := range data.RefreshTokens {
+			assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, 2*time.Hour)
+		}
+
+		require.Len(t, data.RefreshTokenRevocations, 1)
+		require.NotNil(t, data.PasswordReset)
+		assert.Equal(t, lib.TokenFingerprint(*resetToken), data.PasswordReset.Fingerprint)
+		assert.Equal(t, service.PasswordResetScopeAdminForced, data.PasswordReset.Scope)
+		require.NotNil(t, data.OTP)
+		assert.NotNil(t, data.OTP.ExpiresAt)
+
+		encoded, err := json.Marshal(data)
+		require.NoError(t, err)
+		assert.NotContains(t, string(encoded), *refreshToken)
+		assert.NotContains(t, string(encoded), *resetToken)
+	})
+
+	t.Run("Should erase the user data only", func(t *testing.T) {
+		require.NoError(t, userData.EraseUser(t.Context(), userID))
+
+		data, err := userData.ExportUserAuthData(t.Context(), userID)
+		require.NoError(t, err)
+		assert.Empty(t, data.RefreshTokens)
+		assert.Empty(t, data.RefreshTokenRevocations)
+		assert.Nil(t, data.PasswordReset)
+		assert.Nil(t, data.OTP)
+
+		info, err := prs.IdentifyPasswordResetToken(t.Context(), *resetToken)
+		require.NoError(t, err)
+		assert.Nil(t, info)
+
+		valid, err := rts.VerifyRefreshToken(t.Context(), otherID, *otherToken)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		require.NotEmpty(t, events)
+		event := events[len(events)-1]
+		assert.Equal(t, service.AuditEventUserDataErased, event.Type)
+		assert.Equal(t, userID, event.UserID)
+		assert.Equal(t, "true", event.Details["complete"])
+	})
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	"github.com/bcetienne/tools-go-token/v4/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupWebhookOutbox returns an outbox delivering to handler, with an empty outbox.
+func setupWebhookOutbox(t *testing.T, handler http.HandlerFunc) *service.WebhookOutbox {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	require.NoError(t, redisDB.Del(t.Context(), "webhook_outbox", "webhook_outbox:dead").Err())
+
+	outbox, err := service.NewWebhookOutbox(t.Context(), redisDB, []service.WebhookEndpoint{{URL: server.URL, Secret: "secret"}})
+	require.NoError(t, err)
+	return outbox
+}
+
+func TestNewWebhookOutbox(t *testing.T) {
+	t.Run("Should fail with nil database", func(t *testing.T) {
+		_, err := service.NewWebhookOutbox(context.Background(), nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db is nil")
+	})
+
+	t.Run("Should fail without secret", func(t *testing.T) {
+		_, err := service.NewWebhookOutbox(context.Background(), redisDB, []service.WebhookEndpoint{{URL: "https://example.com"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "webhook secret is empty")
+	})
+}
+
+func TestWebhookOutbox(t *testing.T) {
+	t.Run("Should deliver signed revocation events", func(t *testing.T) {
+		var mu sync.Mutex
+		var received []lib.AuditEvent
+		outbox := setupWebhookOutbox(t, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, "sha256="+service.SignWebhookPayload("secret", body), r.Header.Get("X-Webhook-Signature"))
+			assert.NotEmpty(t, r.Header.Get("X-Webhook-ID"))
+
+			var event lib.AuditEvent
+			assert.NoError(t, json.Unmarshal(body, &event))
+			mu.Lock()
+			received = append(received, event)
+			mu.Unlock()
+		})
+
+		rts := setupService(t)
+		rts.SetAuditLogger(outbox)
+		token, err := rts.CreateRefreshToken(context.Background(), "123")
+		require.NoError(t, err)
+		require.NoError(t, rts.RevokeRefreshTokenWithReason(context.Background(), *token, "123", service.RevocationReasonAdmin))
+
+		pending, err := outbox.Pending(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), pending)
+
+		delivered, err := outbox.ProcessDue(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, received, 1)
+		assert.Equal(t, service.AuditEventRefreshTokenRevoked, received[0].Type)
+		assert.Equal(t, "ADMIN", received[0].Details["reason"])
+
+		pending, err = outbox.Pending(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, pending)
+	})
+
+	t.Run("Should ignore events the endpoint is not subscribed to", func(t *testing.T) {
+		outbox := setupWebhookOutbox(t, func(w http.ResponseWriter, r *http.Request) {})
+
+		require.NoError(t, outbox.Enqueue(context.Background(), lib.AuditEvent{Type: service.AuditEventPasswordResetCreated}))
+
+		pending, err := outbox.Pending(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, pending)
+	})
+
+	t.Run("Should retry then dead-letter failed deliveries", func(t *testing.T) {
+		var calls atomic.Int32
+		outbox := setupWebhookOutbox(t, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		outbox.SetRetryPolicy(2, time.Millisecond)
+
+		require.NoError(t, outbox.Enqueue(context.Background(), lib.AuditEvent{Type: service.AuditEventLoginLocked, UserID: "123"}))
+
+		delivered, err := outbox.ProcessDue(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+
+		time.Sleep(10 * time.Millisecond)
+		_, err = outbox.ProcessDue(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+
+		pending, err := outbox.Pending(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, pending)
+		dead, err := outbox.DeadLetters(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), dead)
+	})
+}
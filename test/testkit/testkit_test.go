@@ -0,0 +1,188 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/bcetienne/tools-go-token/v4/testkit"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := testkit.NewClock(start)
+
+	assert.Equal(t, start, clock.Now())
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clock.Advance(time.Second)
+			_ = clock.Now()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, start.Add(10*time.Second), clock.Now())
+}
+
+func TestNewOTPGenerator(t *testing.T) {
+	first, second := testkit.NewOTPGenerator(42), testkit.NewOTPGenerator(42)
+	for range 20 {
+		a, err := first()
+		require.NoError(t, err)
+		b, err := second()
+		require.NoError(t, err)
+		assert.Equal(t, a, b)
+		assert.Regexp(t, `^\d{6}$`, a)
+	}
+
+	other := testkit.NewOTPGenerator(7)
+	a, _ := testkit.NewOTPGenerator(42)()
+	b, _ := other()
+	assert.NotEqual(t, a, b)
+}
+
+func TestOTPSequence(t *testing.T) {
+	generate := testkit.OTPSequence("000123", "999999")
+
+	code, err := generate()
+	require.NoError(t, err)
+	assert.Equal(t, "000123", code)
+	code, err = generate()
+	require.NoError(t, err)
+	assert.Equal(t, "999999", code)
+
+	_, err = generate()
+	require.Error(t, err)
+}
+
+func TestAuditRecorder(t *testing.T) {
+	recorder := testkit.NewAuditRecorder()
+	recorder.LogAuditEvent(context.Background(), lib.AuditEvent{Type: "a", UserID: "1"})
+	recorder.LogAuditEvent(context.Background(), lib.AuditEvent{Type: "b", UserID: "2"})
+	recorder.LogAuditEvent(context.Background(), lib.AuditEvent{Type: "a", UserID: "3"})
+
+	assert.Len(t, recorder.Events(), 3)
+	events := recorder.EventsOfType("a")
+	require.Len(t, events, 2)
+	assert.Equal(t, "3", events[1].UserID)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Events())
+}
+
+func TestAccessTokens(t *testing.T) {
+	config := &lib.Config{Issuer: "testkit", JWTSecret: "secret", JWTExpiry: "15m"}
+	user := &modelAuth.User{ID: "123", Email: "user@example.com"}
+	accessService := service.NewAccessTokenService(config)
+
+	t.Run("Should mint an expired token", func(t *testing.T) {
+		token, err := testkit.ExpiredAccessToken(config, user)
+		require.NoError(t, err)
+
+		_, err = accessService.VerifyAccessToken(token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("Should mint a token close to expiry", func(t *testing.T) {
+		token, err := testkit.NearExpiryAccessToken(config, user, time.Minute)
+		require.NoError(t, err)
+
+		claim, err := accessService.VerifyAccessToken(token)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), claim.ExpiresAt.Time, 2*time.Second)
+	})
+
+	t.Run("Should fail with an invalid expiry", func(t *testing.T) {
+		_, err := testkit.ExpiredAccessToken(&lib.Config{JWTExpiry: "soon"}, user)
+		require.Error(t, err)
+	})
+}
+
+func TestFaultInjector(t *testing.T) {
+	t.Run("Should inject nothing by default", func(t *testing.T) {
+		store := testkit.NewFaultInjector().WrapKVStore(service.NewMemoryKVStore())
+		require.NoError(t, store.SetEX(t.Context(), "key", "value", time.Minute))
+
+		value, found, err := store.Get(t.Context(), "key")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "value", value)
+	})
+
+	t.Run("Should fail the selected operations", func(t *testing.T) {
+		faults := testkit.NewFaultInjector(testkit.WithErrorRate(1), testkit.WithFaultCommands("setex"))
+		store := faults.WrapKVStore(service.NewMemoryKVStore())
+
+		assert.ErrorIs(t, store.SetEX(t.Context(), "key", "value", time.Minute), testkit.ErrInjectedFault)
+		_, found, err := store.Get(t.Context(), "key")
+		require.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, testkit.FaultStats{Operations: 1, Failed: 1}, faults.Stats())
+
+		faults.Configure()
+		require.NoError(t, store.SetEX(t.Context(), "key", "value", time.Minute))
+	})
+
+	t.Run("Should fail the same operations with the same seed", func(t *testing.T) {
+		failures := func() []bool {
+			store := testkit.NewFaultInjector(testkit.WithErrorRate(0.5), testkit.WithFaultSeed(42)).WrapKVStore(service.NewMemoryKVStore())
+			var failed []bool
+			for range 50 {
+				_, err := store.Incr(t.Context(), "counter", time.Minute)
+				failed = append(failed, err != nil)
+			}
+			return failed
+		}
+		first := failures()
+		assert.Equal(t, first, failures())
+		assert.Contains(t, first, true)
+		assert.Contains(t, first, false)
+	})
+
+	t.Run("Should delay operations until the context is done", func(t *testing.T) {
+		custom := errors.New("connection reset")
+		store := testkit.NewFaultInjector(testkit.WithLatency(20*time.Millisecond, 0), testkit.WithErrorRate(1), testkit.WithFaultError(custom)).
+			WrapKVStore(service.NewMemoryKVStore())
+
+		start := time.Now()
+		_, err := store.TTL(t.Context(), "key")
+		assert.ErrorIs(t, err, custom)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+		defer cancel()
+		_, err = store.Del(ctx, "key")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Should fail Redis commands through the hook", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+		defer client.Close()
+		client.AddHook(testkit.NewFaultInjector(testkit.WithErrorRate(1)).RedisHook())
+
+		assert.ErrorIs(t, client.Get(t.Context(), "key").Err(), testkit.ErrInjectedFault)
+
+		pipe := client.TxPipeline()
+		incr := pipe.Incr(t.Context(), "counter")
+		_, err := pipe.Exec(t.Context())
+		assert.ErrorIs(t, err, testkit.ErrInjectedFault)
+		assert.ErrorIs(t, incr.Err(), testkit.ErrInjectedFault)
+	})
+}
@@ -1,7 +1,12 @@
 package validation
 
 import (
+	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bcetienne/tools-go-token/v4/validation"
 )
@@ -41,3 +46,146 @@ func Test_Validation_Email_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+type staticMXResolver struct {
+	records []*net.MX
+	err     error
+	addrs   []string
+}
+
+func (r staticMXResolver) LookupMX(_ context.Context, _ string) ([]*net.MX, error) {
+	return r.records, r.err
+}
+
+func (r staticMXResolver) LookupHost(_ context.Context, _ string) ([]string, error) {
+	if len(r.addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+	}
+	return r.addrs, nil
+}
+
+func Test_Validation_Email_NormalizeEmail(t *testing.T) {
+	t.Run("Success: IDN domain is converted to punycode", func(t *testing.T) {
+		emailValidation := validation.NewEmailValidation()
+		normalized, err := emailValidation.NormalizeEmail(" User@Bücher.Example ")
+		if err != nil {
+			t.Fatalf("The test expect no error, got : %v", err)
+		}
+		if normalized != "User@xn--bcher-kva.example" {
+			t.Fatalf("Unexpected normalized email : %s", normalized)
+		}
+	})
+
+	t.Run("Fail: Missing domain", func(t *testing.T) {
+		emailValidation := validation.NewEmailValidation()
+		if _, err := emailValidation.NormalizeEmail("user@"); err == nil {
+			t.Fatal("The test expect an error")
+		}
+	})
+}
+
+func Test_Validation_Email_EmailHasValidLength(t *testing.T) {
+	emailValidation := validation.NewEmailValidation()
+
+	if !emailValidation.EmailHasValidLength("user@mail.com") {
+		t.Fatal("A short email should have a valid length")
+	}
+	if emailValidation.EmailHasValidLength(strings.Repeat("a", 65) + "@mail.com") {
+		t.Fatal("A local part over 64 characters should be rejected")
+	}
+	if emailValidation.EmailHasValidLength("user@" + strings.Repeat("a", 250) + ".com") {
+		t.Fatal("An email over 254 characters should be rejected")
+	}
+}
+
+func Test_Validation_Email_IsDisposableEmail(t *testing.T) {
+	emailValidation := validation.NewEmailValidation()
+	if emailValidation.IsDisposableEmail("user@mailinator.com") {
+		t.Fatal("No domain should be disposable without a checker")
+	}
+
+	emailValidation.SetDisposableDomainChecker(validation.NewDisposableDomainList([]string{"Mailinator.com"}))
+	if !emailValidation.IsDisposableEmail("user@mailinator.com") {
+		t.Fatal("The listed domain should be disposable")
+	}
+	if !emailValidation.IsDisposableEmail("user@eu.mailinator.com") {
+		t.Fatal("Subdomains of a listed domain should be disposable")
+	}
+	if emailValidation.IsDisposableEmail("user@mail.com") {
+		t.Fatal("An unlisted domain should not be disposable")
+	}
+}
+
+func Test_Validation_Email_ValidateEmail_TableDriven(t *testing.T) {
+	tests := []struct {
+		testName      string
+		expectSuccess bool
+		email         string
+		resolver      staticMXResolver
+	}{
+		{
+			testName:      "Success",
+			expectSuccess: true,
+			email:         "gardena19@mail.com",
+			resolver:      staticMXResolver{records: []*net.MX{{Host: "mx.mail.com.", Pref: 10}}},
+		},
+		{
+			testName:      "Success: IDN domain",
+			expectSuccess: true,
+			email:         "user@bücher.example",
+			resolver:      staticMXResolver{records: []*net.MX{{Host: "mx.example.", Pref: 10}}},
+		},
+		{
+			testName:      "Fail: Wrong format",
+			expectSuccess: false,
+			email:         "b!ad@form()t.com",
+		},
+		{
+			testName:      "Fail: Disposable domain",
+			expectSuccess: false,
+			email:         "user@mailinator.com",
+			resolver:      staticMXResolver{records: []*net.MX{{Host: "mx.mailinator.com.", Pref: 10}}},
+		},
+		{
+			testName:      "Fail: No MX record",
+			expectSuccess: false,
+			email:         "user@nomx.example",
+			resolver:      staticMXResolver{err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+		},
+		{
+			testName:      "Success: No MX record, A record",
+			expectSuccess: true,
+			email:         "user@implicit.example",
+			resolver:      staticMXResolver{err: &net.DNSError{Err: "no such host", IsNotFound: true}, addrs: []string{"192.0.2.1"}},
+		},
+		{
+			testName:      "Fail: Null MX",
+			expectSuccess: false,
+			email:         "user@nomail.example",
+			resolver:      staticMXResolver{records: []*net.MX{{Host: ".", Pref: 0}}, addrs: []string{"192.0.2.1"}},
+		},
+		{
+			testName:      "Fail: Resolver error",
+			expectSuccess: false,
+			email:         "user@mail.com",
+			resolver:      staticMXResolver{err: errors.New("timeout")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			emailValidation := validation.NewEmailValidation()
+			emailValidation.SetDisposableDomainChecker(validation.NewDisposableDomainList([]string{"mailinator.com"}))
+			emailValidation.SetMXLookup(true, time.Second)
+			emailValidation.SetMXResolver(tt.resolver)
+
+			err := emailValidation.ValidateEmail(context.Background(), tt.email)
+			if tt.expectSuccess && err != nil {
+				t.Fatalf("The test expect no error, got : %v", err)
+			}
+			if !tt.expectSuccess && err == nil {
+				t.Fatal("The test expect an error")
+			}
+		})
+	}
+}
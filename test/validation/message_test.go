@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bcetienne/tools-go-token/v4/validation"
+)
+
+func Test_Validation_Message_Localizer_TableDriven(t *testing.T) {
+	french := validation.MessageTemplates{
+		validation.CodePasswordTooShort: "le mot de passe doit contenir au moins {min_length} caractères",
+	}
+
+	tests := []struct {
+		testName        string
+		locale          string
+		expectedMessage string
+	}{
+		{
+			testName:        "Success: Registered locale",
+			locale:          "fr",
+			expectedMessage: "le mot de passe doit contenir au moins 10 caractères",
+		},
+		{
+			testName:        "Success: Regional locale falls back to base language",
+			locale:          "fr-CA",
+			expectedMessage: "le mot de passe doit contenir au moins 10 caractères",
+		},
+		{
+			testName:        "Success: Unknown locale falls back to English",
+			locale:          "de",
+			expectedMessage: "password must be at least 10 characters long",
+		},
+	}
+
+	localizer := validation.NewLocalizer()
+	localizer.Register("FR", french)
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			message := localizer.Message(tt.locale, validation.CodePasswordTooShort, map[string]any{"min_length": 10})
+			if message != tt.expectedMessage {
+				t.Fatalf("Expected message %q, got %q", tt.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func Test_Validation_Message_Localizer_MissingCodeFallsBack(t *testing.T) {
+	localizer := validation.NewLocalizer()
+	localizer.Register("fr", validation.MessageTemplates{})
+
+	message := localizer.Message("fr", validation.CodeOTPInvalidFormat, nil)
+	if message != "otp must only contain digits" {
+		t.Fatalf("Expected English fallback, got %q", message)
+	}
+}
+
+func Test_Validation_Message_Localize(t *testing.T) {
+	localizer := validation.NewLocalizer()
+	localizer.Register("fr", validation.MessageTemplates{
+		validation.CodeTokenEmpty: "jeton vide",
+	})
+
+	t.Run("Success: Token error is localized", func(t *testing.T) {
+		err := validation.IsIncomingTokenValid("", 32)
+		if err == nil {
+			t.Fatal("The test expect an error")
+		}
+		if err.Error() != "empty token" {
+			t.Fatalf("The default message should be kept, got %q", err.Error())
+		}
+		if message := localizer.Localize("fr", err); message != "jeton vide" {
+			t.Fatalf("Expected localized message, got %q", message)
+		}
+	})
+
+	t.Run("Success: Foreign errors keep their message", func(t *testing.T) {
+		if message := localizer.Localize("fr", errors.New("boom")); message != "boom" {
+			t.Fatalf("Expected original message, got %q", message)
+		}
+	})
+
+	t.Run("Success: OTP result is localized", func(t *testing.T) {
+		result := validation.NewOTPValidation().ValidateOTP("12345")
+		messages := localizer.LocalizeResult("fr", result)
+		if len(messages) != 1 || messages[0] != "otp must be 6 characters long" {
+			t.Fatalf("Unexpected messages %v", messages)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"errors"
+	"slices"
 	"testing"
 
 	"github.com/bcetienne/tools-go-token/v4/validation"
@@ -166,3 +168,66 @@ func Test_Validation_Password_TableDriven(t *testing.T) {
 	}
 
 }
+
+func Test_Validation_Password_ValidatePassword_TableDriven(t *testing.T) {
+	tests := []struct {
+		testName      string
+		password      string
+		expectedCodes []validation.ValidationCode
+	}{
+		{
+			testName:      "Success",
+			password:      "V4lid_Passw0rd",
+			expectedCodes: []validation.ValidationCode{},
+		},
+		{
+			testName: "Fail: Too short and missing digit",
+			password: "Ab!c",
+			expectedCodes: []validation.ValidationCode{
+				validation.CodePasswordTooShort,
+				validation.CodePasswordMissingDigit,
+			},
+		},
+		{
+			testName: "Fail: Only lowercase",
+			password: "onlylowercase",
+			expectedCodes: []validation.ValidationCode{
+				validation.CodePasswordMissingUppercase,
+				validation.CodePasswordMissingDigit,
+				validation.CodePasswordMissingSpecialChar,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			passwordValidation := validation.NewPasswordValidation()
+			result := passwordValidation.ValidatePassword(tt.password)
+			if result.Valid() != (len(tt.expectedCodes) == 0) {
+				t.Fatalf("The result has the valid status (%v), while %v was expected", result.Valid(), len(tt.expectedCodes) == 0)
+			}
+			if !slices.Equal(result.Codes(), tt.expectedCodes) {
+				t.Fatalf("Expected codes %v, got %v", tt.expectedCodes, result.Codes())
+			}
+		})
+	}
+}
+
+func Test_Validation_Password_ValidatePassword_Params(t *testing.T) {
+	t.Run("Success: Min length is exposed as a parameter", func(t *testing.T) {
+		passwordValidation := validation.NewPasswordValidation()
+		passwordValidation.SetMinLength(12)
+		result := passwordValidation.ValidatePassword("Sh0rt_Pass")
+		if !result.HasCode(validation.CodePasswordTooShort) {
+			t.Fatal("The result should contain the too short code")
+		}
+		if result.Errors[0].Params["min_length"] != 12 {
+			t.Fatalf("Expected min_length param 12, got %v", result.Errors[0].Params["min_length"])
+		}
+
+		var validationError *validation.ValidationError
+		if !errors.As(result.Err(), &validationError) || validationError.Code != validation.CodePasswordTooShort {
+			t.Fatal("The joined error should expose the validation error")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package testkit
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+)
+
+// AuditRecorder is an in-memory lib.AuditLogger keeping every event, to assert on
+// the audit trail of a test. It is safe for concurrent use.
+//
+// Example:
+//
+//	recorder := testkit.NewAuditRecorder()
+//	refreshService, _ := service.NewRefreshTokenService(ctx, redisClient, config, service.WithLogger(recorder))
+//	// ...
+//	events := recorder.EventsOfType(service.AuditEventRefreshTokenRevoked)
+type AuditRecorder struct {
+	mu     sync.Mutex
+	events []lib.AuditEvent
+}
+
+// NewAuditRecorder creates an empty recorder.
+func NewAuditRecorder() *AuditRecorder {
+	return &AuditRecorder{}
+}
+
+// LogAuditEvent records the event.
+func (r *AuditRecorder) LogAuditEvent(_ context.Context, event lib.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events, oldest first.
+func (r *AuditRecorder) Events() []lib.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// EventsOfType returns the recorded events of the given type, oldest first.
+func (r *AuditRecorder) EventsOfType(eventType lib.AuditEventType) []lib.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []lib.AuditEvent
+	for _, event := range r.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Reset forgets the recorded events.
+func (r *AuditRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
@@ -0,0 +1,274 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/service"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInjectedFault is the error of the failures injected by a FaultInjector,
+// unless replaced with WithFaultError.
+var ErrInjectedFault = errors.New("testkit: injected store fault")
+
+// FaultOption configures the faults of a FaultInjector.
+type FaultOption func(*faultConfig)
+
+// faultConfig holds the values set by the fault options.
+type faultConfig struct {
+	errorRate float64
+	err       error
+	latency   time.Duration
+	jitter    time.Duration
+	commands  map[string]bool
+	seed      uint64
+}
+
+// WithErrorRate fails the given share of the operations, from 0 (never, the default)
+// to 1 (always).
+func WithErrorRate(rate float64) FaultOption {
+	return func(c *faultConfig) {
+		c.errorRate = min(max(rate, 0), 1)
+	}
+}
+
+// WithFaultError replaces ErrInjectedFault as the error of the failed operations,
+// e.g. with context.DeadlineExceeded or a *net.OpError to mimic a network failure.
+func WithFaultError(err error) FaultOption {
+	return func(c *faultConfig) {
+		if err != nil {
+			c.err = err
+		}
+	}
+}
+
+// WithLatency delays every operation by latency plus a random duration up to jitter,
+// or until its context is done.
+func WithLatency(latency time.Duration, jitter time.Duration) FaultOption {
+	return func(c *faultConfig) {
+		c.latency = max(latency, 0)
+		c.jitter = max(jitter, 0)
+	}
+}
+
+// WithFaultCommands limits the faults to some operations: Redis commands ("get",
+// "evalsha") for RedisHook, KVStore methods ("SetEX", "Incr") for WrapKVStore.
+// Names are case-insensitive. Every operation is affected by default.
+func WithFaultCommands(commands ...string) FaultOption {
+	return func(c *faultConfig) {
+		c.commands = make(map[string]bool, len(commands))
+		for _, command := range commands {
+			c.commands[strings.ToLower(command)] = true
+		}
+	}
+}
+
+// WithFaultSeed seeds the choice of the failed operations and of the jitter, so that
+// a soak test fails the same operations on every run. The default seed is 0.
+func WithFaultSeed(seed uint64) FaultOption {
+	return func(c *faultConfig) {
+		c.seed = seed
+	}
+}
+
+// FaultStats counts the operations seen by a FaultInjector.
+type FaultStats struct {
+	Operations int
+	Failed     int
+	Delayed    int
+}
+
+// FaultInjector injects errors and latency into the token stores, to test how an
+// application behaves when Redis misbehaves without a proxy such as toxiproxy.
+// Plug it into a go-redis client with RedisHook, or around a KVStore with
+// WrapKVStore. Without options it injects nothing. It is safe for concurrent use.
+// Never use it outside tests.
+//
+// Example:
+//
+//	faults := testkit.NewFaultInjector(testkit.WithErrorRate(0.2), testkit.WithLatency(50*time.Millisecond, 0))
+//	redisClient.AddHook(faults.RedisHook())
+//	refreshService, _ := service.NewRefreshTokenService(ctx, redisClient, config)
+//	// 20% of the Redis commands fail with testkit.ErrInjectedFault
+//	faults.Configure() // Redis recovers
+type FaultInjector struct {
+	mu     sync.Mutex
+	config faultConfig
+	random *rand.Rand
+	stats  FaultStats
+}
+
+// NewFaultInjector creates a fault injector with the given faults.
+func NewFaultInjector(opts ...FaultOption) *FaultInjector {
+	f := &FaultInjector{}
+	f.Configure(opts...)
+	return f
+}
+
+// Configure replaces the faults, e.g. to change them between the phases of a soak
+// test. Without options, faults stop. The statistics are kept.
+func (f *FaultInjector) Configure(opts ...FaultOption) {
+	config := faultConfig{err: ErrInjectedFault}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&config)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+	f.random = rand.New(rand.NewPCG(config.seed, config.seed))
+}
+
+// Stats returns the operations seen since the injector was created.
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// inject applies the faults to an operation: it waits for the latency, then returns
+// the error to fail the operation with, nil to run it.
+func (f *FaultInjector) inject(ctx context.Context, operation string) error {
+	f.mu.Lock()
+	config := f.config
+	if config.commands != nil && !config.commands[strings.ToLower(operation)] {
+		f.mu.Unlock()
+		return nil
+	}
+	f.stats.Operations++
+	delay := config.latency
+	if config.jitter > 0 {
+		delay += time.Duration(f.random.Int64N(int64(config.jitter) + 1))
+	}
+	fail := config.errorRate > 0 && f.random.Float64() < config.errorRate
+	if delay > 0 {
+		f.stats.Delayed++
+	}
+	if fail {
+		f.stats.Failed++
+	}
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if fail {
+		return config.err
+	}
+	return nil
+}
+
+// RedisHook returns a go-redis hook injecting the faults into the commands of a
+// client, single or pipelined (a pipeline fails as a whole). Connections are not affected.
+//
+// Example:
+//
+//	redisClient.AddHook(faults.RedisHook())
+func (f *FaultInjector) RedisHook() redis.Hook {
+	return faultHook{f}
+}
+
+// faultHook implements redis.Hook for a FaultInjector.
+type faultHook struct {
+	faults *FaultInjector
+}
+
+func (h faultHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h faultHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.faults.inject(ctx, cmd.Name()); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h faultHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := h.faults.inject(ctx, cmd.Name()); err != nil {
+				for _, cmd := range cmds {
+					cmd.SetErr(err)
+				}
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// WrapKVStore returns store with the faults injected into its methods, for
+// service.NewOTPServiceWithStore.
+//
+// Example:
+//
+//	store := faults.WrapKVStore(service.NewMemoryKVStore())
+//	otpService, _ := service.NewOTPServiceWithStore(ctx, store, config)
+func (f *FaultInjector) WrapKVStore(store service.KVStore) service.KVStore {
+	return &faultKVStore{store: store, faults: f}
+}
+
+// faultKVStore implements service.KVStore with faults.
+type faultKVStore struct {
+	store  service.KVStore
+	faults *FaultInjector
+}
+
+func (s *faultKVStore) Get(ctx context.Context, key string) (string, bool, error) {
+	if err := s.faults.inject(ctx, "Get"); err != nil {
+		return "", false, err
+	}
+	return s.store.Get(ctx, key)
+}
+
+func (s *faultKVStore) SetEX(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := s.faults.inject(ctx, "SetEX"); err != nil {
+		return err
+	}
+	return s.store.SetEX(ctx, key, value, ttl)
+}
+
+func (s *faultKVStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	if err := s.faults.inject(ctx, "Del"); err != nil {
+		return 0, err
+	}
+	return s.store.Del(ctx, keys...)
+}
+
+func (s *faultKVStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if err := s.faults.inject(ctx, "Incr"); err != nil {
+		return 0, err
+	}
+	return s.store.Incr(ctx, key, ttl)
+}
+
+func (s *faultKVStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if err := s.faults.inject(ctx, "TTL"); err != nil {
+		return 0, err
+	}
+	return s.store.TTL(ctx, key)
+}
+
+func (s *faultKVStore) Scan(ctx context.Context, prefix string, count int, fn func(keys []string) error) error {
+	if err := s.faults.inject(ctx, "Scan"); err != nil {
+		return err
+	}
+	return s.store.Scan(ctx, prefix, count, fn)
+}
@@ -0,0 +1,47 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// NewOTPGenerator returns a deterministic OTP generator for service.WithOTPGenerator:
+// the same seed always produces the same sequence of 6 digits codes.
+// It is safe for concurrent use. Never use it outside tests.
+//
+// Example:
+//
+//	otpService, _ := service.NewOTPService(ctx, redisClient, config,
+//	    service.WithOTPGenerator(testkit.NewOTPGenerator(42)))
+func NewOTPGenerator(seed uint64) func() (string, error) {
+	var mu sync.Mutex
+	random := rand.New(rand.NewPCG(seed, seed))
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return fmt.Sprintf("%06d", random.IntN(1000000)), nil
+	}
+}
+
+// OTPSequence returns an OTP generator for service.WithOTPGenerator returning codes
+// in order, e.g. to test codes with leading zeros. It fails once all codes were returned.
+//
+// Example:
+//
+//	otpService, _ := service.NewOTPService(ctx, redisClient, config,
+//	    service.WithOTPGenerator(testkit.OTPSequence("000123", "999999")))
+func OTPSequence(codes ...string) func() (string, error) {
+	var mu sync.Mutex
+	next := 0
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= len(codes) {
+			return "", errors.New("otp sequence exhausted")
+		}
+		next++
+		return codes[next-1], nil
+	}
+}
@@ -0,0 +1,55 @@
+package testkit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcetienne/tools-go-token/v4/lib"
+	modelAuth "github.com/bcetienne/tools-go-token/v4/model/auth"
+	"github.com/bcetienne/tools-go-token/v4/service"
+)
+
+// accessTokenLeeway mirrors the clock skew tolerated by VerifyAccessToken.
+const accessTokenLeeway = 5 * time.Second
+
+// AccessTokenIssuedAt mints an access token as if it had been created at issuedAt,
+// expiring JWTExpiry later.
+//
+// Parameters:
+//   - config: Configuration used by the service verifying the token
+//   - user: Token subject
+//   - issuedAt: Creation time of the token
+//
+// Returns:
+//   - string: Signed JWT token
+//   - error: Configuration or signing errors
+func AccessTokenIssuedAt(config *lib.Config, user *modelAuth.User, issuedAt time.Time) (string, error) {
+	return service.NewAccessTokenService(config, service.WithClock(func() time.Time { return issuedAt })).CreateAccessToken(user)
+}
+
+// ExpiredAccessToken mints an access token expired for one minute, beyond the
+// verification leeway, so that VerifyAccessToken returns jwt.ErrTokenExpired
+// without waiting.
+//
+// Example:
+//
+//	token, _ := testkit.ExpiredAccessToken(config, user)
+//	_, err := accessService.VerifyAccessToken(token)
+//	errors.Is(err, jwt.ErrTokenExpired) // true
+func ExpiredAccessToken(config *lib.Config, user *modelAuth.User) (string, error) {
+	expiry, err := time.ParseDuration(config.JWTExpiry)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT expiry format: %w", err)
+	}
+	return AccessTokenIssuedAt(config, user, time.Now().Add(-expiry-accessTokenLeeway-time.Minute))
+}
+
+// NearExpiryAccessToken mints an access token still valid for remaining, e.g. to
+// test proactive refresh logic.
+func NearExpiryAccessToken(config *lib.Config, user *modelAuth.User, remaining time.Duration) (string, error) {
+	expiry, err := time.ParseDuration(config.JWTExpiry)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT expiry format: %w", err)
+	}
+	return AccessTokenIssuedAt(config, user, time.Now().Add(remaining-expiry))
+}
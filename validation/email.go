@@ -1,11 +1,84 @@
 package validation
 
-import "regexp"
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	// emailMaxLength is the maximum length of a forward-path address (RFC 5321 §4.5.3.1.3).
+	emailMaxLength int = 254
+	// emailLocalPartMaxLength is the maximum length of the local part (RFC 5321 §4.5.3.1.1).
+	emailLocalPartMaxLength int = 64
+	// emailDomainMaxLength is the maximum length of a domain name (RFC 1035 §2.3.4).
+	emailDomainMaxLength int = 253
+	// defaultMXLookupTimeout bounds DNS MX lookups when no timeout is configured.
+	defaultMXLookupTimeout = 3 * time.Second
+)
+
+// MXResolver resolves the mail exchangers of a domain, and its addresses for the
+// domains without MX records (RFC 5321 §5.1 implicit MX).
+// *net.Resolver satisfies this interface; custom implementations enable testing.
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DisposableDomainChecker reports whether a domain belongs to a disposable
+// (throwaway) email provider. Implementations can be backed by a static list,
+// a database, or a third-party API.
+type DisposableDomainChecker interface {
+	IsDisposableDomain(domain string) bool
+}
+
+// DisposableDomainList is a static, case-insensitive disposable domain blocklist.
+type DisposableDomainList map[string]struct{}
+
+// NewDisposableDomainList builds a blocklist from the provided domains.
+// Domains are lowercased; subdomains of a listed domain are also matched.
+func NewDisposableDomainList(domains []string) DisposableDomainList {
+	list := make(DisposableDomainList, len(domains))
+	for _, domain := range domains {
+		list[strings.ToLower(strings.TrimSpace(domain))] = struct{}{}
+	}
+	return list
+}
+
+// IsDisposableDomain reports whether the domain, or one of its parent domains,
+// is present in the blocklist.
+func (l DisposableDomainList) IsDisposableDomain(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for domain != "" {
+		if _, found := l[domain]; found {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
 
 // EmailValidation maintains a compiled regular expression for efficient
-// email address pattern matching operations.
+// email address pattern matching operations, along with the optional
+// deeper checks (disposable domains, DNS MX lookup).
+// Validation methods are safe for concurrent use; call the Set methods before
+// sharing the validator.
 type EmailValidation struct {
-	emailRegex *regexp.Regexp
+	emailRegex        *regexp.Regexp
+	asciiEmailRegex   *regexp.Regexp
+	disposableDomains DisposableDomainChecker
+	checkMX           bool
+	mxTimeout         time.Duration
+	resolver          MXResolver
 }
 
 // EmailValidationInterface defines the email validation contract,
@@ -14,15 +87,64 @@ type EmailValidationInterface interface {
 	IsValidEmail(email string) bool
 }
 
+// EmailDeliverabilityInterface is implemented by the validators performing the
+// deeper email checks, such as EmailValidation. It is kept apart from
+// EmailValidationInterface so that existing validators remain valid; callers detect
+// it with a type assertion.
+//
+// Example:
+//
+//	if checker, ok := validator.(validation.EmailDeliverabilityInterface); ok {
+//	    err = checker.ValidateEmail(ctx, email)
+//	}
+type EmailDeliverabilityInterface interface {
+	NormalizeEmail(email string) (string, error)
+	EmailHasValidLength(email string) bool
+	IsDisposableEmail(email string) bool
+	HasMXRecord(ctx context.Context, email string) (bool, error)
+	ValidateEmail(ctx context.Context, email string) error
+}
+
 // NewEmailValidation creates a new email validator with a pre-compiled
 // RFC-compliant regular expression pattern. The validator accepts standard
 // email formats including alphanumeric characters, dots, underscores,
 // percent signs, plus signs, and hyphens in the local part, and
 // alphanumeric characters, dots, and hyphens in the domain part.
+//
+// Deeper checks are disabled by default: no disposable domain blocklist
+// and no DNS MX lookup.
 func NewEmailValidation() *EmailValidation {
 	return &EmailValidation{
-		emailRegex: regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`),
+		emailRegex:      regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`),
+		asciiEmailRegex: regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.(?:[a-zA-Z]{2,}|xn--[a-zA-Z0-9-]+)$`),
+		mxTimeout:       defaultMXLookupTimeout,
+		resolver:        net.DefaultResolver,
+	}
+}
+
+// SetDisposableDomainChecker configures the disposable domain blocklist used
+// by IsDisposableEmail and ValidateEmail. A nil checker disables the check.
+func (ev *EmailValidation) SetDisposableDomainChecker(checker DisposableDomainChecker) {
+	ev.disposableDomains = checker
+}
+
+// SetMXLookup enables or disables the DNS MX lookup performed by ValidateEmail.
+// Non-positive timeouts fall back to the default of 3 seconds.
+func (ev *EmailValidation) SetMXLookup(enabled bool, timeout time.Duration) {
+	ev.checkMX = enabled
+	if timeout <= 0 {
+		timeout = defaultMXLookupTimeout
+	}
+	ev.mxTimeout = timeout
+}
+
+// SetMXResolver replaces the DNS resolver used for MX lookups.
+// Nil values are ignored.
+func (ev *EmailValidation) SetMXResolver(resolver MXResolver) {
+	if resolver == nil {
+		return
 	}
+	ev.resolver = resolver
 }
 
 // IsValidEmail validates whether the provided string conforms to
@@ -34,3 +156,146 @@ func NewEmailValidation() *EmailValidation {
 func (ev *EmailValidation) IsValidEmail(email string) bool {
 	return ev.emailRegex.MatchString(email)
 }
+
+// NormalizeEmail trims surrounding whitespace, converts an internationalized
+// domain name (IDN) to its punycode (ASCII) form and lowercases the domain.
+// The local part is left untouched since it may be case-sensitive.
+//
+// Example:
+//
+//	normalized, _ := validator.NormalizeEmail(" user@Bücher.example ")
+//	// normalized == "user@xn--bcher-kva.example"
+func (ev *EmailValidation) NormalizeEmail(email string) (string, error) {
+	local, domain, err := splitEmail(strings.TrimSpace(email))
+	if err != nil {
+		return "", err
+	}
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", newValidationError(CodeEmailInvalidDomain, nil)
+	}
+	return local + "@" + strings.ToLower(asciiDomain), nil
+}
+
+// EmailHasValidLength checks the RFC 5321 length limits: 64 characters for the
+// local part, 253 for the domain and 254 for the whole address.
+func (ev *EmailValidation) EmailHasValidLength(email string) bool {
+	local, domain, err := splitEmail(email)
+	if err != nil {
+		return false
+	}
+	return len(email) <= emailMaxLength &&
+		len(local) <= emailLocalPartMaxLength &&
+		len(domain) <= emailDomainMaxLength
+}
+
+// IsDisposableEmail reports whether the email domain is listed by the
+// configured disposable domain checker. Always false when none is configured.
+func (ev *EmailValidation) IsDisposableEmail(email string) bool {
+	if ev.disposableDomains == nil {
+		return false
+	}
+	_, domain, err := splitEmail(email)
+	if err != nil {
+		return false
+	}
+	return ev.disposableDomains.IsDisposableDomain(domain)
+}
+
+// HasMXRecord reports whether the email domain accepts mail, bounded by the
+// configured timeout. Following RFC 5321 §5.1, a domain without MX records accepts
+// mail at its A/AAAA addresses. A null MX (a single "." record, RFC 7505) declares
+// that the domain accepts no mail. Domains accepting no mail are reported as false
+// without error; resolution failures are returned as errors.
+func (ev *EmailValidation) HasMXRecord(ctx context.Context, email string) (bool, error) {
+	_, domain, err := splitEmail(email)
+	if err != nil {
+		return false, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, ev.mxTimeout)
+	defer cancel()
+
+	records, err := ev.resolver.LookupMX(ctx, domain)
+	if err != nil && !isDNSNotFound(err) {
+		return false, err
+	}
+	if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+		return false, nil // Null MX
+	}
+	if len(records) > 0 {
+		return true, nil
+	}
+
+	// No MX record: the domain itself is the mail exchanger
+	addrs, err := ev.resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if isDNSNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(addrs) > 0, nil
+}
+
+// isDNSNotFound reports whether err tells that the name or record does not exist.
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// ValidateEmail performs every configured check in order:
+//  1. Normalization (trim, IDN to punycode)
+//  2. Format validation
+//  3. RFC 5321 length limits
+//  4. Disposable domain blocklist (when configured)
+//  5. DNS MX lookup (when enabled)
+//
+// Returns:
+//   - error: Descriptive validation error, nil if the email passed every check
+//
+// Example:
+//
+//	validator := validation.NewEmailValidation()
+//	validator.SetDisposableDomainChecker(validation.NewDisposableDomainList([]string{"mailinator.com"}))
+//	validator.SetMXLookup(true, 2*time.Second)
+//	if err := validator.ValidateEmail(ctx, email); err != nil {
+//	    return fmt.Errorf("invalid email: %w", err)
+//	}
+func (ev *EmailValidation) ValidateEmail(ctx context.Context, email string) error {
+	normalized, err := ev.NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if !ev.asciiEmailRegex.MatchString(normalized) {
+		return newValidationError(CodeEmailInvalidFormat, nil)
+	}
+	if !ev.EmailHasValidLength(normalized) {
+		return newValidationError(CodeEmailTooLong, map[string]any{"max_length": emailMaxLength})
+	}
+	if ev.IsDisposableEmail(normalized) {
+		return newValidationError(CodeEmailDisposable, nil)
+	}
+	if ev.checkMX {
+		found, err := ev.HasMXRecord(ctx, normalized)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return newValidationError(CodeEmailNoMXRecord, nil)
+		}
+	}
+	return nil
+}
+
+// splitEmail separates the local part from the domain at the last "@".
+func splitEmail(email string) (string, string, error) {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return "", "", newValidationError(CodeEmailInvalidFormat, nil)
+	}
+	return email[:at], email[at+1:], nil
+}
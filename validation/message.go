@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultLocale is the locale of the built-in English catalog, used as fallback.
+const defaultLocale string = "en"
+
+const (
+	CodeOTPInvalidLength   ValidationCode = "OTP_INVALID_LENGTH"
+	CodeOTPInvalidFormat   ValidationCode = "OTP_INVALID_FORMAT"
+	CodeTokenEmpty         ValidationCode = "TOKEN_EMPTY"
+	CodeTokenTooLong       ValidationCode = "TOKEN_TOO_LONG"
+	CodeEmailInvalidFormat ValidationCode = "EMAIL_INVALID_FORMAT"
+	CodeEmailInvalidDomain ValidationCode = "EMAIL_INVALID_DOMAIN"
+	CodeEmailTooLong       ValidationCode = "EMAIL_TOO_LONG"
+	CodeEmailDisposable    ValidationCode = "EMAIL_DISPOSABLE"
+	CodeEmailNoMXRecord    ValidationCode = "EMAIL_NO_MX_RECORD"
+)
+
+// MessageCatalog renders the message of a validation code in a single language.
+// Implementations return false when they have no message for the code, letting
+// the Localizer fall back to English.
+type MessageCatalog interface {
+	Message(code ValidationCode, params map[string]any) (string, bool)
+}
+
+// MessageTemplates is a MessageCatalog backed by message templates.
+// Parameters are interpolated with the {name} syntax:
+//
+//	validation.MessageTemplates{
+//	    validation.CodePasswordTooShort: "le mot de passe doit contenir au moins {min_length} caractères",
+//	}
+type MessageTemplates map[ValidationCode]string
+
+// Message renders the template registered for code, interpolating params.
+func (mt MessageTemplates) Message(code ValidationCode, params map[string]any) (string, bool) {
+	template, found := mt[code]
+	if !found {
+		return "", false
+	}
+	for name, value := range params {
+		template = strings.ReplaceAll(template, "{"+name+"}", fmt.Sprint(value))
+	}
+	return template, true
+}
+
+// EnglishMessages is the default catalog, used when no catalog is registered
+// for the requested locale or when a catalog lacks a code.
+var EnglishMessages = MessageTemplates{
+	CodePasswordTooShort:           "password must be at least {min_length} characters long",
+	CodePasswordMissingLowercase:   "password must contain a lowercase letter",
+	CodePasswordMissingUppercase:   "password must contain an uppercase letter",
+	CodePasswordMissingDigit:       "password must contain a digit",
+	CodePasswordMissingSpecialChar: "password must contain a special character",
+	CodePasswordUnauthorizedWord:   "password is not allowed",
+	CodeOTPInvalidLength:           "otp must be {length} characters long",
+	CodeOTPInvalidFormat:           "otp must only contain digits",
+	CodeTokenEmpty:                 "empty token",
+	CodeTokenTooLong:               "token too long",
+	CodeEmailInvalidFormat:         "invalid email format",
+	CodeEmailInvalidDomain:         "invalid email domain",
+	CodeEmailTooLong:               "email too long",
+	CodeEmailDisposable:            "disposable email domain",
+	CodeEmailNoMXRecord:            "email domain has no mx record",
+}
+
+// Localizer renders validation errors in the caller's locale.
+// Catalogs are registered per locale ("fr", "pt-BR"); lookups try the exact
+// locale, then its base language ("pt"), then English.
+// A Localizer is safe for concurrent use.
+type Localizer struct {
+	mu       sync.RWMutex
+	catalogs map[string]MessageCatalog
+}
+
+// LocalizerInterface defines the methods for rendering localized validation messages.
+type LocalizerInterface interface {
+	Register(locale string, catalog MessageCatalog)
+	Message(locale string, code ValidationCode, params map[string]any) string
+	Localize(locale string, err error) string
+	LocalizeResult(locale string, result ValidationResult) []string
+}
+
+// NewLocalizer creates a localizer with the English catalog registered.
+//
+// Example:
+//
+//	localizer := validation.NewLocalizer()
+//	localizer.Register("fr", frenchMessages)
+//	messages := localizer.LocalizeResult("fr-FR", validator.ValidatePassword(password))
+func NewLocalizer() *Localizer {
+	return &Localizer{
+		catalogs: map[string]MessageCatalog{defaultLocale: EnglishMessages},
+	}
+}
+
+// Register adds or replaces the catalog of a locale. Locales are case-insensitive.
+func (l *Localizer) Register(locale string, catalog MessageCatalog) {
+	if catalog == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.catalogs[strings.ToLower(locale)] = catalog
+}
+
+// Message renders the message of code in the requested locale, falling back
+// to the base language and then to English. Unknown codes render as the code itself.
+func (l *Localizer) Message(locale string, code ValidationCode, params map[string]any) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	locale = strings.ToLower(locale)
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, defaultLocale)
+
+	for _, candidate := range candidates {
+		catalog, found := l.catalogs[candidate]
+		if !found {
+			continue
+		}
+		if message, ok := catalog.Message(code, params); ok {
+			return message
+		}
+	}
+	return string(code)
+}
+
+// Localize renders a validation error in the requested locale.
+// Errors that are not a *ValidationError are returned with their own message.
+func (l *Localizer) Localize(locale string, err error) string {
+	if err == nil {
+		return ""
+	}
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		return err.Error()
+	}
+	return l.Message(locale, validationError.Code, validationError.Params)
+}
+
+// LocalizeResult renders every failure of a result in the requested locale.
+func (l *Localizer) LocalizeResult(locale string, result ValidationResult) []string {
+	messages := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		messages = append(messages, l.Message(locale, e.Code, e.Params))
+	}
+	return messages
+}
+
+// newValidationError builds a ValidationError with its default English message.
+func newValidationError(code ValidationCode, params map[string]any) *ValidationError {
+	message, _ := EnglishMessages.Message(code, params)
+	return &ValidationError{Code: code, Message: message, Params: params}
+}
@@ -17,6 +17,7 @@ type OTPValidationInterface interface {
 	OTPHasLength(otp string) bool
 	OTPOnlyContainsDigits(otp string) bool
 	ISOTPValid(otp string) bool
+	ValidateOTP(otp string) ValidationResult
 }
 
 // NewOTPValidation creates a new OTP validator instance.
@@ -83,3 +84,23 @@ func (ov *OTPValidation) ISOTPValid(otp string) bool {
 	return ov.OTPHasLength(otp) &&
 		ov.OTPOnlyContainsDigits(otp)
 }
+
+// ValidateOTP performs complete OTP validation and reports each failure
+// with a machine-readable code (OTP_INVALID_LENGTH, OTP_INVALID_FORMAT).
+//
+// Parameters:
+//   - otp: The OTP code to validate
+//
+// Returns:
+//   - ValidationResult: Failed rules, empty if the OTP is valid
+func (ov *OTPValidation) ValidateOTP(otp string) ValidationResult {
+	result := ValidationResult{}
+	if !ov.OTPHasLength(otp) {
+		result.add(CodeOTPInvalidLength, map[string]any{"length": ov.length})
+		return result
+	}
+	if !ov.OTPOnlyContainsDigits(otp) {
+		result.add(CodeOTPInvalidFormat, nil)
+	}
+	return result
+}
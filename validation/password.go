@@ -7,6 +7,8 @@ import (
 
 // PasswordValidation maintains password validation configuration and compiled
 // regular expressions for efficient pattern matching operations.
+// Validation methods are safe for concurrent use; call the Set methods before
+// sharing the validator.
 type PasswordValidation struct {
 	minLength         int
 	unauthorizedWords []string
@@ -27,6 +29,7 @@ type PasswordValidationInterface interface {
 	PasswordContainsSpecialChar(password string) bool
 	PasswordHasMinLength(password string) bool
 	PasswordContainsUnauthorizedWord(password string) bool
+	ValidatePassword(password string) ValidationResult
 	IsPasswordStrengthEnough(password string) bool
 }
 
@@ -104,6 +107,46 @@ func (pv *PasswordValidation) PasswordContainsUnauthorizedWord(password string)
 	return slices.Contains(pv.unauthorizedWords, password)
 }
 
+// ValidatePassword evaluates every configured rule and reports each failure
+// with a machine-readable code, a default English message and the parameters
+// needed to render a localized message. Rules are evaluated in this order:
+//   - Minimum length (PASSWORD_TOO_SHORT, param "min_length")
+//   - Lowercase letters (PASSWORD_MISSING_LOWERCASE)
+//   - Uppercase letters (PASSWORD_MISSING_UPPERCASE)
+//   - Digits (PASSWORD_MISSING_DIGIT)
+//   - Special characters (PASSWORD_MISSING_SPECIAL_CHAR)
+//   - Unauthorized words list (PASSWORD_UNAUTHORIZED_WORD)
+//
+// Example:
+//
+//	result := validator.ValidatePassword(password)
+//	if !result.Valid() {
+//	    // {"errors": [{"code": "PASSWORD_MISSING_DIGIT", ...}]}
+//	    return json.NewEncoder(w).Encode(result)
+//	}
+func (pv *PasswordValidation) ValidatePassword(password string) ValidationResult {
+	result := ValidationResult{}
+	if !pv.PasswordHasMinLength(password) {
+		result.add(CodePasswordTooShort, map[string]any{"min_length": pv.minLength})
+	}
+	if !pv.PasswordContainsLowercase(password) {
+		result.add(CodePasswordMissingLowercase, nil)
+	}
+	if !pv.PasswordContainsUppercase(password) {
+		result.add(CodePasswordMissingUppercase, nil)
+	}
+	if !pv.PasswordContainsDigit(password) {
+		result.add(CodePasswordMissingDigit, nil)
+	}
+	if !pv.PasswordContainsSpecialChar(password) {
+		result.add(CodePasswordMissingSpecialChar, nil)
+	}
+	if pv.PasswordContainsUnauthorizedWord(password) {
+		result.add(CodePasswordUnauthorizedWord, nil)
+	}
+	return result
+}
+
 // IsPasswordStrengthEnough performs comprehensive validation against all
 // configured rules. The password must satisfy ALL requirements:
 //   - Contains lowercase letters
@@ -114,11 +157,7 @@ func (pv *PasswordValidation) PasswordContainsUnauthorizedWord(password string)
 //   - Not found in unauthorized words list
 //
 // Returns true if the password passes all validation rules.
+// Use ValidatePassword to know which rules failed.
 func (pv *PasswordValidation) IsPasswordStrengthEnough(password string) bool {
-	return pv.PasswordContainsLowercase(password) &&
-		pv.PasswordContainsUppercase(password) &&
-		pv.PasswordContainsDigit(password) &&
-		pv.PasswordContainsSpecialChar(password) &&
-		!pv.PasswordContainsUnauthorizedWord(password) &&
-		pv.PasswordHasMinLength(password)
+	return pv.ValidatePassword(password).Valid()
 }
@@ -0,0 +1,86 @@
+package validation
+
+import "errors"
+
+// ValidationCode is a stable, machine-readable identifier of a failed validation rule.
+// Codes never change between releases, so frontends can map them to their own
+// messages instead of re-implementing the rules.
+type ValidationCode string
+
+const (
+	CodePasswordTooShort           ValidationCode = "PASSWORD_TOO_SHORT"
+	CodePasswordMissingLowercase   ValidationCode = "PASSWORD_MISSING_LOWERCASE"
+	CodePasswordMissingUppercase   ValidationCode = "PASSWORD_MISSING_UPPERCASE"
+	CodePasswordMissingDigit       ValidationCode = "PASSWORD_MISSING_DIGIT"
+	CodePasswordMissingSpecialChar ValidationCode = "PASSWORD_MISSING_SPECIAL_CHAR"
+	CodePasswordUnauthorizedWord   ValidationCode = "PASSWORD_UNAUTHORIZED_WORD"
+)
+
+// ValidationError describes a single failed validation rule.
+//
+// Fields:
+//   - Code: Machine-readable rule identifier (e.g. PASSWORD_TOO_SHORT)
+//   - Message: Default English message
+//   - Params: Values referenced by the message (e.g. "min_length": 8), for i18n interpolation
+type ValidationError struct {
+	Code    ValidationCode `json:"code"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+// Error returns the default English message, so a ValidationError can be
+// used wherever an error is expected.
+func (ve *ValidationError) Error() string {
+	return ve.Message
+}
+
+// ValidationResult lists every rule that failed during a validation.
+// An empty result means the input is valid.
+//
+// JSON serialization:
+//   - Example: {"errors": [{"code": "PASSWORD_TOO_SHORT", "message": "password must be at least 8 characters long", "params": {"min_length": 8}}]}
+type ValidationResult struct {
+	Errors []ValidationError `json:"errors"`
+}
+
+// Valid reports whether no rule failed.
+func (vr ValidationResult) Valid() bool {
+	return len(vr.Errors) == 0
+}
+
+// Codes returns the codes of the failed rules, in evaluation order.
+func (vr ValidationResult) Codes() []ValidationCode {
+	codes := make([]ValidationCode, 0, len(vr.Errors))
+	for _, e := range vr.Errors {
+		codes = append(codes, e.Code)
+	}
+	return codes
+}
+
+// HasCode reports whether the rule identified by code failed.
+func (vr ValidationResult) HasCode(code ValidationCode) bool {
+	for _, e := range vr.Errors {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Err joins every failure into a single error, nil if the result is valid.
+// Individual failures can be extracted with errors.As.
+func (vr ValidationResult) Err() error {
+	if vr.Valid() {
+		return nil
+	}
+	errs := make([]error, 0, len(vr.Errors))
+	for i := range vr.Errors {
+		errs = append(errs, &vr.Errors[i])
+	}
+	return errors.Join(errs...)
+}
+
+// add appends a failed rule to the result, with its default English message.
+func (vr *ValidationResult) add(code ValidationCode, params map[string]any) {
+	vr.Errors = append(vr.Errors, *newValidationError(code, params))
+}
@@ -1,7 +1,5 @@
 package validation
 
-import "errors"
-
 // IsIncomingTokenValid validates the format of an incoming token string.
 // Performs basic validation checks for token length and emptiness.
 //
@@ -18,7 +16,7 @@ import "errors"
 //   - tokenMaxLength: Maximum allowed length for the token
 //
 // Returns:
-//   - error: Validation error with descriptive message, nil if valid
+//   - error: *ValidationError (TOKEN_EMPTY or TOKEN_TOO_LONG), nil if valid
 //
 // Example:
 //
@@ -27,10 +25,10 @@ import "errors"
 //	}
 func IsIncomingTokenValid(token string, tokenMaxLength int) error {
 	if len(token) == 0 {
-		return errors.New("empty token")
+		return newValidationError(CodeTokenEmpty, nil)
 	}
 	if len(token) > tokenMaxLength {
-		return errors.New("token too long")
+		return newValidationError(CodeTokenTooLong, map[string]any{"max_length": tokenMaxLength})
 	}
 	return nil
 }